@@ -0,0 +1,109 @@
+// /home/krylon/go/src/github.com/blicero/guang/auth/auth.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 14:34:15 krylon>
+
+// Package auth provides the building blocks for authenticating users of
+// the web frontend: Roles, password hashes and random tokens.
+//
+// Session keys and API tokens are only ever stored as hashes, so someone
+// who gets to read the database cannot use them.
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MinPasswordLength is the minimum length of a password in bytes.
+const MinPasswordLength = 10
+
+// tokenBytes is the number of random bytes in a token.
+const tokenBytes = 32
+
+// ErrPasswordTooShort is returned when a password is shorter than
+// MinPasswordLength.
+var ErrPasswordTooShort = fmt.Errorf("password must be at least %d characters long",
+	MinPasswordLength)
+
+// HashPassword returns a hash of the password suitable for storing it.
+func HashPassword(password string) (string, error) {
+	var (
+		err  error
+		hash []byte
+	)
+
+	if len(password) < MinPasswordLength {
+		return "", ErrPasswordTooShort
+	} else if hash, err = bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost); err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+} // func HashPassword(password string) (string, error)
+
+// dummyHash is what we check passwords against when there is no user by
+// the given name, so a failed login takes the same time whether the user
+// exists or not.
+var (
+	dummyOnce sync.Once
+	dummyHash []byte
+)
+
+// CheckPassword returns true if password matches the hash. If hash is
+// empty, CheckPassword takes as long as it would otherwise, but always
+// returns false.
+func CheckPassword(hash, password string) bool {
+	if hash == "" {
+		dummyOnce.Do(func() {
+			dummyHash, _ = bcrypt.GenerateFromPassword([]byte("There is no such user"), bcrypt.DefaultCost)
+		})
+
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password)) // nolint: errcheck
+		return false
+	}
+
+	var err = bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+
+	return err == nil
+} // func CheckPassword(hash, password string) bool
+
+// NewToken returns a random token, to be given to the client, along with
+// the hash to store in the database.
+func NewToken() (token, hash string, err error) {
+	var buf = make([]byte, tokenBytes)
+
+	if _, err = rand.Read(buf); err != nil {
+		return "", "", err
+	}
+
+	token = base64.RawURLEncoding.EncodeToString(buf)
+	return token, HashToken(token), nil
+} // func NewToken() (token, hash string, err error)
+
+// HashToken returns the hash of a token that is stored in the database.
+// Unlike passwords, tokens are long random strings, so a plain SHA-256
+// is good enough.
+func HashToken(token string) string {
+	var sum = sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+} // func HashToken(token string) string
+
+// RandomPassword returns a random password for a newly created user.
+func RandomPassword() (string, error) {
+	var buf = make([]byte, 12)
+
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("Cannot generate random password: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+} // func RandomPassword() (string, error)
@@ -0,0 +1,83 @@
+// /home/krylon/go/src/github.com/blicero/guang/auth/auth_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 14:34:15 krylon>
+
+package auth
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPassword(t *testing.T) {
+	var (
+		err  error
+		hash string
+	)
+
+	if _, err = HashPassword("short"); !errors.Is(err, ErrPasswordTooShort) {
+		t.Errorf("Short password was not rejected: %v", err)
+	}
+
+	if hash, err = HashPassword("correct horse battery staple"); err != nil {
+		t.Fatalf("Error hashing password: %s", err.Error())
+	} else if !CheckPassword(hash, "correct horse battery staple") {
+		t.Error("Correct password was not accepted")
+	} else if CheckPassword(hash, "Correct horse battery staple") {
+		t.Error("Wrong password was accepted")
+	} else if CheckPassword("", "") {
+		t.Error("Empty hash was accepted")
+	}
+} // func TestPassword(t *testing.T)
+
+func TestToken(t *testing.T) {
+	var (
+		err          error
+		t1, h1, t2   string
+		seen         = make(map[string]bool)
+		expectedSize = 43 // 32 bytes, base64 without padding
+	)
+
+	for i := 0; i < 16; i++ {
+		if t1, h1, err = NewToken(); err != nil {
+			t.Fatalf("Error creating token: %s", err.Error())
+		} else if len(t1) != expectedSize {
+			t.Errorf("Token %q has length %d, expected %d", t1, len(t1), expectedSize)
+		} else if seen[t1] {
+			t.Errorf("Token %q was generated twice", t1)
+		} else if h1 != HashToken(t1) {
+			t.Errorf("Hash of token %q is not stable", t1)
+		}
+
+		seen[t1] = true
+	}
+
+	if t2, _, err = NewToken(); err != nil {
+		t.Fatalf("Error creating token: %s", err.Error())
+	} else if HashToken(t2) == h1 {
+		t.Error("Different tokens have the same hash")
+	}
+} // func TestToken(t *testing.T)
+
+func TestRole(t *testing.T) {
+	var (
+		err error
+		r   Role
+	)
+
+	if r, err = ParseRole("operator"); err != nil {
+		t.Errorf("Error parsing role: %s", err.Error())
+	} else if r != Operator {
+		t.Errorf("Parsed role %s, expected %s", r, Operator)
+	} else if _, err = ParseRole("admin"); err == nil {
+		t.Error("Unknown role was parsed")
+	}
+
+	if ReadOnly.Allows(Operator) {
+		t.Error("ReadOnly allows Operator")
+	} else if !Operator.Allows(ReadOnly) {
+		t.Error("Operator does not allow ReadOnly")
+	}
+} // func TestRole(t *testing.T)
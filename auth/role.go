@@ -0,0 +1,50 @@
+// /home/krylon/go/src/github.com/blicero/guang/auth/role.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 14:34:15 krylon>
+
+package auth
+
+//go:generate stringer -type=Role
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Role determines what a user of the web frontend is allowed to do.
+type Role uint8
+
+// ReadOnly users can look at everything, but not change anything.
+// Operators can also start and stop workers and trigger updates.
+//
+// The values are stored in the database, so new Roles must be appended.
+const (
+	ReadOnly Role = iota
+	Operator
+)
+
+// AllRoles returns all Roles.
+func AllRoles() []Role {
+	return []Role{
+		ReadOnly,
+		Operator,
+	}
+} // func AllRoles() []Role
+
+// ParseRole returns the Role with the given name, ignoring case.
+func ParseRole(name string) (Role, error) {
+	for _, r := range AllRoles() {
+		if strings.EqualFold(r.String(), name) {
+			return r, nil
+		}
+	}
+
+	return 0, fmt.Errorf("Unknown role %q", name)
+} // func ParseRole(name string) (Role, error)
+
+// Allows returns true if a user with Role r may do what requires Role req.
+func (r Role) Allows(req Role) bool {
+	return r >= req
+} // func (r Role) Allows(req Role) bool
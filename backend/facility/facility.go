@@ -2,7 +2,7 @@
 // -*- mode: go; coding: utf-8; -*-
 // Created on 10. 11. 2022 by Benjamin Walkenhorst
 // (c) 2022 Benjamin Walkenhorst
-// Time-stamp: <2022-11-13 17:01:15 krylon>
+// Time-stamp: <2026-10-19 14:19:36 krylon>
 
 // Package facility provides symbolic constants to enumerate the compoentns
 // of the application.
@@ -10,6 +10,11 @@ package facility
 
 //go:generate stringer -type=Facility
 
+import (
+	"fmt"
+	"strings"
+)
+
 // Facility is a symbolic constant identifying the moving parts of
 // the application.
 type Facility uint8
@@ -29,3 +34,14 @@ func All() []Facility {
 		XFR,
 	}
 } // func All() []Facility
+
+// Parse returns the Facility with the given name, ignoring case.
+func Parse(name string) (Facility, error) {
+	for _, f := range All() {
+		if strings.EqualFold(f.String(), name) {
+			return f, nil
+		}
+	}
+
+	return 0, fmt.Errorf("Unknown facility %q", name)
+} // func Parse(name string) (Facility, error)
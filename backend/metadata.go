@@ -2,7 +2,7 @@
 // -*- mode: go; coding: utf-8; -*-
 // Created on 20. 08. 2016 by Benjamin Walkenhorst
 // (c) 2016 Benjamin Walkenhorst
-// Time-stamp: <2024-05-30 00:03:13 krylon>
+// Time-stamp: <2026-10-19 16:34:17 krylon>
 //
 // Sonntag, 21. 08. 2016, 18:25
 // Looking up locations seems to work reasonably well. Whether or not the
@@ -13,282 +13,524 @@
 package backend
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"log"
+	"net"
+	"os"
 	"path/filepath"
-	"regexp"
+	"sync"
+	"time"
 
+	"github.com/blicero/guang/banner"
 	"github.com/blicero/guang/common"
 	"github.com/blicero/guang/data"
 	"github.com/blicero/guang/database"
+	"github.com/blicero/guang/fingerprint"
+	"github.com/blicero/guang/logdomain"
+	"github.com/blicero/guang/rules"
+	"github.com/blicero/guang/vuln"
 
 	"github.com/oschwald/geoip2-golang"
+	"github.com/oschwald/maxminddb-golang"
 )
 
+// errClaim indicates that the metadata timestamp of a Host could not be
+// set. This usually means that the database has a problem, so there is
+// little point in trying the remaining Hosts.
+var errClaim = errors.New("cannot set metadata timestamp")
+
+// ErrNoGeoIP is returned by lookups that need a GeoIP database that is not
+// available.
+var ErrNoGeoIP = errors.New("GeoIP database is not available")
+
 const (
 	geoIPCityPath    = "GeoLite2-City.mmdb"
 	geoIPCountryPath = "GeoLite2-Country.mmdb"
+	geoIPASNPath     = "GeoLite2-ASN.mmdb"
 )
 
-var osList = []string{
-	"Windows",
-	"Ubuntu",
-	"Debian",
-	"CentOS",
-	"Red Hat",
-	"Fedora",
-	"Yocto",
-	"FreeBSD",
-	"NetBSD",
-	"OpenBSD",
-	"DragonflyBSD",
-	"RouterOS",
-	"Linux",
-	"JUNOS",
-	"Cisco IOS",
-	"SonicOS",
+// asnRecord is the part of a GeoLite2-ASN record we care about.
+type asnRecord struct {
+	ASN uint32 `maxminddb:"autonomous_system_number"`
+	Org string `maxminddb:"autonomous_system_organization"`
 }
 
-var osPatterns = map[string][]*regexp.Regexp{
-	"Windows": {
-		regexp.MustCompile("Microsoft"),
-		regexp.MustCompile("Windows"),
-	},
-	"Debian": {
-		regexp.MustCompile("(?i)Debian"),
-		regexp.MustCompile("(?i)[.]deb"),
-	},
-	"Ubuntu": {
-		regexp.MustCompile("(?i)ubuntu"),
-	},
-	"CentOS": {
-		regexp.MustCompile("(?i)CentOS"),
-	},
-	"Red Hat": {
-		regexp.MustCompile(`(?i)rhel\d+`),
-		regexp.MustCompile("(?i)Red ?Hat"),
-		regexp.MustCompile(`(?i)[.]el\d+[.]`),
-	},
-	"Fedora": {
-		regexp.MustCompile("(?i)fedora"),
-	},
-	"Yocto Linux": {
-		regexp.MustCompile("(?i)yocto"),
-	},
-	"FreeBSD": {
-		regexp.MustCompile("(?i)FreeBSD"),
-	},
-	"OpenBSD": {
-		regexp.MustCompile("(?i)OpenBSD"),
-	},
-	"DragonflyBSD": {
-		regexp.MustCompile("Dragonfly"),
-	},
-	"NetBSD": {
-		regexp.MustCompile("(?i)NetBSD"),
-	},
-	"RouterOS": {
-		regexp.MustCompile("(?i)RouterOS"),
-	},
-	"Linux": {
-		regexp.MustCompile("(?i)Linux"),
-	},
-	"JUNOS": {
-		regexp.MustCompile("(?i:JUNOS|Juniper)"),
-	},
-	"Cisco IOS": {
-		regexp.MustCompile("(?i)Cisco IOS Software"),
-		regexp.MustCompile("(?i)Cisco Systems"),
-	},
-	"SonicOS": {
-		regexp.MustCompile("(?i)SonicOS"),
-		regexp.MustCompile("(?i)SonicWALL"),
-	},
+// geoDB is one of the GeoIP databases the MetaEngine uses. rdr is nil if
+// the file does not exist or cannot be opened, stamp is the modification
+// time of the file when we last tried to open it.
+type geoDB struct {
+	name    string
+	path    string
+	stamp   time.Time
+	missing bool
+	rdr     *maxminddb.Reader
 }
 
-// MetaEngine processes metadata on Hosts.
+// MetaEngine processes metadata on Hosts. It is meant to be long-lived
+// and shared, the Nexus owns one.
+//
+// The GeoIP databases are optional. Without the city and country
+// databases, the MetaEngine leaves the location of Hosts empty, without
+// the AS database, their AS. Reload picks up files that were replaced by
+// a newer download, or added, since they were opened.
 type MetaEngine struct {
-	citydb    *geoip2.Reader
-	countrydb *geoip2.Reader
-	log       *log.Logger
+	geoLock   sync.RWMutex
+	loadLock  sync.Mutex
+	citydb    geoDB
+	countrydb geoDB
+	asndb     geoDB
+	rules     *rules.Engine
+	banners   *banner.DB
+	vulns     *vuln.Matcher
+	log       *common.Logger
 } // type MetaEngine struct
 
-// OpenMetaEngine creates a new MetaEngine.
+// OpenMetaEngine creates a new MetaEngine that looks for the GeoLite2
+// databases in the folder prefix, or in common.BaseDir if prefix is empty.
 func OpenMetaEngine(prefix string) (*MetaEngine, error) {
-	var eng *MetaEngine = new(MetaEngine)
-	var err error
-	var msg, countrydbPath, citydbPath string
+	var (
+		err error
+		eng = new(MetaEngine)
+	)
 
-	// if ex, _ := krylib.Fexists(prefix); ex {
-	// 	countrydbPath = prefix
-	// } else {
-	// 	countrydbPath = filepath.Join(common.BaseDir, geoIPCityPath)
-	// }
+	if prefix == "" {
+		prefix = common.BaseDir
+	}
 
-	countrydbPath = filepath.Join(common.BaseDir, geoIPCountryPath)
-	citydbPath = filepath.Join(common.BaseDir, geoIPCityPath)
+	eng.citydb = geoDB{name: "city", path: filepath.Join(prefix, geoIPCityPath)}
+	eng.countrydb = geoDB{name: "country", path: filepath.Join(prefix, geoIPCountryPath)}
+	eng.asndb = geoDB{name: "AS", path: filepath.Join(prefix, geoIPASNPath)}
 
-	if eng.log, err = common.GetLogger("MetaEngine"); err != nil {
+	if eng.log, err = common.GetLogger(logdomain.MetaEngine); err != nil {
+		return nil, err
+	} else if eng.rules, err = rules.NewEngine(); err != nil {
+		return nil, err
+	} else if eng.vulns, err = vuln.NewMatcher(); err != nil {
 		return nil, err
-	} else if eng.countrydb, err = geoip2.Open(countrydbPath); err != nil {
-		msg = fmt.Sprintf("Error opening GeoIP database %s: %s",
-			countrydbPath,
+	} else if eng.banners, err = banner.Open(common.ServiceProbesPath); err != nil {
+		eng.log.Printf("[ERROR] Cannot load patterns for the banner parser: %s\n",
 			err.Error())
-		eng.log.Println(msg)
-		return nil, errors.New(msg)
-	} else if eng.countrydb == nil {
-		msg = "Opening GeoIP database did not return an error, but the geoip2.Reader was nil!"
-		eng.log.Println(msg)
-		return nil, errors.New(msg)
-	} else if eng.citydb, err = geoip2.Open(citydbPath); err != nil {
-		msg = fmt.Sprintf("Cannot open GeoIP database %s: %s",
-			citydbPath,
+		return nil, err
+	}
+
+	eng.Reload()
+
+	return eng, nil
+} // func OpenMetaEngine(prefix string) (*MetaEngine, error)
+
+// Reload opens the GeoIP databases whose files appeared or were modified
+// since we last looked. It returns true if any database was (re)opened.
+// If a file cannot be opened, e.g. because the download is still in
+// progress, the database opened before, if any, remains in use.
+func (m *MetaEngine) Reload() bool {
+	var changed bool
+
+	m.loadLock.Lock()
+	defer m.loadLock.Unlock()
+
+	for _, g := range []*geoDB{&m.citydb, &m.countrydb, &m.asndb} {
+		if m.reloadDB(g) {
+			changed = true
+		}
+	}
+
+	return changed
+} // func (m *MetaEngine) Reload() bool
+
+// reloadDB opens a single GeoIP database again if its file changed.
+func (m *MetaEngine) reloadDB(g *geoDB) bool {
+	var (
+		err  error
+		info os.FileInfo
+		rdr  *maxminddb.Reader
+	)
+
+	if info, err = os.Stat(g.path); err != nil {
+		if !g.missing {
+			m.log.Printf("[WARN] GeoIP %s database %s is not available, running without it: %s\n",
+				g.name,
+				g.path,
+				err.Error())
+			g.missing = true
+		}
+		return false
+	} else if info.ModTime().Equal(g.stamp) {
+		return false
+	}
+
+	g.missing = false
+	g.stamp = info.ModTime()
+
+	if rdr, err = maxminddb.Open(g.path); err != nil {
+		m.log.Printf("[ERROR] Cannot open GeoIP %s database %s: %s\n",
+			g.name,
+			g.path,
 			err.Error())
-		eng.log.Printf("[ERROR] %s\n", msg)
-		return nil, errors.New(msg)
-	} else {
-		return eng, nil
+		return false
+	}
+
+	m.geoLock.Lock()
+	var old = g.rdr
+	g.rdr = rdr
+	if old != nil {
+		old.Close() // nolint: errcheck
 	}
-} // func OpenMetaEngine() (*MetaEngine, error)
+	m.geoLock.Unlock()
+
+	m.log.Printf("[INFO] Loaded GeoIP %s database %s, built on %s\n",
+		g.name,
+		g.path,
+		time.Unix(int64(rdr.Metadata.BuildEpoch), 0).Format(common.TimestampFormatDate))
+
+	return true
+} // func (m *MetaEngine) reloadDB(g *geoDB) bool
 
 // Close closes the MetaEngine.
 func (m *MetaEngine) Close() {
-	m.countrydb.Close()
+	m.geoLock.Lock()
+	defer m.geoLock.Unlock()
+
+	for _, g := range []*geoDB{&m.citydb, &m.countrydb, &m.asndb} {
+		if g.rdr != nil {
+			g.rdr.Close() // nolint: errcheck
+			g.rdr = nil
+		}
+	}
 } // func (m *MetaEngine) Close()
 
+// geoName returns the name in the language configured in
+// common.GeoIPLanguage, or the English name if there is none.
+func geoName(names map[string]string) string {
+	if name := names[common.GeoIPLanguage]; name != "" {
+		return name
+	}
+
+	return names["en"]
+} // func geoName(names map[string]string) string
+
 // LookupCountry attempts to determine what county a Host is located in.
 func (m *MetaEngine) LookupCountry(h *data.Host) (string, error) {
-	var err error
-	var country *geoip2.Country
+	var (
+		err     error
+		country geoip2.Country
+	)
 
-	if country, err = m.countrydb.Country(h.Address); err != nil {
+	m.geoLock.RLock()
+	defer m.geoLock.RUnlock()
+
+	if m.countrydb.rdr == nil {
+		return "", ErrNoGeoIP
+	} else if err = m.countrydb.rdr.Lookup(h.Address, &country); err != nil {
 		return "", err
 	}
 
-	return country.Country.Names["de"], nil
+	return geoName(country.Country.Names), nil
 } // func (m *MetaEngine) LookupCountry(h *Host) (string, error)
 
 // LookupCity attempts to determine what city a Host is located in.
 func (m *MetaEngine) LookupCity(h *data.Host) (string, error) {
-	var err error
-	var city *geoip2.City
+	var (
+		err  error
+		city geoip2.City
+	)
 
-	if city, err = m.citydb.City(h.Address); err != nil {
+	m.geoLock.RLock()
+	defer m.geoLock.RUnlock()
+
+	if m.citydb.rdr == nil {
+		return "", ErrNoGeoIP
+	} else if err = m.citydb.rdr.Lookup(h.Address, &city); err != nil {
 		return "", err
 	}
 
-	return city.City.Names["de"], nil
+	return geoName(city.City.Names), nil
 } // func (m *MetaEngine) LookupCity(h *Host) (string, error)
 
-// LookupOperatingSystem attempts to determine what OS a Host is running.
-func (m *MetaEngine) LookupOperatingSystem(h *data.HostWithPorts) string {
-	var results map[string]int = make(map[string]int)
-
-PORT:
-	for _, port := range h.Ports {
-		//for os, patterns := range osPatterns {
-		for _, osname := range osList {
-			patterns := osPatterns[osname]
-			for _, pattern := range patterns {
-				if port.Reply != nil && pattern.MatchString(*port.Reply) {
-					results[osname]++
-					continue PORT
-				}
-			}
+// LookupLocation determines where a Host is located. It returns the
+// location as shown to the user, i.e. city and country in the configured
+// language, and the same in structured form. If the GeoIP databases know
+// nothing about the address, or are not available, both are empty.
+func (m *MetaEngine) LookupLocation(h *data.Host) (string, data.GeoInfo, error) {
+	var (
+		err           error
+		rec           geoip2.City
+		geo           data.GeoInfo
+		city, country string
+	)
+
+	m.geoLock.RLock()
+	defer m.geoLock.RUnlock()
+
+	if m.citydb.rdr != nil {
+		if err = m.citydb.rdr.Lookup(h.Address, &rec); err != nil {
+			return "", geo, err
+		}
+
+		geo.Country = rec.Country.IsoCode
+		geo.City = rec.City.Names["en"]
+		city = geoName(rec.City.Names)
+		country = geoName(rec.Country.Names)
+
+		if len(rec.Subdivisions) > 0 {
+			geo.Subdivision = rec.Subdivisions[0].IsoCode
+		}
+
+		if rec.Location.AccuracyRadius != 0 {
+			geo.Latitude = rec.Location.Latitude
+			geo.Longitude = rec.Location.Longitude
+			geo.Accuracy = rec.Location.AccuracyRadius
 		}
 	}
 
-	var (
-		os     = "Unknown"
-		hitCnt int
-	)
+	// The country database sometimes knows the country of addresses
+	// missing from the city database.
+	if geo.Country == "" && m.countrydb.rdr != nil {
+		var c geoip2.Country
 
-	for system, cnt := range results {
-		if cnt > hitCnt {
-			os = system
-			hitCnt = cnt
+		if err = m.countrydb.rdr.Lookup(h.Address, &c); err != nil {
+			return "", geo, err
 		}
+
+		geo.Country = c.Country.IsoCode
+		country = geoName(c.Country.Names)
 	}
 
-	return os
-} // func (m *MetaEngine) LookupOperatingSystem(h *HostWithPorts) string
+	if city != "" && country != "" {
+		return city + ", " + country, geo, nil
+	} else if city != "" {
+		return city, geo, nil
+	}
+
+	return country, geo, nil
+} // func (m *MetaEngine) LookupLocation(h *data.Host) (string, data.GeoInfo, error)
 
-// UpdateMetadata refreshes the location and OS metadata for all hosts.
-func (m *MetaEngine) UpdateMetadata() error {
+// LookupAS determines the autonomous system a Host is part of, the prefix
+// it is announced in, and which registry allocated the address and when.
+// What we cannot find out is left empty, so it is not an error if the
+// GeoLite2-ASN database is missing or no delegation covers the address.
+func (m *MetaEngine) LookupAS(ctx context.Context, db database.HostDB, h *data.Host) (data.ASInfo, error) {
 	var (
-		err   error
-		db    *database.HostDB
-		hosts []data.Host
+		err error
+		as  data.ASInfo
+		del *data.Delegation
 	)
 
-	if db, err = database.OpenDB(common.DbPath); err != nil {
-		m.log.Printf("[ERROR] Cannot open HostDB at %s: %s\n",
-			common.DbPath,
-			err.Error())
-		return err
+	m.geoLock.RLock()
+	if m.asndb.rdr != nil {
+		var (
+			rec     asnRecord
+			network *net.IPNet
+			ok      bool
+		)
+
+		if network, ok, err = m.asndb.rdr.LookupNetwork(h.Address, &rec); err != nil {
+			m.log.Printf("[ERROR] Cannot lookup AS for %s: %s\n",
+				h.Address,
+				err.Error())
+		} else if ok {
+			as.ASN = rec.ASN
+			as.Org = rec.Org
+			as.Prefix = network.String()
+		}
 	}
+	m.geoLock.RUnlock()
 
-	defer db.Close() // nolint: errcheck
+	if del, err = db.DelegationLookup(ctx, h.Address); err == nil {
+		as.Registry = del.Registry
+		as.Allocated = del.Date
+	} else if !errors.Is(err, database.ErrNotFound) {
+		return as, err
+	}
 
-	if hosts, err = db.HostGetAll(); err != nil {
-		m.log.Printf("[ERROR] Cannot get all hosts: %s\n",
-			err.Error())
-		return err
+	return as, nil
+} // func (m *MetaEngine) LookupAS(ctx context.Context, db database.HostDB, h *data.Host) (data.ASInfo, error)
+
+// ASNetworks returns the networks the GeoLite2-ASN database says the
+// autonomous system announces.
+func (m *MetaEngine) ASNetworks(asn uint32) ([]*net.IPNet, error) {
+	var (
+		err  error
+		list []*net.IPNet
+	)
+
+	m.geoLock.RLock()
+	defer m.geoLock.RUnlock()
+
+	if m.asndb.rdr == nil {
+		return nil, fmt.Errorf("Cannot look up networks of AS%d: %w", asn, ErrNoGeoIP)
 	}
 
-	for _, host := range hosts {
+	var iter = m.asndb.rdr.Networks(maxminddb.SkipAliasedNetworks)
+
+	for iter.Next() {
 		var (
-			city, country, location, os string
-			hwp                         = data.HostWithPorts{Host: host}
+			rec     asnRecord
+			network *net.IPNet
 		)
 
-		if city, err = m.LookupCity(&host); err != nil {
-			m.log.Printf("[ERROR] Cannot lookup city for %s: %s\n",
-				host.Address,
-				err.Error())
-			city = ""
-		} else if country, err = m.LookupCountry(&host); err != nil {
-			m.log.Printf("[ERROR] Cannot lookup country for %s: %s\n",
-				host.Address, err.Error())
-			goto LOOKUP_OS
+		if network, err = iter.Network(&rec); err != nil {
+			return nil, err
+		} else if rec.ASN == asn {
+			list = append(list, network)
 		}
+	}
+
+	if err = iter.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+} // func (m *MetaEngine) ASNetworks(asn uint32) ([]*net.IPNet, error)
 
-		if city != "" {
-			location = fmt.Sprintf("%s, %s",
-				city, country)
-		} else {
-			location = country
+// LookupOperatingSystem attempts to determine what OS a Host is running.
+func (m *MetaEngine) LookupOperatingSystem(h *data.HostWithPorts) data.OSGuess {
+	return fingerprint.Guess(h.Ports)
+} // func (m *MetaEngine) LookupOperatingSystem(h *HostWithPorts) data.OSGuess
+
+// UpdateDirty refreshes the metadata of up to <max> Hosts that were added
+// or had Ports scanned since their metadata were last updated. It returns
+// the number of Hosts it processed.
+//
+// Failing to update a single Host is logged, but it does not stop the
+// update of the others. The Host is not retried until it is scanned again,
+// lest a Host that cannot be processed keeps the MetaEngine busy forever.
+func (m *MetaEngine) UpdateDirty(ctx context.Context, db database.HostDB, max int) (int, error) {
+	var (
+		err   error
+		hosts []data.Host
+	)
+
+	if hosts, err = db.HostGetDirty(ctx, max); err != nil {
+		m.log.Printf("[ERROR] Cannot get Hosts with outdated metadata: %s\n",
+			err.Error())
+		return 0, err
+	}
+
+	for i := range hosts {
+		if err = m.UpdateHost(ctx, db, &hosts[i]); errors.Is(err, errClaim) {
+			return i, err
 		}
+	}
 
-		if location == "" {
-			goto LOOKUP_OS
-		} else if err = db.HostSetLocation(&host, location); err != nil {
-			m.log.Printf("[ERROR] Cannot set Location for %s to %q: %s\n",
-				host.Address,
-				location,
-				err.Error())
+	return len(hosts), nil
+} // func (m *MetaEngine) UpdateDirty(ctx context.Context, db database.HostDB, max int) (int, error)
+
+// parseBanners runs the replies of the Ports through the banner parser again
+// and updates the Software of those Ports where the result changed, e.g.
+// because the patterns were improved since the Port was scanned.
+func (m *MetaEngine) parseBanners(ctx context.Context, db database.HostDB, ports []data.Port) error {
+	var err error
+
+	for i := range ports {
+		var (
+			sw data.Software
+			ok bool
+		)
+
+		if ports[i].Reply != nil {
+			sw, ok = m.banners.Parse(ports[i].Port, *ports[i].Reply)
 		}
 
-	LOOKUP_OS:
-		if hwp.Ports, err = db.PortGetByHost(host.ID); err != nil {
-			m.log.Printf("[ERROR] Failed to get scanned ports for %s: %s\n",
-				host.Address,
-				err.Error())
+		// Ports imported from other scanners may come with Software
+		// their service detection found, which we keep unless we can
+		// tell at least as much from the reply.
+		if sw == ports[i].Software {
+			continue
+		} else if !ok && !ports[i].Software.IsZero() {
 			continue
-		} else if len(hwp.Ports) == 0 {
+		} else if sw.Product == "" && ports[i].Software.Product != "" {
 			continue
+		} else if err = db.PortSetSoftware(ctx, &ports[i], sw); err != nil {
+			m.log.Printf("[ERROR] Failed to set Software of Port #%d: %s\n",
+				ports[i].ID,
+				err.Error())
+			return err
 		}
+	}
 
-		os = m.LookupOperatingSystem(&hwp)
+	return nil
+} // func (m *MetaEngine) parseBanners(ctx context.Context, db database.HostDB, ports []data.Port) error
 
-		if err = db.HostSetOS(&host, os); err != nil {
-			m.log.Printf("[ERROR] Failed to set OS on host %s to %s: %s\n",
-				host.Address,
-				os,
-				err.Error())
-		}
+// UpdateHost refreshes the location, AS and OS metadata for a single Host
+// and records when it did so.
+// Failing to look up the location is not an error, as many addresses are
+// simply not in the GeoIP database.
+func (m *MetaEngine) UpdateHost(ctx context.Context, db database.HostDB, host *data.Host) error {
+	var (
+		err      error
+		location string
+		geo      data.GeoInfo
+		guess    data.OSGuess
+		as       data.ASInfo
+		hwp      = data.HostWithPorts{Host: *host}
+	)
+
+	// The timestamp is set before the update, so a Port that is scanned
+	// while we are busy flags the Host as outdated again.
+	if err = db.HostSetMetaUpdated(ctx, host.ID, time.Now()); err != nil {
+		m.log.Printf("[ERROR] Cannot set metadata timestamp of %s: %s\n",
+			host.Address,
+			err.Error())
+		return fmt.Errorf("%w: %s", errClaim, err.Error())
+	}
+
+	if as, err = m.LookupAS(ctx, db, host); err != nil {
+		m.log.Printf("[ERROR] Cannot lookup AS for %s: %s\n",
+			host.Address,
+			err.Error())
+		return err
+	} else if err = db.HostSetAS(ctx, host, &as); err != nil {
+		return err
+	}
+
+	if location, geo, err = m.LookupLocation(host); err != nil {
+		m.log.Printf("[ERROR] Cannot lookup location for %s: %s\n",
+			host.Address,
+			err.Error())
+	} else if location == "" {
+		goto LOOKUP_OS
+	} else if err = db.HostSetGeo(ctx, host, location, &geo); err != nil {
+		m.log.Printf("[ERROR] Cannot set Location for %s to %q: %s\n",
+			host.Address,
+			location,
+			err.Error())
+		return err
+	}
+
+LOOKUP_OS:
+	if hwp.Ports, err = db.PortGetByHost(ctx, host.ID); err != nil {
+		m.log.Printf("[ERROR] Failed to get scanned ports for %s: %s\n",
+			host.Address,
+			err.Error())
+		return err
+	} else if len(hwp.Ports) == 0 {
+		return nil
+	} else if err = m.parseBanners(ctx, db, hwp.Ports); err != nil {
+		return err
+	} else if _, err = m.vulns.MatchPorts(ctx, db, hwp.Ports); err != nil {
+		m.log.Printf("[ERROR] Failed to match vulnerabilities for host %s: %s\n",
+			host.Address,
+			err.Error())
+		return err
+	}
+
+	guess = m.LookupOperatingSystem(&hwp)
+
+	if err = db.HostSetOS(ctx, host, &guess); err != nil {
+		m.log.Printf("[ERROR] Failed to set OS on host %s to %s: %s\n",
+			host.Address,
+			guess.OS,
+			err.Error())
+		return err
+	}
+
+	if _, err = m.rules.Evaluate(ctx, db, host, hwp.Ports); err != nil {
+		m.log.Printf("[ERROR] Failed to evaluate Rules for host %s: %s\n",
+			host.Address,
+			err.Error())
+		return err
 	}
 
 	return nil
-} // func (m *MetaEngine) UpdateMetadata() error
+} // func (m *MetaEngine) UpdateHost(ctx context.Context, db database.HostDB, host *data.Host) error
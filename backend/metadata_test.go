@@ -2,7 +2,7 @@
 // -*- mode: go; coding: utf-8; -*-
 // Created on 20. 08. 2016 by Benjamin Walkenhorst
 // (c) 2016 Benjamin Walkenhorst
-// Time-stamp: <2022-10-31 19:04:41 krylon>
+// Time-stamp: <2026-10-19 16:51:13 krylon>
 
 package backend
 
@@ -41,14 +41,26 @@ var testLocations map[krylib.ID]hostLocation = map[krylib.ID]hostLocation{
 
 var metaEngine *MetaEngine
 
+// testGeoIPDir is where the tests look for the GeoLite2 databases.
+var testGeoIPDir = filepath.Join(os.Getenv("HOME"), "guang.d")
+
 func strPtr(s string) *string {
 	return &s
 } // func strPtr(s string) *string
 
+// requireGeoIP skips the test if the GeoIP database file is not installed.
+func requireGeoIP(t *testing.T, file string) {
+	var path = filepath.Join(testGeoIPDir, file)
+
+	if _, err := os.Stat(path); err != nil {
+		t.Skipf("GeoIP database %s is not available: %s", path, err.Error())
+	}
+} // func requireGeoIP(t *testing.T, file string)
+
 func TestOpenMeta(t *testing.T) {
 	var err error
 
-	if metaEngine, err = OpenMetaEngine(filepath.Join(os.Getenv("HOME"), "guang.d/GeoLite2-City.mmdb")); err != nil {
+	if metaEngine, err = OpenMetaEngine(testGeoIPDir); err != nil {
 		t.Fatalf("Error opening meta engine: %s", err.Error())
 	} else if metaEngine == nil {
 		t.Fatal("OpenMetaEngine() returned a nil value!")
@@ -59,6 +71,8 @@ func TestLookupCountry(t *testing.T) {
 	var err error
 	var country string
 
+	requireGeoIP(t, geoIPCountryPath)
+
 	for _, host := range testHosts {
 		loc := testLocations[host.ID]
 
@@ -76,6 +90,8 @@ func TestLookupCity(t *testing.T) {
 	var err error
 	var city string
 
+	requireGeoIP(t, geoIPCityPath)
+
 	for _, host := range testHosts {
 		loc := testLocations[host.ID]
 
@@ -113,11 +129,11 @@ func TestGuessOperatingSystem(t *testing.T) {
 
 	for _, h := range testHostsWithPorts {
 		system := metaEngine.LookupOperatingSystem(&h)
-		if system != osMap[h.Host.ID] {
+		if system.OS != osMap[h.Host.ID] {
 			t.Errorf("Unexpected Operating System for host %s: Expected %s, Result %s",
 				h.Host.Name,
 				osMap[h.Host.ID],
-				system)
+				system.OS)
 		}
 	}
 } // func TestGuessOperatingSystem(t *testing.T)
@@ -0,0 +1,121 @@
+// /home/krylon/go/src/github.com/blicero/guang/backend/metrics.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 14:41:18 krylon>
+
+package backend
+
+import (
+	"strconv"
+
+	"github.com/blicero/guang/backend/facility"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	probesAttempted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "guang",
+		Subsystem: "scanner",
+		Name:      "probes_attempted_total",
+		Help:      "Number of ports probed, by port and type of probe.",
+	}, []string{"port", "probe"})
+
+	probesSucceeded = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "guang",
+		Subsystem: "scanner",
+		Name:      "probes_succeeded_total",
+		Help:      "Number of probes that completed without an error, by port and type of probe.",
+	}, []string{"port", "probe"})
+
+	probesFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "guang",
+		Subsystem: "scanner",
+		Name:      "probes_failed_total",
+		Help:      "Number of probes that failed, by port and type of probe.",
+	}, []string{"port", "probe"})
+
+	probeDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "guang",
+		Subsystem: "scanner",
+		Name:      "probe_duration_seconds",
+		Help:      "How long probes took, by type of probe.",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 15, 30, 60},
+	}, []string{"probe"})
+)
+
+// observeProbe records the outcome of a probe.
+func observeProbe(port uint16, probe string, seconds float64, err error) {
+	var p = strconv.Itoa(int(port))
+
+	probesAttempted.WithLabelValues(p, probe).Inc()
+	probeDuration.WithLabelValues(probe).Observe(seconds)
+
+	if err != nil {
+		probesFailed.WithLabelValues(p, probe).Inc()
+	} else {
+		probesSucceeded.WithLabelValues(p, probe).Inc()
+	}
+} // func observeProbe(port uint16, probe string, seconds float64, err error)
+
+// nexusCollector reports the length of the queues between the components
+// of the backend and the number of workers in each facility.
+type nexusCollector struct {
+	nx      *Nexus
+	queues  *prometheus.Desc
+	workers *prometheus.Desc
+}
+
+func newNexusCollector(nx *Nexus) *nexusCollector {
+	return &nexusCollector{
+		nx: nx,
+		queues: prometheus.NewDesc(
+			"guang_queue_length",
+			"Number of items waiting in a queue.",
+			[]string{"queue"},
+			nil),
+		workers: prometheus.NewDesc(
+			"guang_workers",
+			"Number of active workers, by facility.",
+			[]string{"facility"},
+			nil),
+	}
+} // func newNexusCollector(nx *Nexus) *nexusCollector
+
+// Describe implements prometheus.Collector.
+func (c *nexusCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.queues
+	ch <- c.workers
+} // func (c *nexusCollector) Describe(ch chan<- *prometheus.Desc)
+
+// Collect implements prometheus.Collector.
+func (c *nexusCollector) Collect(ch chan<- prometheus.Metric) {
+	var queue = func(name string, length int) {
+		ch <- prometheus.MustNewConstMetric(c.queues,
+			prometheus.GaugeValue,
+			float64(length),
+			name)
+	}
+
+	if c.nx.generator != nil {
+		queue("HostQueue", len(c.nx.generator.HostQueue))
+	}
+
+	if c.nx.scanner != nil {
+		queue("scanQ", len(c.nx.scanner.scanQ))
+		queue("resultQ", len(c.nx.scanner.resultQ))
+		queue("hostQ", len(c.nx.scanner.hostQ))
+	}
+
+	if c.nx.xfr != nil {
+		queue("xfrQ", c.nx.xfr.QueueLength())
+	}
+
+	for _, f := range facility.All() {
+		ch <- prometheus.MustNewConstMetric(c.workers,
+			prometheus.GaugeValue,
+			float64(c.nx.WorkerCount(f)),
+			f.String())
+	}
+} // func (c *nexusCollector) Collect(ch chan<- prometheus.Metric)
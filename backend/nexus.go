@@ -2,106 +2,173 @@
 // -*- coding: utf-8; mode: go; -*-
 // Created on 12. 02. 2016 by Benjamin Walkenhorst
 // (c) 2016 Benjamin Walkenhorst
-// Time-stamp: <2024-05-30 00:05:54 krylon>
+// Time-stamp: <2026-10-19 16:52:31 krylon>
 
 package backend
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"net"
 	"sync"
+	"time"
 
 	"github.com/blicero/guang/backend/facility"
+	"github.com/blicero/guang/blacklist"
 	"github.com/blicero/guang/common"
 	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/database"
+	"github.com/blicero/guang/events"
 	"github.com/blicero/guang/generator"
+	"github.com/blicero/guang/logdomain"
+	"github.com/blicero/guang/rules"
 	"github.com/blicero/guang/xfr"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
-// FIXME Increase after debugging!!!
-// const metaInterval = time.Minute
+// ErrNotRunning is returned when a request needs a part of the backend that
+// was not started.
+var ErrNotRunning = errors.New("This part of the backend is not running")
+
+// MaxWorkers is the largest number of workers a facility may be asked to
+// run.
+const MaxWorkers = 256
+
+// metaInterval is how often the Nexus looks for Hosts with outdated
+// metadata, metaBatchSize how many of them it loads at once.
+const (
+	metaInterval  = time.Second * 15
+	metaBatchSize = 100
+)
 
 // Nexus aggregates the various pieces that comprise the backend.
 type Nexus struct {
 	generator *generator.HostGenerator
 	scanner   *Scanner
 	xfr       *xfr.Client
-	log       *log.Logger
+	bus       *events.Bus
+	log       *common.Logger
 	lock      sync.RWMutex
+	ruleLock  sync.Mutex
+	metaWake  chan struct{}
+	meta      *MetaEngine
 }
 
-// CreateNexus creates a new Nexus instance with the given components.
-func CreateNexus(gen *generator.HostGenerator, scanner *Scanner, xfr *xfr.Client) (*Nexus, error) {
+// CreateNexus creates a new Nexus instance with the given components and
+// the event bus they publish on.
+func CreateNexus(gen *generator.HostGenerator, scanner *Scanner, xfr *xfr.Client, bus *events.Bus) (*Nexus, error) {
 	var nexus *Nexus = new(Nexus)
 	var err error
 
-	if nexus.log, err = common.GetLogger("Nexus"); err != nil {
+	if nexus.log, err = common.GetLogger(logdomain.Backend); err != nil {
 		return nil, err
 	}
 
 	nexus.generator = gen
 	nexus.scanner = scanner
 	nexus.xfr = xfr
+	nexus.bus = bus
+	nexus.metaWake = make(chan struct{}, 1)
+
+	if nexus.meta, err = OpenMetaEngine(common.GeoIPDir); err != nil {
+		nexus.log.Printf("[ERROR] Cannot open MetaEngine: %s\n", err.Error())
+		return nil, err
+	}
+
+	if err = prometheus.Register(newNexusCollector(nexus)); err != nil {
+		nexus.log.Printf("[ERROR] Cannot register metrics for Nexus: %s\n",
+			err.Error())
+	}
+
+	if err = nexus.loadExclusions(); err != nil {
+		nexus.meta.Close()
+		return nil, err
+	}
+
 	return nexus, nil
-} // func CreateNexus(gen *HostGenerator, scanner *Scanner, xfr *XFRClient) (*Nexus, error)
+} // func CreateNexus(gen *HostGenerator, scanner *Scanner, xfr *XFRClient, bus *events.Bus) (*Nexus, error)
+
+// Close releases the resources held by the Nexus.
+func (nx *Nexus) Close() {
+	nx.meta.Close()
+} // func (nx *Nexus) Close()
+
+// Events returns the event bus the backend publishes on.
+func (nx *Nexus) Events() *events.Bus {
+	return nx.bus
+} // func (nx *Nexus) Events() *events.Bus
 
 // GetGeneratorCount returns the number of workers in the Generator.
 func (nx *Nexus) GetGeneratorCount() int {
-	return nx.generator.Count()
+	return nx.WorkerCount(facility.Generator)
 } // func (nx *Nexus) GetGeneratorCount() int
 
 // GetScannerCount returns the number of workers in the Scanner
 func (nx *Nexus) GetScannerCount() int {
-	var cnt int
-
-	nx.scanner.lock.Lock()
-	cnt = nx.scanner.workerCnt
-	nx.scanner.lock.Unlock()
-
-	return cnt
+	return nx.WorkerCount(facility.Scanner)
 } // func (nx *Nexus) GetScannerCount() int
 
 // GetXFRCount returns the number of XFR workers.
 func (nx *Nexus) GetXFRCount() int {
-	return nx.xfr.Count()
+	return nx.WorkerCount(facility.XFR)
 } // func (nx *Nexus) GetXFRCount() int
 
-// SpawnWorker spawns <n> new workers in the specified facility.
-func (nx *Nexus) SpawnWorker(f facility.Facility, n int) {
-	var c chan data.ControlMessage
-
-	switch f {
-	case facility.Generator:
-		c = nx.generator.RC
-	case facility.Scanner:
-		c = nx.scanner.RC
-	case facility.XFR:
-		c = nx.xfr.RC
+// controlChannel returns the channel the workers of the given facility
+// receive their control messages on. If the facility was not started, it
+// returns ErrNotRunning.
+func (nx *Nexus) controlChannel(f facility.Facility) (chan data.ControlMessage, error) {
+	switch {
+	case f == facility.Generator && nx.generator != nil:
+		return nx.generator.RC, nil
+	case f == facility.Scanner && nx.scanner != nil:
+		return nx.scanner.RC, nil
+	case f == facility.XFR && nx.xfr != nil:
+		return nx.xfr.RC, nil
+	case f == facility.Generator || f == facility.Scanner || f == facility.XFR:
+		return nil, ErrNotRunning
 	default:
-		nx.log.Printf("[ERROR] Don't know how to spawn more workers for %s\n",
-			f)
-		return
+		return nil, fmt.Errorf("Don't know how to control workers for %s", f)
+	}
+} // func (nx *Nexus) controlChannel(f facility.Facility) (chan data.ControlMessage, error)
+
+// SpawnWorker spawns <n> new workers in the specified facility. It returns
+// ErrNotRunning if the facility was not started. Sending the requests may
+// block until the facility gets around to handling them, so callers should
+// keep n reasonable, see MaxWorkers.
+func (nx *Nexus) SpawnWorker(f facility.Facility, n int) error {
+	var (
+		err error
+		c   chan data.ControlMessage
+	)
+
+	if c, err = nx.controlChannel(f); err != nil {
+		nx.log.Printf("[ERROR] Cannot spawn more workers for %s: %s\n",
+			f,
+			err.Error())
+		return err
 	}
 
 	for i := 0; i < n; i++ {
 		c <- data.CtlMsgSpawn
 	}
-} // func (nx *Nexus) SpawnWorker(f facility.Facility, n int)
 
-// StopWorker stops <n> workers in the specified facility.
-func (nx *Nexus) StopWorker(f facility.Facility, n int) {
-	var c chan data.ControlMessage
+	return nil
+} // func (nx *Nexus) SpawnWorker(f facility.Facility, n int) error
 
-	switch f {
-	case facility.Generator:
-		c = nx.generator.RC
-	case facility.Scanner:
-		c = nx.scanner.RC
-	case facility.XFR:
-		c = nx.xfr.RC
-	default:
-		nx.log.Printf("[ERROR] Don't know how to stop workers for %s\n",
-			f)
-		return
+// StopWorker stops <n> workers in the specified facility. It returns
+// ErrNotRunning if the facility was not started.
+func (nx *Nexus) StopWorker(f facility.Facility, n int) error {
+	var (
+		err error
+		c   chan data.ControlMessage
+	)
+
+	if c, err = nx.controlChannel(f); err != nil {
+		nx.log.Printf("[ERROR] Cannot stop workers for %s: %s\n",
+			f,
+			err.Error())
+		return err
 	}
 
 	nx.log.Printf("[INFO] Stopping %d %s workers\n",
@@ -115,45 +182,336 @@ func (nx *Nexus) StopWorker(f facility.Facility, n int) {
 	nx.log.Printf("[INFO] Sent %d stop messages to %s\n",
 		n,
 		f)
-} // func (nx *Nexus) StopWorker(f facility.Facility, n int)
+
+	return nil
+} // func (nx *Nexus) StopWorker(f facility.Facility, n int) error
+
+// Running returns true if the given facility was started.
+func (nx *Nexus) Running(f facility.Facility) bool {
+	var _, err = nx.controlChannel(f)
+
+	return err == nil
+} // func (nx *Nexus) Running(f facility.Facility) bool
 
 // WorkerCount returns the number of active workers in the given facility.
+// Facilities that were not started have no workers.
 func (nx *Nexus) WorkerCount(f facility.Facility) int {
-	switch f {
-	case facility.Generator:
+	switch {
+	case f == facility.Generator && nx.generator != nil:
 		return nx.generator.Count()
-	case facility.Scanner:
+	case f == facility.Scanner && nx.scanner != nil:
 		return nx.scanner.Count()
-	case facility.XFR:
+	case f == facility.XFR && nx.xfr != nil:
 		return nx.xfr.Count()
 	default:
 		return 0
 	}
-} // func (nx *Nexus) WorkerCount(f facility.Facility) int64
+} // func (nx *Nexus) WorkerCount(f facility.Facility) int
 
-// UpdateMetadata triggers an update of the metadata for all hosts.
+// Blacklists returns snapshots of the name and address blacklists used by
+// the given facility. Facilities that do not use any blacklists return
+// nil for both.
+func (nx *Nexus) Blacklists(f facility.Facility) (names, addrs []blacklist.Entry) {
+	var (
+		nameBL *blacklist.NameBlacklist
+		addrBL *blacklist.IPBlacklist
+	)
+
+	switch {
+	case f == facility.Generator && nx.generator != nil:
+		nameBL, addrBL = nx.generator.Blacklists()
+	case f == facility.XFR && nx.xfr != nil:
+		nameBL, addrBL = nx.xfr.Blacklists()
+	default:
+		return nil, nil
+	}
+
+	return nameBL.Entries(), addrBL.Entries()
+} // func (nx *Nexus) Blacklists(f facility.Facility) (names, addrs []blacklist.Entry)
+
+// UpdateMetadata flags the metadata of all hosts as outdated and wakes up
+// the metadata worker to update them.
 func (nx *Nexus) UpdateMetadata() {
 	var (
-		err  error
-		meta *MetaEngine
+		err error
+		cnt int64
+		db  database.HostDB
+	)
+
+	if db, err = database.OpenDB(common.DbPath); err != nil {
+		nx.log.Printf("[ERROR] Cannot open HostDB at %s: %s\n",
+			common.DbPath,
+			err.Error())
+		return
+	}
+
+	defer db.Close()
+
+	if cnt, err = db.HostMarkAllDirty(context.Background()); err != nil {
+		nx.log.Printf("[ERROR] Cannot flag metadata as outdated: %s\n", err.Error())
+		return
+	}
+
+	nx.log.Printf("[INFO] Updating OS and location for %d hosts.\n", cnt)
+
+	select {
+	case nx.metaWake <- struct{}{}:
+	default:
+	}
+} // func (nx *Nexus) UpdateMetadata()
+
+// HostsImported hands Hosts that were added by importing the results of
+// another scanner to the rest of the pipeline, the same way the generator
+// does with the Hosts it finds: They are announced on the event bus, their
+// names are queued for zone transfers, and the metadata worker is woken up
+// to look at them.
+func (nx *Nexus) HostsImported(hosts []data.Host) {
+	var names = make([]string, 0, len(hosts))
+
+	for i := range hosts {
+		var h = hosts[i]
+
+		nx.bus.Publish(events.Event{
+			Kind: events.HostAdded,
+			Host: &h,
+		})
+
+		if h.Name != "" {
+			names = append(names, h.Name)
+		}
+	}
+
+	if nx.xfr != nil && len(names) > 0 {
+		// The queue is drained by the XFR workers, which may take a
+		// while, and we do not want to keep the caller waiting.
+		go func() {
+			for _, name := range names {
+				nx.xfr.Enqueue(name)
+			}
+		}()
+	}
+
+	select {
+	case nx.metaWake <- struct{}{}:
+	default:
+	}
+} // func (nx *Nexus) HostsImported(hosts []data.Host)
+
+// MetaLoop updates the metadata of Hosts that were added or had Ports
+// scanned since their metadata were last updated, and reloads the GeoIP
+// databases when newer versions are downloaded. It runs until the process
+// exits, so it should be started in its own goroutine.
+func (nx *Nexus) MetaLoop() {
+	var (
+		err   error
+		db    database.HostDB
+		pulse = time.NewTicker(metaInterval)
+	)
+
+	defer pulse.Stop()
+
+	if db, err = database.OpenDB(common.DbPath); err != nil {
+		nx.log.Printf("[ERROR] Cannot open HostDB at %s: %s\n",
+			common.DbPath,
+			err.Error())
+		return
+	}
+
+	defer db.Close()
+
+	for {
+		select {
+		case <-pulse.C:
+		case <-nx.metaWake:
+		}
+
+		nx.meta.Reload()
+		nx.updateDirty(db)
+	}
+} // func (nx *Nexus) MetaLoop()
+
+// updateDirty processes Hosts with outdated metadata in batches until none
+// are left.
+func (nx *Nexus) updateDirty(db database.HostDB) {
+	var (
+		err   error
+		n     int
+		total int
+		ctx   = context.Background()
 	)
 
 	if !nx.lock.TryLock() {
-		nx.log.Println("[INFO] Metadata update is already running.")
 		return
 	}
 	defer nx.lock.Unlock()
 
-	nx.log.Printf("[INFO] Updating OS and location for hosts.\n")
+	for {
+		if n, err = nx.meta.UpdateDirty(ctx, db, metaBatchSize); err != nil {
+			nx.log.Printf("[ERROR] Failed to update metadata: %s\n", err.Error())
+			break
+		}
+
+		total += n
+
+		if n < metaBatchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		nx.log.Printf("[INFO] Updated metadata of %d hosts.\n", total)
+	}
+} // func (nx *Nexus) updateDirty(db database.HostDB)
+
+// EvaluateRules matches the Rules against all Hosts in the database.
+func (nx *Nexus) EvaluateRules() {
+	var (
+		err error
+		db  database.HostDB
+		eng *rules.Engine
+	)
+
+	if !nx.ruleLock.TryLock() {
+		nx.log.Println("[INFO] Rules are already being evaluated.")
+		return
+	}
+	defer nx.ruleLock.Unlock()
 
-	if meta, err = OpenMetaEngine("bla"); err != nil {
-		nx.log.Printf("[ERROR] Cannot open MetaEngine: %s\n", err.Error())
+	if eng, err = rules.NewEngine(); err != nil {
+		nx.log.Printf("[ERROR] Cannot create Rule Engine: %s\n", err.Error())
+		return
+	} else if db, err = database.OpenDB(common.DbPath); err != nil {
+		nx.log.Printf("[ERROR] Cannot open HostDB at %s: %s\n",
+			common.DbPath,
+			err.Error())
 		return
 	}
 
-	defer meta.Close()
+	defer db.Close()
 
-	if err = meta.UpdateMetadata(); err != nil {
-		nx.log.Printf("[ERROR] Failed to update metadata: %s\n", err.Error())
+	if _, err = eng.EvaluateAll(context.Background(), db); err != nil {
+		nx.log.Printf("[ERROR] Failed to evaluate Rules: %s\n", err.Error())
 	}
-} // func (nx *Nexus) UpdateMetadata()
+} // func (nx *Nexus) EvaluateRules()
+
+// UpdateHostMetadata refreshes the location and OS metadata of a single
+// Host.
+func (nx *Nexus) UpdateHostMetadata(ctx context.Context, db database.HostDB, host *data.Host) error {
+	return nx.meta.UpdateHost(ctx, db, host)
+} // func (nx *Nexus) UpdateHostMetadata(ctx context.Context, db database.HostDB, host *data.Host) error
+
+// Rescan has the Scanner scan the Host again right away.
+func (nx *Nexus) Rescan(host data.Host) error {
+	if nx.scanner == nil {
+		return ErrNotRunning
+	}
+
+	nx.scanner.Rescan(host)
+	return nil
+} // func (nx *Nexus) Rescan(host data.Host) error
+
+// Exclude stores the Exclusion in the database and makes the Generator, the
+// XFR client and the Scanner leave the network alone from now on. For an
+// autonomous system, that means all the networks the GeoLite2-ASN database
+// says it announces.
+func (nx *Nexus) Exclude(ctx context.Context, db database.HostDB, ex *data.Exclusion) error {
+	var (
+		err  error
+		nets []*net.IPNet
+	)
+
+	if nets, err = nx.exclusionNetworks(ex); err != nil {
+		nx.log.Printf("[ERROR] Cannot find networks of %s: %s\n",
+			ex.Target(),
+			err.Error())
+		return err
+	} else if err = db.ExclusionAdd(ctx, ex); err != nil {
+		nx.log.Printf("[ERROR] Cannot add Exclusion of %s: %s\n",
+			ex.Target(),
+			err.Error())
+		return err
+	}
+
+	nx.log.Printf("[INFO] Excluding %s (%d networks): %s\n",
+		ex.Target(),
+		len(nets),
+		ex.Comment)
+
+	for _, n := range nets {
+		nx.applyExclusion(n)
+	}
+
+	return nil
+} // func (nx *Nexus) Exclude(ctx context.Context, db database.HostDB, ex *data.Exclusion) error
+
+// exclusionNetworks returns the networks an Exclusion covers.
+func (nx *Nexus) exclusionNetworks(ex *data.Exclusion) ([]*net.IPNet, error) {
+	if ex.ASN == 0 {
+		return []*net.IPNet{ex.Network}, nil
+	}
+
+	return nx.meta.ASNetworks(ex.ASN)
+} // func (nx *Nexus) exclusionNetworks(ex *data.Exclusion) ([]*net.IPNet, error)
+
+// applyExclusion adds the network to the address blacklists of all the
+// parts of the backend that are running.
+func (nx *Nexus) applyExclusion(network *net.IPNet) {
+	if nx.generator != nil {
+		var _, addrBL = nx.generator.Blacklists()
+		addrBL.Add(network)
+	}
+
+	if nx.xfr != nil {
+		var _, addrBL = nx.xfr.Blacklists()
+		addrBL.Add(network)
+	}
+
+	if nx.scanner != nil {
+		nx.scanner.Exclude(network)
+	}
+} // func (nx *Nexus) applyExclusion(network *net.IPNet)
+
+// loadExclusions applies the Exclusions stored in the database.
+func (nx *Nexus) loadExclusions() error {
+	var (
+		err  error
+		db   database.HostDB
+		list []data.Exclusion
+	)
+
+	if db, err = database.OpenDB(common.DbPath); err != nil {
+		nx.log.Printf("[ERROR] Cannot open HostDB at %s: %s\n",
+			common.DbPath,
+			err.Error())
+		return err
+	}
+
+	defer db.Close()
+
+	if list, err = db.ExclusionGetAll(context.Background()); err != nil {
+		return err
+	}
+
+	for i := range list {
+		var nets []*net.IPNet
+
+		// Without the AS database, we cannot apply the Exclusions of
+		// autonomous systems, but that should not keep us from running.
+		if nets, err = nx.exclusionNetworks(&list[i]); err != nil {
+			nx.log.Printf("[ERROR] Cannot apply Exclusion of %s: %s\n",
+				list[i].Target(),
+				err.Error())
+			continue
+		}
+
+		for _, n := range nets {
+			nx.applyExclusion(n)
+		}
+	}
+
+	if len(list) > 0 {
+		nx.log.Printf("[INFO] Loaded %d exclusions\n", len(list))
+	}
+
+	return nil
+} // func (nx *Nexus) loadExclusions() error
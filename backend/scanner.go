@@ -2,7 +2,7 @@
 // -*- coding: utf-8; mode: go; -*-
 // Created on 28. 12. 2015 by Benjamin Walkenhorst
 // (c) 2015 Benjamin Walkenhorst
-// Time-stamp: <2024-05-30 00:07:46 krylon>
+// Time-stamp: <2026-10-19 17:37:52 krylon>
 //
 // Freitag, 08. 01. 2016, 22:10
 // I kinda feel like I'm not going to write a comprehensive test suite for this
@@ -12,22 +12,28 @@ package backend
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
-	"log"
 	"math/rand"
 	"net"
 	"net/http"
 	"regexp"
+	"strconv"
 	"sync"
 	"time"
 	"unicode"
 	"unicode/utf8"
 
 	"github.com/alouca/gosnmp"
+	"github.com/blicero/guang/banner"
+	"github.com/blicero/guang/blacklist"
 	"github.com/blicero/guang/common"
 	"github.com/blicero/guang/data"
 	"github.com/blicero/guang/database"
+	"github.com/blicero/guang/events"
+	"github.com/blicero/guang/logdomain"
+	"github.com/blicero/guang/rules"
 	"github.com/miekg/dns"
 )
 
@@ -113,21 +119,27 @@ func getScanPort(host *data.Host, ports map[uint16]bool) uint16 {
 
 // Scanner is a port scanner. Kind of.
 type Scanner struct {
-	db        *database.HostDB
+	db        database.HostDB
+	batch     *database.Batcher
+	rules     *rules.Engine
+	banners   *banner.DB
 	scanQ     chan data.ScanRequest
 	resultQ   chan data.ScanResult
 	RC        chan data.ControlMessage
 	hostQ     chan data.HostWithPorts
 	mmQ       chan data.ControlMessage
-	log       *log.Logger
+	bus       *events.Bus
+	excluded  *blacklist.IPBlacklist
+	log       *common.Logger
 	workerCnt int
 	started   int
 	lock      sync.RWMutex
 	running   bool
 }
 
-// CreateScanner creates a new Scanner.
-func CreateScanner(workerCnt int) (*Scanner, error) {
+// CreateScanner creates a new Scanner. The Scanner publishes its results
+// on bus, which may be nil.
+func CreateScanner(workerCnt int, bus *events.Bus) (*Scanner, error) {
 	var err error
 	var scanner *Scanner
 	var msg string
@@ -138,23 +150,51 @@ func CreateScanner(workerCnt int) (*Scanner, error) {
 		hostQ:     make(chan data.HostWithPorts, workerCnt),
 		mmQ:       make(chan data.ControlMessage, workerCnt),
 		RC:        make(chan data.ControlMessage, 2),
+		bus:       bus,
+		excluded:  new(blacklist.IPBlacklist),
 		workerCnt: workerCnt,
 	}
 
-	if scanner.log, err = common.GetLogger("Scanner"); err != nil {
+	if scanner.log, err = common.GetLogger(logdomain.Scanner); err != nil {
 		msg = fmt.Sprintf("Error getting Logger instance for scanner: %s", err.Error())
 		return nil, errors.New(msg)
 	} else if scanner.db, err = database.OpenDB(common.DbPath); err != nil {
-		msg = fmt.Sprintf("Error opening database at %s: %s",
-			common.DbPath, err.Error())
-		scanner.log.Println(msg)
-		return nil, errors.New(msg)
+		scanner.log.Error("Cannot open database", "path", common.DbPath, "err", err)
+		return nil, fmt.Errorf("Error opening database at %s: %w", common.DbPath, err)
+	} else if scanner.batch, err = database.NewBatcher(
+		scanner.db,
+		database.DefaultBatchSize,
+		database.DefaultBatchInterval,
+		nil); err != nil {
+		scanner.log.Error("Cannot create Batcher for scan results", "err", err)
+		scanner.db.Close()
+		return nil, fmt.Errorf("Error creating Batcher for scan results: %w", err)
+	} else if scanner.rules, err = rules.NewEngine(); err != nil {
+		scanner.log.Error("Cannot create Rule Engine", "err", err)
+		scanner.db.Close()
+		return nil, fmt.Errorf("Error creating Rule Engine: %w", err)
+	} else if scanner.banners, err = banner.Open(common.ServiceProbesPath); err != nil {
+		scanner.log.Error("Cannot load patterns for the banner parser",
+			"path", common.ServiceProbesPath,
+			"err", err)
+		scanner.db.Close()
+		return nil, fmt.Errorf("Error loading patterns for the banner parser: %w", err)
 	} else if common.Debug {
-		scanner.log.Printf("[DEBUG] Created new Scanner, will use %d workers, ready to go.\n", workerCnt)
+		scanner.log.Debug("Created new Scanner, ready to go", "workers", workerCnt)
+	}
+
+	// The Rule Engine logs its own errors, there is nothing else we
+	// could do about them.
+	scanner.batch.OnResult(func(db database.HostDB, res *data.ScanResult) {
+		scanner.rules.EvaluateResult(context.Background(), db, res) // nolint: errcheck
+	})
+
+	if err = scanner.batch.Register("results"); err != nil {
+		scanner.log.Warn("Cannot register metrics for Batcher", "err", err)
 	}
 
 	return scanner, nil
-} // func CreateScanner(worker_cnt int) (*Scanner, error)
+} // func CreateScanner(workerCnt int, bus *events.Bus) (*Scanner, error)
 
 // Start starts the Scanner. If it is already running, this method does nothing.
 func (sc *Scanner) Start() {
@@ -168,9 +208,10 @@ func (sc *Scanner) Start() {
 	defer sc.lock.Unlock()
 
 	if common.Debug {
-		sc.log.Printf("Scanner starting Host feeder and %d workers.\n", sc.workerCnt)
+		sc.log.Debug("Scanner starting Host feeder", "workers", sc.workerCnt)
 	}
 
+	sc.batch.Start()
 	go sc.hostFeeder()
 
 	for i := 1; i <= sc.workerCnt; i++ {
@@ -221,37 +262,55 @@ func (sc *Scanner) PrintStatus() {
 
 } // func (sc *Scanner) PrintStatus()
 
+// Exclude makes the Scanner leave the Hosts in the given network alone.
+func (sc *Scanner) Exclude(network *net.IPNet) {
+	sc.excluded.Add(network)
+} // func (sc *Scanner) Exclude(network *net.IPNet)
+
+// Rescan scans all the interesting Ports on the given Host again, right
+// away rather than whenever the Host's turn would come. The results replace
+// the ones from earlier scans.
+// The scans are performed by the regular workers, so Rescan returns
+// immediately.
+func (sc *Scanner) Rescan(host data.Host) {
+	sc.log.Info("Rescanning host", "address", host.Address, "host", host.Name)
+
+	go func() {
+		for _, port := range Ports {
+			sc.scanQ <- data.ScanRequest{Host: host, Port: port}
+		}
+	}()
+} // func (sc *Scanner) Rescan(host data.Host)
+
 // Loop is the Scanner's main loop.
 func (sc *Scanner) Loop() {
 	var (
-		err error
 		req data.ScanRequest
 		res data.ScanResult
-		msg string
 		ctl data.ControlMessage
 	)
 
 	req = sc.getRandomScanRequest()
 
 	if common.Debug {
-		sc.log.Println("Scanner Loop() starting up...")
+		sc.log.Debug("Scanner Loop() starting up")
 	}
 
 	for sc.IsRunning() {
 		select {
 		case ctl = <-sc.RC:
-			sc.log.Printf("[DEBUG] Got one control message: %s\n",
-				ctl)
+			sc.log.Debug("Got one control message", "command", ctl)
 
 			switch ctl {
 			case data.CtlMsgShutdown:
 				sc.Stop()
+				sc.batch.Stop()
 				return
 			case data.CtlMsgStop:
-				sc.log.Printf("[DEBUG] Telling one worker to stop.\n")
+				sc.log.Debug("Telling one worker to stop")
 				sc.mmQ <- data.CtlMsgStop
 			case data.CtlMsgSpawn:
-				sc.log.Printf("[DEBUG] Spawning one additional worker\n")
+				sc.log.Debug("Spawning one additional worker")
 				go sc.worker(sc.Count())
 			case data.CtlMsgStatus:
 				sc.PrintStatus()
@@ -259,7 +318,7 @@ func (sc *Scanner) Loop() {
 
 		case sc.scanQ <- req:
 			if common.Debug {
-				sc.log.Println("Scanner Loop dispatched one ScanRequest, getting another one.")
+				sc.log.Trace("Scanner Loop dispatched one ScanRequest, getting another one")
 			}
 			req = sc.getRandomScanRequest()
 
@@ -272,15 +331,26 @@ func (sc *Scanner) Loop() {
 				} else {
 					reply = *res.Reply
 				}
-				msg = fmt.Sprintf("Got ScanResult: %s:%d - %s",
-					res.Host.Name, res.Port, reply)
-				sc.log.Println(msg)
+				sc.log.Debug("Got ScanResult",
+					"host", res.Host.Name,
+					"port", res.Port,
+					"reply", reply)
 			}
 
-			if err = sc.db.PortAdd(&res); err != nil {
-				msg = fmt.Sprintf("Error adding Port to DB: %s", err.Error())
-				sc.log.Println(msg)
+			if res.Reply != nil {
+				res.Software, _ = sc.banners.Parse(res.Port, *res.Reply)
 			}
+
+			// res is overwritten by the next result, while subscribers
+			// may still hold on to the Event, so they get a copy.
+			var snapshot = res
+
+			sc.batch.AddResult(res)
+			sc.bus.Publish(events.Event{
+				Kind:   events.ScanResult,
+				Stamp:  snapshot.Stamp,
+				Result: &snapshot,
+			})
 		}
 	}
 
@@ -288,42 +358,45 @@ func (sc *Scanner) Loop() {
 
 func (sc *Scanner) hostFeeder() {
 	var hosts []data.Host
-	var db *database.HostDB
+	var db database.HostDB
 	var err error
-	var msg string
+	var ctx = context.Background()
 
 	if db, err = database.OpenDB(common.DbPath); err != nil {
-		msg = fmt.Sprintf("Error opening DB at %s for hostFeeder: %s",
-			common.DbPath, err.Error())
-		sc.log.Println(msg)
+		sc.log.Error("Cannot open database for hostFeeder", "path", common.DbPath, "err", err)
 		return
 	}
 
 	defer db.Close()
 
 	if common.Debug {
-		sc.log.Println("hostFeeder() starting up...")
+		sc.log.Debug("hostFeeder() starting up")
 	}
 
 	for sc.IsRunning() {
-		if hosts, err = db.HostGetRandom(sc.workerCnt); err != nil {
-			msg = fmt.Sprintf("Error getting (up to) %d random hosts: %s",
-				sc.workerCnt, err.Error())
-			sc.log.Println(msg)
+		if hosts, err = db.HostGetRandom(ctx, sc.workerCnt); err != nil {
+			sc.log.Error("Cannot get random hosts", "count", sc.workerCnt, "err", err)
 		} else {
 			if common.Debug {
-				sc.log.Printf("hostFeeder retrieved %d hosts from the database.\n",
-					len(hosts))
+				sc.log.Debug("hostFeeder retrieved hosts from the database", "count", len(hosts))
 			}
 
 			for _, host := range hosts {
 				var ports []data.Port
 				var phost *data.Host = new(data.Host)
 
-				if ports, err = db.PortGetByHost(host.ID); err != nil {
-					msg = fmt.Sprintf("Error getting ports for host %s/%s: %s",
-						host.Name, host.Address, err.Error())
-					sc.log.Println(msg)
+				if sc.excluded.MatchesIP(host.Address) {
+					if common.Debug {
+						sc.log.Trace("Skipping excluded host",
+							"address", host.Address,
+							"host", host.Name)
+					}
+					continue
+				} else if ports, err = db.PortGetByHost(ctx, host.ID); err != nil {
+					sc.log.Error("Cannot get ports for host",
+						"host", host.Name,
+						"address", host.Address,
+						"err", err)
 				} else {
 					*phost = host
 					hostWithPorts := data.HostWithPorts{
@@ -332,8 +405,9 @@ func (sc *Scanner) hostFeeder() {
 					}
 
 					if common.Debug {
-						sc.log.Printf("Enqueueing host %s/%s as a scan target.\n",
-							host.Address.String(), host.Name)
+						sc.log.Trace("Enqueueing host as a scan target",
+							"address", host.Address,
+							"host", host.Name)
 					}
 
 					sc.hostQ <- hostWithPorts
@@ -349,15 +423,14 @@ func (sc *Scanner) getRandomScanRequest() data.ScanRequest {
 	var hwp data.HostWithPorts
 
 	if common.Debug {
-		sc.log.Println("Getting one random scan request from the host queue...")
+		sc.log.Trace("Getting one random scan request from the host queue")
 	}
 
 GET_HOST:
 	hwp = <-sc.hostQ
 
 	if common.Debug {
-		sc.log.Printf("\t...got one random scan request from the host queue: %s\n",
-			hwp.Host.Name)
+		sc.log.Trace("Got one random scan request from the host queue", "host", hwp.Host.Name)
 	}
 
 	for _, port := range hwp.Ports {
@@ -371,8 +444,7 @@ GET_HOST:
 	if req.Port == 0 {
 		goto GET_HOST
 	} else if common.Debug {
-		sc.log.Printf("Returning Request to scan %s:%d\n",
-			req.Host.Name, req.Port)
+		sc.log.Trace("Returning Request to scan", "host", req.Host.Name, "port", req.Port)
 	}
 
 	return req
@@ -394,8 +466,7 @@ func (sc *Scanner) worker(id int) {
 	defer sc.cntDec()
 
 	if common.Debug {
-		sc.log.Printf("[DEBUG] Scanner worker %d starting up...\n",
-			id)
+		sc.log.Debug("Scanner worker starting up", "worker", id)
 	}
 
 	for sc.IsRunning() {
@@ -403,26 +474,27 @@ func (sc *Scanner) worker(id int) {
 		case msg = <-sc.mmQ:
 			switch msg {
 			case data.CtlMsgStop:
-				sc.log.Printf("[INFO] Worker #%d is quitting as ordered.\n",
-					id)
+				sc.log.Info("Scanner worker is quitting as ordered", "worker", id)
 				return
 			default:
-				sc.log.Printf("[DEBUG] Scanner Worker #%d ignoring message %s\n",
-					id,
-					msg)
+				sc.log.Debug("Scanner worker ignoring message", "worker", id, "command", msg)
 			}
 		case request = <-sc.scanQ:
 			if result, err = scanHost(&request.Host, request.Port); err != nil {
-				msg := fmt.Sprintf("Error scanning %s:%d -- %s",
-					request.Host.Name,
-					request.Port,
-					err.Error())
-				sc.log.Println(msg)
+				// Most Ports we try are closed, so a failed scan is
+				// nothing to warn about.
+				sc.log.Debug("Error scanning host",
+					"host", request.Host.Name,
+					"port", request.Port,
+					"err", err)
 				result = new(data.ScanResult)
 				result.Host = request.Host
 				result.Port = request.Port
 				result.Reply = nil
-				result.Err = errors.New(msg)
+				result.Err = fmt.Errorf("Error scanning %s:%d -- %w",
+					request.Host.Name,
+					request.Port,
+					err)
 
 				sc.resultQ <- *result
 			} else {
@@ -433,10 +505,10 @@ func (sc *Scanner) worker(id int) {
 					} else {
 						reply = *result.Reply
 					}
-					sc.log.Printf("Successfully scanned %s:%d - %s\n",
-						request.Host.Name,
-						request.Port,
-						reply)
+					sc.log.Debug("Successfully scanned host",
+						"host", request.Host.Name,
+						"port", request.Port,
+						"reply", reply)
 				}
 
 				sc.resultQ <- *result
@@ -447,30 +519,57 @@ func (sc *Scanner) worker(id int) {
 	}
 } // func (sc *Scanner) worker(id int)
 
-func scanHost(host *data.Host, port uint16) (*data.ScanResult, error) {
+// probe is a way to ask a port what is behind it.
+type probe struct {
+	name string
+	scan func(*data.Host, uint16) (*data.ScanResult, error)
+}
+
+var (
+	probeTelnet = probe{"telnet", scanTelnet}
+	probePlain  = probe{"plain", scanPlain}
+	probeDNS    = probe{"dns", scanDNS}
+	probeFinger = probe{"finger", scanFinger}
+	probeHTTP   = probe{"http", scanHTTP}
+	probeSNMP   = probe{"snmp", scanSNMP}
+)
+
+// probeFor returns the probe to use for the given port.
+func probeFor(port uint16) probe {
 	switch port {
 	case 23:
-		return scanTelnet(host, port)
+		return probeTelnet
 	case 21, 22, 25, 110, 2525:
-		return scanPlain(host, port)
+		return probePlain
 	case 53, 5353:
-		return scanDNS(host, port)
+		return probeDNS
 	case 79:
-		return scanFinger(host, port)
+		return probeFinger
 	case 80, 443, 8000, 8080, 8081, 3128, 3689, 631, 1024, 4444, 5800:
-		return scanHTTP(host, port)
+		return probeHTTP
 	case 161:
-		return scanSNMP(host, port)
+		return probeSNMP
 	default:
-		return scanPlain(host, port)
+		return probePlain
 	}
+} // func probeFor(port uint16) probe
+
+func scanHost(host *data.Host, port uint16) (*data.ScanResult, error) {
+	var (
+		p     = probeFor(port)
+		start = time.Now()
+	)
+
+	res, err := p.scan(host, port)
+	observeProbe(port, p.name, time.Since(start).Seconds(), err)
+	return res, err
 } // func scanHost(host *Host, port uint16) (*ScanResult, error)
 
 func scanPlain(host *data.Host, port uint16) (*data.ScanResult, error) {
 	if common.Debug {
 		fmt.Printf("Scanning %s:%d using plain scanner.\n", host.Address.String(), port)
 	}
-	srv := fmt.Sprintf("%s:%d", host.Address, port)
+	srv := net.JoinHostPort(host.Address.String(), strconv.Itoa(int(port)))
 	conn, err := net.Dial("tcp", srv)
 	if err != nil {
 		msg := fmt.Sprintf("Error connecting to %s: %s", srv, err.Error())
@@ -509,7 +608,7 @@ func scanFinger(host *data.Host, port uint16) (*data.ScanResult, error) {
 			host.Name, port)
 	}
 
-	srv := fmt.Sprintf("%s:%d", host.Address, port)
+	srv := net.JoinHostPort(host.Address.String(), strconv.Itoa(int(port)))
 	conn, err := net.Dial("tcp", srv)
 	if err != nil {
 		msg := fmt.Sprintf("Error connecting to %s: %s", srv, err.Error())
@@ -697,7 +796,7 @@ func scanTelnet(host *data.Host, port uint16) (*data.ScanResult, error) {
 		0xff, 0xfb, 0x22, // Will Linemode
 	}
 
-	target := fmt.Sprintf("%s:%d", host.Address, port)
+	target := net.JoinHostPort(host.Address.String(), strconv.Itoa(int(port)))
 
 	conn, err := net.Dial("tcp", target)
 	if err != nil {
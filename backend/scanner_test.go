@@ -2,7 +2,7 @@
 // -*- coding: utf-8; mode: go; -*-
 // Created on 05. 02. 2016 by Benjamin Walkenhorst
 // (c) 2016 Benjamin Walkenhorst
-// Time-stamp: <2024-06-03 18:57:16 krylon>
+// Time-stamp: <2026-10-19 14:39:07 krylon>
 
 package backend
 
@@ -89,7 +89,7 @@ var scanner *Scanner
 func TestCreateScanner(t *testing.T) {
 	var err error
 
-	if scanner, err = CreateScanner(1); err != nil {
+	if scanner, err = CreateScanner(1, nil); err != nil {
 		t.Fatalf("Error creating scanner: %s\n", err.Error())
 	}
 }
@@ -0,0 +1,417 @@
+// /home/krylon/go/src/github.com/blicero/guang/banner/banner.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 15:24:51 krylon>
+
+// Package banner extracts the product, version and related information
+// from the replies we get from open Ports, using a database of patterns.
+//
+// The pattern database is a text file modelled on nmap's
+// nmap-service-probes. Blank lines and lines starting with # are ignored,
+// the remaining lines are directives:
+//
+//	Probe TCP http                    starts a new section
+//	ports 80,443,8000-8100            the ports the section is meant for
+//	match <service> m|<regex>|[is] [<field>/<template>/ ...]
+//	softmatch <service> m|<regex>|[is]
+//
+// The fields of a match are p/product/, v/version/, i/extra info/,
+// o/operating system/ and cpe:/cpe-name/. h/ and d/ are accepted for
+// compatibility with nmap, but ignored. The delimiter of the regex and
+// the fields can be any character that does not occur in them. Templates
+// may refer to the groups of the regex as $1 through $9, $P(n) for the
+// printable characters of a group only, and $SUBST(n,"old","new").
+//
+// Unlike nmap, the regular expressions are Go's (RE2) and they are matched
+// against the replies as guang stores them, which are not necessarily what
+// the server sent. The http section, for example, only ever sees the
+// Server header.
+package banner
+
+import (
+	"bufio"
+	_ "embed" // for the built-in patterns
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/blicero/guang/data"
+)
+
+//go:embed service-probes
+var builtin string
+
+// Pattern is a single match or softmatch directive.
+type Pattern struct {
+	Service string
+	Soft    bool
+	re      *regexp.Regexp
+	product string
+	version string
+	info    string
+	os      string
+	cpe     string
+}
+
+// section is a group of Patterns meant for replies from the same probe.
+type section struct {
+	name     string
+	ports    map[uint16]bool
+	patterns []*Pattern
+}
+
+// DB is a database of Patterns. It is safe for concurrent use, as it is
+// never modified after it has been loaded.
+type DB struct {
+	sections []*section
+}
+
+// Builtin returns a DB with only the patterns that are compiled into the
+// application.
+func Builtin() (*DB, error) {
+	return Load(strings.NewReader(builtin), "built-in patterns")
+} // func Builtin() (*DB, error)
+
+// Open loads the built-in patterns and, if it exists, the pattern file at
+// path. The patterns from the file are tried before the built-in ones, so
+// they can be used to override them.
+func Open(path string) (*DB, error) {
+	var (
+		err      error
+		fh       *os.File
+		db, base *DB
+	)
+
+	if base, err = Builtin(); err != nil {
+		return nil, err
+	} else if fh, err = os.Open(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return base, nil
+		}
+
+		return nil, fmt.Errorf("Cannot open pattern file %s: %w", path, err)
+	}
+
+	defer fh.Close() // nolint: errcheck
+
+	if db, err = Load(fh, path); err != nil {
+		return nil, err
+	}
+
+	db.sections = append(db.sections, base.sections...)
+	return db, nil
+} // func Open(path string) (*DB, error)
+
+// Load reads a pattern database. name is only used in error messages.
+func Load(r io.Reader, name string) (*DB, error) {
+	var (
+		err     error
+		lineNo  int
+		cur     *section
+		db      = new(DB)
+		scanner = bufio.NewScanner(r)
+	)
+
+	scanner.Buffer(make([]byte, 4096), 1<<20)
+
+	for scanner.Scan() {
+		var (
+			directive, rest string
+			line            = strings.TrimSpace(scanner.Text())
+		)
+
+		lineNo++
+
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		directive, rest, _ = strings.Cut(line, " ")
+		rest = strings.TrimSpace(rest)
+
+		switch directive {
+		case "Probe":
+			cur = &section{name: rest}
+			db.sections = append(db.sections, cur)
+		case "ports":
+			if cur == nil {
+				return nil, fmt.Errorf("%s:%d: ports outside of a Probe section",
+					name,
+					lineNo)
+			} else if cur.ports, err = parsePorts(rest); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", name, lineNo, err)
+			}
+		case "match", "softmatch":
+			var p *Pattern
+
+			if cur == nil {
+				cur = &section{name: "default"}
+				db.sections = append(db.sections, cur)
+			}
+
+			if p, err = parsePattern(rest, directive == "softmatch"); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", name, lineNo, err)
+			}
+
+			cur.patterns = append(cur.patterns, p)
+		default:
+			// nmap-service-probes has a few more directives, e.g.
+			// rarity or totalwaitms, which mean nothing to us.
+			continue
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Cannot read %s: %w", name, err)
+	}
+
+	return db, nil
+} // func Load(r io.Reader, name string) (*DB, error)
+
+// Len returns the number of Patterns in the DB.
+func (db *DB) Len() int {
+	var cnt int
+
+	for _, s := range db.sections {
+		cnt += len(s.patterns)
+	}
+
+	return cnt
+} // func (db *DB) Len() int
+
+// Parse matches the reply from the given port against the DB. The sections
+// meant for the port are tried first, then all the others. The first match
+// wins. If only a softmatch matches, the result has only the Service set.
+// The second return value is false if nothing matched at all.
+func (db *DB) Parse(port uint16, reply string) (data.Software, bool) {
+	var soft string
+
+	if reply == "" {
+		return data.Software{}, false
+	}
+
+	for _, preferred := range []bool{true, false} {
+		for _, s := range db.sections {
+			if s.ports[port] != preferred {
+				continue
+			}
+
+			for _, p := range s.patterns {
+				var groups []string
+
+				if soft != "" && p.Service != soft {
+					// Once a softmatch told us what the service is, only
+					// Patterns for that service are of interest.
+					continue
+				} else if groups = p.re.FindStringSubmatch(reply); groups == nil {
+					continue
+				} else if p.Soft {
+					soft = p.Service
+					continue
+				}
+
+				return p.software(groups), true
+			}
+		}
+	}
+
+	if soft != "" {
+		return data.Software{Service: soft}, true
+	}
+
+	return data.Software{}, false
+} // func (db *DB) Parse(port uint16, reply string) (data.Software, bool)
+
+// software fills in the templates of the Pattern. A CPE that ends in a
+// version the match did not capture is cut back to the product.
+func (p *Pattern) software(groups []string) data.Software {
+	return data.Software{
+		Service: p.Service,
+		Product: expand(p.product, groups),
+		Version: expand(p.version, groups),
+		Info:    expand(p.info, groups),
+		OS:      expand(p.os, groups),
+		CPE:     strings.TrimRight(expand(p.cpe, groups), ":"),
+	}
+} // func (p *Pattern) software(groups []string) data.Software
+
+// parsePorts parses a list of ports and port ranges, separated by commas.
+func parsePorts(s string) (map[uint16]bool, error) {
+	var ports = make(map[uint16]bool)
+
+	for _, item := range strings.Split(s, ",") {
+		var (
+			err        error
+			lo, hi     uint64
+			first, end string
+			isRange    bool
+		)
+
+		item = strings.TrimSpace(item)
+		first, end, isRange = strings.Cut(item, "-")
+
+		if lo, err = strconv.ParseUint(first, 10, 16); err != nil {
+			return nil, fmt.Errorf("Invalid port %q", item)
+		} else if !isRange {
+			hi = lo
+		} else if hi, err = strconv.ParseUint(end, 10, 16); err != nil || hi < lo {
+			return nil, fmt.Errorf("Invalid port range %q", item)
+		}
+
+		for p := lo; p <= hi; p++ {
+			ports[uint16(p)] = true
+		}
+	}
+
+	return ports, nil
+} // func parsePorts(s string) (map[uint16]bool, error)
+
+// parsePattern parses the arguments of a match or softmatch directive.
+func parsePattern(s string, soft bool) (*Pattern, error) {
+	var (
+		err            error
+		service, expr  string
+		flags, pattern string
+		p              = &Pattern{Soft: soft}
+	)
+
+	if service, s, _ = strings.Cut(s, " "); service == "" {
+		return nil, errors.New("Missing service name")
+	}
+
+	p.Service = service
+	s = strings.TrimSpace(s)
+
+	if !strings.HasPrefix(s, "m") || len(s) < 3 {
+		return nil, fmt.Errorf("Missing regex for service %s", service)
+	} else if expr, flags, s, err = delimited(s[1:]); err != nil {
+		return nil, fmt.Errorf("Invalid regex for service %s: %w", service, err)
+	}
+
+	for _, f := range flags {
+		switch f {
+		case 'i', 's':
+			pattern += "(?" + string(f) + ")"
+		default:
+			return nil, fmt.Errorf("Unknown regex flag %q for service %s", f, service)
+		}
+	}
+
+	if p.re, err = regexp.Compile(pattern + expr); err != nil {
+		return nil, fmt.Errorf("Invalid regex for service %s: %w", service, err)
+	}
+
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		var key, value string
+
+		if strings.HasPrefix(s, "cpe:") {
+			key = "cpe"
+			s = s[4:]
+		} else {
+			key = s[:1]
+			s = s[1:]
+		}
+
+		if value, _, s, err = delimited(s); err != nil {
+			return nil, fmt.Errorf("Invalid field %s for service %s: %w",
+				key,
+				service,
+				err)
+		}
+
+		switch key {
+		case "p":
+			p.product = value
+		case "v":
+			p.version = value
+		case "i":
+			p.info = value
+		case "o":
+			p.os = value
+		case "cpe":
+			// nmap allows several CPEs per match, we keep the first,
+			// which is usually the one for the product itself.
+			if p.cpe == "" {
+				p.cpe = "cpe:/" + value
+			}
+		case "h", "d":
+			continue
+		default:
+			return nil, fmt.Errorf("Unknown field %q for service %s", key, service)
+		}
+	}
+
+	return p, nil
+} // func parsePattern(s string, soft bool) (*Pattern, error)
+
+// delimited splits s, which starts with a delimiter, into the text up to
+// the next occurrence of the delimiter, the flags following it, and the
+// rest.
+func delimited(s string) (value, flags, rest string, err error) {
+	var end int
+
+	if s == "" {
+		return "", "", "", errors.New("Missing delimiter")
+	} else if end = strings.IndexByte(s[1:], s[0]); end < 0 {
+		return "", "", "", fmt.Errorf("Missing closing %c", s[0])
+	}
+
+	value = s[1 : end+1]
+	rest = s[end+2:]
+
+	if i := strings.IndexFunc(rest, unicode.IsSpace); i >= 0 {
+		flags, rest = rest[:i], rest[i:]
+	} else {
+		flags, rest = rest, ""
+	}
+
+	return value, flags, rest, nil
+} // func delimited(s string) (value, flags, rest string, err error)
+
+// templateVar matches the references to regex groups in a template.
+var templateVar = regexp.MustCompile(`\$(?:(\d)|P\((\d)\)|SUBST\((\d),"([^"]*)","([^"]*)"\))`)
+
+// expand fills in the references to the groups of a match in a template.
+func expand(tmpl string, groups []string) string {
+	if tmpl == "" {
+		return ""
+	}
+
+	var group = func(s string) string {
+		var n, _ = strconv.Atoi(s)
+
+		if n < len(groups) {
+			return groups[n]
+		}
+
+		return ""
+	}
+
+	var res = templateVar.ReplaceAllStringFunc(tmpl, func(ref string) string {
+		var m = templateVar.FindStringSubmatch(ref)
+
+		switch {
+		case m[1] != "":
+			return group(m[1])
+		case m[2] != "":
+			return strings.Map(func(r rune) rune {
+				if unicode.IsPrint(r) {
+					return r
+				}
+				return -1
+			}, group(m[2]))
+		default:
+			return strings.ReplaceAll(group(m[3]), m[4], m[5])
+		}
+	})
+
+	// Groups that did not participate in the match leave behind dangling
+	// separators, e.g. "; protocol 2.0".
+	return strings.Trim(res, " \t;,")
+} // func expand(tmpl string, groups []string) string
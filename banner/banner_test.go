@@ -0,0 +1,238 @@
+// /home/krylon/go/src/github.com/blicero/guang/banner/banner_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 15:24:51 krylon>
+
+package banner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blicero/guang/data"
+)
+
+func TestBuiltin(t *testing.T) {
+	type testCase struct {
+		port  uint16
+		reply string
+		ok    bool
+		sw    data.Software
+	}
+
+	var (
+		err   error
+		db    *DB
+		cases = []testCase{
+			{
+				port:  22,
+				reply: "SSH-2.0-OpenSSH_7.4",
+				ok:    true,
+				sw: data.Software{
+					Service: "ssh",
+					Product: "OpenSSH",
+					Version: "7.4",
+					Info:    "protocol 2.0",
+					CPE:     "cpe:/a:openbsd:openssh:7.4",
+				},
+			},
+			{
+				port:  22,
+				reply: "SSH-2.0-OpenSSH_7.9p1 Debian-10+deb10u2",
+				ok:    true,
+				sw: data.Software{
+					Service: "ssh",
+					Product: "OpenSSH",
+					Version: "7.9p1",
+					Info:    "Debian 10+deb10u2; protocol 2.0",
+					OS:      "Linux",
+					CPE:     "cpe:/a:openbsd:openssh:7.9p1",
+				},
+			},
+			{
+				port:  21,
+				reply: "220 ProFTPD 1.3.5 Server (Debian) [::ffff:192.0.2.1]",
+				ok:    true,
+				sw: data.Software{
+					Service: "ftp",
+					Product: "ProFTPD",
+					Version: "1.3.5",
+					CPE:     "cpe:/a:proftpd:proftpd:1.3.5",
+				},
+			},
+			{
+				port:  80,
+				reply: "Apache/2.2.15 (CentOS)",
+				ok:    true,
+				sw: data.Software{
+					Service: "http",
+					Product: "Apache httpd",
+					Version: "2.2.15",
+					Info:    "CentOS",
+					OS:      "Linux",
+					CPE:     "cpe:/a:apache:http_server:2.2.15",
+				},
+			},
+			{
+				// The pattern for OpenResty captures the version, if
+				// there is one, the CPE must not end in a colon.
+				port:  8080,
+				reply: "openresty",
+				ok:    true,
+				sw: data.Software{
+					Service: "http",
+					Product: "OpenResty web app server",
+					CPE:     "cpe:/a:openresty:openresty",
+				},
+			},
+			{
+				port:  53,
+				reply: "9.11.4-P2-RedHat-9.11.4-26.P2.el7_9.13",
+				ok:    true,
+				sw: data.Software{
+					Service: "domain",
+					Product: "ISC BIND",
+					Version: "9.11.4-P2",
+					Info:    "RedHat-9.11.4-26.P2.el7_9.13",
+					CPE:     "cpe:/a:isc:bind:9.11.4-P2",
+				},
+			},
+			{
+				// Replies on unusual ports are matched against all
+				// sections.
+				port:  2222,
+				reply: "SSH-2.0-dropbear_2019.78",
+				ok:    true,
+				sw: data.Software{
+					Service: "ssh",
+					Product: "Dropbear sshd",
+					Version: "2019.78",
+					Info:    "protocol 2.0",
+					OS:      "Linux",
+					CPE:     "cpe:/a:matt_johnston:dropbear_ssh_server:2019.78",
+				},
+			},
+			{
+				port:  22,
+				reply: "SSH-2.0-SomethingHomegrown",
+				ok:    true,
+				sw:    data.Software{Service: "ssh"},
+			},
+			{
+				port:  25,
+				reply: "Wer das liest, ist doof.",
+			},
+			{
+				port: 80,
+			},
+		}
+	)
+
+	if db, err = Builtin(); err != nil {
+		t.Fatalf("Cannot load built-in patterns: %s", err.Error())
+	} else if db.Len() == 0 {
+		t.Fatal("There are no built-in patterns")
+	}
+
+	for i, c := range cases {
+		var sw, ok = db.Parse(c.port, c.reply)
+
+		if ok != c.ok {
+			t.Errorf("Case %d: Parse(%d, %q) returned %t, expected %t",
+				i,
+				c.port,
+				c.reply,
+				ok,
+				c.ok)
+		} else if sw != c.sw {
+			t.Errorf("Case %d: Parse(%d, %q) returned\n%#v\nexpected\n%#v",
+				i,
+				c.port,
+				c.reply,
+				sw,
+				c.sw)
+		}
+	}
+} // func TestBuiltin(t *testing.T)
+
+func TestLoad(t *testing.T) {
+	type testCase struct {
+		src string
+		ok  bool
+	}
+
+	var cases = []testCase{
+		{src: "# nothing but a comment\n\n", ok: true},
+		{src: `match foo m|^foo (\d+)|i p/Foo/ v/$1/ h/$2/ cpe:/a:foo:foo:$1/a`, ok: true},
+		{src: "Probe TCP foo\nports 1,2,10-20\nsoftmatch foo m=^foo|bar=", ok: true},
+		{src: "ports 22", ok: false},
+		{src: "Probe TCP foo\nports 22-21", ok: false},
+		{src: "match foo", ok: false},
+		{src: "match foo m|^foo", ok: false},
+		{src: "match foo m|(unbalanced|", ok: false},
+		{src: "match foo m|^foo|x", ok: false},
+		{src: "match foo m|^foo| q/what/", ok: false},
+		{src: "match foo m|^foo| p/unterminated", ok: false},
+	}
+
+	for i, c := range cases {
+		if _, err := Load(strings.NewReader(c.src), "test"); (err == nil) != c.ok {
+			t.Errorf("Case %d: Loading %q returned %v", i, c.src, err)
+		}
+	}
+} // func TestLoad(t *testing.T)
+
+func TestExpand(t *testing.T) {
+	type testCase struct {
+		tmpl   string
+		groups []string
+		result string
+	}
+
+	var cases = []testCase{
+		{tmpl: "", groups: []string{"x"}, result: ""},
+		{tmpl: "$1", groups: []string{"x", "1.2"}, result: "1.2"},
+		{tmpl: "$2; protocol $1", groups: []string{"x", "2.0", ""}, result: "protocol 2.0"},
+		{tmpl: "v$3", groups: []string{"x"}, result: "v"},
+		{tmpl: "$P(1)", groups: []string{"x", "a\x00b\x07c"}, result: "abc"},
+		{tmpl: `$SUBST(1,"_",".")`, groups: []string{"x", "1_2_3"}, result: "1.2.3"},
+	}
+
+	for i, c := range cases {
+		if res := expand(c.tmpl, c.groups); res != c.result {
+			t.Errorf("Case %d: expand(%q) returned %q, expected %q",
+				i,
+				c.tmpl,
+				res,
+				c.result)
+		}
+	}
+} // func TestExpand(t *testing.T)
+
+func TestOpen(t *testing.T) {
+	var (
+		err  error
+		db   *DB
+		sw   data.Software
+		ok   bool
+		path = filepath.Join(t.TempDir(), "service-probes")
+	)
+
+	// A missing file is no error, we just get the built-in patterns.
+	if db, err = Open(path); err != nil {
+		t.Fatalf("Cannot open missing pattern file: %s", err.Error())
+	} else if _, ok = db.Parse(22, "SSH-2.0-OpenSSH_7.4"); !ok {
+		t.Error("The built-in patterns are missing")
+	} else if err = os.WriteFile(path, []byte("Probe TCP ssh\nports 22\nmatch ssh m|^SSH-2.0-OpenSSH_([\\w.]+)| p/Our OpenSSH/ v/$1/\n"), 0600); err != nil {
+		t.Fatalf("Cannot write pattern file: %s", err.Error())
+	} else if db, err = Open(path); err != nil {
+		t.Fatalf("Cannot open pattern file: %s", err.Error())
+	} else if sw, ok = db.Parse(22, "SSH-2.0-OpenSSH_7.4"); !ok || sw.Product != "Our OpenSSH" {
+		t.Errorf("The patterns from the file do not take precedence: %#v", sw)
+	} else if sw, ok = db.Parse(80, "nginx/1.18.0 (Ubuntu)"); !ok || sw.Product != "nginx" {
+		t.Errorf("The built-in patterns are missing: %#v", sw)
+	}
+} // func TestOpen(t *testing.T)
@@ -2,7 +2,7 @@
 // -*- coding: utf-8; mode: go; -*-
 // Created on 23. 12. 2015 by Benjamin Walkenhorst
 // (c) 2015 Benjamin Walkenhorst
-// Time-stamp: <2022-10-29 18:11:59 krylon>
+// Time-stamp: <2026-10-19 14:54:49 krylon>
 
 package blacklist
 
@@ -11,6 +11,7 @@ import (
 	"net"
 	"regexp"
 	"sort"
+	"strings"
 	"sync"
 )
 
@@ -157,6 +158,31 @@ func (bl *NameBlacklist) Matches(x string) bool {
 	return false
 } // func (bl NameBlacklist) Matches(x string) bool
 
+// Entry is a snapshot of a single blacklist item: The pattern or network
+// and the number of times it has matched.
+type Entry struct {
+	Pattern string
+	Hits    int64
+}
+
+// Entries returns a snapshot of the items in the blacklist, the ones that
+// matched most often first.
+func (bl *NameBlacklist) Entries() []Entry {
+	bl.lock.Lock()
+	defer bl.lock.Unlock()
+
+	var entries = make([]Entry, len(bl.blacklist))
+
+	for i, item := range bl.blacklist {
+		entries[i] = Entry{
+			Pattern: strings.TrimPrefix(item.Pattern.String(), "(?i)"),
+			Hits:    item.Cnt,
+		}
+	}
+
+	return entries
+} // func (bl *NameBlacklist) Entries() []Entry
+
 // IP blacklist
 
 // IPBlacklistItem is a blacklist item that matches IP addresses against
@@ -205,6 +231,20 @@ func MakeIPBlacklist(networks []string) (*IPBlacklist, error) {
 	return bl, nil
 }
 
+// Add adds a network to the blacklist, unless it is already on it.
+func (bl *IPBlacklist) Add(network *net.IPNet) {
+	bl.lock.Lock()
+	defer bl.lock.Unlock()
+
+	for _, item := range bl.blacklist {
+		if item.Network.String() == network.String() {
+			return
+		}
+	}
+
+	bl.blacklist = append(bl.blacklist, IPBlacklistItem{network, 0})
+} // func (bl *IPBlacklist) Add(network *net.IPNet)
+
 // Matches returns true if the given IP address is a member of any of
 // the networks in the blacklist.
 func (bl *IPBlacklist) Matches(x string) bool {
@@ -224,6 +264,24 @@ func (bl *IPBlacklist) Matches(x string) bool {
 	return false
 } // func (bl *IPBlacklist) Matches(x string) bool
 
+// Entries returns a snapshot of the items in the blacklist, the ones that
+// matched most often first.
+func (bl *IPBlacklist) Entries() []Entry {
+	bl.lock.Lock()
+	defer bl.lock.Unlock()
+
+	var entries = make([]Entry, len(bl.blacklist))
+
+	for i, item := range bl.blacklist {
+		entries[i] = Entry{
+			Pattern: item.Network.String(),
+			Hits:    int64(item.Cnt),
+		}
+	}
+
+	return entries
+} // func (bl *IPBlacklist) Entries() []Entry
+
 // MatchesIP returns true if the given IP address is a member of any of
 // the networks in the blacklist.
 func (bl *IPBlacklist) MatchesIP(x net.IP) bool {
@@ -2,12 +2,13 @@
 // -*- coding: utf-8; mode: go; -*-
 // Created on 21. 06. 2014 by Benjamin Walkenhorst
 // (c) 2014 Benjamin Walkenhorst
-// Time-stamp: <2022-10-29 18:11:51 krylon>
+// Time-stamp: <2026-10-19 14:54:49 krylon>
 
 package blacklist
 
 import (
 	"fmt"
+	"net"
 	"testing"
 )
 
@@ -94,3 +95,29 @@ func TestNameBlacklist(t *testing.T) {
 		}
 	}
 } // func TestNameBlacklist(t *testing.T)
+
+func TestIPBlacklistAdd(t *testing.T) {
+	var (
+		bl         = DefaultIPBlacklist()
+		cnt        = bl.Len()
+		_, n, _    = net.ParseCIDR("198.51.99.0/24")
+		_, dup, _  = net.ParseCIDR("198.51.99.0/24")
+		excluded   = net.ParseIP("198.51.99.12")
+		neighbours = net.ParseIP("198.51.98.12")
+	)
+
+	if bl.MatchesIP(excluded) {
+		t.Fatalf("Blacklist matches %s before it was added", excluded)
+	}
+
+	bl.Add(n)
+	bl.Add(dup)
+
+	if bl.Len() != cnt+1 {
+		t.Errorf("Blacklist has %d items, expected %d", bl.Len(), cnt+1)
+	} else if !bl.MatchesIP(excluded) {
+		t.Errorf("Blacklist does not match %s after adding %s", excluded, n)
+	} else if bl.MatchesIP(neighbours) {
+		t.Errorf("Blacklist matches %s after adding %s", neighbours, n)
+	}
+} // func TestIPBlacklistAdd(t *testing.T)
@@ -2,7 +2,7 @@
 // -*- mode: go; coding: utf-8; -*-
 // Created on 01. 02. 2021 by Benjamin Walkenhorst
 // (c) 2021 Benjamin Walkenhorst
-// Time-stamp: <2022-11-10 22:44:05 krylon>
+// Time-stamp: <2026-10-19 14:07:53 krylon>
 
 // +build ignore
 
@@ -73,6 +73,7 @@ var candidates = map[string][]string{
 		"database",
 		"frontend",
 		"generator",
+		"search",
 		"xfr",
 	},
 	"vet": []string{
@@ -86,6 +87,7 @@ var candidates = map[string][]string{
 		"frontend",
 		"generator",
 		"logdomain",
+		"search",
 		"xfr",
 	},
 	"lint": []string{
@@ -99,6 +101,7 @@ var candidates = map[string][]string{
 		"frontend",
 		"generator",
 		"logdomain",
+		"search",
 		"xfr",
 	},
 }
@@ -267,7 +270,8 @@ This flag is not case-sensitive.`, strings.Join(orderedSteps, ", ")))
 		var sWorkerCnt = strconv.FormatInt(int64(workerCnt), 10)
 
 		// The -tags flag is required so the build will succeed on Debian.
-		var args = []string{"build", "-v", "-tags", "pango_1_42,gtk_3_22", "-p", sWorkerCnt}
+		// sqlite_fts5 enables the full-text index used for searching.
+		var args = []string{"build", "-v", "-tags", "pango_1_42,gtk_3_22,sqlite_fts5", "-p", sWorkerCnt}
 
 		if raceDetect && ((runtime.GOOS == "linux" || runtime.GOOS == "freebsd") && runtime.GOARCH == "amd64") {
 			dbg.Println("[INFO] Building with race detection enabled.")
@@ -397,9 +401,9 @@ func worker(n int, op string, pkgq <-chan string, errq chan<- error, wg *sync.Wa
 			cmd = exec.Command(lintCommand, pkg)
 		} else if op == "test" {
 			if raceDetect && ((runtime.GOOS == "linux" || runtime.GOOS == "freebsd") && runtime.GOARCH == "amd64") {
-				cmd = exec.Command("go", op, "-v", "-timeout", "30m", "-race", pkg)
+				cmd = exec.Command("go", op, "-v", "-tags", "sqlite_fts5", "-timeout", "30m", "-race", pkg)
 			} else {
-				cmd = exec.Command("go", op, "-v", "-timeout", "30m", pkg)
+				cmd = exec.Command("go", op, "-v", "-tags", "sqlite_fts5", "-timeout", "30m", pkg)
 			}
 		} else {
 			cmd = exec.Command("go", op, "-v", pkg)
@@ -2,7 +2,7 @@
 // -*- coding: utf-8; mode: go; -*-
 // Created on 23. 12. 2015 by Benjamin Walkenhorst
 // (c) 2015 Benjamin Walkenhorst
-// Time-stamp: <2023-01-12 12:58:02 krylon>
+// Time-stamp: <2026-10-19 16:22:36 krylon>
 
 // Package common provides constants, variables and functions used
 // throughout the application.
@@ -12,8 +12,6 @@ import (
 	"crypto/sha512"
 	"errors"
 	"fmt"
-	"io"
-	"log"
 	"os"
 	"path/filepath"
 	"time"
@@ -72,60 +70,65 @@ func init() {
 // BaseDir is the folder where all application-specific files (database,
 // log files, etc) are stored.
 // LogPath is the file to the log path.
-// DbPath is the path of the main database.
+// DbPath is the path of the main database, or the URL of a PostgreSQL database.
 // HostCachePath is the path to the IP cache.
 // XfrDbgPath is the path of the folder where data on DNS zone transfers
 // are stored.
+// CertPath and KeyPath are the TLS certificate and private key of the web
+// frontend.
+// ServiceProbesPath is the file with the user's own patterns for the banner
+// parser, if any.
+// GeoIPDir is the folder with the GeoLite2 databases.
 var (
 	BaseDir       = filepath.Join(os.Getenv("HOME"), "guang.d")
 	LogPath       = filepath.Join(BaseDir, "guang.log")
 	DbPath        = filepath.Join(BaseDir, "guang.db")
 	HostCachePath = filepath.Join(BaseDir, "ip_cache")
 	XfrDbgPath    = filepath.Join(BaseDir, "xfr")
+	CertPath      = filepath.Join(BaseDir, "guang.crt")
+	KeyPath       = filepath.Join(BaseDir, "guang.key")
+
+	ServiceProbesPath = filepath.Join(BaseDir, "service-probes")
+	GeoIPDir          = BaseDir
 )
 
+// GeoIPLanguage is the language of the place names in the locations of
+// Hosts. Names missing in that language are given in English.
+var GeoIPLanguage = "de"
+
+// GeoIPLanguages are the languages the GeoLite2 databases have names in.
+var GeoIPLanguages = []string{"de", "en", "es", "fr", "ja", "pt-BR", "ru", "zh-CN"}
+
+// ValidGeoIPLanguage returns true if lang is one of the GeoIPLanguages.
+func ValidGeoIPLanguage(lang string) bool {
+	for _, l := range GeoIPLanguages {
+		if l == lang {
+			return true
+		}
+	}
+
+	return false
+} // func ValidGeoIPLanguage(lang string) bool
+
 // SetBaseDir sets the BaseDir and related variables.
 func SetBaseDir(path string) {
-	fmt.Printf("Setting BASE_DIR to %s\n", path)
+	fmt.Fprintf(os.Stderr, "Setting BASE_DIR to %s\n", path)
 
 	BaseDir = path
 	LogPath = filepath.Join(BaseDir, "guang.log")
 	DbPath = filepath.Join(BaseDir, "guang.db")
 	HostCachePath = filepath.Join(BaseDir, "ip_cache.kch")
 	XfrDbgPath = filepath.Join(BaseDir, "xfr")
+	CertPath = filepath.Join(BaseDir, "guang.crt")
+	KeyPath = filepath.Join(BaseDir, "guang.key")
+	ServiceProbesPath = filepath.Join(BaseDir, "service-probes")
+	GeoIPDir = BaseDir
 
 	if err := InitApp(); err != nil {
 		fmt.Printf("Error initializing application environment: %s\n", err.Error())
 	}
 } // func SetBaseDir(path string)
 
-// GetLogger Tries to create a named logger instance and return it.
-// If the directory to hold the log file does not exist, try to create it.
-func GetLogger(name string) (*log.Logger, error) {
-	var err error
-	err = InitApp()
-	if err != nil {
-		return nil, fmt.Errorf("Error initializing application environment: %s", err.Error())
-	}
-
-	logName := fmt.Sprintf("%s.%s",
-		AppName,
-		name)
-
-	var logfile *os.File
-	logfile, err = os.OpenFile(LogPath, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0644)
-	if err != nil {
-		msg := fmt.Sprintf("Error opening log file: %s\n", err.Error())
-		fmt.Println(msg)
-		return nil, errors.New(msg)
-	}
-
-	writer := io.MultiWriter(os.Stdout, logfile)
-
-	logger := log.New(writer, logName, log.Ldate|log.Ltime|log.Lshortfile)
-	return logger, nil
-} // func GetLogger(name string) (*log.logger, error)
-
 // InitApp performs some basic preparations for the application to run.
 // Currently, this means creating the BASE_DIR folder.
 func InitApp() error {
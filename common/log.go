@@ -0,0 +1,517 @@
+// /home/krylon/go/src/github.com/blicero/guang/common/log.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 16:22:36 krylon>
+
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blicero/guang/logdomain"
+	"github.com/hashicorp/logutils"
+)
+
+// DefaultLogLevel is the level of messages that do not say what level they
+// are, i.e. that do not start with something like "[DEBUG]".
+const DefaultLogLevel logutils.LogLevel = "INFO"
+
+// rotateStampFormat is appended to the names of rotated log files. It has a
+// fixed width, so the names sort chronologically.
+const rotateStampFormat = "20060102-150405.000000000"
+
+// LogConfig controls the format of the log and when the log file is
+// rotated.
+type LogConfig struct {
+	// JSON makes the log emit one JSON object per message instead of a
+	// line of text.
+	JSON bool
+	// MaxSize is the size in bytes after which the log file is rotated.
+	// Zero means no limit.
+	MaxSize int64
+	// MaxAge is the age after which the log file is rotated. Zero means
+	// no limit.
+	MaxAge time.Duration
+	// MaxBackups is the number of rotated log files to keep. Zero means
+	// keeping all of them.
+	MaxBackups int
+	// Quiet keeps log messages off the terminal, they only go to the log
+	// file. This is needed when standard output is used for data, e.g. an
+	// export.
+	Quiet bool
+}
+
+// DefaultLogConfig is the configuration used unless ConfigureLogging is
+// called.
+var DefaultLogConfig = LogConfig{
+	MaxSize:    64 << 20,
+	MaxAge:     time.Hour * 24 * 7,
+	MaxBackups: 8,
+}
+
+// Logger writes log messages for one log domain.
+//
+// It embeds a *log.Logger, so the classic style of logging with Printf and a
+// level prefix like "[ERROR]" keeps working. The methods Trace, Debug, Info,
+// Warn and Error take a message followed by alternating keys and values,
+// like log/slog does.
+//
+// Messages below the minimum level of the Logger's domain are discarded.
+type Logger struct {
+	*log.Logger
+	domain logdomain.ID
+}
+
+// logRecord is a single message on its way to the log.
+type logRecord struct {
+	stamp  time.Time
+	level  logutils.LogLevel
+	domain logdomain.ID
+	source string
+	msg    string
+	attrs  []any
+}
+
+var (
+	levelLock sync.RWMutex
+	sinkLock  sync.Mutex
+	sink      *logSink
+	levelPat  = regexp.MustCompile(`^\[([A-Z]+)\]\s*`)
+	sourcePat = regexp.MustCompile(`^([^:\s]+\.go:\d+): `)
+)
+
+// GetLogger returns a Logger for the given log domain.
+// If the directory to hold the log file does not exist, try to create it.
+func GetLogger(dom logdomain.ID) (*Logger, error) {
+	var (
+		err error
+		l   = &Logger{domain: dom}
+	)
+
+	if err = InitApp(); err != nil {
+		return nil, fmt.Errorf("Error initializing application environment: %s", err.Error())
+	} else if _, err = getSink(); err != nil {
+		msg := fmt.Sprintf("Error opening log file: %s\n", err.Error())
+		fmt.Println(msg)
+		return nil, err
+	}
+
+	l.Logger = log.New(&domainWriter{domain: dom}, "", log.Lshortfile)
+	return l, nil
+} // func GetLogger(dom logdomain.ID) (*Logger, error)
+
+// Domain returns the log domain of the Logger.
+func (l *Logger) Domain() logdomain.ID {
+	return l.domain
+} // func (l *Logger) Domain() logdomain.ID
+
+// Trace logs a message with level TRACE.
+func (l *Logger) Trace(msg string, args ...any) {
+	l.emit("TRACE", msg, args)
+} // func (l *Logger) Trace(msg string, args ...any)
+
+// Debug logs a message with level DEBUG.
+func (l *Logger) Debug(msg string, args ...any) {
+	l.emit("DEBUG", msg, args)
+} // func (l *Logger) Debug(msg string, args ...any)
+
+// Info logs a message with level INFO.
+func (l *Logger) Info(msg string, args ...any) {
+	l.emit("INFO", msg, args)
+} // func (l *Logger) Info(msg string, args ...any)
+
+// Warn logs a message with level WARN.
+func (l *Logger) Warn(msg string, args ...any) {
+	l.emit("WARN", msg, args)
+} // func (l *Logger) Warn(msg string, args ...any)
+
+// Error logs a message with level ERROR.
+func (l *Logger) Error(msg string, args ...any) {
+	l.emit("ERROR", msg, args)
+} // func (l *Logger) Error(msg string, args ...any)
+
+func (l *Logger) emit(level logutils.LogLevel, msg string, args []any) {
+	if !LogEnabled(l.domain, level) {
+		return
+	}
+
+	var rec = logRecord{
+		stamp:  time.Now(),
+		level:  level,
+		domain: l.domain,
+		msg:    msg,
+		attrs:  args,
+	}
+
+	// 0 is emit, 1 is Info et al., 2 is whoever called them.
+	if _, file, line, ok := runtime.Caller(2); ok {
+		rec.source = filepath.Base(file) + ":" + strconv.Itoa(line)
+	}
+
+	writeRecord(&rec)
+} // func (l *Logger) emit(level logutils.LogLevel, msg string, args []any)
+
+// domainWriter receives the output of the *log.Logger embedded in a
+// Logger, one message per call to Write.
+type domainWriter struct {
+	domain logdomain.ID
+}
+
+func (w *domainWriter) Write(p []byte) (int, error) {
+	var (
+		m   []string
+		msg = strings.TrimRight(string(p), "\r\n")
+		rec = logRecord{
+			stamp:  time.Now(),
+			level:  DefaultLogLevel,
+			domain: w.domain,
+		}
+	)
+
+	if m = sourcePat.FindStringSubmatch(msg); m != nil {
+		rec.source = m[1]
+		msg = msg[len(m[0]):]
+	}
+
+	if m = levelPat.FindStringSubmatch(msg); m != nil && levelRank(logutils.LogLevel(m[1])) >= 0 {
+		rec.level = logutils.LogLevel(m[1])
+		msg = msg[len(m[0]):]
+	}
+
+	if LogEnabled(w.domain, rec.level) {
+		rec.msg = msg
+		writeRecord(&rec)
+	}
+
+	return len(p), nil
+} // func (w *domainWriter) Write(p []byte) (int, error)
+
+// levelRank returns the position of the level in LogLevels, or -1 if it
+// is not a valid level.
+func levelRank(level logutils.LogLevel) int {
+	for i, l := range LogLevels {
+		if l == level {
+			return i
+		}
+	}
+
+	return -1
+} // func levelRank(level logutils.LogLevel) int
+
+// ParseLogLevel returns the level with the given name, ignoring case.
+func ParseLogLevel(name string) (logutils.LogLevel, error) {
+	var level = logutils.LogLevel(strings.ToUpper(name))
+
+	if levelRank(level) < 0 {
+		return "", fmt.Errorf("Invalid log level %q", name)
+	}
+
+	return level, nil
+} // func ParseLogLevel(name string) (logutils.LogLevel, error)
+
+// LogEnabled returns true if messages of the given level are logged for
+// the given domain.
+func LogEnabled(dom logdomain.ID, level logutils.LogLevel) bool {
+	return levelRank(level) >= levelRank(GetLogLevel(dom))
+} // func LogEnabled(dom logdomain.ID, level logutils.LogLevel) bool
+
+// GetLogLevel returns the minimum level of messages logged for a domain.
+func GetLogLevel(dom logdomain.ID) logutils.LogLevel {
+	levelLock.RLock()
+	defer levelLock.RUnlock()
+
+	if level, ok := PackageLevels[dom]; ok {
+		return level
+	}
+
+	return MinLogLevel
+} // func GetLogLevel(dom logdomain.ID) logutils.LogLevel
+
+// SetLogLevel sets the minimum level of messages logged for a domain.
+// It takes effect immediately for all Loggers of that domain.
+func SetLogLevel(dom logdomain.ID, level logutils.LogLevel) error {
+	if levelRank(level) < 0 {
+		return fmt.Errorf("Invalid log level %q", level)
+	}
+
+	levelLock.Lock()
+	PackageLevels[dom] = level
+	levelLock.Unlock()
+	return nil
+} // func SetLogLevel(dom logdomain.ID, level logutils.LogLevel) error
+
+// ConfigureLogging sets the format of the log and the rules for rotating
+// the log file. Loggers that already exist pick up the change.
+func ConfigureLogging(cfg LogConfig) error {
+	if err := InitApp(); err != nil {
+		return err
+	}
+
+	sinkLock.Lock()
+	defer sinkLock.Unlock()
+
+	if sink != nil {
+		sink.file.Close() // nolint: errcheck
+		sink = nil
+	}
+
+	DefaultLogConfig = cfg
+	return openSink()
+} // func ConfigureLogging(cfg LogConfig) error
+
+// logSink is where all log messages end up: on the terminal and in the
+// log file.
+type logSink struct {
+	cfg    LogConfig
+	path   string
+	file   *os.File
+	size   int64
+	opened time.Time
+}
+
+// getSink returns the logSink, opening it if needed. If the base directory
+// was changed since it was opened, it is reopened in the new location.
+func getSink() (*logSink, error) {
+	sinkLock.Lock()
+	defer sinkLock.Unlock()
+
+	if sink != nil && sink.path == LogPath {
+		return sink, nil
+	} else if sink != nil {
+		sink.file.Close() // nolint: errcheck
+		sink = nil
+	}
+
+	if err := openSink(); err != nil {
+		return nil, err
+	}
+
+	return sink, nil
+} // func getSink() (*logSink, error)
+
+// openSink opens the log file. The caller must hold sinkLock.
+func openSink() error {
+	var (
+		err  error
+		info os.FileInfo
+		s    = &logSink{cfg: DefaultLogConfig, path: LogPath}
+	)
+
+	if s.file, err = os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644); err != nil {
+		return err
+	} else if info, err = s.file.Stat(); err != nil {
+		s.file.Close() // nolint: errcheck
+		return err
+	}
+
+	s.size = info.Size()
+	s.opened = info.ModTime()
+	if s.size == 0 {
+		s.opened = time.Now()
+	}
+
+	sink = s
+	return nil
+} // func openSink() error
+
+// writeRecord formats a record and writes it to the terminal, unless the
+// log is quiet, and the log file, rotating the log file if it has grown
+// too big or old.
+func writeRecord(rec *logRecord) {
+	var buf bytes.Buffer
+
+	sinkLock.Lock()
+	defer sinkLock.Unlock()
+
+	if sink == nil {
+		if err := openSink(); err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot open log file: %s\n", err.Error())
+			return
+		}
+	}
+
+	if sink.cfg.JSON {
+		formatJSON(&buf, rec)
+	} else {
+		formatText(&buf, rec)
+	}
+
+	if !sink.cfg.Quiet {
+		os.Stdout.Write(buf.Bytes()) // nolint: errcheck
+	}
+
+	if sink.needsRotation(int64(buf.Len())) {
+		if err := sink.rotate(); err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot rotate log file %s: %s\n",
+				sink.path,
+				err.Error())
+		}
+	}
+
+	if n, err := sink.file.Write(buf.Bytes()); err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot write to log file %s: %s\n",
+			sink.path,
+			err.Error())
+	} else {
+		sink.size += int64(n)
+	}
+} // func writeRecord(rec *logRecord)
+
+func (s *logSink) needsRotation(n int64) bool {
+	if s.size == 0 {
+		return false
+	} else if s.cfg.MaxSize > 0 && s.size+n > s.cfg.MaxSize {
+		return true
+	}
+
+	return s.cfg.MaxAge > 0 && time.Since(s.opened) > s.cfg.MaxAge
+} // func (s *logSink) needsRotation(n int64) bool
+
+// rotate renames the current log file, adding a timestamp to its name,
+// opens a new one and removes backups beyond the configured number.
+func (s *logSink) rotate() error {
+	var (
+		err     error
+		backups []string
+		backup  = s.path + "." + time.Now().Format(rotateStampFormat)
+	)
+
+	if fileExists(backup) {
+		return fmt.Errorf("Backup %s already exists", backup)
+	}
+
+	if err = s.file.Close(); err != nil {
+		return err
+	} else if err = os.Rename(s.path, backup); err != nil {
+		return err
+	} else if s.file, err = os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644); err != nil {
+		return err
+	}
+
+	s.size = 0
+	s.opened = time.Now()
+
+	if s.cfg.MaxBackups <= 0 {
+		return nil
+	} else if backups, err = filepath.Glob(s.path + ".*"); err != nil {
+		return err
+	}
+
+	sort.Strings(backups)
+
+	for len(backups) > s.cfg.MaxBackups {
+		if err = os.Remove(backups[0]); err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+
+	return nil
+} // func (s *logSink) rotate() error
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+} // func fileExists(path string) bool
+
+// attrString renders a value for the text format.
+func attrString(v any) string {
+	var s string
+
+	switch x := v.(type) {
+	case error:
+		s = x.Error()
+	case fmt.Stringer:
+		s = x.String()
+	default:
+		s = fmt.Sprint(x)
+	}
+
+	if s == "" || strings.ContainsAny(s, " \t\r\n\"=") {
+		return strconv.Quote(s)
+	}
+
+	return s
+} // func attrString(v any) string
+
+// attrKey returns the key of the i-th key/value pair. A missing or non-string
+// key is rendered like log/slog does.
+func attrKey(attrs []any, i int) (string, any) {
+	if i+1 >= len(attrs) {
+		return "!BADKEY", attrs[i]
+	} else if k, ok := attrs[i].(string); ok {
+		return k, attrs[i+1]
+	}
+
+	return fmt.Sprint(attrs[i]), attrs[i+1]
+} // func attrKey(attrs []any, i int) (string, any)
+
+func formatText(buf *bytes.Buffer, rec *logRecord) {
+	fmt.Fprintf(buf, "%s [%s] %s",
+		rec.stamp.Format(TimestampFormat),
+		rec.level,
+		rec.domain)
+
+	if rec.source != "" {
+		buf.WriteString(" " + rec.source)
+	}
+
+	buf.WriteString(": " + rec.msg)
+
+	for i := 0; i < len(rec.attrs); i += 2 {
+		var k, v = attrKey(rec.attrs, i)
+		buf.WriteString(" " + k + "=" + attrString(v))
+	}
+
+	buf.WriteByte('\n')
+} // func formatText(buf *bytes.Buffer, rec *logRecord)
+
+func formatJSON(buf *bytes.Buffer, rec *logRecord) {
+	var field = func(k string, v any) {
+		var (
+			err error
+			b   []byte
+		)
+
+		if e, ok := v.(error); ok {
+			v = e.Error()
+		}
+
+		if b, err = json.Marshal(v); err != nil {
+			b, _ = json.Marshal(fmt.Sprint(v))
+		}
+
+		b2, _ := json.Marshal(k)
+		buf.WriteByte(',')
+		buf.Write(b2)
+		buf.WriteByte(':')
+		buf.Write(b)
+	}
+
+	fmt.Fprintf(buf, `{"time":%q`, rec.stamp.Format(time.RFC3339Nano))
+	field("level", string(rec.level))
+	field("domain", rec.domain.String())
+	if rec.source != "" {
+		field("source", rec.source)
+	}
+	field("msg", rec.msg)
+
+	for i := 0; i < len(rec.attrs); i += 2 {
+		field(attrKey(rec.attrs, i))
+	}
+
+	buf.WriteString("}\n")
+} // func formatJSON(buf *bytes.Buffer, rec *logRecord)
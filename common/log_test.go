@@ -0,0 +1,142 @@
+// /home/krylon/go/src/github.com/blicero/guang/common/log_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 14:47:35 krylon>
+
+package common
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blicero/guang/logdomain"
+)
+
+// readLog returns the lines in the current log file.
+func readLog(t *testing.T) []string {
+	var (
+		err   error
+		fh    *os.File
+		lines []string
+	)
+
+	if fh, err = os.Open(LogPath); err != nil {
+		t.Fatalf("Cannot open log file: %s", err.Error())
+	}
+
+	defer fh.Close() // nolint: errcheck
+
+	var scanner = bufio.NewScanner(fh)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	return lines
+} // func readLog(t *testing.T) []string
+
+func TestLog(t *testing.T) {
+	var (
+		err   error
+		l     *Logger
+		lines []string
+		dir   = t.TempDir()
+	)
+
+	SetBaseDir(dir)
+
+	if err = ConfigureLogging(LogConfig{}); err != nil {
+		t.Fatalf("Cannot configure logging: %s", err.Error())
+	} else if l, err = GetLogger(logdomain.Scanner); err != nil {
+		t.Fatalf("Cannot create Logger: %s", err.Error())
+	}
+
+	defer SetLogLevel(logdomain.Scanner, MinLogLevel) // nolint: errcheck
+
+	if err = SetLogLevel(logdomain.Scanner, "WARN"); err != nil {
+		t.Fatalf("Cannot set log level: %s", err.Error())
+	} else if err = SetLogLevel(logdomain.Scanner, "LOUD"); err == nil {
+		t.Error("Setting an invalid log level did not fail")
+	}
+
+	l.Printf("[DEBUG] Filtered\n")
+	l.Printf("No level, filtered as well\n")
+	l.Debug("Filtered", "port", 22)
+	l.Printf("[ERROR] Printf message\n")
+	l.Warn("Structured message", "addr", "192.168.0.1", "err", errors.New("no route"), "dangling")
+
+	lines = readLog(t)
+
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines in the log, got %d:\n%s",
+			len(lines),
+			strings.Join(lines, "\n"))
+	} else if !strings.Contains(lines[0], "[ERROR] Scanner log_test.go:") ||
+		!strings.HasSuffix(lines[0], ": Printf message") {
+		t.Errorf("Unexpected log line: %s", lines[0])
+	} else if !strings.Contains(lines[1], "[WARN] Scanner log_test.go:") ||
+		!strings.HasSuffix(lines[1], `: Structured message addr=192.168.0.1 err="no route" !BADKEY=dangling`) {
+		t.Errorf("Unexpected log line: %s", lines[1])
+	}
+
+	if err = ConfigureLogging(LogConfig{JSON: true}); err != nil {
+		t.Fatalf("Cannot configure logging: %s", err.Error())
+	}
+
+	l.Error("JSON message", "port", 53)
+
+	lines = readLog(t)
+
+	var rec map[string]any
+
+	if err = json.Unmarshal([]byte(lines[len(lines)-1]), &rec); err != nil {
+		t.Fatalf("Cannot parse JSON log line %q: %s",
+			lines[len(lines)-1],
+			err.Error())
+	} else if rec["level"] != "ERROR" || rec["domain"] != "Scanner" ||
+		rec["msg"] != "JSON message" || rec["port"] != 53.0 {
+		t.Errorf("Unexpected JSON log record: %v", rec)
+	} else if src, _ := rec["source"].(string); !strings.HasPrefix(src, "log_test.go:") {
+		t.Errorf("Unexpected source in JSON log record: %q", src)
+	}
+} // func TestLog(t *testing.T)
+
+func TestLogRotate(t *testing.T) {
+	var (
+		err     error
+		l       *Logger
+		backups []string
+		dir     = t.TempDir()
+	)
+
+	SetBaseDir(dir)
+
+	if err = ConfigureLogging(LogConfig{MaxSize: 512, MaxBackups: 2}); err != nil {
+		t.Fatalf("Cannot configure logging: %s", err.Error())
+	} else if l, err = GetLogger(logdomain.Common); err != nil {
+		t.Fatalf("Cannot create Logger: %s", err.Error())
+	}
+
+	defer ConfigureLogging(DefaultLogConfig) // nolint: errcheck
+
+	for i := 0; i < 100; i++ {
+		l.Info("Filling up the log file", "i", i)
+	}
+
+	if backups, err = filepath.Glob(LogPath + ".*"); err != nil {
+		t.Fatalf("Cannot list rotated log files: %s", err.Error())
+	} else if len(backups) != 2 {
+		t.Errorf("Expected 2 rotated log files, found %d", len(backups))
+	}
+
+	if info, err := os.Stat(LogPath); err != nil {
+		t.Fatalf("Cannot stat log file: %s", err.Error())
+	} else if info.Size() > 512 {
+		t.Errorf("Log file has grown to %d bytes", info.Size())
+	}
+} // func TestLogRotate(t *testing.T)
@@ -2,15 +2,18 @@
 // -*- coding: utf-8; mode: go; -*-
 // Created on 23. 12. 2015 by Benjamin Walkenhorst
 // (c) 2015 Benjamin Walkenhorst
-// Time-stamp: <2023-03-18 21:58:50 krylon>
+// Time-stamp: <2026-10-19 16:34:17 krylon>
 
 // Package data provides data types used throughout the application.
 package data
 
 import (
+	"fmt"
 	"net"
 	"time"
 
+	"github.com/blicero/guang/auth"
+	"github.com/blicero/guang/rules/severity"
 	"github.com/blicero/guang/xfr/xfrstatus"
 	"github.com/blicero/krylib"
 )
@@ -26,25 +29,165 @@ type HostSource int
 // zone transfer.
 // HostSourceMx and HostSourceNs indicate a Host was gathered from the
 // respective records in a zone transfer.
+// HostSourceImport indicates it was imported from the results of another
+// scanner, such as nmap or masscan.
 const (
 	HostSourceUser HostSource = iota
 	HostSourceGen
 	HostSourceA
 	HostSourceMx
 	HostSourceNs
+	HostSourceImport
 )
 
+// hostSourcePrecedence ranks the HostSources by how much they tell us about
+// a Host. A name we got from a reverse lookup is little more than a guess,
+// while a Host that was entered by the user is exactly what the user wants.
+// The names in imported scans are often from reverse lookups, too, but
+// someone chose to scan those Hosts.
+var hostSourcePrecedence = map[HostSource]int{
+	HostSourceGen:    0,
+	HostSourceImport: 1,
+	HostSourceA:      2,
+	HostSourceMx:     3,
+	HostSourceNs:     4,
+	HostSourceUser:   5,
+}
+
+// Supersedes returns true if information about a Host from source s should
+// replace information from source other.
+func (s HostSource) Supersedes(other HostSource) bool {
+	return hostSourcePrecedence[s] > hostSourcePrecedence[other]
+} // func (s HostSource) Supersedes(other HostSource) bool
+
 // Host is a host somewhere on the Internet.
+//
+// Address and Name are the Host's primary address and name. Names and
+// Addresses list every name and address we have seen for the Host,
+// including the primary ones. They are only filled in when a Host is loaded
+// by its ID or address.
+//
+// OS, OSVersion and OSConfidence are the result of the last OS
+// fingerprinting, the confidence is between 0 and 1. Location is where the
+// GeoIP database places the Host, in the language chosen for display, Geo
+// the same in structured form. AS describes the network the Host is part
+// of.
 type Host struct {
-	ID       krylib.ID
-	Source   HostSource
-	Address  net.IP
-	Name     string
-	Added    time.Time
-	OS       string
-	Location string
+	ID           krylib.ID
+	Source       HostSource
+	Address      net.IP
+	Name         string
+	Added        time.Time
+	OS           string
+	OSVersion    string
+	OSConfidence float64
+	Location     string
+	Geo          GeoInfo
+	AS           ASInfo
+	Names        []HostName
+	Addresses    []HostAddress
+}
+
+// OSName returns the Host's operating system including its version, if
+// it is known.
+func (h *Host) OSName() string {
+	if h.OSVersion == "" {
+		return h.OS
+	}
+
+	return h.OS + " " + h.OSVersion
+} // func (h *Host) OSName() string
+
+// GeoInfo is where the GeoIP database places a Host. Country and
+// Subdivision are ISO 3166 codes, e.g. DE and BY, City is the English
+// name. Latitude and Longitude are only meaningful if Accuracy, the radius
+// in kilometers around them the Host is likely in, is not 0.
+type GeoInfo struct {
+	Country     string
+	Subdivision string
+	City        string
+	Latitude    float64
+	Longitude   float64
+	Accuracy    uint16
+}
+
+// HasCoordinates returns true if the latitude and longitude are known.
+func (g GeoInfo) HasCoordinates() bool {
+	return g.Accuracy != 0
+} // func (g GeoInfo) HasCoordinates() bool
+
+// ASInfo describes who owns the network a Host is part of: the autonomous
+// system announcing it, the prefix it is announced in, and the regional
+// internet registry (RIR) that allocated the address block and when.
+// ASN is 0 if the autonomous system is unknown, Registry is empty and
+// Allocated is the zero time if no delegation covers the address.
+type ASInfo struct {
+	ASN       uint32
+	Org       string
+	Prefix    string
+	Registry  string
+	Allocated time.Time
+}
+
+// Delegation is a block of addresses a regional internet registry
+// allocated or assigned, as listed in the delegation files the RIRs
+// publish. First and Last are the first and last address of the block,
+// which need not be a CIDR network.
+type Delegation struct {
+	Registry string
+	Country  string
+	First    net.IP
+	Last     net.IP
+	Date     time.Time
+	Status   string
 }
 
+// HostName is one of the names a Host is known by.
+type HostName struct {
+	Name      string
+	Source    HostSource
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+// HostAddress is one of the addresses a Host can be reached at.
+type HostAddress struct {
+	Address   net.IP
+	Source    HostSource
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+// Software is what the banner parser could tell from the reply of a Port
+// about the software behind it, see package banner. Any of the fields may
+// be empty. CPE is the product's name in the Common Platform Enumeration,
+// in the URI format, e.g. cpe:/a:openbsd:openssh:7.4p1.
+type Software struct {
+	Service string
+	Product string
+	Version string
+	Info    string
+	OS      string
+	CPE     string
+}
+
+// IsZero returns true if nothing at all is known about the Software.
+func (s *Software) IsZero() bool {
+	return *s == Software{}
+} // func (s *Software) IsZero() bool
+
+// String returns the product and version of the Software.
+func (s *Software) String() string {
+	switch {
+	case s.Product == "":
+		return s.Service
+	case s.Version == "":
+		return s.Product
+	default:
+		return s.Product + " " + s.Version
+	}
+} // func (s *Software) String() string
+
 // Port is a TCP/UDP port that was scanned on a given host.
 type Port struct {
 	ID        krylib.ID
@@ -52,6 +195,7 @@ type Port struct {
 	Port      uint16
 	Timestamp time.Time
 	Reply     *string
+	Software  Software
 }
 
 // ReplyString returns the Reply gathered from the Port or an empty string.
@@ -94,19 +238,158 @@ func (x *XFR) IsFinished() bool {
 	return x.Status != xfrstatus.Unfinished
 } // func (self *XFR) IsFinished() bool
 
+// Tag is a label that can be attached to any number of Hosts.
+type Tag struct {
+	ID   krylib.ID
+	Name string
+}
+
+// Note is a remark a User made about a Host.
+type Note struct {
+	ID     krylib.ID
+	HostID krylib.ID
+	Author string
+	Stamp  time.Time
+	Body   string
+}
+
+// Exclusion is a network or an autonomous system the user wants us to
+// leave alone. Hosts in it are neither added nor scanned. If ASN is set,
+// Network is nil, and all networks the AS announces are excluded.
+type Exclusion struct {
+	ID      krylib.ID
+	Network *net.IPNet
+	ASN     uint32
+	Added   time.Time
+	Comment string
+}
+
+// Target returns the network or the autonomous system that is excluded.
+func (ex *Exclusion) Target() string {
+	if ex.ASN != 0 {
+		return fmt.Sprintf("AS%d", ex.ASN)
+	}
+
+	return ex.Network.String()
+} // func (ex *Exclusion) Target() string
+
+// Rule describes Hosts the user finds interesting. Banner, OS, Location and
+// Hostname are regular expressions, a Rule matches a Host if every criterion
+// that is set matches. If Port or Banner are set, the Rule matches
+// individual open Ports rather than the Host as a whole.
+// If Tag is set, matching Hosts are tagged with it.
+type Rule struct {
+	ID       krylib.ID
+	Label    string
+	Severity severity.Severity
+	Port     uint16
+	Banner   string
+	OS       string
+	Location string
+	Hostname string
+	Tag      string
+	Enabled  bool
+	Created  time.Time
+}
+
+// Finding records that a Rule matched a Host, or one of its Ports. Port is
+// 0 for Rules that match the Host as a whole.
+// Label, Severity, HostName and Address are copied from the Rule and the
+// Host when Findings are loaded from the database, for display.
+type Finding struct {
+	ID       krylib.ID
+	RuleID   krylib.ID
+	HostID   krylib.ID
+	Port     uint16
+	Stamp    time.Time
+	Label    string
+	Severity severity.Severity
+	HostName string
+	Address  net.IP
+}
+
+// Vulnerability is a known vulnerability, e.g. a CVE, imported from a feed
+// of the NVD or an OSV dump. Score is the CVSS base score, or 0 if the feed
+// did not provide one.
+type Vulnerability struct {
+	ID        krylib.ID
+	Name      string
+	Summary   string
+	Score     float64
+	Severity  severity.Severity
+	Published time.Time
+	Source    string
+	Ranges    []AffectedRange
+}
+
+// AffectedRange describes which versions of a product are affected by a
+// Vulnerability. If Version is set, only that exact version is affected,
+// otherwise all versions within the bounds that are set. A range without
+// any bounds affects all versions. An empty Vendor matches any vendor.
+type AffectedRange struct {
+	Vendor    string
+	Product   string
+	Version   string
+	StartIncl string
+	StartExcl string
+	EndIncl   string
+	EndExcl   string
+}
+
+// VulnMatch records that the Software running on a Port is affected by a
+// Vulnerability. The Ranges of the Vulnerability are not loaded.
+type VulnMatch struct {
+	Vuln    Vulnerability
+	HostID  krylib.ID
+	PortID  krylib.ID
+	Port    uint16
+	Product string
+	Version string
+}
+
+// VulnerableHost is an entry in the report of the Hosts affected by the
+// most Vulnerabilities.
+type VulnerableHost struct {
+	HostID   krylib.ID
+	Address  net.IP
+	Name     string
+	Vulns    int64
+	MaxScore float64
+}
+
 // ScanRequest is a request to scan a specific port on a given host
 type ScanRequest struct {
 	Host Host
 	Port uint16
 }
 
+// OSEvidence is a hint at the operating system a Host runs, found on one
+// of its Ports. Weight is how much the hint counts, between 0 and 1.
+type OSEvidence struct {
+	Port    uint16
+	Source  string
+	OS      string
+	Version string
+	Weight  float64
+	Match   string
+}
+
+// OSGuess is what we believe about a Host's operating system, and why.
+type OSGuess struct {
+	OS         string
+	Version    string
+	Confidence float64
+	Evidence   []OSEvidence
+}
+
 // ScanResult represents the result of scanning a single port.
 type ScanResult struct {
-	Host  Host
-	Port  uint16
-	Reply *string
-	Stamp time.Time
-	Err   error
+	Host     Host
+	Port     uint16
+	Reply    *string
+	Software Software
+	Stamp    time.Time
+	Err      error
 }
 
 // HostName returns the hostname of the scanned Host.
@@ -143,3 +426,42 @@ const (
 	CtlMsgSpawn
 	CtlMsgBye
 )
+
+// User is someone who can log into the web frontend.
+type User struct {
+	ID      krylib.ID
+	Name    string
+	Role    auth.Role
+	PwHash  string
+	Created time.Time
+}
+
+// Session is a User logged into the web frontend. The client identifies
+// the Session by a key it got in a cookie, the database only stores the
+// hash of that key.
+// CSRF is the token the client must send along with every request that
+// changes anything.
+type Session struct {
+	ID      krylib.ID
+	User    User
+	KeyHash string
+	CSRF    string
+	Created time.Time
+	Expires time.Time
+}
+
+// IsExpired returns true if the Session has expired.
+func (s *Session) IsExpired() bool {
+	return time.Now().After(s.Expires)
+} // func (s *Session) IsExpired() bool
+
+// APIToken allows scripts to use the REST API on behalf of a User. Like
+// the key of a Session, only the hash of the token is stored.
+type APIToken struct {
+	ID       krylib.ID
+	User     User
+	Name     string
+	KeyHash  string
+	Created  time.Time
+	LastUsed time.Time
+}
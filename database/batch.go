@@ -0,0 +1,366 @@
+// /home/krylon/go/src/github.com/blicero/guang/database/batch.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 17:39:15 krylon>
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/blicero/guang/common"
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/logdomain"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultBatchSize is the number of items after which a Batcher commits,
+// DefaultBatchInterval is the time after which a Batcher commits whatever
+// it has collected so far, even if the batch is not full.
+const (
+	DefaultBatchSize     = 64
+	DefaultBatchInterval = time.Second * 5
+)
+
+// BatchStats provides some numbers on how a Batcher is doing.
+type BatchStats struct {
+	Batches      int64
+	Items        int64
+	Failed       int64
+	LastSize     int
+	LastLatency  time.Duration
+	MaxLatency   time.Duration
+	TotalLatency time.Duration
+}
+
+// AvgLatency returns the average time it took to write a batch.
+func (s *BatchStats) AvgLatency() time.Duration {
+	if s.Batches == 0 {
+		return 0
+	}
+
+	return s.TotalLatency / time.Duration(s.Batches)
+} // func (s *BatchStats) AvgLatency() time.Duration
+
+// Batcher collects ScanResults and new Hosts and writes them to the
+// database in batches, one transaction per batch. A batch is written once
+// it holds size items or interval has passed since the last write,
+// whichever comes first.
+//
+// The Batcher takes ownership of the HostDB it is given, no one else should
+// use it while the Batcher is running.
+type Batcher struct {
+	db        HostDB
+	log       *common.Logger
+	size      int
+	interval  time.Duration
+	hostQ     chan data.Host
+	resQ      chan data.ScanResult
+	stopQ     chan struct{}
+	doneQ     chan struct{}
+	hosts     []data.Host
+	results   []data.ScanResult
+	hostAdded func(*data.Host)
+	resStored func(HostDB, *data.ScanResult)
+	lock      sync.RWMutex
+	stats     BatchStats
+	running   bool
+}
+
+// NewBatcher creates a new Batcher that writes to db. If hostAdded is not
+// nil, it is called for every Host that was added to the database, after
+// the batch containing it has been committed.
+func NewBatcher(db HostDB, size int, interval time.Duration, hostAdded func(*data.Host)) (*Batcher, error) {
+	var (
+		err error
+		b   = &Batcher{
+			db:        db,
+			size:      size,
+			interval:  interval,
+			hostQ:     make(chan data.Host, size),
+			resQ:      make(chan data.ScanResult, size),
+			stopQ:     make(chan struct{}),
+			doneQ:     make(chan struct{}),
+			hosts:     make([]data.Host, 0, size),
+			results:   make([]data.ScanResult, 0, size),
+			hostAdded: hostAdded,
+		}
+	)
+
+	if size < 1 {
+		return nil, fmt.Errorf("Batch size must be a positive number, not %d", size)
+	} else if interval <= 0 {
+		return nil, fmt.Errorf("Batch interval must be positive, not %s", interval)
+	} else if b.log, err = common.GetLogger(logdomain.Batcher); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+} // func NewBatcher(db HostDB, size int, interval time.Duration, hostAdded func(*data.Host)) (*Batcher, error)
+
+// OnResult registers a function that is called for every ScanResult that
+// was written to the database, after the batch containing it has been
+// committed. f is called from the Batcher's goroutine with the Batcher's
+// HostDB, which it may use, but must not hold on to.
+// OnResult must be called before the Batcher is started.
+func (b *Batcher) OnResult(f func(db HostDB, res *data.ScanResult)) {
+	b.resStored = f
+} // func (b *Batcher) OnResult(f func(db HostDB, res *data.ScanResult))
+
+// Start starts the Batcher's background goroutine.
+// If it is already running, this method does nothing.
+func (b *Batcher) Start() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.running {
+		return
+	}
+
+	b.running = true
+	go b.loop()
+} // func (b *Batcher) Start()
+
+// Stop writes any pending items to the database and stops the Batcher.
+// Items added after Stop has been called are not written.
+func (b *Batcher) Stop() {
+	b.lock.Lock()
+	if !b.running {
+		b.lock.Unlock()
+		return
+	}
+
+	b.running = false
+	b.lock.Unlock()
+
+	close(b.stopQ)
+	<-b.doneQ
+} // func (b *Batcher) Stop()
+
+// AddHost queues a Host to be added to the database, see HostDB.HostUpsert.
+// Once the Batcher has been stopped, the Host is dropped.
+func (b *Batcher) AddHost(h data.Host) {
+	select {
+	case b.hostQ <- h:
+	case <-b.stopQ:
+	}
+} // func (b *Batcher) AddHost(h data.Host)
+
+// AddResult queues a ScanResult to be added to the database.
+// Once the Batcher has been stopped, the ScanResult is dropped.
+func (b *Batcher) AddResult(res data.ScanResult) {
+	select {
+	case b.resQ <- res:
+	case <-b.stopQ:
+	}
+} // func (b *Batcher) AddResult(res data.ScanResult)
+
+// Stats returns a snapshot of the Batcher's statistics.
+func (b *Batcher) Stats() BatchStats {
+	b.lock.RLock()
+	var s = b.stats
+	b.lock.RUnlock()
+	return s
+} // func (b *Batcher) Stats() BatchStats
+
+// Register exports the Batcher's statistics as Prometheus metrics, labeled
+// with name. Each Batcher needs a name of its own.
+func (b *Batcher) Register(name string) error {
+	return prometheus.Register(newBatchCollector(b, name))
+} // func (b *Batcher) Register(name string) error
+
+func (b *Batcher) pending() int {
+	return len(b.hosts) + len(b.results)
+} // func (b *Batcher) pending() int
+
+func (b *Batcher) loop() {
+	var ticker = time.NewTicker(b.interval)
+	defer ticker.Stop()
+	defer close(b.doneQ)
+
+	for {
+		select {
+		case <-b.stopQ:
+			// Pick up whatever is still sitting in the queues.
+			for {
+				select {
+				case h := <-b.hostQ:
+					b.hosts = append(b.hosts, h)
+				case r := <-b.resQ:
+					b.results = append(b.results, r)
+				default:
+					b.flush()
+					return
+				}
+			}
+		case h := <-b.hostQ:
+			b.hosts = append(b.hosts, h)
+		case r := <-b.resQ:
+			b.results = append(b.results, r)
+		case <-ticker.C:
+			b.flush()
+			continue
+		}
+
+		if b.pending() >= b.size {
+			b.flush()
+			ticker.Reset(b.interval)
+		}
+	}
+} // func (b *Batcher) loop()
+
+// flush writes all pending items to the database in one transaction.
+// If the transaction fails, the items are written one by one, so a single
+// bad item does not take the whole batch down with it.
+func (b *Batcher) flush() {
+	var (
+		err    error
+		cnt    = b.pending()
+		ctx    = context.Background()
+		start  = time.Now()
+		added  []*data.Host
+		stored []*data.ScanResult
+	)
+
+	if cnt == 0 {
+		return
+	}
+
+	if err = b.db.Begin(ctx); err != nil {
+		b.log.Printf("[ERROR] Cannot start transaction for batch of %d items: %s\n",
+			cnt,
+			err.Error())
+	} else if added, stored, err = b.write(ctx); err != nil {
+		b.log.Printf("[ERROR] Error writing batch of %d items, rolling back: %s\n",
+			cnt,
+			err.Error())
+		b.db.Rollback() // nolint: errcheck
+	} else if err = b.db.Commit(); err != nil {
+		b.log.Printf("[ERROR] Cannot commit batch of %d items: %s\n",
+			cnt,
+			err.Error())
+	}
+
+	if err != nil {
+		// writeSingle must not end up in a transaction that is already
+		// lost.
+		if b.db.InTransaction() {
+			b.db.Rollback() // nolint: errcheck
+		}
+		added, stored = b.writeSingle(ctx)
+	}
+
+	var latency = time.Since(start)
+
+	b.lock.Lock()
+	b.stats.Batches++
+	b.stats.Items += int64(cnt)
+	b.stats.LastSize = cnt
+	b.stats.LastLatency = latency
+	b.stats.TotalLatency += latency
+	if latency > b.stats.MaxLatency {
+		b.stats.MaxLatency = latency
+	}
+	if err != nil {
+		b.stats.Failed++
+	}
+	b.lock.Unlock()
+
+	if common.Debug {
+		b.log.Printf("[DEBUG] Wrote batch of %d items (%d hosts, %d results) in %s\n",
+			cnt,
+			len(b.hosts),
+			len(b.results),
+			latency)
+	}
+
+	if b.hostAdded != nil {
+		for _, h := range added {
+			b.hostAdded(h)
+		}
+	}
+
+	if b.resStored != nil {
+		for _, r := range stored {
+			b.resStored(b.db, r)
+		}
+	}
+
+	b.hosts = b.hosts[:0]
+	b.results = b.results[:0]
+} // func (b *Batcher) flush()
+
+// write adds all pending items to the database, stopping at the first error.
+// It returns the Hosts that were new and the ScanResults that were stored.
+func (b *Batcher) write(ctx context.Context) ([]*data.Host, []*data.ScanResult, error) {
+	var (
+		err    error
+		added  = make([]*data.Host, 0, len(b.hosts))
+		stored = make([]*data.ScanResult, 0, len(b.results))
+	)
+
+	for i := range b.hosts {
+		var (
+			isNew bool
+			h     = &b.hosts[i]
+		)
+
+		if isNew, err = b.db.HostUpsert(ctx, h); err != nil {
+			return nil, nil, err
+		} else if isNew {
+			added = append(added, h)
+		}
+	}
+
+	for i := range b.results {
+		if err = b.db.PortAdd(ctx, &b.results[i]); err != nil {
+			return nil, nil, err
+		}
+
+		stored = append(stored, &b.results[i])
+	}
+
+	return added, stored, nil
+} // func (b *Batcher) write(ctx context.Context) ([]*data.Host, []*data.ScanResult, error)
+
+// writeSingle adds all pending items to the database without a transaction,
+// logging and skipping any items that cannot be written.
+// It returns the Hosts that were new and the ScanResults that were stored.
+func (b *Batcher) writeSingle(ctx context.Context) ([]*data.Host, []*data.ScanResult) {
+	var (
+		added  = make([]*data.Host, 0, len(b.hosts))
+		stored = make([]*data.ScanResult, 0, len(b.results))
+	)
+
+	for i := range b.hosts {
+		var (
+			err   error
+			isNew bool
+			h     = &b.hosts[i]
+		)
+
+		if isNew, err = b.db.HostUpsert(ctx, h); err != nil {
+			b.log.Printf("[ERROR] Error adding host %s/%s to database: %s\n",
+				h.Name,
+				h.Address,
+				err.Error())
+		} else if isNew {
+			added = append(added, h)
+		}
+	}
+
+	for i := range b.results {
+		if err := b.db.PortAdd(ctx, &b.results[i]); err != nil {
+			b.log.Printf("[ERROR] Error adding Port to DB: %s\n",
+				err.Error())
+		} else {
+			stored = append(stored, &b.results[i])
+		}
+	}
+
+	return added, stored
+} // func (b *Batcher) writeSingle(ctx context.Context) ([]*data.Host, []*data.ScanResult)
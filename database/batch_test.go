@@ -0,0 +1,264 @@
+// /home/krylon/go/src/github.com/blicero/guang/database/batch_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 17:39:15 krylon>
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/blicero/guang/data"
+	"github.com/blicero/krylib"
+)
+
+func TestBatcher(t *testing.T) {
+	const (
+		hostCnt = 10
+		size    = 4
+	)
+
+	var (
+		err   error
+		bdb   HostDB
+		b     *Batcher
+		cnt   int64
+		added int
+		stats BatchStats
+		path  = filepath.Join(t.TempDir(), "batch.db")
+		reply = "SSH-2.0-OpenSSH_9.3"
+	)
+
+	if bdb, err = OpenDB(path); err != nil {
+		t.Fatalf("Cannot open database at %s: %s", path, err.Error())
+	}
+
+	defer bdb.Close()
+
+	if b, err = NewBatcher(bdb, size, time.Hour, func(h *data.Host) {
+		if h.ID == krylib.INVALID_ID {
+			t.Errorf("Host %s was reported as added, but has no ID",
+				h.Address)
+		}
+		added++
+	}); err != nil {
+		t.Fatalf("Cannot create Batcher: %s", err.Error())
+	}
+
+	b.Start()
+
+	for i := 0; i < hostCnt; i++ {
+		b.AddHost(data.Host{
+			ID:      krylib.INVALID_ID,
+			Address: net.ParseIP(fmt.Sprintf("10.0.0.%d", i+1)),
+			Name:    fmt.Sprintf("host%02d.example.com", i+1),
+			Source:  data.HostSourceGen,
+		})
+	}
+
+	// A duplicate must not end up in the database twice.
+	b.AddHost(data.Host{
+		ID:      krylib.INVALID_ID,
+		Address: net.ParseIP("10.0.0.1"),
+		Name:    "host01.example.com",
+		Source:  data.HostSourceGen,
+	})
+
+	b.Stop()
+
+	if cnt, err = bdb.HostGetCount(context.Background()); err != nil {
+		t.Fatalf("Cannot count Hosts: %s", err.Error())
+	} else if cnt != hostCnt {
+		t.Errorf("Unexpected number of Hosts in database: %d (expected %d)",
+			cnt,
+			hostCnt)
+	} else if added != hostCnt {
+		t.Errorf("hostAdded was called %d times, expected %d",
+			added,
+			hostCnt)
+	}
+
+	stats = b.Stats()
+
+	if stats.Items != hostCnt+1 {
+		t.Errorf("Batcher processed %d items, expected %d",
+			stats.Items,
+			hostCnt+1)
+	} else if stats.Batches != (hostCnt+1+size-1)/size {
+		t.Errorf("Batcher wrote %d batches, expected %d",
+			stats.Batches,
+			(hostCnt+1+size-1)/size)
+	}
+
+	var host *data.Host
+
+	if host, err = bdb.HostGetByID(context.Background(), 1); err != nil {
+		t.Fatalf("Cannot load Host #1: %s", err.Error())
+	}
+
+	// The interval should take care of a batch that never fills up.
+	if b, err = NewBatcher(bdb, size, 50*time.Millisecond, nil); err != nil {
+		t.Fatalf("Cannot create Batcher: %s", err.Error())
+	}
+
+	var storedQ = make(chan *data.ScanResult, 1)
+
+	b.OnResult(func(db HostDB, res *data.ScanResult) {
+		var (
+			err   error
+			ports []data.Port
+		)
+
+		// The callback may use the Batcher's database.
+		if ports, err = db.PortGetByHost(context.Background(), res.Host.ID); err != nil || len(ports) != 1 {
+			t.Errorf("Stored port not found in database: %v", err)
+		}
+		storedQ <- res
+	})
+
+	b.Start()
+	defer b.Stop()
+
+	b.AddResult(data.ScanResult{
+		Host:  *host,
+		Port:  22,
+		Reply: &reply,
+		Stamp: time.Now(),
+	})
+
+	select {
+	case res := <-storedQ:
+		if res.Port != 22 {
+			t.Errorf("OnResult got the wrong result: port %d", res.Port)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Error("OnResult was not called for the stored result")
+	}
+
+	if stats = b.Stats(); stats.Batches != 1 || stats.LastSize != 1 {
+		t.Errorf("Unexpected statistics after interval: %d batches, last size %d",
+			stats.Batches,
+			stats.LastSize)
+	}
+} // func TestBatcher(t *testing.T)
+
+func TestBatcherStopped(t *testing.T) {
+	var (
+		err  error
+		bdb  HostDB
+		b    *Batcher
+		path = filepath.Join(t.TempDir(), "stopped.db")
+		done = make(chan struct{})
+	)
+
+	if bdb, err = OpenDB(path); err != nil {
+		t.Fatalf("Cannot open database at %s: %s", path, err.Error())
+	}
+
+	defer bdb.Close()
+
+	if b, err = NewBatcher(bdb, 1, time.Hour, nil); err != nil {
+		t.Fatalf("Cannot create Batcher: %s", err.Error())
+	}
+
+	b.Start()
+	b.Stop()
+
+	// The queues only hold one item each, so without anyone reading from
+	// them, this would block.
+	go func() {
+		for i := 0; i < 4; i++ {
+			b.AddHost(data.Host{
+				Address: net.ParseIP(fmt.Sprintf("192.0.2.%d", i+1)),
+				Name:    fmt.Sprintf("host%02d.example.com", i+1),
+			})
+			b.AddResult(data.ScanResult{Port: 22})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Adding items to a stopped Batcher blocks")
+	}
+} // func TestBatcherStopped(t *testing.T)
+
+// failingCommitDB makes the next commits fail by ending the transaction
+// behind the database's back.
+type failingCommitDB struct {
+	*sqlDB
+	fail int
+}
+
+func (db *failingCommitDB) Commit() error {
+	if db.fail > 0 {
+		db.fail--
+		db.tx.Rollback() // nolint: errcheck
+	}
+
+	return db.sqlDB.Commit()
+} // func (db *failingCommitDB) Commit() error
+
+func TestBatcherCommitFailed(t *testing.T) {
+	var (
+		err   error
+		bdb   HostDB
+		b     *Batcher
+		cnt   int64
+		stats BatchStats
+		path  = filepath.Join(t.TempDir(), "commit.db")
+	)
+
+	if bdb, err = OpenDB(path); err != nil {
+		t.Fatalf("Cannot open database at %s: %s", path, err.Error())
+	}
+
+	defer bdb.Close()
+
+	var fdb = &failingCommitDB{sqlDB: bdb.(*sqlDB), fail: 1}
+
+	if b, err = NewBatcher(fdb, 2, time.Hour, nil); err != nil {
+		t.Fatalf("Cannot create Batcher: %s", err.Error())
+	}
+
+	b.Start()
+
+	for i := 0; i < 4; i++ {
+		b.AddHost(data.Host{
+			Address: net.ParseIP(fmt.Sprintf("192.0.2.%d", i+1)),
+			Name:    fmt.Sprintf("host%02d.example.com", i+1),
+		})
+	}
+
+	b.Stop()
+
+	if fdb.InTransaction() {
+		t.Error("A transaction is still active after the Batcher stopped")
+	} else if cnt, err = bdb.HostGetCount(context.Background()); err != nil {
+		t.Fatalf("Cannot count Hosts: %s", err.Error())
+	} else if cnt != 4 {
+		t.Errorf("Database holds %d Hosts, expected 4", cnt)
+	} else if stats = b.Stats(); stats.Batches != 2 || stats.Failed != 1 {
+		t.Errorf("Expected 2 batches, 1 of them failed, got %d and %d",
+			stats.Batches,
+			stats.Failed)
+	}
+
+	// The Batcher would clean up after Commit, so check Commit itself.
+	fdb.fail = 1
+
+	if err = fdb.Begin(context.Background()); err != nil {
+		t.Fatalf("Cannot start transaction: %s", err.Error())
+	} else if err = fdb.Commit(); err == nil {
+		t.Error("Commit of a lost transaction did not fail")
+	} else if fdb.InTransaction() {
+		t.Error("The lost transaction is still active after Commit failed")
+	}
+} // func TestBatcherCommitFailed(t *testing.T)
@@ -2,7 +2,7 @@
 // -*- coding: utf-8; mode: go; -*-
 // Created on 23. 12. 2015 by Benjamin Walkenhorst
 // (c) 2015 Benjamin Walkenhorst
-// Time-stamp: <2023-05-19 16:44:23 krylon>
+// Time-stamp: <2026-10-19 17:39:15 krylon>
 //
 // Samstag, 20. 08. 2016, 21:27
 // Ich würde für Hosts gern a) anhand der Antworten, die ich erhalte, das
@@ -13,23 +13,24 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
-	"log"
 	"net"
 	"os"
 	"regexp"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/blicero/guang/common"
 	"github.com/blicero/guang/data"
 	"github.com/blicero/guang/database/query"
+	"github.com/blicero/guang/logdomain"
 	"github.com/blicero/guang/xfr/xfrstatus"
 	"github.com/blicero/krylib"
 
-	_ "github.com/mattn/go-sqlite3" // Import the database driver
 	"github.com/muesli/cache2go"
 )
 
@@ -39,33 +40,39 @@ var (
 )
 
 const (
-	retryDelay   = 10 * time.Millisecond
 	cacheTimeout = time.Second * 1200
 )
 
-// HostDB is a wrapper around the database connection.
-type HostDB struct {
+// sqlDB implements HostDB on top of database/sql. The differences between
+// the supported database engines are handled by its dialect.
+type sqlDB struct {
 	db        *sql.DB
+	dialect   *dialect
 	stmtTable map[query.ID]*sql.Stmt
 	tx        *sql.Tx
-	log       *log.Logger
+	log       *common.Logger
 	path      string
 	hostCache *cache2go.CacheTable
+	// fts is true if the full-text index is available, see
+	// setupSearchIndex.
+	fts bool
 }
 
-// OpenDB opens a new database connection.
-func OpenDB(path string) (*HostDB, error) {
+// OpenSQLite opens the SQLite database at the given path, creating it if
+// it does not exist yet.
+func OpenSQLite(path string) (HostDB, error) {
 	var err error
 	var msg string
 	var dbExists bool
 
-	db := &HostDB{
+	db := &sqlDB{
 		path:      path,
+		dialect:   dialectSQLite,
 		stmtTable: make(map[query.ID]*sql.Stmt),
-		hostCache: cache2go.Cache("host"),
+		hostCache: cache2go.Cache("host:" + path),
 	}
 
-	if db.log, err = common.GetLogger("HostDB"); err != nil {
+	if db.log, err = common.GetLogger(logdomain.Database); err != nil {
 		msg = fmt.Sprintf("Error creating logger for HostDB: %s", err.Error())
 		fmt.Println(msg)
 		return nil, errors.New(msg)
@@ -81,85 +88,141 @@ func OpenDB(path string) (*HostDB, error) {
 		msg = fmt.Sprintf("Error checking if HostDB exists at %s: %s", path, err.Error())
 		db.log.Println(msg)
 		return nil, errors.New(msg)
-	} else if db.db, err = sql.Open("sqlite3", connstring); err != nil {
+	} else if db.db, err = sql.Open(db.dialect.driver, connstring); err != nil {
 		msg = fmt.Sprintf("Error opening database at %s: %s", path, err.Error())
 		db.log.Println(msg)
 		return nil, errors.New(msg)
 	} else if !dbExists {
 		db.log.Printf("Initializing fresh database at %s...\n", path)
-		if err = db.initialize(); err != nil {
-			msg = fmt.Sprintf("Error initializing database at %s: %s",
-				path, err.Error())
-			db.log.Println(msg)
-			db.db.Close()
+	}
+
+	if err = db.migrate(context.Background()); err != nil {
+		err = fmt.Errorf("Error initializing database at %s: %w",
+			path,
+			err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		db.db.Close()
+		if !dbExists {
 			os.Remove(path)
-			return nil, errors.New(msg)
 		}
+		return nil, err
+	} else if err = db.setupSearchIndex(context.Background()); err != nil {
+		db.log.Printf("[ERROR] Full-text index is not available: %s\n",
+			err.Error())
 	}
 
 	return db, nil
-} // func OpenDB(path string) (*HostDB, error)
-
-func (db *HostDB) worthARetry(err error) bool {
-	return retryPat.MatchString(err.Error())
-} // func (db *HostDB) worth_a_retry(err error) bool
+} // func OpenSQLite(path string) (HostDB, error)
 
-func (db *HostDB) getStatement(qid query.ID) (*sql.Stmt, error) {
+func (db *sqlDB) getStatement(ctx context.Context, qid query.ID) (*sql.Stmt, error) {
 	if stmt, ok := db.stmtTable[qid]; ok {
 		return stmt, nil
 	}
 
-	var stmt *sql.Stmt
-	var err error
-	var msg string
+	var (
+		err  error
+		stmt *sql.Stmt
+	)
 
-PREPARE_QUERY:
-	if stmt, err = db.db.Prepare(dbQueries[qid]); err != nil {
-		if db.worthARetry(err) {
-			time.Sleep(retryDelay)
-			goto PREPARE_QUERY
-		} else {
-			msg = fmt.Sprintf("Error preparing query %s %s\n\n%s\n",
-				qid, err.Error(), dbQueries[qid])
-			db.log.Println(msg)
-			return nil, errors.New(msg)
-		}
-	} else {
-		db.stmtTable[qid] = stmt
-		return stmt, nil
+	if stmt, err = retry(ctx, db, func() (*sql.Stmt, error) {
+		return db.db.PrepareContext(ctx, db.dialect.queries[qid])
+	}); err != nil {
+		err = fmt.Errorf("Error preparing query %s: %w\n\n%s",
+			qid,
+			err,
+			db.dialect.queries[qid])
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	db.stmtTable[qid] = stmt
+	return stmt, nil
+} // func (db *sqlDB) getStatement(ctx context.Context, qid query.ID) (*sql.Stmt, error)
+
+// exec executes the query identified by qid with the given arguments,
+// inside the current transaction if there is one.
+func (db *sqlDB) exec(ctx context.Context, qid query.ID, args ...any) (sql.Result, error) {
+	var (
+		err  error
+		stmt *sql.Stmt
+	)
+
+	if stmt, err = db.getStatement(ctx, qid); err != nil {
+		return nil, err
+	} else if db.tx != nil {
+		stmt = db.tx.StmtContext(ctx, stmt)
+	}
+
+	return retry(ctx, db, func() (sql.Result, error) {
+		return stmt.ExecContext(ctx, args...)
+	})
+} // func (db *sqlDB) exec(ctx context.Context, qid query.ID, args ...any) (sql.Result, error)
+
+// query runs the query identified by qid with the given arguments,
+// inside the current transaction if there is one.
+// The caller is responsible for closing the returned Rows.
+func (db *sqlDB) query(ctx context.Context, qid query.ID, args ...any) (*sql.Rows, error) {
+	var (
+		err  error
+		stmt *sql.Stmt
+	)
+
+	if stmt, err = db.getStatement(ctx, qid); err != nil {
+		return nil, err
+	} else if db.tx != nil {
+		stmt = db.tx.StmtContext(ctx, stmt)
+	}
+
+	return retry(ctx, db, func() (*sql.Rows, error) {
+		return stmt.QueryContext(ctx, args...)
+	})
+} // func (db *sqlDB) query(ctx context.Context, qid query.ID, args ...any) (*sql.Rows, error)
+
+// insert runs the INSERT query identified by qid, inside the current
+// transaction if there is one, and returns the ID of the new row. The query
+// is expected to end with a RETURNING id clause.
+func (db *sqlDB) insert(ctx context.Context, qid query.ID, args ...any) (int64, error) {
+	var (
+		err  error
+		stmt *sql.Stmt
+	)
+
+	if stmt, err = db.getStatement(ctx, qid); err != nil {
+		return 0, err
+	} else if db.tx != nil {
+		stmt = db.tx.StmtContext(ctx, stmt)
 	}
-} // func (db *HostDB) getStatement(stmt_id query.QueryID) (*sql.Stmt, error)
+
+	return retry(ctx, db, func() (int64, error) {
+		var id int64
+		err := stmt.QueryRowContext(ctx, args...).Scan(&id)
+		return id, err
+	})
+} // func (db *sqlDB) insert(ctx context.Context, qid query.ID, args ...any) (int64, error)
 
 // Begin starts a transaction
-func (db *HostDB) Begin() error {
+func (db *sqlDB) Begin(ctx context.Context) error {
 	var err error
-	var msg string
 	var tx *sql.Tx
 
 	if db.tx != nil {
-		msg = "Cannot start transaction: A transaction is already in progress!"
-		db.log.Println(msg)
-		return errors.New(msg)
+		err = errors.New("Cannot start transaction: A transaction is already in progress")
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	} else if tx, err = retry(ctx, db, func() (*sql.Tx, error) {
+		return db.db.BeginTx(ctx, nil)
+	}); err != nil {
+		err = fmt.Errorf("Cannot start transaction: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
 	}
 
-BEGIN:
-	if tx, err = db.db.Begin(); err != nil {
-		if db.worthARetry(err) {
-			time.Sleep(retryDelay)
-			goto BEGIN
-		} else {
-			msg = fmt.Sprintf("Cannot start transaction: %s", err.Error())
-			db.log.Println(msg)
-			return errors.New(msg)
-		}
-	} else {
-		db.tx = tx
-		return nil
-	}
-} // func (db *HostDB) Begin() error
+	db.tx = tx
+	return nil
+} // func (db *sqlDB) Begin(ctx context.Context) error
 
 // Rollback aborts a transaction
-func (db *HostDB) Rollback() error {
+func (db *sqlDB) Rollback() error {
 	var err error
 	var msg string
 
@@ -167,67 +230,52 @@ func (db *HostDB) Rollback() error {
 		msg = "Cannot roll back transaction: No transaction is active!"
 		db.log.Println(msg)
 		return errors.New(msg)
-	} else if err = db.tx.Rollback(); err != nil {
+	}
+
+	// Even if the rollback fails, the transaction is over.
+	err = db.tx.Rollback()
+	db.tx = nil
+
+	if err != nil {
 		msg = fmt.Sprintf("Cannot roll back transaction: %s", err.Error())
 		db.log.Println(msg)
 		return errors.New(msg)
-	} else {
-		db.tx = nil
-		return nil
 	}
-} // func (db *HostDB) Rollback() error
+
+	return nil
+} // func (db *sqlDB) Rollback() error
 
 // Commit finishes a transaction
-func (db *HostDB) Commit() error {
+func (db *sqlDB) Commit() error {
 	var err error
-	var msg string
 
 	if db.tx == nil {
-		msg = "Cannot commit transaction: No transaction is active!"
-		db.log.Println(msg)
-		return errors.New(msg)
-	} else if err = db.tx.Commit(); err != nil {
-		msg = fmt.Sprintf("Cannot commit transaction: %s", err.Error())
-		db.log.Println(msg)
-		return errors.New(msg)
-	} else {
-		db.tx = nil
-		return nil
+		err = errors.New("Cannot commit transaction: No transaction is active")
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
 	}
-} // func (db *HostDB) Commit() error
 
-// Initialize a fresh database, i.e. create all the tables and indices.
-// Commit if everythings works as planned, otherwise, roll back, close
-// the database, delete the database file, and return an error.
-func (db *HostDB) initialize() error {
-	var err error
+	// Even if the commit fails, the transaction is over, it cannot be
+	// used or rolled back anymore.
+	err = db.tx.Commit()
+	db.tx = nil
 
-	err = db.Begin()
 	if err != nil {
-		msg := fmt.Sprintf("Error starting transaction to initialize database: %s",
-			err.Error())
-		db.log.Println(msg)
-		return errors.New(msg)
+		err = fmt.Errorf("Cannot commit transaction: %w", classify(err))
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
 	}
 
-	for _, query := range initQueries {
-		if _, err = db.tx.Exec(query); err != nil {
-			msg := fmt.Sprintf("Error executing query %s: %s",
-				query, err.Error())
-			db.log.Println(msg)
-			db.db.Close()
-			db.db = nil
-			os.Remove(db.path)
-			return errors.New(msg)
-		}
-	}
-
-	db.Commit() // nolint: errcheck
 	return nil
-} // func (db *HostDB) initialize() error
+} // func (db *sqlDB) Commit() error
+
+// InTransaction returns true if a transaction is active.
+func (db *sqlDB) InTransaction() bool {
+	return db.tx != nil
+} // func (db *sqlDB) InTransaction() bool
 
 // Close closes the database connection
-func (db *HostDB) Close() {
+func (db *sqlDB) Close() {
 	for _, stmt := range db.stmtTable {
 		stmt.Close()
 	}
@@ -240,1023 +288,1211 @@ func (db *HostDB) Close() {
 	}
 
 	db.db.Close()
-} // func (db *HostDB) Close()
+} // func (db *sqlDB) Close()
+
+// hostColumns returns the destinations to scan the columns of a Host
+// into, in the order id, addr, name, location, os, os_version,
+// os_confidence, source, add_stamp, asn, as_org, prefix, registry,
+// allocated, country, subdivision, city, latitude, longitude, accuracy,
+// and a function that fills in the remaining fields of host once the row
+// has been scanned.
+func hostColumns(host *data.Host) ([]any, func()) {
+	var (
+		id, stamp, source, asn, allocated, accuracy int64
+		addr                                        string
+	)
 
-// HostAdd adds a new Host to the database.
-func (db *HostDB) HostAdd(host *data.Host) error {
-	var err error
-	var msg string
-	var stmt *sql.Stmt
-	var tx *sql.Tx
-	var adHoc bool
-
-GET_QUERY:
-	if stmt, err = db.getStatement(query.HostAdd); err != nil {
-		if db.worthARetry(err) {
-			time.Sleep(retryDelay)
-			goto GET_QUERY
-		} else {
-			msg = fmt.Sprintf("Error getting query STMT_HOST_ADD: %s", err.Error())
-			db.log.Println(msg)
-			return errors.New(msg)
-		}
-	} else if db.tx != nil {
-		tx = db.tx
-	} else {
-		adHoc = true
-	START_ADHOC_TX:
-		if tx, err = db.db.Begin(); err != nil {
-			if db.worthARetry(err) {
-				time.Sleep(retryDelay)
-				goto START_ADHOC_TX
-			} else {
-				msg = fmt.Sprintf("Error starting ad-hoc transaction: %s", err.Error())
-				db.log.Println(msg)
-				return errors.New(msg)
-			}
+	var dest = []any{
+		&id,
+		&addr,
+		&host.Name,
+		&host.Location,
+		&host.OS,
+		&host.OSVersion,
+		&host.OSConfidence,
+		&source,
+		&stamp,
+		&asn,
+		&host.AS.Org,
+		&host.AS.Prefix,
+		&host.AS.Registry,
+		&allocated,
+		&host.Geo.Country,
+		&host.Geo.Subdivision,
+		&host.Geo.City,
+		&host.Geo.Latitude,
+		&host.Geo.Longitude,
+		&accuracy,
+	}
+
+	var finish = func() {
+		host.ID = krylib.ID(id)
+		host.Source = data.HostSource(source)
+		host.Address = net.ParseIP(addr)
+		host.Added = time.Unix(stamp, 0)
+		host.AS.ASN = uint32(asn)
+		host.Geo.Accuracy = uint16(accuracy)
+
+		if allocated != 0 {
+			host.AS.Allocated = time.Unix(allocated, 0)
 		}
 	}
 
-	stmt = tx.Stmt(stmt)
-	now := time.Now()
+	return dest, finish
+} // func hostColumns(host *data.Host) ([]any, func())
+
+// scanHost reads a single Host from a result row. The columns are expected
+// in the order hostColumns describes.
+func scanHost(rows *sql.Rows) (*data.Host, error) {
+	var (
+		err          error
+		host         = new(data.Host)
+		dest, finish = hostColumns(host)
+	)
+
+	if err = rows.Scan(dest...); err != nil {
+		return nil, fmt.Errorf("Error scanning Host from row: %w", err)
+	}
+
+	finish()
+	return host, nil
+} // func scanHost(rows *sql.Rows) (*data.Host, error)
 
-	var res sql.Result
-	var id int64
+// HostAdd adds a new Host to the database.
+func (db *sqlDB) HostAdd(ctx context.Context, host *data.Host) error {
+	var (
+		err error
+		id  int64
+		now = time.Now()
+	)
 
-EXEC_QUERY:
-	res, err = stmt.Exec(
+	if id, err = db.insert(
+		ctx,
+		query.HostAdd,
 		host.Address.String(),
 		host.Name,
 		host.Source,
-		now.Unix())
-	if err != nil {
-		if db.worthARetry(err) {
-			time.Sleep(retryDelay)
-			goto EXEC_QUERY
-		} else {
-			msg = fmt.Sprintf("Error add host %s (%s) to database: %s",
-				host.Name, host.Address.String(), err.Error())
-			db.log.Println(msg)
-
-			if adHoc {
-				tx.Rollback() // nolint: errcheck
-			}
-
-			return errors.New(msg)
-		}
+		now.Unix()); err != nil {
+		err = fmt.Errorf("Error adding host %s (%s) to database: %w",
+			host.Name,
+			host.Address,
+			err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
 	}
 
+	host.ID = krylib.ID(id)
 	host.Added = now
-	if id, err = res.LastInsertId(); err != nil {
-		msg = fmt.Sprintf("Error getting ID of freshly added host %s (%s): %s",
-			host.Name, host.Address.String(), err.Error())
-		db.log.Println(msg)
-		if adHoc {
-			tx.Rollback() // nolint: errcheck
-		}
-		return errors.New(msg)
+
+	if err = db.addLinks(ctx, host, host.Address, host.Name, host.Source); err != nil {
+		return err
 	}
 
-	host.ID = krylib.ID(id)
 	db.hostCache.Add(host.Address.String(), cacheTimeout, true)
 
-	if adHoc {
-		tx.Commit() // nolint: errcheck
+	return nil
+} // func (db *sqlDB) HostAdd(ctx context.Context, host *data.Host) error
+
+// addLinks records that the Host is reachable at addr and known by name.
+// If the address or name are known for the Host already, only their
+// last-seen timestamps are updated.
+func (db *sqlDB) addLinks(ctx context.Context, host *data.Host, addr net.IP, name string, src data.HostSource) error {
+	var (
+		err error
+		now = time.Now().Unix()
+	)
+
+	if addr != nil {
+		if _, err = db.exec(
+			ctx,
+			query.HostAddressAdd,
+			host.ID,
+			addr.String(),
+			src,
+			now,
+			now); err != nil {
+			err = fmt.Errorf("Error adding address %s to host #%d: %w",
+				addr,
+				host.ID,
+				err)
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return err
+		}
+	}
+
+	if name != "" {
+		if _, err = db.exec(
+			ctx,
+			query.HostNameAdd,
+			host.ID,
+			name,
+			src,
+			now,
+			now); err != nil {
+			err = fmt.Errorf("Error adding name %s to host #%d: %w",
+				name,
+				host.ID,
+				err)
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return err
+		}
 	}
 
 	return nil
-} // func (db *HostDB) HostAdd(host *Host) error
+} // func (db *sqlDB) addLinks(ctx context.Context, host *data.Host, addr net.IP, name string, src data.HostSource) error
+
+// hostByName looks for the Host the given name belongs to, considering only
+// names that did not come from the HostGenerator: Those are the result of a
+// reverse lookup and tell us little about which addresses belong together.
+// If there is no such Host, or there is more than one, it returns
+// ErrNotFound.
+func (db *sqlDB) hostByName(ctx context.Context, name string) (krylib.ID, error) {
+	var (
+		err   error
+		rows  *sql.Rows
+		owner = krylib.INVALID_ID
+	)
 
-// HostGetByID loads a Host by its ID
-func (db *HostDB) HostGetByID(id krylib.ID) (*data.Host, error) {
-	var msg string
-	var err error
-	var stmt *sql.Stmt
-
-GET_QUERY:
-	if stmt, err = db.getStatement(query.HostGetByID); err != nil {
-		if db.worthARetry(err) {
-			time.Sleep(retryDelay)
-			goto GET_QUERY
-		} else {
-			msg = fmt.Sprintf("Error getting query STMT_HOST_GET_BY_ID: %s", err.Error())
-			db.log.Println(msg)
-			return nil, errors.New(msg)
+	if rows, err = db.query(ctx, query.HostNameLookup, name); err != nil {
+		err = fmt.Errorf("Error looking up hosts named %s: %w", name, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return krylib.INVALID_ID, err
+	}
+
+	defer rows.Close() // nolint: errcheck
+
+	for rows.Next() {
+		var id, src int64
+
+		if err = rows.Scan(&id, &src); err != nil {
+			err = fmt.Errorf("Error scanning row: %w", err)
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return krylib.INVALID_ID, err
+		} else if data.HostSource(src) == data.HostSourceGen {
+			continue
+		} else if owner != krylib.INVALID_ID && owner != krylib.ID(id) {
+			return krylib.INVALID_ID, fmt.Errorf("Name %s is ambiguous: %w",
+				name,
+				ErrNotFound)
 		}
-	} else if db.tx != nil {
-		stmt = db.tx.Stmt(stmt)
+
+		owner = krylib.ID(id)
+	}
+
+	if err = rows.Err(); err != nil {
+		err = fmt.Errorf("Error looking up hosts named %s: %w", name, classify(err))
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return krylib.INVALID_ID, err
+	} else if owner == krylib.INVALID_ID {
+		return krylib.INVALID_ID, fmt.Errorf("Name %s: %w", name, ErrNotFound)
 	}
 
-	var rows *sql.Rows
+	return owner, nil
+} // func (db *sqlDB) hostByName(ctx context.Context, name string) (krylib.ID, error)
+
+// HostUpsert adds a Host to the database, unless we know its address
+// already. It returns true if the Host was new.
+//
+// If the address is new, but the name is known from a zone transfer or the
+// user, the address is added to the Host the name belongs to, so a
+// multi-homed Host does not end up as several unrelated Hosts.
+//
+// If the Host exists, host is updated with the ID and the information
+// stored in the database, and its name is added to the names of the Host.
+// If host comes from a source that supersedes the one stored in the
+// database, the stored primary name and source are updated first.
+func (db *sqlDB) HostUpsert(ctx context.Context, host *data.Host) (bool, error) {
+	var (
+		err    error
+		id     int64
+		now    = time.Now()
+		addr   = host.Address.String()
+		owner  krylib.ID
+		stored *data.Host
+		res    sql.Result
+	)
+
+	if stored, err = db.fetchHost(ctx, query.HostGetByAddr, addr); err == nil {
+		goto EXISTS
+	} else if !errors.Is(err, ErrNotFound) {
+		err = fmt.Errorf("Error upserting host %s (%s): %w",
+			host.Name,
+			host.Address,
+			err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return false, err
+	} else if host.Source != data.HostSourceGen && host.Name != "" {
+		if owner, err = db.hostByName(ctx, host.Name); err == nil {
+			if stored, err = db.fetchHost(ctx, query.HostGetByID, owner); err != nil {
+				return false, err
+			}
 
-EXEC_QUERY:
-	if rows, err = stmt.Query(id); err != nil {
-		if db.worthARetry(err) {
-			time.Sleep(retryDelay)
-			goto EXEC_QUERY
-		} else {
-			msg = fmt.Sprintf("Error querying host by ID %d: %s",
-				id, err.Error())
-			db.log.Println(msg)
-			return nil, errors.New(msg)
+			goto EXISTS
+		} else if !errors.Is(err, ErrNotFound) {
+			return false, err
 		}
-	} else {
-		defer rows.Close()
 	}
 
-	if rows.Next() {
-		var host *data.Host = &data.Host{ID: id}
-		var addr string
-		var stamp int64
+	if id, err = db.insert(
+		ctx,
+		query.HostUpsert,
+		addr,
+		host.Name,
+		host.Source,
+		now.Unix()); err == nil {
+		host.ID = krylib.ID(id)
+		host.Added = now
 
-		if err = rows.Scan(&addr, &host.Name, &host.Location, &host.OS, &host.Source, &stamp); err != nil {
-			msg = fmt.Sprintf("Error scanning Host from row: %s",
-				err.Error())
-			db.log.Println(msg)
-			return nil, errors.New(msg)
+		if err = db.addLinks(ctx, host, host.Address, host.Name, host.Source); err != nil {
+			return false, err
 		}
 
-		host.Address = net.ParseIP(addr)
-		host.Added = time.Unix(stamp, 0)
-		return host, nil
+		db.hostCache.Add(addr, cacheTimeout, true)
+		return true, nil
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		err = fmt.Errorf("Error upserting host %s (%s): %w",
+			host.Name,
+			host.Address,
+			err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return false, err
+	} else if stored, err = db.fetchHost(ctx, query.HostGetByAddr, addr); err != nil {
+		// Someone else added the Host after we looked for it.
+		return false, err
 	}
 
-	return nil, nil
-} // func (db *HostDB) HostGetByID(id krylib.ID) (*Host, error)
-
-// HostGetAll returns ALL hosts from the database.
-func (db *HostDB) HostGetAll() ([]data.Host, error) {
-	const qid query.ID = query.HostGetAll
-	var err error
-	var msg string
-	var stmt *sql.Stmt
-	var hosts []data.Host
-
-GET_QUERY:
-	if stmt, err = db.getStatement(qid); err != nil {
-		if db.worthARetry(err) {
-			time.Sleep(retryDelay)
-			goto GET_QUERY
-		} else {
-			msg = fmt.Sprintf("Error getting query %s: %s",
-				qid,
-				err.Error())
-			db.log.Println(msg)
+EXISTS:
+	if host.Source.Supersedes(stored.Source) && host.Name != "" {
+		// If someone else updated the Host in the meantime, the WHERE
+		// clause will not match, and we leave their update alone.
+		if res, err = db.exec(
+			ctx,
+			query.HostUpdateSource,
+			host.Name,
+			host.Source,
+			stored.ID,
+			stored.Source); err != nil {
+			err = fmt.Errorf("Error updating source of host %s (%s): %w",
+				host.Name,
+				host.Address,
+				err)
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return false, err
+		} else if n, _ := res.RowsAffected(); n > 0 {
+			stored.Name = host.Name
+			stored.Source = host.Source
 		}
-	} else if db.tx != nil {
-		stmt = db.tx.Stmt(stmt)
 	}
 
-	var rows *sql.Rows
+	if err = db.addLinks(ctx, stored, host.Address, host.Name, host.Source); err != nil {
+		return false, err
+	}
 
-EXEC_QUERY:
-	if rows, err = stmt.Query(); err != nil {
-		if db.worthARetry(err) {
-			time.Sleep(retryDelay)
-			goto EXEC_QUERY
-		} else {
-			msg = fmt.Sprintf("Error querying all hosts: %s",
-				err.Error())
-			db.log.Println(msg)
-			return nil, errors.New(msg)
-		}
-	} else {
-		defer rows.Close()
+	*host = *stored
+	db.hostCache.Add(addr, cacheTimeout, true)
+	return false, nil
+} // func (db *sqlDB) HostUpsert(ctx context.Context, host *data.Host) (bool, error)
+
+// fetchHost runs a query that returns at most one Host.
+// If it returns none, ErrNotFound is returned.
+// The Host's names and addresses are not loaded.
+func (db *sqlDB) fetchHost(ctx context.Context, qid query.ID, args ...any) (*data.Host, error) {
+	var (
+		err  error
+		rows *sql.Rows
+		host *data.Host
+	)
+
+	if rows, err = db.query(ctx, qid, args...); err != nil {
+		err = fmt.Errorf("Error querying host %s: %w", fmt.Sprint(args...), err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
 	}
 
-	hosts = make([]data.Host, 0)
+	defer rows.Close() // nolint: errcheck
 
-	for rows.Next() {
-		var id, stamp, source int64
-		var host data.Host
-		var addrStr string
-
-	SCAN_ROW:
-		err = rows.Scan(
-			&id,
-			&addrStr,
-			&host.Name,
-			&host.Location,
-			&host.OS,
-			&source,
-			&stamp)
-		if err != nil {
-			if db.worthARetry(err) {
-				time.Sleep(retryDelay)
-				goto SCAN_ROW
-			} else {
-				msg = fmt.Sprintf("Error scanning row: %s", err.Error())
-				db.log.Println(msg)
-				return nil, errors.New(msg)
-			}
-		} else {
-			host.ID = krylib.ID(id)
-			host.Source = data.HostSource(source)
-			host.Address = net.ParseIP(addrStr)
-			host.Added = time.Unix(stamp, 0)
-			hosts = append(hosts, host)
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			err = fmt.Errorf("Error querying host %s: %w", fmt.Sprint(args...), classify(err))
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return nil, err
 		}
+
+		return nil, fmt.Errorf("Host %s: %w", fmt.Sprint(args...), ErrNotFound)
+	} else if host, err = scanHost(rows); err != nil {
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
 	}
 
-	return hosts, nil
-} // func (db *HostDB) HostGetAll() ([]data.Host, error)
+	return host, nil
+} // func (db *sqlDB) fetchHost(ctx context.Context, qid query.ID, args ...any) (*data.Host, error)
 
-// HostGetRandom fetches up to <max> randomly chosen hosts from the database.
-func (db *HostDB) HostGetRandom(max int) ([]data.Host, error) {
-	const qid query.ID = query.HostGetRandom
-	var err error
-	var msg string
-	var stmt *sql.Stmt
-	var hosts []data.Host
-
-GET_QUERY:
-	if stmt, err = db.getStatement(qid); err != nil {
-		if db.worthARetry(err) {
-			time.Sleep(retryDelay)
-			goto GET_QUERY
-		} else {
-			msg = fmt.Sprintf("Error getting query %s: %s",
-				qid,
-				err.Error())
-			db.log.Println(msg)
-		}
-	} else if db.tx != nil {
-		stmt = db.tx.Stmt(stmt)
+// loadLinks loads all the names and addresses of a Host.
+func (db *sqlDB) loadLinks(ctx context.Context, host *data.Host) error {
+	var (
+		err  error
+		rows *sql.Rows
+	)
+
+	if rows, err = db.query(ctx, query.HostAddressGetByHost, host.ID); err != nil {
+		err = fmt.Errorf("Error querying addresses of host #%d: %w", host.ID, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
 	}
 
-	var rows *sql.Rows
+	defer rows.Close() // nolint: errcheck
+
+	host.Addresses = make([]data.HostAddress, 0, 1)
 
-EXEC_QUERY:
-	if rows, err = stmt.Query(max); err != nil {
-		if db.worthARetry(err) {
-			time.Sleep(retryDelay)
-			goto EXEC_QUERY
-		} else {
-			msg = fmt.Sprintf("Error querying %d random hosts: %s",
-				max, err.Error())
-			db.log.Println(msg)
-			return nil, errors.New(msg)
+	for rows.Next() {
+		var (
+			addr               string
+			src, first, latest int64
+		)
+
+		if err = rows.Scan(&addr, &src, &first, &latest); err != nil {
+			err = fmt.Errorf("Error scanning row: %w", err)
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return err
 		}
-	} else {
-		defer rows.Close()
+
+		host.Addresses = append(host.Addresses, data.HostAddress{
+			Address:   net.ParseIP(addr),
+			Source:    data.HostSource(src),
+			FirstSeen: time.Unix(first, 0),
+			LastSeen:  time.Unix(latest, 0),
+		})
+	}
+
+	if err = rows.Err(); err != nil {
+		err = fmt.Errorf("Error querying addresses of host #%d: %w", host.ID, classify(err))
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	rows.Close() // nolint: errcheck
+
+	if rows, err = db.query(ctx, query.HostNameGetByHost, host.ID); err != nil {
+		err = fmt.Errorf("Error querying names of host #%d: %w", host.ID, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
 	}
 
-	hosts = make([]data.Host, max)
-	idx := 0
+	defer rows.Close() // nolint: errcheck
+
+	host.Names = make([]data.HostName, 0, 1)
 
 	for rows.Next() {
-		var id, stamp, source int64
-		var host data.Host
-		var addrStr string
-
-	SCAN_ROW:
-		err = rows.Scan(
-			&id,
-			&addrStr,
-			&host.Name,
-			&host.Location,
-			&host.OS,
-			&source,
-			&stamp)
-		if err != nil {
-			if db.worthARetry(err) {
-				time.Sleep(retryDelay)
-				goto SCAN_ROW
-			} else {
-				msg = fmt.Sprintf("Error scanning row: %s", err.Error())
-				db.log.Println(msg)
-				return nil, errors.New(msg)
-			}
-		} else {
-			host.ID = krylib.ID(id)
-			host.Source = data.HostSource(source)
-			host.Address = net.ParseIP(addrStr)
-			host.Added = time.Unix(stamp, 0)
-			hosts[idx] = host
-			idx++
+		var (
+			name               string
+			src, first, latest int64
+		)
+
+		if err = rows.Scan(&name, &src, &first, &latest); err != nil {
+			err = fmt.Errorf("Error scanning row: %w", err)
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return err
 		}
+
+		host.Names = append(host.Names, data.HostName{
+			Name:      name,
+			Source:    data.HostSource(src),
+			FirstSeen: time.Unix(first, 0),
+			LastSeen:  time.Unix(latest, 0),
+		})
 	}
 
-	if idx < max {
-		return hosts[0:idx], nil
+	if err = rows.Err(); err != nil {
+		err = fmt.Errorf("Error querying names of host #%d: %w", host.ID, classify(err))
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
 	}
 
-	return hosts, nil
-} // func (db *HostDB) HostGetRandom(max int) ([]Host, error)
+	return nil
+} // func (db *sqlDB) loadLinks(ctx context.Context, host *data.Host) error
 
-// HostExists checks if a Host with the given address already exists in the database.
-func (db *HostDB) HostExists(addr string) (bool, error) {
-	var err error
-	var msg string
-	var stmt *sql.Stmt
+// HostGetByID loads a Host by its ID, including all its names and
+// addresses.
+// If no such Host exists, ErrNotFound is returned.
+func (db *sqlDB) HostGetByID(ctx context.Context, id krylib.ID) (*data.Host, error) {
+	var (
+		err  error
+		host *data.Host
+	)
 
-	if _, err = db.hostCache.Value(addr); err == nil {
-		return true, nil
+	if host, err = db.fetchHost(ctx, query.HostGetByID, id); err != nil {
+		return nil, err
+	} else if err = db.loadLinks(ctx, host); err != nil {
+		return nil, err
 	}
 
-GET_QUERY:
-	if stmt, err = db.getStatement(query.HostExists); err != nil {
-		if db.worthARetry(err) {
-			time.Sleep(retryDelay)
-			goto GET_QUERY
-		} else {
-			msg = fmt.Sprintf("Error getting query STMT_HOST_EXISTS: %s",
-				err.Error())
-			db.log.Println(msg)
-			return false, errors.New(msg)
-		}
+	return host, nil
+} // func (db *sqlDB) HostGetByID(ctx context.Context, id krylib.ID) (*data.Host, error)
+
+// HostGetByAddr loads the Host that can be reached at the given address,
+// including all its names and addresses.
+// If no such Host exists, ErrNotFound is returned.
+func (db *sqlDB) HostGetByAddr(ctx context.Context, addr string) (*data.Host, error) {
+	var (
+		err  error
+		host *data.Host
+	)
+
+	if host, err = db.fetchHost(ctx, query.HostGetByAddr, addr); err != nil {
+		return nil, err
+	} else if err = db.loadLinks(ctx, host); err != nil {
+		return nil, err
 	}
 
-	var rows *sql.Rows
+	return host, nil
+} // func (db *sqlDB) HostGetByAddr(ctx context.Context, addr string) (*data.Host, error)
 
-EXEC_QUERY:
-	if rows, err = stmt.Query(addr); err != nil {
-		if db.worthARetry(err) {
-			time.Sleep(retryDelay)
-			goto EXEC_QUERY
-		} else {
-			msg = fmt.Sprintf("Error querying if host %s is already in the database: %s",
-				addr, err.Error())
-			db.log.Println(msg)
-			return false, errors.New(msg)
-		}
-	} else {
-		defer rows.Close()
-	}
-
-	if rows.Next() {
-		var cnt int64
-
-		if err = rows.Scan(&cnt); err != nil {
-			msg = fmt.Sprintf("Error scanning row: %s",
-				err.Error())
-			db.log.Println(msg)
-			return false, errors.New(msg)
-		} else if cnt > 0 {
-			db.hostCache.Add(addr, cacheTimeout, true)
-			return true, nil
-		} else {
-			return false, nil
-		}
-	} else {
-		msg = fmt.Sprintf("CANTHAPPEN: No result rows looking for presence of host %s!",
-			addr)
-		db.log.Println(msg)
-		return false, errors.New(msg)
+// HostGetAll returns ALL hosts from the database.
+func (db *sqlDB) HostGetAll(ctx context.Context) ([]data.Host, error) {
+	var (
+		err   error
+		rows  *sql.Rows
+		hosts []data.Host
+	)
+
+	if rows, err = db.query(ctx, query.HostGetAll); err != nil {
+		err = fmt.Errorf("Error querying all hosts: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
 	}
-} // func (db *HostDB) HostExists(addr string) (bool, error)
 
-// XfrAdd starts a new zone transfer
-func (db *HostDB) XfrAdd(xfr *data.XFR) error {
-	var err error
-	var msg string
-	var stmt *sql.Stmt
-	var tx *sql.Tx
-	var adHoc bool
-
-GET_STATEMENT:
-	if stmt, err = db.getStatement(query.XfrAdd); err != nil {
-		if db.worthARetry(err) {
-			time.Sleep(retryDelay)
-			goto GET_STATEMENT
-		} else {
-			msg = fmt.Sprintf("Error getting query STMT_XFR_ADD: %s", err.Error())
-			db.log.Println(msg)
-			return errors.New(msg)
-		}
-	} else if db.tx == nil {
-		adHoc = true
-	BEGIN_ADHOC:
-		if tx, err = db.db.Begin(); err != nil {
-			if db.worthARetry(err) {
-				time.Sleep(retryDelay)
-				goto BEGIN_ADHOC
-			} else {
-				msg = fmt.Sprintf("Error starting ad-hoc transaction for adding XFR of %s: %s",
-					xfr.Zone, err.Error())
-				db.log.Println(msg)
-				return errors.New(msg)
-			}
+	defer rows.Close() // nolint: errcheck
+
+	hosts = make([]data.Host, 0)
+
+	for rows.Next() {
+		var host *data.Host
+
+		if host, err = scanHost(rows); err != nil {
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return nil, err
 		}
-	} else {
-		tx = db.tx
+
+		hosts = append(hosts, *host)
 	}
 
-	stmt = tx.Stmt(stmt)
+	if err = rows.Err(); err != nil {
+		err = fmt.Errorf("Error querying all hosts: %w", classify(err))
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
 
-	var result sql.Result
+	return hosts, nil
+} // func (db *sqlDB) HostGetAll(ctx context.Context) ([]data.Host, error)
 
-EXEC_QUERY:
-	if result, err = stmt.Exec(xfr.Zone, xfr.Start.Unix()); err != nil {
-		if db.worthARetry(err) {
-			time.Sleep(retryDelay)
-			goto EXEC_QUERY
-		} else {
-			msg = fmt.Sprintf("Error adding XFR for %s: %s",
-				xfr.Zone, err.Error())
-			db.log.Println(msg)
+// HostGetRandom fetches up to <max> randomly chosen hosts from the database.
+func (db *sqlDB) HostGetRandom(ctx context.Context, max int) ([]data.Host, error) {
+	var (
+		err   error
+		rows  *sql.Rows
+		hosts []data.Host
+	)
 
-			if adHoc {
-				tx.Rollback() // nolint: errcheck
-			}
+	if rows, err = db.query(ctx, query.HostGetRandom, max); err != nil {
+		err = fmt.Errorf("Error querying %d random hosts: %w", max, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
 
-			return errors.New(msg)
-		}
-	} else {
-		var id int64
+	defer rows.Close() // nolint: errcheck
 
-		if id, err = result.LastInsertId(); err != nil {
-			msg = fmt.Sprintf("Error getting ID of freshly added XFR (%s): %s",
-				xfr.Zone, err.Error())
-			db.log.Println(msg)
+	hosts = make([]data.Host, 0, max)
 
-			if adHoc {
-				tx.Rollback() // nolint: errcheck
-			}
+	for rows.Next() {
+		var host *data.Host
 
-			return errors.New(msg)
+		if host, err = scanHost(rows); err != nil {
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return nil, err
 		}
 
-		xfr.ID = krylib.ID(id)
-		if adHoc {
-			tx.Commit() // nolint: errcheck
-		}
-		return nil
+		hosts = append(hosts, *host)
 	}
-} // func (db *HostDB) XfrAdd(xfr *XFR) error
 
-// XfrFinish marks a zone transfer as finished.
-func (db *HostDB) XfrFinish(xfr *data.XFR, status xfrstatus.XfrStatus) error {
-	var msg string
-	var err error
-	var stmt *sql.Stmt
-	var tx *sql.Tx
-	var adHoc bool
-
-GET_QUERY:
-	if stmt, err = db.getStatement(query.XfrFinish); err != nil {
-		if db.worthARetry(err) {
-			time.Sleep(retryDelay)
-			goto GET_QUERY
-		} else {
-			msg = fmt.Sprintf("Error getting query STMT_XFR_FINISH: %s", err.Error())
-			db.log.Println(msg)
-			return errors.New(msg)
-		}
-	} else if db.tx == nil {
-		adHoc = true
-	BEGIN_ADHOC:
-		if tx, err = db.db.Begin(); err != nil {
-			if db.worthARetry(err) {
-				time.Sleep(retryDelay)
-				goto BEGIN_ADHOC
-			} else {
-				msg = fmt.Sprintf("Error starting ad-hoc transaction for finishing XFR of %s: %s",
-					xfr.Zone, err.Error())
-				db.log.Println(msg)
-				return errors.New(msg)
-			}
-		}
-	} else {
-		tx = db.tx
+	if err = rows.Err(); err != nil {
+		err = fmt.Errorf("Error querying %d random hosts: %w", max, classify(err))
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
 	}
 
-	stmt = tx.Stmt(stmt)
+	return hosts, nil
+} // func (db *sqlDB) HostGetRandom(ctx context.Context, max int) ([]data.Host, error)
 
-	var now = time.Now()
+// HostExists checks if a Host with the given address already exists in the database.
+func (db *sqlDB) HostExists(ctx context.Context, addr string) (bool, error) {
+	var (
+		err  error
+		rows *sql.Rows
+		cnt  int64
+	)
 
-EXEC_QUERY:
-	if _, err = stmt.Exec(now.Unix(), status, xfr.ID); err != nil {
-		if db.worthARetry(err) {
-			time.Sleep(retryDelay)
-			goto EXEC_QUERY
-		} else {
-			msg = fmt.Sprintf("Error finishing XFR of %s (%d): %s",
-				xfr.Zone, xfr.ID, err.Error())
-			db.log.Println(msg)
-			if adHoc {
-				tx.Rollback() // nolint: errcheck
-			}
-			return errors.New(msg)
-		}
-	} else if adHoc {
-		tx.Commit() // nolint: errcheck
+	if _, err = db.hostCache.Value(addr); err == nil {
+		return true, nil
+	} else if rows, err = db.query(ctx, query.HostExists, addr); err != nil {
+		err = fmt.Errorf("Error querying if host %s is already in the database: %w",
+			addr,
+			err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return false, err
+	}
+
+	defer rows.Close() // nolint: errcheck
+
+	if !rows.Next() {
+		err = fmt.Errorf("CANTHAPPEN: No result rows looking for presence of host %s: %v",
+			addr,
+			rows.Err())
+		db.log.Printf("[CANTHAPPEN] %s\n", err.Error())
+		return false, err
+	} else if err = rows.Scan(&cnt); err != nil {
+		err = fmt.Errorf("Error scanning row: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return false, err
+	} else if cnt > 0 {
+		db.hostCache.Add(addr, cacheTimeout, true)
+		return true, nil
+	}
+
+	return false, nil
+} // func (db *sqlDB) HostExists(ctx context.Context, addr string) (bool, error)
+
+// XfrAdd starts a new zone transfer
+func (db *sqlDB) XfrAdd(ctx context.Context, xfr *data.XFR) error {
+	var (
+		err error
+		id  int64
+	)
+
+	if id, err = db.insert(ctx, query.XfrAdd, xfr.Zone, xfr.Start.Unix()); err != nil {
+		err = fmt.Errorf("Error adding XFR for %s: %w", xfr.Zone, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	xfr.ID = krylib.ID(id)
+	return nil
+} // func (db *sqlDB) XfrAdd(ctx context.Context, xfr *data.XFR) error
+
+// XfrFinish marks a zone transfer as finished.
+func (db *sqlDB) XfrFinish(ctx context.Context, xfr *data.XFR, status xfrstatus.XfrStatus) error {
+	var (
+		err error
+		now = time.Now()
+	)
+
+	if _, err = db.exec(ctx, query.XfrFinish, now.Unix(), status, xfr.ID); err != nil {
+		err = fmt.Errorf("Error finishing XFR of %s (%d): %w",
+			xfr.Zone,
+			xfr.ID,
+			err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
 	}
 
 	xfr.End = now
+	xfr.Status = status
 	return nil
-} // func (db *HostDB) XfrFinish(xfr *XFR, status XfrStatus) error
+} // func (db *sqlDB) XfrFinish(ctx context.Context, xfr *data.XFR, status xfrstatus.XfrStatus) error
 
 // XfrGetByZone fetches an Xfr by the zone's name.
-func (db *HostDB) XfrGetByZone(zone string) (*data.XFR, error) {
-	var msg string
-	var err error
-	var stmt *sql.Stmt
-
-GET_QUERY:
-	if stmt, err = db.getStatement(query.XfrGetByZone); err != nil {
-		if db.worthARetry(err) {
-			time.Sleep(retryDelay)
-			goto GET_QUERY
-		} else {
-			msg = fmt.Sprintf("Error getting query STMT_XFR_GET_BY_ZONE: %s", err.Error())
-			db.log.Println(msg)
-			return nil, errors.New(msg)
-		}
-	} else if db.tx != nil {
-		stmt = db.tx.Stmt(stmt)
+// If no XFR of the zone exists, ErrNotFound is returned.
+func (db *sqlDB) XfrGetByZone(ctx context.Context, zone string) (*data.XFR, error) {
+	var (
+		err                    error
+		rows                   *sql.Rows
+		id, start, end, status int64
+	)
+
+	if rows, err = db.query(ctx, query.XfrGetByZone, zone); err != nil {
+		err = fmt.Errorf("Error querying XFR for %s: %w", zone, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
 	}
 
-	var rows *sql.Rows
+	defer rows.Close() // nolint: errcheck
 
-EXEC_QUERY:
-	if rows, err = stmt.Query(zone); err != nil {
-		if db.worthARetry(err) {
-			time.Sleep(retryDelay)
-			goto EXEC_QUERY
-		} else {
-			msg = fmt.Sprintf("Error querying XFR for %s: %s",
-				zone, err.Error())
-			db.log.Println(msg)
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			err = fmt.Errorf("Error querying XFR for %s: %w", zone, classify(err))
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return nil, err
 		}
-	} else {
-		defer rows.Close()
+
+		return nil, fmt.Errorf("XFR of %s: %w", zone, ErrNotFound)
+	} else if err = rows.Scan(&id, &start, &end, &status); err != nil {
+		err = fmt.Errorf("Error scanning row into result: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
 	}
 
-	if rows.Next() {
-		xfr := &data.XFR{Zone: zone}
-		var id, start, end, status int64
+	var xfr = &data.XFR{
+		ID:     krylib.ID(id),
+		Zone:   zone,
+		Start:  time.Unix(start, 0),
+		End:    time.Unix(end, 0),
+		Status: xfrstatus.XfrStatus(status),
+	}
+
+	return xfr, nil
+} // func (db *sqlDB) XfrGetByZone(ctx context.Context, zone string) (*data.XFR, error)
+
+// XfrGetPage returns the given page of zone transfers, the most recent
+// first, along with the total number of matching transfers. If status is
+// negative, transfers with any status are returned.
+func (db *sqlDB) XfrGetPage(ctx context.Context, status xfrstatus.XfrStatus, page, perPage int) ([]data.XFR, int64, error) {
+	var (
+		err   error
+		rows  *sql.Rows
+		total int64
+		list  []data.XFR
+	)
+
+	page, perPage = pageBounds(page, perPage)
+
+	if total, err = db.count(ctx, query.XfrGetPageCnt, int64(status)); err != nil {
+		err = fmt.Errorf("Error counting XFRs: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, 0, err
+	} else if rows, err = db.query(ctx, query.XfrGetPage, int64(status), perPage, (page-1)*perPage); err != nil {
+		err = fmt.Errorf("Error querying XFRs: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, 0, err
+	}
 
-		if err = rows.Scan(&id, &start, &end, &status); err != nil {
-			msg = fmt.Sprintf("Error scanning row into result: %s", err.Error())
-			db.log.Println(msg)
-			return nil, errors.New(msg)
+	defer rows.Close() // nolint: errcheck
+
+	list = make([]data.XFR, 0, perPage)
+
+	for rows.Next() {
+		var (
+			id, start, end, st int64
+			xfr                data.XFR
+		)
+
+		if err = rows.Scan(&id, &xfr.Zone, &start, &end, &st); err != nil {
+			err = fmt.Errorf("Error scanning row into XFR: %w", err)
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return nil, 0, err
 		}
 
 		xfr.ID = krylib.ID(id)
 		xfr.Start = time.Unix(start, 0)
 		xfr.End = time.Unix(end, 0)
-		xfr.Status = xfrstatus.XfrStatus(status)
-		return xfr, nil
+		xfr.Status = xfrstatus.XfrStatus(st)
+		list = append(list, xfr)
 	}
 
-	db.log.Printf("No XFR found for %s\n", zone)
-	return nil, nil
-} // func (db *HostDB) XfrGetByZone(zone string) (*XFR, error)
+	if err = rows.Err(); err != nil {
+		err = fmt.Errorf("Error querying XFRs: %w", classify(err))
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, 0, err
+	}
 
-// PortAdd adds a new scanned port to the database.
-func (db *HostDB) PortAdd(res *data.ScanResult) error {
-	var err error
-	var msg string
-	var stmt *sql.Stmt
-	var tx *sql.Tx
-	var adHoc bool
-
-GET_QUERY:
-	if stmt, err = db.getStatement(query.PortAdd); err != nil {
-		if db.worthARetry(err) {
-			time.Sleep(retryDelay)
-			goto GET_QUERY
-		} else {
-			msg = fmt.Sprintf("Error getting query STMT_PORT_ADD: %s",
-				err.Error())
-			db.log.Println(msg)
-			return errors.New(msg)
-		}
-	} else if db.tx != nil {
-		tx = db.tx
-	} else {
-		adHoc = true
-	BEGIN_ADHOC:
-		if tx, err = db.db.Begin(); err != nil {
-			if db.worthARetry(err) {
-				time.Sleep(retryDelay)
-				goto BEGIN_ADHOC
-			} else {
-				msg = fmt.Sprintf("Error starting ad-hoc TX for adding Port: %s",
-					err.Error())
-				db.log.Println(msg)
-				return errors.New(msg)
-			}
-		}
+	return list, total, nil
+} // func (db *sqlDB) XfrGetPage(ctx context.Context, status xfrstatus.XfrStatus, page, perPage int) ([]data.XFR, int64, error)
+
+// XfrGetByName returns the zone transfer of the most specific zone the given
+// host name belongs to. If there is none, ErrNotFound is returned.
+func (db *sqlDB) XfrGetByName(ctx context.Context, name string) (*data.XFR, error) {
+	var (
+		err                    error
+		rows                   *sql.Rows
+		id, start, end, status int64
+		xfr                    data.XFR
+	)
+
+	name = strings.TrimSuffix(name, ".")
+
+	if rows, err = db.query(ctx, query.XfrGetByName, name); err != nil {
+		err = fmt.Errorf("Error querying XFR for %s: %w", name, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
 	}
 
-	stmt = tx.Stmt(stmt)
+	defer rows.Close() // nolint: errcheck
 
-EXEC_QUERY:
-	if _, err = stmt.Exec(res.Host.ID, res.Port, res.Stamp.Unix(), res.Reply); err != nil {
-		if db.worthARetry(err) {
-			time.Sleep(retryDelay)
-			goto EXEC_QUERY
-		} else {
-			msg = fmt.Sprintf("Error adding ScanResult to database: %s",
-				err.Error())
-			db.log.Println(msg)
-			if adHoc {
-				tx.Rollback() // nolint: errcheck
-			}
-			return errors.New(msg)
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			err = fmt.Errorf("Error querying XFR for %s: %w", name, classify(err))
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return nil, err
 		}
-	} else if adHoc {
-		tx.Commit() // nolint: errcheck
+
+		return nil, fmt.Errorf("XFR containing %s: %w", name, ErrNotFound)
+	} else if err = rows.Scan(&id, &xfr.Zone, &start, &end, &status); err != nil {
+		err = fmt.Errorf("Error scanning row into XFR: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	xfr.ID = krylib.ID(id)
+	xfr.Start = time.Unix(start, 0)
+	xfr.End = time.Unix(end, 0)
+	xfr.Status = xfrstatus.XfrStatus(status)
+
+	return &xfr, nil
+} // func (db *sqlDB) XfrGetByName(ctx context.Context, name string) (*data.XFR, error)
+
+// PortAdd adds a new scanned port to the database. If the port has been
+// scanned before, the earlier result is replaced. Either way, the metadata
+// of the Host are flagged as outdated.
+func (db *sqlDB) PortAdd(ctx context.Context, res *data.ScanResult) error {
+	var err error
+
+	if _, err = db.exec(
+		ctx,
+		query.PortAdd,
+		res.Host.ID,
+		res.Port,
+		res.Stamp.Unix(),
+		res.Reply,
+		res.Software.Service,
+		res.Software.Product,
+		res.Software.Version,
+		res.Software.Info,
+		res.Software.OS,
+		res.Software.CPE); err != nil {
+		err = fmt.Errorf("Error adding ScanResult for %s:%d to database: %w",
+			res.Host.Address,
+			res.Port,
+			err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	// The metadata of the Host depend on its Ports, so they need to be
+	// updated.
+	if err = db.HostMarkDirty(ctx, res.Host.ID); err != nil {
+		return err
 	}
 
 	return nil
-} // func (db *HostDB) PortAdd(res *ScanResult) error
+} // func (db *sqlDB) PortAdd(ctx context.Context, res *data.ScanResult) error
 
 // PortGetByHost loads all the scanned ports of a given Host.
-func (db *HostDB) PortGetByHost(hostID krylib.ID) ([]data.Port, error) {
-	var err error
-	var msg string
-	var stmt *sql.Stmt
-	var rows *sql.Rows
-	var ports []data.Port
-
-GET_QUERY:
-	if stmt, err = db.getStatement(query.PortGetByHost); err != nil {
-		if db.worthARetry(err) {
-			time.Sleep(retryDelay)
-			goto GET_QUERY
-		} else {
-			msg = fmt.Sprintf("Error getting query STMT_PORT_GET_BY_HOST: %s",
-				err.Error())
-			db.log.Println(msg)
-			return nil, errors.New(msg)
-		}
-	} else if db.tx != nil {
-		stmt = db.tx.Stmt(stmt)
-	}
-
-EXEC_QUERY:
-	if rows, err = stmt.Query(hostID); err != nil {
-		if db.worthARetry(err) {
-			time.Sleep(retryDelay)
-			goto EXEC_QUERY
-		} else {
-			msg = fmt.Sprintf("Error querying ports for Host #%d: %s",
-				hostID, err.Error())
-			db.log.Println(msg)
-			return nil, errors.New(msg)
-		}
-	} else {
-		defer rows.Close()
-		ports = make([]data.Port, 0)
+func (db *sqlDB) PortGetByHost(ctx context.Context, hostID krylib.ID) ([]data.Port, error) {
+	var (
+		err   error
+		rows  *sql.Rows
+		ports []data.Port
+	)
+
+	if rows, err = db.query(ctx, query.PortGetByHost, hostID); err != nil {
+		err = fmt.Errorf("Error querying ports for Host #%d: %w", hostID, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
 	}
 
+	defer rows.Close() // nolint: errcheck
+
+	ports = make([]data.Port, 0)
+
 	for rows.Next() {
-		var portID, stamp int64
-		var port data.Port = data.Port{
-			HostID: hostID,
+		var (
+			portID, stamp int64
+			port          = data.Port{HostID: hostID}
+		)
+
+		if err = rows.Scan(
+			&portID,
+			&port.Port,
+			&stamp,
+			&port.Reply,
+			&port.Software.Service,
+			&port.Software.Product,
+			&port.Software.Version,
+			&port.Software.Info,
+			&port.Software.OS,
+			&port.Software.CPE); err != nil {
+			err = fmt.Errorf("Error scanning result row into Port: %w", err)
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return nil, err
 		}
 
-	SCAN_ROW:
-		if err = rows.Scan(&portID, &port.Port, &stamp, &port.Reply); err != nil {
-			if db.worthARetry(err) {
-				time.Sleep(retryDelay)
-				goto SCAN_ROW
-			} else {
-				msg = fmt.Sprintf("Error scanning result row into Port: %s",
-					err.Error())
-				db.log.Println(msg)
-				return nil, errors.New(msg)
-			}
-		} else {
-			port.ID = krylib.ID(portID)
-			port.Timestamp = time.Unix(stamp, 0)
-			ports = append(ports, port)
-		}
+		port.ID = krylib.ID(portID)
+		port.Timestamp = time.Unix(stamp, 0)
+		ports = append(ports, port)
+	}
+
+	if err = rows.Err(); err != nil {
+		err = fmt.Errorf("Error querying ports for Host #%d: %w", hostID, classify(err))
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
 	}
 
 	return ports, nil
-} // func (db *HostDB) PortGetByHost(id krylib.ID) ([]Port, error)
+} // func (db *sqlDB) PortGetByHost(ctx context.Context, hostID krylib.ID) ([]data.Port, error)
 
-// PortGetReplyCount returns the number of open ports found on the given Host
-func (db *HostDB) PortGetReplyCount() (int64, error) {
-	var msg string
-	var err error
-	var stmt *sql.Stmt
-	var rows *sql.Rows
-	var cnt int64
-
-GET_QUERY:
-	if stmt, err = db.getStatement(query.PortGetReplyCnt); err != nil {
-		if db.worthARetry(err) {
-			time.Sleep(retryDelay)
-			goto GET_QUERY
-		} else {
-			msg = fmt.Sprintf("Error getting query STMT_PORT_GET_REPLY_CNT: %s",
-				err.Error())
-			db.log.Println(msg)
-			return 0, errors.New(msg)
-		}
-	} else if db.tx != nil {
-		stmt = db.tx.Stmt(stmt)
-	}
-
-EXEC_QUERY:
-	if rows, err = stmt.Query(); err != nil {
-		if db.worthARetry(err) {
-			time.Sleep(retryDelay)
-			goto EXEC_QUERY
-		} else {
-			msg = fmt.Sprintf("Error querying number of scanned open ports: %s",
-				err.Error())
-			db.log.Println(msg)
-			return 0, errors.New(msg)
-		}
-	} else {
-		defer rows.Close()
+// count runs a query that returns a single integer, such as a COUNT(*).
+func (db *sqlDB) count(ctx context.Context, qid query.ID, args ...any) (int64, error) {
+	var (
+		err  error
+		rows *sql.Rows
+		cnt  int64
+	)
+
+	if rows, err = db.query(ctx, qid, args...); err != nil {
+		return 0, err
 	}
 
-	rows.Next()
+	defer rows.Close() // nolint: errcheck
 
-	if err = rows.Scan(&cnt); err != nil {
-		msg = fmt.Sprintf("Error scanning result set for number of scanned ports: %s",
-			err.Error())
-		db.log.Println(msg)
-		return 0, errors.New(msg)
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return 0, classify(err)
+		}
+
+		return 0, ErrNotFound
+	} else if err = rows.Scan(&cnt); err != nil {
+		return 0, err
 	}
 
 	return cnt, nil
-} // func (db *HostDB) PortGetReplyCount() (int64, error)
+} // func (db *sqlDB) count(ctx context.Context, qid query.ID, args ...any) (int64, error)
 
-// PortGetOpen loads a list of all open ports that were scanned
-func (db *HostDB) PortGetOpen() ([]data.ScanResult, error) {
-	var msg string
-	var err error
-	var stmt *sql.Stmt
-	var rows *sql.Rows
-	var result []data.ScanResult
-	var hostCache map[krylib.ID]*data.Host
-
-GET_QUERY:
-	if stmt, err = db.getStatement(query.PortGetOpen); err != nil {
-		if db.worthARetry(err) {
-			time.Sleep(retryDelay)
-			goto GET_QUERY
-		} else {
-			msg = fmt.Sprintf("Error getting query STMT_PORT_GET_OPEN: %s", err.Error())
-			db.log.Println(msg)
-			return nil, errors.New(msg)
-		}
-	} else if db.tx != nil {
-		stmt = db.tx.Stmt(stmt)
-	}
-
-EXEC_QUERY:
-	if rows, err = stmt.Query(); err != nil {
-		if db.worthARetry(err) {
-			time.Sleep(retryDelay)
-			goto EXEC_QUERY
-		} else {
-			msg = fmt.Sprintf("Error querying for open ports: %s",
-				err.Error())
-			db.log.Println(msg)
-			return nil, errors.New(msg)
-		}
-	} else {
-		defer rows.Close()
+// PortGetReplyCount returns the number of open ports found on the given Host
+func (db *sqlDB) PortGetReplyCount(ctx context.Context) (int64, error) {
+	var (
+		err error
+		cnt int64
+	)
+
+	if cnt, err = db.count(ctx, query.PortGetReplyCnt); err != nil {
+		err = fmt.Errorf("Error querying number of scanned open ports: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return 0, err
 	}
 
-	result = make([]data.ScanResult, 0)
-	hostCache = make(map[krylib.ID]*data.Host)
+	return cnt, nil
+} // func (db *sqlDB) PortGetReplyCount(ctx context.Context) (int64, error)
+
+// loadScanResults reads a list of ScanResults from rows with the columns
+// id, host_id, port, timestamp, reply, looking up each Host only once.
+func (db *sqlDB) loadScanResults(ctx context.Context, rows *sql.Rows) ([]data.ScanResult, error) {
+	var (
+		err       error
+		result    = make([]data.ScanResult, 0)
+		hostCache = make(map[krylib.ID]*data.Host)
+	)
 
 	for rows.Next() {
-		var id, hostID, timestamp, port int64
-		var reply string
+		var (
+			id, hostID, timestamp, port int64
+			reply                       string
+			host                        *data.Host
+			ok                          bool
+		)
 
-	SCAN_ROW:
 		if err = rows.Scan(&id, &hostID, &port, &timestamp, &reply); err != nil {
-			if db.worthARetry(err) {
-				time.Sleep(retryDelay)
-				goto SCAN_ROW
-			} else {
-				msg = fmt.Sprintf("Error scanning result set: %s",
+			return nil, fmt.Errorf("Error scanning result set: %w", err)
+		} else if host, ok = hostCache[krylib.ID(hostID)]; !ok {
+			if host, err = db.HostGetByID(ctx, krylib.ID(hostID)); err != nil {
+				// CANTHAPPEN!!!
+				db.log.Printf("[CANTHAPPEN] Error looking up host for port #%d: %s\n",
+					id,
 					err.Error())
-				db.log.Println(msg)
-				return nil, errors.New(msg)
-			}
-		} else {
-			var host *data.Host // = new(Host)
-			var ok bool
-			if host, ok = hostCache[krylib.ID(hostID)]; !ok {
-				if host, err = db.HostGetByID(krylib.ID(hostID)); err != nil {
-					// CANTHAPPEN!!!
-					msg = fmt.Sprintf("CANTHAPPEN: Error looking up host for port: %s",
-						err.Error())
-					db.log.Println(msg)
-					continue
-				} else if host == nil {
-					// CANTHAPPEN!!!
-					db.log.Printf("CANTHAPPEN: Did not find host for port #%d (Host #%d)\n",
-						id, hostID)
-					continue
-				} else {
-					hostCache[krylib.ID(hostID)] = host
-				}
+				continue
 			}
 
-			var res data.ScanResult = data.ScanResult{
-				Host:  *host,
-				Port:  uint16(port),
-				Reply: &reply,
-				Stamp: time.Unix(timestamp, 0),
-				Err:   nil,
-			}
+			hostCache[krylib.ID(hostID)] = host
+		}
 
-			result = append(result, res)
+		var res = data.ScanResult{
+			Host:  *host,
+			Port:  uint16(port),
+			Reply: &reply,
+			Stamp: time.Unix(timestamp, 0),
 		}
+
+		result = append(result, res)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, classify(err)
 	}
 
 	return result, nil
-} // func (db *HostDB) PortGetOpen() ([]ScanResult, error)
+} // func (db *sqlDB) loadScanResults(ctx context.Context, rows *sql.Rows) ([]data.ScanResult, error)
+
+// PortGetOpen loads a list of all open ports that were scanned.
+// If tag is not empty, only the ports of Hosts with that Tag are returned.
+func (db *sqlDB) PortGetOpen(ctx context.Context, tag string) ([]data.ScanResult, error) {
+	var (
+		err    error
+		rows   *sql.Rows
+		result []data.ScanResult
+	)
+
+	if rows, err = db.queryOpenPorts(ctx, tag); err != nil {
+		err = fmt.Errorf("Error querying for open ports: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck
+
+	if result, err = db.loadScanResults(ctx, rows); err != nil {
+		err = fmt.Errorf("Error loading open ports: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	return result, nil
+} // func (db *sqlDB) PortGetOpen(ctx context.Context, tag string) ([]data.ScanResult, error)
+
+// queryOpenPorts queries all open ports, or those of the Hosts with the
+// given Tag.
+func (db *sqlDB) queryOpenPorts(ctx context.Context, tag string) (*sql.Rows, error) {
+	if tag == "" {
+		return db.query(ctx, query.PortGetOpen)
+	}
+
+	return db.query(ctx, query.PortGetOpenByTag, strings.ToLower(tag))
+} // func (db *sqlDB) queryOpenPorts(ctx context.Context, tag string) (*sql.Rows, error)
 
 // PortGetRecent returns all scanned ports that were scanned since the given time.
-func (db *HostDB) PortGetRecent(ref time.Time) ([]data.ScanResult, error) {
-	const qid = query.PortGetRecent
+func (db *sqlDB) PortGetRecent(ctx context.Context, ref time.Time) ([]data.ScanResult, error) {
 	var (
-		msg       string
-		err       error
-		stmt      *sql.Stmt
-		rows      *sql.Rows
-		result    []data.ScanResult
-		hostCache map[krylib.ID]*data.Host
+		err    error
+		rows   *sql.Rows
+		result []data.ScanResult
 	)
 
-GET_QUERY:
-	if stmt, err = db.getStatement(qid); err != nil {
-		if db.worthARetry(err) {
-			time.Sleep(retryDelay)
-			goto GET_QUERY
-		} else {
-			msg = fmt.Sprintf("Error getting query %s: %s", qid, err.Error())
-			db.log.Println(msg)
-			return nil, errors.New(msg)
-		}
-	} else if db.tx != nil {
-		stmt = db.tx.Stmt(stmt)
-	}
-
-EXEC_QUERY:
-	if rows, err = stmt.Query(ref.Unix()); err != nil {
-		if db.worthARetry(err) {
-			time.Sleep(retryDelay)
-			goto EXEC_QUERY
-		} else {
-			msg = fmt.Sprintf("Error querying for open ports: %s",
-				err.Error())
-			db.log.Println(msg)
-			return nil, errors.New(msg)
-		}
-	} else {
-		defer rows.Close()
+	if rows, err = db.query(ctx, query.PortGetRecent, ref.Unix()); err != nil {
+		err = fmt.Errorf("Error querying for recently scanned ports: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
 	}
 
-	result = make([]data.ScanResult, 0)
-	hostCache = make(map[krylib.ID]*data.Host)
+	defer rows.Close() // nolint: errcheck
 
-	for rows.Next() {
-		var id, hostID, timestamp, port int64
-		var reply string
+	if result, err = db.loadScanResults(ctx, rows); err != nil {
+		err = fmt.Errorf("Error loading recently scanned ports: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
 
-	SCAN_ROW:
-		if err = rows.Scan(&id, &hostID, &port, &timestamp, &reply); err != nil {
-			if db.worthARetry(err) {
-				time.Sleep(retryDelay)
-				goto SCAN_ROW
-			} else {
-				msg = fmt.Sprintf("Error scanning result set: %s",
-					err.Error())
-				db.log.Println(msg)
-				return nil, errors.New(msg)
-			}
-		} else {
-			var host *data.Host // = new(Host)
-			var ok bool
-			if host, ok = hostCache[krylib.ID(hostID)]; !ok {
-				if host, err = db.HostGetByID(krylib.ID(hostID)); err != nil {
-					// CANTHAPPEN!!!
-					msg = fmt.Sprintf("CANTHAPPEN: Error looking up host for port: %s",
-						err.Error())
-					db.log.Println(msg)
-					continue
-				} else if host == nil {
-					// CANTHAPPEN!!!
-					db.log.Printf("CANTHAPPEN: Did not find host for port #%d (Host #%d)\n",
-						id, hostID)
-					continue
-				} else {
-					hostCache[krylib.ID(hostID)] = host
-				}
-			}
+	return result, nil
+} // func (db *sqlDB) PortGetRecent(ctx context.Context, ref time.Time) ([]data.ScanResult, error)
+
+// PortFilter restricts the Ports returned by PortGetPage.
+// Fields left at their zero value do not restrict anything.
+// Product and Version must match the Software of a Port exactly.
+type PortFilter struct {
+	Port     uint16
+	HostID   krylib.ID
+	Since    time.Time
+	OpenOnly bool
+	Product  string
+	Version  string
+}
 
-			var res data.ScanResult = data.ScanResult{
-				Host:  *host,
-				Port:  uint16(port),
-				Reply: &reply,
-				Stamp: time.Unix(timestamp, 0),
-				Err:   nil,
-			}
+// args returns the filter as arguments for the PortGetPage queries.
+func (f *PortFilter) args() []any {
+	var (
+		since int64
+		open  int
+	)
+
+	if !f.Since.IsZero() {
+		since = f.Since.Unix()
+	}
+
+	if f.OpenOnly {
+		open = 1
+	}
+
+	return []any{int64(f.Port), int64(f.HostID), since, open, f.Product, f.Version}
+} // func (f *PortFilter) args() []any
+
+// PortGetPage returns the given page of the Ports matching the filter,
+// most recently scanned first, along with the total number of matching
+// Ports.
+func (db *sqlDB) PortGetPage(ctx context.Context, f PortFilter, page, perPage int) ([]data.Port, int64, error) {
+	var (
+		err   error
+		rows  *sql.Rows
+		total int64
+		ports []data.Port
+		args  = f.args()
+	)
+
+	page, perPage = pageBounds(page, perPage)
 
-			result = append(result, res)
+	if total, err = db.count(ctx, query.PortGetPageCnt, args...); err != nil {
+		err = fmt.Errorf("Error counting Ports: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, 0, err
+	} else if rows, err = db.query(ctx, query.PortGetPage, append(args, perPage, (page-1)*perPage)...); err != nil {
+		err = fmt.Errorf("Error querying Ports: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, 0, err
+	}
+
+	defer rows.Close() // nolint: errcheck
+
+	ports = make([]data.Port, 0, perPage)
+
+	for rows.Next() {
+		var (
+			portID, hostID, stamp, portNo int64
+			port                          data.Port
+		)
+
+		if err = rows.Scan(
+			&portID,
+			&hostID,
+			&portNo,
+			&stamp,
+			&port.Reply,
+			&port.Software.Service,
+			&port.Software.Product,
+			&port.Software.Version,
+			&port.Software.Info,
+			&port.Software.OS,
+			&port.Software.CPE); err != nil {
+			err = fmt.Errorf("Error scanning result row into Port: %w", err)
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return nil, 0, err
 		}
+
+		port.ID = krylib.ID(portID)
+		port.HostID = krylib.ID(hostID)
+		port.Port = uint16(portNo)
+		port.Timestamp = time.Unix(stamp, 0)
+		ports = append(ports, port)
 	}
 
-	return result, nil
-} // func (db *HostDB) PortGetRecent() ([]ScanResult, error)
+	if err = rows.Err(); err != nil {
+		err = fmt.Errorf("Error querying Ports: %w", classify(err))
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, 0, err
+	}
 
-// HostGetCount returns the number of Hosts in the database.
-func (db *HostDB) HostGetCount() (int64, error) {
-	var msg string
+	return ports, total, nil
+} // func (db *sqlDB) PortGetPage(ctx context.Context, f PortFilter, page, perPage int) ([]data.Port, int64, error)
+
+// PortSetSoftware updates the Software found in the reply of a Port.
+func (db *sqlDB) PortSetSoftware(ctx context.Context, port *data.Port, sw data.Software) error {
 	var err error
-	var stmt *sql.Stmt
-	var rows *sql.Rows
-	var cnt int64
-
-GET_QUERY:
-	if stmt, err = db.getStatement(query.HostGetCnt); err != nil {
-		if db.worthARetry(err) {
-			time.Sleep(retryDelay)
-			goto GET_QUERY
-		} else {
-			msg = fmt.Sprintf("Error getting query STMT_HOST_GET_CNT: %s",
-				err.Error())
-			db.log.Println(msg)
-			return -1, errors.New(msg)
-		}
-	} else if db.tx != nil {
-		stmt = db.tx.Stmt(stmt)
-	}
-
-EXEC_QUERY:
-	if rows, err = stmt.Query(); err != nil {
-		if db.worthARetry(err) {
-			time.Sleep(retryDelay)
-			goto EXEC_QUERY
-		} else {
-			msg = fmt.Sprintf("Error querying host count: %s",
-				err.Error())
-			db.log.Println(msg)
-			return 0, errors.New(msg)
-		}
-	} else {
-		defer rows.Close()
+
+	if _, err = db.exec(
+		ctx,
+		query.PortSetSoftware,
+		sw.Service,
+		sw.Product,
+		sw.Version,
+		sw.Info,
+		sw.OS,
+		sw.CPE,
+		port.ID); err != nil {
+		err = fmt.Errorf("Error setting Software of Port #%d: %w", port.ID, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
 	}
 
-	rows.Next()
+	port.Software = sw
+	return nil
+} // func (db *sqlDB) PortSetSoftware(ctx context.Context, port *data.Port, sw data.Software) error
+
+// SoftwareCount is the number of Ports and Hosts a version of a product was
+// found on, see PortGetSoftware. Version is empty for the Ports where the
+// banner parser recognized the product, but not its version.
+type SoftwareCount struct {
+	Product string
+	Version string
+	CPE     string
+	Ports   int64
+	Hosts   int64
+}
 
-	if err = rows.Scan(&cnt); err != nil {
-		msg = fmt.Sprintf("Error scanning result: %s", err.Error())
-		db.log.Println(msg)
-		return -1, errors.New(msg)
+// PortGetSoftware returns the products and versions found on open Ports,
+// ordered by product, the most common versions first.
+func (db *sqlDB) PortGetSoftware(ctx context.Context) ([]SoftwareCount, error) {
+	var (
+		err  error
+		rows *sql.Rows
+		list = make([]SoftwareCount, 0)
+	)
+
+	if rows, err = db.query(ctx, query.PortGetSoftware); err != nil {
+		err = fmt.Errorf("Error querying Software: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
 	}
 
-	return cnt, nil
-} // func (db *HostDB) HostGetCount() (int64, error)
+	defer rows.Close() // nolint: errcheck
 
-// HostGetByHostReport bla
-func (db *HostDB) HostGetByHostReport() ([]data.HostWithPorts, error) {
-	var err error
-	var msg string
-	var stmt *sql.Stmt
-	var rows *sql.Rows
-	var ports map[krylib.ID][]data.Port = make(map[krylib.ID][]data.Port)
-
-GET_QUERY:
-	if stmt, err = db.getStatement(query.PortGetOpen); err != nil {
-		if db.worthARetry(err) {
-			time.Sleep(retryDelay)
-			goto GET_QUERY
-		} else {
-			msg = fmt.Sprintf("Error preparing query STMT_HOST_PORT_BY_HOST: %s",
-				err.Error())
-			db.log.Println(msg)
-			return nil, errors.New(msg)
+	for rows.Next() {
+		var c SoftwareCount
+
+		if err = rows.Scan(&c.Product, &c.Version, &c.CPE, &c.Ports, &c.Hosts); err != nil {
+			err = fmt.Errorf("Error scanning result row into SoftwareCount: %w", err)
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return nil, err
 		}
-	} else if db.tx != nil {
-		stmt = db.tx.Stmt(stmt)
+
+		list = append(list, c)
 	}
 
-EXEC_QUERY:
-	if rows, err = stmt.Query(); err != nil {
-		if db.worthARetry(err) {
-			time.Sleep(retryDelay)
-			goto EXEC_QUERY
-		}
-	} else {
-		defer rows.Close()
+	if err = rows.Err(); err != nil {
+		err = fmt.Errorf("Error querying Software: %w", classify(err))
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	return list, nil
+} // func (db *sqlDB) PortGetSoftware(ctx context.Context) ([]SoftwareCount, error)
+
+// HostGetCount returns the number of Hosts in the database.
+func (db *sqlDB) HostGetCount(ctx context.Context) (int64, error) {
+	var (
+		err error
+		cnt int64
+	)
+
+	if cnt, err = db.count(ctx, query.HostGetCnt); err != nil {
+		err = fmt.Errorf("Error querying host count: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return -1, err
+	}
+
+	return cnt, nil
+} // func (db *sqlDB) HostGetCount(ctx context.Context) (int64, error)
+
+// HostGetByHostReport returns all Hosts that have at least one open port,
+// along with their open ports. If tag is not empty, only Hosts with that
+// Tag are returned.
+func (db *sqlDB) HostGetByHostReport(ctx context.Context, tag string) ([]data.HostWithPorts, error) {
+	var (
+		err   error
+		rows  *sql.Rows
+		ports = make(map[krylib.ID][]data.Port)
+	)
+
+	if rows, err = db.queryOpenPorts(ctx, tag); err != nil {
+		err = fmt.Errorf("Error querying open ports: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
 	}
 
+	defer rows.Close() // nolint: errcheck
+
 	for rows.Next() {
-		var portID, hostID, stamp, portNo int64
-		var ok bool
-		var reply *string
-		var portlist []data.Port
+		var (
+			portID, hostID, stamp, portNo int64
+			reply                         *string
+		)
 
 		if err = rows.Scan(&portID, &hostID, &portNo, &stamp, &reply); err != nil {
-			msg = fmt.Sprintf("Error scanning row: %s", err.Error())
-			db.log.Println(msg)
-			return nil, errors.New(msg)
+			err = fmt.Errorf("Error scanning row: %w", err)
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return nil, err
 		}
 
 		port := data.Port{
@@ -1267,156 +1503,225 @@ EXEC_QUERY:
 			Reply:     reply,
 		}
 
-		if portlist, ok = ports[port.HostID]; ok {
-			ports[port.HostID] = append(portlist, port)
-		} else {
-			ports[port.HostID] = []data.Port{port}
-		}
+		ports[port.HostID] = append(ports[port.HostID], port)
 	}
 
-	var res []data.HostWithPorts = make([]data.HostWithPorts, len(ports))
-	var idx int = 0
+	if err = rows.Err(); err != nil {
+		err = fmt.Errorf("Error querying open ports: %w", classify(err))
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	var res = make([]data.HostWithPorts, 0, len(ports))
 
 	for hostID, portlist := range ports {
 		var host *data.Host
 
-		if host, err = db.HostGetByID(hostID); err != nil {
-			msg = fmt.Sprintf("Error retrieving host #%d from database: %s",
-				hostID, err.Error())
-			db.log.Println(msg)
-			return nil, errors.New(msg)
+		if host, err = db.HostGetByID(ctx, hostID); err != nil {
+			err = fmt.Errorf("Error retrieving host #%d from database: %w",
+				hostID,
+				err)
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return nil, err
 		}
 
-		res[idx] = data.HostWithPorts{
+		res = append(res, data.HostWithPorts{
 			Host:  *host,
 			Ports: portlist,
-		}
-		idx++
+		})
 	}
 
 	return res, nil
-} // func (db *HostDB) HostGetByHostReport() ([]HostWithPorts, error)
+} // func (db *sqlDB) HostGetByHostReport(ctx context.Context, tag string) ([]data.HostWithPorts, error)
 
-// HostSetOS sets a Host's operating system.
-func (db *HostDB) HostSetOS(h *data.Host, osName string) error {
-	const qid query.ID = query.HostSetOS
+// HostSetOS sets a Host's operating system and replaces the evidence
+// stored for it with that of the OSGuess.
+func (db *sqlDB) HostSetOS(ctx context.Context, h *data.Host, guess *data.OSGuess) error {
 	var err error
-	var msg string
-	var stmt *sql.Stmt
-	var tx *sql.Tx
-	var adHoc bool
-
-GET_QUERY:
-	if stmt, err = db.getStatement(qid); err != nil {
-		if db.worthARetry(err) {
-			time.Sleep(retryDelay)
-			goto GET_QUERY
-		} else {
-			msg = fmt.Sprintf("Error getting query STMT_PORT_ADD: %s",
-				err.Error())
-			db.log.Println(msg)
-			return errors.New(msg)
+
+	if _, err = db.exec(ctx, query.HostSetOS, guess.OS, guess.Version, guess.Confidence, h.ID); err != nil {
+		err = fmt.Errorf("Error setting OS of %s to %q: %w",
+			h.Address,
+			guess.OS,
+			err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	} else if _, err = db.exec(ctx, query.OSEvidenceDelete, h.ID); err != nil {
+		err = fmt.Errorf("Error deleting OS evidence of %s: %w",
+			h.Address,
+			err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	for _, ev := range guess.Evidence {
+		if _, err = db.exec(ctx,
+			query.OSEvidenceAdd,
+			h.ID,
+			ev.Port,
+			ev.Source,
+			ev.OS,
+			ev.Version,
+			ev.Weight,
+			ev.Match); err != nil {
+			err = fmt.Errorf("Error adding OS evidence for %s on port %d: %w",
+				h.Address,
+				ev.Port,
+				err)
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return err
 		}
-	} else if db.tx != nil {
-		tx = db.tx
-	} else {
-		adHoc = true
-	BEGIN_ADHOC:
-		if tx, err = db.db.Begin(); err != nil {
-			if db.worthARetry(err) {
-				time.Sleep(retryDelay)
-				goto BEGIN_ADHOC
-			} else {
-				msg = fmt.Sprintf("Error starting ad-hoc TX for adding Port: %s",
-					err.Error())
-				db.log.Println(msg)
-				return errors.New(msg)
-			}
+	}
+
+	h.OS = guess.OS
+	h.OSVersion = guess.Version
+	h.OSConfidence = guess.Confidence
+	return nil
+} // func (db *sqlDB) HostSetOS(ctx context.Context, h *data.Host, guess *data.OSGuess) error
+
+// HostGetOSEvidence returns the evidence the OS of a Host is based on,
+// the heaviest first.
+func (db *sqlDB) HostGetOSEvidence(ctx context.Context, id krylib.ID) ([]data.OSEvidence, error) {
+	var (
+		err  error
+		rows *sql.Rows
+		list = make([]data.OSEvidence, 0)
+	)
+
+	if rows, err = db.query(ctx, query.OSEvidenceGetByHost, id); err != nil {
+		err = fmt.Errorf("Error querying OS evidence of Host #%d: %w", id, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck
+
+	for rows.Next() {
+		var (
+			port int64
+			ev   data.OSEvidence
+		)
+
+		if err = rows.Scan(&port, &ev.Source, &ev.OS, &ev.Version, &ev.Weight, &ev.Match); err != nil {
+			err = fmt.Errorf("Error scanning row into OSEvidence: %w", err)
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return nil, err
 		}
+
+		ev.Port = uint16(port)
+		list = append(list, ev)
 	}
 
-	stmt = tx.Stmt(stmt)
+	if err = rows.Err(); err != nil {
+		err = fmt.Errorf("Error querying OS evidence of Host #%d: %w", id, classify(err))
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
 
-EXEC_QUERY:
-	if _, err = stmt.Exec(osName, h.ID); err != nil {
-		if db.worthARetry(err) {
-			time.Sleep(retryDelay)
-			goto EXEC_QUERY
-		} else {
-			msg = fmt.Sprintf("Error adding ScanResult to database: %s",
-				err.Error())
-			db.log.Println(msg)
-			if adHoc {
-				tx.Rollback() // nolint: errcheck
-			}
-			return errors.New(msg)
+	return list, nil
+} // func (db *sqlDB) HostGetOSEvidence(ctx context.Context, id krylib.ID) ([]data.OSEvidence, error)
+
+// HostGetDirty returns up to <max> Hosts whose metadata need to be updated,
+// i.e. Hosts that were added or had Ports scanned since their metadata were
+// last updated.
+func (db *sqlDB) HostGetDirty(ctx context.Context, max int) ([]data.Host, error) {
+	var (
+		err   error
+		rows  *sql.Rows
+		hosts []data.Host
+	)
+
+	if rows, err = db.query(ctx, query.HostGetDirty, max); err != nil {
+		err = fmt.Errorf("Error querying Hosts with outdated metadata: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck
+
+	hosts = make([]data.Host, 0)
+
+	for rows.Next() {
+		var host *data.Host
+
+		if host, err = scanHost(rows); err != nil {
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return nil, err
 		}
-	} else if adHoc {
-		tx.Commit() // nolint: errcheck
+
+		hosts = append(hosts, *host)
+	}
+
+	if err = rows.Err(); err != nil {
+		err = fmt.Errorf("Error querying Hosts with outdated metadata: %w", classify(err))
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	return hosts, nil
+} // func (db *sqlDB) HostGetDirty(ctx context.Context, max int) ([]data.Host, error)
+
+// HostSetMetaUpdated records when the metadata of a Host were updated.
+func (db *sqlDB) HostSetMetaUpdated(ctx context.Context, id krylib.ID, stamp time.Time) error {
+	var err error
+
+	if _, err = db.exec(ctx, query.HostSetMetaUpdated, stamp.Unix(), id); err != nil {
+		err = fmt.Errorf("Error setting metadata timestamp of Host #%d: %w", id, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
 	}
 
-	h.OS = osName
 	return nil
-} // func (db *HostDB) HostSetOS(h *data.Host, osName string) error
+} // func (db *sqlDB) HostSetMetaUpdated(ctx context.Context, id krylib.ID, stamp time.Time) error
 
-// HostSetLocation sets a Host's location.
-func (db *HostDB) HostSetLocation(h *data.Host, location string) error {
-	const qid query.ID = query.HostSetLocation
+// HostMarkDirty flags the metadata of a Host as outdated.
+func (db *sqlDB) HostMarkDirty(ctx context.Context, id krylib.ID) error {
 	var err error
-	var msg string
-	var stmt *sql.Stmt
-	var tx *sql.Tx
-	var adHoc bool
-
-GET_QUERY:
-	if stmt, err = db.getStatement(qid); err != nil {
-		if db.worthARetry(err) {
-			time.Sleep(retryDelay)
-			goto GET_QUERY
-		} else {
-			msg = fmt.Sprintf("Error getting query STMT_PORT_ADD: %s",
-				err.Error())
-			db.log.Println(msg)
-			return errors.New(msg)
-		}
-	} else if db.tx != nil {
-		tx = db.tx
-	} else {
-		adHoc = true
-	BEGIN_ADHOC:
-		if tx, err = db.db.Begin(); err != nil {
-			if db.worthARetry(err) {
-				time.Sleep(retryDelay)
-				goto BEGIN_ADHOC
-			} else {
-				msg = fmt.Sprintf("Error starting ad-hoc TX for adding Port: %s",
-					err.Error())
-				db.log.Println(msg)
-				return errors.New(msg)
-			}
-		}
+
+	if _, err = db.exec(ctx, query.HostMarkDirty, id); err != nil {
+		err = fmt.Errorf("Error flagging metadata of Host #%d as outdated: %w", id, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
 	}
 
-	stmt = tx.Stmt(stmt)
+	return nil
+} // func (db *sqlDB) HostMarkDirty(ctx context.Context, id krylib.ID) error
 
-EXEC_QUERY:
-	if _, err = stmt.Exec(location, h.ID); err != nil {
-		if db.worthARetry(err) {
-			time.Sleep(retryDelay)
-			goto EXEC_QUERY
-		} else {
-			msg = fmt.Sprintf("Error adding ScanResult to database: %s",
-				err.Error())
-			db.log.Println(msg)
-			if adHoc {
-				tx.Rollback() // nolint: errcheck
-			}
-			return errors.New(msg)
-		}
-	} else if adHoc {
-		tx.Commit() // nolint: errcheck
+// HostMarkAllDirty flags the metadata of all Hosts as outdated, so they are
+// all updated again, e.g. after the GeoIP databases were replaced.
+func (db *sqlDB) HostMarkAllDirty(ctx context.Context) (int64, error) {
+	var (
+		err error
+		res sql.Result
+		cnt int64
+	)
+
+	if res, err = db.exec(ctx, query.HostMarkAllDirty); err != nil {
+		err = fmt.Errorf("Error flagging metadata of all Hosts as outdated: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return 0, err
+	} else if cnt, err = res.RowsAffected(); err != nil {
+		err = fmt.Errorf("Error flagging metadata of all Hosts as outdated: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return 0, err
+	}
+
+	return cnt, nil
+} // func (db *sqlDB) HostMarkAllDirty(ctx context.Context) (int64, error)
+
+// HostSetLocation sets a Host's location.
+func (db *sqlDB) HostSetLocation(ctx context.Context, h *data.Host, location string) error {
+	var err error
+
+	if _, err = db.exec(ctx, query.HostSetLocation, location, h.ID); err != nil {
+		err = fmt.Errorf("Error setting location of %s to %q: %w",
+			h.Address,
+			location,
+			err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
 	}
 
 	h.Location = location
 	return nil
-} // func (db *HostDB) HostSetLocation(h *data.Host, location string) error
+} // func (db *sqlDB) HostSetLocation(ctx context.Context, h *data.Host, location string) error
@@ -2,14 +2,19 @@
 // -*- coding: utf-8; mode: go; -*-
 // Created on 25. 12. 2015 by Benjamin Walkenhorst
 // (c) 2015 Benjamin Walkenhorst
-// Time-stamp: <2023-03-27 11:14:51 krylon>
+// Time-stamp: <2026-10-19 17:42:21 krylon>
 
 package database
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"math/rand"
 	"net"
+	"net/url"
+	"os"
 	"testing"
 	"time"
 
@@ -17,9 +22,30 @@ import (
 	"github.com/blicero/guang/data"
 	"github.com/blicero/guang/xfr/xfrstatus"
 	"github.com/blicero/krylib"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
 )
 
-var db *HostDB
+// testBackend is a database we run the tests against, along with the
+// state the tests share.
+type testBackend struct {
+	name  string
+	db    HostDB
+	hosts []data.Host
+	xfr   *data.XFR
+}
+
+// backends are the databases the tests are run against. The SQLite database
+// is always tested, a PostgreSQL database only if the environment variable
+// named by pgTestEnv contains the URL of a database we can use.
+var backends []*testBackend
+
+const pgTestEnv = "GUANG_TEST_POSTGRES"
+
+// pgTestSchema is the schema we create in the PostgreSQL test database, so we
+// do not trample on anything else living there. It is dropped after all tests
+// have run.
+var pgTestSchema string
 
 var hosts []data.Host = []data.Host{
 	data.Host{
@@ -42,12 +68,97 @@ var hosts []data.Host = []data.Host{
 	},
 }
 
+func TestMain(m *testing.M) {
+	var rc = m.Run()
+
+	for _, b := range backends {
+		b.db.Close()
+	}
+
+	if pgTestSchema != "" {
+		if err := pgDropSchema(os.Getenv(pgTestEnv), pgTestSchema); err != nil {
+			fmt.Printf("Cannot drop test schema %s: %s\n",
+				pgTestSchema,
+				err.Error())
+		}
+	}
+
+	os.Exit(rc)
+} // func TestMain(m *testing.M)
+
+// pgCreateSchema creates a fresh schema in the PostgreSQL database at dsn
+// and returns a URL that uses that schema.
+func pgCreateSchema(dsn, schema string) (string, error) {
+	var (
+		err  error
+		pdb  *sql.DB
+		u    *url.URL
+		qval url.Values
+	)
+
+	if u, err = url.Parse(dsn); err != nil {
+		return "", err
+	} else if pdb, err = sql.Open("postgres", dsn); err != nil {
+		return "", err
+	}
+
+	defer pdb.Close() // nolint: errcheck
+
+	if _, err = pdb.Exec("CREATE SCHEMA " + schema); err != nil {
+		return "", err
+	}
+
+	qval = u.Query()
+	qval.Set("search_path", schema)
+	u.RawQuery = qval.Encode()
+
+	return u.String(), nil
+} // func pgCreateSchema(dsn, schema string) (string, error)
+
+// pgDropSchema removes the test schema from the PostgreSQL database at dsn.
+func pgDropSchema(dsn, schema string) error {
+	var (
+		err error
+		pdb *sql.DB
+	)
+
+	if pdb, err = sql.Open("postgres", dsn); err != nil {
+		return err
+	}
+
+	defer pdb.Close() // nolint: errcheck
+
+	_, err = pdb.Exec("DROP SCHEMA " + schema + " CASCADE")
+	return err
+} // func pgDropSchema(dsn, schema string) error
+
+// forEachBackend runs fn as a subtest for each database we test against.
+func forEachBackend(t *testing.T, fn func(t *testing.T, b *testBackend)) {
+	if len(backends) == 0 {
+		t.SkipNow()
+	}
+
+	for _, b := range backends {
+		b := b
+		t.Run(b.name, func(t *testing.T) { fn(t, b) })
+	}
+} // func forEachBackend(t *testing.T, fn func(t *testing.T, b *testBackend))
+
+func newTestBackend(name string, db HostDB) *testBackend {
+	return &testBackend{
+		name:  name,
+		db:    db,
+		hosts: append([]data.Host(nil), hosts...),
+	}
+} // func newTestBackend(name string, db HostDB) *testBackend
+
 func TestCreateDatabase(t *testing.T) {
 	var rng *rand.Rand = rand.New(rand.NewSource(time.Now().Unix()))
 
 	var testPath string = fmt.Sprintf("/tmp/guang_test_%08x",
 		rng.Int31())
 	var err error
+	var db HostDB
 
 	fmt.Printf("Setting BASE_DIR to %s\n", testPath)
 	common.SetBaseDir(testPath)
@@ -56,130 +167,787 @@ func TestCreateDatabase(t *testing.T) {
 		t.Fatalf("Error opening database at %s: %s",
 			common.DbPath, err.Error())
 	}
-} // func TestCreateDatabase(t *testing.T)
 
-func TestQueries(t *testing.T) {
-	if db == nil {
-		t.SkipNow()
+	backends = append(backends, newTestBackend("SQLite", db))
+
+	var dsn = os.Getenv(pgTestEnv)
+
+	if dsn == "" {
+		t.Logf("%s is not set, skipping tests with PostgreSQL", pgTestEnv)
+		return
 	}
 
-	var err error
+	var schema = fmt.Sprintf("guang_test_%08x", rng.Int31())
+
+	if dsn, err = pgCreateSchema(dsn, schema); err != nil {
+		t.Fatalf("Error creating schema %s for PostgreSQL tests: %s",
+			schema,
+			err.Error())
+	}
+
+	pgTestSchema = schema
+
+	if db, err = OpenDB(dsn); err != nil {
+		t.Fatalf("Error opening PostgreSQL database: %s",
+			err.Error())
+	}
+
+	backends = append(backends, newTestBackend("PostgreSQL", db))
+} // func TestCreateDatabase(t *testing.T)
 
+func TestQueryCatalogue(t *testing.T) {
 	for id := range dbQueries {
-		if _, err = db.getStatement(id); err != nil {
-			t.Errorf("Failed to prepare query %s", id)
+		if _, ok := pgQueries[id]; !ok {
+			t.Errorf("Query %s is missing for PostgreSQL", id)
+		}
+	}
+
+	for id := range pgQueries {
+		if _, ok := dbQueries[id]; !ok {
+			t.Errorf("Query %s is missing for SQLite", id)
 		}
 	}
+} // func TestQueryCatalogue(t *testing.T)
+
+func TestQueries(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b *testBackend) {
+		var (
+			err error
+			db  = b.db.(*sqlDB)
+		)
+
+		for id := range db.dialect.queries {
+			if _, err = db.getStatement(context.Background(), id); err != nil {
+				t.Errorf("Failed to prepare query %s", id)
+			}
+		}
+	})
 } // func TestQueries(t *testing.T)
 
 func TestAddHosts(t *testing.T) {
-	if db == nil {
-		t.SkipNow()
-	}
-	var err error
+	forEachBackend(t, func(t *testing.T, b *testBackend) {
+		var err error
 
-	if err = db.Begin(); err != nil {
-		t.Fatalf("Error starting transaction: %s", err.Error())
-	}
+		if err = b.db.Begin(context.Background()); err != nil {
+			t.Fatalf("Error starting transaction: %s", err.Error())
+		}
 
-	for idx, host := range hosts {
-		if err = db.HostAdd(&host); err != nil {
-			t.Fatalf("Error adding host %s to database: %s",
-				host.Name, err.Error())
-		} else if host.ID == krylib.INVALID_ID {
-			t.Errorf("After adding host %s, no ID was set!",
-				host.Name)
-		} else {
-			hosts[idx].ID = host.ID
+		for idx, host := range b.hosts {
+			if err = b.db.HostAdd(context.Background(), &host); err != nil {
+				t.Fatalf("Error adding host %s to database: %s",
+					host.Name, err.Error())
+			} else if host.ID == krylib.INVALID_ID {
+				t.Errorf("After adding host %s, no ID was set!",
+					host.Name)
+			} else {
+				b.hosts[idx].ID = host.ID
+			}
 		}
-	}
 
-	if err = db.Commit(); err != nil {
-		t.Fatalf("Error committing transaction: %s", err.Error())
-	}
+		if err = b.db.Commit(); err != nil {
+			t.Fatalf("Error committing transaction: %s", err.Error())
+		}
+	})
 } // func TestAddHosts(t *testing.T)
 
 func TestGetHosts(t *testing.T) {
-	if db == nil {
-		t.SkipNow()
-	}
-	var err error
-	var host *data.Host
+	forEachBackend(t, func(t *testing.T, b *testBackend) {
+		var err error
+		var host *data.Host
 
-	for _, refHost := range hosts {
-		if host, err = db.HostGetByID(refHost.ID); err != nil {
-			t.Fatalf("Error getting Host by ID #%d: %s",
-				refHost.ID, err.Error())
-		} else if refHost.ID != host.ID {
-			t.Errorf("Host came back with the wrong ID: %d (expected) <-> %d (actual)",
-				refHost.ID, host.ID)
-		} else if refHost.Name != host.Name {
-			t.Errorf("Host came back with the wrong name: %s (expected) <-> %s (actual)",
-				refHost.Name, host.Name)
+		for _, refHost := range b.hosts {
+			if host, err = b.db.HostGetByID(context.Background(), refHost.ID); err != nil {
+				t.Fatalf("Error getting Host by ID #%d: %s",
+					refHost.ID, err.Error())
+			} else if refHost.ID != host.ID {
+				t.Errorf("Host came back with the wrong ID: %d (expected) <-> %d (actual)",
+					refHost.ID, host.ID)
+			} else if refHost.Name != host.Name {
+				t.Errorf("Host came back with the wrong name: %s (expected) <-> %s (actual)",
+					refHost.Name, host.Name)
+			}
 		}
-	}
+	})
 } // func TestGetHosts(t *testing.T)
 
-var xfrClient *data.XFR
-
 func TestAddXFR(t *testing.T) {
-	if db == nil {
-		t.SkipNow()
-	}
-	var err error
-	xfrClient = &data.XFR{
-		ID:     krylib.INVALID_ID,
-		Zone:   "krylon.net",
-		Start:  time.Now(),
-		Status: xfrstatus.Unfinished,
-	}
+	forEachBackend(t, func(t *testing.T, b *testBackend) {
+		var err error
+		b.xfr = &data.XFR{
+			ID:     krylib.INVALID_ID,
+			Zone:   "krylon.net",
+			Start:  time.Now(),
+			Status: xfrstatus.Unfinished,
+		}
 
-	if err = db.XfrAdd(xfrClient); err != nil {
-		t.Fatalf("Error adding xfr: %s", err.Error())
-	} else if xfrClient.ID == krylib.INVALID_ID {
-		t.Fatalf("Error: XFR was added without error, but it didn't get an ID!")
-	}
+		if err = b.db.XfrAdd(context.Background(), b.xfr); err != nil {
+			t.Fatalf("Error adding xfr: %s", err.Error())
+		} else if b.xfr.ID == krylib.INVALID_ID {
+			t.Fatalf("Error: XFR was added without error, but it didn't get an ID!")
+		}
+	})
 } // func TestAddXFR(t *testing.T)
 
 func TestFinishXFR(t *testing.T) {
-	if db == nil {
-		t.SkipNow()
-	}
-	var err error
-	if err = db.XfrFinish(xfrClient, xfrstatus.Success); err != nil {
-		t.Fatalf("Error finishing XFR: %s", err.Error())
-	}
+	forEachBackend(t, func(t *testing.T, b *testBackend) {
+		if b.xfr == nil {
+			t.SkipNow()
+		}
+
+		var err error
+		if err = b.db.XfrFinish(context.Background(), b.xfr, xfrstatus.Success); err != nil {
+			t.Fatalf("Error finishing XFR: %s", err.Error())
+		}
+	})
 } // func TestFinishXFR(t *testing.T)
 
 func TestPortAdd(t *testing.T) {
-	if db == nil {
-		t.SkipNow()
-	}
-	var err error
-	var testReply string = "Wer das liest, ist doof."
-	var res data.ScanResult = data.ScanResult{
-		Host:  hosts[0],
-		Port:  22,
-		Reply: &testReply,
-		Stamp: time.Now(),
-	}
+	forEachBackend(t, func(t *testing.T, b *testBackend) {
+		var err error
+		var testReply string = "Wer das liest, ist doof."
+		var res data.ScanResult = data.ScanResult{
+			Host:  b.hosts[0],
+			Port:  22,
+			Reply: &testReply,
+			Stamp: time.Now(),
+		}
 
-	if err = db.PortAdd(&res); err != nil {
-		t.Fatalf("Error adding ScanResult: %s", err.Error())
-	}
+		if err = b.db.PortAdd(context.Background(), &res); err != nil {
+			t.Fatalf("Error adding ScanResult: %s", err.Error())
+		}
+	})
 } // func TestPortAdd(t *testing.T)
 
 func TestPortCnt(t *testing.T) {
-	if db == nil {
+	forEachBackend(t, func(t *testing.T, b *testBackend) {
+		var err error
+		var cnt int64
+
+		if cnt, err = b.db.PortGetReplyCount(context.Background()); err != nil {
+			t.Errorf("Error getting reply count from database: %s",
+				err.Error())
+		} else if cnt < 1 {
+			t.Fatalf("Invalid/Unexpected reply count: %d", cnt)
+		}
+	})
+}
+
+func TestHostNotFound(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b *testBackend) {
+		var err error
+
+		if _, err = b.db.HostGetByID(context.Background(), 4711); err == nil {
+			t.Error("Looking up a non-existent Host did not return an error")
+		} else if !errors.Is(err, ErrNotFound) {
+			t.Errorf("Unexpected error looking up non-existent Host: %s",
+				err.Error())
+		}
+	})
+} // func TestHostNotFound(t *testing.T)
+
+func TestHostAddDuplicate(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b *testBackend) {
+		var (
+			err  error
+			host = b.hosts[0]
+		)
+
+		host.ID = krylib.INVALID_ID
+
+		if err = b.db.HostAdd(context.Background(), &host); err == nil {
+			t.Errorf("Adding host %s twice did not return an error",
+				host.Address)
+		} else if !errors.Is(err, ErrConstraint) {
+			t.Errorf("Unexpected error adding host %s twice: %s",
+				host.Address,
+				err.Error())
+		}
+	})
+} // func TestHostAddDuplicate(t *testing.T)
+
+func TestXfrNotFound(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b *testBackend) {
+		var err error
+
+		if _, err = b.db.XfrGetByZone(context.Background(), "example.com"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Unexpected error looking up non-existent XFR: %v", err)
+		}
+	})
+} // func TestXfrNotFound(t *testing.T)
+
+func TestRetry(t *testing.T) {
+	if len(backends) == 0 {
 		t.SkipNow()
 	}
-	var err error
-	var cnt int64
 
-	if cnt, err = db.PortGetReplyCount(); err != nil {
-		t.Errorf("Error getting reply count from database: %s",
+	var (
+		err     error
+		cnt     int
+		res     int
+		db      = backends[0].db.(*sqlDB)
+		busyErr = sqlite3.Error{Code: sqlite3.ErrBusy}
+	)
+
+	if res, err = retry(context.Background(), db, func() (int, error) {
+		if cnt++; cnt < 3 {
+			return 0, busyErr
+		}
+		return 42, nil
+	}); err != nil {
+		t.Errorf("retry returned an error: %s", err.Error())
+	} else if res != 42 || cnt != 3 {
+		t.Errorf("Unexpected result from retry: %d after %d attempts",
+			res,
+			cnt)
+	}
+
+	cnt = 0
+
+	if _, err = retry(context.Background(), db, func() (int, error) {
+		if cnt++; cnt < 2 {
+			return 0, &pq.Error{Code: "40001"}
+		}
+		return 42, nil
+	}); err != nil {
+		t.Errorf("retry did not recover from a serialization failure: %s",
 			err.Error())
-	} else if cnt < 1 {
-		t.Fatalf("Invalid/Unexpected reply count: %d", cnt)
 	}
-}
+
+	cnt = 0
+
+	if _, err = retry(context.Background(), db, func() (int, error) {
+		cnt++
+		return 0, sqlite3.Error{Code: sqlite3.ErrConstraint}
+	}); !errors.Is(err, ErrConstraint) {
+		t.Errorf("Unexpected error from retry: %v", err)
+	} else if cnt != 1 {
+		t.Errorf("retry should not retry a constraint violation, but made %d attempts",
+			cnt)
+	}
+
+	if _, err = retry(context.Background(), db, func() (int, error) {
+		return 0, &pq.Error{Code: "23505"}
+	}); !errors.Is(err, ErrConstraint) {
+		t.Errorf("Unexpected error from retry with PostgreSQL unique violation: %v", err)
+	}
+
+	var ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err = retry(ctx, db, func() (int, error) {
+		return 0, busyErr
+	}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Unexpected error from retry with expired Context: %v", err)
+	}
+
+	// Once all attempts failed, retry gives up without waiting once more.
+	var (
+		backoff time.Duration
+		delay   = retryDelay
+		start   = time.Now()
+	)
+
+	for i := 1; i < retryMaxAttempts; i++ {
+		backoff += delay
+		if delay *= 2; delay > retryMaxDelay {
+			delay = retryMaxDelay
+		}
+	}
+
+	cnt = 0
+
+	if _, err = retry(context.Background(), db, func() (int, error) {
+		cnt++
+		return 0, busyErr
+	}); !errors.Is(err, ErrBusy) {
+		t.Errorf("Unexpected error from retry when the database stays busy: %v", err)
+	} else if cnt != retryMaxAttempts {
+		t.Errorf("retry made %d attempts, expected %d", cnt, retryMaxAttempts)
+	} else if elapsed := time.Since(start); elapsed >= backoff+retryMaxDelay/2 {
+		t.Errorf("retry took %s to give up, the delays between attempts add up to %s",
+			elapsed,
+			backoff)
+	}
+} // func TestRetry(t *testing.T)
+
+func TestHostUpsert(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b *testBackend) {
+		var (
+			err   error
+			isNew bool
+			ctx   = context.Background()
+			host  = data.Host{
+				Address: net.ParseIP("192.168.0.23"),
+				Name:    "gen-192-168-0-23.example.com",
+				Source:  data.HostSourceGen,
+			}
+			dup = host
+		)
+
+		if isNew, err = b.db.HostUpsert(ctx, &host); err != nil {
+			t.Fatalf("Error upserting new Host %s: %s",
+				host.Address,
+				err.Error())
+		} else if !isNew {
+			t.Errorf("New Host %s was not reported as new", host.Address)
+		} else if isNew, err = b.db.HostUpsert(ctx, &dup); err != nil {
+			t.Fatalf("Error upserting Host %s again: %s",
+				host.Address,
+				err.Error())
+		} else if isNew {
+			t.Errorf("Host %s was reported as new twice", host.Address)
+		} else if dup.ID != host.ID {
+			t.Errorf("Upserting Host %s again returned ID %d, expected %d",
+				host.Address,
+				dup.ID,
+				host.ID)
+		}
+
+		// An MX record tells us more than the generator did.
+		var mx = data.Host{
+			Address: host.Address,
+			Name:    "mail.example.com",
+			Source:  data.HostSourceMx,
+		}
+
+		if _, err = b.db.HostUpsert(ctx, &mx); err != nil {
+			t.Fatalf("Error upserting MX %s: %s", mx.Name, err.Error())
+		} else if mx.ID != host.ID || mx.Name != "mail.example.com" || mx.Source != data.HostSourceMx {
+			t.Errorf("Unexpected Host after upserting MX: #%d %s (%v)",
+				mx.ID,
+				mx.Name,
+				mx.Source)
+		}
+
+		// ... but the generator does not get to overwrite it.
+		if _, err = b.db.HostUpsert(ctx, &dup); err != nil {
+			t.Fatalf("Error upserting Host %s again: %s",
+				host.Address,
+				err.Error())
+		} else if dup.Name != "mail.example.com" || dup.Source != data.HostSourceMx {
+			t.Errorf("Generator overwrote MX: %s (%v)",
+				dup.Name,
+				dup.Source)
+		}
+	})
+} // func TestHostUpsert(t *testing.T)
+
+func TestHostMultiHomed(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b *testBackend) {
+		var (
+			err    error
+			isNew  bool
+			ctx    = context.Background()
+			stored *data.Host
+			v4     = data.Host{
+				Address: net.ParseIP("10.1.1.1"),
+				Name:    "dual.example.com",
+				Source:  data.HostSourceA,
+			}
+			v6 = data.Host{
+				Address: net.ParseIP("2001:db8::1"),
+				Name:    "dual.example.com",
+				Source:  data.HostSourceA,
+			}
+			mx = data.Host{
+				Address: net.ParseIP("10.1.1.1"),
+				Name:    "mx.example.com",
+				Source:  data.HostSourceMx,
+			}
+			gen = data.Host{
+				Address: net.ParseIP("10.1.1.2"),
+				Name:    "dual.example.com",
+				Source:  data.HostSourceGen,
+			}
+		)
+
+		if isNew, err = b.db.HostUpsert(ctx, &v4); err != nil {
+			t.Fatalf("Error adding %s: %s", v4.Address, err.Error())
+		} else if !isNew {
+			t.Errorf("Host %s was not reported as new", v4.Address)
+		} else if isNew, err = b.db.HostUpsert(ctx, &v6); err != nil {
+			t.Fatalf("Error adding %s: %s", v6.Address, err.Error())
+		} else if isNew {
+			t.Errorf("Second address %s was reported as a new Host", v6.Address)
+		} else if v6.ID != v4.ID {
+			t.Errorf("Second address %s ended up on Host #%d, expected #%d",
+				v6.Address,
+				v6.ID,
+				v4.ID)
+		} else if _, err = b.db.HostUpsert(ctx, &mx); err != nil {
+			t.Fatalf("Error adding MX %s: %s", mx.Name, err.Error())
+		} else if isNew, err = b.db.HostUpsert(ctx, &gen); err != nil {
+			t.Fatalf("Error adding %s: %s", gen.Address, err.Error())
+		} else if !isNew {
+			t.Errorf("Name from generator merged %s into Host #%d",
+				gen.Address,
+				gen.ID)
+		}
+
+		if stored, err = b.db.HostGetByAddr(ctx, "2001:db8::1"); err != nil {
+			t.Fatalf("Cannot load Host by its second address: %s", err.Error())
+		} else if stored.ID != v4.ID {
+			t.Errorf("Second address belongs to Host #%d, expected #%d",
+				stored.ID,
+				v4.ID)
+		} else if len(stored.Addresses) != 2 {
+			t.Errorf("Host #%d has %d addresses, expected 2",
+				stored.ID,
+				len(stored.Addresses))
+		} else if len(stored.Names) != 2 {
+			t.Errorf("Host #%d has %d names, expected 2",
+				stored.ID,
+				len(stored.Names))
+		} else if stored.Name != "mx.example.com" {
+			t.Errorf("Primary name of Host #%d is %s, expected mx.example.com",
+				stored.ID,
+				stored.Name)
+		}
+	})
+} // func TestHostMultiHomed(t *testing.T)
+
+func TestPortGetPage(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b *testBackend) {
+		var (
+			err   error
+			ports []data.Port
+			total int64
+			ctx   = context.Background()
+			reply = "220 ProFTPD Server ready."
+			now   = time.Now()
+			host  = data.Host{
+				Address: net.ParseIP("203.0.113.77"),
+				Name:    "ftp.example.org",
+				Source:  data.HostSourceUser,
+			}
+		)
+
+		if _, err = b.db.HostUpsert(ctx, &host); err != nil {
+			t.Fatalf("Error adding Host %s: %s", host.Address, err.Error())
+		}
+
+		for i, p := range []uint16{21, 22, 23} {
+			var res = data.ScanResult{
+				Host:  host,
+				Port:  p,
+				Stamp: now.Add(time.Duration(i) * time.Second),
+			}
+
+			if p == 21 {
+				res.Reply = &reply
+				res.Software = data.Software{Service: "ftp", Product: "ProFTPD"}
+			}
+
+			if err = b.db.PortAdd(ctx, &res); err != nil {
+				t.Fatalf("Error adding Port %d: %s", p, err.Error())
+			}
+		}
+
+		type testCase struct {
+			filter  PortFilter
+			page    int
+			perPage int
+			total   int64
+			ports   []uint16
+		}
+
+		var cases = []testCase{
+			{
+				filter:  PortFilter{HostID: host.ID},
+				page:    1,
+				perPage: 10,
+				total:   3,
+				ports:   []uint16{23, 22, 21},
+			},
+			{
+				filter:  PortFilter{HostID: host.ID},
+				page:    2,
+				perPage: 2,
+				total:   3,
+				ports:   []uint16{21},
+			},
+			{
+				filter:  PortFilter{HostID: host.ID, OpenOnly: true},
+				page:    1,
+				perPage: 10,
+				total:   1,
+				ports:   []uint16{21},
+			},
+			{
+				filter:  PortFilter{HostID: host.ID, Port: 22},
+				page:    1,
+				perPage: 10,
+				total:   1,
+				ports:   []uint16{22},
+			},
+			{
+				filter:  PortFilter{HostID: host.ID, Since: now.Add(90 * time.Minute)},
+				page:    1,
+				perPage: 10,
+				total:   0,
+			},
+			{
+				filter:  PortFilter{HostID: host.ID, Product: "ProFTPD"},
+				page:    1,
+				perPage: 10,
+				total:   1,
+				ports:   []uint16{21},
+			},
+			{
+				filter:  PortFilter{HostID: host.ID, Product: "ProFTPD", Version: "1.3.5"},
+				page:    1,
+				perPage: 10,
+				total:   0,
+			},
+		}
+
+		for i, c := range cases {
+			if ports, total, err = b.db.PortGetPage(ctx, c.filter, c.page, c.perPage); err != nil {
+				t.Errorf("Case #%d: Error getting Ports: %s", i, err.Error())
+				continue
+			} else if total != c.total {
+				t.Errorf("Case #%d: unexpected total %d, expected %d",
+					i,
+					total,
+					c.total)
+			} else if len(ports) != len(c.ports) {
+				t.Errorf("Case #%d: got %d Ports, expected %d",
+					i,
+					len(ports),
+					len(c.ports))
+				continue
+			}
+
+			for j, p := range ports {
+				if p.Port != c.ports[j] {
+					t.Errorf("Case #%d: Port #%d is %d, expected %d",
+						i,
+						j,
+						p.Port,
+						c.ports[j])
+				} else if p.HostID != host.ID {
+					t.Errorf("Case #%d: Port %d belongs to Host #%d, expected #%d",
+						i,
+						p.Port,
+						p.HostID,
+						host.ID)
+				}
+			}
+		}
+	})
+} // func TestPortGetPage(t *testing.T)
+
+func TestPortSoftware(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b *testBackend) {
+		var (
+			err   error
+			ports []data.Port
+			list  []SoftwareCount
+			found bool
+			ctx   = context.Background()
+			reply = "SSH-2.0-OpenSSH_7.4"
+			sw    = data.Software{
+				Service: "ssh",
+				Product: "OpenSSH (software test)",
+				Version: "7.4",
+				Info:    "protocol 2.0",
+				CPE:     "cpe:/a:openbsd:openssh:7.4",
+			}
+			host = data.Host{
+				Address: net.ParseIP("203.0.113.78"),
+				Name:    "ssh.example.org",
+				Source:  data.HostSourceUser,
+			}
+		)
+
+		if _, err = b.db.HostUpsert(ctx, &host); err != nil {
+			t.Fatalf("Error adding Host %s: %s", host.Address, err.Error())
+		} else if err = b.db.PortAdd(ctx, &data.ScanResult{
+			Host:  host,
+			Port:  22,
+			Reply: &reply,
+			Stamp: time.Now(),
+		}); err != nil {
+			t.Fatalf("Error adding Port: %s", err.Error())
+		} else if ports, err = b.db.PortGetByHost(ctx, host.ID); err != nil {
+			t.Fatalf("Error loading Ports: %s", err.Error())
+		} else if len(ports) != 1 || !ports[0].Software.IsZero() {
+			t.Fatalf("Unexpected Ports: %#v", ports)
+		} else if err = b.db.PortSetSoftware(ctx, &ports[0], sw); err != nil {
+			t.Fatalf("Error setting Software: %s", err.Error())
+		} else if ports, err = b.db.PortGetByHost(ctx, host.ID); err != nil {
+			t.Fatalf("Error loading Ports: %s", err.Error())
+		} else if ports[0].Software != sw {
+			t.Errorf("Unexpected Software: %#v", ports[0].Software)
+		} else if list, err = b.db.PortGetSoftware(ctx); err != nil {
+			t.Fatalf("Error loading Software: %s", err.Error())
+		}
+
+		for _, c := range list {
+			if c.Product == sw.Product {
+				found = true
+				if c.Version != sw.Version || c.CPE != sw.CPE || c.Ports != 1 || c.Hosts != 1 {
+					t.Errorf("Unexpected SoftwareCount: %#v", c)
+				}
+			}
+		}
+
+		if !found {
+			t.Errorf("%s is missing from the Software: %#v", sw.Product, list)
+		}
+	})
+} // func TestPortSoftware(t *testing.T)
+
+func TestHostSetOS(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b *testBackend) {
+		var (
+			err      error
+			stored   *data.Host
+			evidence []data.OSEvidence
+			ctx      = context.Background()
+			host     = data.Host{
+				Address: net.ParseIP("203.0.113.79"),
+				Name:    "os.example.org",
+				Source:  data.HostSourceUser,
+			}
+			guess = data.OSGuess{
+				OS:         "Debian",
+				Version:    "10",
+				Confidence: 0.95,
+				Evidence: []data.OSEvidence{
+					{Port: 22, Source: "ssh", OS: "Debian", Version: "10", Weight: 0.9, Match: "SSH-2.0-OpenSSH_7.9p1 Debian-10+deb10u2"},
+					{Port: 80, Source: "http", OS: "Debian", Weight: 0.8, Match: "Apache/2.4.38 (Debian)"},
+				},
+			}
+		)
+
+		if _, err = b.db.HostUpsert(ctx, &host); err != nil {
+			t.Fatalf("Error adding Host %s: %s", host.Address, err.Error())
+		} else if err = b.db.HostSetOS(ctx, &host, &guess); err != nil {
+			t.Fatalf("Error setting OS: %s", err.Error())
+		} else if err = b.db.HostSetOS(ctx, &host, &guess); err != nil {
+			t.Fatalf("Error setting OS again: %s", err.Error())
+		} else if stored, err = b.db.HostGetByID(ctx, host.ID); err != nil {
+			t.Fatalf("Error loading Host: %s", err.Error())
+		} else if stored.OSName() != "Debian 10" || stored.OSConfidence != 0.95 {
+			t.Errorf("Unexpected OS: %q, confidence %f", stored.OSName(), stored.OSConfidence)
+		} else if evidence, err = b.db.HostGetOSEvidence(ctx, host.ID); err != nil {
+			t.Fatalf("Error loading OS evidence: %s", err.Error())
+		} else if len(evidence) != 2 || evidence[0] != guess.Evidence[0] || evidence[1] != guess.Evidence[1] {
+			t.Errorf("Unexpected OS evidence: %#v", evidence)
+		}
+	})
+} // func TestHostSetOS(t *testing.T)
+
+func TestHostDirty(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b *testBackend) {
+		var (
+			err  error
+			ctx  = context.Background()
+			host = data.Host{
+				Address: net.ParseIP("203.0.113.81"),
+				Name:    "dirty.example.org",
+				Source:  data.HostSourceUser,
+			}
+		)
+
+		var isDirty = func() bool {
+			var hosts []data.Host
+
+			if hosts, err = b.db.HostGetDirty(ctx, 100000); err != nil {
+				t.Fatalf("Error getting dirty Hosts: %s", err.Error())
+			}
+
+			for _, h := range hosts {
+				if h.ID == host.ID {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if _, err = b.db.HostUpsert(ctx, &host); err != nil {
+			t.Fatalf("Error adding Host %s: %s", host.Address, err.Error())
+		} else if !isDirty() {
+			t.Fatalf("New Host %s is not dirty", host.Address)
+		} else if err = b.db.HostSetMetaUpdated(ctx, host.ID, time.Now()); err != nil {
+			t.Fatalf("Error setting metadata timestamp: %s", err.Error())
+		} else if isDirty() {
+			t.Fatalf("Host %s is still dirty after its metadata were updated", host.Address)
+		} else if err = b.db.PortAdd(ctx, &data.ScanResult{
+			Host:  host,
+			Port:  22,
+			Stamp: time.Now(),
+		}); err != nil {
+			t.Fatalf("Error adding Port: %s", err.Error())
+		} else if !isDirty() {
+			t.Fatalf("Host %s is not dirty after a Port was scanned", host.Address)
+		} else if err = b.db.HostSetMetaUpdated(ctx, host.ID, time.Now()); err != nil {
+			t.Fatalf("Error setting metadata timestamp: %s", err.Error())
+		} else if _, err = b.db.HostMarkAllDirty(ctx); err != nil {
+			t.Fatalf("Error marking all Hosts dirty: %s", err.Error())
+		} else if !isDirty() {
+			t.Errorf("Host %s is not dirty after all Hosts were marked", host.Address)
+		}
+	})
+} // func TestHostDirty(t *testing.T)
+
+func TestXfrGetPage(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b *testBackend) {
+		var (
+			err          error
+			xfrs         []data.XFR
+			all, refused int64
+			ctx          = context.Background()
+			x            = data.XFR{
+				Zone:   "refused.example.org",
+				Start:  time.Now(),
+				Status: xfrstatus.Unfinished,
+			}
+		)
+
+		if _, all, err = b.db.XfrGetPage(ctx, -1, 1, 10); err != nil {
+			t.Fatalf("Error getting XFRs: %s", err.Error())
+		} else if _, refused, err = b.db.XfrGetPage(ctx, xfrstatus.Refused, 1, 10); err != nil {
+			t.Fatalf("Error getting refused XFRs: %s", err.Error())
+		} else if err = b.db.XfrAdd(ctx, &x); err != nil {
+			t.Fatalf("Error adding XFR: %s", err.Error())
+		} else if err = b.db.XfrFinish(ctx, &x, xfrstatus.Refused); err != nil {
+			t.Fatalf("Error finishing XFR: %s", err.Error())
+		}
+
+		if xfrs, _, err = b.db.XfrGetPage(ctx, -1, 1, 10); err != nil {
+			t.Fatalf("Error getting XFRs: %s", err.Error())
+		} else if len(xfrs) == 0 || xfrs[0].ID != x.ID {
+			t.Errorf("Most recent XFR is not #%d", x.ID)
+		} else if !xfrs[0].IsFinished() {
+			t.Errorf("XFR #%d should be finished", x.ID)
+		}
+
+		var cnt int64
+
+		if _, cnt, err = b.db.XfrGetPage(ctx, -1, 1, 10); err != nil {
+			t.Fatalf("Error getting XFRs: %s", err.Error())
+		} else if cnt != all+1 {
+			t.Errorf("Unexpected total number of XFRs: %d, expected %d",
+				cnt,
+				all+1)
+		} else if xfrs, cnt, err = b.db.XfrGetPage(ctx, xfrstatus.Refused, 1, 10); err != nil {
+			t.Fatalf("Error getting refused XFRs: %s", err.Error())
+		} else if cnt != refused+1 {
+			t.Errorf("Unexpected number of refused XFRs: %d, expected %d",
+				cnt,
+				refused+1)
+		}
+
+		for _, r := range xfrs {
+			if r.Status != xfrstatus.Refused {
+				t.Errorf("XFR #%d has status %s, expected %s",
+					r.ID,
+					r.Status,
+					xfrstatus.Refused)
+			}
+		}
+	})
+} // func TestXfrGetPage(t *testing.T)
@@ -0,0 +1,75 @@
+// /home/krylon/go/src/github.com/blicero/guang/database/dialect.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 14:07:53 krylon>
+
+package database
+
+import (
+	"strconv"
+
+	"github.com/blicero/guang/database/query"
+)
+
+// dialect holds everything that differs between the database engines we
+// support: The driver, the SQL for the query catalogue and the schema
+// migrations, and the handful of queries used to manage the schema itself.
+//
+// The migrations of all dialects must have the same version numbers, so a
+// schema version means the same thing regardless of the engine.
+type dialect struct {
+	name           string
+	driver         string
+	queries        map[query.ID]string
+	migrations     []migration
+	qTableExists   string
+	qRecordVersion string
+	// canBackup is true if we can create a backup of the database
+	// ourselves before upgrading the schema.
+	canBackup bool
+	// The remaining fields are used to build search queries, which
+	// are too dynamic for the query catalogue.
+	// placeholder returns the placeholder for the n-th argument of a query,
+	// likeOp is the case-insensitive LIKE operator, and inNet is a format
+	// string that takes a network in CIDR notation and an address as its
+	// arguments and produces an expression that is true if the address
+	// is part of the network.
+	placeholder func(n int) string
+	likeOp      string
+	inNet       string
+}
+
+var dialectSQLite = &dialect{
+	name:           "SQLite",
+	driver:         sqliteDriver,
+	queries:        dbQueries,
+	migrations:     migrations,
+	qTableExists:   "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?",
+	qRecordVersion: "INSERT INTO schema_version (version, stamp, description) VALUES (?, ?, ?)",
+	canBackup:      true,
+	placeholder:    func(int) string { return "?" },
+	likeOp:         "LIKE",
+	inNet:          "cidr_contains(%s, %s)",
+}
+
+var dialectPostgres = &dialect{
+	name:       "PostgreSQL",
+	driver:     "postgres",
+	queries:    pgQueries,
+	migrations: pgMigrations,
+	qTableExists: `
+SELECT COUNT(*)
+FROM information_schema.tables
+WHERE table_schema = current_schema() AND table_name = $1`,
+	qRecordVersion: "INSERT INTO schema_version (version, stamp, description) VALUES ($1, $2, $3)",
+	canBackup:      false,
+	placeholder:    func(n int) string { return "$" + strconv.Itoa(n) },
+	likeOp:         "ILIKE",
+	inNet:          "%[2]s::inet <<= %[1]s::cidr",
+}
+
+// schemaVersion returns the most recent schema version known to this build.
+func (d *dialect) schemaVersion() int {
+	return d.migrations[len(d.migrations)-1].version
+} // func (d *dialect) schemaVersion() int
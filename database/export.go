@@ -0,0 +1,186 @@
+// /home/krylon/go/src/github.com/blicero/guang/database/export.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 16:22:36 krylon>
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/search"
+	"github.com/blicero/krylib"
+)
+
+// ExportFilter selects the Hosts and Ports to export. Query restricts the
+// Hosts and Ports like a search does, Since and Until restrict the Ports
+// to those scanned in that period. Zero values do not restrict anything.
+type ExportFilter struct {
+	Query *search.Query
+	Since time.Time
+	Until time.Time
+}
+
+// restrictsPorts returns true if the filter restricts the Ports, in which
+// case Hosts without any matching Ports are left out.
+func (f *ExportFilter) restrictsPorts() bool {
+	return (f.Query != nil && f.Query.HasPortFilter()) ||
+		!f.Since.IsZero() ||
+		!f.Until.IsZero()
+} // func (f *ExportFilter) restrictsPorts() bool
+
+// Export calls fn for every Host matching the filter, along with its
+// matching open Ports, ordered by their ID. The rows are read one at a
+// time, so the size of the export is not limited by the available memory.
+// If fn returns an error, Export stops and returns that error.
+func (db *sqlDB) Export(ctx context.Context, f ExportFilter, fn func(h *data.HostWithPorts) error) error {
+	var (
+		err        error
+		rows       *sql.Rows
+		join, on   string
+		b          = &searchBuilder{d: db.dialect, fts: db.fts}
+		q          = f.Query
+		cur        *data.HostWithPorts
+		curID      krylib.ID = krylib.INVALID_ID
+		hostDest   []any
+		hostFinish func()
+	)
+
+	if q == nil {
+		q = &search.Query{}
+	}
+
+	if f.restrictsPorts() {
+		join = "INNER JOIN"
+	} else {
+		join = "LEFT JOIN"
+	}
+
+	// SQLite's placeholders are numbered by their position, so the
+	// arguments have to be added in the order they appear in the query.
+	on = b.portCond(q)
+
+	if !f.Since.IsZero() {
+		on += " AND p.timestamp >= " + b.arg(f.Since.Unix())
+	}
+
+	if !f.Until.IsZero() {
+		on += " AND p.timestamp <= " + b.arg(f.Until.Unix())
+	}
+
+	var qstr = fmt.Sprintf(`
+SELECT %s,
+       p.id,
+       p.port,
+       p.timestamp,
+       p.reply,
+       p.service,
+       p.product,
+       p.version,
+       p.info,
+       p.os,
+       p.cpe
+FROM host h
+%s port p ON p.host_id = h.id AND %s
+WHERE %s
+ORDER BY h.id, p.port`,
+		hostSelect,
+		join,
+		on,
+		b.where(q))
+
+	if rows, err = db.queryText(ctx, qstr, b.args...); err != nil {
+		err = fmt.Errorf("Error querying Hosts to export: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	defer rows.Close() // nolint: errcheck
+
+	for rows.Next() {
+		var (
+			host                                         data.Host
+			portID, portNo, stamp                        *int64
+			reply                                        *string
+			service, product, version, info, osName, cpe *string
+		)
+
+		hostDest, hostFinish = hostColumns(&host)
+
+		if err = rows.Scan(append(hostDest,
+			&portID,
+			&portNo,
+			&stamp,
+			&reply,
+			&service,
+			&product,
+			&version,
+			&info,
+			&osName,
+			&cpe)...); err != nil {
+			err = fmt.Errorf("Error scanning row into Host and Port: %w", err)
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return err
+		}
+
+		hostFinish()
+
+		if host.ID != curID {
+			if cur != nil {
+				if err = fn(cur); err != nil {
+					return err
+				}
+			}
+
+			curID = host.ID
+			cur = &data.HostWithPorts{
+				Host:  host,
+				Ports: []data.Port{},
+			}
+		}
+
+		if portID == nil {
+			continue
+		}
+
+		cur.Ports = append(cur.Ports, data.Port{
+			ID:        krylib.ID(*portID),
+			HostID:    host.ID,
+			Port:      uint16(*portNo),
+			Timestamp: time.Unix(*stamp, 0),
+			Reply:     reply,
+			Software: data.Software{
+				Service: strValue(service),
+				Product: strValue(product),
+				Version: strValue(version),
+				Info:    strValue(info),
+				OS:      strValue(osName),
+				CPE:     strValue(cpe),
+			},
+		})
+	}
+
+	if err = rows.Err(); err != nil {
+		err = fmt.Errorf("Error querying Hosts to export: %w", classify(err))
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	} else if cur != nil {
+		return fn(cur)
+	}
+
+	return nil
+} // func (db *sqlDB) Export(ctx context.Context, f ExportFilter, fn func(h *data.HostWithPorts) error) error
+
+// strValue returns the string s points to, or an empty string if s is nil.
+func strValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+} // func strValue(s *string) string
@@ -0,0 +1,154 @@
+// /home/krylon/go/src/github.com/blicero/guang/database/export_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 16:22:36 krylon>
+
+package database
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/search"
+)
+
+func TestExport(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b *testBackend) {
+		var (
+			err   error
+			ctx   = context.Background()
+			reply = "SSH-2.0-OpenSSH_8.4p1 Debian-5"
+			old   = time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+			now   = time.Now().Truncate(time.Second)
+			hosts = []data.Host{
+				{Address: net.ParseIP("203.0.113.97"), Name: "a.export.example.org"},
+				{Address: net.ParseIP("203.0.113.98"), Name: "b.export.example.org"},
+				{Address: net.ParseIP("203.0.113.99"), Name: "c.export.example.org"},
+			}
+		)
+
+		for i := range hosts {
+			hosts[i].Source = data.HostSourceUser
+			if _, err = b.db.HostUpsert(ctx, &hosts[i]); err != nil {
+				t.Fatalf("Error adding Host %s: %s", hosts[i].Address, err.Error())
+			}
+		}
+
+		var results = []data.ScanResult{
+			{Host: hosts[0], Port: 22, Reply: &reply, Stamp: old,
+				Software: data.Software{Service: "ssh", Product: "OpenSSH", Version: "8.4p1"}},
+			{Host: hosts[0], Port: 80, Reply: &reply, Stamp: now},
+			{Host: hosts[1], Port: 22, Reply: &reply, Stamp: now},
+			{Host: hosts[1], Port: 23, Stamp: now},
+		}
+
+		for i := range results {
+			if err = b.db.PortAdd(ctx, &results[i]); err != nil {
+				t.Fatalf("Error adding Port %d: %s", results[i].Port, err.Error())
+			}
+		}
+
+		type testCase struct {
+			query string
+			since time.Time
+			ports map[string][]uint16
+		}
+
+		var cases = []testCase{
+			{
+				query: "net:203.0.113.96/29",
+				ports: map[string][]uint16{
+					"203.0.113.97": {22, 80},
+					"203.0.113.98": {22},
+					"203.0.113.99": {},
+				},
+			},
+			{
+				query: "net:203.0.113.96/29 port:22",
+				ports: map[string][]uint16{
+					"203.0.113.97": {22},
+					"203.0.113.98": {22},
+				},
+			},
+			{
+				query: "net:203.0.113.96/29",
+				since: now.Add(-time.Hour),
+				ports: map[string][]uint16{
+					"203.0.113.97": {80},
+					"203.0.113.98": {22},
+				},
+			},
+		}
+
+		for _, c := range cases {
+			var (
+				q   *search.Query
+				got = make(map[string][]uint16)
+			)
+
+			if q, err = search.Parse(c.query); err != nil {
+				t.Fatalf("Cannot parse query %q: %s", c.query, err.Error())
+			}
+
+			err = b.db.Export(ctx, ExportFilter{Query: q, Since: c.since}, func(h *data.HostWithPorts) error {
+				var ports = make([]uint16, len(h.Ports))
+				for i, p := range h.Ports {
+					ports[i] = p.Port
+				}
+				got[h.Host.Address.String()] = ports
+				return nil
+			})
+
+			if err != nil {
+				t.Fatalf("Error exporting %q: %s", c.query, err.Error())
+			} else if len(got) != len(c.ports) {
+				t.Errorf("%q (since %s): Expected %d Hosts, got %v",
+					c.query,
+					c.since,
+					len(c.ports),
+					got)
+				continue
+			}
+
+			for addr, ports := range c.ports {
+				if len(got[addr]) != len(ports) {
+					t.Errorf("%q: Expected ports %v for %s, got %v",
+						c.query,
+						ports,
+						addr,
+						got[addr])
+					continue
+				}
+
+				for i := range ports {
+					if got[addr][i] != ports[i] {
+						t.Errorf("%q: Expected ports %v for %s, got %v",
+							c.query,
+							ports,
+							addr,
+							got[addr])
+						break
+					}
+				}
+			}
+		}
+
+		// The Software of the Ports is exported, too.
+		err = b.db.Export(ctx, ExportFilter{Query: &search.Query{Ports: []uint16{22}}, Until: old}, func(h *data.HostWithPorts) error {
+			if h.Host.ID != hosts[0].ID {
+				return nil
+			} else if len(h.Ports) != 1 || h.Ports[0].Software.Product != "OpenSSH" {
+				t.Errorf("Unexpected Ports for %s: %#v", h.Host.Address, h.Ports)
+			}
+			return nil
+		})
+
+		if err != nil {
+			t.Fatalf("Error exporting: %s", err.Error())
+		}
+	})
+} // func TestExport(t *testing.T)
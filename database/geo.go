@@ -0,0 +1,95 @@
+// /home/krylon/go/src/github.com/blicero/guang/database/geo.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 16:10:50 krylon>
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/database/query"
+)
+
+// This file contains the methods dealing with the geographic location of
+// Hosts.
+
+// GeoPoint is a place the GeoIP database put Hosts at, with the number of
+// Hosts there, see HostGetLocations.
+type GeoPoint struct {
+	Latitude  float64
+	Longitude float64
+	Country   string
+	City      string
+	Hosts     int64
+}
+
+// HostSetGeo stores the location of a Host, both the text shown to the
+// user and the structured form.
+func (db *sqlDB) HostSetGeo(ctx context.Context, h *data.Host, location string, geo *data.GeoInfo) error {
+	var err error
+
+	if _, err = db.exec(ctx,
+		query.HostSetGeo,
+		location,
+		geo.Country,
+		geo.Subdivision,
+		geo.City,
+		geo.Latitude,
+		geo.Longitude,
+		int64(geo.Accuracy),
+		h.ID); err != nil {
+		err = fmt.Errorf("Error setting location of %s to %q: %w",
+			h.Address,
+			location,
+			err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	h.Location = location
+	h.Geo = *geo
+	return nil
+} // func (db *sqlDB) HostSetGeo(ctx context.Context, h *data.Host, location string, geo *data.GeoInfo) error
+
+// HostGetLocations returns up to <limit> places Hosts are located at,
+// those with the most Hosts first. Hosts without coordinates are left out.
+func (db *sqlDB) HostGetLocations(ctx context.Context, limit int) ([]GeoPoint, error) {
+	var (
+		err  error
+		rows *sql.Rows
+		list = make([]GeoPoint, 0)
+	)
+
+	if rows, err = db.query(ctx, query.HostGetLocations, limit); err != nil {
+		err = fmt.Errorf("Error querying locations: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck
+
+	for rows.Next() {
+		var p GeoPoint
+
+		if err = rows.Scan(&p.Latitude, &p.Longitude, &p.Country, &p.City, &p.Hosts); err != nil {
+			err = fmt.Errorf("Error scanning row into GeoPoint: %w", err)
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return nil, err
+		}
+
+		list = append(list, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		err = fmt.Errorf("Error querying locations: %w", classify(err))
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	return list, nil
+} // func (db *sqlDB) HostGetLocations(ctx context.Context, limit int) ([]GeoPoint, error)
@@ -0,0 +1,64 @@
+// /home/krylon/go/src/github.com/blicero/guang/database/geo_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 16:10:50 krylon>
+
+package database
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/blicero/guang/data"
+)
+
+func TestHostSetGeo(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b *testBackend) {
+		var (
+			err    error
+			stored *data.Host
+			points []GeoPoint
+			ctx    = context.Background()
+			host   = data.Host{
+				Address: net.ParseIP("203.0.113.84"),
+				Name:    "geo.example.org",
+				Source:  data.HostSourceUser,
+			}
+			geo = data.GeoInfo{
+				Country:     "IS",
+				Subdivision: "1",
+				City:        "Reykjavik",
+				Latitude:    64.1355,
+				Longitude:   -21.8954,
+				Accuracy:    20,
+			}
+		)
+
+		if _, err = b.db.HostUpsert(ctx, &host); err != nil {
+			t.Fatalf("Error adding Host %s: %s", host.Address, err.Error())
+		} else if err = b.db.HostSetGeo(ctx, &host, "Reykjavík, Island", &geo); err != nil {
+			t.Fatalf("Error setting location: %s", err.Error())
+		} else if stored, err = b.db.HostGetByID(ctx, host.ID); err != nil {
+			t.Fatalf("Error loading Host: %s", err.Error())
+		} else if stored.Location != "Reykjavík, Island" {
+			t.Errorf("Unexpected location: %q", stored.Location)
+		} else if stored.Geo != geo {
+			t.Errorf("Unexpected structured location: %#v", stored.Geo)
+		} else if points, err = b.db.HostGetLocations(ctx, 1000); err != nil {
+			t.Fatalf("Error loading locations: %s", err.Error())
+		}
+
+		for _, p := range points {
+			if p.Country == geo.Country && p.Latitude == geo.Latitude {
+				if p.Longitude != geo.Longitude || p.City != geo.City || p.Hosts != 1 {
+					t.Errorf("Unexpected location: %#v", p)
+				}
+				return
+			}
+		}
+
+		t.Errorf("%s is missing from the locations: %#v", geo.City, points)
+	})
+} // func TestHostSetGeo(t *testing.T)
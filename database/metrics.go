@@ -0,0 +1,93 @@
+// /home/krylon/go/src/github.com/blicero/guang/database/metrics.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 16:58:00 krylon>
+
+package database
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	dbRetries = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "guang",
+		Subsystem: "db",
+		Name:      "retries_total",
+		Help:      "Number of database operations retried because the database was busy.",
+	})
+
+	dbGaveUp = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "guang",
+		Subsystem: "db",
+		Name:      "retries_exhausted_total",
+		Help:      "Number of database operations that were still busy after the last retry.",
+	})
+)
+
+// batchCollector reports the statistics of a Batcher.
+type batchCollector struct {
+	b          *Batcher
+	batches    *prometheus.Desc
+	items      *prometheus.Desc
+	failed     *prometheus.Desc
+	size       *prometheus.Desc
+	latency    *prometheus.Desc
+	maxLatency *prometheus.Desc
+	sumLatency *prometheus.Desc
+}
+
+func newBatchCollector(b *Batcher, name string) *batchCollector {
+	var (
+		labels = prometheus.Labels{"batcher": name}
+		desc   = func(metric, help string) *prometheus.Desc {
+			return prometheus.NewDesc(
+				prometheus.BuildFQName("guang", "batch", metric),
+				help,
+				nil,
+				labels)
+		}
+	)
+
+	return &batchCollector{
+		b:          b,
+		batches:    desc("batches_total", "Number of batches written to the database."),
+		items:      desc("items_total", "Number of items written to the database in batches."),
+		failed:     desc("failed_total", "Number of batches that had to be written item by item."),
+		size:       desc("last_size", "Number of items in the last batch."),
+		latency:    desc("last_latency_seconds", "How long it took to write the last batch."),
+		maxLatency: desc("max_latency_seconds", "The longest it took to write a batch."),
+		sumLatency: desc("latency_seconds_total", "How long it took to write all batches."),
+	}
+} // func newBatchCollector(b *Batcher, name string) *batchCollector
+
+// Describe implements prometheus.Collector.
+func (c *batchCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.batches
+	ch <- c.items
+	ch <- c.failed
+	ch <- c.size
+	ch <- c.latency
+	ch <- c.maxLatency
+	ch <- c.sumLatency
+} // func (c *batchCollector) Describe(ch chan<- *prometheus.Desc)
+
+// Collect implements prometheus.Collector.
+func (c *batchCollector) Collect(ch chan<- prometheus.Metric) {
+	var (
+		s      = c.b.Stats()
+		metric = func(d *prometheus.Desc, t prometheus.ValueType, v float64) {
+			ch <- prometheus.MustNewConstMetric(d, t, v)
+		}
+	)
+
+	metric(c.batches, prometheus.CounterValue, float64(s.Batches))
+	metric(c.items, prometheus.CounterValue, float64(s.Items))
+	metric(c.failed, prometheus.CounterValue, float64(s.Failed))
+	metric(c.size, prometheus.GaugeValue, float64(s.LastSize))
+	metric(c.latency, prometheus.GaugeValue, s.LastLatency.Seconds())
+	metric(c.maxLatency, prometheus.GaugeValue, s.MaxLatency.Seconds())
+	metric(c.sumLatency, prometheus.CounterValue, s.TotalLatency.Seconds())
+} // func (c *batchCollector) Collect(ch chan<- prometheus.Metric)
@@ -0,0 +1,212 @@
+// /home/krylon/go/src/github.com/blicero/guang/database/migrate.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 13:51:53 krylon>
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrSchemaTooNew is returned by OpenDB if the database was created or
+// upgraded by a newer version of the application than the one running.
+var ErrSchemaTooNew = errors.New("database schema is newer than this version of the application")
+
+const backupStampFormat = "20060102_150405"
+
+// currentVersion determines which version of the schema the database is at.
+// A database without any tables is at version 0. tracked is false if the
+// database has no schema_version table.
+func (db *sqlDB) currentVersion(ctx context.Context) (version int, tracked bool, err error) {
+	var (
+		cnt int64
+		row *sql.Row
+	)
+
+	row = db.db.QueryRowContext(ctx, db.dialect.qTableExists, "schema_version")
+	if err = row.Scan(&cnt); err != nil {
+		return 0, false, classify(err)
+	} else if cnt > 0 {
+		row = db.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_version")
+		if err = row.Scan(&version); err != nil {
+			return 0, false, classify(err)
+		}
+
+		return version, true, nil
+	}
+
+	row = db.db.QueryRowContext(ctx, db.dialect.qTableExists, "host")
+	if err = row.Scan(&cnt); err != nil {
+		return 0, false, classify(err)
+	} else if cnt > 0 {
+		// The database was created before we started to keep track
+		// of schema versions.
+		return 1, false, nil
+	}
+
+	return 0, false, nil
+} // func (db *sqlDB) currentVersion(ctx context.Context) (version int, tracked bool, err error)
+
+// backup creates a copy of the SQLite database file next to the original,
+// tagged with the schema version and the current time, and returns its path.
+func (db *sqlDB) backup(ctx context.Context, version int) (string, error) {
+	var (
+		err  error
+		path = fmt.Sprintf("%s.v%d.%s.bak",
+			db.path,
+			version,
+			time.Now().Format(backupStampFormat))
+	)
+
+	if _, err = db.db.ExecContext(ctx, "VACUUM INTO ?", path); err != nil {
+		return "", fmt.Errorf("Error backing up database to %s: %w",
+			path,
+			classify(err))
+	}
+
+	return path, nil
+} // func (db *sqlDB) backup(ctx context.Context, version int) (string, error)
+
+// migrate brings the database schema up to the most recent version,
+// applying every migration that has not been applied yet. If the database
+// is not empty, a backup is made first.
+func (db *sqlDB) migrate(ctx context.Context) error {
+	var (
+		err        error
+		version    int
+		tracked    bool
+		backupPath string
+		latest     = db.dialect.schemaVersion()
+	)
+
+	if version, tracked, err = db.currentVersion(ctx); err != nil {
+		err = fmt.Errorf("Cannot determine schema version of %s: %w",
+			db.path,
+			err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	} else if version > latest {
+		err = fmt.Errorf("%w: %s is at version %d, we only know version %d",
+			ErrSchemaTooNew,
+			db.path,
+			version,
+			latest)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	} else if version == latest && tracked {
+		return nil
+	} else if version > 0 && !db.dialect.canBackup {
+		db.log.Printf("[WARN] Upgrading %s database %s from version %d to %d without a backup, you might want to create one first\n",
+			db.dialect.name,
+			db.path,
+			version,
+			latest)
+	} else if version > 0 {
+		if backupPath, err = db.backup(ctx, version); err != nil {
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return err
+		}
+
+		db.log.Printf("[INFO] Upgrading database %s from version %d to %d, backup is at %s\n",
+			db.path,
+			version,
+			latest,
+			backupPath)
+	}
+
+	if err = db.Begin(ctx); err != nil {
+		return err
+	}
+
+	for _, q := range schemaQueries {
+		if _, err = db.tx.ExecContext(ctx, q); err != nil {
+			db.Rollback() // nolint: errcheck
+			err = fmt.Errorf("Error creating schema_version table: %w", classify(err))
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return err
+		}
+	}
+
+	// A database from before we tracked versions already has the schema
+	// of version 1, we only need to record that fact.
+	if version == 1 && !tracked {
+		if err = db.recordVersion(ctx, db.dialect.migrations[0]); err != nil {
+			db.Rollback() // nolint: errcheck
+			return err
+		}
+	}
+
+	if err = db.Commit(); err != nil {
+		return err
+	}
+
+	for _, m := range db.dialect.migrations {
+		if m.version <= version {
+			continue
+		} else if err = db.applyMigration(ctx, m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+} // func (db *sqlDB) migrate(ctx context.Context) error
+
+// recordVersion notes in the schema_version table that the given migration
+// has been applied. It must be called with a transaction in progress.
+func (db *sqlDB) recordVersion(ctx context.Context, m migration) error {
+	var err error
+
+	if _, err = db.tx.ExecContext(
+		ctx,
+		db.dialect.qRecordVersion,
+		m.version,
+		time.Now().Unix(),
+		m.description); err != nil {
+		err = fmt.Errorf("Error recording schema version %d: %w",
+			m.version,
+			classify(err))
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	return nil
+} // func (db *sqlDB) recordVersion(ctx context.Context, m migration) error
+
+// applyMigration runs the queries of a single migration in a transaction.
+func (db *sqlDB) applyMigration(ctx context.Context, m migration) error {
+	var err error
+
+	db.log.Printf("[INFO] Applying migration %d (%s) to %s\n",
+		m.version,
+		m.description,
+		db.path)
+
+	if err = db.Begin(ctx); err != nil {
+		return err
+	}
+
+	for _, q := range m.queries {
+		if _, err = db.tx.ExecContext(ctx, q); err != nil {
+			db.Rollback() // nolint: errcheck
+			err = fmt.Errorf("Error in migration %d executing query %s: %w",
+				m.version,
+				q,
+				classify(err))
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return err
+		}
+	}
+
+	if err = db.recordVersion(ctx, m); err != nil {
+		db.Rollback() // nolint: errcheck
+		return err
+	}
+
+	return db.Commit()
+} // func (db *sqlDB) applyMigration(ctx context.Context, m migration) error
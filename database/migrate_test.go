@@ -0,0 +1,192 @@
+// /home/krylon/go/src/github.com/blicero/guang/database/migrate_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 14:34:15 krylon>
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/blicero/guang/data"
+)
+
+// makeFixture creates a database at the given path whose schema is at the
+// given version and adds a host to it. Version 0 means a database created
+// before we kept track of schema versions.
+func makeFixture(path string, version int) error {
+	var (
+		err error
+		db  *sql.DB
+	)
+
+	if db, err = sql.Open("sqlite3", path); err != nil {
+		return err
+	}
+
+	defer db.Close() // nolint: errcheck
+
+	if version == 0 {
+		for _, q := range initQueries {
+			if _, err = db.Exec(q); err != nil {
+				return err
+			}
+		}
+	} else {
+		for _, q := range schemaQueries {
+			if _, err = db.Exec(q); err != nil {
+				return err
+			}
+		}
+
+		for _, m := range migrations[:version] {
+			for _, q := range m.queries {
+				if _, err = db.Exec(q); err != nil {
+					return fmt.Errorf("migration %d: %w", m.version, err)
+				}
+			}
+
+			if _, err = db.Exec(
+				"INSERT INTO schema_version (version, stamp) VALUES (?, ?)",
+				m.version,
+				time.Now().Unix()); err != nil {
+				return err
+			}
+		}
+	}
+
+	var now = time.Now().Unix()
+
+	if _, err = db.Exec(
+		"INSERT INTO host (id, addr, name, source, add_stamp) VALUES (1, '192.168.0.1', 'finn.krylon.net', 0, ?)",
+		now); err != nil || version < 2 {
+		return err
+	}
+
+	// Starting with version 2, names and addresses are stored separately.
+	if _, err = db.Exec(
+		"INSERT INTO host_address (host_id, addr, source, first_seen, last_seen) VALUES (1, '192.168.0.1', 0, ?1, ?1)",
+		now); err != nil {
+		return err
+	}
+
+	_, err = db.Exec(
+		"INSERT INTO host_name (host_id, name, source, first_seen, last_seen) VALUES (1, 'finn.krylon.net', 0, ?1, ?1)",
+		now)
+
+	return err
+} // func makeFixture(path string, version int) error
+
+func TestMigrateFixtures(t *testing.T) {
+	var latest = dialectSQLite.schemaVersion()
+
+	for version := 0; version < latest; version++ {
+		var (
+			err     error
+			db      HostDB
+			current int
+			host    *data.Host
+			backups []string
+			path    = filepath.Join(t.TempDir(), fmt.Sprintf("fixture_v%d.db", version))
+		)
+
+		if err = makeFixture(path, version); err != nil {
+			t.Fatalf("Cannot create fixture for version %d: %s",
+				version,
+				err.Error())
+		} else if db, err = OpenDB(path); err != nil {
+			t.Fatalf("Cannot open/upgrade fixture for version %d: %s",
+				version,
+				err.Error())
+		}
+
+		if current, _, err = db.(*sqlDB).currentVersion(context.Background()); err != nil {
+			t.Errorf("Cannot get schema version of upgraded fixture %d: %s",
+				version,
+				err.Error())
+		} else if current != latest {
+			t.Errorf("Fixture for version %d was upgraded to version %d, expected %d",
+				version,
+				current,
+				latest)
+		} else if host, err = db.HostGetByID(context.Background(), 1); err != nil {
+			t.Errorf("Cannot load Host from upgraded fixture %d: %s",
+				version,
+				err.Error())
+		} else if len(host.Addresses) != 1 || len(host.Names) != 1 {
+			t.Errorf("Host from upgraded fixture %d has %d addresses and %d names, expected 1 each",
+				version,
+				len(host.Addresses),
+				len(host.Names))
+		}
+
+		db.Close()
+
+		if backups, err = filepath.Glob(path + ".v*.bak"); err != nil {
+			t.Errorf("Cannot look for backups of fixture %d: %s",
+				version,
+				err.Error())
+		} else if len(backups) != 1 {
+			t.Errorf("Expected one backup of fixture %d, found %d",
+				version,
+				len(backups))
+		}
+	}
+} // func TestMigrateFixtures(t *testing.T)
+
+func TestMigrateTooNew(t *testing.T) {
+	var (
+		err  error
+		path = filepath.Join(t.TempDir(), "too_new.db")
+		fdb  *sql.DB
+	)
+
+	if err = makeFixture(path, dialectSQLite.schemaVersion()); err != nil {
+		t.Fatalf("Cannot create fixture: %s", err.Error())
+	} else if fdb, err = sql.Open("sqlite3", path); err != nil {
+		t.Fatalf("Cannot open fixture: %s", err.Error())
+	} else if _, err = fdb.Exec(
+		"INSERT INTO schema_version (version, stamp) VALUES (?, ?)",
+		dialectSQLite.schemaVersion()+1,
+		time.Now().Unix()); err != nil {
+		fdb.Close() // nolint: errcheck
+		t.Fatalf("Cannot bump schema version of fixture: %s", err.Error())
+	}
+
+	fdb.Close() // nolint: errcheck
+
+	if _, err = OpenDB(path); err == nil {
+		t.Error("OpenDB accepted a database with a newer schema")
+	} else if !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("Unexpected error opening database with newer schema: %s",
+			err.Error())
+	}
+} // func TestMigrateTooNew(t *testing.T)
+
+func TestMigrationsInSync(t *testing.T) {
+	if len(migrations) != len(pgMigrations) {
+		t.Fatalf("SQLite has %d migrations, PostgreSQL has %d",
+			len(migrations),
+			len(pgMigrations))
+	}
+
+	for idx, m := range migrations {
+		if m.version != idx+1 {
+			t.Errorf("SQLite migration #%d has version %d",
+				idx,
+				m.version)
+		} else if pgMigrations[idx].version != m.version {
+			t.Errorf("PostgreSQL migration #%d has version %d, expected %d",
+				idx,
+				pgMigrations[idx].version,
+				m.version)
+		}
+	}
+} // func TestMigrationsInSync(t *testing.T)
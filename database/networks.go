@@ -0,0 +1,212 @@
+// /home/krylon/go/src/github.com/blicero/guang/database/networks.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 16:04:01 krylon>
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/database/query"
+)
+
+// This file contains the methods dealing with the ownership of networks:
+// the autonomous systems Hosts are part of and the address blocks the
+// regional internet registries delegated.
+
+// addrKey returns the address as 32 hex digits of its 16 byte form, so
+// that comparing the keys as strings compares the addresses, in SQLite and
+// PostgreSQL alike. IPv4 addresses sort before all IPv6 addresses but the
+// IPv4-mapped ones.
+func addrKey(addr net.IP) string {
+	return hex.EncodeToString(addr.To16())
+} // func addrKey(addr net.IP) string
+
+// parseAddrKey is the reverse of addrKey.
+func parseAddrKey(key string) (net.IP, error) {
+	var (
+		err error
+		raw []byte
+	)
+
+	if raw, err = hex.DecodeString(key); err != nil {
+		return nil, err
+	} else if len(raw) != net.IPv6len {
+		return nil, fmt.Errorf("Invalid address key %q", key)
+	}
+
+	return net.IP(raw), nil
+} // func parseAddrKey(key string) (net.IP, error)
+
+// ASCount is the number of Hosts we know in an autonomous system, see
+// HostGetASReport.
+type ASCount struct {
+	ASN   uint32
+	Org   string
+	Hosts int64
+}
+
+// HostSetAS stores what we know about the network a Host is part of.
+func (db *sqlDB) HostSetAS(ctx context.Context, h *data.Host, as *data.ASInfo) error {
+	var (
+		err       error
+		allocated int64
+	)
+
+	if !as.Allocated.IsZero() {
+		allocated = as.Allocated.Unix()
+	}
+
+	if _, err = db.exec(ctx,
+		query.HostSetAS,
+		int64(as.ASN),
+		as.Org,
+		as.Prefix,
+		as.Registry,
+		allocated,
+		h.ID); err != nil {
+		err = fmt.Errorf("Error setting AS of %s to AS%d: %w",
+			h.Address,
+			as.ASN,
+			err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	h.AS = *as
+	return nil
+} // func (db *sqlDB) HostSetAS(ctx context.Context, h *data.Host, as *data.ASInfo) error
+
+// HostGetASReport returns up to <limit> autonomous systems, those we know
+// the most Hosts in first.
+func (db *sqlDB) HostGetASReport(ctx context.Context, limit int) ([]ASCount, error) {
+	var (
+		err  error
+		rows *sql.Rows
+		list = make([]ASCount, 0)
+	)
+
+	if rows, err = db.query(ctx, query.HostGetASReport, limit); err != nil {
+		err = fmt.Errorf("Error querying autonomous systems: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck
+
+	for rows.Next() {
+		var (
+			asn int64
+			c   ASCount
+		)
+
+		if err = rows.Scan(&asn, &c.Org, &c.Hosts); err != nil {
+			err = fmt.Errorf("Error scanning row into ASCount: %w", err)
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return nil, err
+		}
+
+		c.ASN = uint32(asn)
+		list = append(list, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		err = fmt.Errorf("Error querying autonomous systems: %w", classify(err))
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	return list, nil
+} // func (db *sqlDB) HostGetASReport(ctx context.Context, limit int) ([]ASCount, error)
+
+// DelegationDelete removes all Delegations of the given registry, so they
+// can be replaced by those from a newer delegation file.
+func (db *sqlDB) DelegationDelete(ctx context.Context, registry string) error {
+	var err error
+
+	if _, err = db.exec(ctx, query.DelegationDelete, registry); err != nil {
+		err = fmt.Errorf("Error deleting Delegations of %s: %w", registry, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	return nil
+} // func (db *sqlDB) DelegationDelete(ctx context.Context, registry string) error
+
+// DelegationAdd adds a Delegation to the database.
+func (db *sqlDB) DelegationAdd(ctx context.Context, d *data.Delegation) error {
+	var err error
+
+	if _, err = db.exec(ctx,
+		query.DelegationAdd,
+		d.Registry,
+		d.Country,
+		addrKey(d.First),
+		addrKey(d.Last),
+		d.Date.Unix(),
+		d.Status); err != nil {
+		err = fmt.Errorf("Error adding Delegation of %s - %s by %s: %w",
+			d.First,
+			d.Last,
+			d.Registry,
+			err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	return nil
+} // func (db *sqlDB) DelegationAdd(ctx context.Context, d *data.Delegation) error
+
+// DelegationLookup returns the Delegation covering the given address.
+// If there is none, ErrNotFound is returned.
+func (db *sqlDB) DelegationLookup(ctx context.Context, addr net.IP) (*data.Delegation, error) {
+	var (
+		err         error
+		rows        *sql.Rows
+		stamp       int64
+		first, last string
+		d           = new(data.Delegation)
+	)
+
+	if rows, err = db.query(ctx, query.DelegationLookup, addrKey(addr)); err != nil {
+		err = fmt.Errorf("Error looking up Delegation of %s: %w", addr, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck
+
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			err = fmt.Errorf("Error looking up Delegation of %s: %w", addr, classify(err))
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return nil, err
+		}
+		return nil, fmt.Errorf("Delegation of %s: %w", addr, ErrNotFound)
+	} else if err = rows.Scan(&d.Registry, &d.Country, &first, &last, &stamp, &d.Status); err != nil {
+		err = fmt.Errorf("Error scanning row into Delegation: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	} else if d.First, err = parseAddrKey(first); err != nil {
+		return nil, err
+	} else if d.Last, err = parseAddrKey(last); err != nil {
+		return nil, err
+	}
+
+	// Delegations do not overlap, so if the closest block below the
+	// address ends before it, no block covers it.
+	if last < addrKey(addr) {
+		return nil, fmt.Errorf("Delegation of %s: %w", addr, ErrNotFound)
+	}
+
+	d.Date = time.Unix(stamp, 0)
+	return d, nil
+} // func (db *sqlDB) DelegationLookup(ctx context.Context, addr net.IP) (*data.Delegation, error)
@@ -0,0 +1,135 @@
+// /home/krylon/go/src/github.com/blicero/guang/database/networks_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 16:04:01 krylon>
+
+package database
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/blicero/guang/data"
+)
+
+func TestDelegations(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b *testBackend) {
+		var (
+			err  error
+			d    *data.Delegation
+			ctx  = context.Background()
+			date = time.Date(2010, 7, 12, 0, 0, 0, 0, time.UTC)
+			list = []data.Delegation{
+				{
+					Registry: "ripencc",
+					Country:  "DE",
+					First:    net.ParseIP("192.0.2.0"),
+					Last:     net.ParseIP("192.0.2.127"),
+					Date:     date,
+					Status:   "allocated",
+				},
+				{
+					Registry: "ripencc",
+					Country:  "NL",
+					First:    net.ParseIP("2001:db8::"),
+					Last:     net.ParseIP("2001:db8:ffff:ffff:ffff:ffff:ffff:ffff"),
+					Date:     date,
+					Status:   "assigned",
+				},
+			}
+		)
+
+		for i := range list {
+			if err = b.db.DelegationAdd(ctx, &list[i]); err != nil {
+				t.Fatalf("Error adding Delegation: %s", err.Error())
+			}
+		}
+
+		type testCase struct {
+			addr    string
+			country string
+		}
+
+		var cases = []testCase{
+			{"192.0.2.0", "DE"},
+			{"192.0.2.77", "DE"},
+			{"192.0.2.127", "DE"},
+			{"192.0.2.128", ""},
+			{"192.0.1.255", ""},
+			{"2001:db8:1::42", "NL"},
+			{"2001:db9::1", ""},
+		}
+
+		for _, c := range cases {
+			d, err = b.db.DelegationLookup(ctx, net.ParseIP(c.addr))
+
+			if c.country == "" {
+				if !errors.Is(err, ErrNotFound) {
+					t.Errorf("Unexpected result looking up %s: %v, %v", c.addr, d, err)
+				}
+			} else if err != nil {
+				t.Errorf("Error looking up %s: %s", c.addr, err.Error())
+			} else if d.Country != c.country || d.Registry != "ripencc" || !d.Date.Equal(date) {
+				t.Errorf("Unexpected Delegation for %s: %#v", c.addr, d)
+			}
+		}
+
+		if err = b.db.DelegationDelete(ctx, "ripencc"); err != nil {
+			t.Fatalf("Error deleting Delegations: %s", err.Error())
+		} else if _, err = b.db.DelegationLookup(ctx, net.ParseIP("192.0.2.77")); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Delegation was not deleted: %v", err)
+		}
+	})
+} // func TestDelegations(t *testing.T)
+
+func TestHostSetAS(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b *testBackend) {
+		var (
+			err    error
+			stored *data.Host
+			report []ASCount
+			ctx    = context.Background()
+			host   = data.Host{
+				Address: net.ParseIP("203.0.113.83"),
+				Name:    "as.example.org",
+				Source:  data.HostSourceUser,
+			}
+			as = data.ASInfo{
+				ASN:       64511,
+				Org:       "Reserved Networks",
+				Prefix:    "203.0.113.0/24",
+				Registry:  "apnic",
+				Allocated: time.Date(2005, 1, 1, 0, 0, 0, 0, time.UTC),
+			}
+		)
+
+		if _, err = b.db.HostUpsert(ctx, &host); err != nil {
+			t.Fatalf("Error adding Host %s: %s", host.Address, err.Error())
+		} else if err = b.db.HostSetAS(ctx, &host, &as); err != nil {
+			t.Fatalf("Error setting AS: %s", err.Error())
+		} else if stored, err = b.db.HostGetByID(ctx, host.ID); err != nil {
+			t.Fatalf("Error loading Host: %s", err.Error())
+		} else if !stored.AS.Allocated.Equal(as.Allocated) {
+			t.Errorf("Unexpected allocation date: %s", stored.AS.Allocated)
+		} else if stored.AS.ASN != as.ASN || stored.AS.Org != as.Org || stored.AS.Prefix != as.Prefix || stored.AS.Registry != as.Registry {
+			t.Errorf("Unexpected AS: %#v", stored.AS)
+		} else if report, err = b.db.HostGetASReport(ctx, 1000); err != nil {
+			t.Fatalf("Error loading AS report: %s", err.Error())
+		}
+
+		for _, c := range report {
+			if c.ASN == as.ASN {
+				if c.Org != as.Org || c.Hosts != 1 {
+					t.Errorf("Unexpected AS in report: %#v", c)
+				}
+				return
+			}
+		}
+
+		t.Errorf("AS%d is missing from the report: %#v", as.ASN, report)
+	})
+} // func TestHostSetAS(t *testing.T)
@@ -2,27 +2,27 @@
 // -*- mode: go; coding: utf-8; -*-
 // Created on 31. 10. 2022 by Benjamin Walkenhorst
 // (c) 2022 Benjamin Walkenhorst
-// Time-stamp: <2022-10-31 19:41:23 krylon>
+// Time-stamp: <2026-10-19 14:47:35 krylon>
 
 package database
 
 import (
 	"fmt"
-	"log"
 	"sync"
 
 	"github.com/blicero/guang/common"
+	"github.com/blicero/guang/logdomain"
 )
 
 type dblink struct {
-	db   *HostDB
+	db   HostDB
 	next *dblink
 }
 
 // Pool is a pool of database connections
 type Pool struct {
 	cnt   int
-	log   *log.Logger
+	log   *common.Logger
 	link  *dblink
 	lock  sync.RWMutex
 	empty *sync.Cond
@@ -43,8 +43,7 @@ func NewPool(cnt int) (*Pool, error) {
 		return nil, fmt.Errorf(
 			"NewPool expects a positive number, you passed %d",
 			cnt)
-		// } else if pool.log, err = common.GetLogger(logdomain.DBPool); err != nil {
-	} else if pool.log, err = common.GetLogger("DBPool"); err != nil {
+	} else if pool.log, err = common.GetLogger(logdomain.DBPool); err != nil {
 		return nil, err
 	}
 
@@ -82,7 +81,7 @@ func (pool *Pool) Close() error {
 
 // Get returns a DB connection from the pool.
 // If the pool is empty, it waits for a connection to be returned.
-func (pool *Pool) Get() *HostDB {
+func (pool *Pool) Get() HostDB {
 	var link *dblink
 
 	pool.lock.Lock()
@@ -105,8 +104,8 @@ WAIT_FOR_LINK:
 
 // GetNoWait returns a DB connection from the pool.
 // If the pool is empty, it creates a new one.
-func (pool *Pool) GetNoWait() (*HostDB, error) {
-	var db *HostDB
+func (pool *Pool) GetNoWait() (HostDB, error) {
+	var db HostDB
 	var err error
 
 	pool.lock.Lock()
@@ -124,15 +123,15 @@ func (pool *Pool) GetNoWait() (*HostDB, error) {
 	}
 
 	return db, nil
-} // func (pool *Pool) GetNoWait() *HostDB
+} // func (pool *Pool) GetNoWait() HostDB
 
 // Put returns a DB connection to the pool.
-func (pool *Pool) Put(db *HostDB) {
+func (pool *Pool) Put(db HostDB) {
 	link := &dblink{
 		db: db,
 	}
 
-	if db.tx != nil {
+	if sdb, ok := db.(*sqlDB); ok && sdb.tx != nil {
 		pool.log.Println("[INFO] DB has pending transaction, rolling back.")
 		if err := db.Rollback(); err != nil {
 			pool.log.Printf("[ERROR] Cannot roll back transaction: %s\n",
@@ -146,7 +145,7 @@ func (pool *Pool) Put(db *HostDB) {
 	pool.cnt++
 	pool.lock.Unlock()
 	pool.empty.Signal()
-} // func (pool *Pool) Put(db *HostDB)
+} // func (pool *Pool) Put(db HostDB)
 
 // IsEmpty returns true if the pool is currently empty.
 func (pool *Pool) IsEmpty() bool {
@@ -0,0 +1,70 @@
+// /home/krylon/go/src/github.com/blicero/guang/database/postgres.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 14:47:35 krylon>
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+
+	"github.com/blicero/guang/common"
+	"github.com/blicero/guang/database/query"
+	"github.com/blicero/guang/logdomain"
+	_ "github.com/lib/pq" // Import the database driver
+	"github.com/muesli/cache2go"
+)
+
+// OpenPostgres connects to the PostgreSQL database identified by the given
+// URL and brings its schema up to date.
+func OpenPostgres(dsn string) (HostDB, error) {
+	var (
+		err error
+		u   *url.URL
+		db  = &sqlDB{
+			dialect:   dialectPostgres,
+			stmtTable: make(map[query.ID]*sql.Stmt),
+		}
+	)
+
+	if db.log, err = common.GetLogger(logdomain.Database); err != nil {
+		err = fmt.Errorf("Error creating logger for HostDB: %w", err)
+		fmt.Println(err.Error())
+		return nil, err
+	} else if u, err = url.Parse(dsn); err != nil {
+		err = fmt.Errorf("Invalid PostgreSQL URL: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	// We do not want the password to end up in the log file.
+	db.path = u.Redacted()
+	db.hostCache = cache2go.Cache("host:" + db.path)
+
+	openLock.Lock()
+	defer openLock.Unlock()
+
+	if db.db, err = sql.Open(db.dialect.driver, dsn); err != nil {
+		err = fmt.Errorf("Error opening database at %s: %w", db.path, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	} else if err = db.db.PingContext(context.Background()); err != nil {
+		err = fmt.Errorf("Cannot connect to database at %s: %w", db.path, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		db.db.Close()
+		return nil, err
+	} else if err = db.migrate(context.Background()); err != nil {
+		err = fmt.Errorf("Error initializing database at %s: %w",
+			db.path,
+			err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		db.db.Close()
+		return nil, err
+	}
+
+	return db, nil
+} // func OpenPostgres(dsn string) (HostDB, error)
@@ -2,7 +2,7 @@
 // -*- mode: go; coding: utf-8; -*-
 // Created on 03. 11. 2022 by Benjamin Walkenhorst
 // (c) 2022 Benjamin Walkenhorst
-// Time-stamp: <2023-05-19 16:47:32 krylon>
+// Time-stamp: <2026-10-19 16:10:50 krylon>
 
 package database
 
@@ -12,17 +12,31 @@ var dbQueries map[query.ID]string = map[query.ID]string{
 	query.HostAdd: `
 INSERT INTO host (addr, name, source, add_stamp)
           VALUES (   ?,    ?,      ?,         ?)
+RETURNING id
 `,
-	query.HostGetByID: "SELECT addr, name, COALESCE(location, ''), COALESCE(os, ''), source, add_stamp FROM host WHERE id = ?",
-	query.HostGetAll:  "SELECT id, addr, COALESCE(location, ''), COALESCE(os, ''), name, source, add_stamp FROM host",
+	query.HostGetByID: "SELECT id, addr, name, COALESCE(location, ''), COALESCE(os, ''), os_version, os_confidence, source, add_stamp, asn, as_org, prefix, registry, allocated, country, subdivision, city, latitude, longitude, accuracy FROM host WHERE id = ?",
+	query.HostGetAll:  "SELECT id, addr, name, COALESCE(location, ''), COALESCE(os, ''), os_version, os_confidence, source, add_stamp, asn, as_org, prefix, registry, allocated, country, subdivision, city, latitude, longitude, accuracy FROM host",
 	query.HostGetRandom: `
 SELECT id,
        addr,
        name,
        COALESCE(location, ''),
        COALESCE(os, ''),
+       os_version,
+       os_confidence,
        source,
-       add_stamp
+       add_stamp,
+       asn,
+       as_org,
+       prefix,
+       registry,
+       allocated,
+       country,
+       subdivision,
+       city,
+       latitude,
+       longitude,
+       accuracy
 FROM host
 LIMIT ?
 OFFSET ABS(RANDOM()) % MAX((SELECT COUNT(*) FROM host), 1)
@@ -44,16 +58,29 @@ FROM port P
 INNER JOIN host h ON p.host_id = h.id
 WHERE p.reply IS NOT NULL
 `,
-	query.HostSetOS:       `UPDATE host SET os = ? WHERE id = ?`,
+	query.HostSetOS:       `UPDATE host SET os = ?, os_version = ?, os_confidence = ? WHERE id = ?`,
 	query.HostSetLocation: `UPDATE host SET location = ? WHERE id = ?`,
 	query.PortAdd: `
-INSERT INTO port (host_id, port, timestamp, reply)
-          VALUES (      ?,    ?,         ?,     ?)
+INSERT INTO port (host_id, port, timestamp, reply, service, product, version, info, os, cpe)
+          VALUES (      ?,    ?,         ?,     ?,       ?,       ?,       ?,    ?,  ?,   ?)
+ON CONFLICT (host_id, port) DO UPDATE
+SET timestamp = excluded.timestamp,
+    reply = excluded.reply,
+    service = excluded.service,
+    product = excluded.product,
+    version = excluded.version,
+    info = excluded.info,
+    os = excluded.os,
+    cpe = excluded.cpe
 `,
-	query.PortGetByHost: "SELECT id, port, timestamp, reply FROM port WHERE host_id = ?",
-	query.XfrAdd:        "INSERT INTO xfr (zone, start, status) VALUES (?, ?, 0)",
-	query.XfrGetByZone:  "SELECT id, start, end, status FROM xfr WHERE zone = ?",
-	query.XfrFinish:     "UPDATE xfr SET end = ?, status = ? WHERE id = ?",
+	query.PortGetByHost: `
+SELECT id, port, timestamp, reply, service, product, version, info, os, cpe
+FROM port
+WHERE host_id = ?
+`,
+	query.XfrAdd:       "INSERT INTO xfr (zone, start, status) VALUES (?, ?, 0) RETURNING id",
+	query.XfrGetByZone: "SELECT id, start, end, status FROM xfr WHERE zone = ?",
+	query.XfrFinish:    "UPDATE xfr SET end = ?, status = ? WHERE id = ?",
 	query.XfrGetUnfinished: `
 SELECT id, 
        zone, 
@@ -84,5 +111,415 @@ SELECT
 FROM port
 WHERE reply IS NOT NULL AND timestamp > ?
 ORDER BY port
+`,
+	query.HostUpsert: `
+INSERT INTO host (addr, name, source, add_stamp)
+          VALUES (?, ?, ?, ?)
+ON CONFLICT (addr) DO NOTHING
+RETURNING id
+`,
+	query.HostGetByAddr: `
+SELECT id, addr, name, COALESCE(location, ''), COALESCE(os, ''), os_version, os_confidence, source, add_stamp, asn, as_org, prefix, registry, allocated, country, subdivision, city, latitude, longitude, accuracy
+FROM host
+WHERE addr = ?1 OR id = (SELECT host_id FROM host_address WHERE addr = ?1)
+`,
+	query.HostUpdateSource: `
+UPDATE host
+SET name = ?, source = ?
+WHERE id = ? AND source = ?
+`,
+	query.HostAddressAdd: `
+INSERT INTO host_address (host_id, addr, source, first_seen, last_seen)
+                  VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (addr) DO UPDATE SET last_seen = excluded.last_seen
+`,
+	query.HostAddressGetByHost: `
+SELECT addr, source, first_seen, last_seen
+FROM host_address
+WHERE host_id = ?
+ORDER BY first_seen, id
+`,
+	query.HostNameAdd: `
+INSERT INTO host_name (host_id, name, source, first_seen, last_seen)
+               VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (host_id, name) DO UPDATE SET last_seen = excluded.last_seen
+`,
+	query.HostNameGetByHost: `
+SELECT name, source, first_seen, last_seen
+FROM host_name
+WHERE host_id = ?
+ORDER BY first_seen, id
+`,
+	query.HostNameLookup: "SELECT host_id, source FROM host_name WHERE name = ?",
+	query.PortGetPage: `
+SELECT id, host_id, port, timestamp, reply, service, product, version, info, os, cpe
+FROM port
+WHERE (?1 = 0 OR port = ?1)
+  AND (?2 = 0 OR host_id = ?2)
+  AND timestamp >= ?3
+  AND (?4 = 0 OR reply IS NOT NULL)
+  AND (?5 = '' OR product = ?5)
+  AND (?6 = '' OR version = ?6)
+ORDER BY timestamp DESC, id DESC
+LIMIT ?7 OFFSET ?8`,
+	query.PortGetPageCnt: `
+SELECT COUNT(*)
+FROM port
+WHERE (?1 = 0 OR port = ?1)
+  AND (?2 = 0 OR host_id = ?2)
+  AND timestamp >= ?3
+  AND (?4 = 0 OR reply IS NOT NULL)
+  AND (?5 = '' OR product = ?5)
+  AND (?6 = '' OR version = ?6)`,
+	query.XfrGetPage: `
+SELECT id, zone, start, end, status
+FROM xfr
+WHERE (?1 < 0 OR status = ?1)
+ORDER BY start DESC, id DESC
+LIMIT ?2 OFFSET ?3`,
+	query.XfrGetPageCnt: "SELECT COUNT(*) FROM xfr WHERE (?1 < 0 OR status = ?1)",
+	query.UserAdd: `
+INSERT INTO web_user (name, pwhash, role, created)
+              VALUES (?, ?, ?, ?)
+RETURNING id
+`,
+	query.UserGetByName:   "SELECT id, pwhash, role, created FROM web_user WHERE name = ?",
+	query.UserGetCnt:      "SELECT COUNT(*) FROM web_user",
+	query.UserSetPassword: "UPDATE web_user SET pwhash = ? WHERE id = ?",
+	query.SessionAdd: `
+INSERT INTO web_session (user_id, key_hash, csrf, created, expires)
+                 VALUES (?, ?, ?, ?, ?)
+RETURNING id
+`,
+	query.SessionGet: `
+SELECT s.id, s.csrf, s.created, s.expires,
+       u.id, u.name, u.pwhash, u.role, u.created
+FROM web_session s
+INNER JOIN web_user u ON s.user_id = u.id
+WHERE s.key_hash = ? AND s.expires > ?
+`,
+	query.SessionDelete: "DELETE FROM web_session WHERE key_hash = ?",
+	query.SessionPurge:  "DELETE FROM web_session WHERE expires <= ?",
+	query.TokenAdd: `
+INSERT INTO api_token (user_id, name, key_hash, created)
+               VALUES (?, ?, ?, ?)
+RETURNING id
+`,
+	query.TokenGet: `
+SELECT t.id, t.name, t.created, t.last_used,
+       u.id, u.name, u.pwhash, u.role, u.created
+FROM api_token t
+INNER JOIN web_user u ON t.user_id = u.id
+WHERE t.key_hash = ?
+`,
+	query.TokenGetByUser: `
+SELECT id, name, created, last_used
+FROM api_token
+WHERE user_id = ?
+ORDER BY created, id
+`,
+	query.TokenDelete: "DELETE FROM api_token WHERE id = ? AND user_id = ?",
+	query.TokenTouch:  "UPDATE api_token SET last_used = ? WHERE id = ?",
+	query.TagAdd: `
+INSERT INTO tag (name) VALUES (?)
+ON CONFLICT (name) DO UPDATE SET name = excluded.name
+RETURNING id
+`,
+	query.HostTagAdd: `
+INSERT INTO host_tag (host_id, tag_id, stamp) VALUES (?, ?, ?)
+ON CONFLICT (host_id, tag_id) DO NOTHING
+`,
+	query.HostTagGetByHost: `
+SELECT t.id, t.name
+FROM host_tag h
+INNER JOIN tag t ON h.tag_id = t.id
+WHERE h.host_id = ?
+ORDER BY t.name
+`,
+	query.NoteAdd: `
+INSERT INTO host_note (host_id, author, stamp, body)
+               VALUES (?, ?, ?, ?)
+RETURNING id
+`,
+	query.NoteGetByHost: `
+SELECT id, author, stamp, body
+FROM host_note
+WHERE host_id = ?
+ORDER BY stamp, id
+`,
+	query.ExclusionAdd: `
+INSERT INTO exclusion (network, asn, added, comment)
+               VALUES (?, ?, ?, ?)
+RETURNING id
+`,
+	query.ExclusionGetAll: "SELECT id, network, asn, added, comment FROM exclusion ORDER BY id",
+	query.XfrGetByName: `
+SELECT id, zone, start, end, status
+FROM xfr
+WHERE RTRIM(zone, '.') = ?1 OR ?1 LIKE '%.' || RTRIM(zone, '.')
+ORDER BY LENGTH(zone) DESC
+LIMIT 1
+`,
+	query.HostTagDelete: `
+DELETE FROM host_tag
+WHERE host_id = ?
+  AND tag_id = (SELECT id FROM tag WHERE name = ?)
+`,
+	query.TagGetAll: `
+SELECT t.id, t.name
+FROM tag t
+WHERE EXISTS (SELECT 1 FROM host_tag h WHERE h.tag_id = t.id)
+ORDER BY t.name
+`,
+	query.NoteDelete: "DELETE FROM host_note WHERE id = ? AND host_id = ?",
+	query.PortGetOpenByTag: `
+SELECT
+  p.id,
+  p.host_id,
+  p.port,
+  p.timestamp,
+  p.reply
+FROM port p
+INNER JOIN host_tag h ON p.host_id = h.host_id
+INNER JOIN tag t ON h.tag_id = t.id
+WHERE p.reply IS NOT NULL AND t.name = ?
+ORDER BY p.port`,
+	query.RuleAdd: `
+INSERT INTO rule (label, severity, port, banner, os, location, hostname, tag, enabled, created)
+          VALUES (    ?,        ?,    ?,      ?,  ?,        ?,        ?,   ?,       ?,       ?)
+RETURNING id
+`,
+	query.RuleUpdate: `
+UPDATE rule
+SET label = ?,
+    severity = ?,
+    port = ?,
+    banner = ?,
+    os = ?,
+    location = ?,
+    hostname = ?,
+    tag = ?,
+    enabled = ?
+WHERE id = ?
+`,
+	query.RuleDelete: "DELETE FROM rule WHERE id = ?",
+	query.RuleGetAll: `
+SELECT id, label, severity, port, banner, os, location, hostname, tag, enabled, created
+FROM rule
+ORDER BY id
+`,
+	query.RuleGetByID: `
+SELECT label, severity, port, banner, os, location, hostname, tag, enabled, created
+FROM rule
+WHERE id = ?
+`,
+	query.FindingAdd: `
+INSERT INTO finding (rule_id, host_id, port, stamp) VALUES (?, ?, ?, ?)
+ON CONFLICT (rule_id, host_id, port) DO UPDATE SET stamp = excluded.stamp
+`,
+	query.FindingDeleteByRule: "DELETE FROM finding WHERE rule_id = ?",
+	query.FindingPurge:        "DELETE FROM finding WHERE stamp < ?",
+	query.FindingGetAll: `
+SELECT f.id, f.rule_id, f.host_id, f.port, f.stamp, r.label, r.severity, h.name, h.addr
+FROM finding f
+INNER JOIN rule r ON f.rule_id = r.id
+INNER JOIN host h ON f.host_id = h.id
+WHERE r.severity >= ?
+ORDER BY r.severity DESC, f.stamp DESC, f.id
+`,
+	query.FindingGetByHost: `
+SELECT f.id, f.rule_id, f.host_id, f.port, f.stamp, r.label, r.severity, h.name, h.addr
+FROM finding f
+INNER JOIN rule r ON f.rule_id = r.id
+INNER JOIN host h ON f.host_id = h.id
+WHERE f.host_id = ?
+ORDER BY r.severity DESC, f.port, r.label
+`,
+	query.PortSetSoftware: `
+UPDATE port
+SET service = ?, product = ?, version = ?, info = ?, os = ?, cpe = ?
+WHERE id = ?
+`,
+	query.PortGetSoftware: `
+SELECT product, version, MAX(cpe), COUNT(*), COUNT(DISTINCT host_id)
+FROM port
+WHERE product <> ''
+GROUP BY product, version
+ORDER BY product, COUNT(*) DESC, version
+`,
+	query.PortGetIdentified: `
+SELECT id, host_id, port, timestamp, service, product, version, info, os, cpe
+FROM port
+WHERE cpe <> ''
+ORDER BY id
+`,
+	query.VulnAdd: `
+INSERT INTO vuln (name, summary, score, severity, published, source)
+          VALUES (?, ?, ?, ?, ?, ?)
+ON CONFLICT (name) DO UPDATE
+  SET summary = excluded.summary,
+      score = excluded.score,
+      severity = excluded.severity,
+      published = excluded.published,
+      source = excluded.source
+RETURNING id
+`,
+	query.VulnRangeDelete: "DELETE FROM vuln_range WHERE vuln_id = ?",
+	query.VulnRangeAdd: `
+INSERT INTO vuln_range (vuln_id, vendor, product, version, start_incl, start_excl, end_incl, end_excl)
+                VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+`,
+	query.VulnGetByProduct: `
+SELECT v.id,
+       v.name,
+       v.summary,
+       v.score,
+       v.severity,
+       v.published,
+       v.source,
+       r.vendor,
+       r.product,
+       r.version,
+       r.start_incl,
+       r.start_excl,
+       r.end_incl,
+       r.end_excl
+FROM vuln_range r
+INNER JOIN vuln v ON r.vuln_id = v.id
+WHERE r.product = ?
+ORDER BY v.id
+`,
+	query.VulnGetCount:   "SELECT COUNT(*) FROM vuln",
+	query.PortVulnDelete: "DELETE FROM port_vuln WHERE port_id = ?",
+	query.PortVulnAdd: `
+INSERT INTO port_vuln (port_id, vuln_id, stamp) VALUES (?, ?, ?)
+ON CONFLICT (port_id, vuln_id) DO UPDATE SET stamp = excluded.stamp
+`,
+	query.VulnGetByHost: `
+SELECT v.id,
+       v.name,
+       v.summary,
+       v.score,
+       v.severity,
+       v.published,
+       v.source,
+       p.host_id,
+       p.id,
+       p.port,
+       p.product,
+       p.version
+FROM port_vuln pv
+INNER JOIN port p ON pv.port_id = p.id
+INNER JOIN vuln v ON pv.vuln_id = v.id
+WHERE p.host_id = ?
+ORDER BY p.port, v.score DESC, v.name
+`,
+	query.VulnGetHostReport: `
+SELECT h.id,
+       h.addr,
+       h.name,
+       COUNT(DISTINCT pv.vuln_id),
+       MAX(v.score)
+FROM port_vuln pv
+INNER JOIN port p ON pv.port_id = p.id
+INNER JOIN host h ON p.host_id = h.id
+INNER JOIN vuln v ON pv.vuln_id = v.id
+GROUP BY h.id, h.addr, h.name
+ORDER BY COUNT(DISTINCT pv.vuln_id) DESC, MAX(v.score) DESC, h.addr
+LIMIT ?
+`,
+	query.OSEvidenceDelete: "DELETE FROM os_evidence WHERE host_id = ?",
+	query.OSEvidenceAdd: `
+INSERT INTO os_evidence (host_id, port, source, os, version, weight, matched)
+                 VALUES (?, ?, ?, ?, ?, ?, ?)
+`,
+	query.OSEvidenceGetByHost: `
+SELECT port, source, os, version, weight, matched
+FROM os_evidence
+WHERE host_id = ?
+ORDER BY weight DESC, port, os
+`,
+	query.HostGetDirty: `
+SELECT id,
+       addr,
+       name,
+       COALESCE(location, ''),
+       COALESCE(os, ''),
+       os_version,
+       os_confidence,
+       source,
+       add_stamp,
+       asn,
+       as_org,
+       prefix,
+       registry,
+       allocated,
+       country,
+       subdivision,
+       city,
+       latitude,
+       longitude,
+       accuracy
+FROM host
+WHERE meta_updated IS NULL
+ORDER BY id
+LIMIT ?
+`,
+	query.HostSetMetaUpdated: "UPDATE host SET meta_updated = ? WHERE id = ?",
+	query.HostMarkDirty:      "UPDATE host SET meta_updated = NULL WHERE id = ?",
+	query.HostMarkAllDirty:   "UPDATE host SET meta_updated = NULL",
+	query.HostSetAS: `
+UPDATE host
+SET asn = ?,
+    as_org = ?,
+    prefix = ?,
+    registry = ?,
+    allocated = ?
+WHERE id = ?
+`,
+	query.HostGetASReport: `
+SELECT asn,
+       MAX(as_org),
+       COUNT(id)
+FROM host
+WHERE asn <> 0
+GROUP BY asn
+ORDER BY COUNT(id) DESC, asn
+LIMIT ?
+`,
+	query.DelegationDelete: "DELETE FROM delegation WHERE registry = ?",
+	query.DelegationAdd: `
+INSERT INTO delegation (registry, cc, first_addr, last_addr, allocated, status)
+                VALUES (?, ?, ?, ?, ?, ?)
+`,
+	query.DelegationLookup: `
+SELECT registry, cc, first_addr, last_addr, allocated, status
+FROM delegation
+WHERE first_addr <= ?
+ORDER BY first_addr DESC
+LIMIT 1
+`,
+	query.HostSetGeo: `
+UPDATE host
+SET location = ?,
+    country = ?,
+    subdivision = ?,
+    city = ?,
+    latitude = ?,
+    longitude = ?,
+    accuracy = ?
+WHERE id = ?
+`,
+	query.HostGetLocations: `
+SELECT latitude,
+       longitude,
+       country,
+       MAX(city),
+       COUNT(id)
+FROM host
+WHERE accuracy <> 0
+GROUP BY latitude, longitude, country
+ORDER BY COUNT(id) DESC
+LIMIT ?
 `,
 }
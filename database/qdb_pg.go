@@ -0,0 +1,527 @@
+// /home/krylon/go/src/github.com/blicero/guang/database/qdb_pg.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 16:10:50 krylon>
+
+package database
+
+import "github.com/blicero/guang/database/query"
+
+// pgQueries is the PostgreSQL version of dbQueries. Every query.ID in
+// dbQueries must be present here, too.
+var pgQueries map[query.ID]string = map[query.ID]string{
+	query.HostAdd: `
+INSERT INTO host (addr, name, source, add_stamp)
+          VALUES (  $1,   $2,     $3,        $4)
+RETURNING id
+`,
+	query.HostGetByID: "SELECT id, addr, name, COALESCE(location, ''), COALESCE(os, ''), os_version, os_confidence, source, add_stamp, asn, as_org, prefix, registry, allocated, country, subdivision, city, latitude, longitude, accuracy FROM host WHERE id = $1",
+	query.HostGetAll:  "SELECT id, addr, name, COALESCE(location, ''), COALESCE(os, ''), os_version, os_confidence, source, add_stamp, asn, as_org, prefix, registry, allocated, country, subdivision, city, latitude, longitude, accuracy FROM host",
+	query.HostGetRandom: `
+SELECT id,
+       addr,
+       name,
+       COALESCE(location, ''),
+       COALESCE(os, ''),
+       os_version,
+       os_confidence,
+       source,
+       add_stamp,
+       asn,
+       as_org,
+       prefix,
+       registry,
+       allocated,
+       country,
+       subdivision,
+       city,
+       latitude,
+       longitude,
+       accuracy
+FROM host
+LIMIT $1
+OFFSET floor(random() * GREATEST((SELECT COUNT(*) FROM host), 1))::bigint
+`,
+	query.HostGetCnt: "SELECT COUNT(id) FROM host",
+	query.HostExists: "SELECT COUNT(id) FROM host WHERE addr = $1",
+	query.HostPortByPort: `
+SELECT
+  P.id,
+  P.host_id,
+  P.port,
+  P.timestamp,
+  P.reply,
+  H.addr,
+  H.name,
+  COALESCE(H.location, ''),
+  COALESCE(H.os, '')
+FROM port P
+INNER JOIN host H ON P.host_id = H.id
+WHERE P.reply IS NOT NULL
+`,
+	query.HostSetOS:       `UPDATE host SET os = $1, os_version = $2, os_confidence = $3 WHERE id = $4`,
+	query.HostSetLocation: `UPDATE host SET location = $1 WHERE id = $2`,
+	query.PortAdd: `
+INSERT INTO port (host_id, port, timestamp, reply, service, product, version, info, os, cpe)
+          VALUES (     $1,   $2,        $3,    $4,      $5,      $6,      $7,   $8, $9,  $10)
+ON CONFLICT (host_id, port) DO UPDATE
+SET timestamp = excluded.timestamp,
+    reply = excluded.reply,
+    service = excluded.service,
+    product = excluded.product,
+    version = excluded.version,
+    info = excluded.info,
+    os = excluded.os,
+    cpe = excluded.cpe
+`,
+	query.PortGetByHost: `
+SELECT id, port, timestamp, reply, service, product, version, info, os, cpe
+FROM port
+WHERE host_id = $1
+`,
+	query.XfrAdd:       "INSERT INTO xfr (zone, start, status) VALUES ($1, $2, 0) RETURNING id",
+	query.XfrGetByZone: `SELECT id, start, "end", status FROM xfr WHERE zone = $1`,
+	query.XfrFinish:    `UPDATE xfr SET "end" = $1, status = $2 WHERE id = $3`,
+	query.XfrGetUnfinished: `
+SELECT id,
+       zone,
+       start,
+       "end",
+       status
+FROM xfr
+WHERE status = 0
+`,
+	query.PortGetReplyCnt: "SELECT COUNT(id) FROM port WHERE reply IS NOT NULL",
+	query.PortGetOpen: `
+SELECT
+  id,
+  host_id,
+  port,
+  timestamp,
+  reply
+FROM port
+WHERE reply IS NOT NULL
+ORDER BY port`,
+	query.PortGetRecent: `
+SELECT
+  id,
+  host_id,
+  port,
+  timestamp,
+  reply
+FROM port
+WHERE reply IS NOT NULL AND timestamp > $1
+ORDER BY port
+`,
+	query.HostUpsert: `
+INSERT INTO host (addr, name, source, add_stamp)
+          VALUES ($1, $2, $3, $4)
+ON CONFLICT (addr) DO NOTHING
+RETURNING id
+`,
+	query.HostGetByAddr: `
+SELECT id, addr, name, COALESCE(location, ''), COALESCE(os, ''), os_version, os_confidence, source, add_stamp, asn, as_org, prefix, registry, allocated, country, subdivision, city, latitude, longitude, accuracy
+FROM host
+WHERE addr = $1 OR id = (SELECT host_id FROM host_address WHERE addr = $1)
+`,
+	query.HostUpdateSource: `
+UPDATE host
+SET name = $1, source = $2
+WHERE id = $3 AND source = $4
+`,
+	query.HostAddressAdd: `
+INSERT INTO host_address (host_id, addr, source, first_seen, last_seen)
+                  VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (addr) DO UPDATE SET last_seen = excluded.last_seen
+`,
+	query.HostAddressGetByHost: `
+SELECT addr, source, first_seen, last_seen
+FROM host_address
+WHERE host_id = $1
+ORDER BY first_seen, id
+`,
+	query.HostNameAdd: `
+INSERT INTO host_name (host_id, name, source, first_seen, last_seen)
+               VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (host_id, name) DO UPDATE SET last_seen = excluded.last_seen
+`,
+	query.HostNameGetByHost: `
+SELECT name, source, first_seen, last_seen
+FROM host_name
+WHERE host_id = $1
+ORDER BY first_seen, id
+`,
+	query.HostNameLookup: "SELECT host_id, source FROM host_name WHERE name = $1",
+	query.PortGetPage: `
+SELECT id, host_id, port, timestamp, reply, service, product, version, info, os, cpe
+FROM port
+WHERE ($1 = 0 OR port = $1)
+  AND ($2 = 0 OR host_id = $2)
+  AND timestamp >= $3
+  AND ($4 = 0 OR reply IS NOT NULL)
+  AND ($5 = '' OR product = $5)
+  AND ($6 = '' OR version = $6)
+ORDER BY timestamp DESC, id DESC
+LIMIT $7 OFFSET $8`,
+	query.PortGetPageCnt: `
+SELECT COUNT(*)
+FROM port
+WHERE ($1 = 0 OR port = $1)
+  AND ($2 = 0 OR host_id = $2)
+  AND timestamp >= $3
+  AND ($4 = 0 OR reply IS NOT NULL)
+  AND ($5 = '' OR product = $5)
+  AND ($6 = '' OR version = $6)`,
+	query.XfrGetPage: `
+SELECT id, zone, start, "end", status
+FROM xfr
+WHERE ($1 < 0 OR status = $1)
+ORDER BY start DESC, id DESC
+LIMIT $2 OFFSET $3`,
+	query.XfrGetPageCnt: "SELECT COUNT(*) FROM xfr WHERE ($1 < 0 OR status = $1)",
+	query.UserAdd: `
+INSERT INTO web_user (name, pwhash, role, created)
+              VALUES ($1, $2, $3, $4)
+RETURNING id
+`,
+	query.UserGetByName:   "SELECT id, pwhash, role, created FROM web_user WHERE name = $1",
+	query.UserGetCnt:      "SELECT COUNT(*) FROM web_user",
+	query.UserSetPassword: "UPDATE web_user SET pwhash = $1 WHERE id = $2",
+	query.SessionAdd: `
+INSERT INTO web_session (user_id, key_hash, csrf, created, expires)
+                 VALUES ($1, $2, $3, $4, $5)
+RETURNING id
+`,
+	query.SessionGet: `
+SELECT s.id, s.csrf, s.created, s.expires,
+       u.id, u.name, u.pwhash, u.role, u.created
+FROM web_session s
+INNER JOIN web_user u ON s.user_id = u.id
+WHERE s.key_hash = $1 AND s.expires > $2
+`,
+	query.SessionDelete: "DELETE FROM web_session WHERE key_hash = $1",
+	query.SessionPurge:  "DELETE FROM web_session WHERE expires <= $1",
+	query.TokenAdd: `
+INSERT INTO api_token (user_id, name, key_hash, created)
+               VALUES ($1, $2, $3, $4)
+RETURNING id
+`,
+	query.TokenGet: `
+SELECT t.id, t.name, t.created, t.last_used,
+       u.id, u.name, u.pwhash, u.role, u.created
+FROM api_token t
+INNER JOIN web_user u ON t.user_id = u.id
+WHERE t.key_hash = $1
+`,
+	query.TokenGetByUser: `
+SELECT id, name, created, last_used
+FROM api_token
+WHERE user_id = $1
+ORDER BY created, id
+`,
+	query.TokenDelete: "DELETE FROM api_token WHERE id = $1 AND user_id = $2",
+	query.TokenTouch:  "UPDATE api_token SET last_used = $1 WHERE id = $2",
+	query.TagAdd: `
+INSERT INTO tag (name) VALUES ($1)
+ON CONFLICT (name) DO UPDATE SET name = excluded.name
+RETURNING id
+`,
+	query.HostTagAdd: `
+INSERT INTO host_tag (host_id, tag_id, stamp) VALUES ($1, $2, $3)
+ON CONFLICT (host_id, tag_id) DO NOTHING
+`,
+	query.HostTagGetByHost: `
+SELECT t.id, t.name
+FROM host_tag h
+INNER JOIN tag t ON h.tag_id = t.id
+WHERE h.host_id = $1
+ORDER BY t.name
+`,
+	query.NoteAdd: `
+INSERT INTO host_note (host_id, author, stamp, body)
+               VALUES ($1, $2, $3, $4)
+RETURNING id
+`,
+	query.NoteGetByHost: `
+SELECT id, author, stamp, body
+FROM host_note
+WHERE host_id = $1
+ORDER BY stamp, id
+`,
+	query.ExclusionAdd: `
+INSERT INTO exclusion (network, asn, added, comment)
+               VALUES ($1, $2, $3, $4)
+RETURNING id
+`,
+	query.ExclusionGetAll: "SELECT id, network, asn, added, comment FROM exclusion ORDER BY id",
+	query.XfrGetByName: `
+SELECT id, zone, start, "end", status
+FROM xfr
+WHERE RTRIM(zone, '.') = $1 OR $1 LIKE '%.' || RTRIM(zone, '.')
+ORDER BY LENGTH(zone) DESC
+LIMIT 1
+`,
+	query.HostTagDelete: `
+DELETE FROM host_tag
+WHERE host_id = $1
+  AND tag_id = (SELECT id FROM tag WHERE name = $2)
+`,
+	query.TagGetAll: `
+SELECT t.id, t.name
+FROM tag t
+WHERE EXISTS (SELECT 1 FROM host_tag h WHERE h.tag_id = t.id)
+ORDER BY t.name
+`,
+	query.NoteDelete: "DELETE FROM host_note WHERE id = $1 AND host_id = $2",
+	query.PortGetOpenByTag: `
+SELECT
+  p.id,
+  p.host_id,
+  p.port,
+  p.timestamp,
+  p.reply
+FROM port p
+INNER JOIN host_tag h ON p.host_id = h.host_id
+INNER JOIN tag t ON h.tag_id = t.id
+WHERE p.reply IS NOT NULL AND t.name = $1
+ORDER BY p.port`,
+	query.RuleAdd: `
+INSERT INTO rule (label, severity, port, banner, os, location, hostname, tag, enabled, created)
+          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING id
+`,
+	query.RuleUpdate: `
+UPDATE rule
+SET label = $1,
+    severity = $2,
+    port = $3,
+    banner = $4,
+    os = $5,
+    location = $6,
+    hostname = $7,
+    tag = $8,
+    enabled = $9
+WHERE id = $10
+`,
+	query.RuleDelete: "DELETE FROM rule WHERE id = $1",
+	query.RuleGetAll: `
+SELECT id, label, severity, port, banner, os, location, hostname, tag, enabled, created
+FROM rule
+ORDER BY id
+`,
+	query.RuleGetByID: `
+SELECT label, severity, port, banner, os, location, hostname, tag, enabled, created
+FROM rule
+WHERE id = $1
+`,
+	query.FindingAdd: `
+INSERT INTO finding (rule_id, host_id, port, stamp) VALUES ($1, $2, $3, $4)
+ON CONFLICT (rule_id, host_id, port) DO UPDATE SET stamp = excluded.stamp
+`,
+	query.FindingDeleteByRule: "DELETE FROM finding WHERE rule_id = $1",
+	query.FindingPurge:        "DELETE FROM finding WHERE stamp < $1",
+	query.FindingGetAll: `
+SELECT f.id, f.rule_id, f.host_id, f.port, f.stamp, r.label, r.severity, h.name, h.addr
+FROM finding f
+INNER JOIN rule r ON f.rule_id = r.id
+INNER JOIN host h ON f.host_id = h.id
+WHERE r.severity >= $1
+ORDER BY r.severity DESC, f.stamp DESC, f.id
+`,
+	query.FindingGetByHost: `
+SELECT f.id, f.rule_id, f.host_id, f.port, f.stamp, r.label, r.severity, h.name, h.addr
+FROM finding f
+INNER JOIN rule r ON f.rule_id = r.id
+INNER JOIN host h ON f.host_id = h.id
+WHERE f.host_id = $1
+ORDER BY r.severity DESC, f.port, r.label
+`,
+	query.PortSetSoftware: `
+UPDATE port
+SET service = $1, product = $2, version = $3, info = $4, os = $5, cpe = $6
+WHERE id = $7
+`,
+	query.PortGetSoftware: `
+SELECT product, version, MAX(cpe), COUNT(*), COUNT(DISTINCT host_id)
+FROM port
+WHERE product <> ''
+GROUP BY product, version
+ORDER BY product, COUNT(*) DESC, version
+`,
+	query.PortGetIdentified: `
+SELECT id, host_id, port, timestamp, service, product, version, info, os, cpe
+FROM port
+WHERE cpe <> ''
+ORDER BY id
+`,
+	query.VulnAdd: `
+INSERT INTO vuln (name, summary, score, severity, published, source)
+          VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (name) DO UPDATE
+  SET summary = excluded.summary,
+      score = excluded.score,
+      severity = excluded.severity,
+      published = excluded.published,
+      source = excluded.source
+RETURNING id
+`,
+	query.VulnRangeDelete: "DELETE FROM vuln_range WHERE vuln_id = $1",
+	query.VulnRangeAdd: `
+INSERT INTO vuln_range (vuln_id, vendor, product, version, start_incl, start_excl, end_incl, end_excl)
+                VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+`,
+	query.VulnGetByProduct: `
+SELECT v.id,
+       v.name,
+       v.summary,
+       v.score,
+       v.severity,
+       v.published,
+       v.source,
+       r.vendor,
+       r.product,
+       r.version,
+       r.start_incl,
+       r.start_excl,
+       r.end_incl,
+       r.end_excl
+FROM vuln_range r
+INNER JOIN vuln v ON r.vuln_id = v.id
+WHERE r.product = $1
+ORDER BY v.id
+`,
+	query.VulnGetCount:   "SELECT COUNT(*) FROM vuln",
+	query.PortVulnDelete: "DELETE FROM port_vuln WHERE port_id = $1",
+	query.PortVulnAdd: `
+INSERT INTO port_vuln (port_id, vuln_id, stamp) VALUES ($1, $2, $3)
+ON CONFLICT (port_id, vuln_id) DO UPDATE SET stamp = excluded.stamp
+`,
+	query.VulnGetByHost: `
+SELECT v.id,
+       v.name,
+       v.summary,
+       v.score,
+       v.severity,
+       v.published,
+       v.source,
+       p.host_id,
+       p.id,
+       p.port,
+       p.product,
+       p.version
+FROM port_vuln pv
+INNER JOIN port p ON pv.port_id = p.id
+INNER JOIN vuln v ON pv.vuln_id = v.id
+WHERE p.host_id = $1
+ORDER BY p.port, v.score DESC, v.name
+`,
+	query.VulnGetHostReport: `
+SELECT h.id,
+       h.addr,
+       h.name,
+       COUNT(DISTINCT pv.vuln_id),
+       MAX(v.score)
+FROM port_vuln pv
+INNER JOIN port p ON pv.port_id = p.id
+INNER JOIN host h ON p.host_id = h.id
+INNER JOIN vuln v ON pv.vuln_id = v.id
+GROUP BY h.id, h.addr, h.name
+ORDER BY COUNT(DISTINCT pv.vuln_id) DESC, MAX(v.score) DESC, h.addr
+LIMIT $1
+`,
+	query.OSEvidenceDelete: "DELETE FROM os_evidence WHERE host_id = $1",
+	query.OSEvidenceAdd: `
+INSERT INTO os_evidence (host_id, port, source, os, version, weight, matched)
+                 VALUES ($1, $2, $3, $4, $5, $6, $7)
+`,
+	query.OSEvidenceGetByHost: `
+SELECT port, source, os, version, weight, matched
+FROM os_evidence
+WHERE host_id = $1
+ORDER BY weight DESC, port, os
+`,
+	query.HostGetDirty: `
+SELECT id,
+       addr,
+       name,
+       COALESCE(location, ''),
+       COALESCE(os, ''),
+       os_version,
+       os_confidence,
+       source,
+       add_stamp,
+       asn,
+       as_org,
+       prefix,
+       registry,
+       allocated,
+       country,
+       subdivision,
+       city,
+       latitude,
+       longitude,
+       accuracy
+FROM host
+WHERE meta_updated IS NULL
+ORDER BY id
+LIMIT $1
+`,
+	query.HostSetMetaUpdated: "UPDATE host SET meta_updated = $1 WHERE id = $2",
+	query.HostMarkDirty:      "UPDATE host SET meta_updated = NULL WHERE id = $1",
+	query.HostMarkAllDirty:   "UPDATE host SET meta_updated = NULL",
+	query.HostSetAS: `
+UPDATE host
+SET asn = $1,
+    as_org = $2,
+    prefix = $3,
+    registry = $4,
+    allocated = $5
+WHERE id = $6
+`,
+	query.HostGetASReport: `
+SELECT asn,
+       MAX(as_org),
+       COUNT(id)
+FROM host
+WHERE asn <> 0
+GROUP BY asn
+ORDER BY COUNT(id) DESC, asn
+LIMIT $1
+`,
+	query.DelegationDelete: "DELETE FROM delegation WHERE registry = $1",
+	query.DelegationAdd: `
+INSERT INTO delegation (registry, cc, first_addr, last_addr, allocated, status)
+                VALUES ($1, $2, $3, $4, $5, $6)
+`,
+	query.DelegationLookup: `
+SELECT registry, cc, first_addr, last_addr, allocated, status
+FROM delegation
+WHERE first_addr <= $1
+ORDER BY first_addr DESC
+LIMIT 1
+`,
+	query.HostSetGeo: `
+UPDATE host
+SET location = $1,
+    country = $2,
+    subdivision = $3,
+    city = $4,
+    latitude = $5,
+    longitude = $6,
+    accuracy = $7
+WHERE id = $8
+`,
+	query.HostGetLocations: `
+SELECT latitude,
+       longitude,
+       country,
+       MAX(city),
+       COUNT(id)
+FROM host
+WHERE accuracy <> 0
+GROUP BY latitude, longitude, country
+ORDER BY COUNT(id) DESC
+LIMIT $9
+`,
+}
@@ -0,0 +1,51 @@
+// /home/krylon/go/src/github.com/blicero/guang/database/qinit_pg.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 13:51:53 krylon>
+
+package database
+
+// pgInitQueries create the same schema as initQueries, in PostgreSQL's
+// dialect. Timestamps are stored as seconds since the epoch, just like we
+// do in SQLite.
+var pgInitQueries = []string{
+	`
+CREATE TABLE host (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    addr TEXT UNIQUE NOT NULL,
+    name TEXT NOT NULL,
+    source INTEGER NOT NULL,
+    add_stamp BIGINT NOT NULL,
+    location TEXT,
+    os TEXT
+)`,
+	"CREATE INDEX host_addr_idx ON host (addr)",
+	"CREATE INDEX host_name_idx ON host (name)",
+	"CREATE INDEX host_stamp_idx ON host (add_stamp)",
+	"CREATE INDEX host_location_idx ON host (location)",
+	"CREATE INDEX host_os_stamp ON host (os)",
+	`
+CREATE TABLE port (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    host_id BIGINT NOT NULL,
+    port INTEGER NOT NULL,
+    timestamp BIGINT NOT NULL,
+    reply TEXT,
+    UNIQUE (host_id, port),
+    FOREIGN KEY (host_id) REFERENCES host (id))`,
+	"CREATE INDEX port_host_idx ON port (host_id)",
+	"CREATE INDEX port_port_idx ON port (port)",
+	"CREATE INDEX port_ts_idx   ON port (timestamp)",
+
+	`
+CREATE TABLE xfr (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    zone TEXT UNIQUE NOT NULL,
+    start BIGINT NOT NULL,
+    "end" BIGINT NOT NULL DEFAULT 0,
+    status INTEGER NOT NULL DEFAULT 0
+)`,
+	"CREATE INDEX xfr_zone_idx ON xfr (zone)",
+	"CREATE INDEX xfr_status_idx ON xfr (status)",
+}
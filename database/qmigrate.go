@@ -0,0 +1,559 @@
+// /home/krylon/go/src/github.com/blicero/guang/database/qmigrate.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 16:10:50 krylon>
+
+package database
+
+// migration is a single step in the evolution of the database schema.
+// Migrations are numbered consecutively, starting at 1, and are applied in
+// order, each one in its own transaction.
+type migration struct {
+	version     int
+	description string
+	queries     []string
+}
+
+// schemaQueries create the table used to keep track of the schema version.
+var schemaQueries = []string{
+	`
+CREATE TABLE IF NOT EXISTS schema_version (
+    version INTEGER PRIMARY KEY,
+    stamp INTEGER NOT NULL,
+    description TEXT NOT NULL DEFAULT ''
+)`,
+}
+
+// migrations is the ordered list of all schema migrations for SQLite.
+// NEVER modify a migration that has been released, add a new one instead.
+//
+// Version 1 is the schema as it was before we started tracking versions, so
+// databases that have a host table, but no schema_version table, are
+// considered to be at version 1.
+var migrations = []migration{
+	{
+		version:     1,
+		description: "Initial schema",
+		queries:     initQueries,
+	},
+	{
+		version:     2,
+		description: "Multiple names and addresses per host",
+		queries: []string{
+			`
+CREATE TABLE host_address (
+    id INTEGER PRIMARY KEY,
+    host_id INTEGER NOT NULL,
+    addr TEXT UNIQUE NOT NULL,
+    source INTEGER NOT NULL,
+    first_seen INTEGER NOT NULL,
+    last_seen INTEGER NOT NULL,
+    FOREIGN KEY (host_id) REFERENCES host (id) ON DELETE CASCADE)`,
+			"CREATE INDEX host_address_host_idx ON host_address (host_id)",
+			`
+CREATE TABLE host_name (
+    id INTEGER PRIMARY KEY,
+    host_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    source INTEGER NOT NULL,
+    first_seen INTEGER NOT NULL,
+    last_seen INTEGER NOT NULL,
+    UNIQUE (host_id, name),
+    FOREIGN KEY (host_id) REFERENCES host (id) ON DELETE CASCADE)`,
+			"CREATE INDEX host_name_name_idx ON host_name (name)",
+			`
+INSERT INTO host_address (host_id, addr, source, first_seen, last_seen)
+SELECT id, addr, source, add_stamp, add_stamp FROM host`,
+			`
+INSERT INTO host_name (host_id, name, source, first_seen, last_seen)
+SELECT id, name, source, add_stamp, add_stamp FROM host WHERE name <> ''`,
+		},
+	},
+	{
+		version:     3,
+		description: "Users, sessions and API tokens for the web frontend",
+		queries: []string{
+			`
+CREATE TABLE web_user (
+    id INTEGER PRIMARY KEY,
+    name TEXT UNIQUE NOT NULL,
+    pwhash TEXT NOT NULL,
+    role INTEGER NOT NULL,
+    created INTEGER NOT NULL)`,
+			`
+CREATE TABLE web_session (
+    id INTEGER PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    key_hash TEXT UNIQUE NOT NULL,
+    csrf TEXT NOT NULL,
+    created INTEGER NOT NULL,
+    expires INTEGER NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES web_user (id) ON DELETE CASCADE)`,
+			"CREATE INDEX web_session_expires_idx ON web_session (expires)",
+			`
+CREATE TABLE api_token (
+    id INTEGER PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    key_hash TEXT UNIQUE NOT NULL,
+    created INTEGER NOT NULL,
+    last_used INTEGER NOT NULL DEFAULT 0,
+    FOREIGN KEY (user_id) REFERENCES web_user (id) ON DELETE CASCADE)`,
+			"CREATE INDEX api_token_user_idx ON api_token (user_id)",
+		},
+	},
+	{
+		version:     4,
+		description: "Tags, notes and exclusions",
+		queries: []string{
+			`
+CREATE TABLE tag (
+    id INTEGER PRIMARY KEY,
+    name TEXT UNIQUE NOT NULL)`,
+			`
+CREATE TABLE host_tag (
+    host_id INTEGER NOT NULL,
+    tag_id INTEGER NOT NULL,
+    stamp INTEGER NOT NULL,
+    PRIMARY KEY (host_id, tag_id),
+    FOREIGN KEY (host_id) REFERENCES host (id) ON DELETE CASCADE,
+    FOREIGN KEY (tag_id) REFERENCES tag (id) ON DELETE CASCADE)`,
+			"CREATE INDEX host_tag_tag_idx ON host_tag (tag_id)",
+			`
+CREATE TABLE host_note (
+    id INTEGER PRIMARY KEY,
+    host_id INTEGER NOT NULL,
+    author TEXT NOT NULL,
+    stamp INTEGER NOT NULL,
+    body TEXT NOT NULL,
+    FOREIGN KEY (host_id) REFERENCES host (id) ON DELETE CASCADE)`,
+			"CREATE INDEX host_note_host_idx ON host_note (host_id)",
+			`
+CREATE TABLE exclusion (
+    id INTEGER PRIMARY KEY,
+    network TEXT UNIQUE NOT NULL,
+    added INTEGER NOT NULL,
+    comment TEXT NOT NULL DEFAULT '')`,
+		},
+	},
+	{
+		version:     5,
+		description: "Rules and findings",
+		queries: []string{
+			`
+CREATE TABLE rule (
+    id INTEGER PRIMARY KEY,
+    label TEXT NOT NULL,
+    severity INTEGER NOT NULL,
+    port INTEGER NOT NULL DEFAULT 0,
+    banner TEXT NOT NULL DEFAULT '',
+    os TEXT NOT NULL DEFAULT '',
+    location TEXT NOT NULL DEFAULT '',
+    hostname TEXT NOT NULL DEFAULT '',
+    tag TEXT NOT NULL DEFAULT '',
+    enabled INTEGER NOT NULL,
+    created INTEGER NOT NULL)`,
+			`
+CREATE TABLE finding (
+    id INTEGER PRIMARY KEY,
+    rule_id INTEGER NOT NULL,
+    host_id INTEGER NOT NULL,
+    port INTEGER NOT NULL DEFAULT 0,
+    stamp INTEGER NOT NULL,
+    UNIQUE (rule_id, host_id, port),
+    FOREIGN KEY (rule_id) REFERENCES rule (id) ON DELETE CASCADE,
+    FOREIGN KEY (host_id) REFERENCES host (id) ON DELETE CASCADE)`,
+			"CREATE INDEX finding_host_idx ON finding (host_id)",
+			`
+INSERT INTO rule (label, severity, banner, tag, enabled, created) VALUES
+    ('Outdated Windows', 2, '(?i)Windows\s*(?:NT|95|98|ME|2000|XP|Server 2003|Vista)\b', 'legacy-os', 1, CAST(strftime('%s', 'now') AS INTEGER)),
+    ('Outdated CentOS or RHEL', 2, '(?i)CentOS(?:\s+release)?\s+[2-6]\b|[.]el[2-6][._]|Red Hat Enterprise Linux.*release [2-6]\b', 'legacy-os', 1, CAST(strftime('%s', 'now') AS INTEGER)),
+    ('IBM mainframe (z/OS)', 3, '(?i)\bz/OS\b|\bMVS\b', '', 1, CAST(strftime('%s', 'now') AS INTEGER)),
+    ('OpenVMS', 3, '(?i)\bOpenVMS\b', '', 1, CAST(strftime('%s', 'now') AS INTEGER))`,
+		},
+	},
+	{
+		version:     6,
+		description: "Software parsed from the replies of ports",
+		queries:     portSoftwareQueries,
+	},
+	{
+		version:     7,
+		description: "Vulnerabilities and the ports they affect",
+		queries: []string{
+			`
+CREATE TABLE vuln (
+    id INTEGER PRIMARY KEY,
+    name TEXT UNIQUE NOT NULL,
+    summary TEXT NOT NULL DEFAULT '',
+    score REAL NOT NULL DEFAULT 0,
+    severity INTEGER NOT NULL DEFAULT 0,
+    published INTEGER NOT NULL DEFAULT 0,
+    source TEXT NOT NULL)`,
+			`
+CREATE TABLE vuln_range (
+    id INTEGER PRIMARY KEY,
+    vuln_id INTEGER NOT NULL,
+    vendor TEXT NOT NULL DEFAULT '',
+    product TEXT NOT NULL,
+    version TEXT NOT NULL DEFAULT '',
+    start_incl TEXT NOT NULL DEFAULT '',
+    start_excl TEXT NOT NULL DEFAULT '',
+    end_incl TEXT NOT NULL DEFAULT '',
+    end_excl TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (vuln_id) REFERENCES vuln (id) ON DELETE CASCADE)`,
+			"CREATE INDEX vuln_range_vuln_idx ON vuln_range (vuln_id)",
+			"CREATE INDEX vuln_range_product_idx ON vuln_range (product)",
+			`
+CREATE TABLE port_vuln (
+    port_id INTEGER NOT NULL,
+    vuln_id INTEGER NOT NULL,
+    stamp INTEGER NOT NULL,
+    PRIMARY KEY (port_id, vuln_id),
+    FOREIGN KEY (port_id) REFERENCES port (id) ON DELETE CASCADE,
+    FOREIGN KEY (vuln_id) REFERENCES vuln (id) ON DELETE CASCADE)`,
+			"CREATE INDEX port_vuln_vuln_idx ON port_vuln (vuln_id)",
+		},
+	},
+	{
+		version:     8,
+		description: "Scored OS fingerprints and their evidence",
+		queries: []string{
+			"ALTER TABLE host ADD COLUMN os_version TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE host ADD COLUMN os_confidence REAL NOT NULL DEFAULT 0",
+			`
+CREATE TABLE os_evidence (
+    id INTEGER PRIMARY KEY,
+    host_id INTEGER NOT NULL,
+    port INTEGER NOT NULL,
+    source TEXT NOT NULL,
+    os TEXT NOT NULL,
+    version TEXT NOT NULL DEFAULT '',
+    weight REAL NOT NULL,
+    matched TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (host_id) REFERENCES host (id) ON DELETE CASCADE)`,
+			"CREATE INDEX os_evidence_host_idx ON os_evidence (host_id)",
+		},
+	},
+	{
+		version:     9,
+		description: "Incremental metadata updates",
+		queries: []string{
+			"ALTER TABLE host ADD COLUMN meta_updated INTEGER",
+			"CREATE INDEX host_meta_updated_idx ON host (meta_updated)",
+		},
+	},
+	{
+		version:     10,
+		description: "Autonomous systems and RIR delegations",
+		queries: []string{
+			"ALTER TABLE host ADD COLUMN asn INTEGER NOT NULL DEFAULT 0",
+			"ALTER TABLE host ADD COLUMN as_org TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE host ADD COLUMN prefix TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE host ADD COLUMN registry TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE host ADD COLUMN allocated INTEGER NOT NULL DEFAULT 0",
+			"CREATE INDEX host_asn_idx ON host (asn)",
+			// The metadata of all Hosts lack the AS, so they are outdated.
+			"UPDATE host SET meta_updated = NULL",
+			`
+CREATE TABLE delegation (
+    id INTEGER PRIMARY KEY,
+    registry TEXT NOT NULL,
+    cc TEXT NOT NULL DEFAULT '',
+    first_addr TEXT NOT NULL,
+    last_addr TEXT NOT NULL,
+    allocated INTEGER NOT NULL,
+    status TEXT NOT NULL DEFAULT '')`,
+			"CREATE INDEX delegation_first_idx ON delegation (first_addr)",
+			"CREATE INDEX delegation_registry_idx ON delegation (registry)",
+			"ALTER TABLE exclusion ADD COLUMN asn INTEGER NOT NULL DEFAULT 0",
+		},
+	},
+	{
+		version:     11,
+		description: "Structured locations",
+		queries: []string{
+			"ALTER TABLE host ADD COLUMN country TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE host ADD COLUMN subdivision TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE host ADD COLUMN city TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE host ADD COLUMN latitude REAL NOT NULL DEFAULT 0",
+			"ALTER TABLE host ADD COLUMN longitude REAL NOT NULL DEFAULT 0",
+			"ALTER TABLE host ADD COLUMN accuracy INTEGER NOT NULL DEFAULT 0",
+			"CREATE INDEX host_country_idx ON host (country)",
+			// The locations of all Hosts lack the structured fields.
+			"UPDATE host SET meta_updated = NULL",
+		},
+	},
+}
+
+// pgMigrations is the list of schema migrations for PostgreSQL. It must
+// always have the same versions as migrations.
+var pgMigrations = []migration{
+	{
+		version:     1,
+		description: "Initial schema",
+		queries:     pgInitQueries,
+	},
+	{
+		version:     2,
+		description: "Multiple names and addresses per host",
+		queries: []string{
+			`
+CREATE TABLE host_address (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    host_id BIGINT NOT NULL,
+    addr TEXT UNIQUE NOT NULL,
+    source INTEGER NOT NULL,
+    first_seen BIGINT NOT NULL,
+    last_seen BIGINT NOT NULL,
+    FOREIGN KEY (host_id) REFERENCES host (id) ON DELETE CASCADE)`,
+			"CREATE INDEX host_address_host_idx ON host_address (host_id)",
+			`
+CREATE TABLE host_name (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    host_id BIGINT NOT NULL,
+    name TEXT NOT NULL,
+    source INTEGER NOT NULL,
+    first_seen BIGINT NOT NULL,
+    last_seen BIGINT NOT NULL,
+    UNIQUE (host_id, name),
+    FOREIGN KEY (host_id) REFERENCES host (id) ON DELETE CASCADE)`,
+			"CREATE INDEX host_name_name_idx ON host_name (name)",
+			`
+INSERT INTO host_address (host_id, addr, source, first_seen, last_seen)
+SELECT id, addr, source, add_stamp, add_stamp FROM host`,
+			`
+INSERT INTO host_name (host_id, name, source, first_seen, last_seen)
+SELECT id, name, source, add_stamp, add_stamp FROM host WHERE name <> ''`,
+		},
+	},
+	{
+		version:     3,
+		description: "Users, sessions and API tokens for the web frontend",
+		queries: []string{
+			`
+CREATE TABLE web_user (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    name TEXT UNIQUE NOT NULL,
+    pwhash TEXT NOT NULL,
+    role INTEGER NOT NULL,
+    created BIGINT NOT NULL)`,
+			`
+CREATE TABLE web_session (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    key_hash TEXT UNIQUE NOT NULL,
+    csrf TEXT NOT NULL,
+    created BIGINT NOT NULL,
+    expires BIGINT NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES web_user (id) ON DELETE CASCADE)`,
+			"CREATE INDEX web_session_expires_idx ON web_session (expires)",
+			`
+CREATE TABLE api_token (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    name TEXT NOT NULL,
+    key_hash TEXT UNIQUE NOT NULL,
+    created BIGINT NOT NULL,
+    last_used BIGINT NOT NULL DEFAULT 0,
+    FOREIGN KEY (user_id) REFERENCES web_user (id) ON DELETE CASCADE)`,
+			"CREATE INDEX api_token_user_idx ON api_token (user_id)",
+		},
+	},
+	{
+		version:     4,
+		description: "Tags, notes and exclusions",
+		queries: []string{
+			`
+CREATE TABLE tag (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    name TEXT UNIQUE NOT NULL)`,
+			`
+CREATE TABLE host_tag (
+    host_id BIGINT NOT NULL,
+    tag_id BIGINT NOT NULL,
+    stamp BIGINT NOT NULL,
+    PRIMARY KEY (host_id, tag_id),
+    FOREIGN KEY (host_id) REFERENCES host (id) ON DELETE CASCADE,
+    FOREIGN KEY (tag_id) REFERENCES tag (id) ON DELETE CASCADE)`,
+			"CREATE INDEX host_tag_tag_idx ON host_tag (tag_id)",
+			`
+CREATE TABLE host_note (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    host_id BIGINT NOT NULL,
+    author TEXT NOT NULL,
+    stamp BIGINT NOT NULL,
+    body TEXT NOT NULL,
+    FOREIGN KEY (host_id) REFERENCES host (id) ON DELETE CASCADE)`,
+			"CREATE INDEX host_note_host_idx ON host_note (host_id)",
+			`
+CREATE TABLE exclusion (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    network TEXT UNIQUE NOT NULL,
+    added BIGINT NOT NULL,
+    comment TEXT NOT NULL DEFAULT '')`,
+		},
+	},
+	{
+		version:     5,
+		description: "Rules and findings",
+		queries: []string{
+			`
+CREATE TABLE rule (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    label TEXT NOT NULL,
+    severity INTEGER NOT NULL,
+    port INTEGER NOT NULL DEFAULT 0,
+    banner TEXT NOT NULL DEFAULT '',
+    os TEXT NOT NULL DEFAULT '',
+    location TEXT NOT NULL DEFAULT '',
+    hostname TEXT NOT NULL DEFAULT '',
+    tag TEXT NOT NULL DEFAULT '',
+    enabled BOOLEAN NOT NULL,
+    created BIGINT NOT NULL)`,
+			`
+CREATE TABLE finding (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    rule_id BIGINT NOT NULL,
+    host_id BIGINT NOT NULL,
+    port INTEGER NOT NULL DEFAULT 0,
+    stamp BIGINT NOT NULL,
+    UNIQUE (rule_id, host_id, port),
+    FOREIGN KEY (rule_id) REFERENCES rule (id) ON DELETE CASCADE,
+    FOREIGN KEY (host_id) REFERENCES host (id) ON DELETE CASCADE)`,
+			"CREATE INDEX finding_host_idx ON finding (host_id)",
+			`
+INSERT INTO rule (label, severity, banner, tag, enabled, created) VALUES
+    ('Outdated Windows', 2, '(?i)Windows\s*(?:NT|95|98|ME|2000|XP|Server 2003|Vista)\b', 'legacy-os', TRUE, CAST(EXTRACT(EPOCH FROM now()) AS BIGINT)),
+    ('Outdated CentOS or RHEL', 2, '(?i)CentOS(?:\s+release)?\s+[2-6]\b|[.]el[2-6][._]|Red Hat Enterprise Linux.*release [2-6]\b', 'legacy-os', TRUE, CAST(EXTRACT(EPOCH FROM now()) AS BIGINT)),
+    ('IBM mainframe (z/OS)', 3, '(?i)\bz/OS\b|\bMVS\b', '', TRUE, CAST(EXTRACT(EPOCH FROM now()) AS BIGINT)),
+    ('OpenVMS', 3, '(?i)\bOpenVMS\b', '', TRUE, CAST(EXTRACT(EPOCH FROM now()) AS BIGINT))`,
+		},
+	},
+	{
+		version:     6,
+		description: "Software parsed from the replies of ports",
+		queries:     portSoftwareQueries,
+	},
+	{
+		version:     7,
+		description: "Vulnerabilities and the ports they affect",
+		queries: []string{
+			`
+CREATE TABLE vuln (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    name TEXT UNIQUE NOT NULL,
+    summary TEXT NOT NULL DEFAULT '',
+    score DOUBLE PRECISION NOT NULL DEFAULT 0,
+    severity INTEGER NOT NULL DEFAULT 0,
+    published BIGINT NOT NULL DEFAULT 0,
+    source TEXT NOT NULL)`,
+			`
+CREATE TABLE vuln_range (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    vuln_id BIGINT NOT NULL,
+    vendor TEXT NOT NULL DEFAULT '',
+    product TEXT NOT NULL,
+    version TEXT NOT NULL DEFAULT '',
+    start_incl TEXT NOT NULL DEFAULT '',
+    start_excl TEXT NOT NULL DEFAULT '',
+    end_incl TEXT NOT NULL DEFAULT '',
+    end_excl TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (vuln_id) REFERENCES vuln (id) ON DELETE CASCADE)`,
+			"CREATE INDEX vuln_range_vuln_idx ON vuln_range (vuln_id)",
+			"CREATE INDEX vuln_range_product_idx ON vuln_range (product)",
+			`
+CREATE TABLE port_vuln (
+    port_id BIGINT NOT NULL,
+    vuln_id BIGINT NOT NULL,
+    stamp BIGINT NOT NULL,
+    PRIMARY KEY (port_id, vuln_id),
+    FOREIGN KEY (port_id) REFERENCES port (id) ON DELETE CASCADE,
+    FOREIGN KEY (vuln_id) REFERENCES vuln (id) ON DELETE CASCADE)`,
+			"CREATE INDEX port_vuln_vuln_idx ON port_vuln (vuln_id)",
+		},
+	},
+	{
+		version:     8,
+		description: "Scored OS fingerprints and their evidence",
+		queries: []string{
+			"ALTER TABLE host ADD COLUMN os_version TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE host ADD COLUMN os_confidence DOUBLE PRECISION NOT NULL DEFAULT 0",
+			`
+CREATE TABLE os_evidence (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    host_id BIGINT NOT NULL,
+    port INTEGER NOT NULL,
+    source TEXT NOT NULL,
+    os TEXT NOT NULL,
+    version TEXT NOT NULL DEFAULT '',
+    weight DOUBLE PRECISION NOT NULL,
+    matched TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (host_id) REFERENCES host (id) ON DELETE CASCADE)`,
+			"CREATE INDEX os_evidence_host_idx ON os_evidence (host_id)",
+		},
+	},
+	{
+		version:     9,
+		description: "Incremental metadata updates",
+		queries: []string{
+			"ALTER TABLE host ADD COLUMN meta_updated BIGINT",
+			"CREATE INDEX host_meta_updated_idx ON host (meta_updated)",
+		},
+	},
+	{
+		version:     10,
+		description: "Autonomous systems and RIR delegations",
+		queries: []string{
+			"ALTER TABLE host ADD COLUMN asn BIGINT NOT NULL DEFAULT 0",
+			"ALTER TABLE host ADD COLUMN as_org TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE host ADD COLUMN prefix TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE host ADD COLUMN registry TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE host ADD COLUMN allocated BIGINT NOT NULL DEFAULT 0",
+			"CREATE INDEX host_asn_idx ON host (asn)",
+			// The metadata of all Hosts lack the AS, so they are outdated.
+			"UPDATE host SET meta_updated = NULL",
+			`
+CREATE TABLE delegation (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    registry TEXT NOT NULL,
+    cc TEXT NOT NULL DEFAULT '',
+    first_addr TEXT COLLATE "C" NOT NULL,
+    last_addr TEXT COLLATE "C" NOT NULL,
+    allocated BIGINT NOT NULL,
+    status TEXT NOT NULL DEFAULT '')`,
+			"CREATE INDEX delegation_first_idx ON delegation (first_addr)",
+			"CREATE INDEX delegation_registry_idx ON delegation (registry)",
+			"ALTER TABLE exclusion ADD COLUMN asn BIGINT NOT NULL DEFAULT 0",
+		},
+	},
+	{
+		version:     11,
+		description: "Structured locations",
+		queries: []string{
+			"ALTER TABLE host ADD COLUMN country TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE host ADD COLUMN subdivision TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE host ADD COLUMN city TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE host ADD COLUMN latitude DOUBLE PRECISION NOT NULL DEFAULT 0",
+			"ALTER TABLE host ADD COLUMN longitude DOUBLE PRECISION NOT NULL DEFAULT 0",
+			"ALTER TABLE host ADD COLUMN accuracy INTEGER NOT NULL DEFAULT 0",
+			"CREATE INDEX host_country_idx ON host (country)",
+			// The locations of all Hosts lack the structured fields.
+			"UPDATE host SET meta_updated = NULL",
+		},
+	},
+}
+
+// portSoftwareQueries add the columns for the Software the banner parser
+// finds in the replies of ports. The statements work with SQLite and
+// PostgreSQL alike. Existing ports are parsed at the next metadata update.
+var portSoftwareQueries = []string{
+	"ALTER TABLE port ADD COLUMN service TEXT NOT NULL DEFAULT ''",
+	"ALTER TABLE port ADD COLUMN product TEXT NOT NULL DEFAULT ''",
+	"ALTER TABLE port ADD COLUMN version TEXT NOT NULL DEFAULT ''",
+	"ALTER TABLE port ADD COLUMN info TEXT NOT NULL DEFAULT ''",
+	"ALTER TABLE port ADD COLUMN os TEXT NOT NULL DEFAULT ''",
+	"ALTER TABLE port ADD COLUMN cpe TEXT NOT NULL DEFAULT ''",
+	"CREATE INDEX port_product_idx ON port (product, version)",
+}
@@ -2,7 +2,7 @@
 // -*- mode: go; coding: utf-8; -*-
 // Created on 27. 10. 2022 by Benjamin Walkenhorst
 // (c) 2022 Benjamin Walkenhorst
-// Time-stamp: <2023-04-03 19:37:24 krylon>
+// Time-stamp: <2026-10-19 16:10:50 krylon>
 
 // Package query provides symbolic constants for the various
 // database queries/operations.
@@ -33,4 +33,77 @@ const (
 	XfrGetByZone
 	XfrFinish
 	XfrGetUnfinished
+	HostUpsert
+	HostGetByAddr
+	HostUpdateSource
+	HostAddressAdd
+	HostAddressGetByHost
+	HostNameAdd
+	HostNameGetByHost
+	HostNameLookup
+	PortGetPage
+	PortGetPageCnt
+	XfrGetPage
+	XfrGetPageCnt
+	UserAdd
+	UserGetByName
+	UserGetCnt
+	UserSetPassword
+	SessionAdd
+	SessionGet
+	SessionDelete
+	SessionPurge
+	TokenAdd
+	TokenGet
+	TokenGetByUser
+	TokenDelete
+	TokenTouch
+	TagAdd
+	HostTagAdd
+	HostTagGetByHost
+	NoteAdd
+	NoteGetByHost
+	ExclusionAdd
+	ExclusionGetAll
+	XfrGetByName
+	HostTagDelete
+	TagGetAll
+	NoteDelete
+	PortGetOpenByTag
+	RuleAdd
+	RuleUpdate
+	RuleDelete
+	RuleGetAll
+	RuleGetByID
+	FindingAdd
+	FindingDeleteByRule
+	FindingPurge
+	FindingGetAll
+	FindingGetByHost
+	PortSetSoftware
+	PortGetSoftware
+	PortGetIdentified
+	VulnAdd
+	VulnRangeDelete
+	VulnRangeAdd
+	VulnGetByProduct
+	VulnGetCount
+	PortVulnDelete
+	PortVulnAdd
+	VulnGetByHost
+	VulnGetHostReport
+	OSEvidenceDelete
+	OSEvidenceAdd
+	OSEvidenceGetByHost
+	HostGetDirty
+	HostSetMetaUpdated
+	HostMarkDirty
+	HostMarkAllDirty
+	HostSetAS
+	HostGetASReport
+	DelegationDelete
+	DelegationAdd
+	DelegationLookup
+	HostSetGeo
+	HostGetLocations
 )
@@ -0,0 +1,121 @@
+// /home/krylon/go/src/github.com/blicero/guang/database/retry.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 17:42:21 krylon>
+
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/blicero/guang/common"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// These errors are returned (wrapped) by the methods of HostDB, so callers
+// can use errors.Is to find out what went wrong.
+//
+// ErrBusy indicates the database remained locked after we gave up retrying,
+// or, for PostgreSQL, that a transaction kept failing due to a
+// serialization failure or deadlock.
+// ErrNotFound indicates the requested object does not exist.
+// ErrConstraint indicates an operation violated a constraint, e.g. a UNIQUE
+// index.
+var (
+	ErrBusy       = errors.New("database is busy")
+	ErrNotFound   = errors.New("object was not found in database")
+	ErrConstraint = errors.New("constraint violation")
+)
+
+// retryDelay is the delay before the first retry, it is doubled after every
+// failed attempt up to retryMaxDelay. After retryMaxAttempts attempts, we
+// give up.
+const (
+	retryDelay       = 10 * time.Millisecond
+	retryMaxDelay    = time.Second
+	retryMaxAttempts = 12
+)
+
+// classify wraps errors returned by the database driver in one of our own
+// error values, if it is one we know about.
+func classify(err error) error {
+	if err == nil {
+		return nil
+	} else if errors.Is(err, ErrBusy) || errors.Is(err, ErrConstraint) || errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	var (
+		serr sqlite3.Error
+		perr *pq.Error
+	)
+
+	if errors.As(err, &serr) {
+		switch serr.Code {
+		case sqlite3.ErrBusy, sqlite3.ErrLocked:
+			return fmt.Errorf("%w: %s", ErrBusy, err.Error())
+		case sqlite3.ErrConstraint:
+			return fmt.Errorf("%w: %s", ErrConstraint, err.Error())
+		}
+	} else if errors.As(err, &perr) {
+		switch {
+		case perr.Code.Class() == "23": // integrity_constraint_violation
+			return fmt.Errorf("%w: %s", ErrConstraint, err.Error())
+		case perr.Code == "40001", // serialization_failure
+			perr.Code == "40P01", // deadlock_detected
+			perr.Code == "55P03": // lock_not_available
+			return fmt.Errorf("%w: %s", ErrBusy, err.Error())
+		}
+	} else if retryPat.MatchString(err.Error()) {
+		return fmt.Errorf("%w: %s", ErrBusy, err.Error())
+	}
+
+	return err
+} // func classify(err error) error
+
+// retry calls op until it either succeeds, fails with an error that is not
+// worth a retry, the number of attempts is exhausted, or the Context is
+// cancelled. The delay between attempts grows exponentially. There is no
+// delay after the last attempt, once we give up, we return right away.
+func retry[T any](ctx context.Context, db *sqlDB, op func() (T, error)) (T, error) {
+	var (
+		res   T
+		err   error
+		delay = retryDelay
+	)
+
+	for attempt := 1; ; attempt++ {
+		if res, err = op(); err == nil {
+			return res, nil
+		} else if err = classify(err); !errors.Is(err, ErrBusy) {
+			return res, err
+		} else if attempt == retryMaxAttempts {
+			dbGaveUp.Inc()
+			return res, err
+		}
+
+		dbRetries.Inc()
+
+		if common.Debug {
+			db.log.Printf("[TRACE] Database is busy (attempt %d/%d), retrying in %s\n",
+				attempt,
+				retryMaxAttempts,
+				delay)
+		}
+
+		select {
+		case <-ctx.Done():
+			return res, fmt.Errorf("%w (last error: %s)", ctx.Err(), err.Error())
+		case <-time.After(delay):
+		}
+
+		if delay *= 2; delay > retryMaxDelay {
+			delay = retryMaxDelay
+		}
+	}
+} // func retry[T any](ctx context.Context, db *sqlDB, op func() (T, error)) (T, error)
@@ -0,0 +1,331 @@
+// /home/krylon/go/src/github.com/blicero/guang/database/rules.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 15:11:36 krylon>
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/database/query"
+	"github.com/blicero/guang/rules/severity"
+	"github.com/blicero/krylib"
+)
+
+// This file contains the methods dealing with Rules that flag interesting
+// Hosts, and the Findings they produce.
+
+// RuleAdd adds a Rule to the database.
+func (db *sqlDB) RuleAdd(ctx context.Context, r *data.Rule) error {
+	var (
+		err error
+		id  int64
+	)
+
+	if r.Created.IsZero() {
+		r.Created = time.Now()
+	}
+
+	if id, err = db.insert(
+		ctx,
+		query.RuleAdd,
+		r.Label,
+		int64(r.Severity),
+		int64(r.Port),
+		r.Banner,
+		r.OS,
+		r.Location,
+		r.Hostname,
+		r.Tag,
+		r.Enabled,
+		r.Created.Unix()); err != nil {
+		err = fmt.Errorf("Error adding Rule %q: %w", r.Label, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	r.ID = krylib.ID(id)
+	return nil
+} // func (db *sqlDB) RuleAdd(ctx context.Context, r *data.Rule) error
+
+// RuleUpdate saves the changes made to a Rule. Since the Findings the Rule
+// produced may no longer be valid, they are removed. It returns ErrNotFound
+// if the Rule does not exist.
+func (db *sqlDB) RuleUpdate(ctx context.Context, r *data.Rule) error {
+	var (
+		err error
+		res sql.Result
+		cnt int64
+	)
+
+	if res, err = db.exec(
+		ctx,
+		query.RuleUpdate,
+		r.Label,
+		int64(r.Severity),
+		int64(r.Port),
+		r.Banner,
+		r.OS,
+		r.Location,
+		r.Hostname,
+		r.Tag,
+		r.Enabled,
+		r.ID); err != nil {
+		err = fmt.Errorf("Error updating Rule #%d: %w", r.ID, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	} else if cnt, err = res.RowsAffected(); err != nil {
+		err = fmt.Errorf("Error updating Rule #%d: %w", r.ID, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	} else if cnt == 0 {
+		return fmt.Errorf("Rule #%d: %w", r.ID, ErrNotFound)
+	} else if _, err = db.exec(ctx, query.FindingDeleteByRule, r.ID); err != nil {
+		err = fmt.Errorf("Error deleting Findings of Rule #%d: %w", r.ID, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	return nil
+} // func (db *sqlDB) RuleUpdate(ctx context.Context, r *data.Rule) error
+
+// RuleDelete removes a Rule and all its Findings. It returns ErrNotFound
+// if the Rule does not exist.
+func (db *sqlDB) RuleDelete(ctx context.Context, id krylib.ID) error {
+	var (
+		err error
+		res sql.Result
+		cnt int64
+	)
+
+	if res, err = db.exec(ctx, query.RuleDelete, id); err != nil {
+		err = fmt.Errorf("Error deleting Rule #%d: %w", id, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	} else if cnt, err = res.RowsAffected(); err != nil {
+		err = fmt.Errorf("Error deleting Rule #%d: %w", id, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	} else if cnt == 0 {
+		return fmt.Errorf("Rule #%d: %w", id, ErrNotFound)
+	}
+
+	return nil
+} // func (db *sqlDB) RuleDelete(ctx context.Context, id krylib.ID) error
+
+// RuleGetAll returns all Rules, enabled or not, in the order they were
+// added.
+func (db *sqlDB) RuleGetAll(ctx context.Context) ([]data.Rule, error) {
+	var (
+		err  error
+		rows *sql.Rows
+		list = make([]data.Rule, 0)
+	)
+
+	if rows, err = db.query(ctx, query.RuleGetAll); err != nil {
+		err = fmt.Errorf("Error querying Rules: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck
+
+	for rows.Next() {
+		var (
+			id int64
+			r  data.Rule
+		)
+
+		if err = scanRule(rows, &id, &r); err != nil {
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return nil, err
+		}
+
+		r.ID = krylib.ID(id)
+		list = append(list, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		err = fmt.Errorf("Error querying Rules: %w", classify(err))
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	return list, nil
+} // func (db *sqlDB) RuleGetAll(ctx context.Context) ([]data.Rule, error)
+
+// RuleGetByID loads a single Rule. It returns ErrNotFound if the Rule does
+// not exist.
+func (db *sqlDB) RuleGetByID(ctx context.Context, id krylib.ID) (*data.Rule, error) {
+	var (
+		err  error
+		rows *sql.Rows
+		r    = &data.Rule{ID: id}
+	)
+
+	if rows, err = db.query(ctx, query.RuleGetByID, id); err != nil {
+		err = fmt.Errorf("Error querying Rule #%d: %w", id, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck
+
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			err = fmt.Errorf("Error querying Rule #%d: %w", id, classify(err))
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("Rule #%d: %w", id, ErrNotFound)
+	} else if err = scanRule(rows, nil, r); err != nil {
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	return r, nil
+} // func (db *sqlDB) RuleGetByID(ctx context.Context, id krylib.ID) (*data.Rule, error)
+
+// scanRule reads a Rule from the current row. If id is nil, the row is
+// expected not to contain the Rule's ID.
+func scanRule(rows *sql.Rows, id *int64, r *data.Rule) error {
+	var (
+		err                error
+		sev, port, created int64
+		dest               []any
+	)
+
+	if id != nil {
+		dest = append(dest, id)
+	}
+
+	dest = append(dest,
+		&r.Label,
+		&sev,
+		&port,
+		&r.Banner,
+		&r.OS,
+		&r.Location,
+		&r.Hostname,
+		&r.Tag,
+		&r.Enabled,
+		&created)
+
+	if err = rows.Scan(dest...); err != nil {
+		return fmt.Errorf("Error scanning row into Rule: %w", err)
+	}
+
+	r.Severity = severity.Severity(sev)
+	r.Port = uint16(port)
+	r.Created = time.Unix(created, 0)
+	return nil
+} // func scanRule(rows *sql.Rows, id *int64, r *data.Rule) error
+
+// FindingAdd records that a Rule matched a Host or Port. If the Rule has
+// matched before, only the timestamp is updated.
+func (db *sqlDB) FindingAdd(ctx context.Context, f *data.Finding) error {
+	var err error
+
+	if f.Stamp.IsZero() {
+		f.Stamp = time.Now()
+	}
+
+	if _, err = db.exec(ctx, query.FindingAdd, f.RuleID, f.HostID, int64(f.Port), f.Stamp.Unix()); err != nil {
+		err = fmt.Errorf("Error adding Finding of Rule #%d on Host #%d: %w",
+			f.RuleID,
+			f.HostID,
+			err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	return nil
+} // func (db *sqlDB) FindingAdd(ctx context.Context, f *data.Finding) error
+
+// FindingPurge removes all Findings that have not been confirmed since the
+// given time and returns how many it removed.
+func (db *sqlDB) FindingPurge(ctx context.Context, before time.Time) (int64, error) {
+	var (
+		err error
+		res sql.Result
+		cnt int64
+	)
+
+	if res, err = db.exec(ctx, query.FindingPurge, before.Unix()); err != nil {
+		err = fmt.Errorf("Error purging Findings: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return 0, err
+	} else if cnt, err = res.RowsAffected(); err != nil {
+		err = fmt.Errorf("Error purging Findings: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return 0, err
+	}
+
+	return cnt, nil
+} // func (db *sqlDB) FindingPurge(ctx context.Context, before time.Time) (int64, error)
+
+// FindingGetAll returns all Findings of at least the given Severity, the
+// most severe and most recent ones first.
+func (db *sqlDB) FindingGetAll(ctx context.Context, minSev severity.Severity) ([]data.Finding, error) {
+	return db.queryFindings(ctx, query.FindingGetAll, int64(minSev))
+} // func (db *sqlDB) FindingGetAll(ctx context.Context, minSev severity.Severity) ([]data.Finding, error)
+
+// FindingGetByHost returns the Findings on a Host, the most severe first.
+func (db *sqlDB) FindingGetByHost(ctx context.Context, hostID krylib.ID) ([]data.Finding, error) {
+	return db.queryFindings(ctx, query.FindingGetByHost, hostID)
+} // func (db *sqlDB) FindingGetByHost(ctx context.Context, hostID krylib.ID) ([]data.Finding, error)
+
+func (db *sqlDB) queryFindings(ctx context.Context, qid query.ID, args ...any) ([]data.Finding, error) {
+	var (
+		err  error
+		rows *sql.Rows
+		list = make([]data.Finding, 0)
+	)
+
+	if rows, err = db.query(ctx, qid, args...); err != nil {
+		err = fmt.Errorf("Error querying Findings: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck
+
+	for rows.Next() {
+		var (
+			id, ruleID, hostID, port, stamp, sev int64
+			addr                                 string
+			f                                    data.Finding
+		)
+
+		if err = rows.Scan(&id, &ruleID, &hostID, &port, &stamp, &f.Label, &sev, &f.HostName, &addr); err != nil {
+			err = fmt.Errorf("Error scanning row into Finding: %w", err)
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return nil, err
+		}
+
+		f.ID = krylib.ID(id)
+		f.RuleID = krylib.ID(ruleID)
+		f.HostID = krylib.ID(hostID)
+		f.Port = uint16(port)
+		f.Stamp = time.Unix(stamp, 0)
+		f.Severity = severity.Severity(sev)
+		f.Address = net.ParseIP(addr)
+		list = append(list, f)
+	}
+
+	if err = rows.Err(); err != nil {
+		err = fmt.Errorf("Error querying Findings: %w", classify(err))
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	return list, nil
+} // func (db *sqlDB) queryFindings(ctx context.Context, qid query.ID, args ...any) ([]data.Finding, error)
@@ -0,0 +1,100 @@
+// /home/krylon/go/src/github.com/blicero/guang/database/rules_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 15:11:36 krylon>
+
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/rules/severity"
+	"github.com/blicero/krylib"
+)
+
+func TestRulesAndFindings(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b *testBackend) {
+		var (
+			err      error
+			list     []data.Rule
+			rule     *data.Rule
+			findings []data.Finding
+			ctx      = context.Background()
+			host     = b.hosts[1]
+			r        = data.Rule{
+				Label:    "Telnet in Sweden",
+				Severity: severity.High,
+				Port:     23,
+				Location: "Schweden",
+				Enabled:  true,
+			}
+		)
+
+		if list, err = b.db.RuleGetAll(ctx); err != nil {
+			t.Fatalf("Error loading Rules: %s", err.Error())
+		} else if len(list) < 4 {
+			t.Errorf("The default Rules are missing: %#v", list)
+		} else if err = b.db.RuleAdd(ctx, &r); err != nil {
+			t.Fatalf("Error adding Rule: %s", err.Error())
+		} else if rule, err = b.db.RuleGetByID(ctx, r.ID); err != nil {
+			t.Fatalf("Error loading Rule #%d: %s", r.ID, err.Error())
+		} else if rule.Label != r.Label || rule.Severity != r.Severity || rule.Port != 23 || !rule.Enabled {
+			t.Errorf("Unexpected Rule: %#v", rule)
+		} else if _, err = b.db.RuleGetByID(ctx, 999999); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Loading a missing Rule: %v", err)
+		}
+
+		var f = data.Finding{
+			RuleID: r.ID,
+			HostID: host.ID,
+			Port:   23,
+			Stamp:  time.Now().Add(-time.Hour),
+		}
+
+		if err = b.db.FindingAdd(ctx, &f); err != nil {
+			t.Fatalf("Error adding Finding: %s", err.Error())
+		} else if err = b.db.FindingAdd(ctx, &f); err != nil {
+			t.Fatalf("Error adding Finding twice: %s", err.Error())
+		} else if findings, err = b.db.FindingGetByHost(ctx, host.ID); err != nil {
+			t.Fatalf("Error loading Findings: %s", err.Error())
+		} else if len(findings) != 1 || findings[0].Label != r.Label || findings[0].HostName != host.Name {
+			t.Errorf("Unexpected Findings: %#v", findings)
+		} else if findings, err = b.db.FindingGetAll(ctx, severity.Critical); err != nil {
+			t.Fatalf("Error loading Findings: %s", err.Error())
+		} else if len(findings) != 0 {
+			t.Errorf("Findings are not filtered by severity: %#v", findings)
+		}
+
+		// Editing a Rule discards its Findings.
+		r.Severity = severity.Critical
+		if err = b.db.RuleUpdate(ctx, &r); err != nil {
+			t.Fatalf("Error updating Rule: %s", err.Error())
+		} else if findings, err = b.db.FindingGetByHost(ctx, host.ID); err != nil {
+			t.Fatalf("Error loading Findings: %s", err.Error())
+		} else if len(findings) != 0 {
+			t.Errorf("Findings survived an update of their Rule: %#v", findings)
+		}
+
+		var cnt int64
+
+		f.Stamp = time.Now().Add(-time.Hour)
+		if err = b.db.FindingAdd(ctx, &f); err != nil {
+			t.Fatalf("Error adding Finding: %s", err.Error())
+		} else if cnt, err = b.db.FindingPurge(ctx, time.Now().Add(-time.Minute)); err != nil {
+			t.Fatalf("Error purging Findings: %s", err.Error())
+		} else if cnt < 1 {
+			t.Error("The stale Finding was not purged")
+		} else if err = b.db.RuleDelete(ctx, r.ID); err != nil {
+			t.Errorf("Error deleting Rule: %s", err.Error())
+		} else if err = b.db.RuleDelete(ctx, r.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Deleting a Rule twice: %v", err)
+		} else if err = b.db.RuleUpdate(ctx, &data.Rule{ID: krylib.ID(999999), Label: "nope"}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Updating a missing Rule: %v", err)
+		}
+	})
+} // func TestRulesAndFindings(t *testing.T)
@@ -0,0 +1,575 @@
+// /home/krylon/go/src/github.com/blicero/guang/database/search.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 16:22:36 krylon>
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/search"
+	"github.com/blicero/krylib"
+	"github.com/mattn/go-sqlite3"
+)
+
+// sqliteDriver is the go-sqlite3 driver with a few functions of our own
+// registered on every connection.
+const sqliteDriver = "sqlite3_guang"
+
+func init() {
+	sql.Register(sqliteDriver, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("cidr_contains", cidrContains, true)
+		},
+	})
+} // func init()
+
+// cidrContains returns true if addr is part of the network cidr.
+// It is available as an SQL function in SQLite, to search by network.
+func cidrContains(cidr, addr string) bool {
+	var (
+		err error
+		n   *net.IPNet
+		ip  net.IP
+	)
+
+	if _, n, err = net.ParseCIDR(cidr); err != nil {
+		return false
+	} else if ip = net.ParseIP(addr); ip == nil {
+		return false
+	}
+
+	return n.Contains(ip)
+} // func cidrContains(cidr, addr string) bool
+
+// The full-text index is not part of the versioned schema, because FTS5 is
+// only available if go-sqlite3 was built with the sqlite_fts5 tag, and a
+// database has to remain usable by a binary that was built without it.
+// The index is derived from the host and port tables and kept up to date
+// by triggers. If a binary without FTS5 support opens the database, it
+// removes the triggers, and the next binary with FTS5 support rebuilds the
+// index.
+var (
+	ftsTriggers = []string{
+		"host_fts_ai",
+		"host_fts_ad",
+		"host_fts_au",
+		"port_fts_ai",
+		"port_fts_ad",
+		"port_fts_au",
+	}
+
+	ftsQueries = []string{
+		`
+CREATE VIRTUAL TABLE IF NOT EXISTS host_fts USING fts5(
+    name,
+    os,
+    location,
+    content='host',
+    content_rowid='id'
+)`,
+		`
+CREATE VIRTUAL TABLE IF NOT EXISTS port_fts USING fts5(
+    reply,
+    content='port',
+    content_rowid='id'
+)`,
+		`
+CREATE TRIGGER host_fts_ai AFTER INSERT ON host BEGIN
+    INSERT INTO host_fts (rowid, name, os, location)
+    VALUES (new.id, new.name, new.os, new.location);
+END`,
+		`
+CREATE TRIGGER host_fts_ad AFTER DELETE ON host BEGIN
+    INSERT INTO host_fts (host_fts, rowid, name, os, location)
+    VALUES ('delete', old.id, old.name, old.os, old.location);
+END`,
+		`
+CREATE TRIGGER host_fts_au AFTER UPDATE OF name, os, location ON host BEGIN
+    INSERT INTO host_fts (host_fts, rowid, name, os, location)
+    VALUES ('delete', old.id, old.name, old.os, old.location);
+    INSERT INTO host_fts (rowid, name, os, location)
+    VALUES (new.id, new.name, new.os, new.location);
+END`,
+		`
+CREATE TRIGGER port_fts_ai AFTER INSERT ON port BEGIN
+    INSERT INTO port_fts (rowid, reply) VALUES (new.id, new.reply);
+END`,
+		`
+CREATE TRIGGER port_fts_ad AFTER DELETE ON port BEGIN
+    INSERT INTO port_fts (port_fts, rowid, reply) VALUES ('delete', old.id, old.reply);
+END`,
+		`
+CREATE TRIGGER port_fts_au AFTER UPDATE OF reply ON port BEGIN
+    INSERT INTO port_fts (port_fts, rowid, reply) VALUES ('delete', old.id, old.reply);
+    INSERT INTO port_fts (rowid, reply) VALUES (new.id, new.reply);
+END`,
+		"INSERT INTO host_fts (host_fts) VALUES ('rebuild')",
+		"INSERT INTO port_fts (port_fts) VALUES ('rebuild')",
+	}
+)
+
+var ftsWarnOnce sync.Once
+
+const (
+	qFtsAvailable     = "SELECT sqlite_compileoption_used('ENABLE_FTS5')"
+	qFtsTriggerCount  = "SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name IN ('host_fts_ai', 'host_fts_ad', 'host_fts_au', 'port_fts_ai', 'port_fts_ad', 'port_fts_au')"
+	ftsUnavailableMsg = "SQLite was built without FTS5, searching will be slow. Build with -tags sqlite_fts5 to enable the full-text index."
+)
+
+// setupSearchIndex creates the full-text index if SQLite supports FTS5,
+// or removes the triggers maintaining it if it does not.
+func (db *sqlDB) setupSearchIndex(ctx context.Context) error {
+	var (
+		err          error
+		tx           *sql.Tx
+		available    bool
+		triggerCnt   int
+		dropTriggers = func(tx *sql.Tx) error {
+			for _, name := range ftsTriggers {
+				if _, err := tx.ExecContext(ctx, "DROP TRIGGER IF EXISTS "+name); err != nil {
+					return fmt.Errorf("Cannot drop trigger %s: %w", name, err)
+				}
+			}
+			return nil
+		}
+	)
+
+	if err = db.db.QueryRowContext(ctx, qFtsAvailable).Scan(&available); err != nil {
+		err = fmt.Errorf("Cannot check if FTS5 is available: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	} else if err = db.db.QueryRowContext(ctx, qFtsTriggerCount).Scan(&triggerCnt); err != nil {
+		err = fmt.Errorf("Cannot check for full-text index triggers: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	} else if available && triggerCnt == len(ftsTriggers) {
+		db.fts = true
+		return nil
+	} else if !available && triggerCnt == 0 {
+		ftsWarnOnce.Do(func() { db.log.Printf("[INFO] %s\n", ftsUnavailableMsg) })
+		return nil
+	} else if tx, err = db.db.BeginTx(ctx, nil); err != nil {
+		err = fmt.Errorf("Cannot start transaction: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	defer tx.Rollback() // nolint: errcheck
+
+	if err = dropTriggers(tx); err != nil {
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	} else if !available {
+		db.log.Printf("[INFO] %s Removing the triggers maintaining the index.\n",
+			ftsUnavailableMsg)
+		return tx.Commit()
+	}
+
+	db.log.Println("[INFO] Building full-text index, this may take a while.")
+
+	for _, q := range ftsQueries {
+		if _, err = tx.ExecContext(ctx, q); err != nil {
+			err = fmt.Errorf("Error building full-text index: %w\n%s", err, q)
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		err = fmt.Errorf("Cannot commit full-text index: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	db.fts = true
+	return nil
+} // func (db *sqlDB) setupSearchIndex(ctx context.Context) error
+
+// hostSelect is the list of columns of the host h that scanHost expects.
+const hostSelect = `h.id,
+       h.addr,
+       h.name,
+       COALESCE(h.location, ''),
+       COALESCE(h.os, ''),
+       h.os_version,
+       h.os_confidence,
+       h.source,
+       h.add_stamp,
+       h.asn,
+       h.as_org,
+       h.prefix,
+       h.registry,
+       h.allocated,
+       h.country,
+       h.subdivision,
+       h.city,
+       h.latitude,
+       h.longitude,
+       h.accuracy`
+
+// searchBuilder assembles the SQL for a search query.
+type searchBuilder struct {
+	d    *dialect
+	fts  bool
+	args []any
+}
+
+// arg adds an argument to the query and returns its placeholder.
+func (b *searchBuilder) arg(v any) string {
+	b.args = append(b.args, v)
+	return b.d.placeholder(len(b.args))
+} // func (b *searchBuilder) arg(v any) string
+
+// likePattern turns s into a LIKE pattern that matches any string
+// containing s.
+func likePattern(s string) string {
+	var r = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + r.Replace(s) + "%"
+} // func likePattern(s string) string
+
+// ftsPhrase turns s into an FTS5 phrase that matches any text containing
+// the words of s in order, with the last word possibly being a prefix.
+func ftsPhrase(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `" *`
+} // func ftsPhrase(s string) string
+
+func (b *searchBuilder) like(col, term string) string {
+	return fmt.Sprintf(`%s %s %s ESCAPE '\'`,
+		col,
+		b.d.likeOp,
+		b.arg(likePattern(term)))
+} // func (b *searchBuilder) like(col, term string) string
+
+// hostField returns a condition that is true if the given column of the
+// host table contains term.
+func (b *searchBuilder) hostField(col, term string) string {
+	if b.fts {
+		return fmt.Sprintf("h.id IN (SELECT rowid FROM host_fts WHERE host_fts MATCH %s)",
+			b.arg(col+" : "+ftsPhrase(term)))
+	}
+
+	return b.like("h."+col, term)
+} // func (b *searchBuilder) hostField(col, term string) string
+
+// hostName returns a condition that is true if any of the host's names
+// contains term.
+func (b *searchBuilder) hostName(term string) string {
+	return fmt.Sprintf("(%s OR EXISTS (SELECT 1 FROM host_name n WHERE n.host_id = h.id AND %s))",
+		b.hostField("name", term),
+		b.like("n.name", term))
+} // func (b *searchBuilder) hostName(term string) string
+
+// reply returns a condition that is true if the reply of the port p
+// contains term.
+func (b *searchBuilder) reply(term string) string {
+	if b.fts {
+		return fmt.Sprintf("p.id IN (SELECT rowid FROM port_fts WHERE port_fts MATCH %s)",
+			b.arg(ftsPhrase(term)))
+	}
+
+	return b.like("p.reply", term)
+} // func (b *searchBuilder) reply(term string) string
+
+// portCond returns a condition on the port p for the port:, banner:,
+// product: and version: terms of q. If q has none of them, it matches all
+// open ports.
+func (b *searchBuilder) portCond(q *search.Query) string {
+	var conds = make([]string, 0, len(q.Banner)+len(q.Product)+len(q.Version)+1)
+
+	if len(q.Ports) > 0 {
+		var ph = make([]string, len(q.Ports))
+		for i, p := range q.Ports {
+			ph[i] = b.arg(p)
+		}
+		conds = append(conds, "p.port IN ("+strings.Join(ph, ", ")+")")
+	}
+
+	for _, term := range q.Banner {
+		conds = append(conds, b.reply(term))
+	}
+
+	for _, term := range q.Product {
+		conds = append(conds, b.like("p.product", term))
+	}
+
+	for _, term := range q.Version {
+		conds = append(conds, fmt.Sprintf(`p.version LIKE %s ESCAPE '\'`,
+			b.arg(strings.TrimPrefix(likePattern(term), "%"))))
+	}
+
+	if len(conds) == 0 {
+		return "p.reply IS NOT NULL"
+	}
+
+	return strings.Join(conds, " AND ")
+} // func (b *searchBuilder) portCond(q *search.Query) string
+
+// where returns the WHERE clause selecting the hosts h matching q.
+func (b *searchBuilder) where(q *search.Query) string {
+	var conds = make([]string, 0, 8)
+
+	for _, term := range q.Terms {
+		var hostCond string
+
+		if b.fts {
+			hostCond = fmt.Sprintf("h.id IN (SELECT rowid FROM host_fts WHERE host_fts MATCH %s)",
+				b.arg(ftsPhrase(term)))
+		} else {
+			hostCond = fmt.Sprintf("%s OR %s OR %s",
+				b.like("h.name", term),
+				b.like("h.os", term),
+				b.like("h.location", term))
+		}
+
+		conds = append(conds, fmt.Sprintf(
+			"(%s OR EXISTS (SELECT 1 FROM host_name n WHERE n.host_id = h.id AND %s) OR EXISTS (SELECT 1 FROM port p WHERE p.host_id = h.id AND %s))",
+			hostCond,
+			b.like("n.name", term),
+			b.reply(term)))
+	}
+
+	for _, term := range q.Name {
+		conds = append(conds, b.hostName(term))
+	}
+
+	for _, term := range q.OS {
+		conds = append(conds, b.hostField("os", term))
+	}
+
+	for _, term := range q.Location {
+		conds = append(conds, b.hostField("location", term))
+	}
+
+	if len(q.Country) > 0 {
+		var ph = make([]string, len(q.Country))
+		for i, cc := range q.Country {
+			ph[i] = b.arg(cc)
+		}
+		conds = append(conds, "h.country IN ("+strings.Join(ph, ", ")+")")
+	}
+
+	if len(q.ASNs) > 0 {
+		var ph = make([]string, len(q.ASNs))
+		for i, asn := range q.ASNs {
+			ph[i] = b.arg(int64(asn))
+		}
+		conds = append(conds, "h.asn IN ("+strings.Join(ph, ", ")+")")
+	}
+
+	for _, term := range q.Org {
+		conds = append(conds, b.like("h.as_org", term))
+	}
+
+	for _, tag := range q.Tags {
+		conds = append(conds, fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM host_tag ht INNER JOIN tag t ON ht.tag_id = t.id WHERE ht.host_id = h.id AND t.name = %s)",
+			b.arg(tag)))
+	}
+
+	if q.HasPortFilter() {
+		conds = append(conds, "EXISTS (SELECT 1 FROM port p WHERE p.host_id = h.id AND "+
+			b.portCond(q)+")")
+	}
+
+	if len(q.Nets) > 0 {
+		var nets = make([]string, len(q.Nets))
+		for i, n := range q.Nets {
+			nets[i] = fmt.Sprintf(b.d.inNet, b.arg(n.String()), "a.addr")
+		}
+		conds = append(conds, "EXISTS (SELECT 1 FROM host_address a WHERE a.host_id = h.id AND ("+
+			strings.Join(nets, " OR ")+"))")
+	}
+
+	if len(conds) == 0 {
+		return "1 = 1"
+	}
+
+	return strings.Join(conds, " AND ")
+} // func (b *searchBuilder) where(q *search.Query) string
+
+// pageBounds replaces an invalid page number or page size with the
+// defaults and limits the page size to search.MaxPerPage.
+func pageBounds(page, perPage int) (int, int) {
+	if perPage < 1 {
+		perPage = search.DefaultPerPage
+	} else if perPage > search.MaxPerPage {
+		perPage = search.MaxPerPage
+	}
+
+	if page < 1 {
+		page = 1
+	}
+
+	return page, perPage
+} // func pageBounds(page, perPage int) (int, int)
+
+// queryText runs a query that is not part of the query catalogue, inside
+// the current transaction if there is one.
+func (db *sqlDB) queryText(ctx context.Context, qstr string, args ...any) (*sql.Rows, error) {
+	return retry(ctx, db, func() (*sql.Rows, error) {
+		if db.tx != nil {
+			return db.tx.QueryContext(ctx, qstr, args...)
+		}
+		return db.db.QueryContext(ctx, qstr, args...)
+	})
+} // func (db *sqlDB) queryText(ctx context.Context, qstr string, args ...any) (*sql.Rows, error)
+
+// Search returns the given page of the Hosts matching q, along with their
+// matching ports. If q does not restrict the ports, all open ports of the
+// Hosts are returned.
+func (db *sqlDB) Search(ctx context.Context, q *search.Query, page, perPage int) (*search.Result, error) {
+	var (
+		err   error
+		rows  *sql.Rows
+		where string
+		b     = &searchBuilder{d: db.dialect, fts: db.fts}
+		res   = &search.Result{Query: q.Raw}
+	)
+
+	page, perPage = pageBounds(page, perPage)
+	res.Page = page
+	res.PerPage = perPage
+	where = b.where(q)
+
+	if rows, err = db.queryText(ctx, "SELECT COUNT(*) FROM host h WHERE "+where, b.args...); err != nil {
+		err = fmt.Errorf("Error counting search results for %q: %w", q.Raw, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	for rows.Next() {
+		if err = rows.Scan(&res.Total); err != nil {
+			rows.Close() // nolint: errcheck
+			err = fmt.Errorf("Error scanning search result count: %w", err)
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return nil, err
+		}
+	}
+
+	if err = rows.Close(); err != nil {
+		err = fmt.Errorf("Error counting search results for %q: %w", q.Raw, classify(err))
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	} else if res.Total <= int64((page-1)*perPage) {
+		res.Hits = []data.HostWithPorts{}
+		return res, nil
+	}
+
+	var qstr = fmt.Sprintf(`
+SELECT %s
+FROM host h
+WHERE %s
+ORDER BY h.add_stamp DESC, h.id DESC
+LIMIT %s OFFSET %s`,
+		hostSelect,
+		where,
+		b.arg(perPage),
+		b.arg((page-1)*perPage))
+
+	if rows, err = db.queryText(ctx, qstr, b.args...); err != nil {
+		err = fmt.Errorf("Error searching for %q: %w", q.Raw, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck
+
+	var idx = make(map[krylib.ID]int, perPage)
+	res.Hits = make([]data.HostWithPorts, 0, perPage)
+
+	for rows.Next() {
+		var host *data.Host
+
+		if host, err = scanHost(rows); err != nil {
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return nil, err
+		}
+
+		idx[host.ID] = len(res.Hits)
+		res.Hits = append(res.Hits, data.HostWithPorts{
+			Host:  *host,
+			Ports: []data.Port{},
+		})
+	}
+
+	if err = rows.Err(); err != nil {
+		err = fmt.Errorf("Error searching for %q: %w", q.Raw, classify(err))
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	rows.Close() // nolint: errcheck
+
+	if len(res.Hits) == 0 {
+		return res, nil
+	}
+
+	for i := range res.Hits {
+		if err = db.loadLinks(ctx, &res.Hits[i].Host); err != nil {
+			return nil, err
+		}
+	}
+
+	// Now we fetch the matching ports for all Hosts on the page.
+	b = &searchBuilder{d: db.dialect, fts: db.fts}
+	var ids = make([]string, len(res.Hits))
+	for i, h := range res.Hits {
+		ids[i] = b.arg(h.Host.ID)
+	}
+
+	qstr = fmt.Sprintf(`
+SELECT p.id, p.host_id, p.port, p.timestamp, p.reply
+FROM port p
+WHERE p.host_id IN (%s) AND %s
+ORDER BY p.host_id, p.port`,
+		strings.Join(ids, ", "),
+		b.portCond(q))
+
+	if rows, err = db.queryText(ctx, qstr, b.args...); err != nil {
+		err = fmt.Errorf("Error loading ports for search results: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck
+
+	for rows.Next() {
+		var (
+			portID, hostID, stamp, portNo int64
+			reply                         *string
+		)
+
+		if err = rows.Scan(&portID, &hostID, &portNo, &stamp, &reply); err != nil {
+			err = fmt.Errorf("Error scanning row: %w", err)
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return nil, err
+		}
+
+		var i = idx[krylib.ID(hostID)]
+		res.Hits[i].Ports = append(res.Hits[i].Ports, data.Port{
+			ID:        krylib.ID(portID),
+			HostID:    krylib.ID(hostID),
+			Port:      uint16(portNo),
+			Timestamp: time.Unix(stamp, 0),
+			Reply:     reply,
+		})
+	}
+
+	if err = rows.Err(); err != nil {
+		err = fmt.Errorf("Error loading ports for search results: %w", classify(err))
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	return res, nil
+} // func (db *sqlDB) Search(ctx context.Context, q *search.Query, page, perPage int) (*search.Result, error)
@@ -0,0 +1,186 @@
+// /home/krylon/go/src/github.com/blicero/guang/database/search_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 16:10:50 krylon>
+
+package database
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/search"
+	"github.com/blicero/krylib"
+)
+
+func TestSearch(t *testing.T) {
+	type searchHost struct {
+		addr, name, os, location string
+		ports                    map[uint16]string
+		software                 map[uint16]data.Software
+		tags                     []string
+		as                       data.ASInfo
+		geo                      data.GeoInfo
+	}
+
+	var fixtures = []searchHost{
+		{
+			addr:     "198.51.100.1",
+			name:     "alpha.search.test",
+			os:       "FreeBSD",
+			location: "Berlin, Germany",
+			ports: map[uint16]string{
+				22: "SSH-2.0-OpenSSH_7.4 FreeBSD-20170903",
+				80: "HTTP/1.0 200 OK\r\nServer: nginx",
+			},
+			tags: []string{"Legacy-OS", "dmz"},
+			as:   data.ASInfo{ASN: 64500, Org: "Example Networks"},
+			geo:  data.GeoInfo{Country: "DE", City: "Berlin"},
+		},
+		{
+			addr:     "198.51.100.2",
+			name:     "beta.search.test",
+			os:       "Linux",
+			location: "Paris, France",
+			ports: map[uint16]string{
+				21: "220 (vsFTPd 2.3.4)",
+				22: "SSH-2.0-OpenSSH_8.9p1 Ubuntu-3",
+			},
+			software: map[uint16]data.Software{
+				21: {Service: "ftp", Product: "vsftpd", Version: "2.3.4"},
+				22: {Service: "ssh", Product: "OpenSSH", Version: "8.9p1"},
+			},
+			as:  data.ASInfo{ASN: 64501, Org: "Documentation Transit"},
+			geo: data.GeoInfo{Country: "FR", City: "Paris"},
+		},
+		{
+			addr:     "198.51.100.130",
+			name:     "gamma.search.test",
+			os:       "Windows",
+			location: "Paris, France",
+			tags:     []string{"dmz"},
+			geo:      data.GeoInfo{Country: "FR", City: "Paris"},
+		},
+	}
+
+	type testCase struct {
+		query   string
+		page    int
+		perPage int
+		total   int64
+		hits    int
+		ports   int
+	}
+
+	var cases = []testCase{
+		{query: "name:search.test", total: 3, hits: 3, ports: 4},
+		{query: "name:search.test", page: 2, perPage: 2, total: 3, hits: 1, ports: 2},
+		{query: `port:22 os:FreeBSD banner:"OpenSSH_7"`, total: 1, hits: 1, ports: 1},
+		{query: "port:22,2222 name:search.test", total: 2, hits: 2, ports: 2},
+		{query: "vsftpd", total: 1, hits: 1, ports: 2},
+		{query: "198.51.100.128/25", total: 1, hits: 1, ports: 0},
+		{query: "net:198.51.100.0/24 os:linux", total: 1, hits: 1, ports: 2},
+		{query: "location:paris name:search.test", total: 2, hits: 2, ports: 2},
+		{query: "os:Solaris name:search.test", total: 0, hits: 0, ports: 0},
+		{query: "tag:legacy-os", total: 1, hits: 1, ports: 2},
+		{query: "tag:DMZ port:22", total: 1, hits: 1, ports: 1},
+		{query: "tag:dmz tag:legacy-os", total: 1, hits: 1, ports: 2},
+		{query: "tag:nonexistent", total: 0, hits: 0, ports: 0},
+		{query: "product:openssh version:8 name:search.test", total: 1, hits: 1, ports: 1},
+		{query: "product:vsftpd version:3 name:search.test", total: 0, hits: 0, ports: 0},
+		{query: "asn:64500", total: 1, hits: 1, ports: 2},
+		{query: "asn:AS64500,64501 port:21", total: 1, hits: 1, ports: 1},
+		{query: "org:transit", total: 1, hits: 1, ports: 2},
+		{query: "country:fr name:search.test", total: 2, hits: 2, ports: 2},
+		{query: "country:DE,AT name:search.test", total: 1, hits: 1, ports: 2},
+	}
+
+	forEachBackend(t, func(t *testing.T, b *testBackend) {
+		var (
+			err error
+			ctx = context.Background()
+		)
+
+		for _, f := range fixtures {
+			var host = &data.Host{
+				ID:      krylib.INVALID_ID,
+				Address: net.ParseIP(f.addr),
+				Name:    f.name,
+				Source:  data.HostSourceA,
+			}
+
+			if err = b.db.HostAdd(ctx, host); err != nil {
+				t.Fatalf("Cannot add Host %s: %s", f.name, err.Error())
+			} else if err = b.db.HostSetOS(ctx, host, &data.OSGuess{OS: f.os}); err != nil {
+				t.Fatalf("Cannot set OS of %s: %s", f.name, err.Error())
+			} else if err = b.db.HostSetGeo(ctx, host, f.location, &f.geo); err != nil {
+				t.Fatalf("Cannot set location of %s: %s", f.name, err.Error())
+			} else if err = b.db.HostSetAS(ctx, host, &f.as); err != nil {
+				t.Fatalf("Cannot set AS of %s: %s", f.name, err.Error())
+			}
+
+			for _, tag := range f.tags {
+				if _, err = b.db.HostTagAdd(ctx, host.ID, tag); err != nil {
+					t.Fatalf("Cannot tag %s with %s: %s", f.name, tag, err.Error())
+				}
+			}
+
+			for port, reply := range f.ports {
+				var r = reply
+				if err = b.db.PortAdd(ctx, &data.ScanResult{
+					Host:     *host,
+					Port:     port,
+					Reply:    &r,
+					Software: f.software[port],
+					Stamp:    time.Now(),
+				}); err != nil {
+					t.Fatalf("Cannot add port %d of %s: %s",
+						port,
+						f.name,
+						err.Error())
+				}
+			}
+		}
+
+		for _, c := range cases {
+			var (
+				q     *search.Query
+				res   *search.Result
+				ports int
+			)
+
+			if q, err = search.Parse(c.query); err != nil {
+				t.Errorf("Cannot parse query %q: %s", c.query, err.Error())
+				continue
+			} else if res, err = b.db.Search(ctx, q, c.page, c.perPage); err != nil {
+				t.Errorf("Error searching for %q: %s", c.query, err.Error())
+				continue
+			}
+
+			for _, h := range res.Hits {
+				ports += len(h.Ports)
+			}
+
+			if res.Total != c.total {
+				t.Errorf("Search for %q found %d Hosts, expected %d",
+					c.query,
+					res.Total,
+					c.total)
+			} else if len(res.Hits) != c.hits {
+				t.Errorf("Search for %q returned %d Hosts, expected %d",
+					c.query,
+					len(res.Hits),
+					c.hits)
+			} else if ports != c.ports {
+				t.Errorf("Search for %q returned %d ports, expected %d",
+					c.query,
+					ports,
+					c.ports)
+			}
+		}
+	})
+} // func TestSearch(t *testing.T)
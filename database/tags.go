@@ -0,0 +1,320 @@
+// /home/krylon/go/src/github.com/blicero/guang/database/tags.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 16:04:01 krylon>
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/database/query"
+	"github.com/blicero/krylib"
+)
+
+// This file contains the methods dealing with what the users record about
+// Hosts: tags, notes, and networks to leave alone.
+
+// HostTagAdd attaches the Tag with the given name to a Host, creating the
+// Tag if it does not exist yet. Tag names are case-insensitive and stored
+// in lower case. Tagging a Host twice with the same Tag is not an error.
+func (db *sqlDB) HostTagAdd(ctx context.Context, hostID krylib.ID, name string) (*data.Tag, error) {
+	var (
+		err error
+		id  int64
+		tag = &data.Tag{Name: strings.ToLower(strings.TrimSpace(name))}
+	)
+
+	if tag.Name == "" {
+		return nil, fmt.Errorf("Tag name must not be empty")
+	} else if id, err = db.insert(ctx, query.TagAdd, tag.Name); err != nil {
+		err = fmt.Errorf("Error adding Tag %s: %w", tag.Name, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	tag.ID = krylib.ID(id)
+
+	if _, err = db.exec(ctx, query.HostTagAdd, hostID, tag.ID, time.Now().Unix()); err != nil {
+		err = fmt.Errorf("Error tagging Host #%d with %s: %w",
+			hostID,
+			tag.Name,
+			err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	return tag, nil
+} // func (db *sqlDB) HostTagAdd(ctx context.Context, hostID krylib.ID, name string) (*data.Tag, error)
+
+// HostTagGet returns the Tags attached to a Host, ordered by name.
+func (db *sqlDB) HostTagGet(ctx context.Context, hostID krylib.ID) ([]data.Tag, error) {
+	var (
+		err  error
+		rows *sql.Rows
+		tags []data.Tag
+	)
+
+	if rows, err = db.query(ctx, query.HostTagGetByHost, hostID); err != nil {
+		err = fmt.Errorf("Error querying Tags of Host #%d: %w", hostID, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck
+
+	if tags, err = scanTags(rows); err != nil {
+		err = fmt.Errorf("Error querying Tags of Host #%d: %w", hostID, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	return tags, nil
+} // func (db *sqlDB) HostTagGet(ctx context.Context, hostID krylib.ID) ([]data.Tag, error)
+
+// HostTagDelete removes the Tag with the given name from a Host. It returns
+// ErrNotFound if the Host did not have that Tag.
+func (db *sqlDB) HostTagDelete(ctx context.Context, hostID krylib.ID, name string) error {
+	var (
+		err error
+		res sql.Result
+		cnt int64
+	)
+
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	if res, err = db.exec(ctx, query.HostTagDelete, hostID, name); err != nil {
+		err = fmt.Errorf("Error removing Tag %s from Host #%d: %w", name, hostID, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	} else if cnt, err = res.RowsAffected(); err != nil {
+		err = fmt.Errorf("Error removing Tag %s from Host #%d: %w", name, hostID, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	} else if cnt == 0 {
+		return fmt.Errorf("Tag %s on Host #%d: %w", name, hostID, ErrNotFound)
+	}
+
+	return nil
+} // func (db *sqlDB) HostTagDelete(ctx context.Context, hostID krylib.ID, name string) error
+
+// TagGetAll returns all Tags that are attached to at least one Host,
+// ordered by name.
+func (db *sqlDB) TagGetAll(ctx context.Context) ([]data.Tag, error) {
+	var (
+		err  error
+		rows *sql.Rows
+		tags []data.Tag
+	)
+
+	if rows, err = db.query(ctx, query.TagGetAll); err != nil {
+		err = fmt.Errorf("Error querying Tags: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck
+
+	if tags, err = scanTags(rows); err != nil {
+		err = fmt.Errorf("Error querying Tags: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	return tags, nil
+} // func (db *sqlDB) TagGetAll(ctx context.Context) ([]data.Tag, error)
+
+// scanTags reads Tags from rows of (id, name).
+func scanTags(rows *sql.Rows) ([]data.Tag, error) {
+	var (
+		err  error
+		tags = make([]data.Tag, 0)
+	)
+
+	for rows.Next() {
+		var (
+			id  int64
+			tag data.Tag
+		)
+
+		if err = rows.Scan(&id, &tag.Name); err != nil {
+			return nil, fmt.Errorf("Error scanning row into Tag: %w", err)
+		}
+
+		tag.ID = krylib.ID(id)
+		tags = append(tags, tag)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, classify(err)
+	}
+
+	return tags, nil
+} // func scanTags(rows *sql.Rows) ([]data.Tag, error)
+
+// NoteAdd adds a Note to a Host.
+func (db *sqlDB) NoteAdd(ctx context.Context, n *data.Note) error {
+	var (
+		err error
+		id  int64
+	)
+
+	if n.Stamp.IsZero() {
+		n.Stamp = time.Now()
+	}
+
+	if id, err = db.insert(ctx, query.NoteAdd, n.HostID, n.Author, n.Stamp.Unix(), n.Body); err != nil {
+		err = fmt.Errorf("Error adding Note to Host #%d: %w", n.HostID, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	n.ID = krylib.ID(id)
+	return nil
+} // func (db *sqlDB) NoteAdd(ctx context.Context, n *data.Note) error
+
+// NoteGetByHost returns the Notes on a Host, oldest first.
+func (db *sqlDB) NoteGetByHost(ctx context.Context, hostID krylib.ID) ([]data.Note, error) {
+	var (
+		err   error
+		rows  *sql.Rows
+		notes = make([]data.Note, 0)
+	)
+
+	if rows, err = db.query(ctx, query.NoteGetByHost, hostID); err != nil {
+		err = fmt.Errorf("Error querying Notes on Host #%d: %w", hostID, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck
+
+	for rows.Next() {
+		var (
+			id, stamp int64
+			note      = data.Note{HostID: hostID}
+		)
+
+		if err = rows.Scan(&id, &note.Author, &stamp, &note.Body); err != nil {
+			err = fmt.Errorf("Error scanning row into Note: %w", err)
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return nil, err
+		}
+
+		note.ID = krylib.ID(id)
+		note.Stamp = time.Unix(stamp, 0)
+		notes = append(notes, note)
+	}
+
+	if err = rows.Err(); err != nil {
+		err = fmt.Errorf("Error querying Notes on Host #%d: %w", hostID, classify(err))
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	return notes, nil
+} // func (db *sqlDB) NoteGetByHost(ctx context.Context, hostID krylib.ID) ([]data.Note, error)
+
+// NoteDelete removes a Note from a Host. It returns ErrNotFound if the Host
+// has no such Note.
+func (db *sqlDB) NoteDelete(ctx context.Context, hostID, noteID krylib.ID) error {
+	var (
+		err error
+		res sql.Result
+		cnt int64
+	)
+
+	if res, err = db.exec(ctx, query.NoteDelete, noteID, hostID); err != nil {
+		err = fmt.Errorf("Error deleting Note #%d: %w", noteID, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	} else if cnt, err = res.RowsAffected(); err != nil {
+		err = fmt.Errorf("Error deleting Note #%d: %w", noteID, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	} else if cnt == 0 {
+		return fmt.Errorf("Note #%d on Host #%d: %w", noteID, hostID, ErrNotFound)
+	}
+
+	return nil
+} // func (db *sqlDB) NoteDelete(ctx context.Context, hostID, noteID krylib.ID) error
+
+// ExclusionAdd adds a network or an autonomous system to the list of
+// networks to leave alone. For autonomous systems, the network column
+// holds the AS number in the form AS<n>, so the unique constraint covers
+// them as well.
+func (db *sqlDB) ExclusionAdd(ctx context.Context, ex *data.Exclusion) error {
+	var (
+		err error
+		id  int64
+	)
+
+	if ex.Added.IsZero() {
+		ex.Added = time.Now()
+	}
+
+	if id, err = db.insert(ctx, query.ExclusionAdd, ex.Target(), int64(ex.ASN), ex.Added.Unix(), ex.Comment); err != nil {
+		err = fmt.Errorf("Error adding Exclusion of %s: %w", ex.Target(), err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	ex.ID = krylib.ID(id)
+	return nil
+} // func (db *sqlDB) ExclusionAdd(ctx context.Context, ex *data.Exclusion) error
+
+// ExclusionGetAll returns all excluded networks and autonomous systems.
+func (db *sqlDB) ExclusionGetAll(ctx context.Context) ([]data.Exclusion, error) {
+	var (
+		err  error
+		rows *sql.Rows
+		list = make([]data.Exclusion, 0)
+	)
+
+	if rows, err = db.query(ctx, query.ExclusionGetAll); err != nil {
+		err = fmt.Errorf("Error querying Exclusions: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck
+
+	for rows.Next() {
+		var (
+			id, asn, added int64
+			network        string
+			ex             data.Exclusion
+		)
+
+		if err = rows.Scan(&id, &network, &asn, &added, &ex.Comment); err != nil {
+			err = fmt.Errorf("Error scanning row into Exclusion: %w", err)
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return nil, err
+		} else if asn != 0 {
+			ex.ASN = uint32(asn)
+		} else if _, ex.Network, err = net.ParseCIDR(network); err != nil {
+			err = fmt.Errorf("Invalid network in Exclusion #%d: %w", id, err)
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return nil, err
+		}
+
+		ex.ID = krylib.ID(id)
+		ex.Added = time.Unix(added, 0)
+		list = append(list, ex)
+	}
+
+	if err = rows.Err(); err != nil {
+		err = fmt.Errorf("Error querying Exclusions: %w", classify(err))
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	return list, nil
+} // func (db *sqlDB) ExclusionGetAll(ctx context.Context) ([]data.Exclusion, error)
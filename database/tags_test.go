@@ -0,0 +1,189 @@
+// /home/krylon/go/src/github.com/blicero/guang/database/tags_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 16:04:01 krylon>
+
+package database
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/blicero/guang/data"
+)
+
+func TestTagsAndNotes(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b *testBackend) {
+		var (
+			err   error
+			tag   *data.Tag
+			tags  []data.Tag
+			notes []data.Note
+			ctx   = context.Background()
+			host  = b.hosts[0]
+		)
+
+		if _, err = b.db.HostTagAdd(ctx, host.ID, "  "); err == nil {
+			t.Error("Adding an empty Tag did not fail")
+		} else if tag, err = b.db.HostTagAdd(ctx, host.ID, "Legacy-OS"); err != nil {
+			t.Fatalf("Error tagging Host: %s", err.Error())
+		} else if tag.Name != "legacy-os" {
+			t.Errorf("Tag name was not normalized: %q", tag.Name)
+		} else if _, err = b.db.HostTagAdd(ctx, host.ID, "legacy-os"); err != nil {
+			t.Errorf("Error tagging Host twice: %s", err.Error())
+		} else if _, err = b.db.HostTagAdd(ctx, host.ID, "backup"); err != nil {
+			t.Errorf("Error tagging Host: %s", err.Error())
+		} else if tags, err = b.db.HostTagGet(ctx, host.ID); err != nil {
+			t.Fatalf("Error loading Tags: %s", err.Error())
+		} else if len(tags) != 2 || tags[0].Name != "backup" || tags[1].ID != tag.ID {
+			t.Errorf("Unexpected Tags: %#v", tags)
+		}
+
+		var note = data.Note{
+			HostID: host.ID,
+			Author: "operator",
+			Body:   "Runs an ancient SSH server",
+		}
+
+		if err = b.db.NoteAdd(ctx, &note); err != nil {
+			t.Fatalf("Error adding Note: %s", err.Error())
+		} else if notes, err = b.db.NoteGetByHost(ctx, host.ID); err != nil {
+			t.Fatalf("Error loading Notes: %s", err.Error())
+		} else if len(notes) != 1 || notes[0].ID != note.ID || notes[0].Body != note.Body {
+			t.Errorf("Unexpected Notes: %#v", notes)
+		}
+
+		var ports []data.ScanResult
+
+		if err = b.db.NoteDelete(ctx, b.hosts[1].ID, note.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Deleting a Note of another Host: %v", err)
+		} else if err = b.db.NoteDelete(ctx, host.ID, note.ID); err != nil {
+			t.Errorf("Error deleting Note: %s", err.Error())
+		} else if notes, err = b.db.NoteGetByHost(ctx, host.ID); err != nil {
+			t.Fatalf("Error loading Notes: %s", err.Error())
+		} else if len(notes) != 0 {
+			t.Errorf("Note was not deleted: %#v", notes)
+		} else if tags, err = b.db.TagGetAll(ctx); err != nil {
+			t.Fatalf("Error loading all Tags: %s", err.Error())
+		} else if len(tags) < 2 {
+			t.Errorf("Unexpected Tags: %#v", tags)
+		} else if ports, err = b.db.PortGetOpen(ctx, "Backup"); err != nil {
+			t.Fatalf("Error loading open Ports by Tag: %s", err.Error())
+		}
+
+		// Other tests may have tagged Hosts as well, we only make sure
+		// all the Ports belong to Hosts with the Tag.
+		for _, p := range ports {
+			var found bool
+
+			if tags, err = b.db.HostTagGet(ctx, p.Host.ID); err != nil {
+				t.Fatalf("Error loading Tags: %s", err.Error())
+			}
+
+			for _, tag := range tags {
+				found = found || tag.Name == "backup"
+			}
+
+			if !found {
+				t.Errorf("Port %d of Host %s is not tagged backup",
+					p.Port,
+					p.Host.Name)
+			}
+		}
+
+		if err = b.db.HostTagDelete(ctx, host.ID, "Backup"); err != nil {
+			t.Errorf("Error removing Tag: %s", err.Error())
+		} else if err = b.db.HostTagDelete(ctx, host.ID, "backup"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Removing a Tag twice: %v", err)
+		} else if tags, err = b.db.TagGetAll(ctx); err != nil {
+			t.Fatalf("Error loading all Tags: %s", err.Error())
+		}
+
+		for _, tag := range tags {
+			if tag.Name == "backup" {
+				t.Errorf("Unused Tag is still listed: %#v", tags)
+			}
+		}
+	})
+} // func TestTagsAndNotes(t *testing.T)
+
+func TestExclusions(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b *testBackend) {
+		var (
+			err     error
+			list    []data.Exclusion
+			ctx     = context.Background()
+			_, n, _ = net.ParseCIDR("198.51.100.7/32")
+			ex      = data.Exclusion{Network: n, Comment: "Please leave us alone"}
+		)
+
+		if err = b.db.ExclusionAdd(ctx, &ex); err != nil {
+			t.Fatalf("Error adding Exclusion: %s", err.Error())
+		} else if err = b.db.ExclusionAdd(ctx, &data.Exclusion{Network: n}); !errors.Is(err, ErrConstraint) {
+			t.Errorf("Unexpected error adding Exclusion twice: %v", err)
+		} else if err = b.db.ExclusionAdd(ctx, &data.Exclusion{ASN: 64496}); err != nil {
+			t.Fatalf("Error adding Exclusion of AS: %s", err.Error())
+		} else if err = b.db.ExclusionAdd(ctx, &data.Exclusion{ASN: 64496}); !errors.Is(err, ErrConstraint) {
+			t.Errorf("Unexpected error adding Exclusion of AS twice: %v", err)
+		} else if list, err = b.db.ExclusionGetAll(ctx); err != nil {
+			t.Fatalf("Error loading Exclusions: %s", err.Error())
+		} else if len(list) != 2 || list[0].Network.String() != n.String() || list[0].Comment != ex.Comment {
+			t.Errorf("Unexpected Exclusions: %#v", list)
+		} else if list[1].ASN != 64496 || list[1].Network != nil || list[1].Target() != "AS64496" {
+			t.Errorf("Unexpected Exclusion of AS: %#v", list[1])
+		}
+	})
+} // func TestExclusions(t *testing.T)
+
+func TestXfrGetByName(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b *testBackend) {
+		var (
+			err error
+			x   *data.XFR
+			ctx = context.Background()
+			sub = data.XFR{Zone: "lab.krylon.net.", Start: time.Now()}
+		)
+
+		if b.xfr == nil {
+			t.SkipNow()
+		} else if err = b.db.XfrAdd(ctx, &sub); err != nil {
+			t.Fatalf("Error adding XFR: %s", err.Error())
+		}
+
+		type testCase struct {
+			name string
+			zone string
+		}
+
+		var cases = []testCase{
+			{"finn.krylon.net", "krylon.net"},
+			{"finn.krylon.net.", "krylon.net"},
+			{"krylon.net", "krylon.net"},
+			{"box.lab.krylon.net", "lab.krylon.net."},
+			{"notkrylon.net", ""},
+			{"example.org", ""},
+		}
+
+		for _, c := range cases {
+			if x, err = b.db.XfrGetByName(ctx, c.name); c.zone == "" {
+				if !errors.Is(err, ErrNotFound) {
+					t.Errorf("Unexpected result looking up XFR for %s: %v %v",
+						c.name,
+						x,
+						err)
+				}
+			} else if err != nil {
+				t.Errorf("Error looking up XFR for %s: %s", c.name, err.Error())
+			} else if x.Zone != c.zone {
+				t.Errorf("Found zone %s for %s, expected %s",
+					x.Zone,
+					c.name,
+					c.zone)
+			}
+		}
+	})
+} // func TestXfrGetByName(t *testing.T)
@@ -0,0 +1,388 @@
+// /home/krylon/go/src/github.com/blicero/guang/database/users.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 14:34:15 krylon>
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/blicero/guang/auth"
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/database/query"
+	"github.com/blicero/krylib"
+)
+
+// This file contains the methods dealing with the users of the web
+// frontend, their sessions and API tokens.
+
+// UserAdd adds a new User to the database. The User's password must
+// already be hashed.
+func (db *sqlDB) UserAdd(ctx context.Context, u *data.User) error {
+	var (
+		err error
+		id  int64
+	)
+
+	if u.Created.IsZero() {
+		u.Created = time.Now()
+	}
+
+	if id, err = db.insert(ctx, query.UserAdd, u.Name, u.PwHash, u.Role, u.Created.Unix()); err != nil {
+		err = fmt.Errorf("Error adding User %s: %w", u.Name, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	u.ID = krylib.ID(id)
+	return nil
+} // func (db *sqlDB) UserAdd(ctx context.Context, u *data.User) error
+
+// UserGetByName loads the User with the given name.
+// If there is no such User, ErrNotFound is returned.
+func (db *sqlDB) UserGetByName(ctx context.Context, name string) (*data.User, error) {
+	var (
+		err         error
+		rows        *sql.Rows
+		id, created int64
+		role        int64
+		u           = &data.User{Name: name}
+	)
+
+	if rows, err = db.query(ctx, query.UserGetByName, name); err != nil {
+		err = fmt.Errorf("Error querying User %s: %w", name, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck
+
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			err = fmt.Errorf("Error querying User %s: %w", name, classify(err))
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("User %s: %w", name, ErrNotFound)
+	} else if err = rows.Scan(&id, &u.PwHash, &role, &created); err != nil {
+		err = fmt.Errorf("Error scanning row into User: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	u.ID = krylib.ID(id)
+	u.Role = auth.Role(role)
+	u.Created = time.Unix(created, 0)
+	return u, nil
+} // func (db *sqlDB) UserGetByName(ctx context.Context, name string) (*data.User, error)
+
+// UserGetCount returns the number of Users.
+func (db *sqlDB) UserGetCount(ctx context.Context) (int64, error) {
+	var (
+		err error
+		cnt int64
+	)
+
+	if cnt, err = db.count(ctx, query.UserGetCnt); err != nil {
+		err = fmt.Errorf("Error counting Users: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return 0, err
+	}
+
+	return cnt, nil
+} // func (db *sqlDB) UserGetCount(ctx context.Context) (int64, error)
+
+// UserSetPassword sets a new password hash for the User.
+func (db *sqlDB) UserSetPassword(ctx context.Context, u *data.User, hash string) error {
+	var err error
+
+	if _, err = db.exec(ctx, query.UserSetPassword, hash, u.ID); err != nil {
+		err = fmt.Errorf("Error setting password of User %s: %w", u.Name, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	u.PwHash = hash
+	return nil
+} // func (db *sqlDB) UserSetPassword(ctx context.Context, u *data.User, hash string) error
+
+// SessionAdd stores a new Session.
+func (db *sqlDB) SessionAdd(ctx context.Context, s *data.Session) error {
+	var (
+		err error
+		id  int64
+	)
+
+	if id, err = db.insert(ctx, query.SessionAdd,
+		s.User.ID,
+		s.KeyHash,
+		s.CSRF,
+		s.Created.Unix(),
+		s.Expires.Unix()); err != nil {
+		err = fmt.Errorf("Error adding Session for User %s: %w", s.User.Name, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	s.ID = krylib.ID(id)
+	return nil
+} // func (db *sqlDB) SessionAdd(ctx context.Context, s *data.Session) error
+
+// SessionGet loads the Session with the given key hash, along with its
+// User. If there is no such Session, or it has expired, ErrNotFound is
+// returned.
+func (db *sqlDB) SessionGet(ctx context.Context, keyHash string) (*data.Session, error) {
+	var (
+		err                  error
+		rows                 *sql.Rows
+		id, created, expires int64
+		uid, ucreated, role  int64
+		s                    = &data.Session{KeyHash: keyHash}
+	)
+
+	if rows, err = db.query(ctx, query.SessionGet, keyHash, time.Now().Unix()); err != nil {
+		err = fmt.Errorf("Error querying Session: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck
+
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			err = fmt.Errorf("Error querying Session: %w", classify(err))
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("Session: %w", ErrNotFound)
+	} else if err = rows.Scan(
+		&id,
+		&s.CSRF,
+		&created,
+		&expires,
+		&uid,
+		&s.User.Name,
+		&s.User.PwHash,
+		&role,
+		&ucreated); err != nil {
+		err = fmt.Errorf("Error scanning row into Session: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	s.ID = krylib.ID(id)
+	s.Created = time.Unix(created, 0)
+	s.Expires = time.Unix(expires, 0)
+	s.User.ID = krylib.ID(uid)
+	s.User.Role = auth.Role(role)
+	s.User.Created = time.Unix(ucreated, 0)
+	return s, nil
+} // func (db *sqlDB) SessionGet(ctx context.Context, keyHash string) (*data.Session, error)
+
+// SessionDelete removes the Session with the given key hash.
+func (db *sqlDB) SessionDelete(ctx context.Context, keyHash string) error {
+	var err error
+
+	if _, err = db.exec(ctx, query.SessionDelete, keyHash); err != nil {
+		err = fmt.Errorf("Error deleting Session: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	return nil
+} // func (db *sqlDB) SessionDelete(ctx context.Context, keyHash string) error
+
+// SessionPurge removes all expired Sessions and returns how many there were.
+func (db *sqlDB) SessionPurge(ctx context.Context) (int64, error) {
+	var (
+		err error
+		res sql.Result
+	)
+
+	if res, err = db.exec(ctx, query.SessionPurge, time.Now().Unix()); err != nil {
+		err = fmt.Errorf("Error purging expired Sessions: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return 0, err
+	}
+
+	return res.RowsAffected()
+} // func (db *sqlDB) SessionPurge(ctx context.Context) (int64, error)
+
+// TokenAdd stores a new APIToken.
+func (db *sqlDB) TokenAdd(ctx context.Context, t *data.APIToken) error {
+	var (
+		err error
+		id  int64
+	)
+
+	if t.Created.IsZero() {
+		t.Created = time.Now()
+	}
+
+	if id, err = db.insert(ctx, query.TokenAdd,
+		t.User.ID,
+		t.Name,
+		t.KeyHash,
+		t.Created.Unix()); err != nil {
+		err = fmt.Errorf("Error adding API token %q for User %s: %w",
+			t.Name,
+			t.User.Name,
+			err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	t.ID = krylib.ID(id)
+	return nil
+} // func (db *sqlDB) TokenAdd(ctx context.Context, t *data.APIToken) error
+
+// TokenGet loads the APIToken with the given key hash, along with its
+// User. If there is no such token, ErrNotFound is returned.
+func (db *sqlDB) TokenGet(ctx context.Context, keyHash string) (*data.APIToken, error) {
+	var (
+		err                 error
+		rows                *sql.Rows
+		id, created, used   int64
+		uid, ucreated, role int64
+		t                   = &data.APIToken{KeyHash: keyHash}
+	)
+
+	if rows, err = db.query(ctx, query.TokenGet, keyHash); err != nil {
+		err = fmt.Errorf("Error querying API token: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck
+
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			err = fmt.Errorf("Error querying API token: %w", classify(err))
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("API token: %w", ErrNotFound)
+	} else if err = rows.Scan(
+		&id,
+		&t.Name,
+		&created,
+		&used,
+		&uid,
+		&t.User.Name,
+		&t.User.PwHash,
+		&role,
+		&ucreated); err != nil {
+		err = fmt.Errorf("Error scanning row into API token: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	t.ID = krylib.ID(id)
+	t.Created = time.Unix(created, 0)
+	t.User.ID = krylib.ID(uid)
+	t.User.Role = auth.Role(role)
+	t.User.Created = time.Unix(ucreated, 0)
+
+	if used != 0 {
+		t.LastUsed = time.Unix(used, 0)
+	}
+
+	return t, nil
+} // func (db *sqlDB) TokenGet(ctx context.Context, keyHash string) (*data.APIToken, error)
+
+// TokenGetByUser returns all APITokens of the given User, the oldest first.
+// The tokens' key hashes are not loaded.
+func (db *sqlDB) TokenGetByUser(ctx context.Context, u *data.User) ([]data.APIToken, error) {
+	var (
+		err    error
+		rows   *sql.Rows
+		tokens []data.APIToken
+	)
+
+	if rows, err = db.query(ctx, query.TokenGetByUser, u.ID); err != nil {
+		err = fmt.Errorf("Error querying API tokens of User %s: %w", u.Name, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck
+
+	for rows.Next() {
+		var (
+			id, created, used int64
+			t                 = data.APIToken{User: *u}
+		)
+
+		if err = rows.Scan(&id, &t.Name, &created, &used); err != nil {
+			err = fmt.Errorf("Error scanning row into API token: %w", err)
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return nil, err
+		}
+
+		t.ID = krylib.ID(id)
+		t.Created = time.Unix(created, 0)
+
+		if used != 0 {
+			t.LastUsed = time.Unix(used, 0)
+		}
+
+		tokens = append(tokens, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		err = fmt.Errorf("Error querying API tokens of User %s: %w", u.Name, classify(err))
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	return tokens, nil
+} // func (db *sqlDB) TokenGetByUser(ctx context.Context, u *data.User) ([]data.APIToken, error)
+
+// TokenDelete removes the APIToken with the given ID, provided it belongs
+// to the User. Otherwise, ErrNotFound is returned.
+func (db *sqlDB) TokenDelete(ctx context.Context, u *data.User, id krylib.ID) error {
+	var (
+		err error
+		res sql.Result
+		cnt int64
+	)
+
+	if res, err = db.exec(ctx, query.TokenDelete, id, u.ID); err != nil {
+		err = fmt.Errorf("Error deleting API token #%d: %w", id, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	} else if cnt, err = res.RowsAffected(); err != nil {
+		err = fmt.Errorf("Error deleting API token #%d: %w", id, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	} else if cnt == 0 {
+		return fmt.Errorf("API token #%d of User %s: %w", id, u.Name, ErrNotFound)
+	}
+
+	return nil
+} // func (db *sqlDB) TokenDelete(ctx context.Context, u *data.User, id krylib.ID) error
+
+// TokenTouch records that the APIToken was just used.
+func (db *sqlDB) TokenTouch(ctx context.Context, t *data.APIToken) error {
+	var (
+		err error
+		now = time.Now()
+	)
+
+	if _, err = db.exec(ctx, query.TokenTouch, now.Unix(), t.ID); err != nil {
+		err = fmt.Errorf("Error updating API token #%d: %w", t.ID, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	t.LastUsed = now
+	return nil
+} // func (db *sqlDB) TokenTouch(ctx context.Context, t *data.APIToken) error
@@ -0,0 +1,136 @@
+// /home/krylon/go/src/github.com/blicero/guang/database/users_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 14:34:15 krylon>
+
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blicero/guang/auth"
+	"github.com/blicero/guang/data"
+)
+
+func TestUsers(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b *testBackend) {
+		var (
+			err    error
+			cnt    int64
+			stored *data.User
+			ctx    = context.Background()
+			user   = data.User{
+				Name:   "operator",
+				Role:   auth.Operator,
+				PwHash: "$2a$10$not.a.real.hash",
+			}
+		)
+
+		if err = b.db.UserAdd(ctx, &user); err != nil {
+			t.Fatalf("Error adding User: %s", err.Error())
+		} else if stored, err = b.db.UserGetByName(ctx, user.Name); err != nil {
+			t.Fatalf("Error loading User %s: %s", user.Name, err.Error())
+		} else if stored.ID != user.ID || stored.Role != auth.Operator || stored.PwHash != user.PwHash {
+			t.Errorf("Unexpected User: %#v", stored)
+		}
+
+		var dup = data.User{Name: user.Name, PwHash: "x"}
+
+		if err = b.db.UserAdd(ctx, &dup); !errors.Is(err, ErrConstraint) {
+			t.Errorf("Unexpected error adding User %s twice: %v", user.Name, err)
+		} else if _, err = b.db.UserGetByName(ctx, "nobody"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Unexpected error loading nonexistent User: %v", err)
+		} else if cnt, err = b.db.UserGetCount(ctx); err != nil {
+			t.Errorf("Error counting Users: %s", err.Error())
+		} else if cnt != 1 {
+			t.Errorf("Unexpected number of Users: %d", cnt)
+		}
+
+		if err = b.db.UserSetPassword(ctx, &user, "$2a$10$another.hash"); err != nil {
+			t.Errorf("Error setting password: %s", err.Error())
+		} else if stored, err = b.db.UserGetByName(ctx, user.Name); err != nil {
+			t.Errorf("Error loading User %s: %s", user.Name, err.Error())
+		} else if stored.PwHash != "$2a$10$another.hash" {
+			t.Errorf("Password was not changed: %s", stored.PwHash)
+		}
+
+		var (
+			now     = time.Now()
+			session = data.Session{
+				User:    user,
+				KeyHash: auth.HashToken("valid"),
+				CSRF:    "csrf",
+				Created: now,
+				Expires: now.Add(time.Hour),
+			}
+			expired = data.Session{
+				User:    user,
+				KeyHash: auth.HashToken("expired"),
+				CSRF:    "csrf",
+				Created: now.Add(-2 * time.Hour),
+				Expires: now.Add(-time.Hour),
+			}
+			s *data.Session
+		)
+
+		if err = b.db.SessionAdd(ctx, &session); err != nil {
+			t.Fatalf("Error adding Session: %s", err.Error())
+		} else if err = b.db.SessionAdd(ctx, &expired); err != nil {
+			t.Fatalf("Error adding expired Session: %s", err.Error())
+		} else if s, err = b.db.SessionGet(ctx, session.KeyHash); err != nil {
+			t.Errorf("Error loading Session: %s", err.Error())
+		} else if s.ID != session.ID || s.User.ID != user.ID || s.User.Role != auth.Operator || s.CSRF != "csrf" {
+			t.Errorf("Unexpected Session: %#v", s)
+		} else if _, err = b.db.SessionGet(ctx, expired.KeyHash); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Unexpected error loading expired Session: %v", err)
+		} else if cnt, err = b.db.SessionPurge(ctx); err != nil {
+			t.Errorf("Error purging Sessions: %s", err.Error())
+		} else if cnt != 1 {
+			t.Errorf("Purged %d Sessions, expected 1", cnt)
+		} else if err = b.db.SessionDelete(ctx, session.KeyHash); err != nil {
+			t.Errorf("Error deleting Session: %s", err.Error())
+		} else if _, err = b.db.SessionGet(ctx, session.KeyHash); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Unexpected error loading deleted Session: %v", err)
+		}
+
+		var (
+			token = data.APIToken{
+				User:    user,
+				Name:    "cron",
+				KeyHash: auth.HashToken("token"),
+			}
+			tok    *data.APIToken
+			tokens []data.APIToken
+		)
+
+		if err = b.db.TokenAdd(ctx, &token); err != nil {
+			t.Fatalf("Error adding API token: %s", err.Error())
+		} else if tok, err = b.db.TokenGet(ctx, token.KeyHash); err != nil {
+			t.Errorf("Error loading API token: %s", err.Error())
+		} else if tok.ID != token.ID || tok.User.Name != user.Name || !tok.LastUsed.IsZero() {
+			t.Errorf("Unexpected API token: %#v", tok)
+		} else if err = b.db.TokenTouch(ctx, tok); err != nil {
+			t.Errorf("Error updating API token: %s", err.Error())
+		} else if tokens, err = b.db.TokenGetByUser(ctx, &user); err != nil {
+			t.Errorf("Error loading API tokens: %s", err.Error())
+		} else if len(tokens) != 1 || tokens[0].Name != "cron" || tokens[0].LastUsed.IsZero() {
+			t.Errorf("Unexpected API tokens: %#v", tokens)
+		}
+
+		var other = data.User{Name: "somebody", PwHash: "x"}
+
+		if err = b.db.UserAdd(ctx, &other); err != nil {
+			t.Fatalf("Error adding User: %s", err.Error())
+		} else if err = b.db.TokenDelete(ctx, &other, token.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Unexpected error deleting another User's API token: %v", err)
+		} else if err = b.db.TokenDelete(ctx, &user, token.ID); err != nil {
+			t.Errorf("Error deleting API token: %s", err.Error())
+		} else if _, err = b.db.TokenGet(ctx, token.KeyHash); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Unexpected error loading deleted API token: %v", err)
+		}
+	})
+} // func TestUsers(t *testing.T)
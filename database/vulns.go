@@ -0,0 +1,345 @@
+// /home/krylon/go/src/github.com/blicero/guang/database/vulns.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 15:40:22 krylon>
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/database/query"
+	"github.com/blicero/guang/rules/severity"
+	"github.com/blicero/krylib"
+)
+
+// This file contains the methods dealing with known Vulnerabilities and
+// the Ports running software affected by them.
+
+// VulnAdd adds a Vulnerability to the database, or updates it if a
+// Vulnerability with the same name exists already. The affected ranges
+// stored previously are replaced by those of v. Since this takes several
+// queries, callers should wrap it in a transaction.
+func (db *sqlDB) VulnAdd(ctx context.Context, v *data.Vulnerability) error {
+	var (
+		err       error
+		id        int64
+		published int64
+	)
+
+	if !v.Published.IsZero() {
+		published = v.Published.Unix()
+	}
+
+	if id, err = db.insert(
+		ctx,
+		query.VulnAdd,
+		v.Name,
+		v.Summary,
+		v.Score,
+		int64(v.Severity),
+		published,
+		v.Source); err != nil {
+		err = fmt.Errorf("Error adding Vulnerability %s: %w", v.Name, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	v.ID = krylib.ID(id)
+
+	if _, err = db.exec(ctx, query.VulnRangeDelete, v.ID); err != nil {
+		err = fmt.Errorf("Error deleting affected ranges of %s: %w", v.Name, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	for _, r := range v.Ranges {
+		if _, err = db.exec(
+			ctx,
+			query.VulnRangeAdd,
+			v.ID,
+			r.Vendor,
+			r.Product,
+			r.Version,
+			r.StartIncl,
+			r.StartExcl,
+			r.EndIncl,
+			r.EndExcl); err != nil {
+			err = fmt.Errorf("Error adding affected range of %s: %w", v.Name, err)
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return err
+		}
+	}
+
+	return nil
+} // func (db *sqlDB) VulnAdd(ctx context.Context, v *data.Vulnerability) error
+
+// VulnGetByProduct returns the Vulnerabilities affecting some version of
+// the given product. Only the ranges for that product are loaded.
+func (db *sqlDB) VulnGetByProduct(ctx context.Context, product string) ([]data.Vulnerability, error) {
+	var (
+		err  error
+		rows *sql.Rows
+		list = make([]data.Vulnerability, 0)
+	)
+
+	if rows, err = db.query(ctx, query.VulnGetByProduct, product); err != nil {
+		err = fmt.Errorf("Error querying Vulnerabilities of %s: %w", product, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck
+
+	for rows.Next() {
+		var (
+			v  data.Vulnerability
+			r  data.AffectedRange
+			id int64
+		)
+
+		if err = scanVuln(rows, &id, &v,
+			&r.Vendor,
+			&r.Product,
+			&r.Version,
+			&r.StartIncl,
+			&r.StartExcl,
+			&r.EndIncl,
+			&r.EndExcl); err != nil {
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return nil, err
+		}
+
+		// The rows are ordered by the ID of the Vulnerability, so all
+		// ranges of a Vulnerability are adjacent.
+		if n := len(list); n > 0 && list[n-1].ID == v.ID {
+			list[n-1].Ranges = append(list[n-1].Ranges, r)
+		} else {
+			v.Ranges = []data.AffectedRange{r}
+			list = append(list, v)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		err = fmt.Errorf("Error querying Vulnerabilities of %s: %w", product, classify(err))
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	return list, nil
+} // func (db *sqlDB) VulnGetByProduct(ctx context.Context, product string) ([]data.Vulnerability, error)
+
+// VulnGetCount returns the number of known Vulnerabilities.
+func (db *sqlDB) VulnGetCount(ctx context.Context) (int64, error) {
+	var (
+		err error
+		cnt int64
+	)
+
+	if cnt, err = db.count(ctx, query.VulnGetCount); err != nil {
+		err = fmt.Errorf("Error querying number of Vulnerabilities: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return 0, err
+	}
+
+	return cnt, nil
+} // func (db *sqlDB) VulnGetCount(ctx context.Context) (int64, error)
+
+// PortGetIdentified returns all Ports on which the banner parser found a
+// CPE name, which is what we need to look up Vulnerabilities. The replies
+// of the Ports are not loaded.
+func (db *sqlDB) PortGetIdentified(ctx context.Context) ([]data.Port, error) {
+	var (
+		err   error
+		rows  *sql.Rows
+		ports = make([]data.Port, 0)
+	)
+
+	if rows, err = db.query(ctx, query.PortGetIdentified); err != nil {
+		err = fmt.Errorf("Error querying identified Ports: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck
+
+	for rows.Next() {
+		var (
+			id, hostID, stamp int64
+			port              data.Port
+		)
+
+		if err = rows.Scan(
+			&id,
+			&hostID,
+			&port.Port,
+			&stamp,
+			&port.Software.Service,
+			&port.Software.Product,
+			&port.Software.Version,
+			&port.Software.Info,
+			&port.Software.OS,
+			&port.Software.CPE); err != nil {
+			err = fmt.Errorf("Error scanning result row into Port: %w", err)
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return nil, err
+		}
+
+		port.ID = krylib.ID(id)
+		port.HostID = krylib.ID(hostID)
+		port.Timestamp = time.Unix(stamp, 0)
+		ports = append(ports, port)
+	}
+
+	if err = rows.Err(); err != nil {
+		err = fmt.Errorf("Error querying identified Ports: %w", classify(err))
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	return ports, nil
+} // func (db *sqlDB) PortGetIdentified(ctx context.Context) ([]data.Port, error)
+
+// PortVulnSet records that the Port is affected by the given
+// Vulnerabilities, replacing whatever was recorded before.
+func (db *sqlDB) PortVulnSet(ctx context.Context, port *data.Port, vulns []data.Vulnerability) error {
+	var (
+		err   error
+		stamp = time.Now().Unix()
+	)
+
+	if _, err = db.exec(ctx, query.PortVulnDelete, port.ID); err != nil {
+		err = fmt.Errorf("Error deleting Vulnerabilities of Port #%d: %w", port.ID, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	for i := range vulns {
+		if _, err = db.exec(ctx, query.PortVulnAdd, port.ID, vulns[i].ID, stamp); err != nil {
+			err = fmt.Errorf("Error adding Vulnerability %s to Port #%d: %w",
+				vulns[i].Name,
+				port.ID,
+				err)
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return err
+		}
+	}
+
+	return nil
+} // func (db *sqlDB) PortVulnSet(ctx context.Context, port *data.Port, vulns []data.Vulnerability) error
+
+// VulnGetByHost returns the Vulnerabilities found on the Ports of a Host,
+// ordered by Port, the most severe first.
+func (db *sqlDB) VulnGetByHost(ctx context.Context, hostID krylib.ID) ([]data.VulnMatch, error) {
+	var (
+		err  error
+		rows *sql.Rows
+		list = make([]data.VulnMatch, 0)
+	)
+
+	if rows, err = db.query(ctx, query.VulnGetByHost, hostID); err != nil {
+		err = fmt.Errorf("Error querying Vulnerabilities of Host #%d: %w", hostID, err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck
+
+	for rows.Next() {
+		var (
+			id, host, portID, port int64
+			m                      data.VulnMatch
+		)
+
+		if err = scanVuln(rows, &id, &m.Vuln, &host, &portID, &port, &m.Product, &m.Version); err != nil {
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return nil, err
+		}
+
+		m.HostID = krylib.ID(host)
+		m.PortID = krylib.ID(portID)
+		m.Port = uint16(port)
+		list = append(list, m)
+	}
+
+	if err = rows.Err(); err != nil {
+		err = fmt.Errorf("Error querying Vulnerabilities of Host #%d: %w", hostID, classify(err))
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	return list, nil
+} // func (db *sqlDB) VulnGetByHost(ctx context.Context, hostID krylib.ID) ([]data.VulnMatch, error)
+
+// VulnGetHostReport returns up to limit Hosts, ordered by the number of
+// distinct Vulnerabilities found on their Ports.
+func (db *sqlDB) VulnGetHostReport(ctx context.Context, limit int) ([]data.VulnerableHost, error) {
+	var (
+		err  error
+		rows *sql.Rows
+		list = make([]data.VulnerableHost, 0)
+	)
+
+	if rows, err = db.query(ctx, query.VulnGetHostReport, limit); err != nil {
+		err = fmt.Errorf("Error querying most vulnerable Hosts: %w", err)
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	defer rows.Close() // nolint: errcheck
+
+	for rows.Next() {
+		var (
+			id   int64
+			addr string
+			h    data.VulnerableHost
+		)
+
+		if err = rows.Scan(&id, &addr, &h.Name, &h.Vulns, &h.MaxScore); err != nil {
+			err = fmt.Errorf("Error scanning row into VulnerableHost: %w", err)
+			db.log.Printf("[ERROR] %s\n", err.Error())
+			return nil, err
+		}
+
+		h.HostID = krylib.ID(id)
+		h.Address = net.ParseIP(addr)
+		list = append(list, h)
+	}
+
+	if err = rows.Err(); err != nil {
+		err = fmt.Errorf("Error querying most vulnerable Hosts: %w", classify(err))
+		db.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	return list, nil
+} // func (db *sqlDB) VulnGetHostReport(ctx context.Context, limit int) ([]data.VulnerableHost, error)
+
+// scanVuln reads a Vulnerability from the first columns of the current row,
+// the remaining columns are scanned into extra.
+func scanVuln(rows *sql.Rows, id *int64, v *data.Vulnerability, extra ...any) error {
+	var (
+		err            error
+		sev, published int64
+		dest           = []any{id, &v.Name, &v.Summary, &v.Score, &sev, &published, &v.Source}
+	)
+
+	if err = rows.Scan(append(dest, extra...)...); err != nil {
+		return fmt.Errorf("Error scanning row into Vulnerability: %w", err)
+	}
+
+	v.ID = krylib.ID(*id)
+	v.Severity = severity.Severity(sev)
+
+	if published != 0 {
+		v.Published = time.Unix(published, 0)
+	}
+
+	return nil
+} // func scanVuln(rows *sql.Rows, id *int64, v *data.Vulnerability, extra ...any) error
@@ -0,0 +1,115 @@
+// /home/krylon/go/src/github.com/blicero/guang/database/vulns_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 15:40:22 krylon>
+
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/rules/severity"
+)
+
+func TestVulnerabilities(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, b *testBackend) {
+		var (
+			err    error
+			cnt    int64
+			vulns  []data.Vulnerability
+			ports  []data.Port
+			port   *data.Port
+			found  []data.VulnMatch
+			report []data.VulnerableHost
+			ctx    = context.Background()
+			host   = b.hosts[2]
+			reply  = "SSH-2.0-OpenSSH_7.4"
+			res    = data.ScanResult{
+				Host:  host,
+				Port:  2022,
+				Reply: &reply,
+				Software: data.Software{
+					Service: "ssh",
+					Product: "OpenSSH",
+					Version: "7.4",
+					CPE:     "cpe:/a:openbsd:openssh:7.4",
+				},
+				Stamp: time.Now(),
+			}
+			v = data.Vulnerability{
+				Name:      "CVE-2018-15473",
+				Summary:   "OpenSSH through 7.7 is prone to a user enumeration vulnerability.",
+				Score:     5.3,
+				Severity:  severity.Medium,
+				Published: time.Date(2018, 8, 17, 19, 29, 0, 0, time.UTC),
+				Source:    "NVD",
+				Ranges: []data.AffectedRange{
+					{Vendor: "openbsd", Product: "openssh", EndIncl: "7.7"},
+					{Vendor: "openbsd", Product: "openssh", Version: "7.7p1"},
+				},
+			}
+		)
+
+		if err = b.db.PortAdd(ctx, &res); err != nil {
+			t.Fatalf("Error adding Port: %s", err.Error())
+		} else if err = b.db.VulnAdd(ctx, &v); err != nil {
+			t.Fatalf("Error adding Vulnerability: %s", err.Error())
+		} else if vulns, err = b.db.VulnGetByProduct(ctx, "openssh"); err != nil {
+			t.Fatalf("Error loading Vulnerabilities: %s", err.Error())
+		} else if len(vulns) != 1 || vulns[0].ID != v.ID || len(vulns[0].Ranges) != 2 {
+			t.Fatalf("Unexpected Vulnerabilities: %#v", vulns)
+		} else if !vulns[0].Published.Equal(v.Published) || vulns[0].Severity != severity.Medium {
+			t.Errorf("Unexpected Vulnerability: %#v", vulns[0])
+		}
+
+		// Importing a Vulnerability again replaces its ranges.
+		v.Ranges = v.Ranges[:1]
+		if err = b.db.VulnAdd(ctx, &v); err != nil {
+			t.Fatalf("Error adding Vulnerability again: %s", err.Error())
+		} else if vulns, err = b.db.VulnGetByProduct(ctx, "openssh"); err != nil {
+			t.Fatalf("Error loading Vulnerabilities: %s", err.Error())
+		} else if len(vulns) != 1 || len(vulns[0].Ranges) != 1 {
+			t.Errorf("Unexpected Vulnerabilities after update: %#v", vulns)
+		} else if cnt, err = b.db.VulnGetCount(ctx); err != nil {
+			t.Fatalf("Error counting Vulnerabilities: %s", err.Error())
+		} else if cnt != 1 {
+			t.Errorf("Expected 1 Vulnerability, got %d", cnt)
+		} else if ports, err = b.db.PortGetIdentified(ctx); err != nil {
+			t.Fatalf("Error loading identified Ports: %s", err.Error())
+		}
+
+		for i := range ports {
+			if ports[i].Port == 2022 && ports[i].HostID == host.ID {
+				port = &ports[i]
+			}
+		}
+
+		if port == nil {
+			t.Fatalf("Port 2022 is missing from identified Ports: %#v", ports)
+		} else if port.Software.CPE != res.Software.CPE {
+			t.Errorf("Unexpected Software: %#v", port.Software)
+		} else if err = b.db.PortVulnSet(ctx, port, vulns); err != nil {
+			t.Fatalf("Error adding Vulnerabilities to Port: %s", err.Error())
+		} else if err = b.db.PortVulnSet(ctx, port, vulns); err != nil {
+			t.Fatalf("Error adding Vulnerabilities to Port twice: %s", err.Error())
+		} else if found, err = b.db.VulnGetByHost(ctx, host.ID); err != nil {
+			t.Fatalf("Error loading Vulnerabilities of Host: %s", err.Error())
+		} else if len(found) != 1 || found[0].Vuln.Name != v.Name || found[0].Port != 2022 || found[0].Version != "7.4" {
+			t.Errorf("Unexpected Vulnerabilities of Host: %#v", found)
+		} else if report, err = b.db.VulnGetHostReport(ctx, 10); err != nil {
+			t.Fatalf("Error loading report: %s", err.Error())
+		} else if len(report) != 1 || report[0].HostID != host.ID || report[0].Vulns != 1 || report[0].MaxScore != 5.3 {
+			t.Errorf("Unexpected report: %#v", report)
+		} else if err = b.db.PortVulnSet(ctx, port, nil); err != nil {
+			t.Fatalf("Error clearing Vulnerabilities of Port: %s", err.Error())
+		} else if found, err = b.db.VulnGetByHost(ctx, host.ID); err != nil {
+			t.Fatalf("Error loading Vulnerabilities of Host: %s", err.Error())
+		} else if len(found) != 0 {
+			t.Errorf("Vulnerabilities were not cleared: %#v", found)
+		}
+	})
+} // func TestVulnerabilities(t *testing.T)
@@ -0,0 +1,221 @@
+// /home/krylon/go/src/github.com/blicero/guang/events/bus.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 17:03:19 krylon>
+
+// Package events provides a simple in-process event bus. The backend
+// publishes what it is doing - new hosts, scan results, zone transfers -
+// and whoever is interested, e.g. a browser looking at the web frontend,
+// subscribes.
+package events
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/blicero/guang/data"
+)
+
+// DefaultBufferSize is the number of Events a Subscription buffers before
+// it starts dropping them.
+const DefaultBufferSize = 256
+
+// Event is something that happened in the backend.
+// Depending on the Kind, Host, Result or XFR are set.
+type Event struct {
+	ID     uint64
+	Kind   Kind
+	Stamp  time.Time
+	Host   *data.Host
+	Result *data.ScanResult
+	XFR    *data.XFR
+}
+
+// Address returns the address of the Host the Event is about, or nil if
+// it is not about a Host.
+func (ev *Event) Address() net.IP {
+	switch {
+	case ev.Result != nil:
+		return ev.Result.Host.Address
+	case ev.Host != nil:
+		return ev.Host.Address
+	default:
+		return nil
+	}
+} // func (ev *Event) Address() net.IP
+
+// Filter decides which Events a Subscription receives.
+// Fields left at their zero value do not restrict anything.
+type Filter struct {
+	// Kinds are the kinds of Events to receive.
+	Kinds []Kind
+	// Ports restricts ScanResults to the given ports.
+	Ports []uint16
+	// Networks restricts Events about Hosts or ScanResults to addresses
+	// within the given networks. XFR Events are not affected.
+	Networks []*net.IPNet
+	// RepliesOnly restricts ScanResults to those where the port replied.
+	RepliesOnly bool
+}
+
+// Match returns true if the Event passes the Filter.
+func (f *Filter) Match(ev *Event) bool {
+	if len(f.Kinds) > 0 {
+		var found bool
+		for _, k := range f.Kinds {
+			if k == ev.Kind {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if ev.Kind == ScanResult && ev.Result != nil {
+		if f.RepliesOnly && ev.Result.Reply == nil {
+			return false
+		} else if len(f.Ports) > 0 {
+			var found bool
+			for _, p := range f.Ports {
+				if p == ev.Result.Port {
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				return false
+			}
+		}
+	}
+
+	if addr := ev.Address(); addr != nil && len(f.Networks) > 0 {
+		for _, n := range f.Networks {
+			if n.Contains(addr) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return true
+} // func (f *Filter) Match(ev *Event) bool
+
+// Bus delivers Events to all Subscriptions whose Filter they pass.
+// Publishing never blocks: if a subscriber does not keep up and its buffer
+// is full, it misses the Event.
+// A nil *Bus is valid and discards everything published on it, its
+// Subscriptions are closed right away.
+type Bus struct {
+	lock sync.RWMutex
+	subs map[*Subscription]bool
+	seq  atomic.Uint64
+}
+
+// NewBus creates a new Bus.
+func NewBus() *Bus {
+	return &Bus{subs: make(map[*Subscription]bool)}
+} // func NewBus() *Bus
+
+// Publish sends the Event to all interested subscribers. It fills in
+// the Event's ID and, if it is not set yet, its timestamp.
+func (b *Bus) Publish(ev Event) {
+	if b == nil {
+		return
+	}
+
+	ev.ID = b.seq.Add(1)
+	if ev.Stamp.IsZero() {
+		ev.Stamp = time.Now()
+	}
+
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	for s := range b.subs {
+		if !s.filter.Match(&ev) {
+			continue
+		}
+
+		select {
+		case s.c <- ev:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+} // func (b *Bus) Publish(ev Event)
+
+// Subscribe returns a new Subscription that receives the Events that pass
+// the Filter. size is the number of Events it buffers.
+func (b *Bus) Subscribe(f Filter, size int) *Subscription {
+	var s = &Subscription{
+		bus:    b,
+		filter: f,
+		c:      make(chan Event, size),
+	}
+
+	if b == nil {
+		s.Close()
+		return s
+	}
+
+	b.lock.Lock()
+	b.subs[s] = true
+	b.lock.Unlock()
+
+	return s
+} // func (b *Bus) Subscribe(f Filter, size int) *Subscription
+
+// Subscribers returns the number of active Subscriptions.
+func (b *Bus) Subscribers() int {
+	if b == nil {
+		return 0
+	}
+
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	return len(b.subs)
+} // func (b *Bus) Subscribers() int
+
+// Subscription receives Events from a Bus.
+type Subscription struct {
+	bus     *Bus
+	filter  Filter
+	c       chan Event
+	dropped atomic.Uint64
+	once    sync.Once
+}
+
+// Events returns the channel the Subscription's Events arrive on.
+// It is closed when the Subscription is closed.
+func (s *Subscription) Events() <-chan Event {
+	return s.c
+} // func (s *Subscription) Events() <-chan Event
+
+// Dropped returns the number of Events the Subscription missed because
+// its buffer was full.
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+} // func (s *Subscription) Dropped() uint64
+
+// Close ends the Subscription. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		if s.bus == nil {
+			close(s.c)
+			return
+		}
+
+		s.bus.lock.Lock()
+		delete(s.bus.subs, s)
+		close(s.c)
+		s.bus.lock.Unlock()
+	})
+} // func (s *Subscription) Close()
@@ -0,0 +1,121 @@
+// /home/krylon/go/src/github.com/blicero/guang/events/bus_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 17:03:19 krylon>
+
+package events
+
+import (
+	"net"
+	"testing"
+
+	"github.com/blicero/guang/data"
+)
+
+func result(addr string, port uint16, reply string) Event {
+	var ev = Event{
+		Kind: ScanResult,
+		Result: &data.ScanResult{
+			Host: data.Host{Address: net.ParseIP(addr)},
+			Port: port,
+		},
+	}
+
+	if reply != "" {
+		ev.Result.Reply = &reply
+	}
+
+	return ev
+} // func result(addr string, port uint16, reply string) Event
+
+func TestFilter(t *testing.T) {
+	var _, lan, _ = net.ParseCIDR("192.168.0.0/16")
+
+	type testCase struct {
+		f     Filter
+		ev    Event
+		match bool
+	}
+
+	var cases = []testCase{
+		{Filter{}, result("10.0.0.1", 22, ""), true},
+		{Filter{Kinds: []Kind{HostAdded}}, result("10.0.0.1", 22, ""), false},
+		{Filter{Kinds: []Kind{HostAdded, ScanResult}}, result("10.0.0.1", 22, ""), true},
+		{Filter{Ports: []uint16{80, 443}}, result("10.0.0.1", 22, "SSH-2.0"), false},
+		{Filter{Ports: []uint16{80, 443}}, result("10.0.0.1", 443, ""), true},
+		{Filter{RepliesOnly: true}, result("10.0.0.1", 443, ""), false},
+		{Filter{RepliesOnly: true}, result("10.0.0.1", 443, "HTTP/1.1"), true},
+		{Filter{Networks: []*net.IPNet{lan}}, result("10.0.0.1", 22, ""), false},
+		{Filter{Networks: []*net.IPNet{lan}}, result("192.168.1.1", 22, ""), true},
+		{
+			Filter{Networks: []*net.IPNet{lan}},
+			Event{Kind: HostAdded, Host: &data.Host{Address: net.ParseIP("192.168.7.7")}},
+			true,
+		},
+		// Ports and networks do not apply to zone transfers.
+		{
+			Filter{Ports: []uint16{22}, Networks: []*net.IPNet{lan}},
+			Event{Kind: XfrStatus, XFR: &data.XFR{Zone: "example.com"}},
+			true,
+		},
+	}
+
+	for i, c := range cases {
+		if m := c.f.Match(&c.ev); m != c.match {
+			t.Errorf("Test case #%d: expected %t, got %t", i, c.match, m)
+		}
+	}
+} // func TestFilter(t *testing.T)
+
+func TestBus(t *testing.T) {
+	var (
+		bus   = NewBus()
+		all   = bus.Subscribe(Filter{}, 2)
+		hosts = bus.Subscribe(Filter{Kinds: []Kind{HostAdded}}, 2)
+		nilb  *Bus
+	)
+
+	nilb.Publish(result("10.0.0.1", 22, "")) // must not panic
+
+	var nilSub = nilb.Subscribe(Filter{}, 1)
+
+	if _, ok := <-nilSub.Events(); ok {
+		t.Error("Subscription to a nil Bus is not closed")
+	}
+
+	nilSub.Close() // must not panic either
+
+	bus.Publish(result("10.0.0.1", 22, ""))
+	bus.Publish(Event{Kind: HostAdded, Host: &data.Host{Name: "www.example.com"}})
+	bus.Publish(result("10.0.0.2", 22, ""))
+
+	if n := bus.Subscribers(); n != 2 {
+		t.Errorf("Expected 2 subscribers, not %d", n)
+	} else if d := all.Dropped(); d != 1 {
+		t.Errorf("Expected 1 dropped event, not %d", d)
+	} else if d = hosts.Dropped(); d != 0 {
+		t.Errorf("Expected no dropped events, not %d", d)
+	}
+
+	var ev = <-all.Events()
+	if ev.ID != 1 || ev.Kind != ScanResult || ev.Stamp.IsZero() {
+		t.Errorf("Unexpected first event: %#v", ev)
+	} else if ev = <-all.Events(); ev.ID != 2 || ev.Kind != HostAdded {
+		t.Errorf("Unexpected second event: %#v", ev)
+	} else if ev = <-hosts.Events(); ev.ID != 2 || ev.Host.Name != "www.example.com" {
+		t.Errorf("Unexpected host event: %#v", ev)
+	}
+
+	all.Close()
+	all.Close()
+
+	if _, ok := <-all.Events(); ok {
+		t.Error("Channel of closed Subscription is still open")
+	} else if n := bus.Subscribers(); n != 1 {
+		t.Errorf("Expected 1 subscriber, not %d", n)
+	}
+
+	bus.Publish(result("10.0.0.3", 22, ""))
+	hosts.Close()
+} // func TestBus(t *testing.T)
@@ -0,0 +1,46 @@
+// /home/krylon/go/src/github.com/blicero/guang/events/kind.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 14:39:07 krylon>
+
+package events
+
+import (
+	"fmt"
+	"strings"
+)
+
+//go:generate stringer -type=Kind
+
+// Kind identifies what an Event is about.
+type Kind uint8
+
+// HostAdded means a new Host was added to the database.
+// ScanResult means the Scanner probed a port.
+// XfrStatus means a zone transfer was started or finished.
+const (
+	HostAdded Kind = iota
+	ScanResult
+	XfrStatus
+)
+
+// AllKinds returns all Kinds of Events.
+func AllKinds() []Kind {
+	return []Kind{
+		HostAdded,
+		ScanResult,
+		XfrStatus,
+	}
+} // func AllKinds() []Kind
+
+// ParseKind returns the Kind with the given name, ignoring case.
+func ParseKind(name string) (Kind, error) {
+	for _, k := range AllKinds() {
+		if strings.EqualFold(name, k.String()) {
+			return k, nil
+		}
+	}
+
+	return 0, fmt.Errorf("Unknown event kind %q", name)
+} // func ParseKind(name string) (Kind, error)
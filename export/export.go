@@ -0,0 +1,204 @@
+// /home/krylon/go/src/github.com/blicero/guang/export/export.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 16:22:36 krylon>
+
+// Package export writes the Hosts and Ports we found to files other tools
+// can process: CSV with one row per Port, JSON Lines with one object per
+// Host, and the XML format of nmap.
+//
+// The Hosts are read from the database and written one at a time, so an
+// export of the whole database does not have to fit into memory.
+package export
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/database"
+	"github.com/blicero/guang/search"
+)
+
+// Format is the file format of an export.
+type Format string
+
+// CSV writes one row per Port, or one row for a Host without Ports.
+// JSONL writes one JSON object per line and Host, with the Ports nested.
+// NmapXML writes the XML format of nmap's -oX option.
+const (
+	CSV     Format = "csv"
+	JSONL   Format = "jsonl"
+	NmapXML Format = "xml"
+)
+
+// Formats lists all supported formats.
+var Formats = []Format{CSV, JSONL, NmapXML}
+
+// ErrFormat is returned for an unsupported format.
+var ErrFormat = errors.New("Unsupported export format")
+
+// ParseFormat returns the Format called s. It accepts json and nmap as
+// aliases.
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "csv":
+		return CSV, nil
+	case "jsonl", "json":
+		return JSONL, nil
+	case "xml", "nmap":
+		return NmapXML, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrFormat, s)
+	}
+} // func ParseFormat(s string) (Format, error)
+
+// ContentType returns the MIME type of the format.
+func (f Format) ContentType() string {
+	switch f {
+	case CSV:
+		return "text/csv; charset=utf-8"
+	case JSONL:
+		return "application/x-ndjson"
+	case NmapXML:
+		return "application/xml"
+	default:
+		return "application/octet-stream"
+	}
+} // func (f Format) ContentType() string
+
+// Extension returns the usual file name extension of the format.
+func (f Format) Extension() string {
+	switch f {
+	case JSONL:
+		return ".jsonl"
+	case NmapXML:
+		return ".xml"
+	default:
+		return "." + string(f)
+	}
+} // func (f Format) Extension() string
+
+// Writer writes Hosts in one of the supported formats. Begin is called
+// before the first Host, End after the last one.
+type Writer interface {
+	Begin() error
+	Host(h *data.HostWithPorts) error
+	End() error
+}
+
+// NewWriter returns a Writer for the given format that writes to w.
+func NewWriter(f Format, w io.Writer) (Writer, error) {
+	switch f {
+	case CSV:
+		return newCSVWriter(w), nil
+	case JSONL:
+		return newJSONWriter(w), nil
+	case NmapXML:
+		return newXMLWriter(w), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrFormat, f)
+	}
+} // func NewWriter(f Format, w io.Writer) (Writer, error)
+
+// Run exports the Hosts matching the filter from db to w in the given
+// format. It returns the number of Hosts it exported.
+func Run(ctx context.Context, db database.HostDB, filter database.ExportFilter, f Format, w io.Writer) (int, error) {
+	var (
+		err error
+		ew  Writer
+		cnt int
+		buf = bufio.NewWriter(w)
+	)
+
+	if ew, err = NewWriter(f, buf); err != nil {
+		return 0, err
+	} else if err = ew.Begin(); err != nil {
+		return 0, err
+	}
+
+	err = db.Export(ctx, filter, func(h *data.HostWithPorts) error {
+		if err := ew.Host(h); err != nil {
+			return err
+		}
+
+		cnt++
+		return nil
+	})
+
+	if err != nil {
+		return cnt, err
+	} else if err = ew.End(); err != nil {
+		return cnt, err
+	} else if err = buf.Flush(); err != nil {
+		return cnt, fmt.Errorf("Cannot write export: %w", err)
+	}
+
+	return cnt, nil
+} // func Run(ctx context.Context, db database.HostDB, filter database.ExportFilter, f Format, w io.Writer) (int, error)
+
+// timeFormats are the formats ParseTime accepts.
+var timeFormats = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// ParseTime parses the bounds of the time range of an export, either in
+// RFC 3339 format or as a date with an optional time of day, which is
+// taken to be in the local time zone. An empty string is the zero time,
+// which does not restrict the export.
+func ParseTime(s string) (time.Time, error) {
+	if s = strings.TrimSpace(s); s == "" {
+		return time.Time{}, nil
+	}
+
+	for _, layout := range timeFormats {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("Invalid time %q, use YYYY-MM-DD [HH:MM[:SS]] or RFC 3339", s)
+} // func ParseTime(s string) (time.Time, error)
+
+// ReadTargets reads a set of targets, one network in CIDR notation or
+// address per line. Empty lines and lines starting with # are skipped.
+func ReadTargets(r io.Reader) ([]*net.IPNet, error) {
+	var (
+		lineNo int
+		nets   = make([]*net.IPNet, 0)
+		sc     = bufio.NewScanner(r)
+	)
+
+	for sc.Scan() {
+		var (
+			n    *net.IPNet
+			line = strings.TrimSpace(sc.Text())
+		)
+
+		lineNo++
+
+		if line == "" || line[0] == '#' {
+			continue
+		} else if n = search.ParseNet(line); n == nil {
+			return nil, fmt.Errorf("Line %d: Invalid network or address %q", lineNo, line)
+		}
+
+		nets = append(nets, n)
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("Cannot read targets: %w", err)
+	}
+
+	return nets, nil
+} // func ReadTargets(r io.Reader) ([]*net.IPNet, error)
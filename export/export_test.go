@@ -0,0 +1,244 @@
+// /home/krylon/go/src/github.com/blicero/guang/export/export_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 16:22:36 krylon>
+
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/database"
+	"github.com/blicero/guang/nmap"
+)
+
+// testDB returns a database with two Hosts, one with two Ports, one
+// without any.
+func testDB(t *testing.T) database.HostDB {
+	var (
+		err   error
+		db    database.HostDB
+		ctx   = context.Background()
+		reply = "SSH-2.0-OpenSSH_8.4p1 Debian-5"
+		stamp = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+		hosts = []data.Host{
+			{Address: net.ParseIP("192.0.2.10"), Name: "ssh.example.org", Source: data.HostSourceUser},
+			{Address: net.ParseIP("2001:db8::10"), Name: "quiet.example.org", Source: data.HostSourceUser},
+		}
+	)
+
+	if db, err = database.OpenDB(filepath.Join(t.TempDir(), "export.db")); err != nil {
+		t.Fatalf("Cannot open database: %s", err.Error())
+	}
+
+	t.Cleanup(db.Close)
+
+	for i := range hosts {
+		if _, err = db.HostUpsert(ctx, &hosts[i]); err != nil {
+			t.Fatalf("Cannot add Host %s: %s", hosts[i].Address, err.Error())
+		}
+	}
+
+	var results = []data.ScanResult{
+		{
+			Host:  hosts[0],
+			Port:  22,
+			Reply: &reply,
+			Stamp: stamp,
+			Software: data.Software{
+				Service: "ssh",
+				Product: "OpenSSH",
+				Version: "8.4p1",
+				OS:      "Debian",
+				CPE:     "cpe:/a:openbsd:openssh:8.4p1",
+			},
+		},
+		{Host: hosts[0], Port: 23, Stamp: stamp},
+	}
+
+	for i := range results {
+		if err = db.PortAdd(ctx, &results[i]); err != nil {
+			t.Fatalf("Cannot add Port %d: %s", results[i].Port, err.Error())
+		}
+	}
+
+	return db
+} // func testDB(t *testing.T) database.HostDB
+
+func TestCSV(t *testing.T) {
+	var (
+		err  error
+		cnt  int
+		recs [][]string
+		buf  bytes.Buffer
+		db   = testDB(t)
+	)
+
+	if cnt, err = Run(context.Background(), db, database.ExportFilter{}, CSV, &buf); err != nil {
+		t.Fatalf("Error exporting: %s", err.Error())
+	} else if cnt != 2 {
+		t.Errorf("Exported %d Hosts, expected 2", cnt)
+	} else if recs, err = csv.NewReader(&buf).ReadAll(); err != nil {
+		t.Fatalf("Cannot read CSV: %s", err.Error())
+	} else if len(recs) != 3 {
+		t.Fatalf("Expected a header and 2 rows, got %#v", recs)
+	}
+
+	// Only open Ports are exported unless the filter asks for others, so
+	// port 23 is missing.
+	if recs[1][0] != "192.0.2.10" || recs[1][8] != "22" || recs[1][9] != "open" || recs[1][12] != "OpenSSH" {
+		t.Errorf("Unexpected row: %#v", recs[1])
+	} else if recs[2][0] != "2001:db8::10" || recs[2][8] != "" {
+		t.Errorf("Unexpected row for Host without Ports: %#v", recs[2])
+	}
+} // func TestCSV(t *testing.T)
+
+func TestJSONL(t *testing.T) {
+	var (
+		err   error
+		buf   bytes.Buffer
+		db    = testDB(t)
+		lines []string
+		rec   jsonHost
+	)
+
+	if _, err = Run(context.Background(), db, database.ExportFilter{}, JSONL, &buf); err != nil {
+		t.Fatalf("Error exporting: %s", err.Error())
+	}
+
+	lines = strings.Split(strings.TrimSpace(buf.String()), "\n")
+
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d:\n%s", len(lines), buf.String())
+	} else if err = json.Unmarshal([]byte(lines[0]), &rec); err != nil {
+		t.Fatalf("Cannot parse %s: %s", lines[0], err.Error())
+	} else if rec.Address != "192.0.2.10" || len(rec.Ports) != 1 {
+		t.Errorf("Unexpected Host: %s", lines[0])
+	} else if p := rec.Ports[0]; p.Port != 22 || p.Reply == nil || p.CPE == "" {
+		t.Errorf("Unexpected Port: %#v", p)
+	}
+} // func TestJSONL(t *testing.T)
+
+func TestNmapXML(t *testing.T) {
+	var (
+		err   error
+		buf   bytes.Buffer
+		db    = testDB(t)
+		hosts []nmap.Host
+		stats nmap.RunStats
+		run   bool
+	)
+
+	if _, err = Run(context.Background(), db, database.ExportFilter{}, NmapXML, &buf); err != nil {
+		t.Fatalf("Error exporting: %s", err.Error())
+	}
+
+	var dec = xml.NewDecoder(&buf)
+
+	for {
+		var tok xml.Token
+
+		if tok, err = dec.Token(); err != nil {
+			break
+		}
+
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch se.Name.Local {
+		case "nmaprun":
+			run = true
+		case "host":
+			var h nmap.Host
+			if err = dec.DecodeElement(&h, &se); err != nil {
+				t.Fatalf("Cannot decode host: %s", err.Error())
+			}
+			hosts = append(hosts, h)
+		case "runstats":
+			if err = dec.DecodeElement(&stats, &se); err != nil {
+				t.Fatalf("Cannot decode runstats: %s", err.Error())
+			}
+		}
+	}
+
+	if !run {
+		t.Fatalf("nmaprun element is missing:\n%s", buf.String())
+	} else if len(hosts) != 2 || stats.Hosts.Total != 2 {
+		t.Fatalf("Expected 2 hosts, got %d (total %d)", len(hosts), stats.Hosts.Total)
+	}
+
+	var h = hosts[0]
+
+	if h.Addresses[0].Addr != "192.0.2.10" || h.Addresses[0].AddrType != "ipv4" {
+		t.Errorf("Unexpected address: %#v", h.Addresses)
+	} else if len(h.Ports) != 1 || h.Ports[0].PortID != 22 || h.Ports[0].State.State != nmap.StateOpen {
+		t.Errorf("Unexpected ports: %#v", h.Ports)
+	} else if s := h.Ports[0].Service; s == nil || s.Product != "OpenSSH" || len(s.CPE) != 1 {
+		t.Errorf("Unexpected service: %#v", s)
+	} else if len(h.Ports[0].Scripts) != 1 || h.Ports[0].Scripts[0].ID != nmap.ScriptBanner {
+		t.Errorf("Banner is missing: %#v", h.Ports[0].Scripts)
+	} else if hosts[1].Addresses[0].AddrType != "ipv6" {
+		t.Errorf("Unexpected address: %#v", hosts[1].Addresses)
+	}
+} // func TestNmapXML(t *testing.T)
+
+func TestParseTime(t *testing.T) {
+	type testCase struct {
+		s     string
+		zero  bool
+		valid bool
+	}
+
+	var cases = []testCase{
+		{"", true, true},
+		{"2026-10-19", false, true},
+		{"2026-10-19 16:30", false, true},
+		{"2026-10-19 16:30:05", false, true},
+		{"2026-10-19T16:30:05+02:00", false, true},
+		{"yesterday", false, false},
+	}
+
+	for _, c := range cases {
+		var stamp, err = ParseTime(c.s)
+
+		if c.valid != (err == nil) {
+			t.Errorf("ParseTime(%q): unexpected error %v", c.s, err)
+		} else if c.valid && stamp.IsZero() != c.zero {
+			t.Errorf("ParseTime(%q) = %s", c.s, stamp)
+		}
+	}
+} // func TestParseTime(t *testing.T)
+
+func TestReadTargets(t *testing.T) {
+	const targets = `# Our networks
+192.0.2.0/24
+
+198.51.100.7
+2001:db8::/32
+`
+
+	var nets, err = ReadTargets(strings.NewReader(targets))
+
+	if err != nil {
+		t.Fatalf("Cannot read targets: %s", err.Error())
+	} else if len(nets) != 3 {
+		t.Fatalf("Expected 3 networks, got %v", nets)
+	} else if nets[1].String() != "198.51.100.7/32" {
+		t.Errorf("Unexpected network: %s", nets[1])
+	} else if _, err = ReadTargets(strings.NewReader("192.0.2.0/33\n")); err == nil {
+		t.Error("Invalid network was accepted")
+	}
+} // func TestReadTargets(t *testing.T)
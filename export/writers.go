@@ -0,0 +1,354 @@
+// /home/krylon/go/src/github.com/blicero/guang/export/writers.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 16:22:36 krylon>
+
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blicero/guang/common"
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/fingerprint"
+	"github.com/blicero/guang/nmap"
+)
+
+// portState returns the state of a Port in nmap's terms: a Port that
+// replied is open, one that did not is closed.
+func portState(p *data.Port) string {
+	if p.Reply == nil {
+		return nmap.StateClosed
+	}
+
+	return nmap.StateOpen
+} // func portState(p *data.Port) string
+
+// asn returns the AS number of a Host as a string, or an empty string if
+// it is unknown.
+func asn(h *data.Host) string {
+	if h.AS.ASN == 0 {
+		return ""
+	}
+
+	return strconv.FormatUint(uint64(h.AS.ASN), 10)
+} // func asn(h *data.Host) string
+
+// csvHeader names the columns of a CSV export.
+var csvHeader = []string{
+	"address",
+	"name",
+	"os",
+	"os_version",
+	"country",
+	"city",
+	"asn",
+	"as_org",
+	"port",
+	"state",
+	"scanned",
+	"service",
+	"product",
+	"version",
+	"info",
+	"cpe",
+	"reply",
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w)}
+} // func newCSVWriter(w io.Writer) *csvWriter
+
+func (c *csvWriter) Begin() error {
+	return c.w.Write(csvHeader)
+} // func (c *csvWriter) Begin() error
+
+func (c *csvWriter) Host(h *data.HostWithPorts) error {
+	var host = []string{
+		h.Host.Address.String(),
+		h.Host.Name,
+		h.Host.OS,
+		h.Host.OSVersion,
+		h.Host.Geo.Country,
+		h.Host.Geo.City,
+		asn(&h.Host),
+		h.Host.AS.Org,
+	}
+
+	if len(h.Ports) == 0 {
+		return c.w.Write(append(host, make([]string, len(csvHeader)-len(host))...))
+	}
+
+	for i := range h.Ports {
+		var p = &h.Ports[i]
+
+		if err := c.w.Write(append(host[:len(host):len(host)],
+			strconv.Itoa(int(p.Port)),
+			portState(p),
+			p.Timestamp.Format(time.RFC3339),
+			p.Software.Service,
+			p.Software.Product,
+			p.Software.Version,
+			p.Software.Info,
+			p.Software.CPE,
+			p.ReplyString())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+} // func (c *csvWriter) Host(h *data.HostWithPorts) error
+
+func (c *csvWriter) End() error {
+	c.w.Flush()
+	return c.w.Error()
+} // func (c *csvWriter) End() error
+
+// jsonHost is a Host as it appears in a JSON Lines export.
+type jsonHost struct {
+	Address      string     `json:"address"`
+	Name         string     `json:"name"`
+	OS           string     `json:"os,omitempty"`
+	OSVersion    string     `json:"os_version,omitempty"`
+	OSConfidence float64    `json:"os_confidence,omitempty"`
+	Country      string     `json:"country,omitempty"`
+	City         string     `json:"city,omitempty"`
+	Latitude     *float64   `json:"latitude,omitempty"`
+	Longitude    *float64   `json:"longitude,omitempty"`
+	ASN          uint32     `json:"asn,omitempty"`
+	ASOrg        string     `json:"as_org,omitempty"`
+	Prefix       string     `json:"prefix,omitempty"`
+	Source       string     `json:"source"`
+	Added        time.Time  `json:"added"`
+	Ports        []jsonPort `json:"ports"`
+}
+
+// jsonPort is a Port as it appears in a JSON Lines export.
+type jsonPort struct {
+	Port    uint16    `json:"port"`
+	State   string    `json:"state"`
+	Scanned time.Time `json:"scanned"`
+	Reply   *string   `json:"reply,omitempty"`
+	Service string    `json:"service,omitempty"`
+	Product string    `json:"product,omitempty"`
+	Version string    `json:"version,omitempty"`
+	Info    string    `json:"info,omitempty"`
+	OS      string    `json:"os,omitempty"`
+	CPE     string    `json:"cpe,omitempty"`
+}
+
+type jsonWriter struct {
+	enc *json.Encoder
+}
+
+func newJSONWriter(w io.Writer) *jsonWriter {
+	var enc = json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &jsonWriter{enc: enc}
+} // func newJSONWriter(w io.Writer) *jsonWriter
+
+func (j *jsonWriter) Begin() error { return nil }
+
+func (j *jsonWriter) Host(h *data.HostWithPorts) error {
+	var rec = jsonHost{
+		Address:      h.Host.Address.String(),
+		Name:         h.Host.Name,
+		OS:           h.Host.OS,
+		OSVersion:    h.Host.OSVersion,
+		OSConfidence: h.Host.OSConfidence,
+		Country:      h.Host.Geo.Country,
+		City:         h.Host.Geo.City,
+		ASN:          h.Host.AS.ASN,
+		ASOrg:        h.Host.AS.Org,
+		Prefix:       h.Host.AS.Prefix,
+		Source:       strings.TrimPrefix(h.Host.Source.String(), "HostSource"),
+		Added:        h.Host.Added,
+		Ports:        make([]jsonPort, len(h.Ports)),
+	}
+
+	if h.Host.Geo.HasCoordinates() {
+		var lat, lon = h.Host.Geo.Latitude, h.Host.Geo.Longitude
+		rec.Latitude = &lat
+		rec.Longitude = &lon
+	}
+
+	for i, p := range h.Ports {
+		rec.Ports[i] = jsonPort{
+			Port:    p.Port,
+			State:   portState(&p),
+			Scanned: p.Timestamp,
+			Reply:   p.Reply,
+			Service: p.Software.Service,
+			Product: p.Software.Product,
+			Version: p.Software.Version,
+			Info:    p.Software.Info,
+			OS:      p.Software.OS,
+			CPE:     p.Software.CPE,
+		}
+	}
+
+	return j.enc.Encode(&rec)
+} // func (j *jsonWriter) Host(h *data.HostWithPorts) error
+
+func (j *jsonWriter) End() error { return nil }
+
+// xmlWriter writes the format of nmap -oX, so the export can be processed
+// by tools that understand nmap's output.
+type xmlWriter struct {
+	w     io.Writer
+	enc   *xml.Encoder
+	run   nmap.Run
+	start time.Time
+	cnt   int
+}
+
+func newXMLWriter(w io.Writer) *xmlWriter {
+	var enc = xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return &xmlWriter{w: w, enc: enc}
+} // func newXMLWriter(w io.Writer) *xmlWriter
+
+func (x *xmlWriter) Begin() error {
+	x.start = time.Now()
+	x.run = nmap.Run{
+		Scanner:          common.AppName,
+		Args:             "export",
+		Start:            x.start.Unix(),
+		StartStr:         x.start.Format(time.ANSIC),
+		Version:          common.Version,
+		XMLOutputVersion: nmap.XMLOutputVersion,
+	}
+
+	if _, err := io.WriteString(x.w, xml.Header+"<!DOCTYPE nmaprun>\n"); err != nil {
+		return err
+	}
+
+	return x.enc.EncodeToken(x.run.StartElement())
+} // func (x *xmlWriter) Begin() error
+
+func (x *xmlWriter) Host(h *data.HostWithPorts) error {
+	var (
+		host = nmap.Host{
+			Status: nmap.Status{State: "up", Reason: "user-set"},
+			Ports:  make([]nmap.Port, len(h.Ports)),
+		}
+		addrType = "ipv6"
+	)
+
+	if h.Host.Address.To4() != nil {
+		addrType = "ipv4"
+	}
+
+	host.Addresses = []nmap.Address{
+		{Addr: h.Host.Address.String(), AddrType: addrType},
+	}
+
+	if h.Host.Name != "" {
+		host.Hostnames = []nmap.Hostname{{Name: h.Host.Name, Type: "user"}}
+	}
+
+	for i := range h.Ports {
+		var (
+			p    = &h.Ports[i]
+			port = nmap.Port{
+				Protocol: "tcp",
+				PortID:   p.Port,
+				State:    nmap.State{State: portState(p), Reason: "syn-ack"},
+			}
+			stamp = p.Timestamp.Unix()
+		)
+
+		if p.Reply == nil {
+			port.State.Reason = "conn-refused"
+		} else {
+			port.Scripts = []nmap.Script{{ID: nmap.ScriptBanner, Output: *p.Reply}}
+		}
+
+		if !p.Software.IsZero() {
+			port.Service = &nmap.Service{
+				Name:      p.Software.Service,
+				Product:   p.Software.Product,
+				Version:   p.Software.Version,
+				ExtraInfo: p.Software.Info,
+				OSType:    p.Software.OS,
+				Method:    "probed",
+				Conf:      10,
+			}
+
+			if port.Service.Name == "" {
+				port.Service.Name = "unknown"
+			}
+
+			if p.Software.CPE != "" {
+				port.Service.CPE = []string{p.Software.CPE}
+			}
+		}
+
+		if host.StartTime == 0 || stamp < host.StartTime {
+			host.StartTime = stamp
+		}
+
+		if stamp > host.EndTime {
+			host.EndTime = stamp
+		}
+
+		host.Ports[i] = port
+	}
+
+	if h.Host.OS != "" && h.Host.OS != fingerprint.Unknown {
+		var name = h.Host.OS
+
+		if h.Host.OSVersion != "" {
+			name += " " + h.Host.OSVersion
+		}
+
+		host.OS = &nmap.OS{
+			Matches: []nmap.OSMatch{
+				{Name: name, Accuracy: int(math.Round(h.Host.OSConfidence * 100))},
+			},
+		}
+	}
+
+	x.cnt++
+	return x.enc.Encode(&host)
+} // func (x *xmlWriter) Host(h *data.HostWithPorts) error
+
+func (x *xmlWriter) End() error {
+	var (
+		err   error
+		now   = time.Now()
+		stats = nmap.RunStats{
+			Finished: nmap.Finished{
+				Time:    now.Unix(),
+				TimeStr: now.Format(time.ANSIC),
+				Elapsed: math.Round(now.Sub(x.start).Seconds()*100) / 100,
+				Exit:    "success",
+			},
+			Hosts: nmap.HostStats{Up: x.cnt, Total: x.cnt},
+		}
+	)
+
+	if err = x.enc.Encode(&stats); err != nil {
+		return err
+	} else if err = x.enc.EncodeToken(x.run.StartElement().End()); err != nil {
+		return err
+	} else if err = x.enc.Flush(); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(x.w)
+	return err
+} // func (x *xmlWriter) End() error
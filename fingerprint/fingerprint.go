@@ -0,0 +1,216 @@
+// /home/krylon/go/src/github.com/blicero/guang/fingerprint/fingerprint.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 15:48:35 krylon>
+
+// Package fingerprint guesses the operating system of a Host from the
+// replies of its Ports.
+//
+// Each signature that matches a Port's reply is a piece of evidence with a
+// weight between 0 and 1, depending on how reliable the signature is. The
+// suffix OpenSSH distributions put in the SSH version string, for example,
+// is much better evidence than the word Linux appearing somewhere in a
+// banner. A Port counts at most once per OS, with its heaviest evidence.
+//
+// The evidence for an OS is combined as if the pieces were independent,
+// i.e. 1 - (1 - w1) * (1 - w2) * ..., and evidence for a family, such as
+// Linux, also counts for its members, such as Debian. The OS with the
+// highest score wins, and its confidence is lowered by the evidence for
+// other, conflicting systems.
+package fingerprint
+
+import (
+	"math"
+	"sort"
+
+	"github.com/blicero/guang/data"
+)
+
+// Unknown is the OS of Hosts we found no evidence for.
+const Unknown = "Unknown"
+
+// maxMatch is the length at which the matched text is cut off in the
+// evidence.
+const maxMatch = 80
+
+// Explain returns the evidence the given Ports provide, at most one piece
+// per Port and OS.
+func Explain(ports []data.Port) []data.OSEvidence {
+	var list = make([]data.OSEvidence, 0)
+
+	for i := range ports {
+		list = append(list, explainPort(&ports[i])...)
+	}
+
+	return list
+} // func Explain(ports []data.Port) []data.OSEvidence
+
+// explainPort applies all signatures to one Port.
+func explainPort(p *data.Port) []data.OSEvidence {
+	var found = make(map[string]data.OSEvidence)
+
+	for i := range signatures {
+		var (
+			sig  = &signatures[i]
+			text string
+		)
+
+		if sig.source == SourceBanner {
+			text = p.Software.OS
+		} else if p.Reply != nil {
+			text = *p.Reply
+		}
+
+		if text == "" {
+			continue
+		}
+
+		var m = sig.pattern.FindStringSubmatch(text)
+
+		if m == nil {
+			continue
+		}
+
+		var ev = data.OSEvidence{
+			Port:   p.Port,
+			Source: sig.source,
+			OS:     sig.os,
+			Weight: sig.weight,
+			Match:  m[0],
+		}
+
+		if sig.version != nil {
+			ev.Version = sig.version(m)
+		}
+
+		if len(ev.Match) > maxMatch {
+			ev.Match = ev.Match[:maxMatch] + "..."
+		}
+
+		if prev, ok := found[ev.OS]; !ok || ev.Weight > prev.Weight {
+			if ev.Version == "" {
+				ev.Version = prev.Version
+			}
+			found[ev.OS] = ev
+		} else if prev.Version == "" && ev.Version != "" {
+			prev.Version = ev.Version
+			found[ev.OS] = prev
+		}
+	}
+
+	var list = make([]data.OSEvidence, 0, len(found))
+
+	for name, ev := range found {
+		// Evidence for a family is implied by the evidence for a member
+		// found on the same Port.
+		var implied bool
+
+		for other := range found {
+			if families[other] == name {
+				implied = true
+				break
+			}
+		}
+
+		if !implied {
+			list = append(list, ev)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Weight != list[j].Weight {
+			return list[i].Weight > list[j].Weight
+		}
+		return list[i].OS < list[j].OS
+	})
+
+	return list
+} // func explainPort(p *data.Port) []data.OSEvidence
+
+// related returns true if a and b are the same OS, or one is the family of
+// the other.
+func related(a, b string) bool {
+	return a == b || families[a] == b || families[b] == a
+} // func related(a, b string) bool
+
+// Guess determines the operating system of a Host from its Ports.
+func Guess(ports []data.Port) data.OSGuess {
+	var (
+		guess = data.OSGuess{
+			OS:       Unknown,
+			Evidence: Explain(ports),
+		}
+		miss  = make(map[string]float64)
+		score = make(map[string]float64)
+	)
+
+	if len(guess.Evidence) == 0 {
+		return guess
+	}
+
+	// miss is the probability that all evidence for an OS is wrong.
+	for _, ev := range guess.Evidence {
+		if _, ok := miss[ev.OS]; !ok {
+			miss[ev.OS] = 1
+		}
+		miss[ev.OS] *= 1 - ev.Weight
+	}
+
+	for name, m := range miss {
+		if family, ok := families[name]; ok {
+			if fm, ok := miss[family]; ok {
+				m *= fm
+			}
+		}
+		score[name] = 1 - m
+	}
+
+	var best float64
+
+	for name, s := range score {
+		if s > best || (s == best && name < guess.OS) {
+			guess.OS = name
+			best = s
+		}
+	}
+
+	// Evidence for other systems lowers the confidence, evidence for the
+	// winner's family or members does not.
+	var conflict float64
+
+	for name, m := range miss {
+		if !related(name, guess.OS) {
+			conflict += 1 - m
+		}
+	}
+
+	guess.Confidence = math.Round(best*best/(best+conflict)*100) / 100
+	guess.Version = version(guess.OS, guess.Evidence)
+
+	return guess
+} // func Guess(ports []data.Port) data.OSGuess
+
+// version picks the version of the OS that has the most weight behind it.
+func version(osName string, evidence []data.OSEvidence) string {
+	var (
+		weights = make(map[string]float64)
+		ver     string
+		best    float64
+	)
+
+	for _, ev := range evidence {
+		if ev.OS == osName && ev.Version != "" {
+			weights[ev.Version] += ev.Weight
+		}
+	}
+
+	for v, w := range weights {
+		if w > best || (w == best && v < ver) {
+			ver = v
+			best = w
+		}
+	}
+
+	return ver
+} // func version(osName string, evidence []data.OSEvidence) string
@@ -0,0 +1,167 @@
+// /home/krylon/go/src/github.com/blicero/guang/fingerprint/fingerprint_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 15:48:35 krylon>
+
+package fingerprint
+
+import (
+	"testing"
+
+	"github.com/blicero/guang/data"
+)
+
+func port(num uint16, reply, osName string) data.Port {
+	var p = data.Port{
+		Port:     num,
+		Software: data.Software{OS: osName},
+	}
+
+	if reply != "" {
+		p.Reply = &reply
+	}
+
+	return p
+} // func port(num uint16, reply, osName string) data.Port
+
+func TestGuess(t *testing.T) {
+	type testCase struct {
+		name    string
+		ports   []data.Port
+		os      string
+		version string
+		minConf float64
+		maxConf float64
+	}
+
+	var cases = []testCase{
+		{
+			name:    "no evidence",
+			ports:   []data.Port{port(25, "220 mail.example.com ESMTP", "")},
+			os:      Unknown,
+			maxConf: 0,
+		},
+		{
+			name: "Debian with release from the SSH suffix",
+			ports: []data.Port{
+				port(22, "SSH-2.0-OpenSSH_7.9p1 Debian-10+deb10u2", "Linux"),
+				port(80, "Apache/2.4.38 (Debian)", ""),
+			},
+			os:      "Debian",
+			version: "10",
+			minConf: 0.95,
+			maxConf: 1,
+		},
+		{
+			name: "FreeBSD banner mentioning Linux",
+			ports: []data.Port{
+				port(21, "220 ftp.example.org FTP server (Version 6.00LS) ready. <FreeBSD>", ""),
+				port(22, "SSH-2.0-OpenSSH_7.5 FreeBSD-20170903", "FreeBSD"),
+				port(80, "nginx/1.14.0 serving Linux ISOs", ""),
+			},
+			os:      "FreeBSD",
+			minConf: 0.6,
+			maxConf: 0.9,
+		},
+		{
+			name: "Windows release from the IIS version",
+			ports: []data.Port{
+				port(80, "Microsoft-IIS/7.0", ""),
+				port(21, "220 Microsoft FTP Service", "Windows"),
+			},
+			os:      "Windows",
+			version: "Server 2008",
+			minConf: 0.9,
+			maxConf: 1,
+		},
+		{
+			name:    "Red Hat release from the package suffix",
+			ports:   []data.Port{port(22, "SSH-2.0-OpenSSH_7.4 openssh-7.4p1-21.el7_9.x86_64", "")},
+			os:      "Red Hat",
+			version: "7",
+			minConf: 0.5,
+			maxConf: 0.5,
+		},
+		{
+			name:    "Yocto",
+			ports:   []data.Port{port(80, "lighttpd/1.4.45 (Yocto Project Reference Distro)", "")},
+			os:      "Yocto",
+			minConf: 0.5,
+			maxConf: 0.5,
+		},
+		{
+			name:    "Generic Linux",
+			ports:   []data.Port{port(22, "SSH-2.0-dropbear_2019.78", "Linux")},
+			os:      "Linux",
+			minConf: 0.7,
+			maxConf: 0.7,
+		},
+		{
+			name: "Conflicting evidence",
+			ports: []data.Port{
+				port(22, "SSH-2.0-OpenSSH_8.2p1 Ubuntu-4ubuntu0.5", "Linux"),
+				port(80, "Apache/2.4.6 (CentOS)", ""),
+			},
+			os:      "Ubuntu",
+			minConf: 0.4,
+			maxConf: 0.6,
+		},
+	}
+
+	for _, c := range cases {
+		var g = Guess(c.ports)
+
+		if g.OS != c.os || g.Version != c.version {
+			t.Errorf("%s: Expected %s %q, got %s %q (evidence %#v)",
+				c.name,
+				c.os,
+				c.version,
+				g.OS,
+				g.Version,
+				g.Evidence)
+		} else if g.Confidence < c.minConf || g.Confidence > c.maxConf {
+			t.Errorf("%s: Confidence %.2f is not between %.2f and %.2f",
+				c.name,
+				g.Confidence,
+				c.minConf,
+				c.maxConf)
+		}
+	}
+} // func TestGuess(t *testing.T)
+
+func TestExplain(t *testing.T) {
+	var (
+		reply = "SSH-2.0-OpenSSH_7.9p1 Debian-10+deb10u2"
+		ev    = Explain([]data.Port{port(22, reply, "Linux")})
+	)
+
+	// The SSH suffix, the deb10u2 and the word Debian all point to Debian,
+	// the Port counts once, with the heaviest evidence. Linux is implied.
+	if len(ev) != 1 {
+		t.Fatalf("Expected 1 piece of evidence, got %#v", ev)
+	} else if ev[0].OS != "Debian" || ev[0].Source != SourceSSH || ev[0].Weight != 0.9 || ev[0].Version != "10" {
+		t.Errorf("Unexpected evidence: %#v", ev[0])
+	} else if ev[0].Port != 22 || ev[0].Match == "" {
+		t.Errorf("Port or match missing from evidence: %#v", ev[0])
+	}
+} // func TestExplain(t *testing.T)
+
+func TestExplainPorts(t *testing.T) {
+	var ports = []data.Port{
+		port(22, "SSH-2.0-OpenSSH_7.4p1 Debian-10+deb9u7", ""),
+		port(25, "", ""),
+		port(80, "Apache/2.4.29 (Ubuntu)", ""),
+		port(79, "No one logged on", ""),
+	}
+
+	var ev = Explain(ports)
+
+	if len(ev) != 2 {
+		t.Fatalf("Expected 2 pieces of evidence, got %d: %#v", len(ev), ev)
+	} else if ev[0].Port != 22 || ev[0].OS != "Debian" || ev[0].Version != "9" {
+		t.Errorf("Unexpected evidence for port 22: %#v", ev[0])
+	} else if ev[1].Port != 80 || ev[1].OS != "Ubuntu" || ev[1].Source != SourceHTTP {
+		t.Errorf("Unexpected evidence for port 80: %#v", ev[1])
+	}
+} // func TestExplainPorts(t *testing.T)
@@ -0,0 +1,412 @@
+// /home/krylon/go/src/github.com/blicero/guang/fingerprint/signatures.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 15:48:35 krylon>
+
+package fingerprint
+
+import "regexp"
+
+// These are the sources of evidence, i.e. which part of a Port a
+// signature looks at.
+const (
+	// SourceSSH is the SSH version string, which often has a suffix
+	// naming the distribution, e.g. SSH-2.0-OpenSSH_7.9p1 Debian-10+deb10u2
+	SourceSSH = "ssh"
+	// SourceHTTP is the Server header, which often names the OS in
+	// parentheses, e.g. Apache/2.4.6 (CentOS)
+	SourceHTTP = "http"
+	// SourceBanner is the OS the banner parser recognized.
+	SourceBanner = "banner"
+	// SourceReply is any mention of an OS in the reply.
+	SourceReply = "reply"
+)
+
+type signature struct {
+	os      string
+	source  string
+	weight  float64
+	pattern *regexp.Regexp
+	version func(m []string) string
+}
+
+// families maps operating systems to the family they belong to. Evidence
+// for the family counts for its members, too.
+var families = map[string]string{
+	"Ubuntu":   "Linux",
+	"Debian":   "Linux",
+	"Raspbian": "Linux",
+	"CentOS":   "Linux",
+	"Red Hat":  "Linux",
+	"Fedora":   "Linux",
+	"Yocto":    "Linux",
+}
+
+// group returns a function that extracts the nth group of a match as the
+// version.
+func group(n int) func([]string) string {
+	return func(m []string) string {
+		return m[n]
+	}
+} // func group(n int) func([]string) string
+
+// iisVersions maps the versions of IIS to the Windows releases they ship
+// with.
+var iisVersions = map[string]string{
+	"5.0":  "2000",
+	"5.1":  "XP",
+	"6.0":  "Server 2003",
+	"7.0":  "Server 2008",
+	"7.5":  "Server 2008 R2",
+	"8.0":  "Server 2012",
+	"8.5":  "Server 2012 R2",
+	"10.0": "Server 2016 or later",
+}
+
+func iisVersion(m []string) string {
+	return iisVersions[m[1]]
+} // func iisVersion(m []string) string
+
+var signatures = []signature{
+	// The SSH version string
+	{
+		os:      "Debian",
+		source:  SourceSSH,
+		weight:  0.9,
+		pattern: regexp.MustCompile(`^SSH-[\d.]+-OpenSSH_\S+ Debian-\S*?deb(\d+)u`),
+		version: group(1),
+	},
+	{
+		os:      "Debian",
+		source:  SourceSSH,
+		weight:  0.9,
+		pattern: regexp.MustCompile(`^SSH-[\d.]+-OpenSSH_\S+ Debian-`),
+	},
+	{
+		os:      "Raspbian",
+		source:  SourceSSH,
+		weight:  0.9,
+		pattern: regexp.MustCompile(`^SSH-[\d.]+-OpenSSH_\S+ Raspbian-\S*?deb(\d+)u`),
+		version: group(1),
+	},
+	{
+		os:      "Raspbian",
+		source:  SourceSSH,
+		weight:  0.9,
+		pattern: regexp.MustCompile(`^SSH-[\d.]+-OpenSSH_\S+ Raspbian-`),
+	},
+	{
+		os:      "Ubuntu",
+		source:  SourceSSH,
+		weight:  0.9,
+		pattern: regexp.MustCompile(`^SSH-[\d.]+-OpenSSH_\S+ Ubuntu-`),
+	},
+	{
+		os:      "FreeBSD",
+		source:  SourceSSH,
+		weight:  0.9,
+		pattern: regexp.MustCompile(`^SSH-[\d.]+-OpenSSH_\S+ FreeBSD-`),
+	},
+	{
+		os:      "NetBSD",
+		source:  SourceSSH,
+		weight:  0.9,
+		pattern: regexp.MustCompile(`^SSH-[\d.]+-OpenSSH_\S+ NetBSD_Secure_Shell`),
+	},
+	{
+		os:      "Windows",
+		source:  SourceSSH,
+		weight:  0.9,
+		pattern: regexp.MustCompile(`^SSH-[\d.]+-OpenSSH_for_Windows`),
+	},
+	{
+		os:      "RouterOS",
+		source:  SourceSSH,
+		weight:  0.9,
+		pattern: regexp.MustCompile(`^SSH-[\d.]+-ROSSSH`),
+	},
+	{
+		os:      "Cisco IOS",
+		source:  SourceSSH,
+		weight:  0.8,
+		pattern: regexp.MustCompile(`^SSH-[\d.]+-Cisco-`),
+	},
+
+	// The Server header
+	{
+		os:      "Windows",
+		source:  SourceHTTP,
+		weight:  0.85,
+		pattern: regexp.MustCompile(`^Microsoft-IIS/(\d+\.\d+)`),
+		version: iisVersion,
+	},
+	{
+		os:      "Windows",
+		source:  SourceHTTP,
+		weight:  0.7,
+		pattern: regexp.MustCompile(`^Microsoft-HTTPAPI/`),
+	},
+	{
+		os:      "Windows",
+		source:  SourceHTTP,
+		weight:  0.8,
+		pattern: regexp.MustCompile(`^\S+/\S+.*\(Win(?:32|64)\)`),
+	},
+	{
+		os:      "Debian",
+		source:  SourceHTTP,
+		weight:  0.8,
+		pattern: regexp.MustCompile(`^\S+/\S+.*\(Debian\)`),
+	},
+	{
+		os:      "Raspbian",
+		source:  SourceHTTP,
+		weight:  0.8,
+		pattern: regexp.MustCompile(`^\S+/\S+.*\(Raspbian\)`),
+	},
+	{
+		os:      "Ubuntu",
+		source:  SourceHTTP,
+		weight:  0.8,
+		pattern: regexp.MustCompile(`^\S+/\S+.*\(Ubuntu\)`),
+	},
+	{
+		os:      "CentOS",
+		source:  SourceHTTP,
+		weight:  0.8,
+		pattern: regexp.MustCompile(`^\S+/\S+.*\(CentOS\)`),
+	},
+	{
+		os:      "Red Hat",
+		source:  SourceHTTP,
+		weight:  0.8,
+		pattern: regexp.MustCompile(`^\S+/\S+.*\(Red Hat(?: Enterprise Linux)?\)`),
+	},
+	{
+		os:      "Fedora",
+		source:  SourceHTTP,
+		weight:  0.8,
+		pattern: regexp.MustCompile(`^\S+/\S+.*\(Fedora\)`),
+	},
+	{
+		os:      "FreeBSD",
+		source:  SourceHTTP,
+		weight:  0.8,
+		pattern: regexp.MustCompile(`^\S+/\S+.*\(FreeBSD\)`),
+	},
+
+	// The OS the banner parser found
+	{
+		os:      "Windows",
+		source:  SourceBanner,
+		weight:  0.7,
+		pattern: regexp.MustCompile(`(?i)Windows`),
+	},
+	{
+		os:      "Linux",
+		source:  SourceBanner,
+		weight:  0.7,
+		pattern: regexp.MustCompile(`(?i)Linux`),
+	},
+	{
+		os:      "FreeBSD",
+		source:  SourceBanner,
+		weight:  0.7,
+		pattern: regexp.MustCompile(`(?i)FreeBSD`),
+	},
+	{
+		os:      "NetBSD",
+		source:  SourceBanner,
+		weight:  0.7,
+		pattern: regexp.MustCompile(`(?i)NetBSD`),
+	},
+	{
+		os:      "OpenBSD",
+		source:  SourceBanner,
+		weight:  0.7,
+		pattern: regexp.MustCompile(`(?i)OpenBSD`),
+	},
+	{
+		os:      "RouterOS",
+		source:  SourceBanner,
+		weight:  0.7,
+		pattern: regexp.MustCompile(`(?i)RouterOS`),
+	},
+	{
+		os:      "JUNOS",
+		source:  SourceBanner,
+		weight:  0.7,
+		pattern: regexp.MustCompile(`(?i)JUNOS`),
+	},
+	{
+		os:      "Cisco IOS",
+		source:  SourceBanner,
+		weight:  0.7,
+		pattern: regexp.MustCompile(`(?i)Cisco IOS`),
+	},
+	{
+		os:      "SonicOS",
+		source:  SourceBanner,
+		weight:  0.7,
+		pattern: regexp.MustCompile(`(?i)SonicOS`),
+	},
+
+	// Mentions anywhere in the reply
+	{
+		os:      "Windows",
+		source:  SourceReply,
+		weight:  0.5,
+		pattern: regexp.MustCompile(`\bWindows(?: (Server \d{4}(?: R2)?|XP|Vista|NT|7|8\.1|8|10|11)\b)?`),
+		version: group(1),
+	},
+	{
+		os:      "Windows",
+		source:  SourceReply,
+		weight:  0.4,
+		pattern: regexp.MustCompile(`Microsoft`),
+	},
+	{
+		os:      "Ubuntu",
+		source:  SourceReply,
+		weight:  0.5,
+		pattern: regexp.MustCompile(`(?i)\bUbuntu[ /](\d{2}\.\d{2})\b`),
+		version: group(1),
+	},
+	{
+		os:      "Ubuntu",
+		source:  SourceReply,
+		weight:  0.5,
+		pattern: regexp.MustCompile(`(?i)ubuntu`),
+	},
+	{
+		os:      "Debian",
+		source:  SourceReply,
+		weight:  0.5,
+		pattern: regexp.MustCompile(`(?i)[.+~]deb(\d+)u\d`),
+		version: group(1),
+	},
+	{
+		os:      "Debian",
+		source:  SourceReply,
+		weight:  0.5,
+		pattern: regexp.MustCompile(`(?i)Debian`),
+	},
+	{
+		os:      "Debian",
+		source:  SourceReply,
+		weight:  0.4,
+		pattern: regexp.MustCompile(`(?i)[.]deb`),
+	},
+	{
+		os:      "Raspbian",
+		source:  SourceReply,
+		weight:  0.5,
+		pattern: regexp.MustCompile(`(?i)Raspbian`),
+	},
+	{
+		os:      "CentOS",
+		source:  SourceReply,
+		weight:  0.5,
+		pattern: regexp.MustCompile(`(?i)CentOS(?: Linux)?(?: release)? ?(\d+)?`),
+		version: group(1),
+	},
+	{
+		os:      "Red Hat",
+		source:  SourceReply,
+		weight:  0.5,
+		pattern: regexp.MustCompile(`(?i)Red ?Hat(?: Enterprise Linux)?(?: Server)?(?: release)? ?(\d+)?`),
+		version: group(1),
+	},
+	{
+		os:      "Red Hat",
+		source:  SourceReply,
+		weight:  0.5,
+		pattern: regexp.MustCompile(`(?i)rhel(\d+)`),
+		version: group(1),
+	},
+	{
+		os:      "Red Hat",
+		source:  SourceReply,
+		weight:  0.5,
+		pattern: regexp.MustCompile(`(?i)[.]el(\d+)[._]`),
+		version: group(1),
+	},
+	{
+		os:      "Fedora",
+		source:  SourceReply,
+		weight:  0.5,
+		pattern: regexp.MustCompile(`(?i)fedora(?: release)? ?(\d+)?`),
+		version: group(1),
+	},
+	{
+		os:      "Fedora",
+		source:  SourceReply,
+		weight:  0.5,
+		pattern: regexp.MustCompile(`[.]fc(\d+)[.]`),
+		version: group(1),
+	},
+	{
+		os:      "Yocto",
+		source:  SourceReply,
+		weight:  0.5,
+		pattern: regexp.MustCompile(`(?i)yocto`),
+	},
+	{
+		os:      "FreeBSD",
+		source:  SourceReply,
+		weight:  0.5,
+		pattern: regexp.MustCompile(`(?i)FreeBSD(?:[ /]?(\d+\.\d+))?`),
+		version: group(1),
+	},
+	{
+		os:      "NetBSD",
+		source:  SourceReply,
+		weight:  0.5,
+		pattern: regexp.MustCompile(`(?i)NetBSD(?:[ /]?(\d+\.\d+))?`),
+		version: group(1),
+	},
+	{
+		os:      "OpenBSD",
+		source:  SourceReply,
+		weight:  0.5,
+		pattern: regexp.MustCompile(`(?i)OpenBSD(?:[ /]?(\d+\.\d+))?`),
+		version: group(1),
+	},
+	{
+		os:      "DragonflyBSD",
+		source:  SourceReply,
+		weight:  0.5,
+		pattern: regexp.MustCompile(`(?i)Dragonfly`),
+	},
+	{
+		os:      "RouterOS",
+		source:  SourceReply,
+		weight:  0.5,
+		pattern: regexp.MustCompile(`(?i)RouterOS|MikroTik`),
+	},
+	{
+		os:      "Linux",
+		source:  SourceReply,
+		weight:  0.3,
+		pattern: regexp.MustCompile(`(?i)\bLinux\b`),
+	},
+	{
+		os:      "JUNOS",
+		source:  SourceReply,
+		weight:  0.5,
+		pattern: regexp.MustCompile(`(?i:JUNOS|Juniper)`),
+	},
+	{
+		os:      "Cisco IOS",
+		source:  SourceReply,
+		weight:  0.5,
+		pattern: regexp.MustCompile(`(?i)Cisco IOS Software|Cisco Systems`),
+	},
+	{
+		os:      "SonicOS",
+		source:  SourceReply,
+		weight:  0.5,
+		pattern: regexp.MustCompile(`(?i)SonicOS|SonicWALL`),
+	},
+}
@@ -2,7 +2,7 @@
 // -*- mode: go; coding: utf-8; -*-
 // Created on 03. 11. 2022 by Benjamin Walkenhorst
 // (c) 2022 Benjamin Walkenhorst
-// Time-stamp: <2023-04-06 01:44:21 krylon>
+// Time-stamp: <2026-10-19 17:40:32 krylon>
 
 package frontend
 
@@ -12,10 +12,12 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/blicero/guang/backend"
 	"github.com/blicero/guang/backend/facility"
 	"github.com/blicero/guang/common"
 	"github.com/blicero/guang/data"
 	"github.com/blicero/guang/database"
+	"github.com/blicero/guang/search"
 	"github.com/gorilla/mux"
 	"github.com/pquerna/ffjson/ffjson"
 )
@@ -45,7 +47,7 @@ func (srv *WebFrontend) handlePortsRecent(w http.ResponseWriter, r *http.Request
 		err     error
 		tstr    string
 		tstamp  int64
-		db      *database.HostDB
+		db      database.HostDB
 		dbRes   []data.ScanResult
 		refTime time.Time
 		outbuf  []byte
@@ -79,7 +81,7 @@ func (srv *WebFrontend) handlePortsRecent(w http.ResponseWriter, r *http.Request
 	// srv.updateCkPortstamp(time.Now())
 	refTime = time.Unix(tstamp, 0)
 
-	if dbRes, err = db.PortGetRecent(refTime); err != nil {
+	if dbRes, err = db.PortGetRecent(r.Context(), refTime); err != nil {
 		srv.log.Printf("[ERROR] Failed to load recently scanned ports from database: %s\n",
 			err.Error())
 		res.Message = err.Error()
@@ -156,16 +158,20 @@ func (srv *WebFrontend) handleWorkerSpawn(w http.ResponseWriter, r *http.Request
 
 	fac = facility.Facility(facID)
 
-	if cnt < 1 {
+	// cnt is checked on its own first, so the sum cannot overflow.
+	if cnt < 1 || cnt > backend.MaxWorkers || int(cnt)+srv.nexus.WorkerCount(fac) > backend.MaxWorkers {
 		res.Message = fmt.Sprintf("Invalid number of %s workers to spawn: %d",
 			fac,
 			cnt)
 		srv.log.Printf("[ERROR] %s\n", res.Message)
 		goto RESPOND
+	} else if err = srv.nexus.SpawnWorker(fac, int(cnt)); err != nil {
+		res.Message = fmt.Sprintf("Cannot spawn %s workers: %s",
+			fac,
+			err.Error())
+		goto RESPOND
 	}
 
-	srv.nexus.SpawnWorker(fac, int(cnt))
-
 	res.NewCnt = srv.nexus.WorkerCount(fac)
 	res.Status = true
 
@@ -244,7 +250,12 @@ func (srv *WebFrontend) handleWorkerStop(w http.ResponseWriter, r *http.Request)
 		cnt,
 		fac)
 
-	srv.nexus.StopWorker(fac, int(cnt))
+	if err = srv.nexus.StopWorker(fac, int(cnt)); err != nil {
+		res.Message = fmt.Sprintf("Cannot stop %s workers: %s",
+			fac,
+			err.Error())
+		goto RESPOND
+	}
 
 	res.NewCnt = srv.nexus.WorkerCount(fac)
 	res.Status = true
@@ -329,3 +340,67 @@ func (srv *WebFrontend) handleUpdateMetadata(w http.ResponseWriter, r *http.Requ
 	w.WriteHeader(200)
 	w.Write(outbuf) // nolint: errcheck
 } // func (srv *WebFrontend) handleUpdateMetadata(w http.ResponseWriter, r *http.Request)
+
+func (srv *WebFrontend) handleAPISearch(w http.ResponseWriter, r *http.Request) {
+	var (
+		err           error
+		db            database.HostDB
+		q             *search.Query
+		result        *search.Result
+		outbuf        []byte
+		status        = 200
+		page, perPage = pageParams(r)
+		res           = ajaxSearchResult{
+			ajaxData: ajaxData{
+				Timestamp: time.Now(),
+			},
+			Query: r.FormValue("q"),
+		}
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	if q, err = search.Parse(res.Query); err != nil {
+		res.Message = fmt.Sprintf("Invalid query %q: %s",
+			res.Query,
+			err.Error())
+		status = 400
+		goto RESPOND
+	}
+
+	db = srv.dbPool.Get()
+	defer srv.dbPool.Put(db)
+
+	if result, err = db.Search(r.Context(), q, page, perPage); err != nil {
+		res.Message = fmt.Sprintf("Error searching for %q: %s",
+			res.Query,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n", res.Message)
+		status = 500
+		goto RESPOND
+	}
+
+	res.Total = result.Total
+	res.Page = result.Page
+	res.PerPage = result.PerPage
+	res.Hits = result.Hits
+	res.Status = true
+
+RESPOND:
+	if outbuf, err = ffjson.Marshal(&res); err != nil {
+		res.Message = fmt.Sprintf("Error serializing Response to %s: %s",
+			r.RemoteAddr,
+			err.Error())
+		srv.log.Printf("[ERROR] %s\n", res.Message)
+	} else {
+		defer ffjson.Pool(outbuf)
+	}
+
+	w.Header().Set("Content-Length", strconv.FormatInt(int64(len(outbuf)), 10))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", cacheControl)
+	w.WriteHeader(status)
+	w.Write(outbuf) // nolint: errcheck
+} // func (srv *WebFrontend) handleAPISearch(w http.ResponseWriter, r *http.Request)
@@ -2,7 +2,7 @@
 // -*- mode: go; coding: utf-8; -*-
 // Created on 03. 11. 2022 by Benjamin Walkenhorst
 // (c) 2022 Benjamin Walkenhorst
-// Time-stamp: <2022-11-14 19:33:27 krylon>
+// Time-stamp: <2026-10-19 14:07:53 krylon>
 
 package frontend
 
@@ -37,3 +37,12 @@ type ajaxWorkerCnt struct {
 	XFR       int
 	Scanner   int
 }
+
+type ajaxSearchResult struct {
+	ajaxData
+	Query   string
+	Total   int64
+	Page    int
+	PerPage int
+	Hits    []data.HostWithPorts
+}
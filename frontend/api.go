@@ -0,0 +1,783 @@
+// /home/krylon/go/src/github.com/blicero/guang/frontend/api.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 16:52:31 krylon>
+
+package frontend
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blicero/guang/backend"
+	"github.com/blicero/guang/backend/facility"
+	"github.com/blicero/guang/blacklist"
+	"github.com/blicero/guang/common"
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/database"
+	"github.com/blicero/guang/logdomain"
+	"github.com/blicero/guang/search"
+	"github.com/blicero/guang/xfr/xfrstatus"
+	"github.com/blicero/krylib"
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/logutils"
+	"github.com/pquerna/ffjson/ffjson"
+)
+
+// This file is the home of the versioned REST API, see html/openapi.yaml.
+
+// hostFilters are the query parameters of /api/v1/hosts that are
+// translated to qualified terms of a search query.
+var hostFilters = []string{"port", "banner", "os", "name", "location", "country", "net", "asn", "org", "tag"}
+
+func (srv *WebFrontend) registerAPI() {
+	var api = srv.router.PathPrefix("/api/v1").Subrouter()
+
+	api.HandleFunc("/openapi.yaml", srv.handleAPISpec).Methods("GET")
+	api.HandleFunc("/hosts", srv.handleAPIHosts).Methods("GET")
+	api.HandleFunc("/hosts/{id:[0-9]+}", srv.handleAPIHost).Methods("GET")
+	api.HandleFunc("/hosts/{id:[0-9]+}/tags", srv.handleAPIHostTags).Methods("GET")
+	api.HandleFunc("/hosts/{id:[0-9]+}/tags/{tag}", srv.operator(srv.handleAPIHostTagSet)).Methods("PUT", "DELETE")
+	api.HandleFunc("/hosts/{id:[0-9]+}/notes", srv.handleAPIHostNotes).Methods("GET")
+	api.HandleFunc("/hosts/{id:[0-9]+}/notes", srv.operator(srv.handleAPIHostNoteAdd)).Methods("POST")
+	api.HandleFunc("/hosts/{id:[0-9]+}/notes/{note:[0-9]+}", srv.operator(srv.handleAPIHostNoteDelete)).Methods("DELETE")
+	api.HandleFunc("/hosts/{id:[0-9]+}/vulns", srv.handleAPIHostVulns).Methods("GET")
+	api.HandleFunc("/tags", srv.handleAPITags).Methods("GET")
+	api.HandleFunc("/ports", srv.handleAPIPorts).Methods("GET")
+	api.HandleFunc("/software", srv.handleAPISoftware).Methods("GET")
+	api.HandleFunc("/networks", srv.handleAPINetworks).Methods("GET")
+	api.HandleFunc("/locations", srv.handleAPILocations).Methods("GET")
+	api.HandleFunc("/export", srv.handleAPIExport).Methods("GET")
+	api.HandleFunc("/import", srv.operator(srv.handleAPIImport)).Methods("POST")
+	api.HandleFunc("/vulns/hosts", srv.handleAPIVulnHosts).Methods("GET")
+	api.HandleFunc("/xfrs", srv.handleAPIXfrs).Methods("GET")
+	api.HandleFunc("/blacklist", srv.handleAPIBlacklist).Methods("GET")
+	api.HandleFunc("/workers", srv.handleAPIWorkers).Methods("GET")
+	api.HandleFunc("/workers/{facility}", srv.operator(srv.handleAPIWorkersSet)).Methods("PUT")
+	api.HandleFunc("/loglevels", srv.handleAPILogLevels).Methods("GET")
+	api.HandleFunc("/loglevels/{domain}", srv.operator(srv.handleAPILogLevelSet)).Methods("PUT")
+} // func (srv *WebFrontend) registerAPI()
+
+// apiRespond sends v to the client as JSON.
+func (srv *WebFrontend) apiRespond(w http.ResponseWriter, r *http.Request, status int, v any) {
+	var (
+		err    error
+		outbuf []byte
+	)
+
+	if outbuf, err = ffjson.Marshal(v); err != nil {
+		srv.log.Printf("[ERROR] Error serializing Response to %s: %s\n",
+			r.RemoteAddr,
+			err.Error())
+		status = http.StatusInternalServerError
+		outbuf = []byte(`{"error":"Cannot serialize response"}`)
+	} else {
+		defer ffjson.Pool(outbuf)
+	}
+
+	w.Header().Set("Content-Length", strconv.FormatInt(int64(len(outbuf)), 10))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", cacheControl)
+	w.WriteHeader(status)
+	w.Write(outbuf) // nolint: errcheck
+} // func (srv *WebFrontend) apiRespond(w http.ResponseWriter, r *http.Request, status int, v any)
+
+// apiError sends an error message to the client. Server errors are logged.
+func (srv *WebFrontend) apiError(w http.ResponseWriter, r *http.Request, status int, format string, args ...any) {
+	var msg = fmt.Sprintf(format, args...)
+
+	if status >= 500 {
+		srv.log.Printf("[ERROR] %s\n", msg)
+	} else if common.Debug {
+		srv.log.Printf("[DEBUG] Bad request for %s: %s\n",
+			r.RequestURI,
+			msg)
+	}
+
+	srv.apiRespond(w, r, status, &apiError{Error: msg})
+} // func (srv *WebFrontend) apiError(w http.ResponseWriter, r *http.Request, status int, format string, args ...any)
+
+func (srv *WebFrontend) handleAPISpec(w http.ResponseWriter, r *http.Request) {
+	var (
+		err  error
+		spec []byte
+	)
+
+	if spec, err = assets.ReadFile("html/openapi.yaml"); err != nil {
+		srv.apiError(w, r, http.StatusInternalServerError,
+			"Cannot read API specification: %s",
+			err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+	w.WriteHeader(200)
+	w.Write(spec) // nolint: errcheck
+} // func (srv *WebFrontend) handleAPISpec(w http.ResponseWriter, r *http.Request)
+
+// apiSource returns the name of a HostSource as used in the API.
+func apiSource(s data.HostSource) string {
+	return strings.TrimPrefix(s.String(), "HostSource")
+} // func apiSource(s data.HostSource) string
+
+func apiHostFrom(h *data.Host) apiHost {
+	return apiHost{
+		ID:           int64(h.ID),
+		Address:      h.Address.String(),
+		Name:         h.Name,
+		Source:       apiSource(h.Source),
+		Added:        h.Added,
+		OS:           h.OS,
+		OSVersion:    h.OSVersion,
+		OSConfidence: h.OSConfidence,
+		Location:     h.Location,
+		Geo:          apiGeoFrom(&h.Geo),
+		AS:           apiASFrom(&h.AS),
+		OpenPorts:    []uint16{},
+	}
+} // func apiHostFrom(h *data.Host) apiHost
+
+// apiGeoFrom returns the structured location of a Host, nil if it is
+// unknown.
+func apiGeoFrom(geo *data.GeoInfo) *apiGeo {
+	if geo.Country == "" && !geo.HasCoordinates() {
+		return nil
+	}
+
+	var g = &apiGeo{
+		Country:     geo.Country,
+		Subdivision: geo.Subdivision,
+		City:        geo.City,
+	}
+
+	if geo.HasCoordinates() {
+		var lat, lon = geo.Latitude, geo.Longitude
+		g.Latitude = &lat
+		g.Longitude = &lon
+		g.AccuracyRadius = geo.Accuracy
+	}
+
+	return g
+} // func apiGeoFrom(geo *data.GeoInfo) *apiGeo
+
+// apiASFrom returns the autonomous system of a Host, nil if it is unknown.
+func apiASFrom(as *data.ASInfo) *apiAS {
+	if as.ASN == 0 && as.Registry == "" {
+		return nil
+	}
+
+	var a = &apiAS{
+		ASN:      as.ASN,
+		Org:      as.Org,
+		Prefix:   as.Prefix,
+		Registry: as.Registry,
+	}
+
+	if !as.Allocated.IsZero() {
+		var t = as.Allocated
+		a.Allocated = &t
+	}
+
+	return a
+} // func apiASFrom(as *data.ASInfo) *apiAS
+
+func apiPortFrom(p *data.Port, addr string) apiPort {
+	var ap = apiPort{
+		ID:        int64(p.ID),
+		HostID:    int64(p.HostID),
+		Address:   addr,
+		Port:      p.Port,
+		Timestamp: p.Timestamp,
+		Reply:     p.Reply,
+	}
+
+	if !p.Software.IsZero() {
+		ap.Software = &apiSoftware{
+			Service: p.Software.Service,
+			Product: p.Software.Product,
+			Version: p.Software.Version,
+			Info:    p.Software.Info,
+			OS:      p.Software.OS,
+			CPE:     p.Software.CPE,
+		}
+	}
+
+	return ap
+} // func apiPortFrom(p *data.Port, addr string) apiPort
+
+func apiXfrFrom(x *data.XFR) apiXfr {
+	var ax = apiXfr{
+		ID:     int64(x.ID),
+		Zone:   x.Zone,
+		Start:  x.Start,
+		Status: x.Status.String(),
+	}
+
+	if x.IsFinished() {
+		var end = x.End
+		ax.End = &end
+	}
+
+	return ax
+} // func apiXfrFrom(x *data.XFR) apiXfr
+
+// qualify turns a key and a value into a qualified search term.
+func qualify(key, value string) string {
+	var r = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return fmt.Sprintf(`%s:"%s"`, key, r.Replace(value))
+} // func qualify(key, value string) string
+
+func (srv *WebFrontend) handleAPIHosts(w http.ResponseWriter, r *http.Request) {
+	var (
+		err           error
+		db            database.HostDB
+		q             *search.Query
+		res           *search.Result
+		page, perPage = pageParams(r)
+		terms         = []string{r.FormValue("q")}
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	for _, key := range hostFilters {
+		for _, value := range r.Form[key] {
+			terms = append(terms, qualify(key, value))
+		}
+	}
+
+	if q, err = search.Parse(strings.Join(terms, " ")); err != nil {
+		srv.apiError(w, r, http.StatusBadRequest, "Invalid filter: %s", err.Error())
+		return
+	}
+
+	db = srv.dbPool.Get()
+	defer srv.dbPool.Put(db)
+
+	if res, err = db.Search(r.Context(), q, page, perPage); err != nil {
+		srv.apiError(w, r, http.StatusInternalServerError,
+			"Error loading Hosts: %s",
+			err.Error())
+		return
+	}
+
+	var list = apiHostList{
+		Total:   res.Total,
+		Page:    res.Page,
+		PerPage: res.PerPage,
+		Items:   make([]apiHost, len(res.Hits)),
+	}
+
+	for i, hit := range res.Hits {
+		list.Items[i] = apiHostFrom(&hit.Host)
+		for _, p := range hit.Ports {
+			if p.Reply != nil {
+				list.Items[i].OpenPorts = append(list.Items[i].OpenPorts, p.Port)
+			}
+		}
+	}
+
+	srv.apiRespond(w, r, 200, &list)
+} // func (srv *WebFrontend) handleAPIHosts(w http.ResponseWriter, r *http.Request)
+
+func (srv *WebFrontend) handleAPIHost(w http.ResponseWriter, r *http.Request) {
+	var (
+		err      error
+		id       int64
+		db       database.HostDB
+		host     *data.Host
+		ports    []data.Port
+		tags     []data.Tag
+		evidence []data.OSEvidence
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	if id, err = strconv.ParseInt(mux.Vars(r)["id"], 10, 64); err != nil {
+		srv.apiError(w, r, http.StatusBadRequest, "Invalid Host ID: %s", err.Error())
+		return
+	}
+
+	db = srv.dbPool.Get()
+	defer srv.dbPool.Put(db)
+
+	if host, err = db.HostGetByID(r.Context(), krylib.ID(id)); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			srv.apiError(w, r, http.StatusNotFound, "Host #%d does not exist", id)
+		} else {
+			srv.apiError(w, r, http.StatusInternalServerError,
+				"Error loading Host #%d: %s",
+				id,
+				err.Error())
+		}
+		return
+	} else if ports, err = db.PortGetByHost(r.Context(), host.ID); err != nil {
+		srv.apiError(w, r, http.StatusInternalServerError,
+			"Error loading Ports of Host #%d: %s",
+			id,
+			err.Error())
+		return
+	} else if tags, err = db.HostTagGet(r.Context(), host.ID); err != nil {
+		srv.apiError(w, r, http.StatusInternalServerError,
+			"Error loading Tags of Host #%d: %s",
+			id,
+			err.Error())
+		return
+	} else if evidence, err = db.HostGetOSEvidence(r.Context(), host.ID); err != nil {
+		srv.apiError(w, r, http.StatusInternalServerError,
+			"Error loading OS evidence of Host #%d: %s",
+			id,
+			err.Error())
+		return
+	}
+
+	var (
+		addr   = host.Address.String()
+		detail = apiHostDetail{
+			ID:           int64(host.ID),
+			Address:      addr,
+			Name:         host.Name,
+			Source:       apiSource(host.Source),
+			Added:        host.Added,
+			OS:           host.OS,
+			OSVersion:    host.OSVersion,
+			OSConfidence: host.OSConfidence,
+			OSEvidence:   make([]apiOSEvidence, len(evidence)),
+			Location:     host.Location,
+			Geo:          apiGeoFrom(&host.Geo),
+			AS:           apiASFrom(&host.AS),
+			Names:        make([]apiHostName, len(host.Names)),
+			Addresses:    make([]apiHostAddress, len(host.Addresses)),
+			Ports:        make([]apiPort, len(ports)),
+			Tags:         tagNames(tags),
+		}
+	)
+
+	for i, ev := range evidence {
+		detail.OSEvidence[i] = apiOSEvidence{
+			Port:    ev.Port,
+			Source:  ev.Source,
+			OS:      ev.OS,
+			Version: ev.Version,
+			Weight:  ev.Weight,
+			Match:   ev.Match,
+		}
+	}
+
+	for i, n := range host.Names {
+		detail.Names[i] = apiHostName{
+			Name:      n.Name,
+			Source:    apiSource(n.Source),
+			FirstSeen: n.FirstSeen,
+			LastSeen:  n.LastSeen,
+		}
+	}
+
+	for i, a := range host.Addresses {
+		detail.Addresses[i] = apiHostAddress{
+			Address:   a.Address.String(),
+			Source:    apiSource(a.Source),
+			FirstSeen: a.FirstSeen,
+			LastSeen:  a.LastSeen,
+		}
+	}
+
+	for i := range ports {
+		detail.Ports[i] = apiPortFrom(&ports[i], addr)
+	}
+
+	srv.apiRespond(w, r, 200, &detail)
+} // func (srv *WebFrontend) handleAPIHost(w http.ResponseWriter, r *http.Request)
+
+// parseTime parses a point in time given either in RFC 3339 format or as
+// seconds since the epoch.
+func parseTime(s string) (time.Time, error) {
+	if sec, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(sec, 0), nil
+	}
+
+	return time.Parse(time.RFC3339, s)
+} // func parseTime(s string) (time.Time, error)
+
+func (srv *WebFrontend) handleAPIPorts(w http.ResponseWriter, r *http.Request) {
+	var (
+		err           error
+		db            database.HostDB
+		num           uint64
+		id            int64
+		filter        database.PortFilter
+		ports         []data.Port
+		total         int64
+		page, perPage = pageParams(r)
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	if s := r.FormValue("port"); s != "" {
+		if num, err = strconv.ParseUint(s, 10, 16); err != nil || num == 0 {
+			srv.apiError(w, r, http.StatusBadRequest, "Invalid port number %q", s)
+			return
+		}
+		filter.Port = uint16(num)
+	}
+
+	if s := r.FormValue("host_id"); s != "" {
+		if id, err = strconv.ParseInt(s, 10, 64); err != nil || id < 1 {
+			srv.apiError(w, r, http.StatusBadRequest, "Invalid Host ID %q", s)
+			return
+		}
+		filter.HostID = krylib.ID(id)
+	}
+
+	if s := r.FormValue("since"); s != "" {
+		if filter.Since, err = parseTime(s); err != nil {
+			srv.apiError(w, r, http.StatusBadRequest, "Invalid timestamp %q", s)
+			return
+		}
+	}
+
+	filter.Product = r.FormValue("product")
+	filter.Version = r.FormValue("version")
+
+	if s := r.FormValue("open"); s != "" {
+		if filter.OpenOnly, err = strconv.ParseBool(s); err != nil {
+			srv.apiError(w, r, http.StatusBadRequest, "Invalid value for open: %q", s)
+			return
+		}
+	}
+
+	db = srv.dbPool.Get()
+	defer srv.dbPool.Put(db)
+
+	if ports, total, err = db.PortGetPage(r.Context(), filter, page, perPage); err != nil {
+		srv.apiError(w, r, http.StatusInternalServerError,
+			"Error loading Ports: %s",
+			err.Error())
+		return
+	}
+
+	var (
+		addrs = make(map[krylib.ID]string)
+		list  = apiPortList{
+			Total:   total,
+			Page:    page,
+			PerPage: perPage,
+			Items:   make([]apiPort, len(ports)),
+		}
+	)
+
+	for i := range ports {
+		var (
+			ok   bool
+			addr string
+			host *data.Host
+		)
+
+		if addr, ok = addrs[ports[i].HostID]; !ok {
+			if host, err = db.HostGetByID(r.Context(), ports[i].HostID); err != nil {
+				srv.apiError(w, r, http.StatusInternalServerError,
+					"Error loading Host #%d: %s",
+					ports[i].HostID,
+					err.Error())
+				return
+			}
+
+			addr = host.Address.String()
+			addrs[ports[i].HostID] = addr
+		}
+
+		list.Items[i] = apiPortFrom(&ports[i], addr)
+	}
+
+	srv.apiRespond(w, r, 200, &list)
+} // func (srv *WebFrontend) handleAPIPorts(w http.ResponseWriter, r *http.Request)
+
+// parseXfrStatus returns the XfrStatus with the given name, ignoring case.
+func parseXfrStatus(s string) (xfrstatus.XfrStatus, error) {
+	for st := xfrstatus.Unfinished; st <= xfrstatus.Abort; st++ {
+		if strings.EqualFold(st.String(), s) {
+			return st, nil
+		}
+	}
+
+	return 0, fmt.Errorf("Unknown XFR status %q", s)
+} // func parseXfrStatus(s string) (xfrstatus.XfrStatus, error)
+
+func (srv *WebFrontend) handleAPIXfrs(w http.ResponseWriter, r *http.Request) {
+	var (
+		err           error
+		db            database.HostDB
+		xfrs          []data.XFR
+		total         int64
+		status        xfrstatus.XfrStatus = -1
+		page, perPage                     = pageParams(r)
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	if s := r.FormValue("status"); s != "" {
+		if status, err = parseXfrStatus(s); err != nil {
+			srv.apiError(w, r, http.StatusBadRequest, "%s", err.Error())
+			return
+		}
+	}
+
+	db = srv.dbPool.Get()
+	defer srv.dbPool.Put(db)
+
+	if xfrs, total, err = db.XfrGetPage(r.Context(), status, page, perPage); err != nil {
+		srv.apiError(w, r, http.StatusInternalServerError,
+			"Error loading XFRs: %s",
+			err.Error())
+		return
+	}
+
+	var list = apiXfrList{
+		Total:   total,
+		Page:    page,
+		PerPage: perPage,
+		Items:   make([]apiXfr, len(xfrs)),
+	}
+
+	for i := range xfrs {
+		list.Items[i] = apiXfrFrom(&xfrs[i])
+	}
+
+	srv.apiRespond(w, r, 200, &list)
+} // func (srv *WebFrontend) handleAPIXfrs(w http.ResponseWriter, r *http.Request)
+
+func (srv *WebFrontend) handleAPIBlacklist(w http.ResponseWriter, r *http.Request) {
+	var (
+		err           error
+		facilities    = []facility.Facility{facility.Generator, facility.XFR}
+		kind          = r.FormValue("kind")
+		entries       = make([]apiBlacklistEntry, 0)
+		page, perPage = pageParams(r)
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	if srv.nexus == nil {
+		srv.apiError(w, r, http.StatusServiceUnavailable, "The backend is not running")
+		return
+	} else if kind != "" && kind != "name" && kind != "network" {
+		srv.apiError(w, r, http.StatusBadRequest,
+			"Invalid kind %q, must be name or network",
+			kind)
+		return
+	} else if s := r.FormValue("facility"); s != "" {
+		var f facility.Facility
+
+		if f, err = facility.Parse(s); err != nil {
+			srv.apiError(w, r, http.StatusBadRequest, "%s", err.Error())
+			return
+		}
+
+		facilities = []facility.Facility{f}
+	}
+
+	for _, f := range facilities {
+		var names, addrs = srv.nexus.Blacklists(f)
+
+		for _, l := range []struct {
+			kind string
+			list []blacklist.Entry
+		}{
+			{"name", names},
+			{"network", addrs},
+		} {
+			if kind != "" && kind != l.kind {
+				continue
+			}
+
+			for _, e := range l.list {
+				entries = append(entries, apiBlacklistEntry{
+					Facility: f.String(),
+					Kind:     l.kind,
+					Pattern:  e.Pattern,
+					Hits:     e.Hits,
+				})
+			}
+		}
+	}
+
+	var (
+		start = (page - 1) * perPage
+		end   = start + perPage
+	)
+
+	if start > len(entries) {
+		start = len(entries)
+	}
+
+	if end > len(entries) {
+		end = len(entries)
+	}
+
+	var list = apiBlacklist{
+		Total:   int64(len(entries)),
+		Page:    page,
+		PerPage: perPage,
+		Items:   entries[start:end],
+	}
+
+	srv.apiRespond(w, r, 200, &list)
+} // func (srv *WebFrontend) handleAPIBlacklist(w http.ResponseWriter, r *http.Request)
+
+func (srv *WebFrontend) handleAPIWorkers(w http.ResponseWriter, r *http.Request) {
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	if srv.nexus == nil {
+		srv.apiError(w, r, http.StatusServiceUnavailable, "The backend is not running")
+		return
+	}
+
+	var workers = make([]apiWorkers, 0, len(facility.All()))
+
+	for _, f := range facility.All() {
+		workers = append(workers, apiWorkers{
+			Facility: f.String(),
+			Count:    srv.nexus.WorkerCount(f),
+		})
+	}
+
+	srv.apiRespond(w, r, 200, workers)
+} // func (srv *WebFrontend) handleAPIWorkers(w http.ResponseWriter, r *http.Request)
+
+// handleAPIWorkersSet spawns or stops workers in a facility so the number
+// of workers matches the requested count. Workers start and stop
+// asynchronously, so the count we return may lag behind for a moment.
+func (srv *WebFrontend) handleAPIWorkersSet(w http.ResponseWriter, r *http.Request) {
+	var (
+		err error
+		f   facility.Facility
+		req apiWorkersRequest
+		cnt int
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	if srv.nexus == nil {
+		srv.apiError(w, r, http.StatusServiceUnavailable, "The backend is not running")
+		return
+	} else if f, err = facility.Parse(mux.Vars(r)["facility"]); err != nil {
+		srv.apiError(w, r, http.StatusNotFound, "%s", err.Error())
+		return
+	} else if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		srv.apiError(w, r, http.StatusBadRequest, "Cannot parse request: %s", err.Error())
+		return
+	} else if req.Count < 0 || req.Count > backend.MaxWorkers {
+		srv.apiError(w, r, http.StatusBadRequest,
+			"Invalid number of workers: %d (must be between 0 and %d)",
+			req.Count,
+			backend.MaxWorkers)
+		return
+	} else if !srv.nexus.Running(f) {
+		srv.apiError(w, r, http.StatusConflict,
+			"The %s facility is not running",
+			f)
+		return
+	}
+
+	cnt = srv.nexus.WorkerCount(f)
+
+	if req.Count > cnt {
+		srv.log.Printf("[INFO] Starting %d %s workers\n",
+			req.Count-cnt,
+			f)
+		err = srv.nexus.SpawnWorker(f, req.Count-cnt)
+	} else if req.Count < cnt {
+		srv.log.Printf("[INFO] Stopping %d %s workers\n",
+			cnt-req.Count,
+			f)
+		err = srv.nexus.StopWorker(f, cnt-req.Count)
+	}
+
+	if err != nil {
+		srv.apiError(w, r, http.StatusInternalServerError,
+			"Cannot change the number of %s workers: %s",
+			f,
+			err.Error())
+		return
+	}
+
+	srv.apiRespond(w, r, http.StatusAccepted, &apiWorkers{
+		Facility:  f.String(),
+		Count:     srv.nexus.WorkerCount(f),
+		Requested: &req.Count,
+	})
+} // func (srv *WebFrontend) handleAPIWorkersSet(w http.ResponseWriter, r *http.Request)
+
+func (srv *WebFrontend) handleAPILogLevels(w http.ResponseWriter, r *http.Request) {
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	var levels = make([]apiLogLevel, 0, len(logdomain.AllDomains()))
+
+	for _, dom := range logdomain.AllDomains() {
+		levels = append(levels, apiLogLevel{
+			Domain: dom.String(),
+			Level:  string(common.GetLogLevel(dom)),
+		})
+	}
+
+	srv.apiRespond(w, r, 200, levels)
+} // func (srv *WebFrontend) handleAPILogLevels(w http.ResponseWriter, r *http.Request)
+
+// handleAPILogLevelSet changes the minimum level of log messages for one
+// log domain. The change is not persisted, after a restart the level given
+// on the command line applies again.
+func (srv *WebFrontend) handleAPILogLevelSet(w http.ResponseWriter, r *http.Request) {
+	var (
+		err   error
+		dom   logdomain.ID
+		req   apiLogLevelRequest
+		level logutils.LogLevel
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	if dom, err = logdomain.Parse(mux.Vars(r)["domain"]); err != nil {
+		srv.apiError(w, r, http.StatusNotFound, "%s", err.Error())
+		return
+	} else if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		srv.apiError(w, r, http.StatusBadRequest, "Cannot parse request: %s", err.Error())
+		return
+	} else if level, err = common.ParseLogLevel(req.Level); err != nil {
+		srv.apiError(w, r, http.StatusBadRequest, "%s", err.Error())
+		return
+	}
+
+	srv.log.Printf("[INFO] Setting log level of %s to %s\n",
+		dom,
+		level)
+
+	common.SetLogLevel(dom, level) // nolint: errcheck
+
+	srv.apiRespond(w, r, 200, &apiLogLevel{
+		Domain: dom.String(),
+		Level:  string(level),
+	})
+} // func (srv *WebFrontend) handleAPILogLevelSet(w http.ResponseWriter, r *http.Request)
@@ -0,0 +1,296 @@
+// /home/krylon/go/src/github.com/blicero/guang/frontend/api_tags.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 15:02:31 krylon>
+
+package frontend
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blicero/guang/common"
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/database"
+	"github.com/blicero/krylib"
+	"github.com/gorilla/mux"
+)
+
+// This file contains the API endpoints dealing with the tags and notes users
+// attach to Hosts.
+
+// tagNames returns the names of the Tags.
+func tagNames(tags []data.Tag) []string {
+	var names = make([]string, len(tags))
+
+	for i, t := range tags {
+		names[i] = t.Name
+	}
+
+	return names
+} // func tagNames(tags []data.Tag) []string
+
+func apiNoteFrom(n *data.Note) apiNote {
+	return apiNote{
+		ID:     int64(n.ID),
+		HostID: int64(n.HostID),
+		Author: n.Author,
+		Stamp:  n.Stamp,
+		Body:   n.Body,
+	}
+} // func apiNoteFrom(n *data.Note) apiNote
+
+// apiHostID returns the ID of the Host the request refers to, after making
+// sure the Host exists. If it does not, it sends an error to the client and
+// returns false.
+func (srv *WebFrontend) apiHostID(w http.ResponseWriter, r *http.Request, db database.HostDB) (krylib.ID, bool) {
+	var (
+		err error
+		id  int64
+	)
+
+	if id, err = strconv.ParseInt(mux.Vars(r)["id"], 10, 64); err != nil {
+		srv.apiError(w, r, http.StatusBadRequest, "Invalid Host ID: %s", err.Error())
+		return krylib.INVALID_ID, false
+	} else if _, err = db.HostGetByID(r.Context(), krylib.ID(id)); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			srv.apiError(w, r, http.StatusNotFound, "Host #%d does not exist", id)
+		} else {
+			srv.apiError(w, r, http.StatusInternalServerError,
+				"Error loading Host #%d: %s",
+				id,
+				err.Error())
+		}
+		return krylib.INVALID_ID, false
+	}
+
+	return krylib.ID(id), true
+} // func (srv *WebFrontend) apiHostID(w http.ResponseWriter, r *http.Request, db database.HostDB) (krylib.ID, bool)
+
+func (srv *WebFrontend) handleAPITags(w http.ResponseWriter, r *http.Request) {
+	var (
+		err  error
+		db   database.HostDB
+		tags []data.Tag
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	db = srv.dbPool.Get()
+	defer srv.dbPool.Put(db)
+
+	if tags, err = db.TagGetAll(r.Context()); err != nil {
+		srv.apiError(w, r, http.StatusInternalServerError,
+			"Error loading Tags: %s",
+			err.Error())
+		return
+	}
+
+	srv.apiRespond(w, r, 200, tagNames(tags))
+} // func (srv *WebFrontend) handleAPITags(w http.ResponseWriter, r *http.Request)
+
+func (srv *WebFrontend) handleAPIHostTags(w http.ResponseWriter, r *http.Request) {
+	var (
+		err  error
+		db   database.HostDB
+		id   krylib.ID
+		ok   bool
+		tags []data.Tag
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	db = srv.dbPool.Get()
+	defer srv.dbPool.Put(db)
+
+	if id, ok = srv.apiHostID(w, r, db); !ok {
+		return
+	} else if tags, err = db.HostTagGet(r.Context(), id); err != nil {
+		srv.apiError(w, r, http.StatusInternalServerError,
+			"Error loading Tags of Host #%d: %s",
+			id,
+			err.Error())
+		return
+	}
+
+	srv.apiRespond(w, r, 200, tagNames(tags))
+} // func (srv *WebFrontend) handleAPIHostTags(w http.ResponseWriter, r *http.Request)
+
+// handleAPIHostTagSet attaches a Tag to a Host (PUT) or removes it (DELETE)
+// and responds with the Host's Tags.
+func (srv *WebFrontend) handleAPIHostTagSet(w http.ResponseWriter, r *http.Request) {
+	var (
+		err  error
+		db   database.HostDB
+		id   krylib.ID
+		ok   bool
+		tags []data.Tag
+		name = strings.TrimSpace(mux.Vars(r)["tag"])
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	db = srv.dbPool.Get()
+	defer srv.dbPool.Put(db)
+
+	if id, ok = srv.apiHostID(w, r, db); !ok {
+		return
+	} else if name == "" {
+		srv.apiError(w, r, http.StatusBadRequest, "Tag name must not be empty")
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		err = db.HostTagDelete(r.Context(), id, name)
+	} else {
+		_, err = db.HostTagAdd(r.Context(), id, name)
+	}
+
+	if errors.Is(err, database.ErrNotFound) {
+		srv.apiError(w, r, http.StatusNotFound, "Host #%d is not tagged %s", id, name)
+		return
+	} else if err != nil {
+		srv.apiError(w, r, http.StatusInternalServerError,
+			"Error changing Tags of Host #%d: %s",
+			id,
+			err.Error())
+		return
+	} else if tags, err = db.HostTagGet(r.Context(), id); err != nil {
+		srv.apiError(w, r, http.StatusInternalServerError,
+			"Error loading Tags of Host #%d: %s",
+			id,
+			err.Error())
+		return
+	}
+
+	srv.apiRespond(w, r, 200, tagNames(tags))
+} // func (srv *WebFrontend) handleAPIHostTagSet(w http.ResponseWriter, r *http.Request)
+
+func (srv *WebFrontend) handleAPIHostNotes(w http.ResponseWriter, r *http.Request) {
+	var (
+		err   error
+		db    database.HostDB
+		id    krylib.ID
+		ok    bool
+		notes []data.Note
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	db = srv.dbPool.Get()
+	defer srv.dbPool.Put(db)
+
+	if id, ok = srv.apiHostID(w, r, db); !ok {
+		return
+	} else if notes, err = db.NoteGetByHost(r.Context(), id); err != nil {
+		srv.apiError(w, r, http.StatusInternalServerError,
+			"Error loading Notes on Host #%d: %s",
+			id,
+			err.Error())
+		return
+	}
+
+	var list = make([]apiNote, len(notes))
+
+	for i := range notes {
+		list[i] = apiNoteFrom(&notes[i])
+	}
+
+	srv.apiRespond(w, r, 200, list)
+} // func (srv *WebFrontend) handleAPIHostNotes(w http.ResponseWriter, r *http.Request)
+
+func (srv *WebFrontend) handleAPIHostNoteAdd(w http.ResponseWriter, r *http.Request) {
+	var (
+		err  error
+		db   database.HostDB
+		ok   bool
+		req  apiNoteRequest
+		note = data.Note{
+			Author: authFrom(r).user.Name,
+			Stamp:  time.Now(),
+		}
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		srv.apiError(w, r, http.StatusBadRequest, "Cannot parse request: %s", err.Error())
+		return
+	} else if note.Body = strings.TrimSpace(req.Body); note.Body == "" {
+		srv.apiError(w, r, http.StatusBadRequest, "The note is empty")
+		return
+	}
+
+	db = srv.dbPool.Get()
+	defer srv.dbPool.Put(db)
+
+	if note.HostID, ok = srv.apiHostID(w, r, db); !ok {
+		return
+	} else if err = db.NoteAdd(r.Context(), &note); err != nil {
+		srv.apiError(w, r, http.StatusInternalServerError,
+			"Error adding Note to Host #%d: %s",
+			note.HostID,
+			err.Error())
+		return
+	}
+
+	var res = apiNoteFrom(&note)
+	srv.apiRespond(w, r, http.StatusCreated, &res)
+} // func (srv *WebFrontend) handleAPIHostNoteAdd(w http.ResponseWriter, r *http.Request)
+
+func (srv *WebFrontend) handleAPIHostNoteDelete(w http.ResponseWriter, r *http.Request) {
+	var (
+		err    error
+		db     database.HostDB
+		id     krylib.ID
+		ok     bool
+		noteID int64
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	if noteID, err = strconv.ParseInt(mux.Vars(r)["note"], 10, 64); err != nil {
+		srv.apiError(w, r, http.StatusBadRequest, "Invalid Note ID: %s", err.Error())
+		return
+	}
+
+	db = srv.dbPool.Get()
+	defer srv.dbPool.Put(db)
+
+	if id, ok = srv.apiHostID(w, r, db); !ok {
+		return
+	} else if err = db.NoteDelete(r.Context(), id, krylib.ID(noteID)); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			srv.apiError(w, r, http.StatusNotFound,
+				"Host #%d has no Note #%d",
+				id,
+				noteID)
+		} else {
+			srv.apiError(w, r, http.StatusInternalServerError,
+				"Error deleting Note #%d: %s",
+				noteID,
+				err.Error())
+		}
+		return
+	}
+
+	w.Header().Set("Cache-Control", cacheControl)
+	w.WriteHeader(http.StatusNoContent)
+} // func (srv *WebFrontend) handleAPIHostNoteDelete(w http.ResponseWriter, r *http.Request)
@@ -0,0 +1,319 @@
+// /home/krylon/go/src/github.com/blicero/guang/frontend/api_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 16:52:31 krylon>
+
+package frontend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blicero/guang/auth"
+	"github.com/blicero/guang/backend"
+	"github.com/blicero/guang/common"
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/database"
+	"github.com/blicero/guang/logdomain"
+)
+
+func TestMain(m *testing.M) {
+	var (
+		err error
+		dir string
+		rc  int
+	)
+
+	// Keep the tests away from the real database.
+	if dir, err = os.MkdirTemp("", "guang_frontend_test_"); err != nil {
+		fmt.Printf("Cannot create temporary directory: %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	common.SetBaseDir(dir)
+	rc = m.Run()
+	os.RemoveAll(dir) // nolint: errcheck
+	os.Exit(rc)
+} // func TestMain(m *testing.M)
+
+// apiRequest sends a request with the given API token to the frontend and
+// decodes the JSON response into v, unless v is nil.
+func apiRequest(t *testing.T, srv *WebFrontend, token, method, uri, body string, v any) int {
+	var (
+		req = httptest.NewRequest(method, uri, strings.NewReader(body))
+		res = httptest.NewRecorder()
+	)
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	srv.router.ServeHTTP(res, req)
+
+	if v != nil && res.Code < 300 {
+		if err := json.Unmarshal(res.Body.Bytes(), v); err != nil {
+			t.Errorf("Cannot decode response to %s %s: %s\n%s",
+				method,
+				uri,
+				err.Error(),
+				res.Body.String())
+		}
+	}
+
+	return res.Code
+} // func apiRequest(t *testing.T, srv *WebFrontend, token, method, uri, body string, v any) int
+
+func TestAPI(t *testing.T) {
+	var (
+		err   error
+		srv   *WebFrontend
+		db    database.HostDB
+		ctx   = context.Background()
+		reply = "SSH-2.0-OpenSSH_8.4"
+		host  = data.Host{
+			Address: net.ParseIP("198.51.100.42"),
+			Name:    "api.example.org",
+			Source:  data.HostSourceUser,
+		}
+	)
+
+	if srv, err = Create("", 4712, nil, nil); err != nil {
+		t.Fatalf("Error creating Web Frontend: %s", err.Error())
+	}
+
+	var token = testToken(t, srv, testUser(t, srv, "api", auth.Operator))
+
+	db = srv.dbPool.Get()
+	defer srv.dbPool.Put(db)
+
+	if _, err = db.HostUpsert(ctx, &host); err != nil {
+		t.Fatalf("Error adding Host: %s", err.Error())
+	} else if err = db.PortAdd(ctx, &data.ScanResult{
+		Host:  host,
+		Port:  22,
+		Reply: &reply,
+		Stamp: time.Now(),
+	}); err != nil {
+		t.Fatalf("Error adding Port: %s", err.Error())
+	} else if err = db.HostSetOS(ctx, &host, &data.OSGuess{
+		OS:         "OpenBSD",
+		Version:    "6.8",
+		Confidence: 0.5,
+		Evidence: []data.OSEvidence{
+			{Port: 22, Source: "reply", OS: "OpenBSD", Version: "6.8", Weight: 0.5, Match: "OpenBSD 6.8"},
+		},
+	}); err != nil {
+		t.Fatalf("Error setting OS: %s", err.Error())
+	}
+
+	var (
+		hosts  apiHostList
+		detail apiHostDetail
+		ports  apiPortList
+		xfrs   apiXfrList
+	)
+
+	if code := apiRequest(t, srv, token, "GET", "/api/v1/hosts?port=22&net=198.51.100.0/24", "", &hosts); code != 200 {
+		t.Errorf("GET /api/v1/hosts returned %d", code)
+	} else if hosts.Total != 1 || len(hosts.Items) != 1 {
+		t.Errorf("Expected exactly one Host, got %d", hosts.Total)
+	} else if h := hosts.Items[0]; h.ID != int64(host.ID) ||
+		h.Source != "User" ||
+		len(h.OpenPorts) != 1 ||
+		h.OpenPorts[0] != 22 {
+		t.Errorf("Unexpected Host: %#v", h)
+	}
+
+	if code := apiRequest(t, srv, token, "GET", fmt.Sprintf("/api/v1/hosts/%d", host.ID), "", &detail); code != 200 {
+		t.Errorf("GET /api/v1/hosts/%d returned %d", host.ID, code)
+	} else if detail.Name != host.Name || len(detail.Ports) != 1 {
+		t.Errorf("Unexpected Host: %#v", detail)
+	} else if p := detail.Ports[0]; p.Reply == nil || *p.Reply != reply || p.Address != "198.51.100.42" {
+		t.Errorf("Unexpected Port: %#v", p)
+	} else if detail.OS != "OpenBSD" || detail.OSVersion != "6.8" || detail.OSConfidence != 0.5 {
+		t.Errorf("Unexpected OS: %s %s (%f)", detail.OS, detail.OSVersion, detail.OSConfidence)
+	} else if len(detail.OSEvidence) != 1 || detail.OSEvidence[0].Source != "reply" || detail.OSEvidence[0].Port != 22 {
+		t.Errorf("Unexpected OS evidence: %#v", detail.OSEvidence)
+	}
+
+	if code := apiRequest(t, srv, token, "GET", fmt.Sprintf("/api/v1/ports?host_id=%d&open=true", host.ID), "", &ports); code != 200 {
+		t.Errorf("GET /api/v1/ports returned %d", code)
+	} else if ports.Total != 1 || ports.Items[0].Port != 22 {
+		t.Errorf("Unexpected Ports: %#v", ports)
+	}
+
+	if code := apiRequest(t, srv, token, "GET", "/api/v1/xfrs?status=success&per_page=5", "", &xfrs); code != 200 {
+		t.Errorf("GET /api/v1/xfrs returned %d", code)
+	} else if xfrs.PerPage != 5 || xfrs.Items == nil {
+		t.Errorf("Unexpected XFR list: %#v", xfrs)
+	}
+
+	type testCase struct {
+		method, uri, body string
+		status            int
+	}
+
+	var cases = []testCase{
+		{"GET", "/api/v1/openapi.yaml", "", 200},
+		{"GET", "/api/v1/hosts/999999", "", 404},
+		{"GET", "/api/v1/hosts?q=port:http", "", 400},
+		{"GET", "/api/v1/ports?port=70000", "", 400},
+		{"GET", "/api/v1/ports?since=yesterday", "", 400},
+		{"GET", "/api/v1/xfrs?status=maybe", "", 400},
+		{"GET", "/api/v1/workers", "", 503},
+		{"PUT", "/api/v1/workers/scanner", `{"count":4}`, 503},
+		{"GET", "/api/v1/blacklist", "", 503},
+		{"POST", "/api/v1/hosts", "", 405},
+	}
+
+	for _, c := range cases {
+		if code := apiRequest(t, srv, token, c.method, c.uri, c.body, nil); code != c.status {
+			t.Errorf("%s %s returned %d, expected %d",
+				c.method,
+				c.uri,
+				code,
+				c.status)
+		}
+	}
+} // func TestAPI(t *testing.T)
+
+func TestAPIWorkersNotRunning(t *testing.T) {
+	var (
+		err   error
+		srv   *WebFrontend
+		nexus *backend.Nexus
+	)
+
+	// None of the facilities are started.
+	if nexus, err = backend.CreateNexus(nil, nil, nil, nil); err != nil {
+		t.Fatalf("Error creating Nexus: %s", err.Error())
+	}
+
+	defer nexus.Close()
+
+	if srv, err = Create("", 4728, nil, nexus); err != nil {
+		t.Fatalf("Error creating Web Frontend: %s", err.Error())
+	}
+
+	var token = testToken(t, srv, testUser(t, srv, "foreman", auth.Operator))
+
+	type testCase struct {
+		uri, body string
+		status    int
+	}
+
+	var cases = []testCase{
+		{"/api/v1/workers/xfr", `{"count":2}`, http.StatusConflict},
+		{"/api/v1/workers/generator", `{"count":0}`, http.StatusConflict},
+		{"/api/v1/workers/scanner", `{"count":1000000}`, http.StatusBadRequest},
+	}
+
+	for _, c := range cases {
+		if code := apiRequest(t, srv, token, "PUT", c.uri, c.body, nil); code != c.status {
+			t.Errorf("PUT %s returned %d, expected %d",
+				c.uri,
+				code,
+				c.status)
+		}
+	}
+} // func TestAPIWorkersNotRunning(t *testing.T)
+
+func TestMetrics(t *testing.T) {
+	var (
+		err   error
+		srv   *WebFrontend
+		token string
+		res   = httptest.NewRecorder()
+		req   = httptest.NewRequest("GET", "/metrics", nil)
+	)
+
+	if srv, err = Create("", 4717, nil, nil); err != nil {
+		t.Fatalf("Error creating Web Frontend: %s", err.Error())
+	}
+
+	token = testToken(t, srv, testUser(t, srv, "prometheus", auth.ReadOnly))
+
+	if code := apiRequest(t, srv, "", "GET", "/metrics", "", nil); code != http.StatusUnauthorized {
+		t.Errorf("GET /metrics without credentials returned %d", code)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	srv.router.ServeHTTP(res, req)
+
+	if res.Code != http.StatusOK {
+		t.Fatalf("GET /metrics returned %d", res.Code)
+	}
+
+	var body = res.Body.String()
+
+	for _, name := range []string{"guang_db_retries_total", "go_goroutines"} {
+		if !strings.Contains(body, name) {
+			t.Errorf("Metric %s is missing", name)
+		}
+	}
+} // func TestMetrics(t *testing.T)
+
+func TestLogLevels(t *testing.T) {
+	var (
+		err    error
+		srv    *WebFrontend
+		viewer string
+		op     string
+		levels []apiLogLevel
+		level  apiLogLevel
+	)
+
+	if srv, err = Create("", 4718, nil, nil); err != nil {
+		t.Fatalf("Error creating Web Frontend: %s", err.Error())
+	}
+
+	defer common.SetLogLevel(logdomain.XFR, common.MinLogLevel) // nolint: errcheck
+
+	viewer = testToken(t, srv, testUser(t, srv, "auditor", auth.ReadOnly))
+	op = testToken(t, srv, testUser(t, srv, "logadmin", auth.Operator))
+
+	if code := apiRequest(t, srv, viewer, "GET", "/api/v1/loglevels", "", &levels); code != http.StatusOK {
+		t.Fatalf("GET /api/v1/loglevels returned %d", code)
+	} else if len(levels) != len(logdomain.AllDomains()) {
+		t.Errorf("Expected %d log domains, got %d",
+			len(logdomain.AllDomains()),
+			len(levels))
+	}
+
+	type testCase struct {
+		token, uri, body string
+		status           int
+	}
+
+	var cases = []testCase{
+		{viewer, "/api/v1/loglevels/xfr", `{"level":"debug"}`, http.StatusForbidden},
+		{op, "/api/v1/loglevels/Nonsense", `{"level":"DEBUG"}`, http.StatusNotFound},
+		{op, "/api/v1/loglevels/XFR", `{"level":"LOUD"}`, http.StatusBadRequest},
+		{op, "/api/v1/loglevels/xfr", `{"level":"debug"}`, http.StatusOK},
+	}
+
+	for _, c := range cases {
+		if code := apiRequest(t, srv, c.token, "PUT", c.uri, c.body, &level); code != c.status {
+			t.Errorf("PUT %s %s returned %d, expected %d",
+				c.uri,
+				c.body,
+				code,
+				c.status)
+		}
+	}
+
+	if level.Domain != "XFR" || level.Level != "DEBUG" {
+		t.Errorf("Unexpected response: %#v", level)
+	} else if lvl := common.GetLogLevel(logdomain.XFR); lvl != "DEBUG" {
+		t.Errorf("Log level of XFR is %s, expected DEBUG", lvl)
+	}
+} // func TestLogLevels(t *testing.T)
@@ -0,0 +1,268 @@
+// /home/krylon/go/src/github.com/blicero/guang/frontend/api_types.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 17:02:59 krylon>
+
+package frontend
+
+import (
+	"time"
+)
+
+//go:generate ffjson api_types.go
+
+// The types in this file make up the public REST API under /api/v1.
+// Unlike the types used by our own JavaScript, their JSON names are part
+// of the interface, described in html/openapi.yaml, and must not change.
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+type apiHost struct {
+	ID           int64     `json:"id"`
+	Address      string    `json:"address"`
+	Name         string    `json:"name"`
+	Source       string    `json:"source"`
+	Added        time.Time `json:"added"`
+	OS           string    `json:"os"`
+	OSVersion    string    `json:"os_version"`
+	OSConfidence float64   `json:"os_confidence"`
+	Location     string    `json:"location"`
+	Geo          *apiGeo   `json:"geo,omitempty"`
+	AS           *apiAS    `json:"as,omitempty"`
+	OpenPorts    []uint16  `json:"open_ports"`
+}
+
+// apiGeo is the location of a Host in structured form. The coordinates
+// are null if they are unknown.
+type apiGeo struct {
+	Country        string   `json:"country"`
+	Subdivision    string   `json:"subdivision"`
+	City           string   `json:"city"`
+	Latitude       *float64 `json:"latitude"`
+	Longitude      *float64 `json:"longitude"`
+	AccuracyRadius uint16   `json:"accuracy_radius"`
+}
+
+// apiGeoPoint is a place Hosts are located at, with the number of Hosts
+// there.
+type apiGeoPoint struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Country   string  `json:"country"`
+	City      string  `json:"city"`
+	Hosts     int64   `json:"hosts"`
+}
+
+// apiAS is the network a Host is part of.
+type apiAS struct {
+	ASN       uint32     `json:"asn"`
+	Org       string     `json:"org"`
+	Prefix    string     `json:"prefix"`
+	Registry  string     `json:"registry"`
+	Allocated *time.Time `json:"allocated"`
+}
+
+type apiHostName struct {
+	Name      string    `json:"name"`
+	Source    string    `json:"source"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+type apiHostAddress struct {
+	Address   string    `json:"address"`
+	Source    string    `json:"source"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+type apiHostDetail struct {
+	ID           int64            `json:"id"`
+	Address      string           `json:"address"`
+	Name         string           `json:"name"`
+	Source       string           `json:"source"`
+	Added        time.Time        `json:"added"`
+	OS           string           `json:"os"`
+	OSVersion    string           `json:"os_version"`
+	OSConfidence float64          `json:"os_confidence"`
+	OSEvidence   []apiOSEvidence  `json:"os_evidence"`
+	Location     string           `json:"location"`
+	Geo          *apiGeo          `json:"geo,omitempty"`
+	AS           *apiAS           `json:"as,omitempty"`
+	Names        []apiHostName    `json:"names"`
+	Addresses    []apiHostAddress `json:"addresses"`
+	Ports        []apiPort        `json:"ports"`
+	Tags         []string         `json:"tags"`
+}
+
+// apiOSEvidence is a hint at a Host's operating system found on a Port.
+type apiOSEvidence struct {
+	Port    uint16  `json:"port"`
+	Source  string  `json:"source"`
+	OS      string  `json:"os"`
+	Version string  `json:"version"`
+	Weight  float64 `json:"weight"`
+	Match   string  `json:"match"`
+}
+
+type apiPort struct {
+	ID        int64        `json:"id"`
+	HostID    int64        `json:"host_id"`
+	Address   string       `json:"address"`
+	Port      uint16       `json:"port"`
+	Timestamp time.Time    `json:"timestamp"`
+	Reply     *string      `json:"reply"`
+	Software  *apiSoftware `json:"software,omitempty"`
+}
+
+type apiSoftware struct {
+	Service string `json:"service"`
+	Product string `json:"product"`
+	Version string `json:"version"`
+	Info    string `json:"info"`
+	OS      string `json:"os"`
+	CPE     string `json:"cpe"`
+}
+
+// apiSoftwareCount tells on how many Ports and Hosts a version of a product
+// was found.
+type apiSoftwareCount struct {
+	Product string `json:"product"`
+	Version string `json:"version"`
+	CPE     string `json:"cpe"`
+	Ports   int64  `json:"ports"`
+	Hosts   int64  `json:"hosts"`
+}
+
+// apiASCount tells how many Hosts we know in an autonomous system.
+type apiASCount struct {
+	ASN   uint32 `json:"asn"`
+	Org   string `json:"org"`
+	Hosts int64  `json:"hosts"`
+}
+
+// apiVulnMatch is a Vulnerability that affects the software on a Port.
+type apiVulnMatch struct {
+	Name      string     `json:"name"`
+	Summary   string     `json:"summary"`
+	Score     float64    `json:"score"`
+	Severity  string     `json:"severity"`
+	Published *time.Time `json:"published"`
+	Source    string     `json:"source"`
+	URL       string     `json:"url"`
+	HostID    int64      `json:"host_id"`
+	PortID    int64      `json:"port_id"`
+	Port      uint16     `json:"port"`
+	Product   string     `json:"product"`
+	Version   string     `json:"version"`
+}
+
+// apiVulnerableHost is an entry in the report of the most vulnerable Hosts.
+type apiVulnerableHost struct {
+	HostID   int64   `json:"host_id"`
+	Address  string  `json:"address"`
+	Name     string  `json:"name"`
+	Vulns    int64   `json:"vulns"`
+	MaxScore float64 `json:"max_score"`
+}
+
+type apiXfr struct {
+	ID     int64      `json:"id"`
+	Zone   string     `json:"zone"`
+	Start  time.Time  `json:"start"`
+	End    *time.Time `json:"end"`
+	Status string     `json:"status"`
+}
+
+// apiEvent is sent to clients of the /events stream. Depending on the kind,
+// host, port or xfr is set. Scan results have both host and port.
+type apiEvent struct {
+	ID    uint64    `json:"id"`
+	Kind  string    `json:"kind"`
+	Stamp time.Time `json:"stamp"`
+	Host  *apiHost  `json:"host,omitempty"`
+	Port  *apiPort  `json:"port,omitempty"`
+	XFR   *apiXfr   `json:"xfr,omitempty"`
+}
+
+type apiBlacklistEntry struct {
+	Facility string `json:"facility"`
+	Kind     string `json:"kind"`
+	Pattern  string `json:"pattern"`
+	Hits     int64  `json:"hits"`
+}
+
+type apiWorkers struct {
+	Facility  string `json:"facility"`
+	Count     int    `json:"count"`
+	Requested *int   `json:"requested,omitempty"`
+}
+
+type apiWorkersRequest struct {
+	Count int `json:"count"`
+}
+
+type apiNote struct {
+	ID     int64     `json:"id"`
+	HostID int64     `json:"host_id"`
+	Author string    `json:"author"`
+	Stamp  time.Time `json:"stamp"`
+	Body   string    `json:"body"`
+}
+
+type apiNoteRequest struct {
+	Body string `json:"body"`
+}
+
+type apiLogLevel struct {
+	Domain string `json:"domain"`
+	Level  string `json:"level"`
+}
+
+type apiLogLevelRequest struct {
+	Level string `json:"level"`
+}
+
+// apiImportStats is also the body of the error response of a failed
+// import, as the batches before the error are stored nonetheless.
+type apiImportStats struct {
+	Results  int    `json:"results"`
+	Ports    int    `json:"ports"`
+	Skipped  int    `json:"skipped"`
+	NewHosts int    `json:"new_hosts"`
+	Error    string `json:"error,omitempty"`
+}
+
+// All list endpoints return one page of items along with the total number
+// of items matching the request.
+
+type apiHostList struct {
+	Total   int64     `json:"total"`
+	Page    int       `json:"page"`
+	PerPage int       `json:"per_page"`
+	Items   []apiHost `json:"items"`
+}
+
+type apiPortList struct {
+	Total   int64     `json:"total"`
+	Page    int       `json:"page"`
+	PerPage int       `json:"per_page"`
+	Items   []apiPort `json:"items"`
+}
+
+type apiXfrList struct {
+	Total   int64    `json:"total"`
+	Page    int      `json:"page"`
+	PerPage int      `json:"per_page"`
+	Items   []apiXfr `json:"items"`
+}
+
+type apiBlacklist struct {
+	Total   int64               `json:"total"`
+	Page    int                 `json:"page"`
+	PerPage int                 `json:"per_page"`
+	Items   []apiBlacklistEntry `json:"items"`
+}
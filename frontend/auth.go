@@ -0,0 +1,593 @@
+// /home/krylon/go/src/github.com/blicero/guang/frontend/auth.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 14:41:18 krylon>
+
+package frontend
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/blicero/guang/auth"
+	"github.com/blicero/guang/backend/facility"
+	"github.com/blicero/guang/common"
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/database"
+	"github.com/blicero/krylib"
+	"github.com/gorilla/mux"
+)
+
+// This file is the home of authentication and authorization.
+//
+// Browsers log in with a user name and a password and get a session cookie.
+// Requests that change anything must also carry the session's CSRF token,
+// either in the X-CSRF-Token header or in the csrf_token form field.
+// Scripts use an API token in the Authorization header instead, which is
+// not sent automatically by browsers, so they do not need a CSRF token.
+
+const (
+	sessionCookie   = "guang_session"
+	sessionLifetime = 12 * time.Hour
+	csrfHeader      = "X-CSRF-Token"
+	csrfField       = "csrf_token"
+	tokenTouchDelay = time.Minute
+)
+
+type ctxKey int
+
+const ctxKeyAuth ctxKey = iota
+
+// authInfo tells us who sent a request. session is nil if the request was
+// authenticated with an API token.
+type authInfo struct {
+	user    data.User
+	session *data.Session
+}
+
+// authFrom returns the authInfo attached to the request by authenticate,
+// or nil if the request was not authenticated.
+func authFrom(r *http.Request) *authInfo {
+	var info, _ = r.Context().Value(ctxKeyAuth).(*authInfo)
+
+	return info
+} // func authFrom(r *http.Request) *authInfo
+
+// isPublic returns true for the paths that can be requested without
+// logging in.
+func isPublic(path string) bool {
+	return path == "/login" || strings.HasPrefix(path, "/static/")
+} // func isPublic(path string) bool
+
+// isSafeMethod returns true for the HTTP methods that must not change
+// anything.
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	default:
+		return false
+	}
+} // func isSafeMethod(method string) bool
+
+// wantsJSON returns true if the client is a program expecting JSON or
+// some other machine-readable response rather than an HTML page.
+func wantsJSON(path string) bool {
+	return strings.HasPrefix(path, "/ajax/") ||
+		strings.HasPrefix(path, "/api/") ||
+		path == "/events" ||
+		path == "/metrics"
+} // func wantsJSON(path string) bool
+
+// localPath returns p if it is a path on this server, "/" otherwise, so we
+// cannot be used to redirect a user to some other site after logging in.
+func localPath(p string) string {
+	if !strings.HasPrefix(p, "/") || strings.HasPrefix(p, "//") || strings.HasPrefix(p, "/\\") {
+		return "/"
+	}
+
+	return p
+} // func localPath(p string) string
+
+// authenticate is a middleware that makes sure every request that is not
+// for a public page comes from a known user and carries a valid CSRF token
+// where one is needed.
+func (srv *WebFrontend) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			err  error
+			info *authInfo
+		)
+
+		if isPublic(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		} else if info, err = srv.lookupAuth(r); err != nil {
+			srv.apiError(w, r, http.StatusInternalServerError,
+				"Cannot check credentials: %s",
+				err.Error())
+			return
+		} else if info == nil {
+			srv.denyUnauthenticated(w, r)
+			return
+		} else if info.session != nil && !isSafeMethod(r.Method) {
+			var token = r.Header.Get(csrfHeader)
+
+			if token == "" {
+				token = r.PostFormValue(csrfField)
+			}
+
+			if subtle.ConstantTimeCompare([]byte(token), []byte(info.session.CSRF)) != 1 {
+				srv.log.Printf("[INFO] Rejecting %s %s from %s (%s): Invalid CSRF token\n",
+					r.Method,
+					r.URL.Path,
+					r.RemoteAddr,
+					info.user.Name)
+				srv.deny(w, r, http.StatusForbidden, "Invalid or missing CSRF token")
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKeyAuth, info)))
+	})
+} // func (srv *WebFrontend) authenticate(next http.Handler) http.Handler
+
+// lookupAuth checks the API token or session cookie the request came with.
+// It returns nil if there is neither or the one there is not valid.
+func (srv *WebFrontend) lookupAuth(r *http.Request) (*authInfo, error) {
+	var (
+		err    error
+		db     database.HostDB
+		cookie *http.Cookie
+		hdr    = r.Header.Get("Authorization")
+	)
+
+	db = srv.dbPool.Get()
+	defer srv.dbPool.Put(db)
+
+	if strings.HasPrefix(hdr, "Bearer ") {
+		var tok *data.APIToken
+
+		if tok, err = db.TokenGet(r.Context(), auth.HashToken(strings.TrimPrefix(hdr, "Bearer "))); err != nil {
+			if errors.Is(err, database.ErrNotFound) {
+				srv.log.Printf("[INFO] Invalid API token from %s\n", r.RemoteAddr)
+				return nil, nil
+			}
+			return nil, err
+		} else if time.Since(tok.LastUsed) > tokenTouchDelay {
+			// Not worth failing the request over.
+			db.TokenTouch(r.Context(), tok) // nolint: errcheck
+		}
+
+		return &authInfo{user: tok.User}, nil
+	} else if cookie, err = r.Cookie(sessionCookie); err != nil {
+		return nil, nil
+	}
+
+	var s *data.Session
+
+	if s, err = db.SessionGet(r.Context(), auth.HashToken(cookie.Value)); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &authInfo{user: s.User, session: s}, nil
+} // func (srv *WebFrontend) lookupAuth(r *http.Request) (*authInfo, error)
+
+// denyUnauthenticated sends browsers to the login page, and tells scripts
+// they need to authenticate.
+func (srv *WebFrontend) denyUnauthenticated(w http.ResponseWriter, r *http.Request) {
+	if wantsJSON(r.URL.Path) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="guang"`)
+		srv.apiError(w, r, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	http.Redirect(w, r,
+		"/login?next="+url.QueryEscape(r.URL.RequestURI()),
+		http.StatusSeeOther)
+} // func (srv *WebFrontend) denyUnauthenticated(w http.ResponseWriter, r *http.Request)
+
+// deny tells the client it is not allowed to do what it asked for.
+func (srv *WebFrontend) deny(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	if wantsJSON(r.URL.Path) {
+		srv.apiError(w, r, status, "%s", msg)
+		return
+	}
+
+	http.Error(w, msg, status)
+} // func (srv *WebFrontend) deny(w http.ResponseWriter, r *http.Request, status int, msg string)
+
+// requireRole wraps a handler so it is only called for users with the
+// given Role.
+func (srv *WebFrontend) requireRole(role auth.Role, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var info = authFrom(r)
+
+		if info == nil || !info.user.Role.Allows(role) {
+			var name = "anonymous"
+
+			if info != nil {
+				name = info.user.Name
+			}
+
+			srv.log.Printf("[INFO] Rejecting %s %s from %s (%s): Requires role %s\n",
+				r.Method,
+				r.URL.Path,
+				r.RemoteAddr,
+				name,
+				role)
+			srv.deny(w, r, http.StatusForbidden,
+				fmt.Sprintf("This requires the %s role", role))
+			return
+		}
+
+		h(w, r)
+	}
+} // func (srv *WebFrontend) requireRole(role auth.Role, h http.HandlerFunc) http.HandlerFunc
+
+// operator wraps a handler so it is only called for Operators.
+func (srv *WebFrontend) operator(h http.HandlerFunc) http.HandlerFunc {
+	return srv.requireRole(auth.Operator, h)
+} // func (srv *WebFrontend) operator(h http.HandlerFunc) http.HandlerFunc
+
+// ensureUser creates an Operator with a random password if there are no
+// users at all, since otherwise nobody could log in.
+func (srv *WebFrontend) ensureUser() error {
+	const name = "admin"
+	var (
+		err      error
+		cnt      int64
+		db       database.HostDB
+		password string
+		user     = data.User{Name: name, Role: auth.Operator}
+		ctx      = context.Background()
+	)
+
+	db = srv.dbPool.Get()
+	defer srv.dbPool.Put(db)
+
+	if cnt, err = db.UserGetCount(ctx); err != nil {
+		return err
+	} else if cnt > 0 {
+		return nil
+	} else if password, err = auth.RandomPassword(); err != nil {
+		return err
+	} else if user.PwHash, err = auth.HashPassword(password); err != nil {
+		return err
+	} else if err = db.UserAdd(ctx, &user); err != nil {
+		return err
+	}
+
+	srv.log.Printf("[INFO] There were no users, created Operator %s\n", name)
+
+	// The password does not go to the log file.
+	fmt.Printf("\nCreated user %s with password %s - please change it at /account\n\n",
+		name,
+		password)
+
+	return nil
+} // func (srv *WebFrontend) ensureUser() error
+
+type tmplDataLogin struct {
+	tmplDataIndex
+	Name string
+	Next string
+}
+
+func (srv *WebFrontend) handleLogin(w http.ResponseWriter, r *http.Request) {
+	var (
+		err      error
+		db       database.HostDB
+		user     *data.User
+		key      string
+		tmplData = tmplDataLogin{
+			tmplDataIndex: tmplDataIndex{
+				Title:      "Log in",
+				Debug:      common.Debug,
+				Facilities: facility.All(),
+				Error:      make([]string, 0),
+			},
+			Name: r.PostFormValue("name"),
+			Next: localPath(r.FormValue("next")),
+		}
+		status = http.StatusOK
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	if r.Method != http.MethodPost {
+		goto RENDER
+	}
+
+	db = srv.dbPool.Get()
+	defer srv.dbPool.Put(db)
+
+	if user, err = db.UserGetByName(r.Context(), tmplData.Name); err != nil && !errors.Is(err, database.ErrNotFound) {
+		srv.sendErrorMessage(w, fmt.Sprintf("Cannot look up user: %s", err.Error()))
+		return
+	} else if user == nil {
+		auth.CheckPassword("", r.PostFormValue("password"))
+	} else if auth.CheckPassword(user.PwHash, r.PostFormValue("password")) {
+		var (
+			hash string
+			now  = time.Now()
+			s    = data.Session{
+				User:    *user,
+				Created: now,
+				Expires: now.Add(sessionLifetime),
+			}
+		)
+
+		if key, hash, err = auth.NewToken(); err != nil {
+			srv.sendErrorMessage(w, fmt.Sprintf("Cannot create session: %s", err.Error()))
+			return
+		} else if s.CSRF, _, err = auth.NewToken(); err != nil {
+			srv.sendErrorMessage(w, fmt.Sprintf("Cannot create session: %s", err.Error()))
+			return
+		}
+
+		s.KeyHash = hash
+
+		if err = db.SessionAdd(r.Context(), &s); err != nil {
+			srv.sendErrorMessage(w, fmt.Sprintf("Cannot create session: %s", err.Error()))
+			return
+		} else if _, err = db.SessionPurge(r.Context()); err != nil {
+			srv.log.Printf("[ERROR] Cannot remove expired sessions: %s\n", err.Error())
+		}
+
+		srv.log.Printf("[INFO] User %s logged in from %s\n", user.Name, r.RemoteAddr)
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     sessionCookie,
+			Value:    key,
+			Path:     "/",
+			Expires:  s.Expires,
+			HttpOnly: true,
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteLaxMode,
+		})
+		http.Redirect(w, r, tmplData.Next, http.StatusSeeOther)
+		return
+	}
+
+	srv.log.Printf("[INFO] Failed login for %q from %s\n",
+		tmplData.Name,
+		r.RemoteAddr)
+	tmplData.Error = append(tmplData.Error, "Invalid user name or password")
+	status = http.StatusUnauthorized
+
+RENDER:
+	srv.render(w, "login", status, tmplData)
+} // func (srv *WebFrontend) handleLogin(w http.ResponseWriter, r *http.Request)
+
+func (srv *WebFrontend) handleLogout(w http.ResponseWriter, r *http.Request) {
+	var (
+		err  error
+		db   database.HostDB
+		info = authFrom(r)
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	if info.session != nil {
+		db = srv.dbPool.Get()
+		defer srv.dbPool.Put(db)
+
+		if err = db.SessionDelete(r.Context(), info.session.KeyHash); err != nil {
+			srv.sendErrorMessage(w, fmt.Sprintf("Cannot end session: %s", err.Error()))
+			return
+		}
+
+		srv.log.Printf("[INFO] User %s logged out\n", info.user.Name)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+} // func (srv *WebFrontend) handleLogout(w http.ResponseWriter, r *http.Request)
+
+type tmplDataAccount struct {
+	tmplDataIndex
+	Tokens   []data.APIToken
+	NewToken string
+	Message  string
+}
+
+// renderAccount shows the account page. newToken is an API token that was
+// just created, it is shown exactly once.
+func (srv *WebFrontend) renderAccount(w http.ResponseWriter, r *http.Request, newToken, msg string, errs ...string) {
+	var (
+		err      error
+		db       database.HostDB
+		status   = http.StatusOK
+		tmplData = tmplDataAccount{
+			tmplDataIndex: tmplDataIndex{
+				Title:      "Account",
+				Debug:      common.Debug,
+				Facilities: facility.All(),
+				Error:      errs,
+			},
+			NewToken: newToken,
+			Message:  msg,
+		}
+	)
+
+	tmplData.setAuth(r)
+
+	db = srv.dbPool.Get()
+	defer srv.dbPool.Put(db)
+
+	if tmplData.Tokens, err = db.TokenGetByUser(r.Context(), tmplData.User); err != nil {
+		srv.sendErrorMessage(w, fmt.Sprintf("Cannot load API tokens: %s", err.Error()))
+		return
+	} else if len(errs) > 0 {
+		status = http.StatusBadRequest
+	}
+
+	srv.render(w, "account", status, tmplData)
+} // func (srv *WebFrontend) renderAccount(w http.ResponseWriter, r *http.Request, newToken, msg string, errs ...string)
+
+func (srv *WebFrontend) handleAccount(w http.ResponseWriter, r *http.Request) {
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	srv.renderAccount(w, r, "", "")
+} // func (srv *WebFrontend) handleAccount(w http.ResponseWriter, r *http.Request)
+
+func (srv *WebFrontend) handlePasswordChange(w http.ResponseWriter, r *http.Request) {
+	var (
+		err     error
+		db      database.HostDB
+		hash    string
+		info    = authFrom(r)
+		newPass = r.PostFormValue("new")
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	if !auth.CheckPassword(info.user.PwHash, r.PostFormValue("old")) {
+		srv.renderAccount(w, r, "", "", "The current password is not correct")
+		return
+	} else if newPass != r.PostFormValue("confirm") {
+		srv.renderAccount(w, r, "", "", "The new passwords do not match")
+		return
+	} else if hash, err = auth.HashPassword(newPass); err != nil {
+		srv.renderAccount(w, r, "", "", "Invalid password: "+err.Error())
+		return
+	}
+
+	// renderAccount needs a connection of its own, so we return ours to
+	// the pool right away.
+	db = srv.dbPool.Get()
+	err = db.UserSetPassword(r.Context(), &info.user, hash)
+	srv.dbPool.Put(db)
+
+	if err != nil {
+		srv.sendErrorMessage(w, fmt.Sprintf("Cannot change password: %s", err.Error()))
+		return
+	}
+
+	srv.log.Printf("[INFO] User %s changed their password\n", info.user.Name)
+	srv.renderAccount(w, r, "", "Your password was changed")
+} // func (srv *WebFrontend) handlePasswordChange(w http.ResponseWriter, r *http.Request)
+
+func (srv *WebFrontend) handleTokenAdd(w http.ResponseWriter, r *http.Request) {
+	var (
+		err   error
+		db    database.HostDB
+		key   string
+		info  = authFrom(r)
+		token = data.APIToken{
+			User: info.user,
+			Name: strings.TrimSpace(r.PostFormValue("name")),
+		}
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	if token.Name == "" {
+		srv.renderAccount(w, r, "", "", "Please give the token a name")
+		return
+	} else if key, token.KeyHash, err = auth.NewToken(); err != nil {
+		srv.sendErrorMessage(w, fmt.Sprintf("Cannot create API token: %s", err.Error()))
+		return
+	}
+
+	db = srv.dbPool.Get()
+	err = db.TokenAdd(r.Context(), &token)
+	srv.dbPool.Put(db)
+
+	if err != nil {
+		srv.sendErrorMessage(w, fmt.Sprintf("Cannot create API token: %s", err.Error()))
+		return
+	}
+
+	srv.log.Printf("[INFO] User %s created API token %q\n",
+		info.user.Name,
+		token.Name)
+	srv.renderAccount(w, r, key, "")
+} // func (srv *WebFrontend) handleTokenAdd(w http.ResponseWriter, r *http.Request)
+
+func (srv *WebFrontend) handleTokenDelete(w http.ResponseWriter, r *http.Request) {
+	var (
+		err  error
+		db   database.HostDB
+		id   int64
+		info = authFrom(r)
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	if id, err = strconv.ParseInt(mux.Vars(r)["id"], 10, 64); err != nil {
+		srv.renderAccount(w, r, "", "", "Invalid token ID")
+		return
+	}
+
+	db = srv.dbPool.Get()
+	err = db.TokenDelete(r.Context(), &info.user, krylib.ID(id))
+	srv.dbPool.Put(db)
+
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			srv.renderAccount(w, r, "", "", "No such token")
+		} else {
+			srv.sendErrorMessage(w, fmt.Sprintf("Cannot delete API token: %s", err.Error()))
+		}
+		return
+	}
+
+	srv.log.Printf("[INFO] User %s deleted API token #%d\n", info.user.Name, id)
+	srv.renderAccount(w, r, "", "The token was deleted")
+} // func (srv *WebFrontend) handleTokenDelete(w http.ResponseWriter, r *http.Request)
+
+// render renders the named template with the given data.
+func (srv *WebFrontend) render(w http.ResponseWriter, name string, status int, tmplData any) {
+	var (
+		err  error
+		tmpl *template.Template
+	)
+
+	if tmpl = srv.tmpl.Lookup(name); tmpl == nil {
+		srv.sendErrorMessage(w, fmt.Sprintf("Template %q was not found!", name))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", cacheControl)
+	w.WriteHeader(status)
+	if err = tmpl.Execute(w, tmplData); err != nil {
+		srv.log.Printf("[ERROR] Error rendering template %s or sending output to client: %s\n",
+			name,
+			err.Error())
+	}
+} // func (srv *WebFrontend) render(w http.ResponseWriter, name string, status int, tmplData any)
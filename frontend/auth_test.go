@@ -0,0 +1,232 @@
+// /home/krylon/go/src/github.com/blicero/guang/frontend/auth_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 14:35:54 krylon>
+
+package frontend
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/blicero/guang/auth"
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/database"
+)
+
+const testPassword = "Wer das liest, ist doof."
+
+// testUser adds a User with the given name and Role and the password
+// testPassword.
+func testUser(t *testing.T, srv *WebFrontend, name string, role auth.Role) *data.User {
+	var (
+		err  error
+		db   database.HostDB
+		user = &data.User{Name: name, Role: role}
+	)
+
+	db = srv.dbPool.Get()
+	defer srv.dbPool.Put(db)
+
+	if user.PwHash, err = auth.HashPassword(testPassword); err != nil {
+		t.Fatalf("Error hashing password: %s", err.Error())
+	} else if err = db.UserAdd(context.Background(), user); err != nil {
+		t.Fatalf("Error adding User %s: %s", name, err.Error())
+	}
+
+	return user
+} // func testUser(t *testing.T, srv *WebFrontend, name string, role auth.Role) *data.User
+
+// testToken creates an API token for the User.
+func testToken(t *testing.T, srv *WebFrontend, user *data.User) string {
+	var (
+		err   error
+		db    database.HostDB
+		key   string
+		token = data.APIToken{User: *user, Name: "test"}
+	)
+
+	db = srv.dbPool.Get()
+	defer srv.dbPool.Put(db)
+
+	if key, token.KeyHash, err = auth.NewToken(); err != nil {
+		t.Fatalf("Error creating token: %s", err.Error())
+	} else if err = db.TokenAdd(context.Background(), &token); err != nil {
+		t.Fatalf("Error adding API token for %s: %s", user.Name, err.Error())
+	}
+
+	return key
+} // func testToken(t *testing.T, srv *WebFrontend, user *data.User) string
+
+// formRequest sends a request with the given session cookie, CSRF token and
+// form values to the frontend.
+func formRequest(srv *WebFrontend, method, uri string, cookie *http.Cookie, csrf string, form url.Values) *httptest.ResponseRecorder {
+	var (
+		req = httptest.NewRequest(method, uri, strings.NewReader(form.Encode()))
+		res = httptest.NewRecorder()
+	)
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+
+	if csrf != "" {
+		req.Header.Set(csrfHeader, csrf)
+	}
+
+	srv.router.ServeHTTP(res, req)
+	return res
+} // func formRequest(srv *WebFrontend, method, uri string, cookie *http.Cookie, csrf string, form url.Values) *httptest.ResponseRecorder
+
+func TestLogin(t *testing.T) {
+	var (
+		err    error
+		srv    *WebFrontend
+		db     database.HostDB
+		res    *httptest.ResponseRecorder
+		cookie *http.Cookie
+		s      *data.Session
+	)
+
+	if srv, err = Create("", 4713, nil, nil); err != nil {
+		t.Fatalf("Error creating Web Frontend: %s", err.Error())
+	}
+
+	testUser(t, srv, "viewer", auth.ReadOnly)
+
+	if res = formRequest(srv, "GET", "/api/v1/hosts", nil, "", nil); res.Code != http.StatusUnauthorized {
+		t.Errorf("Anonymous API request returned %d", res.Code)
+	} else if res = formRequest(srv, "GET", "/account", nil, "", nil); res.Code != http.StatusSeeOther {
+		t.Errorf("Anonymous page request returned %d", res.Code)
+	} else if loc := res.Header().Get("Location"); loc != "/login?next=%2Faccount" {
+		t.Errorf("Anonymous page request was redirected to %q", loc)
+	} else if res = formRequest(srv, "GET", "/static/style.css", nil, "", nil); res.Code != http.StatusOK {
+		t.Errorf("Anonymous request for static file returned %d", res.Code)
+	}
+
+	res = formRequest(srv, "POST", "/login", nil, "", url.Values{
+		"name":     {"viewer"},
+		"password": {"wrong password"},
+	})
+
+	if res.Code != http.StatusUnauthorized {
+		t.Errorf("Login with wrong password returned %d", res.Code)
+	} else if len(res.Result().Cookies()) != 0 {
+		t.Error("Login with wrong password set a cookie")
+	}
+
+	res = formRequest(srv, "POST", "/login", nil, "", url.Values{
+		"name":     {"viewer"},
+		"password": {testPassword},
+		"next":     {"//evil.example.com/"},
+	})
+
+	if res.Code != http.StatusSeeOther {
+		t.Fatalf("Login returned %d", res.Code)
+	} else if loc := res.Header().Get("Location"); loc != "/" {
+		t.Errorf("Login redirected to %q", loc)
+	}
+
+	for _, c := range res.Result().Cookies() {
+		if c.Name == sessionCookie {
+			cookie = c
+		}
+	}
+
+	if cookie == nil {
+		t.Fatal("Login did not set a session cookie")
+	} else if !cookie.HttpOnly {
+		t.Error("Session cookie is accessible to JavaScript")
+	}
+
+	db = srv.dbPool.Get()
+	s, err = db.SessionGet(context.Background(), auth.HashToken(cookie.Value))
+	srv.dbPool.Put(db)
+
+	if err != nil {
+		t.Fatalf("Cannot load Session: %s", err.Error())
+	}
+
+	type testCase struct {
+		method, uri string
+		csrf        string
+		form        url.Values
+		status      int
+	}
+
+	var cases = []testCase{
+		{"GET", "/account", "", nil, http.StatusOK},
+		{"GET", "/api/v1/hosts", "", nil, http.StatusOK},
+		// Without a CSRF token, nothing can be changed.
+		{"POST", "/account/token", "", url.Values{"name": {"script"}}, http.StatusForbidden},
+		{"POST", "/account/token", "bogus", url.Values{"name": {"script"}}, http.StatusForbidden},
+		{"POST", "/account/token", s.CSRF, url.Values{"name": {"script"}}, http.StatusOK},
+		{"POST", "/account/password", s.CSRF, url.Values{"old": {"wrong"}, "new": {"0123456789"}, "confirm": {"0123456789"}}, http.StatusBadRequest},
+		// ReadOnly users cannot control workers.
+		{"PUT", "/api/v1/workers/scanner", s.CSRF, nil, http.StatusForbidden},
+		{"POST", "/ajax/spawn_worker/1/1", s.CSRF, nil, http.StatusForbidden},
+		{"POST", "/ajax/update_metadata", s.CSRF, nil, http.StatusForbidden},
+		{"GET", "/ajax/update_metadata", s.CSRF, nil, http.StatusMethodNotAllowed},
+		{"POST", "/logout", s.CSRF, nil, http.StatusSeeOther},
+		{"GET", "/account", "", nil, http.StatusSeeOther},
+	}
+
+	for _, c := range cases {
+		if res = formRequest(srv, c.method, c.uri, cookie, c.csrf, c.form); res.Code != c.status {
+			t.Errorf("%s %s returned %d, expected %d",
+				c.method,
+				c.uri,
+				res.Code,
+				c.status)
+		}
+	}
+} // func TestLogin(t *testing.T)
+
+func TestAPIToken(t *testing.T) {
+	var (
+		err    error
+		srv    *WebFrontend
+		viewer string
+		op     string
+	)
+
+	if srv, err = Create("", 4714, nil, nil); err != nil {
+		t.Fatalf("Error creating Web Frontend: %s", err.Error())
+	}
+
+	viewer = testToken(t, srv, testUser(t, srv, "script", auth.ReadOnly))
+	op = testToken(t, srv, testUser(t, srv, "cron", auth.Operator))
+
+	type testCase struct {
+		token, method, uri, body string
+		status                   int
+	}
+
+	var cases = []testCase{
+		{"", "GET", "/api/v1/hosts", "", http.StatusUnauthorized},
+		{"bogus", "GET", "/api/v1/hosts", "", http.StatusUnauthorized},
+		{viewer, "GET", "/api/v1/hosts", "", http.StatusOK},
+		{viewer, "PUT", "/api/v1/workers/scanner", `{"count":4}`, http.StatusForbidden},
+		// API tokens do not need a CSRF token. The backend is not running
+		// in the test, though.
+		{op, "PUT", "/api/v1/workers/scanner", `{"count":4}`, http.StatusServiceUnavailable},
+	}
+
+	for _, c := range cases {
+		if code := apiRequest(t, srv, c.token, c.method, c.uri, c.body, nil); code != c.status {
+			t.Errorf("%s %s with token %q returned %d, expected %d",
+				c.method,
+				c.uri,
+				c.token,
+				code,
+				c.status)
+		}
+	}
+} // func TestAPIToken(t *testing.T)
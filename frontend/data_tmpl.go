@@ -2,13 +2,17 @@
 // -*- mode: go; coding: utf-8; -*-
 // Created on 31. 10. 2022 by Benjamin Walkenhorst
 // (c) 2022 Benjamin Walkenhorst
-// Time-stamp: <2023-03-18 22:09:54 krylon>
+// Time-stamp: <2026-10-19 15:02:31 krylon>
 
 package frontend
 
 import (
+	"net/http"
+
+	"github.com/blicero/guang/auth"
 	"github.com/blicero/guang/backend/facility"
 	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/search"
 	"github.com/blicero/krylib"
 )
 
@@ -22,8 +26,31 @@ type tmplDataIndex struct {
 	ScanCnt      int
 	HostCnt      int64
 	PortReplyCnt int64
+	User         *data.User
+	CSRFToken    string
 }
 
+// setAuth fills in the User who sent the request and the CSRF token the
+// page has to send back.
+func (d *tmplDataIndex) setAuth(r *http.Request) {
+	var info = authFrom(r)
+
+	if info == nil {
+		return
+	}
+
+	d.User = &info.user
+
+	if info.session != nil {
+		d.CSRFToken = info.session.CSRF
+	}
+} // func (d *tmplDataIndex) setAuth(r *http.Request)
+
+// IsOperator returns true if the User may change things.
+func (d tmplDataIndex) IsOperator() bool {
+	return d.User != nil && d.User.Role.Allows(auth.Operator)
+} // func (d tmplDataIndex) IsOperator() bool
+
 type reportInfoPort struct {
 	Port    uint16
 	Results []data.ScanResult
@@ -34,6 +61,8 @@ type tmplDataByPort struct {
 	Count int
 	Hosts map[krylib.ID]data.Host
 	Ports map[uint16]reportInfoPort
+	Tag   string
+	Tags  []data.Tag
 }
 
 // Donnerstag, 18. 08. 2016, 21:10
@@ -46,4 +75,15 @@ type tmplDataByHost struct {
 	tmplDataIndex
 	Count int
 	Hosts []data.HostWithPorts
+	Tag   string
+	Tags  []data.Tag
+}
+
+// PrevPage and NextPage are 0 if there is no previous or next page.
+type tmplDataSearch struct {
+	tmplDataIndex
+	Query    string
+	Result   *search.Result
+	PrevPage int
+	NextPage int
 }
@@ -0,0 +1,210 @@
+// /home/krylon/go/src/github.com/blicero/guang/frontend/events.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 14:39:07 krylon>
+
+package frontend
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blicero/guang/common"
+	"github.com/blicero/guang/events"
+	"github.com/pquerna/ffjson/ffjson"
+)
+
+// keepaliveInterval is how often we send a comment to clients of the event
+// stream when nothing happens, so proxies do not consider the connection
+// dead.
+// retryInterval is how long a browser waits before reconnecting after
+// losing the connection.
+const (
+	keepaliveInterval = time.Second * 20
+	retryInterval     = time.Second * 5
+)
+
+// formList returns all values of a form field, splitting them at commas,
+// so "?port=22,80&port=443" gives 22, 80 and 443.
+func formList(r *http.Request, key string) []string {
+	var list []string
+
+	for _, value := range r.Form[key] {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+
+	return list
+} // func formList(r *http.Request, key string) []string
+
+// eventFilter builds an events.Filter from the query parameters kind, port,
+// net and replies.
+func eventFilter(r *http.Request) (events.Filter, error) {
+	var (
+		err    error
+		filter events.Filter
+	)
+
+	if err = r.ParseForm(); err != nil {
+		return filter, err
+	}
+
+	for _, name := range formList(r, "kind") {
+		var k events.Kind
+		if k, err = events.ParseKind(name); err != nil {
+			return filter, err
+		}
+		filter.Kinds = append(filter.Kinds, k)
+	}
+
+	for _, s := range formList(r, "port") {
+		var num uint64
+		if num, err = strconv.ParseUint(s, 10, 16); err != nil || num == 0 {
+			return filter, fmt.Errorf("Invalid port number %q", s)
+		}
+		filter.Ports = append(filter.Ports, uint16(num))
+	}
+
+	for _, s := range formList(r, "net") {
+		var n *net.IPNet
+		if _, n, err = net.ParseCIDR(s); err != nil {
+			return filter, fmt.Errorf("Invalid network %q", s)
+		}
+		filter.Networks = append(filter.Networks, n)
+	}
+
+	if s := r.FormValue("replies"); s != "" {
+		if filter.RepliesOnly, err = strconv.ParseBool(s); err != nil {
+			return filter, fmt.Errorf("Invalid value for replies: %q", s)
+		}
+	}
+
+	return filter, nil
+} // func eventFilter(r *http.Request) (events.Filter, error)
+
+func apiEventFrom(ev *events.Event) apiEvent {
+	var ae = apiEvent{
+		ID:    ev.ID,
+		Kind:  ev.Kind.String(),
+		Stamp: ev.Stamp,
+	}
+
+	switch {
+	case ev.Result != nil:
+		var (
+			host = apiHostFrom(&ev.Result.Host)
+			port = apiPort{
+				HostID:    int64(ev.Result.Host.ID),
+				Address:   host.Address,
+				Port:      ev.Result.Port,
+				Timestamp: ev.Result.Stamp,
+				Reply:     ev.Result.Reply,
+			}
+		)
+
+		ae.Host = &host
+		ae.Port = &port
+	case ev.Host != nil:
+		var host = apiHostFrom(ev.Host)
+		ae.Host = &host
+	case ev.XFR != nil:
+		var x = apiXfrFrom(ev.XFR)
+		ae.XFR = &x
+	}
+
+	return ae
+} // func apiEventFrom(ev *events.Event) apiEvent
+
+// handleEvents streams Events from the backend to the client as
+// server-sent events, until the client goes away.
+func (srv *WebFrontend) handleEvents(w http.ResponseWriter, r *http.Request) {
+	var (
+		err       error
+		ok        bool
+		filter    events.Filter
+		flusher   http.Flusher
+		sub       *events.Subscription
+		keepalive *time.Ticker
+		buf       []byte
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	if srv.bus == nil {
+		srv.apiError(w, r, http.StatusServiceUnavailable, "The backend is not running")
+		return
+	} else if filter, err = eventFilter(r); err != nil {
+		srv.apiError(w, r, http.StatusBadRequest, "Invalid filter: %s", err.Error())
+		return
+	} else if flusher, ok = w.(http.Flusher); !ok {
+		srv.apiError(w, r, http.StatusInternalServerError, "Streaming is not supported")
+		return
+	}
+
+	sub = srv.bus.Subscribe(filter, events.DefaultBufferSize)
+	keepalive = time.NewTicker(keepaliveInterval)
+
+	defer func() {
+		keepalive.Stop()
+		sub.Close()
+		srv.log.Printf("[DEBUG] Event stream for %s closed, %d events dropped\n",
+			r.RemoteAddr,
+			sub.Dropped())
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", cacheControl)
+	// Keep nginx from buffering the stream.
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err = fmt.Fprintf(w, "retry: %d\n\n", retryInterval.Milliseconds()); err != nil {
+		return
+	}
+
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepalive.C:
+			_, err = fmt.Fprint(w, ": keepalive\n\n")
+		case ev, open := <-sub.Events():
+			if !open {
+				return
+			}
+
+			var ae = apiEventFrom(&ev)
+
+			if buf, err = ffjson.Marshal(&ae); err != nil {
+				srv.log.Printf("[ERROR] Cannot serialize Event %d: %s\n",
+					ev.ID,
+					err.Error())
+				continue
+			}
+
+			_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n",
+				ev.ID,
+				ae.Kind,
+				buf)
+			ffjson.Pool(buf)
+		}
+
+		if err != nil {
+			return
+		}
+
+		flusher.Flush()
+	}
+} // func (srv *WebFrontend) handleEvents(w http.ResponseWriter, r *http.Request)
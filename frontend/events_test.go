@@ -0,0 +1,129 @@
+// /home/krylon/go/src/github.com/blicero/guang/frontend/events_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 14:39:07 krylon>
+
+package frontend
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blicero/guang/auth"
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/events"
+)
+
+func TestEvents(t *testing.T) {
+	var (
+		err    error
+		srv    *WebFrontend
+		token  string
+		server *httptest.Server
+		req    *http.Request
+		res    *http.Response
+		reply  = "SSH-2.0-OpenSSH_9.6"
+	)
+
+	if srv, err = Create("", 4716, nil, nil); err != nil {
+		t.Fatalf("Error creating Web Frontend: %s", err.Error())
+	}
+
+	token = testToken(t, srv, testUser(t, srv, "events", auth.ReadOnly))
+
+	if code := apiRequest(t, srv, token, "GET", "/events", "", nil); code != http.StatusServiceUnavailable {
+		t.Errorf("GET /events without a backend returned %d", code)
+	}
+
+	srv.bus = events.NewBus()
+
+	if code := apiRequest(t, srv, token, "GET", "/events?kind=bogus", "", nil); code != http.StatusBadRequest {
+		t.Errorf("GET /events with invalid filter returned %d", code)
+	} else if code = apiRequest(t, srv, "", "GET", "/events", "", nil); code != http.StatusUnauthorized {
+		t.Errorf("GET /events without credentials returned %d", code)
+	}
+
+	server = httptest.NewServer(srv.router)
+	defer server.Close()
+
+	req, _ = http.NewRequest("GET", server.URL+"/events?kind=ScanResult&port=22,23&replies=1", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	if res, err = http.DefaultClient.Do(req); err != nil {
+		t.Fatalf("Cannot connect to event stream: %s", err.Error())
+	}
+
+	defer res.Body.Close() // nolint: errcheck
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("GET /events returned %d", res.StatusCode)
+	} else if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Unexpected Content-Type %q", ct)
+	}
+
+	for i := 0; srv.bus.Subscribers() == 0; i++ {
+		if i == 100 {
+			t.Fatal("Event stream did not subscribe to the bus")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	var host = data.Host{
+		ID:      42,
+		Name:    "ssh.example.com",
+		Address: net.ParseIP("192.0.2.22"),
+	}
+
+	// Only the last one passes the filter.
+	srv.bus.Publish(events.Event{Kind: events.HostAdded, Host: &host})
+	srv.bus.Publish(events.Event{
+		Kind:   events.ScanResult,
+		Result: &data.ScanResult{Host: host, Port: 80, Reply: &reply},
+	})
+	srv.bus.Publish(events.Event{
+		Kind:   events.ScanResult,
+		Result: &data.ScanResult{Host: host, Port: 22},
+	})
+	srv.bus.Publish(events.Event{
+		Kind:   events.ScanResult,
+		Result: &data.ScanResult{Host: host, Port: 22, Reply: &reply, Stamp: time.Now()},
+	})
+
+	var (
+		rd        = bufio.NewScanner(res.Body)
+		id, event string
+		ev        apiEvent
+	)
+
+	for rd.Scan() {
+		var line = rd.Text()
+
+		if strings.HasPrefix(line, "id: ") {
+			id = line[4:]
+		} else if strings.HasPrefix(line, "event: ") {
+			event = line[7:]
+		} else if strings.HasPrefix(line, "data: ") {
+			if err = json.Unmarshal([]byte(line[6:]), &ev); err != nil {
+				t.Fatalf("Cannot parse event: %s\n%s", err.Error(), line)
+			}
+			break
+		}
+	}
+
+	if id != "4" {
+		t.Errorf("Unexpected event ID %q", id)
+	} else if event != "ScanResult" {
+		t.Errorf("Unexpected event type %q", event)
+	} else if ev.Host == nil || ev.Host.Name != host.Name {
+		t.Errorf("Unexpected Host in event: %#v", ev.Host)
+	} else if ev.Port == nil || ev.Port.Port != 22 || ev.Port.Reply == nil || *ev.Port.Reply != reply {
+		t.Errorf("Unexpected Port in event: %#v", ev.Port)
+	}
+} // func TestEvents(t *testing.T)
@@ -0,0 +1,89 @@
+// /home/krylon/go/src/github.com/blicero/guang/frontend/export.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 16:22:36 krylon>
+
+package frontend
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/blicero/guang/common"
+	"github.com/blicero/guang/database"
+	"github.com/blicero/guang/export"
+	"github.com/blicero/guang/search"
+)
+
+// This file contains the handler for exporting Hosts and Ports.
+
+// handleAPIExport streams the Hosts matching the filter as CSV, JSON
+// Lines or nmap XML. The filter parameters are those of /api/v1/hosts,
+// plus since and until to restrict the Ports by the time they were
+// scanned.
+func (srv *WebFrontend) handleAPIExport(w http.ResponseWriter, r *http.Request) {
+	var (
+		err    error
+		db     database.HostDB
+		format = export.CSV
+		filter database.ExportFilter
+		cnt    int
+		terms  = []string{r.FormValue("q")}
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	if s := r.FormValue("format"); s != "" {
+		if format, err = export.ParseFormat(s); err != nil {
+			srv.apiError(w, r, http.StatusBadRequest, "%s", err.Error())
+			return
+		}
+	}
+
+	for _, key := range hostFilters {
+		for _, value := range r.Form[key] {
+			terms = append(terms, qualify(key, value))
+		}
+	}
+
+	if filter.Query, err = search.Parse(strings.Join(terms, " ")); err != nil {
+		srv.apiError(w, r, http.StatusBadRequest, "Invalid filter: %s", err.Error())
+		return
+	} else if filter.Since, err = export.ParseTime(r.FormValue("since")); err != nil {
+		srv.apiError(w, r, http.StatusBadRequest, "Invalid since: %s", err.Error())
+		return
+	} else if filter.Until, err = export.ParseTime(r.FormValue("until")); err != nil {
+		srv.apiError(w, r, http.StatusBadRequest, "Invalid until: %s", err.Error())
+		return
+	}
+
+	db = srv.dbPool.Get()
+	defer srv.dbPool.Put(db)
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="guang-%s%s"`,
+			time.Now().Format("20060102-150405"),
+			format.Extension()))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	// Once the export has started, the status can no longer be changed,
+	// so all we can do about an error is log it and cut the export short.
+	if cnt, err = export.Run(r.Context(), db, filter, format, w); err != nil {
+		srv.log.Printf("[ERROR] Export to %s failed after %d Hosts: %s\n",
+			r.RemoteAddr,
+			cnt,
+			err.Error())
+	} else if common.Debug {
+		srv.log.Printf("[DEBUG] Exported %d Hosts as %s to %s\n",
+			cnt,
+			format,
+			r.RemoteAddr)
+	}
+} // func (srv *WebFrontend) handleAPIExport(w http.ResponseWriter, r *http.Request)
@@ -0,0 +1,133 @@
+// /home/krylon/go/src/github.com/blicero/guang/frontend/export_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 17:37:46 krylon>
+
+package frontend
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blicero/guang/auth"
+	"github.com/blicero/guang/backend"
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/database"
+)
+
+func TestExport(t *testing.T) {
+	var (
+		err   error
+		srv   *WebFrontend
+		db    database.HostDB
+		ctx   = context.Background()
+		reply = "220 export.example.org ESMTP Postfix"
+		host  = data.Host{
+			Address: net.ParseIP("198.51.100.81"),
+			Name:    "export.example.org",
+			Source:  data.HostSourceUser,
+		}
+	)
+
+	if srv, err = Create("", 4726, nil, nil); err != nil {
+		t.Fatalf("Error creating Web Frontend: %s", err.Error())
+	}
+
+	var token = testToken(t, srv, testUser(t, srv, "exporter", auth.ReadOnly))
+
+	db = srv.dbPool.Get()
+	if _, err = db.HostUpsert(ctx, &host); err != nil {
+		t.Fatalf("Error adding Host: %s", err.Error())
+	} else if err = db.PortAdd(ctx, &data.ScanResult{
+		Host:  host,
+		Port:  25,
+		Reply: &reply,
+		Stamp: time.Now(),
+	}); err != nil {
+		t.Fatalf("Error adding Port: %s", err.Error())
+	}
+	srv.dbPool.Put(db)
+
+	var get = func(uri string) *httptest.ResponseRecorder {
+		var (
+			req = httptest.NewRequest("GET", uri, nil)
+			rec = httptest.NewRecorder()
+		)
+
+		req.Header.Set("Authorization", "Bearer "+token)
+		srv.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	var rec = get("/api/v1/export?format=jsonl&net=198.51.100.80/29&since=2020-01-01")
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /api/v1/export returned %d: %s", rec.Code, rec.Body.String())
+	} else if ct := rec.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Unexpected Content-Type %q", ct)
+	} else if cd := rec.Header().Get("Content-Disposition"); !strings.HasSuffix(cd, `.jsonl"`) {
+		t.Errorf("Unexpected Content-Disposition %q", cd)
+	}
+
+	var (
+		lines = strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+		rec0  struct {
+			Address string `json:"address"`
+			Ports   []struct {
+				Port  uint16 `json:"port"`
+				Reply string `json:"reply"`
+			} `json:"ports"`
+		}
+	)
+
+	if len(lines) != 1 {
+		t.Fatalf("Expected 1 Host, got %d lines:\n%s", len(lines), rec.Body.String())
+	} else if err = json.Unmarshal([]byte(lines[0]), &rec0); err != nil {
+		t.Fatalf("Cannot decode %s: %s", lines[0], err.Error())
+	} else if rec0.Address != host.Address.String() || len(rec0.Ports) != 1 || rec0.Ports[0].Reply != reply {
+		t.Errorf("Unexpected Host: %s", lines[0])
+	}
+
+	// Ports scanned before the time range leave nothing to export.
+	if rec = get("/api/v1/export?format=csv&net=198.51.100.80/29&until=2020-01-01"); rec.Code != http.StatusOK {
+		t.Errorf("GET /api/v1/export as CSV returned %d", rec.Code)
+	} else if n := strings.Count(rec.Body.String(), "\n"); n != 1 {
+		t.Errorf("Expected only the header, got %d lines:\n%s", n, rec.Body.String())
+	}
+
+	for _, uri := range []string{
+		"/api/v1/export?format=pdf",
+		"/api/v1/export?since=yesterday",
+		"/api/v1/export?net=not-a-network",
+	} {
+		if rec = get(uri); rec.Code != http.StatusBadRequest {
+			t.Errorf("GET %s returned %d, expected %d", uri, rec.Code, http.StatusBadRequest)
+		}
+	}
+
+	// The query ends up in the export links on the search page, which
+	// needs a Nexus to show the number of workers.
+	if srv.nexus, err = backend.CreateNexus(nil, nil, nil, nil); err != nil {
+		t.Fatalf("Error creating Nexus: %s", err.Error())
+	}
+
+	defer srv.nexus.Close()
+
+	var query = url.QueryEscape(`banner:"\"><script>alert(1)</script>" & #`)
+
+	if rec = get("/search?q=" + query); rec.Code != http.StatusOK {
+		t.Errorf("GET /search returned %d", rec.Code)
+	} else if body := rec.Body.String(); strings.Contains(body, "<script>alert") {
+		t.Error("The search page contains the query unescaped")
+	} else if !strings.Contains(body, "format=csv&amp;q="+query+`"`) {
+		t.Errorf("The export link does not carry the escaped query:\n%s", body)
+	}
+} // func TestExport(t *testing.T)
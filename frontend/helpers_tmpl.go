@@ -2,7 +2,7 @@
 // -*- mode: go; coding: utf-8; -*-
 // Created on 31. 10. 2022 by Benjamin Walkenhorst
 // (c) 2022 Benjamin Walkenhorst
-// Time-stamp: <2022-10-31 22:20:32 krylon>
+// Time-stamp: <2026-10-19 15:48:35 krylon>
 
 package frontend
 
@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"html"
 	"html/template"
+	"net/url"
 	"os"
 	"regexp"
 	"strconv"
@@ -18,6 +19,7 @@ import (
 	"time"
 
 	"github.com/blicero/guang/common"
+	"github.com/blicero/guang/data"
 	"github.com/mborgerson/GoTruncateHtml/truncatehtml"
 )
 
@@ -37,6 +39,7 @@ var funcmap = template.FuncMap{
 	"fmt_time":         formatTime,
 	"fmt_time_minute":  formatTimeMinute,
 	"fmt_float":        formatFloat,
+	"fmt_percent":      formatPercent,
 	"current_year":     currentYear,
 	"minutes":          minutes,
 	"lower":            lower,
@@ -54,6 +57,7 @@ var funcmap = template.FuncMap{
 	"truncate":         truncateHTML,
 	"intRange":         intRange,
 	"inc":              inc,
+	"vuln_url":         vulnURL,
 }
 
 type generator struct {
@@ -170,6 +174,21 @@ func formatFloat(f float64) string {
 	return fmt.Sprintf("%.1f", f)
 } // func formatFloat(f float64) string
 
+// formatPercent formats a fraction between 0 and 1 as a percentage.
+func formatPercent(f float64) string {
+	return fmt.Sprintf("%.0f%%", f*100)
+} // func formatPercent(f float64) string
+
+// vulnURL returns the URL of the page describing the Vulnerability at its
+// source.
+func vulnURL(v data.Vulnerability) string {
+	if v.Source == "NVD" {
+		return "https://nvd.nist.gov/vuln/detail/" + url.PathEscape(v.Name)
+	}
+
+	return "https://osv.dev/vulnerability/" + url.PathEscape(v.Name)
+} // func vulnURL(v data.Vulnerability) string
+
 func currentYear() string {
 	var year = time.Now().Year()
 	return strconv.Itoa(year)
@@ -2,11 +2,17 @@
 // -*- mode: go; coding: utf-8; -*-
 // Created on 03. 11. 2022 by Benjamin Walkenhorst
 // (c) 2022 Benjamin Walkenhorst
-// Time-stamp: <2022-11-07 19:01:02 krylon>
+// Time-stamp: <2026-10-19 14:07:53 krylon>
 
 package frontend
 
-import "time"
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/blicero/guang/search"
+)
 
 // Methods that are not directly handling HTTP requests live here.
 
@@ -22,3 +28,22 @@ func (srv *WebFrontend) updateCkPortstamp(t time.Time) {
 	srv.ckPortStamp = t
 	srv.lock.Unlock()
 } // func (srv *WebFrontend) updateCkPortstamp(t time.Time)
+
+// pageParams extracts the page number and the number of items per page
+// from a request's "page" and "per_page" parameters. Missing or invalid
+// values are replaced with defaults.
+func pageParams(r *http.Request) (page, perPage int) {
+	var err error
+
+	if page, err = strconv.Atoi(r.FormValue("page")); err != nil || page < 1 {
+		page = 1
+	}
+
+	if perPage, err = strconv.Atoi(r.FormValue("per_page")); err != nil || perPage < 1 {
+		perPage = search.DefaultPerPage
+	} else if perPage > search.MaxPerPage {
+		perPage = search.MaxPerPage
+	}
+
+	return page, perPage
+} // func pageParams(r *http.Request) (page, perPage int)
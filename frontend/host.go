@@ -0,0 +1,416 @@
+// /home/krylon/go/src/github.com/blicero/guang/frontend/host.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 16:04:01 krylon>
+
+package frontend
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blicero/guang/backend/facility"
+	"github.com/blicero/guang/common"
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/database"
+	"github.com/blicero/guang/fingerprint"
+	"github.com/blicero/krylib"
+	"github.com/gorilla/mux"
+)
+
+const msgNoBackend = "The backend is not running"
+
+type tmplDataHost struct {
+	tmplDataIndex
+	Host       *data.Host
+	Ports      []data.Port
+	OSEvidence []data.OSEvidence
+	XFR        *data.XFR
+	Tags       []data.Tag
+	Notes      []data.Note
+	Findings   []data.Finding
+	Vulns      []data.VulnMatch
+	Message    string
+}
+
+// renderHost shows the detail page of the Host with the given ID.
+func (srv *WebFrontend) renderHost(w http.ResponseWriter, r *http.Request, id krylib.ID, status int, msg string, errs ...string) {
+	var (
+		err      error
+		db       database.HostDB
+		tmplData = tmplDataHost{
+			tmplDataIndex: tmplDataIndex{
+				Debug:      common.Debug,
+				Facilities: facility.All(),
+				Error:      errs,
+			},
+			Message: msg,
+		}
+	)
+
+	tmplData.setAuth(r)
+
+	db = srv.dbPool.Get()
+	defer srv.dbPool.Put(db)
+
+	if tmplData.Host, err = db.HostGetByID(r.Context(), id); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			http.NotFound(w, r)
+		} else {
+			srv.sendErrorMessage(w, fmt.Sprintf("Cannot load Host #%d: %s", id, err.Error()))
+		}
+		return
+	} else if tmplData.Ports, err = db.PortGetByHost(r.Context(), id); err != nil {
+		srv.sendErrorMessage(w, fmt.Sprintf("Cannot load Ports of Host #%d: %s", id, err.Error()))
+		return
+	} else if tmplData.Tags, err = db.HostTagGet(r.Context(), id); err != nil {
+		srv.sendErrorMessage(w, fmt.Sprintf("Cannot load Tags of Host #%d: %s", id, err.Error()))
+		return
+	} else if tmplData.Notes, err = db.NoteGetByHost(r.Context(), id); err != nil {
+		srv.sendErrorMessage(w, fmt.Sprintf("Cannot load Notes of Host #%d: %s", id, err.Error()))
+		return
+	} else if tmplData.Findings, err = db.FindingGetByHost(r.Context(), id); err != nil {
+		srv.sendErrorMessage(w, fmt.Sprintf("Cannot load Findings of Host #%d: %s", id, err.Error()))
+		return
+	} else if tmplData.Vulns, err = db.VulnGetByHost(r.Context(), id); err != nil {
+		srv.sendErrorMessage(w, fmt.Sprintf("Cannot load Vulnerabilities of Host #%d: %s", id, err.Error()))
+		return
+	} else if tmplData.OSEvidence, err = db.HostGetOSEvidence(r.Context(), id); err != nil {
+		srv.sendErrorMessage(w, fmt.Sprintf("Cannot load OS evidence of Host #%d: %s", id, err.Error()))
+		return
+	}
+
+	tmplData.Title = fmt.Sprintf("Host %s", tmplData.Host.Address)
+
+	if tmplData.Host.OS == "" {
+		// The metadata of the Host was not updated yet, so we make a
+		// guess on the spot.
+		var guess = fingerprint.Guess(tmplData.Ports)
+
+		tmplData.Host.OSVersion = guess.Version
+		tmplData.Host.OSConfidence = guess.Confidence
+		tmplData.OSEvidence = guess.Evidence
+		if guess.OS != fingerprint.Unknown {
+			tmplData.Host.OS = guess.OS
+		}
+	}
+
+	// Hosts that came from a zone transfer do not remember which one, so we
+	// look for the zone their names belong to.
+	for _, n := range tmplData.Host.Names {
+		if tmplData.XFR, err = db.XfrGetByName(r.Context(), n.Name); err == nil {
+			break
+		} else if !errors.Is(err, database.ErrNotFound) {
+			srv.log.Printf("[ERROR] Cannot look up zone of %s: %s\n",
+				n.Name,
+				err.Error())
+		}
+	}
+
+	srv.render(w, "host", status, tmplData)
+} // func (srv *WebFrontend) renderHost(w http.ResponseWriter, r *http.Request, id krylib.ID, status int, msg string, errs ...string)
+
+// loadHost loads the Host the request refers to. If that fails, it sends an
+// error to the client and returns nil.
+func (srv *WebFrontend) loadHost(w http.ResponseWriter, r *http.Request) *data.Host {
+	var (
+		err  error
+		id   int64
+		db   database.HostDB
+		host *data.Host
+	)
+
+	if id, err = strconv.ParseInt(mux.Vars(r)["id"], 10, 64); err != nil {
+		http.Error(w, "Invalid Host ID", http.StatusBadRequest)
+		return nil
+	}
+
+	db = srv.dbPool.Get()
+	host, err = db.HostGetByID(r.Context(), krylib.ID(id))
+	srv.dbPool.Put(db)
+
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			http.NotFound(w, r)
+		} else {
+			srv.sendErrorMessage(w, fmt.Sprintf("Cannot load Host #%d: %s", id, err.Error()))
+		}
+		return nil
+	}
+
+	return host
+} // func (srv *WebFrontend) loadHost(w http.ResponseWriter, r *http.Request) *data.Host
+
+func (srv *WebFrontend) handleHost(w http.ResponseWriter, r *http.Request) {
+	var (
+		err error
+		id  int64
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	if id, err = strconv.ParseInt(mux.Vars(r)["id"], 10, 64); err != nil {
+		http.Error(w, "Invalid Host ID", http.StatusBadRequest)
+		return
+	}
+
+	srv.renderHost(w, r, krylib.ID(id), http.StatusOK, "")
+} // func (srv *WebFrontend) handleHost(w http.ResponseWriter, r *http.Request)
+
+func (srv *WebFrontend) handleHostRescan(w http.ResponseWriter, r *http.Request) {
+	var (
+		err  error
+		host *data.Host
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	if host = srv.loadHost(w, r); host == nil {
+		return
+	} else if srv.nexus == nil {
+		srv.renderHost(w, r, host.ID, http.StatusServiceUnavailable, "", msgNoBackend)
+		return
+	} else if err = srv.nexus.Rescan(*host); err != nil {
+		srv.renderHost(w, r, host.ID, http.StatusServiceUnavailable, "",
+			fmt.Sprintf("Cannot rescan Host: %s", err.Error()))
+		return
+	}
+
+	srv.renderHost(w, r, host.ID, http.StatusOK, "The Host is being scanned again")
+} // func (srv *WebFrontend) handleHostRescan(w http.ResponseWriter, r *http.Request)
+
+func (srv *WebFrontend) handleHostMetadata(w http.ResponseWriter, r *http.Request) {
+	var (
+		err  error
+		db   database.HostDB
+		host *data.Host
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	if host = srv.loadHost(w, r); host == nil {
+		return
+	} else if srv.nexus == nil {
+		srv.renderHost(w, r, host.ID, http.StatusServiceUnavailable, "", msgNoBackend)
+		return
+	}
+
+	db = srv.dbPool.Get()
+	err = srv.nexus.UpdateHostMetadata(r.Context(), db, host)
+	srv.dbPool.Put(db)
+
+	if err != nil {
+		srv.renderHost(w, r, host.ID, http.StatusInternalServerError, "",
+			fmt.Sprintf("Cannot update metadata: %s", err.Error()))
+		return
+	}
+
+	srv.renderHost(w, r, host.ID, http.StatusOK, "The metadata was updated")
+} // func (srv *WebFrontend) handleHostMetadata(w http.ResponseWriter, r *http.Request)
+
+func (srv *WebFrontend) handleHostExclude(w http.ResponseWriter, r *http.Request) {
+	var (
+		err  error
+		db   database.HostDB
+		host *data.Host
+		ex   data.Exclusion
+		bits = 128
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	if host = srv.loadHost(w, r); host == nil {
+		return
+	} else if srv.nexus == nil {
+		srv.renderHost(w, r, host.ID, http.StatusServiceUnavailable, "", msgNoBackend)
+		return
+	}
+
+	if host.Address.To4() != nil {
+		bits = 32
+	}
+
+	ex = data.Exclusion{
+		Added:   time.Now(),
+		Comment: strings.TrimSpace(r.PostFormValue("comment")),
+	}
+
+	if r.PostFormValue("scope") == "as" {
+		if host.AS.ASN == 0 {
+			srv.renderHost(w, r, host.ID, http.StatusBadRequest, "",
+				"The autonomous system of the Host is unknown")
+			return
+		}
+		ex.ASN = host.AS.ASN
+	} else {
+		ex.Network = &net.IPNet{
+			IP:   host.Address,
+			Mask: net.CIDRMask(bits, bits),
+		}
+	}
+
+	db = srv.dbPool.Get()
+	err = srv.nexus.Exclude(r.Context(), db, &ex)
+	srv.dbPool.Put(db)
+
+	if err != nil {
+		srv.renderHost(w, r, host.ID, http.StatusInternalServerError, "",
+			fmt.Sprintf("Cannot exclude Host: %s", err.Error()))
+		return
+	}
+
+	srv.renderHost(w, r, host.ID, http.StatusOK,
+		fmt.Sprintf("%s is excluded from now on", ex.Target()))
+} // func (srv *WebFrontend) handleHostExclude(w http.ResponseWriter, r *http.Request)
+
+func (srv *WebFrontend) handleHostNote(w http.ResponseWriter, r *http.Request) {
+	var (
+		err  error
+		db   database.HostDB
+		host *data.Host
+		note data.Note
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	if host = srv.loadHost(w, r); host == nil {
+		return
+	}
+
+	note = data.Note{
+		HostID: host.ID,
+		Author: authFrom(r).user.Name,
+		Stamp:  time.Now(),
+		Body:   strings.TrimSpace(r.PostFormValue("body")),
+	}
+
+	if note.Body == "" {
+		srv.renderHost(w, r, host.ID, http.StatusBadRequest, "", "The note is empty")
+		return
+	}
+
+	db = srv.dbPool.Get()
+	err = db.NoteAdd(r.Context(), &note)
+	srv.dbPool.Put(db)
+
+	if err != nil {
+		srv.sendErrorMessage(w, fmt.Sprintf("Cannot add Note: %s", err.Error()))
+		return
+	}
+
+	srv.renderHost(w, r, host.ID, http.StatusOK, "The note was added")
+} // func (srv *WebFrontend) handleHostNote(w http.ResponseWriter, r *http.Request)
+
+func (srv *WebFrontend) handleHostTag(w http.ResponseWriter, r *http.Request) {
+	var (
+		err  error
+		db   database.HostDB
+		host *data.Host
+		name = strings.TrimSpace(r.PostFormValue("tag"))
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	if host = srv.loadHost(w, r); host == nil {
+		return
+	} else if name == "" {
+		srv.renderHost(w, r, host.ID, http.StatusBadRequest, "", "Please give the tag a name")
+		return
+	}
+
+	db = srv.dbPool.Get()
+	_, err = db.HostTagAdd(r.Context(), host.ID, name)
+	srv.dbPool.Put(db)
+
+	if err != nil {
+		srv.sendErrorMessage(w, fmt.Sprintf("Cannot add Tag: %s", err.Error()))
+		return
+	}
+
+	srv.renderHost(w, r, host.ID, http.StatusOK, "The tag was added")
+} // func (srv *WebFrontend) handleHostTag(w http.ResponseWriter, r *http.Request)
+
+func (srv *WebFrontend) handleHostUntag(w http.ResponseWriter, r *http.Request) {
+	var (
+		err  error
+		db   database.HostDB
+		host *data.Host
+		name = strings.TrimSpace(r.PostFormValue("tag"))
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	if host = srv.loadHost(w, r); host == nil {
+		return
+	}
+
+	db = srv.dbPool.Get()
+	err = db.HostTagDelete(r.Context(), host.ID, name)
+	srv.dbPool.Put(db)
+
+	if errors.Is(err, database.ErrNotFound) {
+		srv.renderHost(w, r, host.ID, http.StatusNotFound, "",
+			fmt.Sprintf("The Host is not tagged %s", name))
+		return
+	} else if err != nil {
+		srv.sendErrorMessage(w, fmt.Sprintf("Cannot remove Tag: %s", err.Error()))
+		return
+	}
+
+	srv.renderHost(w, r, host.ID, http.StatusOK, "The tag was removed")
+} // func (srv *WebFrontend) handleHostUntag(w http.ResponseWriter, r *http.Request)
+
+func (srv *WebFrontend) handleHostNoteDelete(w http.ResponseWriter, r *http.Request) {
+	var (
+		err    error
+		db     database.HostDB
+		host   *data.Host
+		noteID int64
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	if host = srv.loadHost(w, r); host == nil {
+		return
+	} else if noteID, err = strconv.ParseInt(mux.Vars(r)["note"], 10, 64); err != nil {
+		http.Error(w, "Invalid Note ID", http.StatusBadRequest)
+		return
+	}
+
+	db = srv.dbPool.Get()
+	err = db.NoteDelete(r.Context(), host.ID, krylib.ID(noteID))
+	srv.dbPool.Put(db)
+
+	if errors.Is(err, database.ErrNotFound) {
+		srv.renderHost(w, r, host.ID, http.StatusNotFound, "",
+			fmt.Sprintf("The Host has no note #%d", noteID))
+		return
+	} else if err != nil {
+		srv.sendErrorMessage(w, fmt.Sprintf("Cannot delete Note: %s", err.Error()))
+		return
+	}
+
+	srv.renderHost(w, r, host.ID, http.StatusOK, "The note was deleted")
+} // func (srv *WebFrontend) handleHostNoteDelete(w http.ResponseWriter, r *http.Request)
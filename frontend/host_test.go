@@ -0,0 +1,271 @@
+// /home/krylon/go/src/github.com/blicero/guang/frontend/host_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 17:40:14 krylon>
+
+package frontend
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blicero/guang/auth"
+	"github.com/blicero/guang/backend"
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/database"
+)
+
+func TestHostPage(t *testing.T) {
+	var (
+		err    error
+		srv    *WebFrontend
+		db     database.HostDB
+		tags   []data.Tag
+		notes  []data.Note
+		ctx    = context.Background()
+		banner = "220 ftp.example.org FTP server (Version 6.00LS) ready. <FreeBSD>"
+		host   = data.Host{
+			Address: net.ParseIP("198.51.100.21"),
+			Name:    "ftp.example.org",
+			Source:  data.HostSourceUser,
+		}
+	)
+
+	if srv, err = Create("", 4719, nil, nil); err != nil {
+		t.Fatalf("Error creating Web Frontend: %s", err.Error())
+	}
+
+	var (
+		viewer = testToken(t, srv, testUser(t, srv, "hostviewer", auth.ReadOnly))
+		op     = testToken(t, srv, testUser(t, srv, "hostop", auth.Operator))
+	)
+
+	db = srv.dbPool.Get()
+	if _, err = db.HostUpsert(ctx, &host); err != nil {
+		t.Fatalf("Error adding Host: %s", err.Error())
+	} else if err = db.PortAdd(ctx, &data.ScanResult{
+		Host:  host,
+		Port:  21,
+		Reply: &banner,
+		Stamp: time.Now(),
+	}); err != nil {
+		t.Fatalf("Error adding Port: %s", err.Error())
+	}
+	srv.dbPool.Put(db)
+
+	var post = func(token, uri string, form url.Values) *httptest.ResponseRecorder {
+		var (
+			req = httptest.NewRequest("POST", uri, strings.NewReader(form.Encode()))
+			res = httptest.NewRecorder()
+		)
+
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Authorization", "Bearer "+token)
+		srv.router.ServeHTTP(res, req)
+		return res
+	}
+
+	var (
+		res  *httptest.ResponseRecorder
+		page = fmt.Sprintf("/host/%d", host.ID)
+	)
+
+	if code := apiRequest(t, srv, viewer, "GET", page, "", nil); code != http.StatusOK {
+		t.Errorf("GET %s returned %d", page, code)
+	} else if code = apiRequest(t, srv, viewer, "GET", "/host/999999", "", nil); code != http.StatusNotFound {
+		t.Errorf("GET /host/999999 returned %d", code)
+	}
+
+	if res = post(viewer, page+"/note", url.Values{"body": {"Should not work"}}); res.Code != http.StatusForbidden {
+		t.Errorf("Note by ReadOnly user returned %d", res.Code)
+	} else if res = post(op, page+"/note", url.Values{"body": {"Ancient FTP server"}}); res.Code != http.StatusOK {
+		t.Errorf("Note by Operator returned %d", res.Code)
+	} else if !strings.Contains(res.Body.String(), "Ancient FTP server") {
+		t.Error("The new note is not shown on the page")
+	} else if !strings.Contains(res.Body.String(), "FreeBSD") {
+		t.Error("The OS evidence is not shown on the page")
+	} else if res = post(op, page+"/note", url.Values{"body": {"  "}}); res.Code != http.StatusBadRequest {
+		t.Errorf("Empty note returned %d", res.Code)
+	} else if res = post(op, page+"/tag", url.Values{"tag": {"Legacy"}}); res.Code != http.StatusOK {
+		t.Errorf("Tag by Operator returned %d", res.Code)
+	} else if res = post(op, page+"/rescan", nil); res.Code != http.StatusServiceUnavailable {
+		t.Errorf("Rescan without backend returned %d", res.Code)
+	} else if res = post(op, "/host/999999/tag", url.Values{"tag": {"nope"}}); res.Code != http.StatusNotFound {
+		t.Errorf("Tag on missing Host returned %d", res.Code)
+	} else if res = post(op, page+"/tag", url.Values{"tag": {"doomed"}}); res.Code != http.StatusOK {
+		t.Errorf("Tag by Operator returned %d", res.Code)
+	} else if res = post(op, page+"/untag", url.Values{"tag": {"doomed"}}); res.Code != http.StatusOK {
+		t.Errorf("Removing Tag returned %d", res.Code)
+	} else if res = post(op, page+"/untag", url.Values{"tag": {"doomed"}}); res.Code != http.StatusNotFound {
+		t.Errorf("Removing Tag twice returned %d", res.Code)
+	}
+
+	db = srv.dbPool.Get()
+	defer srv.dbPool.Put(db)
+
+	if tags, err = db.HostTagGet(ctx, host.ID); err != nil {
+		t.Fatalf("Cannot load Tags: %s", err.Error())
+	} else if len(tags) != 1 || tags[0].Name != "legacy" {
+		t.Errorf("Unexpected Tags: %#v", tags)
+	} else if notes, err = db.NoteGetByHost(ctx, host.ID); err != nil {
+		t.Fatalf("Cannot load Notes: %s", err.Error())
+	} else if len(notes) != 1 || notes[0].Author != "hostop" {
+		t.Errorf("Unexpected Notes: %#v", notes)
+	}
+} // func TestHostPage(t *testing.T)
+
+func TestAPITagsAndNotes(t *testing.T) {
+	var (
+		err   error
+		srv   *WebFrontend
+		db    database.HostDB
+		tags  []string
+		notes []apiNote
+		note  apiNote
+		hosts apiHostList
+		ctx   = context.Background()
+		host  = data.Host{
+			Address: net.ParseIP("198.51.100.22"),
+			Name:    "tagged.example.org",
+			Source:  data.HostSourceUser,
+		}
+	)
+
+	if srv, err = Create("", 4720, nil, nil); err != nil {
+		t.Fatalf("Error creating Web Frontend: %s", err.Error())
+	}
+
+	var (
+		viewer = testToken(t, srv, testUser(t, srv, "tagviewer", auth.ReadOnly))
+		op     = testToken(t, srv, testUser(t, srv, "tagop", auth.Operator))
+	)
+
+	db = srv.dbPool.Get()
+	if _, err = db.HostUpsert(ctx, &host); err != nil {
+		t.Fatalf("Error adding Host: %s", err.Error())
+	}
+	srv.dbPool.Put(db)
+
+	var (
+		base = fmt.Sprintf("/api/v1/hosts/%d", host.ID)
+		code int
+	)
+
+	if code = apiRequest(t, srv, viewer, "PUT", base+"/tags/dmz", "", nil); code != http.StatusForbidden {
+		t.Errorf("Tagging by ReadOnly user returned %d", code)
+	} else if code = apiRequest(t, srv, op, "PUT", base+"/tags/DMZ", "", &tags); code != http.StatusOK {
+		t.Errorf("Tagging by Operator returned %d", code)
+	} else if code = apiRequest(t, srv, op, "PUT", base+"/tags/printer", "", &tags); code != http.StatusOK {
+		t.Errorf("Tagging by Operator returned %d", code)
+	} else if len(tags) != 2 || tags[0] != "dmz" || tags[1] != "printer" {
+		t.Errorf("Unexpected Tags: %v", tags)
+	} else if code = apiRequest(t, srv, viewer, "GET", "/api/v1/hosts?tag=printer", "", &hosts); code != http.StatusOK {
+		t.Errorf("Filtering Hosts by Tag returned %d", code)
+	} else if hosts.Total != 1 || hosts.Items[0].ID != int64(host.ID) {
+		t.Errorf("Unexpected Hosts tagged printer: %#v", hosts)
+	} else if code = apiRequest(t, srv, op, "DELETE", base+"/tags/printer", "", &tags); code != http.StatusOK {
+		t.Errorf("Removing Tag returned %d", code)
+	} else if code = apiRequest(t, srv, op, "DELETE", base+"/tags/printer", "", nil); code != http.StatusNotFound {
+		t.Errorf("Removing Tag twice returned %d", code)
+	} else if code = apiRequest(t, srv, viewer, "GET", "/api/v1/tags", "", &tags); code != http.StatusOK {
+		t.Errorf("GET /api/v1/tags returned %d", code)
+	} else if all := "," + strings.Join(tags, ",") + ","; !strings.Contains(all, ",dmz,") || strings.Contains(all, ",printer,") {
+		// Other tests share the database, so there may be more Tags.
+		t.Errorf("Unexpected Tags: %v", tags)
+	} else if code = apiRequest(t, srv, op, "PUT", "/api/v1/hosts/999999/tags/dmz", "", nil); code != http.StatusNotFound {
+		t.Errorf("Tagging missing Host returned %d", code)
+	}
+
+	if code = apiRequest(t, srv, viewer, "POST", base+"/notes", `{"body":"nope"}`, nil); code != http.StatusForbidden {
+		t.Errorf("Note by ReadOnly user returned %d", code)
+	} else if code = apiRequest(t, srv, op, "POST", base+"/notes", `{"body":"  "}`, nil); code != http.StatusBadRequest {
+		t.Errorf("Empty note returned %d", code)
+	} else if code = apiRequest(t, srv, op, "POST", base+"/notes", `{"body":"Belongs to accounting"}`, &note); code != http.StatusCreated {
+		t.Errorf("Note by Operator returned %d", code)
+	} else if note.Author != "tagop" || note.HostID != int64(host.ID) {
+		t.Errorf("Unexpected Note: %#v", note)
+	} else if code = apiRequest(t, srv, viewer, "GET", base+"/notes", "", &notes); code != http.StatusOK {
+		t.Errorf("GET notes returned %d", code)
+	} else if len(notes) != 1 || notes[0].ID != note.ID {
+		t.Errorf("Unexpected Notes: %#v", notes)
+	} else if code = apiRequest(t, srv, op, "DELETE", fmt.Sprintf("%s/notes/%d", base, note.ID), "", nil); code != http.StatusNoContent {
+		t.Errorf("Deleting Note returned %d", code)
+	} else if code = apiRequest(t, srv, op, "DELETE", fmt.Sprintf("%s/notes/%d", base, note.ID), "", nil); code != http.StatusNotFound {
+		t.Errorf("Deleting Note twice returned %d", code)
+	}
+} // func TestAPITagsAndNotes(t *testing.T)
+
+func TestByHostEscapesNames(t *testing.T) {
+	const evil = `<script>alert(1)</script>.example.org`
+
+	var (
+		err    error
+		srv    *WebFrontend
+		db     database.HostDB
+		ctx    = context.Background()
+		banner = "SSH-2.0-OpenSSH_9.2p1"
+		hosts  = []data.Host{
+			{
+				Address: net.ParseIP("198.51.100.31"),
+				Name:    "mail.example.org",
+				Source:  data.HostSourceMx,
+			},
+			{
+				Address: net.ParseIP("198.51.100.31"),
+				Name:    evil,
+				Source:  data.HostSourceA,
+			},
+		}
+	)
+
+	if srv, err = Create("", 4729, nil, nil); err != nil {
+		t.Fatalf("Error creating Web Frontend: %s", err.Error())
+	} else if srv.nexus, err = backend.CreateNexus(nil, nil, nil, nil); err != nil {
+		t.Fatalf("Error creating Nexus: %s", err.Error())
+	}
+
+	defer srv.nexus.Close()
+
+	var viewer = testToken(t, srv, testUser(t, srv, "namereader", auth.ReadOnly))
+
+	db = srv.dbPool.Get()
+	for i := range hosts {
+		if _, err = db.HostUpsert(ctx, &hosts[i]); err != nil {
+			t.Fatalf("Error adding Host %s: %s", hosts[i].Name, err.Error())
+		}
+	}
+
+	if err = db.PortAdd(ctx, &data.ScanResult{
+		Host:  hosts[0],
+		Port:  22,
+		Reply: &banner,
+		Stamp: time.Now(),
+	}); err != nil {
+		t.Fatalf("Error adding Port: %s", err.Error())
+	}
+	srv.dbPool.Put(db)
+
+	var (
+		req = httptest.NewRequest("GET", "/by_host", nil)
+		rec = httptest.NewRecorder()
+	)
+
+	req.Header.Set("Authorization", "Bearer "+viewer)
+	srv.router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /by_host returned %d", rec.Code)
+	} else if body := rec.Body.String(); strings.Contains(body, "<script>alert") {
+		t.Error("The host name was written into the page unescaped")
+	} else if !strings.Contains(body, "&lt;script&gt;alert(1)&lt;/script&gt;.example.org") {
+		t.Error("The alternate host name is missing from the page")
+	}
+} // func TestByHostEscapesNames(t *testing.T)
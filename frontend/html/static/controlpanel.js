@@ -1,6 +1,6 @@
 // /home/krylon/go/src/github.com/blicero/guang/frontend/html/static/controlpanel.js
 // -*- mode: javascript; coding: utf-8; -*-
-// Time-stamp: <2023-01-10 20:14:53 krylon>
+// Time-stamp: <2026-10-19 14:47:35 krylon>
 // Copyright 2022 Benjamin Walkenhorst
 
 'use strict'
@@ -26,7 +26,7 @@ const amtID = {
 function workerSpawn(fac) {
     const amt = $(amtID[fac])[0].value
     const addr = `/ajax/spawn_worker/${facilities[fac]}/${amt}`
-    const req = $.get(addr,
+    const req = $.post(addr,
                       {},
                       (res) => {
                           if (res.Status) {
@@ -51,7 +51,7 @@ function workerStop(fac) {
     const amt = $(amtID[fac])[0].value
     const addr = `/ajax/stop_worker/${facilities[fac]}/${amt}`
 
-    const req = $.get(
+    const req = $.post(
         addr,
         {},
         (res) => {
@@ -104,3 +104,61 @@ function loadWorkerCount() {
         window.setTimeout(loadWorkerCount, 2500)
     }
 } // function loadWorkerCount()
+
+const logLevels = [
+    'TRACE',
+    'DEBUG',
+    'INFO',
+    'WARN',
+    'ERROR',
+    'CRITICAL',
+    'CANTHAPPEN',
+    'SILENT',
+]
+
+function loadLogLevels() {
+    $.get(
+        '/api/v1/loglevels',
+        {},
+        (res) => {
+            const body = $('#loglevels')
+            body.empty()
+
+            for (const item of res) {
+                const sel = $('<select class="form-select form-select-sm"></select>')
+                for (const lvl of logLevels) {
+                    sel.append($('<option></option>').val(lvl).text(lvl))
+                }
+                sel.val(item.level)
+                sel.on('change', () => { logLevelSet(item.domain, sel.val()) })
+
+                const row = $('<tr></tr>')
+                row.append($('<th></th>').text(item.domain))
+                row.append($('<td></td>').append(sel))
+                body.append(row)
+            }
+        },
+        'json'
+    ).fail((reply, status, txt) => {
+        const msg = `Failed to load log levels: ${status} -- ${reply} -- ${txt}`
+        console.log(msg)
+        appendMsg(msg)
+    })
+} // function loadLogLevels()
+
+function logLevelSet(domain, level) {
+    $.ajax({
+        url: `/api/v1/loglevels/${encodeURIComponent(domain)}`,
+        method: 'PUT',
+        contentType: 'application/json',
+        data: JSON.stringify({ level: level }),
+        dataType: 'json',
+    }).done((res) => {
+        appendMsg(`Log level of ${res.domain} is now ${res.level}`)
+    }).fail((reply, status, txt) => {
+        const msg = `Failed to set log level of ${domain}: ${status} -- ${txt}`
+        console.log(msg)
+        appendMsg(msg)
+        loadLogLevels()
+    })
+} // function logLevelSet(domain, level)
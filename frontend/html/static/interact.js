@@ -1,4 +1,4 @@
-// Time-stamp: <2023-05-19 15:51:14 krylon>
+// Time-stamp: <2026-10-19 14:34:15 krylon>
 // -*- mode: javascript; coding: utf-8; -*-
 // Copyright 2015 Benjamin Walkenhorst <krylon@gmx.net>
 
@@ -142,7 +142,7 @@ const appendMsg = (msg) => {
 // }
 
 const updateMeta = () => {
-    const req = $.get('/ajax/update_metadata',
+    const req = $.post('/ajax/update_metadata',
                       {},
                       (response) => {
                           const msg = 'Update of metadata started'
@@ -154,3 +154,14 @@ const updateMeta = () => {
                          appendMsg(msg)
                      })
 }
+
+const logout = () => {
+    $.post('/logout',
+           {},
+           () => {
+               window.location.href = '/login'
+           }
+          ).fail(() => {
+              appendMsg('Error logging out')
+          })
+} // const logout = () => { ... }
@@ -1,4 +1,4 @@
-// Time-stamp: <2022-11-07 19:11:26 krylon>
+// Time-stamp: <2026-10-19 14:39:07 krylon>
 // -*- mode: javascript; coding: utf-8; -*-
 // Copyright 2020 Benjamin Walkenhorst <krylon@gmx.net>
 
@@ -12,7 +12,6 @@ var settings = {
 
     "update": {
         "active": true,
-        "interval": 5000,
     },
 
     "messages": {
@@ -39,10 +38,6 @@ function initSettings() {
 
     settings.update.active =
         JSON.parse(localStorage.getItem("update.active")) ? true : false
-    item = JSON.parse(localStorage.getItem("update.interval"))
-    if (Number.isInteger(item)) {
-        settings.update.interval = item
-    }
 
     settings.messages.queryEnabled =
         JSON.parse(localStorage.getItem("messages.queryEnabled"));
@@ -1,91 +1,67 @@
-// Time-stamp: <2023-03-20 19:37:14 krylon>
+// Time-stamp: <2026-10-19 14:39:07 krylon>
 
 'use strict;'
 
-let updateStamp = timeStampUnix()
+// The server pushes new scan results to us as they come in, see
+// handleEvents in frontend/events.go.
+let eventSource = null
 
-function update_results() {
-    try {
-        if (!settings.update.active) {
-            return
-        }
-
-        const addr = `/ajax/port_recent/${updateStamp}`
-        const req = $.get(addr,
-                          {},
-                          (response) => {
-                              if (response.Status) {
-                                  let cntTotal = 0
-                                  for (const [port, responses] of Object.entries(response.Results)) {
-                                      const tid = `#tbody_${port}`
-                                      const tbody = $(tid)[0]
-                                      const cnt = responses.length
-
-                                      const cell = $(`#port_cnt_${port}`)[0]
-                                      const cntOld = parseInt(cell.innerText)
-                                      const cntNew = cntOld + cnt
+function addResult(ev) {
+    const port = ev.port.port
+    const tbody = $(`#tbody_${port}`)
 
-                                      cell.innerText = cntNew
-                                      cntTotal += cnt
-
-                                      for (const r of responses.values()) {
-                                          console.log(r)
+    if (tbody.length == 0) {
+        // We do not have a table for that port.
+        return
+    }
 
-                                          // Eventually, I will have to think about how to render that timestamp properly.
-                                          const row = `<tr>
-                 <td>${r.Host.Name} (${r.Host.Address})</td>
-                 <td>${r.Stamp}</td>
-                 <td></td>
-                 <td></td>
-                 <td><pre>${r.Reply}</pre></td>
-                 </tr>`
+    const row = $('<tr></tr>').addClass(`port_${port}`)
+    row.append($('<td></td>').text(`${ev.host.name} (${ev.host.address})`))
+    row.append($('<td></td>').text(ev.host.location))
+    row.append($('<td></td>').text(ev.host.os))
+    row.append($('<td></td>').text(new Date(ev.port.timestamp).toLocaleString()))
+    row.append($('<td></td>').append($('<pre></pre>').text(ev.port.reply)))
+    tbody.append(row)
 
-                                          tbody.innerHTML += row
-                                      }
-                                  }
+    for (const id of [`#port_cnt_${port}`, '#toc_total']) {
+        const cell = $(id)[0]
+        cell.innerText = parseInt(cell.innerText) + 1
+    }
+} // function addResult(ev)
 
-                                  updateStamp = timeStampUnix()
+function updateStart() {
+    if (eventSource != null) {
+        return
+    }
 
-                                  if (cntTotal > 0) {
-                                      const cell = $('#toc_total')[0]
-                                      const cntOld = parseInt(cell.innerText)
-                                      const cntNew = cntOld + cntTotal
-                                      cell.innerText = cntNew
-                                  }
-                              } else {
-                                  appendMsg(res.Message)
-                              }
-                          },
-                          'json'
-                         ).fail((reply, status, text) => {
-                             const msg = `Failed to load update: ${status} -- ${reply} -- ${text}`
-                             console.log(msg)
-                             // alert(msg)
-                             appendMsg(msg)
-                         })
+    eventSource = new EventSource('/events?kind=ScanResult&replies=1')
+    eventSource.addEventListener('ScanResult', (msg) => {
+        try {
+            addResult(JSON.parse(msg.data))
+        } catch (err) {
+            console.log(`Cannot process event ${msg.lastEventId}: ${err}`)
+        }
+    })
+    eventSource.onerror = () => {
+        // The browser reconnects on its own.
+        console.log('Lost connection to event stream')
+    }
+} // function updateStart()
 
-    } finally {
-        window.setTimeout(update_results, settings.update.interval)
+function updateStop() {
+    if (eventSource != null) {
+        eventSource.close()
+        eventSource = null
     }
-} // function update_results ()
+} // function updateStop()
 
 function updateToggle () {
     settings.update.active = !settings.update.active
     saveSetting('update', 'active', settings.update.active)
-} // function updateToggle ()
 
-function updateIntervalSet (val) {
-    if (Number.isInteger(val)) {
-        settings.update.interval = val
-        saveSetting('update', 'interval', val)
+    if (settings.update.active) {
+        updateStart()
     } else {
-        const errMsg = `Invalid argument: ${val} is not an integer`
-        console.log(errMsg)
-        appendMsg(errMsg)
+        updateStop()
     }
-} // function updateIntervalSet ()
-
-function updateIntervalEdit () {
-    const interval = $('#update_interval_edit')[0].value * 1000
-    updateIntervalSet(interval)
-} // function updateIntervalEdit()
+} // function updateToggle ()
@@ -0,0 +1,98 @@
+// /home/krylon/go/src/github.com/blicero/guang/frontend/import.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 17:02:59 krylon>
+
+package frontend
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/blicero/guang/common"
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/database"
+	"github.com/blicero/guang/importer"
+)
+
+// This file contains the handler for importing the results of other
+// scanners.
+
+// handleAPIImport stores the scan results in the request body, the output
+// of nmap or masscan. If the backend is running, the new Hosts are handed
+// to it, so their zones are transferred and their metadata are looked up
+// right away, otherwise that happens the next time it runs.
+func (srv *WebFrontend) handleAPIImport(w http.ResponseWriter, r *http.Request) {
+	var (
+		err    error
+		db     database.HostDB
+		stats  importer.Stats
+		body   io.Reader = r.Body
+		name             = r.URL.Query().Get("name")
+		onAdd  func(hosts []data.Host)
+		res    = new(apiImportStats)
+		status = http.StatusOK
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	// The body is read straight from the request, so the name is taken
+	// from the URL. r.FormValue would consume the body of a request that
+	// claims to be a form, as curl's --data-binary does.
+	if name == "" {
+		name = "request body"
+	}
+
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		var gz *gzip.Reader
+
+		if gz, err = gzip.NewReader(r.Body); err != nil {
+			srv.apiError(w, r, http.StatusBadRequest, "Cannot decompress request: %s", err.Error())
+			return
+		}
+
+		defer gz.Close() // nolint: errcheck
+		body = gz
+	}
+
+	if srv.nexus != nil {
+		onAdd = srv.nexus.HostsImported
+	}
+
+	db = srv.dbPool.Get()
+	defer srv.dbPool.Put(db)
+
+	stats, err = importer.Import(r.Context(), db, body, name, onAdd)
+
+	srv.log.Printf("[INFO] Imported %d of %d results from %s, %d new Hosts\n",
+		stats.Ports,
+		stats.Results,
+		name,
+		stats.NewHosts)
+
+	// Whatever was committed before the error stays in the database, so
+	// the client learns about it along with the error.
+	if err != nil {
+		if errors.Is(err, importer.ErrInput) {
+			status = http.StatusBadRequest
+			res.Error = err.Error()
+		} else {
+			status = http.StatusInternalServerError
+			res.Error = fmt.Sprintf("Cannot import %s: %s", name, err.Error())
+			srv.log.Printf("[ERROR] %s\n", res.Error)
+		}
+	}
+
+	res.Results = stats.Results
+	res.Ports = stats.Ports
+	res.Skipped = stats.Skipped
+	res.NewHosts = stats.NewHosts
+
+	srv.apiRespond(w, r, status, res)
+} // func (srv *WebFrontend) handleAPIImport(w http.ResponseWriter, r *http.Request)
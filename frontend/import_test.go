@@ -0,0 +1,65 @@
+// /home/krylon/go/src/github.com/blicero/guang/frontend/import_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 17:40:45 krylon>
+
+package frontend
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/blicero/guang/auth"
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/database"
+)
+
+func TestImport(t *testing.T) {
+	const scan = `#masscan
+open tcp 22 198.51.100.91 1760002000
+banner tcp 22 198.51.100.91 1760002000 ssh SSH-2.0-OpenSSH_9.2p1 Debian-2
+open tcp 80 198.51.100.92 1760002001
+`
+
+	var (
+		err      error
+		srv      *WebFrontend
+		db       database.HostDB
+		host     *data.Host
+		stats    apiImportStats
+		operator string
+		viewer   string
+	)
+
+	if srv, err = Create("", 4727, nil, nil); err != nil {
+		t.Fatalf("Error creating Web Frontend: %s", err.Error())
+	}
+
+	operator = testToken(t, srv, testUser(t, srv, "importer", auth.Operator))
+	viewer = testToken(t, srv, testUser(t, srv, "onlooker", auth.ReadOnly))
+
+	if code := apiRequest(t, srv, viewer, "POST", "/api/v1/import", scan, nil); code != http.StatusForbidden {
+		t.Errorf("Import by a read-only user returned %d, expected %d", code, http.StatusForbidden)
+	}
+
+	if code := apiRequest(t, srv, operator, "POST", "/api/v1/import?name=masscan.txt", scan, &stats); code != http.StatusOK {
+		t.Fatalf("POST /api/v1/import returned %d", code)
+	} else if stats.Results != 3 || stats.Ports != 3 || stats.NewHosts != 2 {
+		t.Errorf("Unexpected result: %+v", stats)
+	}
+
+	db = srv.dbPool.Get()
+	defer srv.dbPool.Put(db)
+
+	if host, err = db.HostGetByAddr(context.Background(), "198.51.100.91"); err != nil {
+		t.Fatalf("Imported Host is missing: %s", err.Error())
+	} else if host.Source != data.HostSourceImport {
+		t.Errorf("Unexpected source %v", host.Source)
+	}
+
+	if code := apiRequest(t, srv, operator, "POST", "/api/v1/import", "<html></html>", nil); code != http.StatusBadRequest {
+		t.Errorf("Import of junk returned %d, expected %d", code, http.StatusBadRequest)
+	}
+} // func TestImport(t *testing.T)
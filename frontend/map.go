@@ -0,0 +1,128 @@
+// /home/krylon/go/src/github.com/blicero/guang/frontend/map.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 16:10:50 krylon>
+
+package frontend
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/blicero/guang/backend/facility"
+	"github.com/blicero/guang/common"
+	"github.com/blicero/guang/database"
+)
+
+// This file contains the handlers for the map of the places the GeoIP
+// database puts the Hosts at.
+
+const (
+	mapSize    = 1000
+	mapSizeMax = 10000
+)
+
+type tmplDataMap struct {
+	tmplDataIndex
+	Points []database.GeoPoint
+}
+
+// Radius returns the radius of the circle marking a place on the map, in
+// degrees. It grows with the logarithm of the number of Hosts, so a few
+// big hosting centers do not cover the map.
+func (d tmplDataMap) Radius(p database.GeoPoint) string {
+	return strconv.FormatFloat(0.6+0.4*math.Log2(float64(p.Hosts)), 'f', 2, 64)
+} // func (d tmplDataMap) Radius(p database.GeoPoint) string
+
+// Y returns the vertical position of a place on the map. SVG coordinates
+// grow downwards, latitudes grow northwards.
+func (d tmplDataMap) Y(p database.GeoPoint) float64 {
+	return -p.Latitude
+} // func (d tmplDataMap) Y(p database.GeoPoint) float64
+
+// SearchURL returns the URL of the search for the Hosts in the country of
+// the given place.
+func (d tmplDataMap) SearchURL(p database.GeoPoint) string {
+	return "/search?q=" + url.QueryEscape(qualify("country", p.Country))
+} // func (d tmplDataMap) SearchURL(p database.GeoPoint) string
+
+func (srv *WebFrontend) handleMap(w http.ResponseWriter, r *http.Request) {
+	var (
+		err      error
+		db       database.HostDB
+		tmplData = tmplDataMap{
+			tmplDataIndex: tmplDataIndex{
+				Title:      "Map",
+				Debug:      common.Debug,
+				Facilities: facility.All(),
+			},
+		}
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	tmplData.setAuth(r)
+
+	db = srv.dbPool.Get()
+	defer srv.dbPool.Put(db)
+
+	if tmplData.Points, err = db.HostGetLocations(r.Context(), mapSize); err != nil {
+		srv.sendErrorMessage(w, fmt.Sprintf("Cannot load locations: %s", err.Error()))
+		return
+	}
+
+	srv.render(w, "map", http.StatusOK, tmplData)
+} // func (srv *WebFrontend) handleMap(w http.ResponseWriter, r *http.Request)
+
+func (srv *WebFrontend) handleAPILocations(w http.ResponseWriter, r *http.Request) {
+	var (
+		err   error
+		db    database.HostDB
+		limit = mapSize
+		list  []database.GeoPoint
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	if s := r.FormValue("limit"); s != "" {
+		if limit, err = strconv.Atoi(s); err != nil || limit < 1 || limit > mapSizeMax {
+			srv.apiError(w, r, http.StatusBadRequest,
+				"Invalid limit %q, must be between 1 and %d",
+				s,
+				mapSizeMax)
+			return
+		}
+	}
+
+	db = srv.dbPool.Get()
+	defer srv.dbPool.Put(db)
+
+	if list, err = db.HostGetLocations(r.Context(), limit); err != nil {
+		srv.apiError(w, r, http.StatusInternalServerError,
+			"Error loading locations: %s",
+			err.Error())
+		return
+	}
+
+	var items = make([]apiGeoPoint, len(list))
+
+	for i, p := range list {
+		items[i] = apiGeoPoint{
+			Latitude:  p.Latitude,
+			Longitude: p.Longitude,
+			Country:   p.Country,
+			City:      p.City,
+			Hosts:     p.Hosts,
+		}
+	}
+
+	srv.apiRespond(w, r, 200, items)
+} // func (srv *WebFrontend) handleAPILocations(w http.ResponseWriter, r *http.Request)
@@ -0,0 +1,110 @@
+// /home/krylon/go/src/github.com/blicero/guang/frontend/map_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 16:10:50 krylon>
+
+package frontend
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/blicero/guang/auth"
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/database"
+)
+
+func TestMap(t *testing.T) {
+	var (
+		err    error
+		srv    *WebFrontend
+		db     database.HostDB
+		hosts  apiHostList
+		detail apiHostDetail
+		list   []apiGeoPoint
+		found  bool
+		ctx    = context.Background()
+		host   = data.Host{
+			Address: net.ParseIP("198.51.100.64"),
+			Name:    "map.example.org",
+			Source:  data.HostSourceUser,
+		}
+		geo = data.GeoInfo{
+			Country:     "NZ",
+			Subdivision: "WGN",
+			City:        "Wellington",
+			Latitude:    -41.2866,
+			Longitude:   174.7756,
+			Accuracy:    50,
+		}
+	)
+
+	if srv, err = Create("", 4725, nil, nil); err != nil {
+		t.Fatalf("Error creating Web Frontend: %s", err.Error())
+	}
+
+	var token = testToken(t, srv, testUser(t, srv, "mapviewer", auth.ReadOnly))
+
+	db = srv.dbPool.Get()
+	if _, err = db.HostUpsert(ctx, &host); err != nil {
+		t.Fatalf("Error adding Host: %s", err.Error())
+	} else if err = db.HostSetGeo(ctx, &host, "Wellington, Neuseeland", &geo); err != nil {
+		t.Fatalf("Error setting location of Host: %s", err.Error())
+	}
+	srv.dbPool.Put(db)
+
+	if code := apiRequest(t, srv, token, "GET", "/api/v1/locations", "", &list); code != http.StatusOK {
+		t.Fatalf("GET /api/v1/locations returned %d", code)
+	}
+
+	for _, p := range list {
+		if p.Country == geo.Country && p.Latitude == geo.Latitude {
+			found = true
+			if p.Longitude != geo.Longitude || p.City != geo.City || p.Hosts != 1 {
+				t.Errorf("Unexpected location: %#v", p)
+			}
+		}
+	}
+
+	if !found {
+		t.Errorf("%s is missing from %#v", geo.City, list)
+	}
+
+	if code := apiRequest(t, srv, token, "GET", "/api/v1/hosts?country=nz", "", &hosts); code != http.StatusOK {
+		t.Errorf("GET /api/v1/hosts by country returned %d", code)
+	} else if hosts.Total != 1 || len(hosts.Items) != 1 {
+		t.Errorf("Expected 1 Host in %s, got %d", geo.Country, hosts.Total)
+	}
+
+	var uri = fmt.Sprintf("/api/v1/hosts/%d", host.ID)
+
+	if code := apiRequest(t, srv, token, "GET", uri, "", &detail); code != http.StatusOK {
+		t.Errorf("GET %s returned %d", uri, code)
+	} else if g := detail.Geo; g == nil || g.Country != geo.Country || g.Subdivision != geo.Subdivision {
+		t.Errorf("Unexpected location: %#v", g)
+	} else if g.Latitude == nil || *g.Latitude != geo.Latitude || g.AccuracyRadius != geo.Accuracy {
+		t.Errorf("Unexpected coordinates: %#v", g)
+	}
+
+	var (
+		req = httptest.NewRequest("GET", "/map", nil)
+		rec = httptest.NewRecorder()
+	)
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	srv.router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("GET /map returned %d", rec.Code)
+	} else if body := rec.Body.String(); !strings.Contains(body, `cx="174.7756"`) {
+		t.Error("Wellington is missing from the map")
+	} else if !strings.Contains(body, "/search?q=country%3A%22NZ%22") {
+		t.Error("The link to the search is missing from the map")
+	}
+} // func TestMap(t *testing.T)
@@ -0,0 +1,112 @@
+// /home/krylon/go/src/github.com/blicero/guang/frontend/networks.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 16:04:01 krylon>
+
+package frontend
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/blicero/guang/backend/facility"
+	"github.com/blicero/guang/common"
+	"github.com/blicero/guang/database"
+)
+
+// This file contains the handlers for the overview of the autonomous
+// systems the Hosts we know are part of.
+
+const (
+	asReportSize    = 100
+	asReportSizeMax = 1000
+)
+
+type tmplDataNetworks struct {
+	tmplDataIndex
+	Networks []database.ASCount
+}
+
+// SearchURL returns the URL of the search for the Hosts in the given
+// autonomous system.
+func (d tmplDataNetworks) SearchURL(ac database.ASCount) string {
+	return "/search?q=" + url.QueryEscape(fmt.Sprintf("asn:%d", ac.ASN))
+} // func (d tmplDataNetworks) SearchURL(ac database.ASCount) string
+
+func (srv *WebFrontend) handleNetworks(w http.ResponseWriter, r *http.Request) {
+	var (
+		err      error
+		db       database.HostDB
+		tmplData = tmplDataNetworks{
+			tmplDataIndex: tmplDataIndex{
+				Title:      "Networks",
+				Debug:      common.Debug,
+				Facilities: facility.All(),
+			},
+		}
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	tmplData.setAuth(r)
+
+	db = srv.dbPool.Get()
+	defer srv.dbPool.Put(db)
+
+	if tmplData.Networks, err = db.HostGetASReport(r.Context(), asReportSize); err != nil {
+		srv.sendErrorMessage(w, fmt.Sprintf("Cannot load autonomous systems: %s", err.Error()))
+		return
+	}
+
+	srv.render(w, "networks", http.StatusOK, tmplData)
+} // func (srv *WebFrontend) handleNetworks(w http.ResponseWriter, r *http.Request)
+
+func (srv *WebFrontend) handleAPINetworks(w http.ResponseWriter, r *http.Request) {
+	var (
+		err   error
+		db    database.HostDB
+		limit = asReportSize
+		list  []database.ASCount
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	if s := r.FormValue("limit"); s != "" {
+		if limit, err = strconv.Atoi(s); err != nil || limit < 1 || limit > asReportSizeMax {
+			srv.apiError(w, r, http.StatusBadRequest,
+				"Invalid limit %q, must be between 1 and %d",
+				s,
+				asReportSizeMax)
+			return
+		}
+	}
+
+	db = srv.dbPool.Get()
+	defer srv.dbPool.Put(db)
+
+	if list, err = db.HostGetASReport(r.Context(), limit); err != nil {
+		srv.apiError(w, r, http.StatusInternalServerError,
+			"Error loading autonomous systems: %s",
+			err.Error())
+		return
+	}
+
+	var items = make([]apiASCount, len(list))
+
+	for i, ac := range list {
+		items[i] = apiASCount{
+			ASN:   ac.ASN,
+			Org:   ac.Org,
+			Hosts: ac.Hosts,
+		}
+	}
+
+	srv.apiRespond(w, r, 200, items)
+} // func (srv *WebFrontend) handleAPINetworks(w http.ResponseWriter, r *http.Request)
@@ -0,0 +1,104 @@
+// /home/krylon/go/src/github.com/blicero/guang/frontend/networks_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 16:04:01 krylon>
+
+package frontend
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blicero/guang/auth"
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/database"
+)
+
+func TestNetworks(t *testing.T) {
+	var (
+		err   error
+		srv   *WebFrontend
+		db    database.HostDB
+		hosts apiHostList
+		list  []apiASCount
+		found bool
+		ctx   = context.Background()
+		host  = data.Host{
+			Address: net.ParseIP("198.51.100.63"),
+			Name:    "network.example.org",
+			Source:  data.HostSourceUser,
+		}
+		as = data.ASInfo{
+			ASN:       64511,
+			Org:       "Example Transit",
+			Prefix:    "198.51.100.0/24",
+			Registry:  "ripencc",
+			Allocated: time.Date(2010, 7, 12, 0, 0, 0, 0, time.UTC),
+		}
+	)
+
+	if srv, err = Create("", 4724, nil, nil); err != nil {
+		t.Fatalf("Error creating Web Frontend: %s", err.Error())
+	}
+
+	var token = testToken(t, srv, testUser(t, srv, "netviewer", auth.ReadOnly))
+
+	db = srv.dbPool.Get()
+	if _, err = db.HostUpsert(ctx, &host); err != nil {
+		t.Fatalf("Error adding Host: %s", err.Error())
+	} else if err = db.HostSetAS(ctx, &host, &as); err != nil {
+		t.Fatalf("Error setting AS of Host: %s", err.Error())
+	}
+	srv.dbPool.Put(db)
+
+	if code := apiRequest(t, srv, token, "GET", "/api/v1/networks", "", &list); code != http.StatusOK {
+		t.Fatalf("GET /api/v1/networks returned %d", code)
+	}
+
+	for _, ac := range list {
+		if ac.ASN == as.ASN {
+			found = true
+			if ac.Org != as.Org || ac.Hosts != 1 {
+				t.Errorf("Unexpected count: %#v", ac)
+			}
+		}
+	}
+
+	if !found {
+		t.Errorf("AS%d is missing from %#v", as.ASN, list)
+	}
+
+	if code := apiRequest(t, srv, token, "GET", "/api/v1/networks?limit=0", "", nil); code != http.StatusBadRequest {
+		t.Errorf("GET /api/v1/networks with an invalid limit returned %d", code)
+	}
+
+	if code := apiRequest(t, srv, token, "GET", "/api/v1/hosts?asn=AS64511", "", &hosts); code != http.StatusOK {
+		t.Errorf("GET /api/v1/hosts by AS returned %d", code)
+	} else if hosts.Total != 1 || len(hosts.Items) != 1 {
+		t.Errorf("Expected 1 Host in AS%d, got %d", as.ASN, hosts.Total)
+	} else if a := hosts.Items[0].AS; a == nil || a.Prefix != as.Prefix || a.Allocated == nil || !a.Allocated.Equal(as.Allocated) {
+		t.Errorf("Unexpected AS: %#v", a)
+	}
+
+	var (
+		req = httptest.NewRequest("GET", "/networks", nil)
+		rec = httptest.NewRecorder()
+	)
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	srv.router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("GET /networks returned %d", rec.Code)
+	} else if body := rec.Body.String(); !strings.Contains(body, "Example Transit") {
+		t.Error("AS64511 is missing from the Networks page")
+	} else if !strings.Contains(body, "/search?q=asn%3A64511") {
+		t.Error("The link to the search is missing from the Networks page")
+	}
+} // func TestNetworks(t *testing.T)
@@ -0,0 +1,266 @@
+// /home/krylon/go/src/github.com/blicero/guang/frontend/rules.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 15:11:36 krylon>
+
+package frontend
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/blicero/guang/backend/facility"
+	"github.com/blicero/guang/common"
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/database"
+	"github.com/blicero/guang/rules"
+	"github.com/blicero/guang/rules/severity"
+	"github.com/blicero/krylib"
+	"github.com/gorilla/mux"
+)
+
+// This file contains the handlers for the dashboard that shows which Hosts
+// the Rules flagged, and for editing the Rules.
+
+type tmplDataRules struct {
+	tmplDataIndex
+	Rules      []data.Rule
+	Findings   []data.Finding
+	Severities []severity.Severity
+	MinSev     severity.Severity
+	NewRule    data.Rule
+	Message    string
+}
+
+// ruleForm is what the ruleform template needs to edit a Rule.
+type ruleForm struct {
+	Action     string
+	Rule       data.Rule
+	Severities []severity.Severity
+	CSRFToken  string
+}
+
+// Form returns the data for the form to edit the Rule, or to add a new one
+// if the Rule has no ID.
+func (d tmplDataRules) Form(r data.Rule) ruleForm {
+	var f = ruleForm{
+		Action:     "/rules",
+		Rule:       r,
+		Severities: d.Severities,
+		CSRFToken:  d.CSRFToken,
+	}
+
+	if r.ID > 0 {
+		f.Action = fmt.Sprintf("/rules/%d/edit", r.ID)
+	}
+
+	return f
+} // func (d tmplDataRules) Form(r data.Rule) ruleForm
+
+// renderRules shows the dashboard.
+func (srv *WebFrontend) renderRules(w http.ResponseWriter, r *http.Request, status int, msg string, errs ...string) {
+	var (
+		err      error
+		db       database.HostDB
+		tmplData = tmplDataRules{
+			tmplDataIndex: tmplDataIndex{
+				Title:      "Findings",
+				Debug:      common.Debug,
+				Facilities: facility.All(),
+				Error:      errs,
+			},
+			Severities: severity.AllSeverities(),
+			NewRule: data.Rule{
+				ID:       krylib.INVALID_ID,
+				Severity: severity.Medium,
+				Enabled:  true,
+			},
+			Message: msg,
+		}
+	)
+
+	tmplData.setAuth(r)
+
+	if r.Method == http.MethodGet && r.FormValue("severity") != "" {
+		if tmplData.MinSev, err = severity.Parse(r.FormValue("severity")); err != nil {
+			tmplData.Error = append(tmplData.Error, err.Error())
+			status = http.StatusBadRequest
+		}
+	}
+
+	db = srv.dbPool.Get()
+	defer srv.dbPool.Put(db)
+
+	if tmplData.Rules, err = db.RuleGetAll(r.Context()); err != nil {
+		srv.sendErrorMessage(w, fmt.Sprintf("Cannot load Rules: %s", err.Error()))
+		return
+	} else if tmplData.Findings, err = db.FindingGetAll(r.Context(), tmplData.MinSev); err != nil {
+		srv.sendErrorMessage(w, fmt.Sprintf("Cannot load Findings: %s", err.Error()))
+		return
+	}
+
+	srv.render(w, "rules", status, tmplData)
+} // func (srv *WebFrontend) renderRules(w http.ResponseWriter, r *http.Request, status int, msg string, errs ...string)
+
+// ruleFromForm builds a Rule from the submitted form and checks it.
+func ruleFromForm(r *http.Request) (data.Rule, error) {
+	var (
+		err  error
+		port uint64
+		rule = data.Rule{
+			Label:    strings.TrimSpace(r.FormValue("label")),
+			Banner:   r.FormValue("banner"),
+			OS:       r.FormValue("os"),
+			Location: r.FormValue("location"),
+			Hostname: r.FormValue("hostname"),
+			Tag:      strings.ToLower(strings.TrimSpace(r.FormValue("tag"))),
+			Enabled:  r.FormValue("enabled") != "",
+		}
+	)
+
+	if rule.Severity, err = severity.Parse(r.FormValue("severity")); err != nil {
+		return rule, err
+	} else if s := strings.TrimSpace(r.FormValue("port")); s != "" {
+		if port, err = strconv.ParseUint(s, 10, 16); err != nil {
+			return rule, fmt.Errorf("Invalid port %q", s)
+		}
+		rule.Port = uint16(port)
+	}
+
+	if _, err = rules.Compile(rule); err != nil {
+		return rule, err
+	}
+
+	return rule, nil
+} // func ruleFromForm(r *http.Request) (data.Rule, error)
+
+func (srv *WebFrontend) handleRules(w http.ResponseWriter, r *http.Request) {
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	srv.renderRules(w, r, http.StatusOK, "")
+} // func (srv *WebFrontend) handleRules(w http.ResponseWriter, r *http.Request)
+
+func (srv *WebFrontend) handleRuleAdd(w http.ResponseWriter, r *http.Request) {
+	var (
+		err  error
+		db   database.HostDB
+		rule data.Rule
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	if rule, err = ruleFromForm(r); err != nil {
+		srv.renderRules(w, r, http.StatusBadRequest, "", err.Error())
+		return
+	}
+
+	db = srv.dbPool.Get()
+	err = db.RuleAdd(r.Context(), &rule)
+	srv.dbPool.Put(db)
+
+	if err != nil {
+		srv.renderRules(w, r, http.StatusInternalServerError, "",
+			fmt.Sprintf("Cannot add Rule: %s", err.Error()))
+		return
+	}
+
+	srv.renderRules(w, r, http.StatusOK,
+		fmt.Sprintf("Rule %q was added", rule.Label))
+} // func (srv *WebFrontend) handleRuleAdd(w http.ResponseWriter, r *http.Request)
+
+func (srv *WebFrontend) handleRuleUpdate(w http.ResponseWriter, r *http.Request) {
+	var (
+		err  error
+		id   int64
+		db   database.HostDB
+		rule data.Rule
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	if id, err = strconv.ParseInt(mux.Vars(r)["id"], 10, 64); err != nil {
+		http.Error(w, "Invalid Rule ID", http.StatusBadRequest)
+		return
+	} else if rule, err = ruleFromForm(r); err != nil {
+		srv.renderRules(w, r, http.StatusBadRequest, "", err.Error())
+		return
+	}
+
+	rule.ID = krylib.ID(id)
+
+	db = srv.dbPool.Get()
+	err = db.RuleUpdate(r.Context(), &rule)
+	srv.dbPool.Put(db)
+
+	if errors.Is(err, database.ErrNotFound) {
+		srv.renderRules(w, r, http.StatusNotFound, "",
+			fmt.Sprintf("Rule #%d does not exist", id))
+		return
+	} else if err != nil {
+		srv.renderRules(w, r, http.StatusInternalServerError, "",
+			fmt.Sprintf("Cannot update Rule: %s", err.Error()))
+		return
+	}
+
+	srv.renderRules(w, r, http.StatusOK,
+		fmt.Sprintf("Rule %q was updated, its Findings will show up again when the Rules are evaluated", rule.Label))
+} // func (srv *WebFrontend) handleRuleUpdate(w http.ResponseWriter, r *http.Request)
+
+func (srv *WebFrontend) handleRuleDelete(w http.ResponseWriter, r *http.Request) {
+	var (
+		err error
+		id  int64
+		db  database.HostDB
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	if id, err = strconv.ParseInt(mux.Vars(r)["id"], 10, 64); err != nil {
+		http.Error(w, "Invalid Rule ID", http.StatusBadRequest)
+		return
+	}
+
+	db = srv.dbPool.Get()
+	err = db.RuleDelete(r.Context(), krylib.ID(id))
+	srv.dbPool.Put(db)
+
+	if errors.Is(err, database.ErrNotFound) {
+		srv.renderRules(w, r, http.StatusNotFound, "",
+			fmt.Sprintf("Rule #%d does not exist", id))
+		return
+	} else if err != nil {
+		srv.renderRules(w, r, http.StatusInternalServerError, "",
+			fmt.Sprintf("Cannot delete Rule: %s", err.Error()))
+		return
+	}
+
+	srv.renderRules(w, r, http.StatusOK, "The Rule was deleted")
+} // func (srv *WebFrontend) handleRuleDelete(w http.ResponseWriter, r *http.Request)
+
+func (srv *WebFrontend) handleRulesEvaluate(w http.ResponseWriter, r *http.Request) {
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	if srv.nexus == nil {
+		srv.renderRules(w, r, http.StatusServiceUnavailable, "", msgNoBackend)
+		return
+	}
+
+	go srv.nexus.EvaluateRules()
+
+	srv.renderRules(w, r, http.StatusOK,
+		"The Rules are being evaluated for all Hosts, reload the page in a while to see the results")
+} // func (srv *WebFrontend) handleRulesEvaluate(w http.ResponseWriter, r *http.Request)
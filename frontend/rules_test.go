@@ -0,0 +1,116 @@
+// /home/krylon/go/src/github.com/blicero/guang/frontend/rules_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 15:11:36 krylon>
+
+package frontend
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/blicero/guang/auth"
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/database"
+)
+
+func TestRulesPage(t *testing.T) {
+	var (
+		err   error
+		srv   *WebFrontend
+		db    database.HostDB
+		list  []data.Rule
+		added *data.Rule
+		ctx   = context.Background()
+	)
+
+	if srv, err = Create("", 4721, nil, nil); err != nil {
+		t.Fatalf("Error creating Web Frontend: %s", err.Error())
+	}
+
+	var (
+		viewer = testToken(t, srv, testUser(t, srv, "ruleviewer", auth.ReadOnly))
+		op     = testToken(t, srv, testUser(t, srv, "ruleop", auth.Operator))
+	)
+
+	var post = func(token, uri string, form url.Values) *httptest.ResponseRecorder {
+		var (
+			req = httptest.NewRequest("POST", uri, strings.NewReader(form.Encode()))
+			res = httptest.NewRecorder()
+		)
+
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Authorization", "Bearer "+token)
+		srv.router.ServeHTTP(res, req)
+		return res
+	}
+
+	var (
+		res  *httptest.ResponseRecorder
+		rule = url.Values{
+			"label":    {"Telnet on a printer"},
+			"severity": {"High"},
+			"port":     {"23"},
+			"hostname": {"^printer"},
+			"enabled":  {"on"},
+		}
+	)
+
+	if code := apiRequest(t, srv, viewer, "GET", "/rules", "", nil); code != http.StatusOK {
+		t.Errorf("GET /rules returned %d", code)
+	} else if code = apiRequest(t, srv, viewer, "GET", "/rules?severity=bogus", "", nil); code != http.StatusBadRequest {
+		t.Errorf("GET /rules with invalid severity returned %d", code)
+	} else if res = post(viewer, "/rules", rule); res.Code != http.StatusForbidden {
+		t.Errorf("Rule by ReadOnly user returned %d", res.Code)
+	} else if res = post(op, "/rules", url.Values{"label": {"Broken"}, "severity": {"Low"}, "banner": {"(oops"}}); res.Code != http.StatusBadRequest {
+		t.Errorf("Invalid Rule returned %d", res.Code)
+	} else if res = post(op, "/rules", url.Values{"label": {"Everything"}, "severity": {"Low"}}); res.Code != http.StatusBadRequest {
+		t.Errorf("Rule without criteria returned %d", res.Code)
+	} else if res = post(op, "/rules", rule); res.Code != http.StatusOK {
+		t.Errorf("Rule by Operator returned %d", res.Code)
+	} else if !strings.Contains(res.Body.String(), "Telnet on a printer") {
+		t.Error("The new Rule is not shown on the page")
+	} else if res = post(op, "/rules/evaluate", nil); res.Code != http.StatusServiceUnavailable {
+		t.Errorf("Evaluating Rules without backend returned %d", res.Code)
+	}
+
+	db = srv.dbPool.Get()
+	list, err = db.RuleGetAll(ctx)
+	srv.dbPool.Put(db)
+
+	if err != nil {
+		t.Fatalf("Cannot load Rules: %s", err.Error())
+	}
+
+	for i := range list {
+		if list[i].Label == "Telnet on a printer" {
+			added = &list[i]
+		}
+	}
+
+	if added == nil {
+		t.Fatal("The new Rule was not stored")
+	} else if added.Port != 23 || added.Hostname != "^printer" || !added.Enabled {
+		t.Errorf("Unexpected Rule: %#v", added)
+	}
+
+	var page = fmt.Sprintf("/rules/%d", added.ID)
+
+	rule.Del("enabled")
+
+	if res = post(op, page+"/edit", rule); res.Code != http.StatusOK {
+		t.Errorf("Editing Rule returned %d", res.Code)
+	} else if res = post(op, "/rules/999999/edit", rule); res.Code != http.StatusNotFound {
+		t.Errorf("Editing missing Rule returned %d", res.Code)
+	} else if res = post(op, page+"/delete", nil); res.Code != http.StatusOK {
+		t.Errorf("Deleting Rule returned %d", res.Code)
+	} else if res = post(op, page+"/delete", nil); res.Code != http.StatusNotFound {
+		t.Errorf("Deleting Rule twice returned %d", res.Code)
+	}
+} // func TestRulesPage(t *testing.T)
@@ -0,0 +1,103 @@
+// /home/krylon/go/src/github.com/blicero/guang/frontend/software.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 15:24:51 krylon>
+
+package frontend
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/blicero/guang/backend/facility"
+	"github.com/blicero/guang/common"
+	"github.com/blicero/guang/database"
+)
+
+// This file contains the handlers for the overview of the software versions
+// the banner parser found on the scanned Ports.
+
+type tmplDataSoftware struct {
+	tmplDataIndex
+	Software []database.SoftwareCount
+}
+
+// SearchURL returns the URL of the search for the Ports running the given
+// product and version.
+func (d tmplDataSoftware) SearchURL(sc database.SoftwareCount) string {
+	var q = qualify("product", sc.Product)
+
+	if sc.Version != "" {
+		q += " " + qualify("version", sc.Version)
+	}
+
+	return "/search?q=" + url.QueryEscape(q)
+} // func (d tmplDataSoftware) SearchURL(sc database.SoftwareCount) string
+
+func (srv *WebFrontend) handleSoftware(w http.ResponseWriter, r *http.Request) {
+	var (
+		err      error
+		db       database.HostDB
+		tmplData = tmplDataSoftware{
+			tmplDataIndex: tmplDataIndex{
+				Title:      "Software",
+				Debug:      common.Debug,
+				Facilities: facility.All(),
+			},
+		}
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	tmplData.setAuth(r)
+
+	db = srv.dbPool.Get()
+	defer srv.dbPool.Put(db)
+
+	if tmplData.Software, err = db.PortGetSoftware(r.Context()); err != nil {
+		srv.sendErrorMessage(w, fmt.Sprintf("Cannot load Software: %s", err.Error()))
+		return
+	}
+
+	srv.render(w, "software", http.StatusOK, tmplData)
+} // func (srv *WebFrontend) handleSoftware(w http.ResponseWriter, r *http.Request)
+
+func (srv *WebFrontend) handleAPISoftware(w http.ResponseWriter, r *http.Request) {
+	var (
+		err  error
+		db   database.HostDB
+		list []database.SoftwareCount
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	db = srv.dbPool.Get()
+	defer srv.dbPool.Put(db)
+
+	if list, err = db.PortGetSoftware(r.Context()); err != nil {
+		srv.apiError(w, r, http.StatusInternalServerError,
+			"Error loading Software: %s",
+			err.Error())
+		return
+	}
+
+	var items = make([]apiSoftwareCount, len(list))
+
+	for i, sc := range list {
+		items[i] = apiSoftwareCount{
+			Product: sc.Product,
+			Version: sc.Version,
+			CPE:     sc.CPE,
+			Ports:   sc.Ports,
+			Hosts:   sc.Hosts,
+		}
+	}
+
+	srv.apiRespond(w, r, 200, items)
+} // func (srv *WebFrontend) handleAPISoftware(w http.ResponseWriter, r *http.Request)
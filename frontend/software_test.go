@@ -0,0 +1,107 @@
+// /home/krylon/go/src/github.com/blicero/guang/frontend/software_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 15:24:51 krylon>
+
+package frontend
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blicero/guang/auth"
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/database"
+)
+
+func TestSoftware(t *testing.T) {
+	var (
+		err   error
+		srv   *WebFrontend
+		db    database.HostDB
+		ports apiPortList
+		list  []apiSoftwareCount
+		found bool
+		ctx   = context.Background()
+		reply = "220 ProFTPD 1.3.5e Server (Debian) [::ffff:198.51.100.61]"
+		host  = data.Host{
+			Address: net.ParseIP("198.51.100.61"),
+			Name:    "software.example.org",
+			Source:  data.HostSourceUser,
+		}
+		res = data.ScanResult{
+			Port:  21,
+			Reply: &reply,
+			Software: data.Software{
+				Service: "ftp",
+				Product: "ProFTPD",
+				Version: "1.3.5e",
+				CPE:     "cpe:/a:proftpd:proftpd:1.3.5e",
+			},
+			Stamp: time.Now(),
+		}
+	)
+
+	if srv, err = Create("", 4722, nil, nil); err != nil {
+		t.Fatalf("Error creating Web Frontend: %s", err.Error())
+	}
+
+	var token = testToken(t, srv, testUser(t, srv, "softviewer", auth.ReadOnly))
+
+	db = srv.dbPool.Get()
+	if _, err = db.HostUpsert(ctx, &host); err != nil {
+		t.Fatalf("Error adding Host: %s", err.Error())
+	}
+	res.Host = host
+	if err = db.PortAdd(ctx, &res); err != nil {
+		t.Fatalf("Error adding Port: %s", err.Error())
+	}
+	srv.dbPool.Put(db)
+
+	if code := apiRequest(t, srv, token, "GET", "/api/v1/software", "", &list); code != http.StatusOK {
+		t.Fatalf("GET /api/v1/software returned %d", code)
+	}
+
+	for _, sc := range list {
+		if sc.Product == "ProFTPD" && sc.Version == "1.3.5e" {
+			found = true
+			if sc.Ports != 1 || sc.Hosts != 1 {
+				t.Errorf("Unexpected count: %#v", sc)
+			}
+		}
+	}
+
+	if !found {
+		t.Errorf("ProFTPD 1.3.5e is missing from %#v", list)
+	}
+
+	if code := apiRequest(t, srv, token, "GET", "/api/v1/ports?product=ProFTPD&version=1.3.5e", "", &ports); code != http.StatusOK {
+		t.Errorf("GET /api/v1/ports by product returned %d", code)
+	} else if ports.Total != 1 || len(ports.Items) != 1 {
+		t.Errorf("Expected 1 Port running ProFTPD 1.3.5e, got %d", ports.Total)
+	} else if sw := ports.Items[0].Software; sw == nil || sw.CPE != res.Software.CPE {
+		t.Errorf("Unexpected Software: %#v", sw)
+	}
+
+	var (
+		req = httptest.NewRequest("GET", "/software", nil)
+		rec = httptest.NewRecorder()
+	)
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	srv.router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("GET /software returned %d", rec.Code)
+	} else if body := rec.Body.String(); !strings.Contains(body, "cpe:/a:proftpd:proftpd:1.3.5e") {
+		t.Error("ProFTPD is missing from the Software page")
+	} else if !strings.Contains(body, "/search?q=product%3A%22ProFTPD%22+version%3A%221.3.5e%22") {
+		t.Error("The link to the search is missing from the Software page")
+	}
+} // func TestSoftware(t *testing.T)
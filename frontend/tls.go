@@ -0,0 +1,219 @@
+// /home/krylon/go/src/github.com/blicero/guang/frontend/tls.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 14:35:54 krylon>
+
+package frontend
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/big"
+	"net"
+	"os"
+	"time"
+
+	"github.com/blicero/guang/common"
+)
+
+// certLifetime is how long a self-signed certificate is valid.
+const certLifetime = time.Hour * 24 * 365 * 5
+
+// TLSConfig describes how the web frontend uses TLS.
+type TLSConfig struct {
+	// CertFile and KeyFile are the paths of the PEM-encoded certificate
+	// and private key, by default common.CertPath and common.KeyPath.
+	// If neither file exists, a self-signed certificate is generated and
+	// saved there.
+	CertFile string
+	KeyFile  string
+	// ClientCA is the path of a PEM file with one or more CA certificates.
+	// If it is set, clients must present a certificate signed by one of
+	// them before they even get to see the login page.
+	ClientCA string
+}
+
+// tlsConfig loads the certificate (generating it if needed) and returns the
+// configuration for the HTTP server.
+func (srv *WebFrontend) tlsConfig(cfg *TLSConfig, addr string) (*tls.Config, error) {
+	var (
+		err     error
+		cert    tls.Certificate
+		pemData []byte
+		tcfg    = &tls.Config{MinVersion: tls.VersionTLS12}
+	)
+
+	if cfg.CertFile == "" {
+		cfg.CertFile = common.CertPath
+	}
+
+	if cfg.KeyFile == "" {
+		cfg.KeyFile = common.KeyPath
+	}
+
+	if err = srv.ensureCert(cfg, addr); err != nil {
+		return nil, err
+	} else if cert, err = tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile); err != nil {
+		err = fmt.Errorf("Cannot load TLS certificate %s: %w",
+			cfg.CertFile,
+			err)
+		srv.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	tcfg.Certificates = []tls.Certificate{cert}
+
+	if cfg.ClientCA == "" {
+		return tcfg, nil
+	} else if pemData, err = os.ReadFile(cfg.ClientCA); err != nil {
+		err = fmt.Errorf("Cannot read client CA %s: %w", cfg.ClientCA, err)
+		srv.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	tcfg.ClientCAs = x509.NewCertPool()
+	if !tcfg.ClientCAs.AppendCertsFromPEM(pemData) {
+		err = fmt.Errorf("No certificates found in client CA %s", cfg.ClientCA)
+		srv.log.Printf("[ERROR] %s\n", err.Error())
+		return nil, err
+	}
+
+	tcfg.ClientAuth = tls.RequireAndVerifyClientCert
+	return tcfg, nil
+} // func (srv *WebFrontend) tlsConfig(cfg *TLSConfig, addr string) (*tls.Config, error)
+
+// ensureCert generates a self-signed certificate if neither the certificate
+// nor the key file exist. If only one of them exists, something is amiss,
+// and we do not touch either.
+func (srv *WebFrontend) ensureCert(cfg *TLSConfig, addr string) error {
+	var (
+		err                     error
+		certMissing, keyMissing bool
+	)
+
+	if _, err = os.Stat(cfg.CertFile); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		certMissing = true
+	}
+
+	if _, err = os.Stat(cfg.KeyFile); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		keyMissing = true
+	}
+
+	if !certMissing && !keyMissing {
+		return nil
+	} else if certMissing != keyMissing {
+		err = fmt.Errorf("Only one of certificate (%s) and key (%s) exists",
+			cfg.CertFile,
+			cfg.KeyFile)
+		srv.log.Printf("[ERROR] %s\n", err.Error())
+		return err
+	}
+
+	srv.log.Printf("[INFO] Generating self-signed certificate %s\n",
+		cfg.CertFile)
+
+	if err = generateCert(cfg.CertFile, cfg.KeyFile, srv.Hostname, addr); err != nil {
+		srv.log.Printf("[ERROR] Cannot generate certificate: %s\n",
+			err.Error())
+		return err
+	}
+
+	return nil
+} // func (srv *WebFrontend) ensureCert(cfg *TLSConfig, addr string) error
+
+// generateCert creates a self-signed certificate for the given hostname,
+// localhost and - unless it is empty or unspecified - the listen address,
+// and saves it and its key to certPath and keyPath.
+func generateCert(certPath, keyPath, hostname, addr string) error {
+	var (
+		err      error
+		key      *ecdsa.PrivateKey
+		serial   *big.Int
+		der, kdr []byte
+		now      = time.Now()
+		tmpl     = &x509.Certificate{
+			Subject: pkix.Name{
+				Organization: []string{common.AppName},
+				CommonName:   hostname,
+			},
+			NotBefore:             now.Add(-time.Hour),
+			NotAfter:              now.Add(certLifetime),
+			KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+			ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+			BasicConstraintsValid: true,
+			IsCA:                  true,
+			DNSNames:              []string{"localhost"},
+			IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		}
+	)
+
+	if hostname != "" && hostname != "localhost" {
+		tmpl.DNSNames = append(tmpl.DNSNames, hostname)
+	}
+
+	if ip := net.ParseIP(addr); ip != nil {
+		if !ip.IsUnspecified() && !ip.IsLoopback() {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		}
+	} else if addr != "" && addr != "localhost" && addr != hostname {
+		tmpl.DNSNames = append(tmpl.DNSNames, addr)
+	}
+
+	if serial, err = rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128)); err != nil {
+		return fmt.Errorf("Cannot generate serial number: %w", err)
+	}
+
+	tmpl.SerialNumber = serial
+
+	if key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+		return fmt.Errorf("Cannot generate key: %w", err)
+	} else if der, err = x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key); err != nil {
+		return fmt.Errorf("Cannot create certificate: %w", err)
+	} else if kdr, err = x509.MarshalECPrivateKey(key); err != nil {
+		return fmt.Errorf("Cannot encode key: %w", err)
+	} else if err = writePEM(keyPath, "EC PRIVATE KEY", kdr, 0600); err != nil {
+		return err
+	} else if err = writePEM(certPath, "CERTIFICATE", der, 0644); err != nil {
+		os.Remove(keyPath) // nolint: errcheck
+		return err
+	}
+
+	return nil
+} // func generateCert(certPath, keyPath, hostname, addr string) error
+
+// writePEM writes a single PEM block to a new file.
+func writePEM(path, blockType string, der []byte, mode os.FileMode) error {
+	var (
+		err error
+		fh  *os.File
+	)
+
+	if fh, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode); err != nil {
+		return fmt.Errorf("Cannot create %s: %w", path, err)
+	}
+
+	if err = pem.Encode(fh, &pem.Block{Type: blockType, Bytes: der}); err != nil {
+		fh.Close()      // nolint: errcheck
+		os.Remove(path) // nolint: errcheck
+		return fmt.Errorf("Cannot write %s: %w", path, err)
+	} else if err = fh.Close(); err != nil {
+		return fmt.Errorf("Cannot write %s: %w", path, err)
+	}
+
+	return nil
+} // func writePEM(path, blockType string, der []byte, mode os.FileMode) error
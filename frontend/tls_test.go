@@ -0,0 +1,105 @@
+// /home/krylon/go/src/github.com/blicero/guang/frontend/tls_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 14:35:54 krylon>
+
+package frontend
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTLS(t *testing.T) {
+	var (
+		err     error
+		srv     *WebFrontend
+		certPEM []byte
+		again   []byte
+		info    os.FileInfo
+		res     *http.Response
+		dir     = t.TempDir()
+		cfg     = &TLSConfig{
+			CertFile: filepath.Join(dir, "test.crt"),
+			KeyFile:  filepath.Join(dir, "test.key"),
+		}
+	)
+
+	if srv, err = Create("localhost", 4715, cfg, nil); err != nil {
+		t.Fatalf("Error creating Web Frontend with TLS: %s", err.Error())
+	} else if srv.srv.TLSConfig == nil {
+		t.Fatal("Web Frontend has no TLS configuration")
+	} else if certPEM, err = os.ReadFile(cfg.CertFile); err != nil {
+		t.Fatalf("Certificate was not generated: %s", err.Error())
+	} else if info, err = os.Stat(cfg.KeyFile); err != nil {
+		t.Fatalf("Key was not generated: %s", err.Error())
+	} else if info.Mode().Perm() != 0600 {
+		t.Errorf("Key file is accessible to others: %s", info.Mode())
+	}
+
+	// The second time around, the certificate is reused.
+	if _, err = Create("localhost", 4715, cfg, nil); err != nil {
+		t.Fatalf("Error creating Web Frontend with existing certificate: %s",
+			err.Error())
+	} else if again, err = os.ReadFile(cfg.CertFile); err != nil {
+		t.Fatalf("Cannot read certificate: %s", err.Error())
+	} else if !bytes.Equal(certPEM, again) {
+		t.Error("Existing certificate was replaced")
+	}
+
+	var (
+		pool   = x509.NewCertPool()
+		server = httptest.NewUnstartedServer(srv.router)
+		client = &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: pool},
+			},
+		}
+	)
+
+	pool.AppendCertsFromPEM(certPEM)
+	server.TLS = srv.srv.TLSConfig
+	server.StartTLS()
+	defer server.Close()
+
+	// httptest listens on 127.0.0.1, which the certificate must cover.
+	if res, err = client.Get(server.URL + "/login"); err != nil {
+		t.Fatalf("Cannot connect to TLS server: %s", err.Error())
+	}
+
+	res.Body.Close() // nolint: errcheck
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("GET /login returned %d", res.StatusCode)
+	}
+
+	// If only one of the files exists, we refuse to overwrite it.
+	os.Remove(cfg.KeyFile) // nolint: errcheck
+
+	if _, err = Create("localhost", 4715, cfg, nil); err == nil {
+		t.Error("Creating the Web Frontend without a key should fail")
+	}
+
+	cfg.ClientCA = filepath.Join(dir, "nonexistent.pem")
+	os.Remove(cfg.CertFile) // nolint: errcheck
+
+	if _, err = Create("localhost", 4715, cfg, nil); err == nil {
+		t.Error("Creating the Web Frontend with a missing client CA should fail")
+	} else if srv, err = Create("localhost", 4715, &TLSConfig{
+		CertFile: cfg.CertFile,
+		KeyFile:  cfg.KeyFile,
+		ClientCA: cfg.CertFile,
+	}, nil); err != nil {
+		t.Fatalf("Error creating Web Frontend with client CA: %s", err.Error())
+	} else if srv.srv.TLSConfig.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Errorf("Client certificates are not required: %s",
+			srv.srv.TLSConfig.ClientAuth)
+	}
+} // func TestTLS(t *testing.T)
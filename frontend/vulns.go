@@ -0,0 +1,182 @@
+// /home/krylon/go/src/github.com/blicero/guang/frontend/vulns.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 15:40:22 krylon>
+
+package frontend
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/blicero/guang/backend/facility"
+	"github.com/blicero/guang/common"
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/database"
+	"github.com/blicero/krylib"
+)
+
+// This file contains the handlers for the vulnerabilities that affect the
+// software found on the scanned Ports.
+
+const (
+	vulnReportSize    = 100
+	vulnReportSizeMax = 1000
+)
+
+type tmplDataVulns struct {
+	tmplDataIndex
+	Hosts []data.VulnerableHost
+	Known int64
+}
+
+func (srv *WebFrontend) handleVulns(w http.ResponseWriter, r *http.Request) {
+	var (
+		err      error
+		db       database.HostDB
+		tmplData = tmplDataVulns{
+			tmplDataIndex: tmplDataIndex{
+				Title:      "Vulnerabilities",
+				Debug:      common.Debug,
+				Facilities: facility.All(),
+			},
+		}
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	tmplData.setAuth(r)
+
+	db = srv.dbPool.Get()
+	defer srv.dbPool.Put(db)
+
+	if tmplData.Known, err = db.VulnGetCount(r.Context()); err != nil {
+		srv.sendErrorMessage(w, fmt.Sprintf("Cannot count Vulnerabilities: %s", err.Error()))
+		return
+	} else if tmplData.Hosts, err = db.VulnGetHostReport(r.Context(), vulnReportSize); err != nil {
+		srv.sendErrorMessage(w, fmt.Sprintf("Cannot load vulnerable Hosts: %s", err.Error()))
+		return
+	}
+
+	srv.render(w, "vulns", http.StatusOK, tmplData)
+} // func (srv *WebFrontend) handleVulns(w http.ResponseWriter, r *http.Request)
+
+func convertVulnMatch(m *data.VulnMatch) apiVulnMatch {
+	var v = apiVulnMatch{
+		Name:     m.Vuln.Name,
+		Summary:  m.Vuln.Summary,
+		Score:    m.Vuln.Score,
+		Severity: m.Vuln.Severity.String(),
+		Source:   m.Vuln.Source,
+		URL:      vulnURL(m.Vuln),
+		HostID:   int64(m.HostID),
+		PortID:   int64(m.PortID),
+		Port:     m.Port,
+		Product:  m.Product,
+		Version:  m.Version,
+	}
+
+	if !m.Vuln.Published.IsZero() {
+		var stamp = m.Vuln.Published
+		v.Published = &stamp
+	}
+
+	return v
+} // func convertVulnMatch(m *data.VulnMatch) apiVulnMatch
+
+func (srv *WebFrontend) handleAPIHostVulns(w http.ResponseWriter, r *http.Request) {
+	var (
+		err   error
+		db    database.HostDB
+		id    krylib.ID
+		ok    bool
+		num   uint64
+		port  uint16
+		list  []data.VulnMatch
+		items = make([]apiVulnMatch, 0)
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	if s := r.FormValue("port"); s != "" {
+		if num, err = strconv.ParseUint(s, 10, 16); err != nil || num == 0 {
+			srv.apiError(w, r, http.StatusBadRequest, "Invalid port number %q", s)
+			return
+		}
+		port = uint16(num)
+	}
+
+	db = srv.dbPool.Get()
+	defer srv.dbPool.Put(db)
+
+	if id, ok = srv.apiHostID(w, r, db); !ok {
+		return
+	} else if list, err = db.VulnGetByHost(r.Context(), id); err != nil {
+		srv.apiError(w, r, http.StatusInternalServerError,
+			"Error loading Vulnerabilities of Host #%d: %s",
+			id,
+			err.Error())
+		return
+	}
+
+	for i := range list {
+		if port == 0 || list[i].Port == port {
+			items = append(items, convertVulnMatch(&list[i]))
+		}
+	}
+
+	srv.apiRespond(w, r, 200, items)
+} // func (srv *WebFrontend) handleAPIHostVulns(w http.ResponseWriter, r *http.Request)
+
+func (srv *WebFrontend) handleAPIVulnHosts(w http.ResponseWriter, r *http.Request) {
+	var (
+		err   error
+		db    database.HostDB
+		limit = vulnReportSize
+		list  []data.VulnerableHost
+	)
+
+	if common.Debug {
+		srv.log.Printf("[TRACE] Handling request for %s\n", r.RequestURI)
+	}
+
+	if s := r.FormValue("limit"); s != "" {
+		if limit, err = strconv.Atoi(s); err != nil || limit < 1 || limit > vulnReportSizeMax {
+			srv.apiError(w, r, http.StatusBadRequest,
+				"Invalid limit %q, must be between 1 and %d",
+				s,
+				vulnReportSizeMax)
+			return
+		}
+	}
+
+	db = srv.dbPool.Get()
+	defer srv.dbPool.Put(db)
+
+	if list, err = db.VulnGetHostReport(r.Context(), limit); err != nil {
+		srv.apiError(w, r, http.StatusInternalServerError,
+			"Error loading vulnerable Hosts: %s",
+			err.Error())
+		return
+	}
+
+	var items = make([]apiVulnerableHost, len(list))
+
+	for i, h := range list {
+		items[i] = apiVulnerableHost{
+			HostID:   int64(h.HostID),
+			Address:  h.Address.String(),
+			Name:     h.Name,
+			Vulns:    h.Vulns,
+			MaxScore: h.MaxScore,
+		}
+	}
+
+	srv.apiRespond(w, r, 200, items)
+} // func (srv *WebFrontend) handleAPIVulnHosts(w http.ResponseWriter, r *http.Request)
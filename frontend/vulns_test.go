@@ -0,0 +1,144 @@
+// /home/krylon/go/src/github.com/blicero/guang/frontend/vulns_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 15:40:22 krylon>
+
+package frontend
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blicero/guang/auth"
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/database"
+	"github.com/blicero/guang/rules/severity"
+)
+
+func TestVulns(t *testing.T) {
+	var (
+		err     error
+		srv     *WebFrontend
+		db      database.HostDB
+		ports   []data.Port
+		port    *data.Port
+		matches []apiVulnMatch
+		hosts   []apiVulnerableHost
+		ctx     = context.Background()
+		reply   = "SSH-2.0-OpenSSH_7.4p1 Debian-10+deb9u7"
+		host    = data.Host{
+			Address: net.ParseIP("198.51.100.62"),
+			Name:    "vulnerable.example.org",
+			Source:  data.HostSourceUser,
+		}
+		res = data.ScanResult{
+			Port:  22,
+			Reply: &reply,
+			Software: data.Software{
+				Service: "ssh",
+				Product: "OpenSSH",
+				Version: "7.4p1",
+				CPE:     "cpe:/a:openbsd:openssh:7.4p1",
+			},
+			Stamp: time.Now(),
+		}
+		v = data.Vulnerability{
+			Name:     "CVE-2018-15473",
+			Summary:  "OpenSSH through 7.7 is prone to a user enumeration vulnerability.",
+			Score:    5.3,
+			Severity: severity.Medium,
+			Source:   "NVD",
+			Ranges: []data.AffectedRange{
+				{Vendor: "openbsd", Product: "openssh", EndIncl: "7.7"},
+			},
+		}
+	)
+
+	if srv, err = Create("", 4723, nil, nil); err != nil {
+		t.Fatalf("Error creating Web Frontend: %s", err.Error())
+	}
+
+	var token = testToken(t, srv, testUser(t, srv, "vulnviewer", auth.ReadOnly))
+
+	db = srv.dbPool.Get()
+	if _, err = db.HostUpsert(ctx, &host); err != nil {
+		t.Fatalf("Error adding Host: %s", err.Error())
+	}
+	res.Host = host
+	if err = db.PortAdd(ctx, &res); err != nil {
+		t.Fatalf("Error adding Port: %s", err.Error())
+	} else if err = db.VulnAdd(ctx, &v); err != nil {
+		t.Fatalf("Error adding Vulnerability: %s", err.Error())
+	} else if ports, err = db.PortGetIdentified(ctx); err != nil {
+		t.Fatalf("Error loading identified Ports: %s", err.Error())
+	}
+
+	for i := range ports {
+		if ports[i].HostID == host.ID {
+			port = &ports[i]
+		}
+	}
+
+	if port == nil {
+		t.Fatalf("Port 22 of %s is missing from identified Ports", host.Address)
+	} else if err = db.PortVulnSet(ctx, port, []data.Vulnerability{v}); err != nil {
+		t.Fatalf("Error adding Vulnerability to Port: %s", err.Error())
+	}
+	srv.dbPool.Put(db)
+
+	var uri = fmt.Sprintf("/api/v1/hosts/%d/vulns", host.ID)
+
+	if code := apiRequest(t, srv, token, "GET", uri, "", &matches); code != http.StatusOK {
+		t.Fatalf("GET %s returned %d", uri, code)
+	} else if len(matches) != 1 || matches[0].Name != v.Name || matches[0].Port != 22 || matches[0].Severity != "Medium" {
+		t.Errorf("Unexpected vulnerabilities: %#v", matches)
+	} else if !strings.HasSuffix(matches[0].URL, "/CVE-2018-15473") {
+		t.Errorf("Unexpected URL: %s", matches[0].URL)
+	}
+
+	if code := apiRequest(t, srv, token, "GET", uri+"?port=80", "", &matches); code != http.StatusOK {
+		t.Errorf("GET %s?port=80 returned %d", uri, code)
+	} else if len(matches) != 0 {
+		t.Errorf("Port 80 should not have any vulnerabilities: %#v", matches)
+	}
+
+	if code := apiRequest(t, srv, token, "GET", uri+"?port=none", "", nil); code != http.StatusBadRequest {
+		t.Errorf("GET %s with an invalid port returned %d", uri, code)
+	} else if code = apiRequest(t, srv, token, "GET", "/api/v1/hosts/999999/vulns", "", nil); code != http.StatusNotFound {
+		t.Errorf("GET vulnerabilities of a missing Host returned %d", code)
+	} else if code = apiRequest(t, srv, token, "GET", "/api/v1/vulns/hosts?limit=0", "", nil); code != http.StatusBadRequest {
+		t.Errorf("GET /api/v1/vulns/hosts with an invalid limit returned %d", code)
+	} else if code = apiRequest(t, srv, token, "GET", "/api/v1/vulns/hosts", "", &hosts); code != http.StatusOK {
+		t.Fatalf("GET /api/v1/vulns/hosts returned %d", code)
+	} else if len(hosts) == 0 || hosts[0].HostID != int64(host.ID) || hosts[0].Vulns != 1 {
+		t.Errorf("Unexpected report: %#v", hosts)
+	}
+
+	var pages = map[string]string{
+		"/vulns":                         fmt.Sprintf("/host/%d", host.ID),
+		fmt.Sprintf("/host/%d", host.ID): "CVE-2018-15473",
+	}
+
+	for page, expect := range pages {
+		var (
+			req = httptest.NewRequest("GET", page, nil)
+			rec = httptest.NewRecorder()
+		)
+
+		req.Header.Set("Authorization", "Bearer "+token)
+		srv.router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %s returned %d", page, rec.Code)
+		} else if !strings.Contains(rec.Body.String(), expect) {
+			t.Errorf("%s is missing from %s", expect, page)
+		}
+	}
+} // func TestVulns(t *testing.T)
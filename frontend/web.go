@@ -2,7 +2,7 @@
 // -*- coding: utf-8; mode: go; -*-
 // Created on 06. 02. 2016 by Benjamin Walkenhorst
 // (c) 2016 Benjamin Walkenhorst
-// Time-stamp: <2024-06-03 19:33:08 krylon>
+// Time-stamp: <2026-10-19 16:56:40 krylon>
 
 package frontend
 
@@ -12,11 +12,13 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
-	"log"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
+	"strings"
 	"sync"
 	"text/template"
 	"time"
@@ -26,10 +28,14 @@ import (
 	"github.com/blicero/guang/common"
 	"github.com/blicero/guang/data"
 	"github.com/blicero/guang/database"
+	"github.com/blicero/guang/events"
+	"github.com/blicero/guang/logdomain"
+	"github.com/blicero/guang/search"
 	"github.com/blicero/krylib"
 
 	"github.com/gorilla/mux"
 	"github.com/muesli/cache2go"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 //go:embed html
@@ -44,9 +50,10 @@ const (
 type WebFrontend struct {
 	Port        uint16
 	Hostname    string
+	Addr        string
 	srv         http.Server
 	router      *mux.Router
-	log         *log.Logger
+	log         *common.Logger
 	tmpl        *template.Template
 	isRunning   bool         // nolint: unused
 	lock        sync.RWMutex // nolint: unused
@@ -55,11 +62,13 @@ type WebFrontend struct {
 	hostCache   *cache2go.CacheTable // nolint: unused
 	dbPool      *database.Pool
 	nexus       *backend.Nexus
+	bus         *events.Bus
 	ckPortStamp time.Time
 }
 
-// Create creates a new web frontend.
-func Create(addr string, port uint16, nexus *backend.Nexus) (*WebFrontend, error) {
+// Create creates a new web frontend listening on the given address and port.
+// If tlsCfg is nil, it serves plain HTTP.
+func Create(addr string, port uint16, tlsCfg *TLSConfig, nexus *backend.Nexus) (*WebFrontend, error) {
 	var msg string
 	var err error
 
@@ -70,6 +79,7 @@ func Create(addr string, port uint16, nexus *backend.Nexus) (*WebFrontend, error
 
 	frontend := &WebFrontend{
 		Port: port,
+		Addr: addr,
 		mimeTypes: map[string]string{
 			"css": "text/css",
 			"js":  "text/javascript",
@@ -79,9 +89,13 @@ func Create(addr string, port uint16, nexus *backend.Nexus) (*WebFrontend, error
 		suffixRe: regexp.MustCompile("[.]([^.]+)$"),
 	}
 
+	if nexus != nil {
+		frontend.bus = nexus.Events()
+	}
+
 	if frontend.Hostname, err = os.Hostname(); err != nil {
 		return nil, err
-	} else if frontend.log, err = common.GetLogger("Web"); err != nil {
+	} else if frontend.log, err = common.GetLogger(logdomain.Web); err != nil {
 		return nil, err
 	}
 
@@ -89,15 +103,50 @@ func Create(addr string, port uint16, nexus *backend.Nexus) (*WebFrontend, error
 	frontend.router.HandleFunc("/{pagename:(?:index|start|main)?$}", frontend.handleIndex)
 	frontend.router.HandleFunc("/by_port", frontend.handleByPort)
 	frontend.router.HandleFunc("/by_host", frontend.handleByHost)
+	frontend.router.HandleFunc("/search", frontend.handleSearch)
+	frontend.router.HandleFunc("/host/{id:[0-9]+}", frontend.handleHost).Methods("GET")
+	frontend.router.HandleFunc("/host/{id:[0-9]+}/rescan", frontend.operator(frontend.handleHostRescan)).Methods("POST")
+	frontend.router.HandleFunc("/host/{id:[0-9]+}/metadata", frontend.operator(frontend.handleHostMetadata)).Methods("POST")
+	frontend.router.HandleFunc("/host/{id:[0-9]+}/exclude", frontend.operator(frontend.handleHostExclude)).Methods("POST")
+	frontend.router.HandleFunc("/host/{id:[0-9]+}/note", frontend.operator(frontend.handleHostNote)).Methods("POST")
+	frontend.router.HandleFunc("/host/{id:[0-9]+}/tag", frontend.operator(frontend.handleHostTag)).Methods("POST")
+	frontend.router.HandleFunc("/host/{id:[0-9]+}/untag", frontend.operator(frontend.handleHostUntag)).Methods("POST")
+	frontend.router.HandleFunc("/host/{id:[0-9]+}/note/{note:[0-9]+}/delete", frontend.operator(frontend.handleHostNoteDelete)).Methods("POST")
+	frontend.router.HandleFunc("/rules", frontend.handleRules).Methods("GET")
+	frontend.router.HandleFunc("/software", frontend.handleSoftware).Methods("GET")
+	frontend.router.HandleFunc("/networks", frontend.handleNetworks).Methods("GET")
+	frontend.router.HandleFunc("/map", frontend.handleMap).Methods("GET")
+	frontend.router.HandleFunc("/vulns", frontend.handleVulns).Methods("GET")
+	frontend.router.HandleFunc("/rules", frontend.operator(frontend.handleRuleAdd)).Methods("POST")
+	frontend.router.HandleFunc("/rules/evaluate", frontend.operator(frontend.handleRulesEvaluate)).Methods("POST")
+	frontend.router.HandleFunc("/rules/{id:[0-9]+}/edit", frontend.operator(frontend.handleRuleUpdate)).Methods("POST")
+	frontend.router.HandleFunc("/rules/{id:[0-9]+}/delete", frontend.operator(frontend.handleRuleDelete)).Methods("POST")
 	frontend.router.HandleFunc("/static/{file}", frontend.handleStaticFile)
 
+	// Users and sessions
+	frontend.router.HandleFunc("/login", frontend.handleLogin).Methods("GET", "POST")
+	frontend.router.HandleFunc("/logout", frontend.handleLogout).Methods("POST")
+	frontend.router.HandleFunc("/account", frontend.handleAccount).Methods("GET")
+	frontend.router.HandleFunc("/account/password", frontend.handlePasswordChange).Methods("POST")
+	frontend.router.HandleFunc("/account/token", frontend.handleTokenAdd).Methods("POST")
+	frontend.router.HandleFunc("/account/token/{id:[0-9]+}/delete", frontend.handleTokenDelete).Methods("POST")
+
 	// AJAX handlers
 	frontend.router.HandleFunc("/ajax/beacon", frontend.handleBeacon)
 	frontend.router.HandleFunc("/ajax/port_recent/{stamp:(?:\\d+$)}", frontend.handlePortsRecent)
-	frontend.router.HandleFunc("/ajax/spawn_worker/{facility:(?:\\d+)}/{cnt:(?:\\d+$)}", frontend.handleWorkerSpawn)
-	frontend.router.HandleFunc("/ajax/stop_worker/{facility:(?:\\d+)}/{cnt:(?:\\d+$)}", frontend.handleWorkerStop)
+	frontend.router.HandleFunc("/ajax/spawn_worker/{facility:(?:\\d+)}/{cnt:(?:\\d+$)}", frontend.operator(frontend.handleWorkerSpawn)).Methods("POST")
+	frontend.router.HandleFunc("/ajax/stop_worker/{facility:(?:\\d+)}/{cnt:(?:\\d+$)}", frontend.operator(frontend.handleWorkerStop)).Methods("POST")
 	frontend.router.HandleFunc("/ajax/worker_count", frontend.handleWorkerCount)
-	frontend.router.HandleFunc("/ajax/update_metadata", frontend.handleUpdateMetadata)
+	frontend.router.HandleFunc("/ajax/update_metadata", frontend.operator(frontend.handleUpdateMetadata)).Methods("POST")
+	frontend.router.HandleFunc("/api/search", frontend.handleAPISearch)
+	frontend.router.HandleFunc("/events", frontend.handleEvents).Methods("GET")
+	frontend.router.Handle("/metrics", promhttp.Handler()).Methods("GET")
+
+	// REST API
+	frontend.registerAPI()
+
+	// Everything except the login page and static files requires a user.
+	frontend.router.Use(frontend.authenticate)
 
 	frontend.tmpl = template.New("").Funcs(funcmap)
 
@@ -106,8 +155,7 @@ func Create(addr string, port uint16, nexus *backend.Nexus) (*WebFrontend, error
 	var tmplRe = regexp.MustCompile("[.]tmpl$")
 
 	if templates, err = assets.ReadDir(tmplFolder); err != nil {
-		frontend.log.Printf("[ERROR] Cannot read embedded templates: %s\n",
-			err.Error())
+		frontend.log.Error("Cannot read embedded templates", "err", err)
 		return nil, err
 	}
 
@@ -123,43 +171,64 @@ func Create(addr string, port uint16, nexus *backend.Nexus) (*WebFrontend, error
 			msg = fmt.Sprintf("Cannot read embedded file %s: %s",
 				path,
 				err.Error())
-			frontend.log.Printf("[CRITICAL] %s\n", msg)
+			frontend.log.Error(msg)
 			return nil, errors.New(msg)
 		} else if frontend.tmpl, err = frontend.tmpl.Parse(string(content)); err != nil {
 			msg = fmt.Sprintf("Could not parse template %s: %s",
 				entry.Name(),
 				err.Error())
-			frontend.log.Println("[CRITICAL] " + msg)
+			frontend.log.Error(msg)
 			return nil, errors.New(msg)
 		}
 
-		frontend.log.Printf("[TRACE] Template \"%s\" was parsed successfully.\n",
-			entry.Name())
+		frontend.log.Trace("Template was parsed successfully", "template", entry.Name())
 	}
 
-	frontend.srv.Addr = fmt.Sprintf("%s:%d", addr, port)
-	frontend.srv.ErrorLog = frontend.log
+	frontend.srv.Addr = net.JoinHostPort(addr, strconv.Itoa(int(port)))
+	frontend.srv.ErrorLog = frontend.log.Logger
 	frontend.srv.Handler = frontend.router
 
+	if tlsCfg != nil {
+		if frontend.srv.TLSConfig, err = frontend.tlsConfig(tlsCfg, addr); err != nil {
+			return nil, err
+		}
+	}
+
 	if frontend.dbPool, err = database.NewPool(dbPoolSize); err != nil {
 		msg = fmt.Sprintf("Failed to create database connection pool: %s",
 			err.Error())
-		frontend.log.Printf("[ERROR] %s\n", msg)
+		frontend.log.Error(msg)
+		return nil, errors.New(msg)
+	} else if err = frontend.ensureUser(); err != nil {
+		msg = fmt.Sprintf("Failed to create initial user: %s",
+			err.Error())
+		frontend.log.Error(msg)
 		return nil, errors.New(msg)
 	}
 
 	return frontend, nil
-} // func CreateFrontend(addr string, port uint16) (*WebFrontend, error)
+} // func Create(addr string, port uint16, tlsCfg *TLSConfig, nexus *backend.Nexus) (*WebFrontend, error)
 
-// Serve runs the web server.
-func (srv *WebFrontend) Serve() {
-	srv.log.Println("The web server is starting to accept requests now.")
-	http.Handle("/", srv.router)
-	srv.srv.ListenAndServe() // nolint: errcheck
-} // func (srv *WebFrontend) Serve()
+// Serve runs the web server. It only returns if the server fails.
+func (srv *WebFrontend) Serve() error {
+	var err error
+
+	if srv.srv.TLSConfig != nil {
+		srv.log.Info("The web server is starting to accept requests",
+			"url", "https://"+srv.srv.Addr+"/")
+		err = srv.srv.ListenAndServeTLS("", "")
+	} else {
+		srv.log.Info("The web server is starting to accept requests",
+			"url", "http://"+srv.srv.Addr+"/")
+		err = srv.srv.ListenAndServe()
+	}
+
+	srv.log.Error("Web server failed", "err", err)
+	return err
+} // func (srv *WebFrontend) Serve() error
 
 func (srv *WebFrontend) handleIndex(w http.ResponseWriter, request *http.Request) {
-	var db *database.HostDB
+	var db database.HostDB
 	var tmpl *template.Template
 	var err error
 	var msg string
@@ -170,55 +239,54 @@ func (srv *WebFrontend) handleIndex(w http.ResponseWriter, request *http.Request
 	}
 
 	if common.Debug {
-		srv.log.Printf("Handling request for %s", request.RequestURI)
+		srv.log.Trace("Handling request", "uri", request.RequestURI)
 	}
 
+	indexData.setAuth(request)
+
 	db = srv.dbPool.Get()
 	defer srv.dbPool.Put(db)
 
 	if common.Debug {
-		srv.log.Println("Getting generator count")
+		srv.log.Trace("Getting generator count")
 	}
 	indexData.HostGenCnt = srv.nexus.GetGeneratorCount()
 	if common.Debug {
-		srv.log.Println("Getting Scanner count")
+		srv.log.Trace("Getting Scanner count")
 	}
 	indexData.ScanCnt = srv.nexus.GetScannerCount()
 	if common.Debug {
-		srv.log.Println("Getting XFR count")
+		srv.log.Trace("Getting XFR count")
 	}
 	indexData.XFRCnt = srv.nexus.GetXFRCount()
 
 	if common.Debug {
-		srv.log.Println("Getting host count from database.")
+		srv.log.Trace("Getting host count from database")
 	}
-	if indexData.HostCnt, err = db.HostGetCount(); err != nil {
+	if indexData.HostCnt, err = db.HostGetCount(request.Context()); err != nil {
 		msg = fmt.Sprintf("Error getting number of hosts: %s", err.Error())
-		srv.log.Println(msg)
 		srv.sendErrorMessage(w, msg)
 		return
-	} else if indexData.PortReplyCnt, err = db.PortGetReplyCount(); err != nil {
+	} else if indexData.PortReplyCnt, err = db.PortGetReplyCount(request.Context()); err != nil {
 		msg = fmt.Sprintf("Error getting number of scanned ports: %s", err.Error())
-		srv.log.Println(msg)
 		srv.sendErrorMessage(w, msg)
 		return
 	}
 
 	if common.Debug {
-		srv.log.Println("Looking up template")
+		srv.log.Trace("Looking up template")
 	}
 	if tmpl = srv.tmpl.Lookup("index"); tmpl == nil {
 		msg = "Template 'index' was not found!"
-		srv.log.Println(msg)
 		srv.sendErrorMessage(w, msg)
 	} else {
 		w.WriteHeader(200)
 		if err = tmpl.Execute(w, indexData); err != nil {
-			msg = fmt.Sprintf("Error rendering template or sending output to client: %s",
-				err.Error())
-			srv.log.Println(msg)
+			srv.log.Warn("Cannot render template or send output to client",
+				"uri", request.RequestURI,
+				"err", err)
 		} else if common.Debug {
-			srv.log.Println("We sure showed THAT client a nice index!")
+			srv.log.Trace("We sure showed THAT client a nice index!")
 		}
 	}
 } // func (srv *WebFrontend) HandleIndex(w http.ResponseWriter, request *http.Request)
@@ -226,26 +294,24 @@ func (srv *WebFrontend) handleIndex(w http.ResponseWriter, request *http.Request
 func (srv *WebFrontend) handleByPort(w http.ResponseWriter, request *http.Request) {
 	var err error
 	var msg string
-	var db *database.HostDB
+	var db database.HostDB
 	var tmplData tmplDataByPort
 	var dbRes []data.ScanResult
 	var tmpl *template.Template
 
 	if common.Debug {
-		srv.log.Printf("[TRACE] Handling request for %s\n", request.RequestURI)
+		srv.log.Trace("Handling request", "uri", request.RequestURI)
 	}
 
 	db = srv.dbPool.Get()
 	defer srv.dbPool.Put(db)
 
-	if dbRes, err = db.PortGetOpen(); err != nil {
+	if dbRes, err = db.PortGetOpen(request.Context(), request.FormValue("tag")); err != nil {
 		msg = fmt.Sprintf("Error getting list of open ports: %s", err.Error())
-		srv.log.Println(msg)
 		srv.sendErrorMessage(w, msg)
 		return
 	} else if tmpl = srv.tmpl.Lookup("by_port"); tmpl == nil {
 		msg = "Template 'by_port' was not found!"
-		srv.log.Println(msg)
 		srv.sendErrorMessage(w, msg)
 		return
 	} else {
@@ -261,22 +327,27 @@ func (srv *WebFrontend) handleByPort(w http.ResponseWriter, request *http.Reques
 			},
 			Count: len(dbRes),
 			Hosts: make(map[krylib.ID]data.Host),
+			Tag:   strings.ToLower(request.FormValue("tag")),
 		}
 
-		if tmplData.HostCnt, err = db.HostGetCount(); err != nil {
+		tmplData.setAuth(request)
+
+		if tmplData.Tags, err = db.TagGetAll(request.Context()); err != nil {
+			msg = fmt.Sprintf("Error getting list of tags: %s", err.Error())
+			srv.sendErrorMessage(w, msg)
+			return
+		} else if tmplData.HostCnt, err = db.HostGetCount(request.Context()); err != nil {
 			msg = fmt.Sprintf("Error getting number of hosts: %s", err.Error())
-			srv.log.Println(msg)
 			srv.sendErrorMessage(w, msg)
 			return
-		} else if tmplData.PortReplyCnt, err = db.PortGetReplyCount(); err != nil {
+		} else if tmplData.PortReplyCnt, err = db.PortGetReplyCount(request.Context()); err != nil {
 			msg = fmt.Sprintf("Error getting number of scanned ports: %s", err.Error())
-			srv.log.Println(msg)
 			srv.sendErrorMessage(w, msg)
 			return
 		}
 
 		if common.Debug {
-			srv.log.Println("*Trying* to sort results.")
+			srv.log.Trace("*Trying* to sort results")
 		}
 		results := make(map[uint16]reportInfoPort)
 
@@ -299,64 +370,14 @@ func (srv *WebFrontend) handleByPort(w http.ResponseWriter, request *http.Reques
 			}
 		}
 
-		var me *backend.MetaEngine
-
-		if me, err = backend.OpenMetaEngine("GeoLite2-Country.mmdb"); err != nil {
-			msg = fmt.Sprintf("Cannot create MetaEngine: %s\n",
-				err.Error())
-			srv.log.Printf("[ERROR] %s\n", msg)
-			srv.sendErrorMessage(w, msg)
-			return
-		}
-
-		for id, h := range tmplData.Hosts {
-			var (
-				city, country string
-				withPorts     = data.HostWithPorts{Host: h}
-			)
-
-			if withPorts.Ports, err = db.PortGetByHost(id); err != nil {
-				srv.log.Printf("[ERROR] Cannot get open ports for Host %s (%s): %s\n",
-					h.Name,
-					h.Address,
-					err.Error())
-			} else {
-				var operatingSystem = me.LookupOperatingSystem(&withPorts)
-				if operatingSystem != "Unknown" {
-					h.OS = operatingSystem
-				}
-			}
-
-			if country, err = me.LookupCountry(&h); err != nil {
-				srv.log.Printf("[ERROR] Cannot find country for Host %s: %s\n",
-					h.Address,
-					err.Error())
-			} else if city, err = me.LookupCity(&h); err != nil {
-				srv.log.Printf("[ERROR] Cannot find city for Host %s: %s\n",
-					h.Address,
-					err.Error())
-			} else {
-				var loc string
-				if len(city) > 0 {
-					loc = fmt.Sprintf("%s, %s",
-						city,
-						country)
-				} else {
-					loc = country
-				}
-				h.Location = loc
-				tmplData.Hosts[id] = h
-			}
-		}
-
 		tmplData.Ports = results
 		srv.updateCkPortstamp(time.Now())
 
 		w.WriteHeader(200)
 		if err = tmpl.Execute(w, tmplData); err != nil {
-			msg = fmt.Sprintf("Error rendering template or sending output to client: %s",
-				err.Error())
-			srv.log.Println(msg)
+			srv.log.Warn("Cannot render template or send output to client",
+				"uri", request.RequestURI,
+				"err", err)
 		}
 	}
 } // func (srv *WebFrontend) HandleByPort(w http.ResponseWriter, request *http.Request)
@@ -364,12 +385,12 @@ func (srv *WebFrontend) handleByPort(w http.ResponseWriter, request *http.Reques
 func (srv *WebFrontend) handleByHost(w http.ResponseWriter, request *http.Request) {
 	var err error
 	var msg string
-	var db *database.HostDB
+	var db database.HostDB
 	var data tmplDataByHost
 	var tmpl *template.Template
 
 	if common.Debug {
-		srv.log.Printf("Handling request for %s\n", request.RequestURI)
+		srv.log.Trace("Handling request", "uri", request.RequestURI)
 	}
 
 	db = srv.dbPool.Get()
@@ -385,22 +406,26 @@ func (srv *WebFrontend) handleByHost(w http.ResponseWriter, request *http.Reques
 			ScanCnt:    srv.nexus.GetScannerCount(),
 			XFRCnt:     srv.nexus.GetXFRCount(),
 		},
+		Tag: strings.ToLower(request.FormValue("tag")),
 	}
 
-	if data.Hosts, err = db.HostGetByHostReport(); err != nil {
+	data.setAuth(request)
+
+	if data.Tags, err = db.TagGetAll(request.Context()); err != nil {
+		msg = fmt.Sprintf("Error getting list of tags: %s", err.Error())
+		srv.sendErrorMessage(w, msg)
+		return
+	} else if data.Hosts, err = db.HostGetByHostReport(request.Context(), data.Tag); err != nil {
 		msg = fmt.Sprintf("Error getting open ports grouped by Host: %s",
 			err.Error())
-		srv.log.Println(msg)
 		srv.sendErrorMessage(w, msg)
 		return
-	} else if data.HostCnt, err = db.HostGetCount(); err != nil {
+	} else if data.HostCnt, err = db.HostGetCount(request.Context()); err != nil {
 		msg = fmt.Sprintf("Error getting number of hosts: %s", err.Error())
-		srv.log.Println(msg)
 		srv.sendErrorMessage(w, msg)
 		return
-	} else if data.PortReplyCnt, err = db.PortGetReplyCount(); err != nil {
+	} else if data.PortReplyCnt, err = db.PortGetReplyCount(request.Context()); err != nil {
 		msg = fmt.Sprintf("Error getting number of scanned ports: %s", err.Error())
-		srv.log.Println(msg)
 		srv.sendErrorMessage(w, msg)
 		return
 	}
@@ -409,19 +434,94 @@ func (srv *WebFrontend) handleByHost(w http.ResponseWriter, request *http.Reques
 
 	if tmpl = srv.tmpl.Lookup("by_host"); tmpl == nil {
 		msg = "Error: Template 'by_host' was not found!"
-		srv.log.Println(msg)
 		srv.sendErrorMessage(w, msg)
 		return
 	}
 
 	w.WriteHeader(200)
 	if err = tmpl.Execute(w, data); err != nil {
-		msg = fmt.Sprintf("Error rendering template or sending output to client: %s",
-			err.Error())
-		srv.log.Println(msg)
+		srv.log.Warn("Cannot render template or send output to client",
+			"uri", request.RequestURI,
+			"err", err)
 	}
 } // func (srv *WebFrontend) HandleByHost(w http.ResponseWriter, request *http.Request)
 
+func (srv *WebFrontend) handleSearch(w http.ResponseWriter, request *http.Request) {
+	var (
+		err      error
+		msg      string
+		db       database.HostDB
+		q        *search.Query
+		tmpl     *template.Template
+		tmplData = tmplDataSearch{
+			tmplDataIndex: tmplDataIndex{
+				Title:      "Search",
+				Debug:      common.Debug,
+				Facilities: facility.All(),
+				Error:      make([]string, 0),
+				HostGenCnt: srv.nexus.GetGeneratorCount(),
+				ScanCnt:    srv.nexus.GetScannerCount(),
+				XFRCnt:     srv.nexus.GetXFRCount(),
+			},
+			Query: request.FormValue("q"),
+		}
+	)
+
+	if common.Debug {
+		srv.log.Trace("Handling request", "uri", request.RequestURI)
+	}
+
+	tmplData.setAuth(request)
+
+	db = srv.dbPool.Get()
+	defer srv.dbPool.Put(db)
+
+	if tmplData.HostCnt, err = db.HostGetCount(request.Context()); err != nil {
+		msg = fmt.Sprintf("Error getting number of hosts: %s", err.Error())
+		srv.sendErrorMessage(w, msg)
+		return
+	} else if tmplData.PortReplyCnt, err = db.PortGetReplyCount(request.Context()); err != nil {
+		msg = fmt.Sprintf("Error getting number of scanned ports: %s", err.Error())
+		srv.sendErrorMessage(w, msg)
+		return
+	}
+
+	if tmplData.Query != "" {
+		var page, perPage = pageParams(request)
+
+		if q, err = search.Parse(tmplData.Query); err != nil {
+			tmplData.Error = append(tmplData.Error,
+				fmt.Sprintf("Invalid query: %s", err.Error()))
+		} else if tmplData.Result, err = db.Search(request.Context(), q, page, perPage); err != nil {
+			msg = fmt.Sprintf("Error searching for %q: %s",
+				tmplData.Query,
+				err.Error())
+			srv.sendErrorMessage(w, msg)
+			return
+		} else {
+			if tmplData.Result.Page > 1 {
+				tmplData.PrevPage = tmplData.Result.Page - 1
+			}
+			if tmplData.Result.Page < tmplData.Result.PageCount() {
+				tmplData.NextPage = tmplData.Result.Page + 1
+			}
+		}
+	}
+
+	if tmpl = srv.tmpl.Lookup("search"); tmpl == nil {
+		msg = "Error: Template 'search' was not found!"
+		srv.sendErrorMessage(w, msg)
+		return
+	}
+
+	w.WriteHeader(200)
+	if err = tmpl.Execute(w, tmplData); err != nil {
+		srv.log.Warn("Cannot render template or send output to client",
+			"uri", request.RequestURI,
+			"err", err)
+	}
+} // func (srv *WebFrontend) handleSearch(w http.ResponseWriter, request *http.Request)
+
 func (srv *WebFrontend) handleStaticFile(w http.ResponseWriter, request *http.Request) {
 	vars := mux.Vars(request)
 	filename := vars["file"]
@@ -429,7 +529,7 @@ func (srv *WebFrontend) handleStaticFile(w http.ResponseWriter, request *http.Re
 	var mimeType string
 
 	if common.Debug {
-		srv.log.Printf("Delivering static file %s to client\n", filename)
+		srv.log.Trace("Delivering static file to client", "file", filename)
 	}
 
 	var match []string
@@ -439,7 +539,7 @@ func (srv *WebFrontend) handleStaticFile(w http.ResponseWriter, request *http.Re
 	} else if mime, ok := srv.mimeTypes[match[1]]; ok {
 		mimeType = mime
 	} else {
-		srv.log.Printf("Kein MIME-Type gefunden fÃ¼r %s\n", filename)
+		srv.log.Warn("No MIME type found", "file", filename)
 	}
 
 	w.Header().Set("Content-Type", mimeType)
@@ -482,7 +582,7 @@ func (srv *WebFrontend) sendErrorMessage(w http.ResponseWriter, msg string) {
 </html>
 `
 
-	srv.log.Println(msg)
+	srv.log.Error(msg)
 
 	output := fmt.Sprintf(html, msg)
 	w.WriteHeader(500)
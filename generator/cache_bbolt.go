@@ -2,17 +2,17 @@
 // -*- mode: go; coding: utf-8; -*-
 // Created on 25. 11. 2022 by Benjamin Walkenhorst
 // (c) 2022 Benjamin Walkenhorst
-// Time-stamp: <2022-12-16 18:35:37 krylon>
+// Time-stamp: <2026-10-19 14:47:35 krylon>
 
 package generator
 
 import (
 	"fmt"
-	"log"
 	"sync"
 	"time"
 
 	"github.com/blicero/guang/common"
+	"github.com/blicero/guang/logdomain"
 	"github.com/blicero/krylib"
 	"go.etcd.io/bbolt"
 )
@@ -23,7 +23,7 @@ var openLock sync.Mutex
 
 type bboltCache struct {
 	path  string
-	log   *log.Logger
+	log   *common.Logger
 	cache *bbolt.DB
 }
 
@@ -50,7 +50,7 @@ func openBoltCache(path string) (cache, error) { // nolint: unused
 		FreelistType: "hashmap",
 	}
 
-	if c.log, err = common.GetLogger("Generator/Cache"); err != nil {
+	if c.log, err = common.GetLogger(logdomain.Cache); err != nil {
 		return nil, err
 	} else if c.cache, err = bbolt.Open(path, 0600, &opt); err != nil {
 		c.log.Printf("[ERROR] Cannot open host cache at %q: %s\n",
@@ -2,22 +2,21 @@
 // -*- mode: go; coding: utf-8; -*-
 // Created on 24. 11. 2022 by Benjamin Walkenhorst
 // (c) 2022 Benjamin Walkenhorst
-// Time-stamp: <2022-12-21 21:13:12 krylon>
+// Time-stamp: <2026-10-19 14:47:35 krylon>
 
 // +build ignore
 
 package generator
 
 import (
-	"log"
-
 	"github.com/blicero/guang/common"
+	"github.com/blicero/guang/logdomain"
 	"github.com/fsouza/gokabinet/kc"
 )
 
 type kyotoCache struct {
 	path  string
-	log   *log.Logger
+	log   *common.Logger
 	cache *kc.DB
 }
 
@@ -27,7 +26,7 @@ func openKyotoCache(path string) (cache, error) {
 		kyCache = &kyotoCache{path: path}
 	)
 
-	if kyCache.log, err = common.GetLogger("Generator/Cache"); err != nil {
+	if kyCache.log, err = common.GetLogger(logdomain.Cache); err != nil {
 		return nil, err
 	} else if kyCache.cache, err = kc.Open(path, kc.WRITE); err != nil {
 		kyCache.log.Printf("[CRITICAL] Cannot open cache at %s: %s\n",
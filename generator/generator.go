@@ -2,7 +2,7 @@
 // -*- coding: utf-8; mode: go; -*-
 // Created on 23. 12. 2015 by Benjamin Walkenhorst
 // (c) 2015 Benjamin Walkenhorst
-// Time-stamp: <2022-12-21 21:13:32 krylon>
+// Time-stamp: <2026-10-19 16:56:40 krylon>
 //
 // IIRC, throughput never was much of an issue with this part of the program.
 // But if it were, there are a few tricks on could pull here.
@@ -13,9 +13,7 @@
 package generator
 
 import (
-	"errors"
 	"fmt"
-	"log"
 	"math/rand"
 	"net"
 	"sync"
@@ -24,6 +22,7 @@ import (
 	"github.com/blicero/guang/blacklist"
 	"github.com/blicero/guang/common"
 	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/logdomain"
 )
 
 var storage = map[string]cacheOpener{
@@ -44,13 +43,12 @@ type HostGenerator struct {
 	running    bool
 	workerCnt  int
 	runningCnt int
-	log        *log.Logger
+	log        *common.Logger
 }
 
 // CreateGenerator creates a new HostGenerator.
 func CreateGenerator(workerCnt int) (*HostGenerator, error) {
 	var err error
-	var msg string
 
 	gen := &HostGenerator{
 		HostQueue: make(chan data.Host, workerCnt*2),
@@ -63,15 +61,14 @@ func CreateGenerator(workerCnt int) (*HostGenerator, error) {
 
 	fn := storage[backendName]
 
-	if gen.log, err = common.GetLogger("Generator"); err != nil {
+	if gen.log, err = common.GetLogger(logdomain.Generator); err != nil {
 		fmt.Printf("Error getting Logger instance for host generator: %s\n",
 			err.Error())
 		return nil, err
 	} else if gen.cache, err = fn(common.HostCachePath); err != nil {
-		msg = fmt.Sprintf("Error opening Host cache at %s: %s",
-			common.HostCachePath, err.Error())
-		gen.log.Println(msg)
-		return nil, errors.New(msg)
+		gen.log.Error("Cannot open Host cache", "path", common.HostCachePath, "err", err)
+		return nil, fmt.Errorf("Error opening Host cache at %s: %w",
+			common.HostCachePath, err)
 	}
 
 	return gen, nil
@@ -107,6 +104,12 @@ func (gen *HostGenerator) Count() int {
 	return cnt
 } // func (gen *HostGenerator) Count() int
 
+// Blacklists returns the blacklists the HostGenerator uses to filter the
+// Hosts it generates.
+func (gen *HostGenerator) Blacklists() (*blacklist.NameBlacklist, *blacklist.IPBlacklist) {
+	return gen.nameBL, gen.addrBL
+} // func (gen *HostGenerator) Blacklists() (*blacklist.NameBlacklist, *blacklist.IPBlacklist)
+
 func (gen *HostGenerator) cntInc() {
 	gen.lock.Lock()
 	gen.runningCnt++
@@ -124,13 +127,13 @@ func (gen *HostGenerator) worker(id int) {
 	defer gen.cntDec()
 
 	var (
-		msg, astr string
-		err       error
-		rng       *rand.Rand = rand.New(rand.NewSource(time.Now().UnixNano()))
-		addr      net.IP
-		known     bool
-		namelist  []string
-		metronom  *time.Ticker
+		astr     string
+		err      error
+		rng      *rand.Rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+		addr     net.IP
+		known    bool
+		namelist []string
+		metronom *time.Ticker
 	)
 
 	metronom = time.NewTicker(common.RCTimeout)
@@ -143,8 +146,7 @@ MAIN_LOOP:
 		case ctl = <-gen.RC:
 			switch ctl {
 			case data.CtlMsgStop:
-				gen.log.Printf("[INFO] Generator worker #%d is quitting\n",
-					id)
+				gen.log.Info("Generator worker is quitting", "worker", id)
 				return
 			case data.CtlMsgShutdown:
 				gen.Stop()
@@ -153,8 +155,7 @@ MAIN_LOOP:
 				var newID = gen.Count() + 1
 				go gen.worker(newID)
 			default:
-				gen.log.Printf("[INFO] Don't know how to handle command %s\n",
-					ctl)
+				gen.log.Warn("Don't know how to handle command", "command", ctl)
 			}
 		case <-metronom.C:
 			// Whatever.
@@ -162,8 +163,7 @@ MAIN_LOOP:
 
 		var host data.Host
 		for addr = gen.getRandIP(rng); gen.addrBL.MatchesIP(addr); addr = gen.getRandIP(rng) {
-			// This loop has no body.
-			// It's all in the head.
+			addressesTried.WithLabelValues(resBlacklisted).Inc()
 		}
 
 		astr = addr.String()
@@ -178,24 +178,30 @@ MAIN_LOOP:
 			// gen.log.Printf("[ERROR] Cannot look for %q in host cache: %s\n",
 			// 	astr,
 			// 	err.Error())
+			cacheLookups.WithLabelValues("error").Inc()
 		} else if known {
+			cacheLookups.WithLabelValues("hit").Inc()
+			addressesTried.WithLabelValues(resKnown).Inc()
 			continue MAIN_LOOP
-		} else if err = gen.cache.AddKey(astr); err != nil {
-			gen.log.Printf("[ERROR] Cannot add %q to host cache: %s\n",
-				astr,
-				err.Error())
+		} else {
+			cacheLookups.WithLabelValues("miss").Inc()
+			if err = gen.cache.AddKey(astr); err != nil {
+				gen.log.Error("Cannot add address to host cache", "address", astr, "err", err)
+			}
 		}
 
 		if namelist, err = net.LookupAddr(astr); err != nil {
+			addressesTried.WithLabelValues(resUnresolved).Inc()
 			continue MAIN_LOOP
 		} else if len(namelist) == 0 {
-			msg = fmt.Sprintf("net.LookupAddr(%s) returned neither an error nor any names",
-				astr)
-			gen.log.Println(msg)
+			gen.log.Warn("net.LookupAddr returned neither an error nor any names", "address", astr)
+			addressesTried.WithLabelValues(resUnresolved).Inc()
 			continue MAIN_LOOP
 		} else if gen.nameBL.Matches(namelist[0]) {
+			addressesTried.WithLabelValues(resNameBlacklisted).Inc()
 			continue MAIN_LOOP
 		} else {
+			addressesTried.WithLabelValues(resResolved).Inc()
 			host.Address = addr
 			host.Name = namelist[0]
 
@@ -206,7 +212,7 @@ MAIN_LOOP:
 	}
 
 	if common.Debug {
-		gen.log.Printf("Generator worker #%d is quitting.\n", id)
+		gen.log.Debug("Generator worker is quitting", "worker", id)
 	}
 } // func (gen *HostGenerator) worker(id int)
 
@@ -0,0 +1,39 @@
+// /home/krylon/go/src/github.com/blicero/guang/generator/metrics.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 14:41:18 krylon>
+
+package generator
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// The results of trying an address, see addressesTried.
+const (
+	resBlacklisted     = "blacklisted"
+	resKnown           = "known"
+	resUnresolved      = "unresolved"
+	resNameBlacklisted = "name_blacklisted"
+	resResolved        = "resolved"
+)
+
+var (
+	// addressesTried counts every random address the generator comes up
+	// with, by what became of it. Only resolved addresses become Hosts.
+	addressesTried = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "guang",
+		Subsystem: "generator",
+		Name:      "addresses_total",
+		Help:      "Number of random addresses tried, by result.",
+	}, []string{"result"})
+
+	cacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "guang",
+		Subsystem: "generator",
+		Name:      "cache_lookups_total",
+		Help:      "Number of lookups in the host cache, by result.",
+	}, []string{"result"})
+)
@@ -2,21 +2,34 @@
 // -*- coding: utf-8; mode: go; -*-
 // Created on 27. 12. 2015 by Benjamin Walkenhorst
 // (c) 2015 Benjamin Walkenhorst
-// Time-stamp: <2024-06-03 19:35:49 krylon>
+// Time-stamp: <2026-10-19 17:39:25 krylon>
 
 package main
 
 import (
+	"bufio"
+	"context"
 	"flag"
 	"fmt"
 	"log"
+	"net"
 	"os"
+	"strings"
 
+	"github.com/blicero/guang/auth"
 	"github.com/blicero/guang/backend"
 	"github.com/blicero/guang/common"
+	"github.com/blicero/guang/data"
 	"github.com/blicero/guang/database"
+	"github.com/blicero/guang/events"
+	"github.com/blicero/guang/export"
 	"github.com/blicero/guang/frontend"
 	"github.com/blicero/guang/generator"
+	"github.com/blicero/guang/importer"
+	"github.com/blicero/guang/logdomain"
+	"github.com/blicero/guang/rir"
+	"github.com/blicero/guang/search"
+	"github.com/blicero/guang/vuln"
 	"github.com/blicero/guang/xfr"
 
 	"net/http"
@@ -30,16 +43,33 @@ func main() {
 		scanCnt                       int = 4
 		doProfile, doXfr, showVersion bool
 		err                           error
-		mlog                          *log.Logger
+		mlog                          *common.Logger
 		gen                           *generator.HostGenerator
 		xfrClient                     *xfr.Client
 		xfrQ                          chan string
-		db                            *database.HostDB
+		db                            database.HostDB
+		hostBatch                     *database.Batcher
 		scanner                       *backend.Scanner
 		webserver                     *frontend.WebFrontend
 		port                          int = 4711
 		nexus                         *backend.Nexus
 		baseDir                       = common.BaseDir
+		dbPath                        string
+		geoIPDir                      string
+		addUser, setPasswd, roleName  string
+		importVulns                   bool
+		importRIR                     bool
+		importScans                   bool
+		exportFormat, exportQuery     string
+		exportSince, exportUntil      string
+		exportTargets, exportOut      string
+		listenAddr                    = "localhost"
+		noTLS                         bool
+		tlsCfg                        = new(frontend.TLSConfig)
+		bus                           = events.NewBus()
+		logLevel                      = common.MinLogLevel
+		logCfg                        = common.DefaultLogConfig
+		logMaxSize                    = logCfg.MaxSize >> 20
 	)
 
 	flag.IntVar(&genCnt, "generator", genCnt, "Number of Host Generators to run")
@@ -47,12 +77,38 @@ func main() {
 	flag.IntVar(&scanCnt, "scanner", scanCnt, "Number of scanner workers to run")
 	flag.BoolVar(&doProfile, "profile", doProfile, "Run the builtin profiling server")
 	flag.IntVar(&port, "port", port, "Port for the web server to listen on")
+	flag.StringVar(&listenAddr, "addr", listenAddr, "Address for the web server to listen on, use 0.0.0.0 or :: to listen on all interfaces")
+	flag.BoolVar(&noTLS, "notls", false, "Serve plain HTTP instead of HTTPS")
+	flag.StringVar(&tlsCfg.CertFile, "cert", "", "TLS certificate for the web server (default: guang.crt in the base directory, a self-signed certificate is generated if it does not exist)")
+	flag.StringVar(&tlsCfg.KeyFile, "key", "", "TLS private key for the web server (default: guang.key in the base directory)")
+	flag.StringVar(&tlsCfg.ClientCA, "clientca", "", "Require clients of the web server to present a certificate signed by a CA from this file")
 	flag.StringVar(&baseDir, "basedir", common.BaseDir, "Base directory for application-specific files")
+	flag.StringVar(&dbPath, "db", "", "Database to use, either the path of an SQLite database or a postgres:// URL (default: guang.db in the base directory)")
+	flag.StringVar(&geoIPDir, "geoipdir", "", "Folder with the GeoLite2 City, Country and ASN databases, they are reloaded when replaced (default: the base directory)")
 	flag.BoolVar(&showVersion, "version", false, "Show the version number and exit")
+	flag.StringVar(&addUser, "adduser", "", "Add a user for the web frontend, reading the password from standard input, and exit")
+	flag.StringVar(&setPasswd, "passwd", "", "Set the password of a user of the web frontend, reading it from standard input, and exit")
+	flag.StringVar(&roleName, "role", auth.ReadOnly.String(), "Role of the user added with -adduser (ReadOnly or Operator)")
+	flag.BoolVar(&importVulns, "importvulns", false, "Import vulnerabilities from the NVD JSON feeds or OSV files given as arguments, match them against the scanned ports, and exit")
+	flag.BoolVar(&importRIR, "importrir", false, "Import the delegation files of the regional internet registries given as arguments and exit")
+	flag.BoolVar(&importScans, "importscan", false, "Import the nmap XML or masscan JSON or list files given as arguments and exit (to have zones of new hosts transferred, POST the files to /api/v1/import of a running instance)")
+	flag.StringVar(&exportFormat, "export", "", "Export the hosts matching -query, -since, -until and -targets in this format (csv, jsonl or xml) and exit")
+	flag.StringVar(&exportQuery, "query", "", "Search query selecting the hosts to export, e.g. 'port:22 tag:dmz'")
+	flag.StringVar(&exportSince, "since", "", "Only export ports scanned at or after this time (YYYY-MM-DD [HH:MM[:SS]] or RFC 3339)")
+	flag.StringVar(&exportUntil, "until", "", "Only export ports scanned at or before this time (YYYY-MM-DD [HH:MM[:SS]] or RFC 3339)")
+	flag.StringVar(&exportTargets, "targets", "", "File with the networks or addresses to export, one per line")
+	flag.StringVar(&exportOut, "out", "", "File to write the export to (default: standard output)")
+	flag.StringVar(&common.GeoIPLanguage, "geolang", common.GeoIPLanguage, "Language of the place names in the locations of hosts ("+strings.Join(common.GeoIPLanguages, ", ")+")")
+	flag.StringVar(&logLevel, "loglevel", logLevel, "Minimum level of log messages (TRACE, DEBUG, INFO, WARN, ERROR, ...)")
+	flag.BoolVar(&logCfg.JSON, "logjson", logCfg.JSON, "Write log messages as JSON objects, one per line")
+	flag.Int64Var(&logMaxSize, "logmaxsize", logMaxSize, "Rotate the log file when it grows beyond this many MiB, 0 means never")
+	flag.DurationVar(&logCfg.MaxAge, "logmaxage", logCfg.MaxAge, "Rotate the log file when it gets older than this, 0 means never")
+	flag.IntVar(&logCfg.MaxBackups, "logbackups", logCfg.MaxBackups, "Number of rotated log files to keep, 0 means keeping all of them")
 
 	flag.Parse()
 
-	if common.Debug || showVersion {
+	// The export may go to standard output, so nothing else must.
+	if (common.Debug && exportFormat == "") || showVersion {
 		fmt.Printf("%s %s - built on %s\n",
 			common.AppName,
 			common.Version,
@@ -63,12 +119,17 @@ func main() {
 		os.Exit(0)
 	}
 
-	if genCnt == 0 && xfrCnt == 0 && scanCnt == 0 {
+	if genCnt == 0 && xfrCnt == 0 && scanCnt == 0 && !importVulns && !importRIR && !importScans && exportFormat == "" {
 		fmt.Println("Alrighty then!")
 		os.Exit(0)
 	} else if port < 0 || port > 65535 {
 		fmt.Printf("Port for web server is not in the valid range (0 - 65535): %d\n", port)
 		os.Exit(1)
+	} else if !common.ValidGeoIPLanguage(common.GeoIPLanguage) {
+		fmt.Printf("Unsupported language for locations: %s (supported are %s)\n",
+			common.GeoIPLanguage,
+			strings.Join(common.GeoIPLanguages, ", "))
+		os.Exit(1)
 	}
 
 	// Freitag, 08. 01. 2016, 22:39
@@ -85,7 +146,37 @@ func main() {
 		common.SetBaseDir(baseDir)
 	}
 
-	if mlog, err = common.GetLogger("MAIN"); err != nil {
+	if dbPath != "" {
+		common.DbPath = dbPath
+	}
+
+	if geoIPDir != "" {
+		common.GeoIPDir = geoIPDir
+	}
+
+	if noTLS {
+		tlsCfg = nil
+	}
+
+	logCfg.MaxSize = logMaxSize << 20
+	logCfg.Quiet = exportFormat != "" && exportOut == ""
+
+	if lvl, err := common.ParseLogLevel(logLevel); err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	} else {
+		for _, dom := range logdomain.AllDomains() {
+			common.SetLogLevel(dom, lvl) // nolint: errcheck
+		}
+	}
+
+	if err = common.ConfigureLogging(logCfg); err != nil {
+		fmt.Printf("Error configuring log: %s\n",
+			err.Error())
+		os.Exit(1)
+	}
+
+	if mlog, err = common.GetLogger(logdomain.Main); err != nil {
 		fmt.Printf("Error creating Logger instance: %s\n",
 			err.Error())
 		os.Exit(1)
@@ -97,6 +188,89 @@ func main() {
 		os.Exit(1)
 	}
 
+	if addUser != "" || setPasswd != "" {
+		if err = manageUser(db, addUser, setPasswd, roleName); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if importVulns {
+		var n, cnt int
+
+		if n, cnt, err = vuln.ImportAll(context.Background(), db, flag.Args()...); err != nil {
+			fmt.Printf("Error importing vulnerabilities: %s\n", err.Error())
+			os.Exit(1)
+		}
+
+		fmt.Printf("Imported %d vulnerabilities, found %d on scanned ports.\n", n, cnt)
+		os.Exit(0)
+	}
+
+	if importRIR {
+		var n int
+
+		if n, err = rir.ImportAll(context.Background(), db, flag.Args()...); err != nil {
+			fmt.Printf("Error importing delegations: %s\n", err.Error())
+			os.Exit(1)
+		}
+
+		fmt.Printf("Imported %d delegations.\n", n)
+		os.Exit(0)
+	}
+
+	if importScans {
+		var st importer.Stats
+
+		// Even if the import fails, the batches before the error have
+		// been stored.
+		st, err = importer.ImportAll(context.Background(), db, nil, flag.Args()...)
+
+		fmt.Printf("Imported %d of %d ports (%d older than the ones we had), %d new hosts.\n",
+			st.Ports,
+			st.Results,
+			st.Skipped,
+			st.NewHosts)
+
+		if err != nil {
+			fmt.Printf("Error importing scan results: %s\n", err.Error())
+			os.Exit(1)
+		}
+
+		os.Exit(0)
+	}
+
+	if exportFormat != "" {
+		var n int
+
+		if n, err = exportHosts(db, exportFormat, exportQuery, exportSince, exportUntil, exportTargets, exportOut); err != nil {
+			fmt.Fprintf(os.Stderr, "Error exporting hosts: %s\n", err.Error())
+			os.Exit(1)
+		}
+
+		fmt.Fprintf(os.Stderr, "Exported %d hosts.\n", n)
+		os.Exit(0)
+	}
+
+	// The XFR client must be set up before the generator, the Batcher
+	// for new hosts hands their names to it.
+	if xfrCnt > 0 {
+		doXfr = true
+		xfrQ = make(chan string, xfrCnt)
+
+		if xfrClient, err = xfr.MakeXFRClient(xfrQ, bus); err != nil {
+			mlog.Printf("Error creating XFR client: %s\n", err.Error())
+			os.Exit(1)
+		} else {
+			xfrClient.Start(xfrCnt)
+		}
+
+		if common.Debug {
+			mlog.Printf("Started %d XFR workers.\n", xfrCnt)
+		}
+	}
+
 	if genCnt > 0 {
 		if gen, err = generator.CreateGenerator(genCnt); err != nil {
 			mlog.Printf("Error creating HostGenerator: %s\n", err.Error())
@@ -108,10 +282,38 @@ func main() {
 			}
 		}
 
+		if hostBatch, err = database.NewBatcher(
+			db,
+			database.DefaultBatchSize,
+			database.DefaultBatchInterval,
+			func(host *data.Host) {
+				// The Batcher reuses its buffer, so we need a copy.
+				var h = *host
+				bus.Publish(events.Event{
+					Kind: events.HostAdded,
+					Host: &h,
+				})
+
+				// The XFR workers are a lot slower than the Batcher,
+				// so we hand the name off without holding it up.
+				if doXfr {
+					go func(name string) {
+						xfrQ <- name
+					}(host.Name)
+				}
+			}); err != nil {
+			mlog.Printf("Error creating Batcher for new hosts: %s\n", err.Error())
+			os.Exit(1)
+		}
+
+		if err = hostBatch.Register("hosts"); err != nil {
+			mlog.Printf("Cannot register metrics for Batcher: %s\n", err.Error())
+		}
+
+		hostBatch.Start()
+
 		go func() {
 			for {
-				var host_present bool
-
 				host := <-gen.HostQueue
 
 				if common.Debug {
@@ -119,39 +321,13 @@ func main() {
 						host.Name, host.Address)
 				}
 
-				if host_present, err = db.HostExists(host.Address.String()); err != nil {
-					fmt.Printf("Error checking if host %s exists: %s\n",
-						host.Address.String(), err.Error())
-				} else if host_present {
-					continue
-				} else if err = db.HostAdd(&host); err != nil {
-					fmt.Printf("Error adding host %s/%s to database: %s",
-						host.Name, host.Address, err.Error())
-				} else if doXfr {
-					xfrQ <- host.Name
-				}
+				hostBatch.AddHost(host)
 			}
 		}()
 	}
 
-	if xfrCnt > 0 {
-		doXfr = true
-		xfrQ = make(chan string, xfrCnt)
-
-		if xfrClient, err = xfr.MakeXFRClient(xfrQ); err != nil {
-			mlog.Printf("Error creating XFR client: %s\n", err.Error())
-			os.Exit(1)
-		} else {
-			xfrClient.Start(xfrCnt)
-		}
-
-		if common.Debug {
-			mlog.Printf("Started %d XFR workers.\n", xfrCnt)
-		}
-	}
-
 	if scanCnt > 0 {
-		if scanner, err = backend.CreateScanner(scanCnt); err != nil {
+		if scanner, err = backend.CreateScanner(scanCnt, bus); err != nil {
 			mlog.Printf("Error creating scanner with %d workers: %s\n",
 				scanCnt, err.Error())
 			os.Exit(1)
@@ -161,12 +337,122 @@ func main() {
 		}
 	}
 
-	if nexus, err = backend.CreateNexus(gen, scanner, xfrClient); err != nil {
+	if nexus, err = backend.CreateNexus(gen, scanner, xfrClient, bus); err != nil {
 		fmt.Printf("Error creating Nexus: %s\n", err.Error())
 		os.Exit(1)
-	} else {
-		webserver, err = frontend.Create("0.0.0.0", uint16(port), nexus)
-		webserver.Serve()
+	}
+
+	defer nexus.Close()
+	go nexus.MetaLoop()
+
+	if webserver, err = frontend.Create(listenAddr, uint16(port), tlsCfg, nexus); err != nil {
+		fmt.Printf("Error creating web frontend: %s\n", err.Error())
+		os.Exit(1)
+	} else if err = webserver.Serve(); err != nil {
+		fmt.Printf("Error running web frontend: %s\n", err.Error())
+		os.Exit(1)
 	}
 
 } // func main()
+
+// manageUser adds the user addName or sets the password of the user
+// passwdName, depending on which one is not empty. The password is read
+// from standard input.
+func manageUser(db database.HostDB, addName, passwdName, roleName string) error {
+	var (
+		err      error
+		role     auth.Role
+		user     *data.User
+		password string
+		hash     string
+		ctx      = context.Background()
+		rd       = bufio.NewReader(os.Stdin)
+	)
+
+	if addName != "" {
+		if role, err = auth.ParseRole(roleName); err != nil {
+			return err
+		}
+		user = &data.User{Name: addName, Role: role}
+	} else if user, err = db.UserGetByName(ctx, passwdName); err != nil {
+		return fmt.Errorf("Cannot load user %s: %w", passwdName, err)
+	}
+
+	fmt.Printf("Password for %s: ", user.Name)
+
+	if password, err = rd.ReadString('\n'); err != nil && password == "" {
+		return fmt.Errorf("Cannot read password: %w", err)
+	} else if hash, err = auth.HashPassword(strings.TrimRight(password, "\r\n")); err != nil {
+		return err
+	} else if addName == "" {
+		if err = db.UserSetPassword(ctx, user, hash); err != nil {
+			return err
+		}
+
+		fmt.Printf("Password of %s was changed.\n", user.Name)
+		return nil
+	}
+
+	user.PwHash = hash
+
+	if err = db.UserAdd(ctx, user); err != nil {
+		return err
+	}
+
+	fmt.Printf("User %s (%s) was added.\n", user.Name, user.Role)
+	return nil
+} // func manageUser(db database.HostDB, addName, passwdName, roleName string) error
+
+// exportHosts writes the Hosts matching the query, the time range and the
+// targets listed in the file at targets to the file at out, or to standard
+// output, if out is empty. It returns the number of Hosts exported.
+func exportHosts(db database.HostDB, format, query, since, until, targets, out string) (int, error) {
+	var (
+		err    error
+		f      export.Format
+		filter database.ExportFilter
+		w      = os.Stdout
+	)
+
+	if f, err = export.ParseFormat(format); err != nil {
+		return 0, err
+	} else if filter.Query, err = search.Parse(query); err != nil {
+		return 0, fmt.Errorf("Invalid query: %w", err)
+	} else if filter.Since, err = export.ParseTime(since); err != nil {
+		return 0, err
+	} else if filter.Until, err = export.ParseTime(until); err != nil {
+		return 0, err
+	}
+
+	if targets != "" {
+		var (
+			fh   *os.File
+			nets []*net.IPNet
+		)
+
+		if fh, err = os.Open(targets); err != nil {
+			return 0, fmt.Errorf("Cannot open %s: %w", targets, err)
+		}
+
+		nets, err = export.ReadTargets(fh)
+		fh.Close() // nolint: errcheck
+
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", targets, err)
+		} else if len(nets) == 0 {
+			return 0, fmt.Errorf("%s does not list any targets", targets)
+		}
+
+		filter.Query.Nets = append(filter.Query.Nets, nets...)
+	}
+
+	if out != "" {
+		if w, err = os.Create(out); err != nil {
+			return 0, fmt.Errorf("Cannot create %s: %w", out, err)
+		}
+
+		defer w.Close() // nolint: errcheck
+	}
+
+	return export.Run(context.Background(), db, filter, f, w)
+} // func exportHosts(db database.HostDB, format, query, since, until, targets, out string) (int, error)
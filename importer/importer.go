@@ -0,0 +1,369 @@
+// /home/krylon/go/src/github.com/blicero/guang/importer/importer.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 17:02:59 krylon>
+
+// Package importer imports the results of other port scanners, so guang
+// can be the single inventory of everything we scanned. It reads the XML
+// output of nmap (-oX) as well as the JSON (-oJ, -oD) and list (-oL)
+// output of masscan, and tells them apart by their content.
+//
+// Imported Hosts are stored with the source HostSourceImport, their Ports
+// with the time they were scanned at, as far as the file tells. A Port is
+// only replaced if the imported result is newer than the one we have, so
+// importing an old scan does not undo newer results. Storing a Port flags
+// the metadata of its Host as outdated, so the location, AS, OS and
+// vulnerabilities of imported Hosts are determined just like those of the
+// Hosts we scanned ourselves.
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/database"
+)
+
+// batchSize is the number of results stored in one transaction. After
+// each transaction, the Hosts that were new are handed to the callback.
+const batchSize = 500
+
+// ErrNoFiles is returned by ImportAll if it is not given any files.
+var ErrNoFiles = errors.New("No files to import")
+
+// ErrInput is returned if a file is not in any of the formats we know,
+// or not well-formed.
+var ErrInput = errors.New("Invalid scan results")
+
+// Format is the format of a file with scan results.
+type Format string
+
+// NmapXML is the output of nmap -oX.
+// MasscanJSON is the output of masscan -oJ or -oD.
+// MasscanList is the output of masscan -oL.
+const (
+	NmapXML     Format = "nmap-xml"
+	MasscanJSON Format = "masscan-json"
+	MasscanList Format = "masscan-list"
+)
+
+// Stats sums up an import. Results is the number of Port results found in
+// the input, Ports the number of those that were stored, Skipped those
+// that were older than the results we had already. NewHosts is the number
+// of Hosts that were not in the database before. Only batches that were
+// committed are counted.
+type Stats struct {
+	Results  int
+	Ports    int
+	Skipped  int
+	NewHosts int
+}
+
+// Add adds the numbers of other to s.
+func (s *Stats) Add(other Stats) {
+	s.Results += other.Results
+	s.Ports += other.Ports
+	s.Skipped += other.Skipped
+	s.NewHosts += other.NewHosts
+} // func (s *Stats) Add(other Stats)
+
+// Detect determines the format of the input by looking at its beginning.
+func Detect(rd *bufio.Reader) (Format, error) {
+	var (
+		err  error
+		head []byte
+	)
+
+	if head, err = rd.Peek(512); err != nil && len(head) == 0 {
+		if err == io.EOF {
+			return "", fmt.Errorf("%w: the file is empty", ErrInput)
+		}
+		return "", err
+	}
+
+	// Skip a byte order mark and leading white space.
+	head = bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))
+	head = bytes.TrimLeft(head, " \t\r\n")
+
+	switch {
+	case bytes.HasPrefix(head, []byte("<")):
+		return NmapXML, nil
+	case bytes.HasPrefix(head, []byte("[")), bytes.HasPrefix(head, []byte("{")):
+		return MasscanJSON, nil
+	case bytes.HasPrefix(head, []byte("#masscan")),
+		bytes.HasPrefix(head, []byte("open ")),
+		bytes.HasPrefix(head, []byte("closed ")),
+		bytes.HasPrefix(head, []byte("banner ")):
+		return MasscanList, nil
+	default:
+		return "", fmt.Errorf("%w: unknown format", ErrInput)
+	}
+} // func Detect(rd *bufio.Reader) (Format, error)
+
+// Parse reads scan results from r and calls fn for each of them. Each
+// result is one Port of one Host. name is only used in error messages.
+// Errors returned by fn are passed on as they are, errors in the input
+// wrap ErrInput.
+func Parse(r io.Reader, name string, fn func(res *data.ScanResult) error) error {
+	var (
+		err    error
+		fnErr  error
+		f      Format
+		rd     = bufio.NewReader(r)
+		handle = func(res *data.ScanResult) error {
+			fnErr = fn(res)
+			return fnErr
+		}
+	)
+
+	if f, err = Detect(rd); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	switch f {
+	case NmapXML:
+		err = parseNmap(rd, handle)
+	case MasscanJSON:
+		err = parseMasscanJSON(rd, handle)
+	case MasscanList:
+		err = parseMasscanList(rd, handle)
+	}
+
+	if err == nil {
+		return nil
+	} else if fnErr != nil {
+		return fnErr
+	}
+
+	return fmt.Errorf("%s: %w: %s", name, ErrInput, err.Error())
+} // func Parse(r io.Reader, name string, fn func(res *data.ScanResult) error) error
+
+// store saves the results of an import in the database.
+type store struct {
+	ctx   context.Context
+	db    database.HostDB
+	onAdd func(hosts []data.Host)
+	// stats are the numbers of the batches committed so far, batch those
+	// of the current one.
+	stats Stats
+	batch Stats
+	// added are the new Hosts of the current batch.
+	added []data.Host
+	// hosts are the Hosts of the current batch, by address, along with
+	// the Ports we have stored for them.
+	hosts   map[string]*storedHost
+	pending int
+}
+
+type storedHost struct {
+	host  data.Host
+	ports map[uint16]*data.Port
+}
+
+// load returns the stored Host with the address of res.Host, adding the
+// Host to the database if it is new.
+func (s *store) load(res *data.ScanResult) (*storedHost, error) {
+	var (
+		err   error
+		isNew bool
+		ports []data.Port
+		addr  = res.Host.Address.String()
+		sh    = s.hosts[addr]
+	)
+
+	if sh != nil {
+		return sh, nil
+	}
+
+	sh = &storedHost{host: res.Host}
+
+	if isNew, err = s.db.HostUpsert(s.ctx, &sh.host); err != nil {
+		return nil, err
+	} else if isNew {
+		s.batch.NewHosts++
+		s.added = append(s.added, sh.host)
+	} else if ports, err = s.db.PortGetByHost(s.ctx, sh.host.ID); err != nil {
+		return nil, err
+	}
+
+	sh.ports = make(map[uint16]*data.Port, len(ports))
+
+	for i := range ports {
+		sh.ports[ports[i].Port] = &ports[i]
+	}
+
+	s.hosts[addr] = sh
+	return sh, nil
+} // func (s *store) load(res *data.ScanResult) (*storedHost, error)
+
+// add stores a single result, unless we already have a newer one.
+func (s *store) add(res *data.ScanResult) error {
+	var (
+		err error
+		sh  *storedHost
+	)
+
+	s.batch.Results++
+
+	if sh, err = s.load(res); err != nil {
+		return err
+	}
+
+	if p := sh.ports[res.Port]; p != nil {
+		// masscan reports the banner of a Port separately from the Port
+		// being open, usually with the same timestamp, so a result from
+		// the same second only replaces one that tells us more.
+		if p.Timestamp.After(res.Stamp) ||
+			(p.Timestamp.Equal(res.Stamp) && p.ReplyString() != "" && (res.Reply == nil || *res.Reply == "")) {
+			s.batch.Skipped++
+			return nil
+		} else if res.Software.IsZero() && p.Timestamp.Equal(res.Stamp) {
+			res.Software = p.Software
+		}
+	}
+
+	res.Host = sh.host
+
+	if err = s.db.PortAdd(s.ctx, res); err != nil {
+		return err
+	}
+
+	sh.ports[res.Port] = &data.Port{
+		HostID:    sh.host.ID,
+		Port:      res.Port,
+		Timestamp: res.Stamp,
+		Reply:     res.Reply,
+		Software:  res.Software,
+	}
+
+	s.batch.Ports++
+
+	if s.pending++; s.pending >= batchSize {
+		return s.flush(true)
+	}
+
+	return nil
+} // func (s *store) add(res *data.ScanResult) error
+
+// flush commits the current batch and hands the new Hosts to the callback.
+// If more is true, it starts the next transaction.
+func (s *store) flush(more bool) error {
+	var err error
+
+	if err = s.db.Commit(); err != nil {
+		return err
+	}
+
+	s.stats.Add(s.batch)
+	s.batch = Stats{}
+
+	if s.onAdd != nil && len(s.added) > 0 {
+		s.onAdd(s.added)
+	}
+
+	s.added = nil
+	s.hosts = make(map[string]*storedHost)
+	s.pending = 0
+
+	if more {
+		return s.db.Begin(s.ctx)
+	}
+
+	return nil
+} // func (s *store) flush(more bool) error
+
+// Import reads scan results from r and stores them in the database. After
+// each batch of results is committed, onAdd, if it is not nil, is called
+// with the Hosts that were added to the database. name is only used in
+// error messages.
+//
+// The results are stored in batches of batchSize, one transaction each.
+// If Import fails, e.g. because the input is malformed half way through,
+// only the current batch is rolled back, the ones before it stay in the
+// database. The returned Stats count those, so the caller can tell how
+// much of the input was imported.
+func Import(ctx context.Context, db database.HostDB, r io.Reader, name string, onAdd func(hosts []data.Host)) (Stats, error) {
+	var (
+		err error
+		s   = &store{
+			ctx:   ctx,
+			db:    db,
+			onAdd: onAdd,
+			hosts: make(map[string]*storedHost),
+		}
+	)
+
+	if err = db.Begin(ctx); err != nil {
+		return s.stats, err
+	} else if err = Parse(r, name, s.add); err != nil {
+		db.Rollback() // nolint: errcheck
+		return s.stats, err
+	} else if err = s.flush(false); err != nil {
+		return s.stats, err
+	}
+
+	return s.stats, nil
+} // func Import(ctx context.Context, db database.HostDB, r io.Reader, name string, onAdd func(hosts []data.Host)) (Stats, error)
+
+// ImportFile imports the scan results from the file at path. Files ending
+// in .gz are decompressed on the fly.
+func ImportFile(ctx context.Context, db database.HostDB, path string, onAdd func(hosts []data.Host)) (Stats, error) {
+	var (
+		err error
+		fh  *os.File
+		rd  io.Reader
+	)
+
+	if fh, err = os.Open(path); err != nil {
+		return Stats{}, fmt.Errorf("Cannot open %s: %w", path, err)
+	}
+
+	defer fh.Close() // nolint: errcheck
+
+	rd = fh
+
+	if strings.HasSuffix(path, ".gz") {
+		var gz *gzip.Reader
+
+		if gz, err = gzip.NewReader(fh); err != nil {
+			return Stats{}, fmt.Errorf("Cannot decompress %s: %w", path, err)
+		}
+
+		defer gz.Close() // nolint: errcheck
+		rd = gz
+	}
+
+	return Import(ctx, db, rd, path, onAdd)
+} // func ImportFile(ctx context.Context, db database.HostDB, path string, onAdd func(hosts []data.Host)) (Stats, error)
+
+// ImportAll imports the scan results from all the given files. It stops at
+// the first file that cannot be imported, the returned Stats include the
+// batches of that file which were committed before.
+func ImportAll(ctx context.Context, db database.HostDB, onAdd func(hosts []data.Host), paths ...string) (Stats, error) {
+	var total Stats
+
+	if len(paths) == 0 {
+		return total, ErrNoFiles
+	}
+
+	for _, path := range paths {
+		var st, err = ImportFile(ctx, db, path, onAdd)
+
+		total.Add(st)
+
+		if err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+} // func ImportAll(ctx context.Context, db database.HostDB, onAdd func(hosts []data.Host), paths ...string) (Stats, error)
@@ -0,0 +1,351 @@
+// /home/krylon/go/src/github.com/blicero/guang/importer/importer_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 17:40:45 krylon>
+
+package importer
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/database"
+)
+
+const nmapXML = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE nmaprun>
+<nmaprun scanner="nmap" args="nmap -sV -oX - 192.0.2.0/29" start="1760000000" version="7.94" xmloutputversion="1.05">
+<scaninfo type="syn" protocol="tcp" numservices="1000" services="1-1000"/>
+<host starttime="1760000010" endtime="1760000020"><status state="up" reason="echo-reply" reason_ttl="54"/>
+<address addr="192.0.2.1" addrtype="ipv4"/>
+<address addr="00:00:5E:00:53:01" addrtype="mac"/>
+<hostnames>
+<hostname name="gw.example.org" type="PTR"/>
+</hostnames>
+<ports><extraports state="filtered" count="996"/>
+<port protocol="tcp" portid="22"><state state="open" reason="syn-ack" reason_ttl="54"/><service name="ssh" product="OpenSSH" version="8.4p1 Debian 5" extrainfo="protocol 2.0" ostype="Linux" method="probed" conf="10"><cpe>cpe:/a:openbsd:openssh:8.4p1</cpe><cpe>cpe:/o:linux:linux_kernel</cpe></service><script id="banner" output="SSH-2.0-OpenSSH_8.4p1 Debian-5"/></port>
+<port protocol="tcp" portid="25"><state state="closed" reason="reset" reason_ttl="54"/><service name="smtp" method="table" conf="3"/></port>
+<port protocol="tcp" portid="80"><state state="filtered" reason="no-response" reason_ttl="0"/><service name="http" method="table" conf="3"/></port>
+<port protocol="udp" portid="53"><state state="open" reason="udp-response" reason_ttl="54"/><service name="domain" method="table" conf="3"/></port>
+</ports>
+</host>
+<host><status state="down" reason="no-response" reason_ttl="0"/>
+<address addr="192.0.2.2" addrtype="ipv4"/>
+</host>
+<host starttime="1760000030" endtime="1760000040"><status state="up" reason="syn-ack" reason_ttl="54"/>
+<address addr="2001:db8::25" addrtype="ipv6"/>
+<hostnames>
+<hostname name="mx.example.org" type="user"/>
+<hostname name="mail.example.org" type="PTR"/>
+</hostnames>
+<ports><port protocol="tcp" portid="25"><state state="open" reason="syn-ack" reason_ttl="54"/><service name="smtp" method="table" conf="3"/></port>
+</ports>
+</host>
+`
+
+const masscanJSON = `[
+{   "ip": "198.51.100.7",   "timestamp": "1760001000", "ports": [ {"port": 80, "proto": "tcp", "status": "open", "reason": "syn-ack", "ttl": 57} ] }
+,
+{   "ip": "198.51.100.7",   "timestamp": "1760001000", "ports": [ {"port": 80, "proto": "tcp", "service": {"name": "http", "banner": "HTTP/1.0 200 OK\\x0d\\x0aServer: nginx"} } ] }
+,
+{   "ip": "198.51.100.7",   "timestamp": "1760001001", "ports": [ {"port": 80, "proto": "tcp", "service": {"name": "title", "banner": "Welcome to nginx!"} } ] }
+,
+{   "ip": "198.51.100.8",   "timestamp": "1760001002", "ports": [ {"port": 443, "proto": "tcp", "status": "closed", "reason": "rst", "ttl": 57} ] }
+,
+{"ip":"198.51.100.9","timestamp":1760001003,"port":22,"proto":"tcp","rec_type":"status","data":{"status":"open","reason":"syn-ack","ttl":57}}
+{finished: 1}
+]
+`
+
+const masscanList = `#masscan
+open tcp 22 203.0.113.5 1760002000
+banner tcp 22 203.0.113.5 1760002000 ssh SSH-2.0-OpenSSH_9.2p1 Debian-2
+open tcp 25 203.0.113.5 1760002001
+open udp 53 203.0.113.5 1760002002
+# end
+`
+
+// collect parses the input and returns the results by address and port.
+func collect(t *testing.T, input string) map[string]map[uint16]data.ScanResult {
+	var (
+		err     error
+		results = make(map[string]map[uint16]data.ScanResult)
+	)
+
+	err = Parse(strings.NewReader(input), "test", func(res *data.ScanResult) error {
+		var addr = res.Host.Address.String()
+
+		if results[addr] == nil {
+			results[addr] = make(map[uint16]data.ScanResult)
+		}
+
+		results[addr][res.Port] = *res
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("Cannot parse input: %s", err.Error())
+	}
+
+	return results
+} // func collect(t *testing.T, input string) map[string]map[uint16]data.ScanResult
+
+func TestDetect(t *testing.T) {
+	type testCase struct {
+		input  string
+		format Format
+	}
+
+	var cases = []testCase{
+		{nmapXML, NmapXML},
+		{"\ufeff" + nmapXML, NmapXML},
+		{masscanJSON, MasscanJSON},
+		{`{"ip":"192.0.2.1"}`, MasscanJSON},
+		{masscanList, MasscanList},
+		{"open tcp 22 192.0.2.1 1760000000\n", MasscanList},
+		{"Starting Nmap 7.94\n", ""},
+		{"", ""},
+	}
+
+	for _, c := range cases {
+		var f, err = Detect(bufio.NewReader(strings.NewReader(c.input)))
+
+		if c.format == "" {
+			if !errors.Is(err, ErrInput) {
+				t.Errorf("Detect(%.20q) = %q, %v, expected ErrInput", c.input, f, err)
+			}
+		} else if err != nil {
+			t.Errorf("Detect(%.20q) failed: %s", c.input, err.Error())
+		} else if f != c.format {
+			t.Errorf("Detect(%.20q) = %q, expected %q", c.input, f, c.format)
+		}
+	}
+} // func TestDetect(t *testing.T)
+
+func TestParseNmap(t *testing.T) {
+	var results = collect(t, nmapXML)
+
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 Hosts, got %d: %v", len(results), results)
+	} else if _, ok := results["192.0.2.2"]; ok {
+		t.Error("Host that is down was imported")
+	}
+
+	var gw = results["192.0.2.1"]
+
+	if len(gw) != 2 {
+		t.Fatalf("Expected ports 22 and 25, got %v", gw)
+	}
+
+	var ssh = gw[22]
+
+	if ssh.Host.Name != "gw.example.org" || ssh.Host.Source != data.HostSourceImport {
+		t.Errorf("Unexpected Host: %#v", ssh.Host)
+	} else if !ssh.Stamp.Equal(time.Unix(1760000020, 0)) {
+		t.Errorf("Unexpected timestamp %s", ssh.Stamp)
+	} else if ssh.Reply == nil || *ssh.Reply != "SSH-2.0-OpenSSH_8.4p1 Debian-5" {
+		t.Errorf("Unexpected reply %v", ssh.Reply)
+	} else if ssh.Software.Product != "OpenSSH" || ssh.Software.CPE != "cpe:/a:openbsd:openssh:8.4p1" || ssh.Software.OS != "Linux" {
+		t.Errorf("Unexpected Software: %#v", ssh.Software)
+	}
+
+	if smtp := gw[25]; smtp.Reply != nil || !smtp.Software.IsZero() {
+		t.Errorf("Closed port should have neither reply nor Software: %#v", smtp)
+	}
+
+	if mx := results["2001:db8::25"][25]; mx.Host.Name != "mx.example.org" {
+		t.Errorf("Expected the name given by the user, got %q", mx.Host.Name)
+	} else if mx.Reply == nil || *mx.Reply != "" || mx.Software.Service != "smtp" || mx.Software.Product != "" {
+		t.Errorf("Unexpected result: %#v", mx)
+	}
+} // func TestParseNmap(t *testing.T)
+
+func TestParseNmapTruncated(t *testing.T) {
+	// An interrupted scan leaves the last host incomplete.
+	var (
+		cut     = strings.Index(nmapXML, `<port protocol="tcp" portid="25"><state state="open"`)
+		results = collect(t, nmapXML[:cut])
+	)
+
+	if len(results) != 1 || len(results["192.0.2.1"]) != 2 {
+		t.Errorf("Expected the first Host only, got %v", results)
+	}
+
+	var err = Parse(strings.NewReader(`<?xml version="1.0"?><html><body/></html>`), "test", func(res *data.ScanResult) error {
+		return nil
+	})
+
+	if !errors.Is(err, ErrInput) {
+		t.Errorf("Expected ErrInput for XML that is not from nmap, got %v", err)
+	}
+} // func TestParseNmapTruncated(t *testing.T)
+
+func TestParseMasscan(t *testing.T) {
+	var results = collect(t, masscanJSON)
+
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 Hosts, got %v", results)
+	} else if web := results["198.51.100.7"][80]; web.Reply == nil || *web.Reply != "HTTP/1.0 200 OK\r\nServer: nginx" {
+		t.Errorf("Expected the banner without the title, got %v", web.Reply)
+	} else if !web.Stamp.Equal(time.Unix(1760001000, 0)) || web.Software.Service != "http" {
+		t.Errorf("Unexpected result: %#v", web)
+	} else if closed := results["198.51.100.8"][443]; closed.Port != 443 || closed.Reply != nil {
+		t.Errorf("Unexpected closed port: %#v", closed)
+	} else if ssh := results["198.51.100.9"][22]; ssh.Reply == nil || !ssh.Stamp.Equal(time.Unix(1760001003, 0)) {
+		t.Errorf("Unexpected result from -oD: %#v", ssh)
+	}
+
+	results = collect(t, masscanList)
+
+	if len(results["203.0.113.5"]) != 2 {
+		t.Fatalf("Expected ports 22 and 25, got %v", results)
+	} else if ssh := results["203.0.113.5"][22]; ssh.Reply == nil || *ssh.Reply != "SSH-2.0-OpenSSH_9.2p1 Debian-2" {
+		t.Errorf("Unexpected banner: %v", ssh.Reply)
+	}
+
+	var err = Parse(strings.NewReader("open tcp 22 not-an-address 1760002000\n"), "test", func(res *data.ScanResult) error {
+		return nil
+	})
+
+	if !errors.Is(err, ErrInput) {
+		t.Errorf("Expected ErrInput for an invalid address, got %v", err)
+	}
+} // func TestParseMasscan(t *testing.T)
+
+func TestImport(t *testing.T) {
+	var (
+		err   error
+		db    database.HostDB
+		st    Stats
+		added []data.Host
+		ports []data.Port
+		host  *data.Host
+		ctx   = context.Background()
+		dir   = t.TempDir()
+		onAdd = func(hosts []data.Host) { added = append(added, hosts...) }
+	)
+
+	if db, err = database.OpenDB(filepath.Join(dir, "import.db")); err != nil {
+		t.Fatalf("Cannot open database: %s", err.Error())
+	}
+
+	defer db.Close()
+
+	if st, err = Import(ctx, db, strings.NewReader(masscanList), "masscan.txt", onAdd); err != nil {
+		t.Fatalf("Cannot import masscan list: %s", err.Error())
+	} else if st.Results != 3 || st.Ports != 3 || st.NewHosts != 1 || len(added) != 1 {
+		t.Errorf("Unexpected result of import: %+v, %d Hosts added", st, len(added))
+	} else if host, err = db.HostGetByAddr(ctx, "203.0.113.5"); err != nil {
+		t.Fatalf("Imported Host is missing: %s", err.Error())
+	} else if host.Source != data.HostSourceImport {
+		t.Errorf("Unexpected source %v", host.Source)
+	} else if ports, err = db.PortGetByHost(ctx, host.ID); err != nil {
+		t.Fatalf("Cannot get Ports: %s", err.Error())
+	} else if len(ports) != 2 {
+		t.Fatalf("Expected 2 Ports, got %d", len(ports))
+	}
+
+	for _, p := range ports {
+		if p.Port == 22 && (p.ReplyString() == "" || !p.Timestamp.Equal(time.Unix(1760002000, 0))) {
+			t.Errorf("Banner or original timestamp is missing: %#v", p)
+		}
+	}
+
+	// An older scan of the same Host does not replace what we know, a
+	// newer one does.
+	const older = "open tcp 22 203.0.113.5 1750000000\nopen tcp 25 203.0.113.5 1770000000\n"
+
+	var path = filepath.Join(dir, "older.txt.gz")
+
+	if fh, err := os.Create(path); err != nil {
+		t.Fatalf("Cannot create %s: %s", path, err.Error())
+	} else {
+		var gz = gzip.NewWriter(fh)
+
+		gz.Write([]byte(older)) // nolint: errcheck
+		gz.Close()              // nolint: errcheck
+		fh.Close()              // nolint: errcheck
+	}
+
+	added = nil
+
+	if st, err = ImportAll(ctx, db, onAdd, path); err != nil {
+		t.Fatalf("Cannot import %s: %s", path, err.Error())
+	} else if st.Results != 2 || st.Ports != 1 || st.Skipped != 1 || st.NewHosts != 0 || len(added) != 0 {
+		t.Errorf("Unexpected result of second import: %+v", st)
+	} else if ports, err = db.PortGetByHost(ctx, host.ID); err != nil {
+		t.Fatalf("Cannot get Ports: %s", err.Error())
+	}
+
+	for _, p := range ports {
+		switch p.Port {
+		case 22:
+			if p.ReplyString() == "" {
+				t.Error("Banner was replaced by an older result")
+			}
+		case 25:
+			if !p.Timestamp.Equal(time.Unix(1770000000, 0)) {
+				t.Errorf("Port 25 was not updated: %s", p.Timestamp)
+			}
+		}
+	}
+
+	if _, err = ImportAll(ctx, db, nil); !errors.Is(err, ErrNoFiles) {
+		t.Errorf("Expected ErrNoFiles, got %v", err)
+	} else if _, err = Import(ctx, db, strings.NewReader("no scan results"), "junk", nil); !errors.Is(err, ErrInput) {
+		t.Errorf("Expected ErrInput, got %v", err)
+	}
+} // func TestImport(t *testing.T)
+
+func TestImportPartial(t *testing.T) {
+	const extra = 10
+
+	var (
+		err   error
+		db    database.HostDB
+		st    Stats
+		cnt   int64
+		added int
+		buf   strings.Builder
+		ctx   = context.Background()
+	)
+
+	if db, err = database.OpenDB(filepath.Join(t.TempDir(), "partial.db")); err != nil {
+		t.Fatalf("Cannot open database: %s", err.Error())
+	}
+
+	defer db.Close()
+
+	// One full batch, the beginning of another, then garbage.
+	for i := 0; i < batchSize+extra; i++ {
+		fmt.Fprintf(&buf, "open tcp 22 198.18.%d.%d 1760000000\n", i/250, i%250+1)
+	}
+
+	buf.WriteString("open tcp 22 not-an-address 1760000000\n")
+
+	st, err = Import(ctx, db, strings.NewReader(buf.String()), "partial.txt", func(hosts []data.Host) {
+		added += len(hosts)
+	})
+
+	if !errors.Is(err, ErrInput) {
+		t.Fatalf("Expected ErrInput, got %v", err)
+	} else if st.Results != batchSize || st.Ports != batchSize || st.NewHosts != batchSize {
+		t.Errorf("Stats should count the committed batch only: %+v", st)
+	} else if added != batchSize {
+		t.Errorf("onAdd was called with %d Hosts, expected %d", added, batchSize)
+	} else if cnt, err = db.HostGetCount(ctx); err != nil {
+		t.Fatalf("Cannot count Hosts: %s", err.Error())
+	} else if cnt != batchSize {
+		t.Errorf("Database holds %d Hosts, expected %d", cnt, batchSize)
+	}
+} // func TestImportPartial(t *testing.T)
@@ -0,0 +1,277 @@
+// /home/krylon/go/src/github.com/blicero/guang/importer/masscan.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 16:34:17 krylon>
+
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blicero/guang/data"
+)
+
+// maxLine is the longest line we accept from masscan. Banners can be long,
+// but not that long.
+const maxLine = 4 * 1024 * 1024
+
+// auxServices are the banners masscan reports in addition to the one the
+// service sent, e.g. the title of a web page. We keep the reply itself.
+var auxServices = map[string]bool{
+	"title":  true,
+	"X509":   true,
+	"X509CA": true,
+	"vuln":   true,
+}
+
+// masscanRecord is a single record of the JSON output of masscan. -oJ
+// writes one record per line, with the Ports nested, -oD writes the fields
+// of a single Port at the top level of the record.
+type masscanRecord struct {
+	IP        string        `json:"ip"`
+	Timestamp json.Number   `json:"timestamp"`
+	Ports     []masscanPort `json:"ports"`
+	Port      uint16        `json:"port"`
+	Proto     string        `json:"proto"`
+	RecType   string        `json:"rec_type"`
+	Data      struct {
+		Status      string `json:"status"`
+		ServiceName string `json:"service_name"`
+		Banner      string `json:"banner"`
+	} `json:"data"`
+}
+
+type masscanPort struct {
+	Port    uint16          `json:"port"`
+	Proto   string          `json:"proto"`
+	Status  string          `json:"status"`
+	Service *masscanService `json:"service"`
+}
+
+type masscanService struct {
+	Name   string `json:"name"`
+	Banner string `json:"banner"`
+}
+
+// masscanResult turns a line of masscan's output into a ScanResult. status
+// is open, closed or banner. It returns nil if the line is nothing we keep.
+func masscanResult(ip, stamp, proto string, port uint16, status, service, banner string) (*data.ScanResult, error) {
+	var (
+		err  error
+		secs int64
+		res  = &data.ScanResult{
+			Port: port,
+			Host: data.Host{
+				Source: data.HostSourceImport,
+			},
+		}
+	)
+
+	if proto != "tcp" {
+		return nil, nil
+	} else if res.Host.Address = net.ParseIP(ip); res.Host.Address == nil {
+		return nil, fmt.Errorf("Invalid address %q", ip)
+	} else if secs, err = strconv.ParseInt(stamp, 10, 64); err != nil {
+		return nil, fmt.Errorf("Invalid timestamp %q", stamp)
+	}
+
+	res.Stamp = time.Unix(secs, 0)
+
+	switch status {
+	case "open":
+		var reply string
+		res.Reply = &reply
+	case "closed":
+		// Like the scanner, we store closed Ports without a reply.
+	case "banner":
+		if auxServices[service] {
+			return nil, nil
+		}
+
+		var reply = unescape(banner)
+		res.Reply = &reply
+		res.Software.Service = service
+	default:
+		return nil, nil
+	}
+
+	return res, nil
+} // func masscanResult(ip, stamp, proto string, port uint16, status, service, banner string) (*data.ScanResult, error)
+
+// unescape replaces the \xHH escapes masscan uses for unprintable bytes in
+// banners with the bytes they stand for.
+func unescape(s string) string {
+	if !strings.Contains(s, `\x`) {
+		return s
+	}
+
+	var buf strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) && s[i+1] == 'x' {
+			if b, err := strconv.ParseUint(s[i+2:i+4], 16, 8); err == nil {
+				buf.WriteByte(byte(b))
+				i += 3
+				continue
+			}
+		}
+
+		buf.WriteByte(s[i])
+	}
+
+	return buf.String()
+} // func unescape(s string) string
+
+// lineScanner returns a Scanner for the lines of r that copes with long
+// banners.
+func lineScanner(r io.Reader) *bufio.Scanner {
+	var sc = bufio.NewScanner(r)
+
+	sc.Buffer(make([]byte, 0, 64*1024), maxLine)
+	return sc
+} // func lineScanner(r io.Reader) *bufio.Scanner
+
+// parseMasscanJSON reads the JSON output of masscan. masscan writes one
+// record per line, but the file as a whole is not always valid JSON, older
+// versions end it with {finished: 1}. So we read it line by line, too.
+func parseMasscanJSON(r io.Reader, fn func(res *data.ScanResult) error) error {
+	var (
+		lineNo int
+		sc     = lineScanner(r)
+	)
+
+	for sc.Scan() {
+		var (
+			err  error
+			rec  masscanRecord
+			res  *data.ScanResult
+			line = bytes.TrimSpace(sc.Bytes())
+		)
+
+		lineNo++
+
+		line = bytes.TrimPrefix(line, []byte("["))
+		line = bytes.TrimPrefix(line, []byte(","))
+		line = bytes.TrimSuffix(line, []byte("]"))
+		line = bytes.TrimSuffix(line, []byte(","))
+		line = bytes.TrimSpace(line)
+
+		if len(line) == 0 || bytes.HasPrefix(line, []byte("{finished")) {
+			continue
+		} else if err = json.Unmarshal(line, &rec); err != nil {
+			return fmt.Errorf("Line %d: Cannot parse masscan JSON: %w", lineNo, err)
+		}
+
+		if rec.Port != 0 {
+			// -oD
+			var status = rec.Data.Status
+
+			if rec.RecType == "banner" {
+				status = "banner"
+			}
+
+			rec.Ports = append(rec.Ports, masscanPort{
+				Port:   rec.Port,
+				Proto:  rec.Proto,
+				Status: status,
+			})
+
+			if status == "banner" {
+				rec.Ports[0].Service = &masscanService{
+					Name:   rec.Data.ServiceName,
+					Banner: rec.Data.Banner,
+				}
+			}
+		}
+
+		for _, p := range rec.Ports {
+			var (
+				status          = p.Status
+				service, banner string
+			)
+
+			if p.Service != nil {
+				status = "banner"
+				service = p.Service.Name
+				banner = p.Service.Banner
+			}
+
+			if res, err = masscanResult(rec.IP, rec.Timestamp.String(), p.Proto, p.Port, status, service, banner); err != nil {
+				return fmt.Errorf("Line %d: %w", lineNo, err)
+			} else if res == nil {
+				continue
+			} else if err = fn(res); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("Cannot read masscan JSON: %w", err)
+	}
+
+	return nil
+} // func parseMasscanJSON(r io.Reader, fn func(res *data.ScanResult) error) error
+
+// parseMasscanList reads the list output of masscan, which looks like this:
+//
+//	open tcp 22 192.0.2.1 1697040000
+//	banner tcp 22 192.0.2.1 1697040001 ssh SSH-2.0-OpenSSH_8.4p1
+func parseMasscanList(r io.Reader, fn func(res *data.ScanResult) error) error {
+	var (
+		lineNo int
+		sc     = lineScanner(r)
+	)
+
+	for sc.Scan() {
+		var (
+			err     error
+			port    uint64
+			res     *data.ScanResult
+			service string
+			banner  string
+			line    = strings.TrimSpace(sc.Text())
+		)
+
+		lineNo++
+
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		var fields = strings.SplitN(line, " ", 7)
+
+		if len(fields) < 5 {
+			return fmt.Errorf("Line %d: Cannot parse %q", lineNo, line)
+		} else if port, err = strconv.ParseUint(fields[2], 10, 16); err != nil {
+			return fmt.Errorf("Line %d: Invalid port %q", lineNo, fields[2])
+		}
+
+		if fields[0] == "banner" && len(fields) == 7 {
+			service, banner = fields[5], fields[6]
+		}
+
+		if res, err = masscanResult(fields[3], fields[4], fields[1], uint16(port), fields[0], service, banner); err != nil {
+			return fmt.Errorf("Line %d: %w", lineNo, err)
+		} else if res == nil {
+			continue
+		} else if err = fn(res); err != nil {
+			return err
+		}
+	}
+
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("Cannot read masscan list: %w", err)
+	}
+
+	return nil
+} // func parseMasscanList(r io.Reader, fn func(res *data.ScanResult) error) error
@@ -0,0 +1,155 @@
+// /home/krylon/go/src/github.com/blicero/guang/importer/nmap.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 16:34:17 krylon>
+
+package importer
+
+import (
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/nmap"
+)
+
+// parseNmap reads the XML output of nmap. Hosts that are down and Ports
+// that are neither open nor closed, i.e. filtered, are skipped, as are
+// Ports other than TCP, which we do not scan.
+func parseNmap(r io.Reader, fn func(res *data.ScanResult) error) error {
+	return nmap.Parse(r, func(run *nmap.Run, h *nmap.Host) error {
+		var host data.Host
+
+		if h.Status.State == "down" {
+			return nil
+		} else if host.Address = nmapAddress(h); host.Address == nil {
+			return nil
+		}
+
+		host.Name = nmapHostname(h)
+		host.Source = data.HostSourceImport
+
+		var stamp = nmapStamp(run, h)
+
+		for i := range h.Ports {
+			var (
+				p   = &h.Ports[i]
+				res = data.ScanResult{
+					Host:  host,
+					Port:  p.PortID,
+					Stamp: stamp,
+				}
+			)
+
+			if p.Protocol != "tcp" {
+				continue
+			}
+
+			switch p.State.State {
+			case nmap.StateOpen:
+				var reply string
+
+				for _, s := range p.Scripts {
+					if s.ID == nmap.ScriptBanner {
+						reply = s.Output
+						break
+					}
+				}
+
+				res.Reply = &reply
+				res.Software = nmapSoftware(p.Service)
+			case nmap.StateClosed:
+				// Like the scanner, we store closed Ports without a reply.
+			default:
+				continue
+			}
+
+			if err := fn(&res); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+} // func parseNmap(r io.Reader, fn func(res *data.ScanResult) error) error
+
+// nmapAddress returns the first IP address of the host.
+func nmapAddress(h *nmap.Host) net.IP {
+	for _, a := range h.Addresses {
+		if a.AddrType == "ipv4" || a.AddrType == "ipv6" {
+			if addr := net.ParseIP(a.Addr); addr != nil {
+				return addr
+			}
+		}
+	}
+
+	return nil
+} // func nmapAddress(h *nmap.Host) net.IP
+
+// nmapHostname returns the name of the host, preferring the one given on
+// the command line over the one from the reverse lookup.
+func nmapHostname(h *nmap.Host) string {
+	var ptr string
+
+	for _, n := range h.Hostnames {
+		if n.Type == "user" {
+			return n.Name
+		} else if ptr == "" {
+			ptr = n.Name
+		}
+	}
+
+	return ptr
+} // func nmapHostname(h *nmap.Host) string
+
+// nmapStamp returns the time the host was scanned at. Without -v, nmap
+// does not record the times of the individual hosts, so we fall back to
+// the start of the run.
+func nmapStamp(run *nmap.Run, h *nmap.Host) time.Time {
+	switch {
+	case h.EndTime > 0:
+		return time.Unix(h.EndTime, 0)
+	case h.StartTime > 0:
+		return time.Unix(h.StartTime, 0)
+	case run.Start > 0:
+		return time.Unix(run.Start, 0)
+	default:
+		return time.Now()
+	}
+} // func nmapStamp(run *nmap.Run, h *nmap.Host) time.Time
+
+// nmapSoftware returns what nmap's service detection found out about a
+// port. If nmap only guessed the service from the port number, that is
+// all we take.
+func nmapSoftware(svc *nmap.Service) data.Software {
+	var sw data.Software
+
+	if svc == nil {
+		return sw
+	}
+
+	sw.Service = svc.Name
+
+	if svc.Method == "table" {
+		return sw
+	}
+
+	sw.Product = svc.Product
+	sw.Version = svc.Version
+	sw.Info = svc.ExtraInfo
+	sw.OS = svc.OSType
+
+	for _, cpe := range svc.CPE {
+		if strings.HasPrefix(cpe, "cpe:/a:") {
+			sw.CPE = cpe
+			break
+		} else if sw.CPE == "" {
+			sw.CPE = cpe
+		}
+	}
+
+	return sw
+} // func nmapSoftware(svc *nmap.Service) data.Software
@@ -2,12 +2,17 @@
 // -*- mode: go; coding: utf-8; -*-
 // Created on 29. 10. 2022 by Benjamin Walkenhorst
 // (c) 2022 Benjamin Walkenhorst
-// Time-stamp: <2022-10-31 22:25:56 krylon>
+// Time-stamp: <2026-10-19 15:40:22 krylon>
 
 // Package logdomain provides symbolic constants to identify the various
 // pieces of the application that need to do logging.
 package logdomain
 
+import (
+	"fmt"
+	"strings"
+)
+
 //go:generate stringer -type=ID
 
 // ID is an id...
@@ -21,6 +26,14 @@ const (
 	Backend
 	Generator
 	XFR
+	Batcher
+	Cache
+	Scanner
+	MetaEngine
+	Web
+	Main
+	Rules
+	Vuln
 )
 
 // AllDomains returns a slice of all the valid values for ID.
@@ -32,5 +45,24 @@ func AllDomains() []ID {
 		Backend,
 		Generator,
 		XFR,
+		Batcher,
+		Cache,
+		Scanner,
+		MetaEngine,
+		Web,
+		Main,
+		Rules,
+		Vuln,
 	}
 } // func AllDomains() []ID
+
+// Parse returns the ID with the given name, ignoring case.
+func Parse(name string) (ID, error) {
+	for _, id := range AllDomains() {
+		if strings.EqualFold(id.String(), name) {
+			return id, nil
+		}
+	}
+
+	return 0, fmt.Errorf("Unknown log domain %q", name)
+} // func Parse(name string) (ID, error)
@@ -0,0 +1,165 @@
+// /home/krylon/go/src/github.com/blicero/guang/nmap/nmap.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 16:22:36 krylon>
+
+// Package nmap describes the XML output of nmap, the part of it we write
+// when exporting Hosts and read when importing scans. The full format is
+// described by the DTD that comes with nmap, nmap.dtd.
+package nmap
+
+import (
+	"encoding/xml"
+	"strconv"
+)
+
+// XMLOutputVersion is the version of the output format we write.
+const XMLOutputVersion = "1.05"
+
+// Port states
+const (
+	StateOpen   = "open"
+	StateClosed = "closed"
+)
+
+// ScriptBanner is the id of the script that reports the banner a port
+// sent, i.e. the output of nmap --script banner.
+const ScriptBanner = "banner"
+
+// Run is the root element of an nmap XML file. Its Hosts are written and
+// read one at a time, so it has no field for them.
+type Run struct {
+	XMLName          xml.Name `xml:"nmaprun"`
+	Scanner          string   `xml:"scanner,attr"`
+	Args             string   `xml:"args,attr,omitempty"`
+	Start            int64    `xml:"start,attr,omitempty"`
+	StartStr         string   `xml:"startstr,attr,omitempty"`
+	Version          string   `xml:"version,attr"`
+	XMLOutputVersion string   `xml:"xmloutputversion,attr"`
+}
+
+// Host is a single host, along with its ports.
+type Host struct {
+	XMLName   xml.Name   `xml:"host"`
+	StartTime int64      `xml:"starttime,attr,omitempty"`
+	EndTime   int64      `xml:"endtime,attr,omitempty"`
+	Status    Status     `xml:"status"`
+	Addresses []Address  `xml:"address"`
+	Hostnames []Hostname `xml:"hostnames>hostname"`
+	Ports     []Port     `xml:"ports>port"`
+	OS        *OS        `xml:"os,omitempty"`
+}
+
+// Status tells if a host is up.
+type Status struct {
+	State  string `xml:"state,attr"`
+	Reason string `xml:"reason,attr"`
+}
+
+// Address is an address of a host. AddrType is ipv4, ipv6 or mac.
+type Address struct {
+	Addr     string `xml:"addr,attr"`
+	AddrType string `xml:"addrtype,attr"`
+}
+
+// Hostname is a name of a host. Type is user for the name given on the
+// command line, PTR for the name from a reverse lookup.
+type Hostname struct {
+	Name string `xml:"name,attr"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+// Port is a single port of a host.
+type Port struct {
+	Protocol string   `xml:"protocol,attr"`
+	PortID   uint16   `xml:"portid,attr"`
+	State    State    `xml:"state"`
+	Service  *Service `xml:"service,omitempty"`
+	Scripts  []Script `xml:"script"`
+}
+
+// State is the state of a port, e.g. open or closed.
+type State struct {
+	State     string `xml:"state,attr"`
+	Reason    string `xml:"reason,attr"`
+	ReasonTTL int    `xml:"reason_ttl,attr"`
+}
+
+// Service is what nmap found out about the software listening on a port.
+type Service struct {
+	Name      string   `xml:"name,attr"`
+	Product   string   `xml:"product,attr,omitempty"`
+	Version   string   `xml:"version,attr,omitempty"`
+	ExtraInfo string   `xml:"extrainfo,attr,omitempty"`
+	OSType    string   `xml:"ostype,attr,omitempty"`
+	Method    string   `xml:"method,attr"`
+	Conf      int      `xml:"conf,attr"`
+	CPE       []string `xml:"cpe"`
+}
+
+// Script is the output of an NSE script run against a port.
+type Script struct {
+	ID     string `xml:"id,attr"`
+	Output string `xml:"output,attr"`
+}
+
+// OS lists the operating systems a host might be running.
+type OS struct {
+	Matches []OSMatch `xml:"osmatch"`
+}
+
+// OSMatch is a guess of the operating system of a host, Accuracy is the
+// confidence in percent.
+type OSMatch struct {
+	Name     string `xml:"name,attr"`
+	Accuracy int    `xml:"accuracy,attr"`
+}
+
+// RunStats sums up a run, it comes after the hosts.
+type RunStats struct {
+	XMLName  xml.Name  `xml:"runstats"`
+	Finished Finished  `xml:"finished"`
+	Hosts    HostStats `xml:"hosts"`
+}
+
+// Finished tells when and how a run ended.
+type Finished struct {
+	Time    int64   `xml:"time,attr"`
+	TimeStr string  `xml:"timestr,attr,omitempty"`
+	Elapsed float64 `xml:"elapsed,attr"`
+	Exit    string  `xml:"exit,attr,omitempty"`
+}
+
+// HostStats is the number of hosts found in a run.
+type HostStats struct {
+	Up    int `xml:"up,attr"`
+	Down  int `xml:"down,attr"`
+	Total int `xml:"total,attr"`
+}
+
+// StartElement returns the opening tag of the run, so the Hosts can be
+// written one at a time after it.
+func (r *Run) StartElement() xml.StartElement {
+	var attrs = []xml.Attr{
+		{Name: xml.Name{Local: "scanner"}, Value: r.Scanner},
+	}
+
+	if r.Args != "" {
+		attrs = append(attrs, xml.Attr{Name: xml.Name{Local: "args"}, Value: r.Args})
+	}
+
+	if r.Start != 0 {
+		attrs = append(attrs, xml.Attr{Name: xml.Name{Local: "start"}, Value: strconv.FormatInt(r.Start, 10)})
+	}
+
+	if r.StartStr != "" {
+		attrs = append(attrs, xml.Attr{Name: xml.Name{Local: "startstr"}, Value: r.StartStr})
+	}
+
+	attrs = append(attrs,
+		xml.Attr{Name: xml.Name{Local: "version"}, Value: r.Version},
+		xml.Attr{Name: xml.Name{Local: "xmloutputversion"}, Value: r.XMLOutputVersion})
+
+	return xml.StartElement{Name: xml.Name{Local: "nmaprun"}, Attr: attrs}
+} // func (r *Run) StartElement() xml.StartElement
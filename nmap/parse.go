@@ -0,0 +1,112 @@
+// /home/krylon/go/src/github.com/blicero/guang/nmap/parse.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 16:34:17 krylon>
+
+package nmap
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// ErrNoRun is returned by Parse if the input has no nmaprun element, i.e.
+// it is not the XML output of nmap.
+var ErrNoRun = errors.New("Not an nmap XML file")
+
+// setAttrs fills in the Run from the attributes of its opening tag.
+func (r *Run) setAttrs(attrs []xml.Attr) {
+	for _, a := range attrs {
+		switch a.Name.Local {
+		case "scanner":
+			r.Scanner = a.Value
+		case "args":
+			r.Args = a.Value
+		case "start":
+			r.Start, _ = strconv.ParseInt(a.Value, 10, 64)
+		case "startstr":
+			r.StartStr = a.Value
+		case "version":
+			r.Version = a.Value
+		case "xmloutputversion":
+			r.XMLOutputVersion = a.Value
+		}
+	}
+} // func (r *Run) setAttrs(attrs []xml.Attr)
+
+// truncated returns true if err means the input ended in the middle of
+// the document.
+func truncated(err error) bool {
+	var se *xml.SyntaxError
+
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	} else if errors.As(err, &se) {
+		return se.Msg == "unexpected EOF"
+	}
+
+	return false
+} // func truncated(err error) bool
+
+// Parse reads the XML output of nmap from r and calls fn for every host in
+// it, along with the run it is part of. The hosts are decoded one at a
+// time, so large files do not have to fit into memory.
+//
+// A file that was cut short, e.g. because nmap was interrupted, is not an
+// error, as long as the hosts in it are complete.
+func Parse(r io.Reader, fn func(run *Run, h *Host) error) error {
+	var (
+		err error
+		tok xml.Token
+		run *Run
+		dec = xml.NewDecoder(r)
+	)
+
+	for {
+		if tok, err = dec.Token(); err == io.EOF {
+			break
+		} else if err != nil {
+			if run != nil && truncated(err) {
+				// nmap writes the XML as it goes, so an interrupted
+				// scan leaves a file without the closing tags.
+				break
+			}
+			return fmt.Errorf("Cannot parse nmap XML: %w", err)
+		}
+
+		var se, ok = tok.(xml.StartElement)
+
+		if !ok {
+			continue
+		}
+
+		switch se.Name.Local {
+		case "nmaprun":
+			run = new(Run)
+			run.setAttrs(se.Attr)
+		case "host":
+			var h Host
+
+			if run == nil {
+				return ErrNoRun
+			} else if err = dec.DecodeElement(&h, &se); err != nil {
+				if truncated(err) {
+					return nil
+				}
+				return fmt.Errorf("Cannot parse host: %w", err)
+			} else if err = fn(run, &h); err != nil {
+				return err
+			}
+		}
+	}
+
+	if run == nil {
+		return ErrNoRun
+	}
+
+	return nil
+} // func Parse(r io.Reader, fn func(run *Run, h *Host) error) error
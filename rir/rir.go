@@ -0,0 +1,249 @@
+// /home/krylon/go/src/github.com/blicero/guang/rir/rir.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 16:04:01 krylon>
+
+// Package rir imports the delegation files the regional internet
+// registries publish, e.g. delegated-ripencc-extended-latest, to find out
+// which registry allocated the address block a Host is part of, and when.
+//
+// The files are in the RIR statistics exchange format: one record per
+// line, with the fields separated by |. Address records look like this:
+//
+//	ripencc|DE|ipv4|192.0.2.0|256|20100712|allocated
+//	ripencc|NL|ipv6|2001:db8::|32|20050101|allocated
+//
+// For IPv4 records, the fifth field is the number of addresses, which
+// need not be a power of two, for IPv6 records it is the length of the
+// prefix. The header, the summary lines, records of AS numbers and blocks
+// that are available or reserved rather than delegated are skipped.
+package rir
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/database"
+)
+
+// ErrNoFiles is returned by ImportAll if it is not given any files.
+var ErrNoFiles = errors.New("No files to import")
+
+// Parse reads the delegation file from r and calls fn for every block of
+// addresses in it. name is only used in error messages.
+func Parse(r io.Reader, name string, fn func(d *data.Delegation) error) error {
+	var (
+		err    error
+		lineNo int
+		sc     = bufio.NewScanner(r)
+	)
+
+	for sc.Scan() {
+		var (
+			d    *data.Delegation
+			line = strings.TrimSpace(sc.Text())
+		)
+
+		lineNo++
+
+		if line == "" || line[0] == '#' {
+			continue
+		} else if d, err = parseRecord(line); err != nil {
+			return fmt.Errorf("%s, line %d: %w", name, lineNo, err)
+		} else if d == nil {
+			continue
+		} else if err = fn(d); err != nil {
+			return err
+		}
+	}
+
+	if err = sc.Err(); err != nil {
+		return fmt.Errorf("Cannot read %s: %w", name, err)
+	}
+
+	return nil
+} // func Parse(r io.Reader, name string, fn func(d *data.Delegation) error) error
+
+// parseRecord parses a single line of a delegation file. It returns nil
+// for lines that do not describe a delegated block of addresses.
+func parseRecord(line string) (*data.Delegation, error) {
+	var (
+		err    error
+		fields = strings.Split(line, "|")
+		d      data.Delegation
+	)
+
+	if len(fields) < 7 {
+		// The header has the version of the format in the first field,
+		// and fewer fields than a record, depending on the version.
+		return nil, nil
+	} else if fields[1] == "*" || fields[5] == "summary" {
+		return nil, nil
+	} else if fields[2] != "ipv4" && fields[2] != "ipv6" {
+		return nil, nil
+	} else if fields[6] != "allocated" && fields[6] != "assigned" {
+		return nil, nil
+	}
+
+	d.Registry = fields[0]
+	d.Country = fields[1]
+	d.Status = fields[6]
+
+	if d.First = net.ParseIP(fields[3]); d.First == nil {
+		return nil, fmt.Errorf("Invalid address %q", fields[3])
+	} else if d.Last, err = lastAddr(fields[2], d.First, fields[4]); err != nil {
+		return nil, err
+	}
+
+	if fields[5] != "" && fields[5] != "00000000" {
+		if d.Date, err = time.Parse("20060102", fields[5]); err != nil {
+			return nil, fmt.Errorf("Invalid date %q", fields[5])
+		}
+	}
+
+	return &d, nil
+} // func parseRecord(line string) (*data.Delegation, error)
+
+// lastAddr returns the last address of the block starting at first. For
+// IPv4, size is the number of addresses, for IPv6 the length of the
+// prefix.
+func lastAddr(family string, first net.IP, size string) (net.IP, error) {
+	var (
+		err error
+		n   uint64
+	)
+
+	if family == "ipv4" {
+		var v4 = first.To4()
+
+		if v4 == nil {
+			return nil, fmt.Errorf("%s is not an IPv4 address", first)
+		} else if n, err = strconv.ParseUint(size, 10, 32); err != nil || n == 0 {
+			return nil, fmt.Errorf("Invalid number of addresses %q", size)
+		}
+
+		var start = uint64(binary.BigEndian.Uint32(v4))
+
+		if start+n-1 > 0xffffffff {
+			return nil, fmt.Errorf("Block of %d addresses at %s is too large", n, first)
+		}
+
+		var last = make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(last, uint32(start+n-1))
+		return last.To16(), nil
+	} else if n, err = strconv.ParseUint(size, 10, 8); err != nil || n > 128 {
+		return nil, fmt.Errorf("Invalid prefix length %q", size)
+	}
+
+	var (
+		mask = net.CIDRMask(int(n), 128)
+		last = make(net.IP, net.IPv6len)
+	)
+
+	for i := range last {
+		last[i] = first[i] | ^mask[i]
+	}
+
+	return last, nil
+} // func lastAddr(family string, first net.IP, size string) (net.IP, error)
+
+// Import reads the Delegations from the file at path and stores them in
+// the database, in a single transaction, replacing the Delegations stored
+// earlier for the registries the file covers. It returns the number of
+// Delegations it imported. Files ending in .gz are decompressed on the fly.
+func Import(ctx context.Context, db database.HostDB, path string) (int, error) {
+	var (
+		err  error
+		fh   *os.File
+		rd   io.Reader
+		cnt  int
+		seen = make(map[string]bool)
+	)
+
+	if fh, err = os.Open(path); err != nil {
+		return 0, fmt.Errorf("Cannot open %s: %w", path, err)
+	}
+
+	defer fh.Close() // nolint: errcheck
+
+	rd = fh
+
+	if strings.HasSuffix(path, ".gz") {
+		var gz *gzip.Reader
+
+		if gz, err = gzip.NewReader(fh); err != nil {
+			return 0, fmt.Errorf("Cannot decompress %s: %w", path, err)
+		}
+
+		defer gz.Close() // nolint: errcheck
+		rd = gz
+	}
+
+	var store = func(d *data.Delegation) error {
+		if !seen[d.Registry] {
+			if err := db.DelegationDelete(ctx, d.Registry); err != nil {
+				return err
+			}
+			seen[d.Registry] = true
+		}
+
+		if err := db.DelegationAdd(ctx, d); err != nil {
+			return err
+		}
+
+		cnt++
+		return nil
+	}
+
+	if err = db.Begin(ctx); err != nil {
+		return 0, err
+	} else if err = Parse(rd, path, store); err != nil {
+		db.Rollback() // nolint: errcheck
+		return 0, err
+	} else if err = db.Commit(); err != nil {
+		return 0, err
+	}
+
+	return cnt, nil
+} // func Import(ctx context.Context, db database.HostDB, path string) (int, error)
+
+// ImportAll imports the Delegations from all the given files, then flags
+// the metadata of all Hosts as outdated, so the allocation of their
+// addresses is looked up again. It returns the number of Delegations
+// imported.
+func ImportAll(ctx context.Context, db database.HostDB, paths ...string) (int, error) {
+	var (
+		err      error
+		total, n int
+	)
+
+	if len(paths) == 0 {
+		return 0, ErrNoFiles
+	}
+
+	for _, path := range paths {
+		if n, err = Import(ctx, db, path); err != nil {
+			return total, err
+		}
+
+		total += n
+	}
+
+	if _, err = db.HostMarkAllDirty(ctx); err != nil {
+		return total, err
+	}
+
+	return total, nil
+} // func ImportAll(ctx context.Context, db database.HostDB, paths ...string) (int, error)
@@ -0,0 +1,126 @@
+// /home/krylon/go/src/github.com/blicero/guang/rir/rir_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 16:04:01 krylon>
+
+package rir
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/database"
+)
+
+const delegated = `2.3|ripencc|1700000000|5|19830705|20231114|+0100
+# A comment
+ripencc|*|ipv4|*|3|summary
+ripencc|*|asn|*|1|summary
+ripencc|DE|asn|3320|1|19930901|allocated
+ripencc|DE|ipv4|192.0.2.0|256|20100712|allocated
+ripencc|NL|ipv4|198.51.100.0|768|00000000|assigned
+ripencc||ipv4|203.0.113.0|256||available
+ripencc|NL|ipv6|2001:db8::|32|20050101|allocated|7b2e5c47-8a1f-4c1b-9f1f-2f1c5d0c3e11
+`
+
+func TestParse(t *testing.T) {
+	var (
+		err  error
+		list []data.Delegation
+	)
+
+	if err = Parse(strings.NewReader(delegated), "test", func(d *data.Delegation) error {
+		list = append(list, *d)
+		return nil
+	}); err != nil {
+		t.Fatalf("Error parsing delegations: %s", err.Error())
+	} else if len(list) != 3 {
+		t.Fatalf("Expected 3 delegations, got %d: %#v", len(list), list)
+	}
+
+	type testCase struct {
+		first, last, country, date string
+	}
+
+	var cases = []testCase{
+		{"192.0.2.0", "192.0.2.255", "DE", "2010-07-12"},
+		{"198.51.100.0", "198.51.102.255", "NL", ""},
+		{"2001:db8::", "2001:db8:ffff:ffff:ffff:ffff:ffff:ffff", "NL", "2005-01-01"},
+	}
+
+	for i, c := range cases {
+		var (
+			d    = list[i]
+			date string
+		)
+
+		if !d.Date.IsZero() {
+			date = d.Date.Format("2006-01-02")
+		}
+
+		if !d.First.Equal(net.ParseIP(c.first)) || !d.Last.Equal(net.ParseIP(c.last)) {
+			t.Errorf("Delegation %d: expected %s - %s, got %s - %s",
+				i,
+				c.first,
+				c.last,
+				d.First,
+				d.Last)
+		} else if d.Country != c.country || date != c.date || d.Registry != "ripencc" {
+			t.Errorf("Delegation %d: unexpected %#v", i, d)
+		}
+	}
+
+	for _, line := range []string{
+		"ripencc|DE|ipv4|192.0.2.300|256|20100712|allocated",
+		"ripencc|DE|ipv4|255.255.255.0|512|20100712|allocated",
+		"ripencc|DE|ipv6|2001:db8::|129|20100712|allocated",
+		"ripencc|DE|ipv4|192.0.2.0|256|2010-07-12|allocated",
+	} {
+		if err = Parse(strings.NewReader(line), "test", func(d *data.Delegation) error { return nil }); err == nil {
+			t.Errorf("Parsing invalid record %q did not fail", line)
+		}
+	}
+} // func TestParse(t *testing.T)
+
+func TestImport(t *testing.T) {
+	var (
+		err  error
+		db   database.HostDB
+		n    int
+		d    *data.Delegation
+		ctx  = context.Background()
+		dir  = t.TempDir()
+		path = filepath.Join(dir, "delegated-ripencc-extended-latest")
+	)
+
+	if err = os.WriteFile(path, []byte(delegated), 0600); err != nil {
+		t.Fatalf("Cannot write %s: %s", path, err.Error())
+	} else if db, err = database.OpenDB(filepath.Join(dir, "rir.db")); err != nil {
+		t.Fatalf("Cannot open database: %s", err.Error())
+	}
+
+	defer db.Close()
+
+	// Importing the file twice replaces the Delegations of the first run.
+	for i := 0; i < 2; i++ {
+		if n, err = ImportAll(ctx, db, path); err != nil {
+			t.Fatalf("Cannot import delegations: %s", err.Error())
+		} else if n != 3 {
+			t.Errorf("Imported %d delegations, expected 3", n)
+		}
+	}
+
+	if d, err = db.DelegationLookup(ctx, net.ParseIP("198.51.101.17")); err != nil {
+		t.Fatalf("Cannot look up delegation: %s", err.Error())
+	} else if d.Country != "NL" || d.Status != "assigned" {
+		t.Errorf("Unexpected delegation: %#v", d)
+	} else if _, err = ImportAll(ctx, db); err != ErrNoFiles {
+		t.Errorf("Unexpected error importing no files: %v", err)
+	}
+} // func TestImport(t *testing.T)
@@ -0,0 +1,243 @@
+// /home/krylon/go/src/github.com/blicero/guang/rules/engine.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 15:24:51 krylon>
+
+package rules
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/blicero/guang/common"
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/database"
+	"github.com/blicero/guang/logdomain"
+	"github.com/blicero/krylib"
+)
+
+// reloadInterval is the time after which the Engine reloads its Rules from
+// the database, so changes the user made are picked up.
+const reloadInterval = time.Minute
+
+// Engine evaluates the enabled Rules against Hosts and records the
+// Findings in the database. It is safe for concurrent use, but the HostDB
+// passed to its methods is not, of course.
+type Engine struct {
+	log    *common.Logger
+	lock   sync.Mutex
+	rules  []*Rule
+	loaded time.Time
+}
+
+// NewEngine creates a new Engine. It has no Rules until they are loaded
+// from the database, which happens automatically the first time it is used.
+func NewEngine() (*Engine, error) {
+	var (
+		err error
+		e   = new(Engine)
+	)
+
+	if e.log, err = common.GetLogger(logdomain.Rules); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+} // func NewEngine() (*Engine, error)
+
+// Load (re)loads the enabled Rules from the database. Rules that cannot be
+// compiled are logged and skipped, so one broken Rule does not disable all
+// the others.
+func (e *Engine) Load(ctx context.Context, db database.HostDB) error {
+	var (
+		err  error
+		list []data.Rule
+	)
+
+	if list, err = db.RuleGetAll(ctx); err != nil {
+		e.log.Printf("[ERROR] Cannot load Rules: %s\n", err.Error())
+		return err
+	}
+
+	var compiled = make([]*Rule, 0, len(list))
+
+	for _, r := range list {
+		var cr *Rule
+
+		if !r.Enabled {
+			continue
+		} else if cr, err = Compile(r); err != nil {
+			e.log.Printf("[ERROR] Skipping Rule #%d: %s\n",
+				r.ID,
+				err.Error())
+			continue
+		}
+
+		compiled = append(compiled, cr)
+	}
+
+	e.lock.Lock()
+	e.rules = compiled
+	e.loaded = time.Now()
+	e.lock.Unlock()
+
+	if common.Debug {
+		e.log.Printf("[DEBUG] Loaded %d of %d Rules\n",
+			len(compiled),
+			len(list))
+	}
+
+	return nil
+} // func (e *Engine) Load(ctx context.Context, db database.HostDB) error
+
+// current returns the Rules, reloading them first if they are stale.
+func (e *Engine) current(ctx context.Context, db database.HostDB) ([]*Rule, error) {
+	var (
+		err   error
+		stale bool
+	)
+
+	e.lock.Lock()
+	stale = time.Since(e.loaded) > reloadInterval
+	e.lock.Unlock()
+
+	if stale {
+		if err = e.Load(ctx, db); err != nil {
+			return nil, err
+		}
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.rules, nil
+} // func (e *Engine) current(ctx context.Context, db database.HostDB) ([]*Rule, error)
+
+// Evaluate matches all Rules against the Host and its Ports, stores the
+// Findings, tags the Host as the Rules say, and returns the Findings.
+// Findings and Tags of Rules that no longer match are left alone.
+func (e *Engine) Evaluate(ctx context.Context, db database.HostDB, host *data.Host, ports []data.Port) ([]data.Finding, error) {
+	var (
+		err    error
+		rules  []*Rule
+		list   []data.Finding
+		tagged = make(map[string]bool)
+		now    = time.Now()
+	)
+
+	if rules, err = e.current(ctx, db); err != nil {
+		return nil, err
+	}
+
+	for _, r := range rules {
+		var found = r.Match(host, ports)
+
+		for i := range found {
+			found[i].Stamp = now
+			if err = db.FindingAdd(ctx, &found[i]); err != nil {
+				return list, err
+			}
+		}
+
+		if len(found) > 0 && r.Tag != "" && !tagged[r.Tag] {
+			if _, err = db.HostTagAdd(ctx, host.ID, r.Tag); err != nil {
+				return list, err
+			}
+			tagged[r.Tag] = true
+		}
+
+		list = append(list, found...)
+	}
+
+	if len(list) > 0 && common.Debug {
+		e.log.Printf("[DEBUG] %d Rules matched %s (%s)\n",
+			len(list),
+			host.Address,
+			host.Name)
+	}
+
+	return list, nil
+} // func (e *Engine) Evaluate(ctx context.Context, db database.HostDB, host *data.Host, ports []data.Port) ([]data.Finding, error)
+
+// EvaluateResult matches all Rules against a ScanResult that was just
+// stored in the database.
+func (e *Engine) EvaluateResult(ctx context.Context, db database.HostDB, res *data.ScanResult) error {
+	var (
+		err  error
+		port = data.Port{
+			HostID:    res.Host.ID,
+			Port:      res.Port,
+			Timestamp: res.Stamp,
+			Reply:     res.Reply,
+			Software:  res.Software,
+		}
+	)
+
+	if res.Host.ID == krylib.INVALID_ID {
+		return nil
+	} else if _, err = e.Evaluate(ctx, db, &res.Host, []data.Port{port}); err != nil {
+		e.log.Printf("[ERROR] Cannot evaluate Rules for %s:%d: %s\n",
+			res.Host.Address,
+			res.Port,
+			err.Error())
+		return err
+	}
+
+	return nil
+} // func (e *Engine) EvaluateResult(ctx context.Context, db database.HostDB, res *data.ScanResult) error
+
+// EvaluateAll reloads the Rules and matches them against every Host in the
+// database. Findings that are not confirmed in the process are removed.
+// It returns the number of Findings.
+func (e *Engine) EvaluateAll(ctx context.Context, db database.HostDB) (int, error) {
+	var (
+		err   error
+		hosts []data.Host
+		cnt   int
+		start = time.Now()
+	)
+
+	if err = e.Load(ctx, db); err != nil {
+		return 0, err
+	} else if hosts, err = db.HostGetAll(ctx); err != nil {
+		e.log.Printf("[ERROR] Cannot load Hosts: %s\n", err.Error())
+		return 0, err
+	}
+
+	e.log.Printf("[INFO] Evaluating Rules for %d Hosts\n", len(hosts))
+
+	for i := range hosts {
+		var (
+			ports []data.Port
+			found []data.Finding
+			h     = &hosts[i]
+		)
+
+		if ports, err = db.PortGetByHost(ctx, h.ID); err != nil {
+			e.log.Printf("[ERROR] Cannot load Ports of %s: %s\n",
+				h.Address,
+				err.Error())
+			return cnt, err
+		} else if found, err = e.Evaluate(ctx, db, h, ports); err != nil {
+			e.log.Printf("[ERROR] Cannot evaluate Rules for %s: %s\n",
+				h.Address,
+				err.Error())
+			return cnt, err
+		}
+
+		cnt += len(found)
+	}
+
+	var purged int64
+
+	if purged, err = db.FindingPurge(ctx, start); err != nil {
+		return cnt, err
+	}
+
+	e.log.Printf("[INFO] Rules produced %d Findings, %d stale Findings were removed\n",
+		cnt,
+		purged)
+
+	return cnt, nil
+} // func (e *Engine) EvaluateAll(ctx context.Context, db database.HostDB) (int, error)
@@ -0,0 +1,139 @@
+// /home/krylon/go/src/github.com/blicero/guang/rules/rules.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 15:11:36 krylon>
+
+// Package rules flags interesting Hosts - exotic or outdated operating
+// systems, suspicious banners, and whatever else the user cares about -
+// by evaluating the Rules stored in the database, see data.Rule.
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/blicero/guang/data"
+)
+
+// Rule is a data.Rule with its regular expressions compiled.
+type Rule struct {
+	data.Rule
+	banner   *regexp.Regexp
+	os       *regexp.Regexp
+	location *regexp.Regexp
+	hostname *regexp.Regexp
+}
+
+// Compile checks a data.Rule for errors and compiles its patterns.
+func Compile(r data.Rule) (*Rule, error) {
+	var (
+		err error
+		cr  = &Rule{Rule: r}
+	)
+
+	if strings.TrimSpace(r.Label) == "" {
+		return nil, errors.New("Rule has no label")
+	} else if r.Port == 0 && r.Banner == "" && r.OS == "" && r.Location == "" && r.Hostname == "" {
+		return nil, fmt.Errorf("Rule %q would match every Host", r.Label)
+	} else if cr.banner, err = compilePattern("banner", r.Banner); err != nil {
+		return nil, err
+	} else if cr.os, err = compilePattern("OS", r.OS); err != nil {
+		return nil, err
+	} else if cr.location, err = compilePattern("location", r.Location); err != nil {
+		return nil, err
+	} else if cr.hostname, err = compilePattern("hostname", r.Hostname); err != nil {
+		return nil, err
+	}
+
+	return cr, nil
+} // func Compile(r data.Rule) (*Rule, error)
+
+// compilePattern compiles a pattern, unless it is empty, in which case it
+// returns nil.
+func compilePattern(what, pattern string) (*regexp.Regexp, error) {
+	var (
+		err error
+		re  *regexp.Regexp
+	)
+
+	if pattern == "" {
+		return nil, nil
+	} else if re, err = regexp.Compile(pattern); err != nil {
+		return nil, fmt.Errorf("Invalid %s pattern %q: %w", what, pattern, err)
+	}
+
+	return re, nil
+} // func compilePattern(what, pattern string) (*regexp.Regexp, error)
+
+// PerPort returns true if the Rule matches individual Ports rather than the
+// Host as a whole.
+func (r *Rule) PerPort() bool {
+	return r.Port != 0 || r.banner != nil
+} // func (r *Rule) PerPort() bool
+
+// MatchHost returns true if the Host matches the criteria of the Rule that
+// concern the Host as a whole, i.e. its OS, location and names.
+func (r *Rule) MatchHost(h *data.Host) bool {
+	if r.os != nil && !r.os.MatchString(h.OS) {
+		return false
+	} else if r.location != nil && !r.location.MatchString(h.Location) {
+		return false
+	} else if r.hostname == nil || r.hostname.MatchString(h.Name) {
+		return true
+	}
+
+	for _, n := range h.Names {
+		if r.hostname.MatchString(n.Name) {
+			return true
+		}
+	}
+
+	return false
+} // func (r *Rule) MatchHost(h *data.Host) bool
+
+// MatchPort returns true if the Port is open and matches the criteria of
+// the Rule that concern Ports.
+func (r *Rule) MatchPort(p *data.Port) bool {
+	if p.Reply == nil {
+		return false
+	} else if r.Port != 0 && p.Port != r.Port {
+		return false
+	}
+
+	return r.banner == nil || r.banner.MatchString(*p.Reply)
+} // func (r *Rule) MatchPort(p *data.Port) bool
+
+// Match returns the Findings of the Rule on the Host, which are not stored
+// anywhere. The Findings are empty if the Rule does not match.
+func (r *Rule) Match(h *data.Host, ports []data.Port) []data.Finding {
+	var list []data.Finding
+
+	if !r.MatchHost(h) {
+		return nil
+	} else if !r.PerPort() {
+		return []data.Finding{r.finding(h, 0)}
+	}
+
+	for i := range ports {
+		if r.MatchPort(&ports[i]) {
+			list = append(list, r.finding(h, ports[i].Port))
+		}
+	}
+
+	return list
+} // func (r *Rule) Match(h *data.Host, ports []data.Port) []data.Finding
+
+func (r *Rule) finding(h *data.Host, port uint16) data.Finding {
+	return data.Finding{
+		RuleID:   r.ID,
+		HostID:   h.ID,
+		Port:     port,
+		Label:    r.Label,
+		Severity: r.Severity,
+		HostName: h.Name,
+		Address:  h.Address,
+	}
+} // func (r *Rule) finding(h *data.Host, port uint16) data.Finding
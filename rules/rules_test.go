@@ -0,0 +1,194 @@
+// /home/krylon/go/src/github.com/blicero/guang/rules/rules_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 15:11:36 krylon>
+
+package rules
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/database"
+	"github.com/blicero/guang/rules/severity"
+)
+
+func TestCompile(t *testing.T) {
+	type testCase struct {
+		rule data.Rule
+		ok   bool
+	}
+
+	var cases = []testCase{
+		{rule: data.Rule{Label: "SSH", Port: 22}, ok: true},
+		{rule: data.Rule{Label: "BSD", OS: "BSD$"}, ok: true},
+		{rule: data.Rule{Port: 22}, ok: false},
+		{rule: data.Rule{Label: "Everything"}, ok: false},
+		{rule: data.Rule{Label: "Broken", Banner: "(unbalanced"}, ok: false},
+	}
+
+	for i, c := range cases {
+		if _, err := Compile(c.rule); (err == nil) != c.ok {
+			t.Errorf("Case %d: Compile(%#v) returned %v", i, c.rule, err)
+		}
+	}
+} // func TestCompile(t *testing.T)
+
+func TestMatch(t *testing.T) {
+	type testCase struct {
+		rule  data.Rule
+		host  data.Host
+		port  uint16
+		reply string
+		cnt   int
+	}
+
+	var (
+		xp     = data.Rule{Label: "XP", Banner: `(?i)Windows\s*(?:NT|95|98|ME|2000|XP|Server 2003|Vista)\b`}
+		centos = data.Rule{Label: "CentOS", Banner: `(?i)CentOS(?:\s+release)?\s+[2-6]\b|[.]el[2-6][._]`}
+		bsd    = data.Rule{Label: "BSD", OS: "BSD$"}
+		cases  = []testCase{
+			{rule: xp, reply: "220 Microsoft FTP Service (Windows XP)", cnt: 1},
+			{rule: xp, reply: "Microsoft-IIS/10.0 Windows Server 2019", cnt: 0},
+			{rule: centos, reply: "Apache/2.2.15 (CentOS) mod_ssl/2.2.15 el6_10", cnt: 0},
+			{rule: centos, reply: "OpenSSH_5.3 CentOS release 6.10", cnt: 1},
+			{rule: bsd, host: data.Host{OS: "FreeBSD"}, cnt: 1},
+			{rule: bsd, host: data.Host{OS: "Linux"}, cnt: 0},
+			{
+				rule:  data.Rule{Label: "Old BSD SSH", OS: "BSD", Banner: "OpenSSH_[1-6]"},
+				host:  data.Host{OS: "Linux"},
+				reply: "SSH-2.0-OpenSSH_5.1",
+				cnt:   0,
+			},
+			{rule: data.Rule{Label: "Telnet", Port: 23}, port: 23, reply: "login:", cnt: 1},
+			{rule: data.Rule{Label: "Telnet", Port: 23}, port: 22, reply: "login:", cnt: 0},
+			{
+				rule: data.Rule{Label: "Printer", Hostname: "^printer"},
+				host: data.Host{
+					Name:  "foo.example.com",
+					Names: []data.HostName{{Name: "printer.example.com"}},
+				},
+				cnt: 1,
+			},
+			{rule: data.Rule{Label: "Sweden", Location: "Schweden"}, host: data.Host{Location: "Stockholm, Schweden"}, cnt: 1},
+		}
+	)
+
+	for i, c := range cases {
+		var (
+			err   error
+			r     *Rule
+			found []data.Finding
+			reply = c.reply
+			port  = c.port
+		)
+
+		if port == 0 {
+			port = 22
+		}
+
+		if r, err = Compile(c.rule); err != nil {
+			t.Fatalf("Case %d: Cannot compile Rule: %s", i, err.Error())
+		} else if found = r.Match(&c.host, []data.Port{{Port: port, Reply: &reply}}); len(found) != c.cnt {
+			t.Errorf("Case %d: Rule %s matched %q / %q %d times, expected %d",
+				i,
+				c.rule.Label,
+				c.host.OS,
+				c.reply,
+				len(found),
+				c.cnt)
+		}
+	}
+} // func TestMatch(t *testing.T)
+
+func TestEngine(t *testing.T) {
+	var (
+		err      error
+		db       database.HostDB
+		eng      *Engine
+		cnt      int
+		found    []data.Finding
+		tags     []data.Tag
+		ctx      = context.Background()
+		path     = filepath.Join(t.TempDir(), "rules.db")
+		banner   = "220 Microsoft FTP Service (Windows XP)"
+		findings []data.Finding
+		host     = data.Host{
+			Address: net.ParseIP("192.0.2.42"),
+			Name:    "ftp.example.com",
+			Source:  data.HostSourceUser,
+		}
+	)
+
+	if db, err = database.OpenDB(path); err != nil {
+		t.Fatalf("Cannot open database at %s: %s", path, err.Error())
+	}
+
+	defer db.Close()
+
+	if eng, err = NewEngine(); err != nil {
+		t.Fatalf("Cannot create Engine: %s", err.Error())
+	} else if _, err = db.HostUpsert(ctx, &host); err != nil {
+		t.Fatalf("Cannot add Host: %s", err.Error())
+	}
+
+	var res = data.ScanResult{
+		Host:  host,
+		Port:  21,
+		Reply: &banner,
+		Stamp: time.Now(),
+	}
+
+	// The default Rules flag Windows XP and tag the Host legacy-os.
+	if err = db.PortAdd(ctx, &res); err != nil {
+		t.Fatalf("Cannot add Port: %s", err.Error())
+	} else if err = eng.EvaluateResult(ctx, db, &res); err != nil {
+		t.Fatalf("Cannot evaluate Rules: %s", err.Error())
+	} else if findings, err = db.FindingGetByHost(ctx, host.ID); err != nil {
+		t.Fatalf("Cannot load Findings: %s", err.Error())
+	} else if len(findings) != 1 || findings[0].Port != 21 || findings[0].Severity != severity.Medium {
+		t.Errorf("Unexpected Findings: %#v", findings)
+	} else if tags, err = db.HostTagGet(ctx, host.ID); err != nil {
+		t.Fatalf("Cannot load Tags: %s", err.Error())
+	} else if len(tags) != 1 || tags[0].Name != "legacy-os" {
+		t.Errorf("Unexpected Tags: %#v", tags)
+	}
+
+	var r = data.Rule{
+		Label:    "FTP server",
+		Severity: severity.Info,
+		Port:     21,
+		Enabled:  true,
+	}
+
+	if err = db.RuleAdd(ctx, &r); err != nil {
+		t.Fatalf("Cannot add Rule: %s", err.Error())
+	} else if found, err = eng.Evaluate(ctx, db, &host, []data.Port{{Port: 21, Reply: &banner}}); err != nil {
+		t.Fatalf("Cannot evaluate Rules: %s", err.Error())
+	} else if len(found) != 1 {
+		// The Engine has not noticed the new Rule yet.
+		t.Errorf("Unexpected Findings before reload: %#v", found)
+	} else if cnt, err = eng.EvaluateAll(ctx, db); err != nil {
+		t.Fatalf("Cannot evaluate Rules for all Hosts: %s", err.Error())
+	} else if cnt != 2 {
+		t.Errorf("EvaluateAll found %d Findings, expected 2", cnt)
+	}
+
+	// Once the Rule is disabled, its Findings go away at the next full
+	// evaluation.
+	r.Enabled = false
+	if err = db.RuleUpdate(ctx, &r); err != nil {
+		t.Fatalf("Cannot update Rule: %s", err.Error())
+	} else if cnt, err = eng.EvaluateAll(ctx, db); err != nil {
+		t.Fatalf("Cannot evaluate Rules for all Hosts: %s", err.Error())
+	} else if findings, err = db.FindingGetAll(ctx, severity.Info); err != nil {
+		t.Fatalf("Cannot load Findings: %s", err.Error())
+	} else if cnt != 1 || len(findings) != 1 {
+		t.Errorf("Unexpected Findings after disabling Rule: %d / %#v", cnt, findings)
+	}
+} // func TestEngine(t *testing.T)
@@ -0,0 +1,50 @@
+// /home/krylon/go/src/github.com/blicero/guang/rules/severity/severity.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 15:11:36 krylon>
+
+// Package severity provides the levels of importance a Rule can assign to
+// the Hosts it matches.
+package severity
+
+//go:generate stringer -type=Severity
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity indicates how interesting a Finding is.
+type Severity uint8
+
+// The values are stored in the database, so new levels must be appended.
+const (
+	Info Severity = iota
+	Low
+	Medium
+	High
+	Critical
+)
+
+// AllSeverities returns all Severities, from the least to the most severe.
+func AllSeverities() []Severity {
+	return []Severity{
+		Info,
+		Low,
+		Medium,
+		High,
+		Critical,
+	}
+} // func AllSeverities() []Severity
+
+// Parse returns the Severity with the given name, ignoring case.
+func Parse(name string) (Severity, error) {
+	for _, s := range AllSeverities() {
+		if strings.EqualFold(s.String(), name) {
+			return s, nil
+		}
+	}
+
+	return 0, fmt.Errorf("Unknown severity %q", name)
+} // func Parse(name string) (Severity, error)
@@ -0,0 +1,320 @@
+// /home/krylon/go/src/github.com/blicero/guang/search/search.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 16:22:36 krylon>
+
+// Package search implements the little query language used to search
+// the database for Hosts and the banners of their open ports.
+//
+// A query is a list of terms separated by whitespace. A term is either a
+// word or a phrase in double quotes, which must occur in the name,
+// operating system or location of a Host or in the reply of one of its
+// ports, or a qualified term of the form key:value, where value may be
+// quoted as well:
+//
+//	port:22,2222        the Host has one of these ports open
+//	banner:"OpenSSH_7"  the reply of an open port contains the value
+//	product:nginx       the product found on an open port contains the value
+//	version:1.18        the version of that product starts with the value
+//	os:FreeBSD          the operating system contains the value
+//	name:example.com    one of the Host's names contains the value
+//	location:Germany    the location contains the value
+//	country:DE,AT       the Host is in one of these countries, by ISO code
+//	net:10.0.0.0/8      one of the Host's addresses is in the network
+//	asn:3320,AS13335    the Host is in one of these autonomous systems
+//	org:Telekom         the organisation owning the Host's AS contains the value
+//	tag:legacy-os       the Host has the tag
+//
+// Plain IP addresses and networks in CIDR notation are treated as if they
+// were given with net:. All terms must match, except for port:, net:,
+// country: and asn:, which match if any of the given values match. If a query
+// contains more than one of port:, banner:, product: and version:, they
+// must match the same port.
+package search
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/blicero/guang/data"
+)
+
+// DefaultPerPage is the number of Hosts on a page of search results if the
+// caller does not ask for a specific number, MaxPerPage is the largest
+// number of Hosts we return on a single page.
+const (
+	DefaultPerPage = 50
+	MaxPerPage     = 500
+)
+
+// Query is a parsed search query.
+type Query struct {
+	Raw      string
+	Terms    []string
+	Banner   []string
+	Product  []string
+	Version  []string
+	OS       []string
+	Name     []string
+	Location []string
+	Country  []string
+	Ports    []uint16
+	Nets     []*net.IPNet
+	ASNs     []uint32
+	Org      []string
+	Tags     []string
+}
+
+// Result is one page of search results.
+type Result struct {
+	Query   string
+	Total   int64
+	Page    int
+	PerPage int
+	Hits    []data.HostWithPorts
+}
+
+// PageCount returns the number of pages needed to show all results.
+func (r *Result) PageCount() int {
+	if r.PerPage < 1 {
+		return 0
+	}
+
+	return int((r.Total + int64(r.PerPage) - 1) / int64(r.PerPage))
+} // func (r *Result) PageCount() int
+
+// Parse parses a search query.
+func Parse(s string) (*Query, error) {
+	var (
+		err    error
+		tokens []token
+		q      = &Query{Raw: strings.TrimSpace(s)}
+	)
+
+	if tokens, err = tokenize(s); err != nil {
+		return nil, err
+	}
+
+	for _, t := range tokens {
+		if t.key == "" {
+			var n *net.IPNet
+
+			if t.value == "" {
+				continue
+			}
+
+			if !t.quoted {
+				n = ParseNet(t.value)
+			}
+
+			if n != nil {
+				q.Nets = append(q.Nets, n)
+			} else {
+				q.Terms = append(q.Terms, t.value)
+			}
+			continue
+		} else if t.value == "" {
+			return nil, fmt.Errorf("Missing value for %s:", t.key)
+		}
+
+		switch strings.ToLower(t.key) {
+		case "port":
+			for _, p := range strings.Split(t.value, ",") {
+				var num uint64
+
+				if num, err = strconv.ParseUint(p, 10, 16); err != nil || num == 0 {
+					return nil, fmt.Errorf("Invalid port number %q", p)
+				}
+
+				q.Ports = append(q.Ports, uint16(num))
+			}
+		case "banner", "reply":
+			q.Banner = append(q.Banner, t.value)
+		case "product":
+			q.Product = append(q.Product, t.value)
+		case "version":
+			q.Version = append(q.Version, t.value)
+		case "os":
+			q.OS = append(q.OS, t.value)
+		case "name", "host":
+			q.Name = append(q.Name, t.value)
+		case "location", "loc":
+			q.Location = append(q.Location, t.value)
+		case "country", "cc":
+			for _, cc := range strings.Split(t.value, ",") {
+				if len(cc) != 2 || !isAlpha(cc) {
+					return nil, fmt.Errorf("Invalid country code %q", cc)
+				}
+				q.Country = append(q.Country, strings.ToUpper(cc))
+			}
+		case "asn", "as":
+			for _, a := range strings.Split(t.value, ",") {
+				var num uint64
+
+				if num, err = strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(a), "AS"), 10, 32); err != nil || num == 0 {
+					return nil, fmt.Errorf("Invalid AS number %q", a)
+				}
+
+				q.ASNs = append(q.ASNs, uint32(num))
+			}
+		case "org":
+			q.Org = append(q.Org, t.value)
+		case "tag":
+			q.Tags = append(q.Tags, strings.ToLower(t.value))
+		case "net", "cidr", "ip":
+			var n *net.IPNet
+
+			if n = ParseNet(t.value); n == nil {
+				return nil, fmt.Errorf("Invalid network or address %q", t.value)
+			}
+
+			q.Nets = append(q.Nets, n)
+		default:
+			return nil, fmt.Errorf("Unknown qualifier %q", t.key)
+		}
+	}
+
+	return q, nil
+} // func Parse(s string) (*Query, error)
+
+// Empty returns true if the Query does not restrict the results at all.
+func (q *Query) Empty() bool {
+	return len(q.Terms) == 0 &&
+		len(q.Banner) == 0 &&
+		len(q.Product) == 0 &&
+		len(q.Version) == 0 &&
+		len(q.OS) == 0 &&
+		len(q.Name) == 0 &&
+		len(q.Location) == 0 &&
+		len(q.Country) == 0 &&
+		len(q.Ports) == 0 &&
+		len(q.Nets) == 0 &&
+		len(q.ASNs) == 0 &&
+		len(q.Org) == 0 &&
+		len(q.Tags) == 0
+} // func (q *Query) Empty() bool
+
+// HasPortFilter returns true if the Query restricts which ports of a Host
+// are considered.
+func (q *Query) HasPortFilter() bool {
+	return len(q.Ports) > 0 ||
+		len(q.Banner) > 0 ||
+		len(q.Product) > 0 ||
+		len(q.Version) > 0
+} // func (q *Query) HasPortFilter() bool
+
+// ParseNet parses s as either a network in CIDR notation or a single IP
+// address. It returns nil if s is neither.
+func ParseNet(s string) *net.IPNet {
+	if _, n, err := net.ParseCIDR(s); err == nil {
+		return n
+	} else if addr := net.ParseIP(s); addr == nil {
+		return nil
+	} else if v4 := addr.To4(); v4 != nil {
+		return &net.IPNet{IP: v4, Mask: net.CIDRMask(32, 32)}
+	} else {
+		return &net.IPNet{IP: addr, Mask: net.CIDRMask(128, 128)}
+	}
+} // func ParseNet(s string) *net.IPNet
+
+type token struct {
+	key    string
+	value  string
+	quoted bool
+}
+
+// tokenize splits a query into its terms.
+func tokenize(s string) ([]token, error) {
+	var (
+		tokens []token
+		rs     = []rune(s)
+		i      = 0
+	)
+
+	for i < len(rs) {
+		if unicode.IsSpace(rs[i]) {
+			i++
+			continue
+		}
+
+		var (
+			start = i
+			t     token
+		)
+
+		// An IPv6 address looks a lot like a qualified term, so we check
+		// for those first.
+		for i < len(rs) && !unicode.IsSpace(rs[i]) {
+			i++
+		}
+
+		if word := string(rs[start:i]); ParseNet(word) != nil {
+			tokens = append(tokens, token{value: word})
+			continue
+		}
+
+		i = start
+		for i < len(rs) && unicode.IsLetter(rs[i]) {
+			i++
+		}
+
+		if i > start && i < len(rs) && rs[i] == ':' {
+			t.key = string(rs[start:i])
+			i++
+		} else {
+			i = start
+		}
+
+		if i < len(rs) && rs[i] == '"' {
+			var (
+				b     strings.Builder
+				found bool
+			)
+
+			for i++; i < len(rs); i++ {
+				if rs[i] == '\\' && i+1 < len(rs) {
+					i++
+					b.WriteRune(rs[i])
+				} else if rs[i] == '"' {
+					found = true
+					i++
+					break
+				} else {
+					b.WriteRune(rs[i])
+				}
+			}
+
+			if !found {
+				return nil, fmt.Errorf("Unterminated quote at position %d", start)
+			}
+
+			t.value = b.String()
+			t.quoted = true
+		} else {
+			var vstart = i
+			for i < len(rs) && !unicode.IsSpace(rs[i]) {
+				i++
+			}
+			t.value = string(rs[vstart:i])
+		}
+
+		tokens = append(tokens, t)
+	}
+
+	return tokens, nil
+} // func tokenize(s string) ([]token, error)
+
+// isAlpha returns true if s consists of ASCII letters only.
+func isAlpha(s string) bool {
+	for _, c := range s {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+			return false
+		}
+	}
+
+	return true
+} // func isAlpha(s string) bool
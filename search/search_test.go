@@ -0,0 +1,148 @@
+// /home/krylon/go/src/github.com/blicero/guang/search/search_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 16:10:50 krylon>
+
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	type testCase struct {
+		query string
+		err   bool
+		terms []string
+		ports []uint16
+		nets  []string
+		os    []string
+		ban   []string
+		prod  []string
+		ver   []string
+		tags  []string
+		asns  []uint32
+		org   []string
+		cc    []string
+	}
+
+	var cases = []testCase{
+		{query: ""},
+		{query: "  vsftpd   2.3.4 ", terms: []string{"vsftpd", "2.3.4"}},
+		{
+			query: `port:22 os:FreeBSD banner:"OpenSSH_7"`,
+			ports: []uint16{22},
+			os:    []string{"FreeBSD"},
+			ban:   []string{"OpenSSH_7"},
+		},
+		{
+			query: `"Apache httpd" port:80,8080`,
+			terms: []string{"Apache httpd"},
+			ports: []uint16{80, 8080},
+		},
+		{
+			query: `banner:"say \"hello\""`,
+			ban:   []string{`say "hello"`},
+		},
+		{
+			query: "10.0.0.0/8 net:192.168.0.1 2001:db8::/32 dead:beef::1",
+			nets:  []string{"10.0.0.0/8", "192.168.0.1/32", "2001:db8::/32", "dead:beef::1/128"},
+		},
+		{query: "tag:Legacy-OS tag:dmz", tags: []string{"legacy-os", "dmz"}},
+		{
+			query: `product:"Apache httpd" version:2.2`,
+			prod:  []string{"Apache httpd"},
+			ver:   []string{"2.2"},
+		},
+		{
+			query: "asn:3320,AS13335 org:Telekom",
+			asns:  []uint32{3320, 13335},
+			org:   []string{"Telekom"},
+		},
+		{query: "country:de,AT cc:us", cc: []string{"DE", "AT", "US"}},
+		{query: "country:DEU", err: true},
+		{query: "asn:0", err: true},
+		{query: "asn:ASX", err: true},
+		{query: "port:http", err: true},
+		{query: "port:0", err: true},
+		{query: "os:", err: true},
+		{query: "color:blue", err: true},
+		{query: `banner:"unterminated`, err: true},
+		{query: "net:10.0.0.0/33", err: true},
+	}
+
+	for _, c := range cases {
+		var (
+			err  error
+			q    *Query
+			nets []string
+		)
+
+		if q, err = Parse(c.query); err != nil {
+			if !c.err {
+				t.Errorf("Error parsing %q: %s", c.query, err.Error())
+			}
+			continue
+		} else if c.err {
+			t.Errorf("Parsing %q should have failed", c.query)
+			continue
+		}
+
+		for _, n := range q.Nets {
+			nets = append(nets, n.String())
+		}
+
+		if !reflect.DeepEqual(q.Terms, c.terms) {
+			t.Errorf("Query %q: unexpected terms %#v, expected %#v",
+				c.query,
+				q.Terms,
+				c.terms)
+		} else if !reflect.DeepEqual(q.Ports, c.ports) {
+			t.Errorf("Query %q: unexpected ports %v, expected %v",
+				c.query,
+				q.Ports,
+				c.ports)
+		} else if !reflect.DeepEqual(nets, c.nets) {
+			t.Errorf("Query %q: unexpected networks %v, expected %v",
+				c.query,
+				nets,
+				c.nets)
+		} else if !reflect.DeepEqual(q.OS, c.os) {
+			t.Errorf("Query %q: unexpected OS %v, expected %v",
+				c.query,
+				q.OS,
+				c.os)
+		} else if !reflect.DeepEqual(q.Banner, c.ban) {
+			t.Errorf("Query %q: unexpected banners %v, expected %v",
+				c.query,
+				q.Banner,
+				c.ban)
+		} else if !reflect.DeepEqual(q.Product, c.prod) || !reflect.DeepEqual(q.Version, c.ver) {
+			t.Errorf("Query %q: unexpected products %v / versions %v, expected %v / %v",
+				c.query,
+				q.Product,
+				q.Version,
+				c.prod,
+				c.ver)
+		} else if !reflect.DeepEqual(q.Tags, c.tags) {
+			t.Errorf("Query %q: unexpected tags %v, expected %v",
+				c.query,
+				q.Tags,
+				c.tags)
+		} else if !reflect.DeepEqual(q.ASNs, c.asns) || !reflect.DeepEqual(q.Org, c.org) {
+			t.Errorf("Query %q: unexpected ASNs %v / organisations %v, expected %v / %v",
+				c.query,
+				q.ASNs,
+				q.Org,
+				c.asns,
+				c.org)
+		} else if !reflect.DeepEqual(q.Country, c.cc) {
+			t.Errorf("Query %q: unexpected countries %v, expected %v",
+				c.query,
+				q.Country,
+				c.cc)
+		}
+	}
+} // func TestParse(t *testing.T)
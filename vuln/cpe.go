@@ -0,0 +1,102 @@
+// /home/krylon/go/src/github.com/blicero/guang/vuln/cpe.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 17:04:39 krylon>
+
+package vuln
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// NA is the value of a CPE component that does not apply, e.g. the
+// version of a product that has none.
+const NA = "-"
+
+// CPE holds the parts of a CPE name we need to look up Vulnerabilities.
+// Components that are ANY (*) are empty, components that are NA are set
+// to NA, which only matches NA.
+type CPE struct {
+	Part    string
+	Vendor  string
+	Product string
+	Version string
+	Update  string
+}
+
+// ParseCPE parses a CPE name, either in the URI format of CPE 2.2 (e.g.
+// cpe:/a:openbsd:openssh:7.4), which the banner parser produces, or in the
+// formatted string format of CPE 2.3 (cpe:2.3:a:openbsd:openssh:7.4:*:...),
+// which the NVD uses.
+func ParseCPE(s string) (CPE, error) {
+	var (
+		err   error
+		parts []string
+		c     CPE
+	)
+
+	if strings.HasPrefix(s, "cpe:2.3:") {
+		parts = splitEscaped(s[8:])
+	} else if strings.HasPrefix(s, "cpe:/") {
+		parts = strings.Split(s[5:], ":")
+		for i := range parts {
+			if parts[i], err = url.PathUnescape(parts[i]); err != nil {
+				return c, fmt.Errorf("Invalid CPE name %q: %w", s, err)
+			}
+		}
+	} else {
+		return c, fmt.Errorf("Invalid CPE name %q", s)
+	}
+
+	if len(parts) < 2 {
+		return c, fmt.Errorf("CPE name %q has no vendor", s)
+	}
+
+	for i, dst := range []*string{&c.Part, &c.Vendor, &c.Product, &c.Version, &c.Update} {
+		if i < len(parts) && parts[i] != "*" {
+			*dst = strings.ToLower(parts[i])
+		}
+	}
+
+	return c, nil
+} // func ParseCPE(s string) (CPE, error)
+
+// FullVersion returns the version including the update, if any, e.g. 7.9p1
+// for OpenSSH 7.9, update p1.
+func (c *CPE) FullVersion() string {
+	if c.Version == NA || c.Update == NA {
+		return c.Version
+	}
+
+	return c.Version + c.Update
+} // func (c *CPE) FullVersion() string
+
+// splitEscaped splits the components of a CPE 2.3 formatted string at the
+// colons that are not escaped with a backslash, and removes the escapes.
+func splitEscaped(s string) []string {
+	var (
+		parts []string
+		cur   strings.Builder
+		esc   bool
+	)
+
+	for _, r := range s {
+		switch {
+		case esc:
+			cur.WriteRune(r)
+			esc = false
+		case r == '\\':
+			esc = true
+		case r == ':':
+			parts = append(parts, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+
+	return append(parts, cur.String())
+} // func splitEscaped(s string) []string
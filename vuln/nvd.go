@@ -0,0 +1,226 @@
+// /home/krylon/go/src/github.com/blicero/guang/vuln/nvd.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 17:04:39 krylon>
+
+package vuln
+
+import (
+	"strings"
+	"time"
+
+	"github.com/blicero/guang/data"
+)
+
+// This file contains the types the NVD's JSON data is decoded into, both
+// the legacy 1.1 data feeds (nvdcve-1.1-2023.json.gz) and the responses of
+// the 2.0 API, which are also what the NVD offers for download today.
+// Only the fields we use are declared.
+
+type nvdCPEMatch struct {
+	Vulnerable            bool   `json:"vulnerable"`
+	CPE23URI              string `json:"cpe23Uri"`
+	Criteria              string `json:"criteria"`
+	VersionStartIncluding string `json:"versionStartIncluding"`
+	VersionStartExcluding string `json:"versionStartExcluding"`
+	VersionEndIncluding   string `json:"versionEndIncluding"`
+	VersionEndExcluding   string `json:"versionEndExcluding"`
+}
+
+type nvdNode struct {
+	Children []nvdNode     `json:"children"`
+	CPEMatch []nvdCPEMatch `json:"cpe_match"`
+	Match    []nvdCPEMatch `json:"cpeMatch"`
+	Negate   bool          `json:"negate"`
+}
+
+type nvdDescription struct {
+	Lang  string `json:"lang"`
+	Value string `json:"value"`
+}
+
+// nvdItem is a CVE in a 1.1 data feed.
+type nvdItem struct {
+	CVE struct {
+		Meta struct {
+			ID string `json:"ID"`
+		} `json:"CVE_data_meta"`
+		Description struct {
+			Data []nvdDescription `json:"description_data"`
+		} `json:"description"`
+	} `json:"cve"`
+	Configurations struct {
+		Nodes []nvdNode `json:"nodes"`
+	} `json:"configurations"`
+	Impact struct {
+		V3 struct {
+			CVSS struct {
+				Score    float64 `json:"baseScore"`
+				Severity string  `json:"baseSeverity"`
+			} `json:"cvssV3"`
+		} `json:"baseMetricV3"`
+		V2 struct {
+			CVSS struct {
+				Score float64 `json:"baseScore"`
+			} `json:"cvssV2"`
+			Severity string `json:"severity"`
+		} `json:"baseMetricV2"`
+	} `json:"impact"`
+	Published string `json:"publishedDate"`
+}
+
+type nvdMetric struct {
+	CVSS struct {
+		Score    float64 `json:"baseScore"`
+		Severity string  `json:"baseSeverity"`
+	} `json:"cvssData"`
+	Severity string `json:"baseSeverity"`
+}
+
+// nvdCVE is a CVE in a response of the 2.0 API.
+type nvdCVE struct {
+	ID           string           `json:"id"`
+	Published    string           `json:"published"`
+	Descriptions []nvdDescription `json:"descriptions"`
+	Metrics      struct {
+		V31 []nvdMetric `json:"cvssMetricV31"`
+		V30 []nvdMetric `json:"cvssMetricV30"`
+		V2  []nvdMetric `json:"cvssMetricV2"`
+	} `json:"metrics"`
+	Configurations []struct {
+		Nodes []nvdNode `json:"nodes"`
+	} `json:"configurations"`
+}
+
+// nvdTimeFormats are the formats the NVD uses for timestamps, all in UTC.
+var nvdTimeFormats = []string{
+	"2006-01-02T15:04Z",
+	"2006-01-02T15:04:05.000",
+	"2006-01-02T15:04:05",
+}
+
+func parseNVDTime(s string) time.Time {
+	for _, f := range nvdTimeFormats {
+		if t, err := time.Parse(f, s); err == nil {
+			return t
+		}
+	}
+
+	return time.Time{}
+} // func parseNVDTime(s string) time.Time
+
+func englishDescription(list []nvdDescription) string {
+	for _, d := range list {
+		if d.Lang == "en" {
+			return d.Value
+		}
+	}
+
+	return ""
+} // func englishDescription(list []nvdDescription) string
+
+// ranges collects the affected ranges from the configuration nodes.
+//
+// The NVD combines CPEs with AND and OR, e.g. to say that a firmware is
+// only vulnerable on certain hardware. We do not know the hardware or the
+// platform of what we scan, so we take every CPE marked vulnerable to be
+// affected, which errs on the side of reporting too much.
+func ranges(nodes []nvdNode) []data.AffectedRange {
+	var list []data.AffectedRange
+
+	for _, n := range nodes {
+		if n.Negate {
+			continue
+		}
+
+		list = append(list, ranges(n.Children)...)
+
+		for _, m := range append(n.CPEMatch, n.Match...) {
+			var (
+				err  error
+				cpe  CPE
+				name = m.CPE23URI
+			)
+
+			if name == "" {
+				name = m.Criteria
+			}
+
+			if !m.Vulnerable {
+				continue
+			} else if cpe, err = ParseCPE(name); err != nil || cpe.Product == "" || cpe.Product == NA {
+				continue
+			}
+
+			var r = data.AffectedRange{
+				Vendor:    cpe.Vendor,
+				Product:   cpe.Product,
+				Version:   cpe.FullVersion(),
+				StartIncl: m.VersionStartIncluding,
+				StartExcl: m.VersionStartExcluding,
+				EndIncl:   m.VersionEndIncluding,
+				EndExcl:   m.VersionEndExcluding,
+			}
+
+			if r.StartIncl != "" || r.StartExcl != "" || r.EndIncl != "" || r.EndExcl != "" {
+				// Bounds are only given with a wildcard version.
+				r.Version = ""
+			}
+
+			list = append(list, r)
+		}
+	}
+
+	return list
+} // func ranges(nodes []nvdNode) []data.AffectedRange
+
+func (item *nvdItem) vulnerability() data.Vulnerability {
+	var v = data.Vulnerability{
+		Name:      item.CVE.Meta.ID,
+		Summary:   englishDescription(item.CVE.Description.Data),
+		Published: parseNVDTime(item.Published),
+		Source:    "NVD",
+		Ranges:    ranges(item.Configurations.Nodes),
+	}
+
+	if cvss := item.Impact.V3.CVSS; cvss.Score > 0 {
+		v.Score = cvss.Score
+		v.Severity = severityOf(cvss.Score, cvss.Severity)
+	} else {
+		v.Score = item.Impact.V2.CVSS.Score
+		v.Severity = severityOf(v.Score, item.Impact.V2.Severity)
+	}
+
+	return v
+} // func (item *nvdItem) vulnerability() data.Vulnerability
+
+func (cve *nvdCVE) vulnerability() data.Vulnerability {
+	var v = data.Vulnerability{
+		Name:      cve.ID,
+		Summary:   englishDescription(cve.Descriptions),
+		Published: parseNVDTime(cve.Published),
+		Source:    "NVD",
+	}
+
+	for _, c := range cve.Configurations {
+		v.Ranges = append(v.Ranges, ranges(c.Nodes)...)
+	}
+
+	// We prefer the most recent version of CVSS the NVD provides.
+	for _, metrics := range [][]nvdMetric{cve.Metrics.V31, cve.Metrics.V30, cve.Metrics.V2} {
+		if len(metrics) > 0 {
+			var sev = metrics[0].CVSS.Severity
+
+			if sev == "" {
+				sev = metrics[0].Severity
+			}
+
+			v.Score = metrics[0].CVSS.Score
+			v.Severity = severityOf(v.Score, strings.TrimSpace(sev))
+			break
+		}
+	}
+
+	return v
+} // func (cve *nvdCVE) vulnerability() data.Vulnerability
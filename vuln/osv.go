@@ -0,0 +1,121 @@
+// /home/krylon/go/src/github.com/blicero/guang/vuln/osv.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 15:40:22 krylon>
+
+package vuln
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/blicero/guang/data"
+)
+
+// This file contains the types OSV records are decoded into, see
+// https://ossf.github.io/osv-schema/. Only the fields we use are declared.
+
+type osvEvent struct {
+	Introduced   string `json:"introduced"`
+	Fixed        string `json:"fixed"`
+	LastAffected string `json:"last_affected"`
+}
+
+type osvAffected struct {
+	Package struct {
+		Ecosystem string `json:"ecosystem"`
+		Name      string `json:"name"`
+	} `json:"package"`
+	Ranges []struct {
+		Type   string     `json:"type"`
+		Events []osvEvent `json:"events"`
+	} `json:"ranges"`
+	Versions []string `json:"versions"`
+}
+
+type osvRecord struct {
+	ID        string        `json:"id"`
+	Summary   string        `json:"summary"`
+	Details   string        `json:"details"`
+	Published time.Time     `json:"published"`
+	Affected  []osvAffected `json:"affected"`
+	Specific  struct {
+		Severity string `json:"severity"`
+	} `json:"database_specific"`
+}
+
+// epoch matches the epoch some packaging systems, e.g. Debian's, put in
+// front of versions. Banners never contain it.
+var epoch = regexp.MustCompile(`^\d+:`)
+
+// vulnerability converts the OSV record. OSV describes affected packages
+// rather than CPEs, so the package name is used as the product and the
+// vendor is left empty, which matches any vendor. That works for packages
+// named like their upstream project, e.g. openssh, but not for those that
+// are not, e.g. apache2.
+func (rec *osvRecord) vulnerability() data.Vulnerability {
+	var v = data.Vulnerability{
+		Name:      rec.ID,
+		Summary:   rec.Summary,
+		Published: rec.Published,
+		Source:    "OSV",
+		Severity:  severityOf(0, rec.Specific.Severity),
+	}
+
+	if v.Summary == "" {
+		v.Summary, _, _ = strings.Cut(rec.Details, "\n")
+	}
+
+	for _, a := range rec.Affected {
+		var product = strings.ToLower(a.Package.Name)
+
+		if product == "" {
+			continue
+		}
+
+		for _, ver := range a.Versions {
+			v.Ranges = append(v.Ranges, data.AffectedRange{
+				Product: product,
+				Version: epoch.ReplaceAllString(ver, ""),
+			})
+		}
+
+		for _, rng := range a.Ranges {
+			var cur *data.AffectedRange
+
+			if rng.Type == "GIT" {
+				// Commit hashes mean nothing to us.
+				continue
+			}
+
+			for _, ev := range rng.Events {
+				switch {
+				case ev.Introduced != "":
+					cur = &data.AffectedRange{Product: product}
+					if ev.Introduced != "0" {
+						cur.StartIncl = epoch.ReplaceAllString(ev.Introduced, "")
+					}
+				case cur == nil:
+					continue
+				case ev.Fixed != "":
+					cur.EndExcl = epoch.ReplaceAllString(ev.Fixed, "")
+					v.Ranges = append(v.Ranges, *cur)
+					cur = nil
+				case ev.LastAffected != "":
+					cur.EndIncl = epoch.ReplaceAllString(ev.LastAffected, "")
+					v.Ranges = append(v.Ranges, *cur)
+					cur = nil
+				}
+			}
+
+			if cur != nil {
+				// Introduced, but not fixed yet.
+				v.Ranges = append(v.Ranges, *cur)
+			}
+		}
+	}
+
+	return v
+} // func (rec *osvRecord) vulnerability() data.Vulnerability
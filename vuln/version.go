@@ -0,0 +1,164 @@
+// /home/krylon/go/src/github.com/blicero/guang/vuln/version.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 15:40:22 krylon>
+
+package vuln
+
+import (
+	"strings"
+	"unicode"
+)
+
+// preRelease are the suffixes that mark a version as coming before the
+// release it is attached to, e.g. 2.0rc1 comes before 2.0. Other suffixes,
+// like the p1 in OpenSSH 7.9p1 or the a in OpenSSL 1.0.2a, mark a later
+// version.
+var preRelease = map[string]bool{
+	"alpha": true,
+	"beta":  true,
+	"rc":    true,
+	"pre":   true,
+	"dev":   true,
+}
+
+// CompareVersions compares two version strings and returns -1, 0 or 1 if a
+// is lower than, equal to, or greater than b.
+//
+// Versions are split into runs of digits and runs of letters, everything
+// else only separates the parts. Numbers are compared numerically, letters
+// alphabetically, and a number is greater than letters. If one version is
+// a prefix of the other, the longer one is greater, unless its remainder
+// starts with a pre-release marker such as rc or beta.
+//
+// This is not how any particular packaging system orders its versions, but
+// it gets the versions we see in banners right most of the time.
+func CompareVersions(a, b string) int {
+	var (
+		ta = versionParts(a)
+		tb = versionParts(b)
+	)
+
+	for i := 0; i < len(ta) && i < len(tb); i++ {
+		if c := comparePart(ta[i], tb[i]); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case len(ta) == len(tb):
+		return 0
+	case len(ta) > len(tb):
+		if preRelease[ta[len(tb)]] {
+			return -1
+		}
+		return 1
+	default:
+		if preRelease[tb[len(ta)]] {
+			return 1
+		}
+		return -1
+	}
+} // func CompareVersions(a, b string) int
+
+// versionParts splits a version into runs of digits and runs of letters.
+// Leading zeros are removed from numbers, letters are lowercased.
+func versionParts(v string) []string {
+	var (
+		parts []string
+		cur   strings.Builder
+		digit bool
+	)
+
+	var flush = func() {
+		if cur.Len() == 0 {
+			return
+		}
+
+		var p = cur.String()
+
+		if digit {
+			if p = strings.TrimLeft(p, "0"); p == "" {
+				p = "0"
+			}
+		}
+
+		parts = append(parts, p)
+		cur.Reset()
+	}
+
+	v = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(v)), "v")
+
+	for _, r := range v {
+		switch {
+		case unicode.IsDigit(r):
+			if !digit {
+				flush()
+			}
+			digit = true
+			cur.WriteRune(r)
+		case unicode.IsLetter(r):
+			if digit {
+				flush()
+			}
+			digit = false
+			cur.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+
+	flush()
+	return parts
+} // func versionParts(v string) []string
+
+func isNumber(s string) bool {
+	return s != "" && unicode.IsDigit(rune(s[0]))
+} // func isNumber(s string) bool
+
+// comparePart compares two parts of a version, see CompareVersions.
+func comparePart(a, b string) int {
+	var na, nb = isNumber(a), isNumber(b)
+
+	switch {
+	case na && nb:
+		// Without leading zeros, the longer number is the greater one,
+		// and we do not have to worry about overflows.
+		if len(a) != len(b) {
+			if len(a) < len(b) {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a, b)
+	case na:
+		return 1
+	case nb:
+		return -1
+	default:
+		return strings.Compare(a, b)
+	}
+} // func comparePart(a, b string) int
+
+// sameRelease returns true if version is release followed by a suffix that
+// starts with letters, e.g. 7.7p1 for 7.7. release must consist of numbers
+// only.
+func sameRelease(version, release string) bool {
+	var (
+		pv = versionParts(version)
+		pr = versionParts(release)
+	)
+
+	if len(pv) <= len(pr) || isNumber(pv[len(pr)]) {
+		return false
+	}
+
+	for i := range pr {
+		if !isNumber(pr[i]) || pr[i] != pv[i] {
+			return false
+		}
+	}
+
+	return true
+} // func sameRelease(version, release string) bool
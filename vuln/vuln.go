@@ -0,0 +1,456 @@
+// /home/krylon/go/src/github.com/blicero/guang/vuln/vuln.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 17:04:39 krylon>
+
+// Package vuln correlates the software the banner parser found on scanned
+// Ports with known vulnerabilities.
+//
+// The vulnerabilities are imported from files the user downloads
+// separately, so this works offline: the JSON data feeds of the NVD, in
+// the legacy 1.1 format or the format of the 2.0 API, and OSV records,
+// either as single files, as JSON arrays, or as the zip archives OSV
+// publishes per ecosystem. Files ending in .gz are decompressed on the fly.
+//
+// A Port is affected by a vulnerability if the vendor and product of its
+// CPE match an affected range of the vulnerability and its version lies
+// within that range. Ports without a CPE or without a version are never
+// affected, as we cannot tell.
+package vuln
+
+import (
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blicero/guang/common"
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/database"
+	"github.com/blicero/guang/logdomain"
+	"github.com/blicero/guang/rules/severity"
+)
+
+// reloadInterval is the time after which a Matcher forgets the
+// Vulnerabilities it loaded, so newly imported ones are picked up.
+const reloadInterval = time.Minute
+
+// document is the top level of a JSON file we import. It has the fields
+// of all the formats we support, so we can find out which one we got by
+// decoding it once.
+type document struct {
+	CVEItems        []nvdItem `json:"CVE_Items"`
+	Vulnerabilities []struct {
+		CVE nvdCVE `json:"cve"`
+	} `json:"vulnerabilities"`
+	osvRecord
+}
+
+// severityOf determines the Severity of a Vulnerability from its CVSS base
+// score, using the ratings of CVSS v3, or from the name the source gave
+// it, if there is no score.
+func severityOf(score float64, name string) severity.Severity {
+	switch {
+	case score >= 9:
+		return severity.Critical
+	case score >= 7:
+		return severity.High
+	case score >= 4:
+		return severity.Medium
+	case score > 0:
+		return severity.Low
+	case strings.EqualFold(name, "moderate"):
+		// GitHub's advisories, among others, say moderate.
+		return severity.Medium
+	}
+
+	if sev, err := severity.Parse(name); err == nil {
+		return sev
+	}
+
+	return severity.Info
+} // func severityOf(score float64, name string) severity.Severity
+
+// Decode reads the Vulnerabilities from a JSON document in one of the
+// supported formats and passes them to fn, one at a time. name is only
+// used in error messages.
+func Decode(r io.Reader, name string, fn func(v *data.Vulnerability) error) error {
+	var (
+		err   error
+		first []byte
+		br    = bufio.NewReader(r)
+		dec   = json.NewDecoder(br)
+	)
+
+	// Skip leading whitespace to see if we got an object or an array.
+	for {
+		if first, err = br.Peek(1); err != nil {
+			return fmt.Errorf("Cannot read %s: %w", name, err)
+		} else if strings.ContainsRune(" \t\r\n", rune(first[0])) {
+			br.ReadByte() // nolint: errcheck
+			continue
+		}
+		break
+	}
+
+	if first[0] == '[' {
+		var records []osvRecord
+
+		if err = dec.Decode(&records); err != nil {
+			return fmt.Errorf("Cannot decode %s: %w", name, err)
+		}
+
+		for i := range records {
+			var v = records[i].vulnerability()
+			if err = fn(&v); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	var doc document
+
+	if err = dec.Decode(&doc); err != nil {
+		return fmt.Errorf("Cannot decode %s: %w", name, err)
+	}
+
+	switch {
+	case len(doc.CVEItems) > 0:
+		for i := range doc.CVEItems {
+			var v = doc.CVEItems[i].vulnerability()
+			if err = fn(&v); err != nil {
+				return err
+			}
+		}
+	case len(doc.Vulnerabilities) > 0:
+		for i := range doc.Vulnerabilities {
+			var v = doc.Vulnerabilities[i].CVE.vulnerability()
+			if err = fn(&v); err != nil {
+				return err
+			}
+		}
+	case doc.ID != "":
+		var v = doc.osvRecord.vulnerability()
+		return fn(&v)
+	}
+
+	return nil
+} // func Decode(r io.Reader, name string, fn func(v *data.Vulnerability) error) error
+
+// Import reads the Vulnerabilities from the file at path and stores them
+// in the database, in a single transaction. It returns the number of
+// Vulnerabilities it imported. Vulnerabilities that are in the database
+// already are updated.
+func Import(ctx context.Context, db database.HostDB, path string) (int, error) {
+	var (
+		err error
+		cnt int
+	)
+
+	var store = func(v *data.Vulnerability) error {
+		if v.Name == "" {
+			return nil
+		} else if err := db.VulnAdd(ctx, v); err != nil {
+			return err
+		}
+
+		cnt++
+		return nil
+	}
+
+	if err = db.Begin(ctx); err != nil {
+		return 0, err
+	} else if err = importFile(path, store); err != nil {
+		db.Rollback() // nolint: errcheck
+		return 0, err
+	} else if err = db.Commit(); err != nil {
+		return 0, err
+	}
+
+	return cnt, nil
+} // func Import(ctx context.Context, db database.HostDB, path string) (int, error)
+
+// importFile decodes the file at path, unpacking it first if necessary.
+func importFile(path string, fn func(v *data.Vulnerability) error) error {
+	var (
+		err error
+		fh  *os.File
+		rd  io.Reader
+	)
+
+	if strings.HasSuffix(path, ".zip") {
+		return importZip(path, fn)
+	} else if fh, err = os.Open(path); err != nil {
+		return fmt.Errorf("Cannot open %s: %w", path, err)
+	}
+
+	defer fh.Close() // nolint: errcheck
+
+	rd = fh
+
+	if strings.HasSuffix(path, ".gz") {
+		var gz *gzip.Reader
+
+		if gz, err = gzip.NewReader(fh); err != nil {
+			return fmt.Errorf("Cannot decompress %s: %w", path, err)
+		}
+
+		defer gz.Close() // nolint: errcheck
+		rd = gz
+	}
+
+	return Decode(rd, path, fn)
+} // func importFile(path string, fn func(v *data.Vulnerability) error) error
+
+// importZip decodes all JSON files in the zip archive at path, e.g. one of
+// the all.zip files OSV publishes for each ecosystem.
+func importZip(path string, fn func(v *data.Vulnerability) error) error {
+	var (
+		err error
+		zr  *zip.ReadCloser
+	)
+
+	if zr, err = zip.OpenReader(path); err != nil {
+		return fmt.Errorf("Cannot open %s: %w", path, err)
+	}
+
+	defer zr.Close() // nolint: errcheck
+
+	for _, f := range zr.File {
+		var rc io.ReadCloser
+
+		if filepath.Ext(f.Name) != ".json" {
+			continue
+		} else if rc, err = f.Open(); err != nil {
+			return fmt.Errorf("Cannot open %s in %s: %w", f.Name, path, err)
+		}
+
+		err = Decode(rc, path+":"+f.Name, fn)
+		rc.Close() // nolint: errcheck
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+} // func importZip(path string, fn func(v *data.Vulnerability) error) error
+
+// Affects returns true if the given version of the product made by vendor
+// lies within the AffectedRange.
+//
+// An inclusive upper bound without a suffix also includes the versions of
+// the same release with a suffix, e.g. OpenSSH 7.7p1 is affected by
+// vulnerabilities the NVD says affect OpenSSH "through 7.7".
+//
+// A range whose version is NA only matches a version that is NA, too. A
+// version that is NA is matched by that and by ranges without any bounds,
+// which cover every version.
+func Affects(r *data.AffectedRange, vendor, version string) bool {
+	if r.Vendor != "" && vendor != "" && r.Vendor != vendor {
+		return false
+	} else if r.Version == NA {
+		return version == NA
+	} else if version == NA {
+		return r.Version == "" && r.StartIncl == "" && r.StartExcl == "" && r.EndIncl == "" && r.EndExcl == ""
+	} else if r.Version != "" {
+		return CompareVersions(version, r.Version) == 0
+	} else if r.StartIncl != "" && CompareVersions(version, r.StartIncl) < 0 {
+		return false
+	} else if r.StartExcl != "" && CompareVersions(version, r.StartExcl) <= 0 {
+		return false
+	} else if r.EndIncl != "" && CompareVersions(version, r.EndIncl) > 0 && !sameRelease(version, r.EndIncl) {
+		return false
+	} else if r.EndExcl != "" && CompareVersions(version, r.EndExcl) >= 0 {
+		return false
+	}
+
+	return true
+} // func Affects(r *data.AffectedRange, vendor, version string) bool
+
+// Matcher finds the Vulnerabilities affecting the software on Ports. It
+// keeps the Vulnerabilities it loads from the database for a while, so
+// matching many Ports running the same product is cheap. It is safe for
+// concurrent use, but the HostDB passed to its methods is not, of course.
+type Matcher struct {
+	log    *common.Logger
+	lock   sync.Mutex
+	cache  map[string][]data.Vulnerability
+	loaded time.Time
+}
+
+// NewMatcher creates a new Matcher.
+func NewMatcher() (*Matcher, error) {
+	var (
+		err error
+		m   = new(Matcher)
+	)
+
+	if m.log, err = common.GetLogger(logdomain.Vuln); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+} // func NewMatcher() (*Matcher, error)
+
+// vulnsOf returns the Vulnerabilities affecting some version of product.
+func (m *Matcher) vulnsOf(ctx context.Context, db database.HostDB, product string) ([]data.Vulnerability, error) {
+	var (
+		err   error
+		ok    bool
+		vulns []data.Vulnerability
+	)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.cache == nil || time.Since(m.loaded) > reloadInterval {
+		m.cache = make(map[string][]data.Vulnerability)
+		m.loaded = time.Now()
+	}
+
+	if vulns, ok = m.cache[product]; ok {
+		return vulns, nil
+	} else if vulns, err = db.VulnGetByProduct(ctx, product); err != nil {
+		return nil, err
+	}
+
+	m.cache[product] = vulns
+	return vulns, nil
+} // func (m *Matcher) vulnsOf(ctx context.Context, db database.HostDB, product string) ([]data.Vulnerability, error)
+
+// Affecting returns the Vulnerabilities affecting the Software.
+func (m *Matcher) Affecting(ctx context.Context, db database.HostDB, sw *data.Software) ([]data.Vulnerability, error) {
+	var (
+		err     error
+		cpe     CPE
+		version string
+		vulns   []data.Vulnerability
+		list    []data.Vulnerability
+	)
+
+	if sw.CPE == "" {
+		return nil, nil
+	} else if cpe, err = ParseCPE(sw.CPE); err != nil {
+		m.log.Printf("[DEBUG] %s\n", err.Error())
+		return nil, nil
+	} else if version = cpe.FullVersion(); version == "" {
+		version = sw.Version
+	}
+
+	if cpe.Product == "" || cpe.Product == NA || version == "" {
+		return nil, nil
+	} else if vulns, err = m.vulnsOf(ctx, db, cpe.Product); err != nil {
+		return nil, err
+	}
+
+	for i := range vulns {
+		for j := range vulns[i].Ranges {
+			if Affects(&vulns[i].Ranges[j], cpe.Vendor, version) {
+				list = append(list, vulns[i])
+				break
+			}
+		}
+	}
+
+	return list, nil
+} // func (m *Matcher) Affecting(ctx context.Context, db database.HostDB, sw *data.Software) ([]data.Vulnerability, error)
+
+// MatchPorts records which Vulnerabilities affect the given Ports and
+// returns how many it found in total.
+func (m *Matcher) MatchPorts(ctx context.Context, db database.HostDB, ports []data.Port) (int, error) {
+	var (
+		err error
+		cnt int
+	)
+
+	for i := range ports {
+		var vulns []data.Vulnerability
+
+		if vulns, err = m.Affecting(ctx, db, &ports[i].Software); err != nil {
+			return cnt, err
+		} else if err = db.PortVulnSet(ctx, &ports[i], vulns); err != nil {
+			return cnt, err
+		}
+
+		cnt += len(vulns)
+	}
+
+	return cnt, nil
+} // func (m *Matcher) MatchPorts(ctx context.Context, db database.HostDB, ports []data.Port) (int, error)
+
+// MatchAll matches all Ports with a CPE against the Vulnerabilities, in a
+// single transaction, and returns how many matches it found.
+func (m *Matcher) MatchAll(ctx context.Context, db database.HostDB) (int, error) {
+	var (
+		err   error
+		cnt   int
+		ports []data.Port
+	)
+
+	if ports, err = db.PortGetIdentified(ctx); err != nil {
+		return 0, err
+	} else if err = db.Begin(ctx); err != nil {
+		return 0, err
+	} else if cnt, err = m.MatchPorts(ctx, db, ports); err != nil {
+		db.Rollback() // nolint: errcheck
+		return 0, err
+	} else if err = db.Commit(); err != nil {
+		return 0, err
+	}
+
+	m.log.Printf("[INFO] Found %d vulnerabilities on %d Ports\n",
+		cnt,
+		len(ports))
+
+	return cnt, nil
+} // func (m *Matcher) MatchAll(ctx context.Context, db database.HostDB) (int, error)
+
+// ErrNoFiles is returned by ImportAll if it is not given any files.
+var ErrNoFiles = errors.New("No files to import")
+
+// ImportAll imports the Vulnerabilities from all the given files, then
+// matches them against the Ports in the database. It returns the number of
+// Vulnerabilities imported and the number of matches.
+func ImportAll(ctx context.Context, db database.HostDB, paths ...string) (int, int, error) {
+	var (
+		err           error
+		m             *Matcher
+		total, cnt, n int
+	)
+
+	if len(paths) == 0 {
+		return 0, 0, ErrNoFiles
+	} else if m, err = NewMatcher(); err != nil {
+		return 0, 0, err
+	}
+
+	for _, path := range paths {
+		if n, err = Import(ctx, db, path); err != nil {
+			m.log.Printf("[ERROR] Cannot import %s: %s\n", path, err.Error())
+			return total, 0, err
+		}
+
+		m.log.Printf("[INFO] Imported %d vulnerabilities from %s\n", n, path)
+		total += n
+	}
+
+	if cnt, err = m.MatchAll(ctx, db); err != nil {
+		m.log.Printf("[ERROR] Cannot match vulnerabilities: %s\n", err.Error())
+		return total, 0, err
+	}
+
+	return total, cnt, nil
+} // func ImportAll(ctx context.Context, db database.HostDB, paths ...string) (int, int, error)
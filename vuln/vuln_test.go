@@ -0,0 +1,361 @@
+// /home/krylon/go/src/github.com/blicero/guang/vuln/vuln_test.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 17:04:39 krylon>
+
+package vuln
+
+import (
+	"compress/gzip"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blicero/guang/data"
+	"github.com/blicero/guang/database"
+	"github.com/blicero/guang/rules/severity"
+)
+
+const nvd11 = `{
+  "CVE_data_type" : "CVE",
+  "CVE_Items" : [ {
+    "cve" : {
+      "CVE_data_meta" : { "ID" : "CVE-2018-15473" },
+      "description" : { "description_data" : [ { "lang" : "en", "value" : "OpenSSH through 7.7 is prone to a user enumeration vulnerability." } ] }
+    },
+    "configurations" : {
+      "nodes" : [ {
+        "operator" : "OR",
+        "children" : [ ],
+        "cpe_match" : [ {
+          "vulnerable" : true,
+          "cpe23Uri" : "cpe:2.3:a:openbsd:openssh:*:*:*:*:*:*:*:*",
+          "versionEndIncluding" : "7.7"
+        }, {
+          "vulnerable" : false,
+          "cpe23Uri" : "cpe:2.3:o:debian:debian_linux:8.0:*:*:*:*:*:*:*"
+        } ]
+      } ]
+    },
+    "impact" : {
+      "baseMetricV3" : { "cvssV3" : { "baseScore" : 5.3, "baseSeverity" : "MEDIUM" } },
+      "baseMetricV2" : { "cvssV2" : { "baseScore" : 5.0 }, "severity" : "MEDIUM" }
+    },
+    "publishedDate" : "2018-08-17T19:29Z"
+  } ]
+}`
+
+const nvd20 = `{
+  "format": "NVD_CVE",
+  "version": "2.0",
+  "vulnerabilities": [ {
+    "cve": {
+      "id": "CVE-2016-0777",
+      "published": "2016-01-14T22:59:01.140",
+      "descriptions": [ { "lang": "en", "value": "The roaming feature in OpenSSH 5.x through 7.x before 7.1p2 leaks memory." } ],
+      "metrics": {
+        "cvssMetricV2": [ { "cvssData": { "baseScore": 4.0 }, "baseSeverity": "MEDIUM" } ]
+      },
+      "configurations": [ {
+        "nodes": [ {
+          "operator": "OR",
+          "negate": false,
+          "cpeMatch": [
+            { "vulnerable": true, "criteria": "cpe:2.3:a:openbsd:openssh:7.1:p1:*:*:*:*:*:*" },
+            { "vulnerable": true, "criteria": "cpe:2.3:a:openbsd:openssh:*:*:*:*:*:*:*:*", "versionStartIncluding": "5.4", "versionEndExcluding": "7.1" }
+          ]
+        } ]
+      } ]
+    }
+  } ]
+}`
+
+const osv = `{
+  "id": "DSA-4387-1",
+  "summary": "openssh - security update",
+  "published": "2019-02-09T00:00:00Z",
+  "affected": [ {
+    "package": { "ecosystem": "Debian:9", "name": "openssh" },
+    "ranges": [ { "type": "ECOSYSTEM", "events": [ { "introduced": "0" }, { "fixed": "1:7.4p1-10+deb9u5" } ] } ]
+  } ],
+  "database_specific": { "severity": "HIGH" }
+}`
+
+func TestCompareVersions(t *testing.T) {
+	type testCase struct {
+		a, b string
+		res  int
+	}
+
+	var cases = []testCase{
+		{a: "7.4", b: "7.4", res: 0},
+		{a: "7.4", b: "7.7", res: -1},
+		{a: "7.10", b: "7.9", res: 1},
+		{a: "7.9p1", b: "7.9", res: 1},
+		{a: "7.9p1", b: "7.9p2", res: -1},
+		{a: "1.0.2a", b: "1.0.2", res: 1},
+		{a: "2.0rc1", b: "2.0", res: -1},
+		{a: "2.0", b: "2.0-beta2", res: 1},
+		{a: "v1.18.0", b: "1.18", res: 1},
+		{a: "2.4.007", b: "2.4.7", res: 0},
+		{a: "9.11.4-P2", b: "9.11.4-P10", res: -1},
+	}
+
+	for i, c := range cases {
+		if res := CompareVersions(c.a, c.b); res != c.res {
+			t.Errorf("Case %d: CompareVersions(%q, %q) returned %d, expected %d",
+				i,
+				c.a,
+				c.b,
+				res,
+				c.res)
+		}
+	}
+} // func TestCompareVersions(t *testing.T)
+
+func TestParseCPE(t *testing.T) {
+	type testCase struct {
+		name string
+		ok   bool
+		cpe  CPE
+	}
+
+	var cases = []testCase{
+		{
+			name: "cpe:/a:openbsd:openssh:7.4",
+			ok:   true,
+			cpe:  CPE{Part: "a", Vendor: "openbsd", Product: "openssh", Version: "7.4"},
+		},
+		{
+			name: "cpe:/a:apache:http_server",
+			ok:   true,
+			cpe:  CPE{Part: "a", Vendor: "apache", Product: "http_server"},
+		},
+		{
+			name: "cpe:2.3:a:openbsd:openssh:7.9:p1:*:*:*:*:*:*",
+			ok:   true,
+			cpe:  CPE{Part: "a", Vendor: "openbsd", Product: "openssh", Version: "7.9", Update: "p1"},
+		},
+		{
+			name: `cpe:2.3:a:foo\:bar:baz:-:*:*:*:*:*:*:*`,
+			ok:   true,
+			cpe:  CPE{Part: "a", Vendor: "foo:bar", Product: "baz", Version: NA},
+		},
+		{
+			name: "cpe:2.3:a:openbsd:openssh:7.9:-:*:*:*:*:*:*",
+			ok:   true,
+			cpe:  CPE{Part: "a", Vendor: "openbsd", Product: "openssh", Version: "7.9", Update: NA},
+		},
+		{name: "openssh 7.4"},
+		{name: "cpe:/a"},
+	}
+
+	for i, c := range cases {
+		var cpe, err = ParseCPE(c.name)
+
+		if (err == nil) != c.ok {
+			t.Errorf("Case %d: ParseCPE(%q) returned %v", i, c.name, err)
+		} else if c.ok && cpe != c.cpe {
+			t.Errorf("Case %d: ParseCPE(%q) returned %#v, expected %#v",
+				i,
+				c.name,
+				cpe,
+				c.cpe)
+		}
+	}
+} // func TestParseCPE(t *testing.T)
+
+func TestAffectsNA(t *testing.T) {
+	var (
+		cpe, _ = ParseCPE("cpe:2.3:h:example:router:-:*:*:*:*:*:*:*")
+		na     = data.AffectedRange{Vendor: "example", Product: "router", Version: cpe.FullVersion()}
+		wild   = data.AffectedRange{Vendor: "example", Product: "router"}
+		upTo   = data.AffectedRange{Vendor: "example", Product: "router", EndExcl: "2.0"}
+	)
+
+	if cpe.FullVersion() != NA {
+		t.Fatalf("Version NA was lost: %#v", cpe)
+	} else if Affects(&na, "example", "1.0") {
+		t.Error("A range with version NA affects version 1.0")
+	} else if !Affects(&na, "example", NA) {
+		t.Error("A range with version NA does not affect version NA")
+	} else if !Affects(&wild, "example", "1.0") {
+		t.Error("A range with any version does not affect version 1.0")
+	} else if !Affects(&wild, "example", NA) {
+		t.Error("A range with any version does not affect version NA")
+	} else if Affects(&upTo, "example", NA) {
+		t.Error("A range with bounds affects version NA")
+	}
+
+	if cpe, _ = ParseCPE("cpe:2.3:a:openbsd:openssh:7.9:-:*:*:*:*:*:*"); cpe.FullVersion() != "7.9" {
+		t.Errorf("Unexpected full version %q", cpe.FullVersion())
+	}
+} // func TestAffectsNA(t *testing.T)
+
+func TestDecode(t *testing.T) {
+	type testCase struct {
+		doc      string
+		name     string
+		score    float64
+		sev      severity.Severity
+		ranges   int
+		affected []string
+		safe     []string
+	}
+
+	var cases = []testCase{
+		{
+			doc:      nvd11,
+			name:     "CVE-2018-15473",
+			score:    5.3,
+			sev:      severity.Medium,
+			ranges:   1,
+			affected: []string{"5.3", "7.7", "7.7p1"},
+			safe:     []string{"7.8", "8.0p1"},
+		},
+		{
+			doc:      nvd20,
+			name:     "CVE-2016-0777",
+			score:    4.0,
+			sev:      severity.Medium,
+			ranges:   2,
+			affected: []string{"5.4", "6.6p1", "7.1p1"},
+			safe:     []string{"5.3", "7.1", "7.1p2"},
+		},
+		{
+			doc:      osv,
+			name:     "DSA-4387-1",
+			sev:      severity.High,
+			ranges:   1,
+			affected: []string{"7.4p1"},
+			safe:     []string{"7.9p1"},
+		},
+		{
+			doc:    "[" + osv + "]",
+			name:   "DSA-4387-1",
+			sev:    severity.High,
+			ranges: 1,
+		},
+	}
+
+	for i, c := range cases {
+		var (
+			err   error
+			vulns []data.Vulnerability
+		)
+
+		if err = Decode(strings.NewReader(c.doc), "test", func(v *data.Vulnerability) error {
+			vulns = append(vulns, *v)
+			return nil
+		}); err != nil {
+			t.Errorf("Case %d: Cannot decode document: %s", i, err.Error())
+			continue
+		} else if len(vulns) != 1 {
+			t.Errorf("Case %d: Expected 1 Vulnerability, got %d", i, len(vulns))
+			continue
+		}
+
+		var v = vulns[0]
+
+		if v.Name != c.name || v.Score != c.score || v.Severity != c.sev || v.Published.IsZero() {
+			t.Errorf("Case %d: Unexpected Vulnerability: %#v", i, v)
+		} else if len(v.Ranges) != c.ranges {
+			t.Errorf("Case %d: Expected %d ranges, got %#v", i, c.ranges, v.Ranges)
+		}
+
+		for _, version := range c.affected {
+			if !affected(v.Ranges, "openbsd", version) {
+				t.Errorf("Case %d: %s should affect OpenSSH %s", i, v.Name, version)
+			}
+		}
+
+		for _, version := range c.safe {
+			if affected(v.Ranges, "openbsd", version) {
+				t.Errorf("Case %d: %s should not affect OpenSSH %s", i, v.Name, version)
+			}
+		}
+	}
+
+	if err := Decode(strings.NewReader("{ broken"), "test", func(*data.Vulnerability) error { return nil }); err == nil {
+		t.Error("Decoding broken JSON did not fail")
+	}
+} // func TestDecode(t *testing.T)
+
+func affected(ranges []data.AffectedRange, vendor, version string) bool {
+	for i := range ranges {
+		if Affects(&ranges[i], vendor, version) {
+			return true
+		}
+	}
+
+	return false
+} // func affected(ranges []data.AffectedRange, vendor, version string) bool
+
+func TestImport(t *testing.T) {
+	var (
+		err     error
+		db      database.HostDB
+		fh      *os.File
+		n, cnt  int
+		found   []data.VulnMatch
+		ctx     = context.Background()
+		dir     = t.TempDir()
+		feed    = filepath.Join(dir, "nvdcve-1.1-2018.json.gz")
+		osvFile = filepath.Join(dir, "DSA-4387-1.json")
+		reply   = "SSH-2.0-OpenSSH_7.4"
+		host    = data.Host{
+			Address: net.ParseIP("192.0.2.22"),
+			Name:    "ssh.example.com",
+			Source:  data.HostSourceUser,
+		}
+	)
+
+	if fh, err = os.Create(feed); err != nil {
+		t.Fatalf("Cannot create %s: %s", feed, err.Error())
+	}
+
+	var gz = gzip.NewWriter(fh)
+	gz.Write([]byte(nvd11)) // nolint: errcheck
+	gz.Close()              // nolint: errcheck
+	fh.Close()              // nolint: errcheck
+
+	if err = os.WriteFile(osvFile, []byte(osv), 0600); err != nil {
+		t.Fatalf("Cannot write %s: %s", osvFile, err.Error())
+	} else if db, err = database.OpenDB(filepath.Join(dir, "vuln.db")); err != nil {
+		t.Fatalf("Cannot open database: %s", err.Error())
+	}
+
+	defer db.Close()
+
+	if _, err = db.HostUpsert(ctx, &host); err != nil {
+		t.Fatalf("Cannot add Host: %s", err.Error())
+	} else if err = db.PortAdd(ctx, &data.ScanResult{
+		Host:  host,
+		Port:  22,
+		Reply: &reply,
+		Software: data.Software{
+			Service: "ssh",
+			Product: "OpenSSH",
+			Version: "7.4",
+			CPE:     "cpe:/a:openbsd:openssh:7.4",
+		},
+		Stamp: time.Now(),
+	}); err != nil {
+		t.Fatalf("Cannot add Port: %s", err.Error())
+	} else if n, cnt, err = ImportAll(ctx, db, feed, osvFile); err != nil {
+		t.Fatalf("Cannot import vulnerabilities: %s", err.Error())
+	} else if n != 2 || cnt != 2 {
+		t.Errorf("Imported %d vulnerabilities with %d matches, expected 2 and 2", n, cnt)
+	} else if found, err = db.VulnGetByHost(ctx, host.ID); err != nil {
+		t.Fatalf("Cannot load vulnerabilities of Host: %s", err.Error())
+	} else if len(found) != 2 || found[0].Vuln.Name != "CVE-2018-15473" || found[1].Vuln.Name != "DSA-4387-1" {
+		t.Errorf("Unexpected vulnerabilities: %#v", found)
+	} else if _, _, err = ImportAll(ctx, db, filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("Importing a missing file did not fail")
+	}
+} // func TestImport(t *testing.T)
@@ -0,0 +1,19 @@
+// /home/krylon/go/src/github.com/blicero/guang/xfr/metrics.go
+// -*- mode: go; coding: utf-8; -*-
+// Created on 19. 10. 2026 by Benjamin Walkenhorst
+// (c) 2026 Benjamin Walkenhorst
+// Time-stamp: <2026-10-19 14:41:18 krylon>
+
+package xfr
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var xfrAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "guang",
+	Subsystem: "xfr",
+	Name:      "attempts_total",
+	Help:      "Number of zone transfers attempted, by outcome.",
+}, []string{"status"})
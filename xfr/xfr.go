@@ -2,14 +2,14 @@
 // -*- coding: utf-8; mode: go; -*-
 // Created on 25. 12. 2015 by Benjamin Walkenhorst
 // (c) 2015 Benjamin Walkenhorst
-// Time-stamp: <2022-11-23 22:18:11 krylon>
+// Time-stamp: <2026-10-19 16:56:40 krylon>
 
 package xfr
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"log"
 	"net"
 	"os"
 	"path/filepath"
@@ -21,6 +21,8 @@ import (
 	"github.com/blicero/guang/common"
 	"github.com/blicero/guang/data"
 	"github.com/blicero/guang/database"
+	"github.com/blicero/guang/events"
+	"github.com/blicero/guang/logdomain"
 	"github.com/blicero/guang/xfr/xfrstatus"
 	"github.com/blicero/krylib"
 
@@ -39,20 +41,23 @@ type Client struct {
 	res          *dns.Client
 	requestQueue chan string
 	RC           chan data.ControlMessage
-	log          *log.Logger
+	log          *common.Logger
 	hostRe       *regexp.Regexp
 	nameBL       *blacklist.NameBlacklist
 	addrBL       *blacklist.IPBlacklist
+	bus          *events.Bus
 	workerCnt    int
 	lock         sync.RWMutex
 	isRunning    bool
 }
 
-// MakeXFRClient creates a new XFRClient
-func MakeXFRClient(queue chan string) (*Client, error) {
+// MakeXFRClient creates a new XFRClient. It publishes the status of zone
+// transfers and the Hosts it finds on bus, which may be nil.
+func MakeXFRClient(queue chan string, bus *events.Bus) (*Client, error) {
 	var err error
 	var client *Client = &Client{
 		requestQueue: queue,
+		bus:          bus,
 		RC:           make(chan data.ControlMessage, 4),
 		hostRe:       regexp.MustCompile(hostRePat),
 		nameBL:       blacklist.DefaultNameBlacklist(),
@@ -62,13 +67,13 @@ func MakeXFRClient(queue chan string) (*Client, error) {
 
 	client.res.Net = "tcp"
 
-	if client.log, err = common.GetLogger("XFRClient"); err != nil {
+	if client.log, err = common.GetLogger(logdomain.XFR); err != nil {
 		fmt.Printf("Error getting Logger instance for XFRClient: %s\n", err.Error())
 		return nil, err
 	}
 
 	return client, nil
-} // func MakeXFRClient(queue chan string) (*XFRClient, error)
+} // func MakeXFRClient(queue chan string, bus *events.Bus) (*Client, error)
 
 // Start starts the XFRCient
 func (xfrc *Client) Start(cnt int) {
@@ -79,7 +84,7 @@ func (xfrc *Client) Start(cnt int) {
 
 	for i := 1; i <= cnt; i++ {
 		if common.Debug {
-			xfrc.log.Printf("Starting XFR Worker #%d\n", i)
+			xfrc.log.Debug("Starting XFR worker", "worker", i)
 		}
 		go xfrc.worker(i)
 		//xfrc.workerCnt++
@@ -107,6 +112,23 @@ func (xfrc *Client) Count() int {
 	return c
 } // func (xfrc *XFRClient) WorkerCount() int
 
+// QueueLength returns the number of zones waiting to be transferred.
+func (xfrc *Client) QueueLength() int {
+	return len(xfrc.requestQueue)
+} // func (xfrc *Client) QueueLength() int
+
+// Enqueue asks for a transfer of the zone the host name is part of. It
+// blocks while the queue is full.
+func (xfrc *Client) Enqueue(hostname string) {
+	xfrc.requestQueue <- hostname
+} // func (xfrc *Client) Enqueue(hostname string)
+
+// Blacklists returns the blacklists the Client uses to filter the names
+// and addresses it finds.
+func (xfrc *Client) Blacklists() (*blacklist.NameBlacklist, *blacklist.IPBlacklist) {
+	return xfrc.nameBL, xfrc.addrBL
+} // func (xfrc *Client) Blacklists() (*blacklist.NameBlacklist, *blacklist.IPBlacklist)
+
 func (xfrc *Client) cntInc() {
 	xfrc.lock.Lock()
 	xfrc.workerCnt++
@@ -121,21 +143,20 @@ func (xfrc *Client) cntDec() {
 
 func (xfrc *Client) worker(workerID int) {
 	var (
-		hostname, zone, msg string
-		err                 error
-		xfr                 *data.XFR
-		submatch            []string
-		db                  *database.HostDB
-		pulse               *time.Ticker
+		hostname, zone string
+		err            error
+		xfr            *data.XFR
+		submatch       []string
+		db             database.HostDB
+		pulse          *time.Ticker
+		ctx            = context.Background()
 	)
 
 	xfrc.cntInc()
 	defer xfrc.cntDec()
 
 	if db, err = database.OpenDB(common.DbPath); err != nil {
-		msg = fmt.Sprintf("Error opening database at %s: %s",
-			common.DbPath, err.Error())
-		xfrc.log.Println(msg)
+		xfrc.log.Error("Cannot open database", "path", common.DbPath, "err", err)
 		return
 	}
 
@@ -152,8 +173,7 @@ LOOP:
 		case ctl := <-xfrc.RC:
 			switch ctl {
 			case data.CtlMsgStop:
-				xfrc.log.Printf("[INFO] XFR worker %d is quitting.\n",
-					workerID)
+				xfrc.log.Info("XFR worker is quitting", "worker", workerID)
 				return
 			case data.CtlMsgShutdown:
 				xfrc.Stop()
@@ -162,8 +182,7 @@ LOOP:
 				var cnt = xfrc.Count()
 				go xfrc.worker(cnt + 1)
 			default:
-				xfrc.log.Printf("[INFO] Don't know how to handle command %s\n",
-					ctl)
+				xfrc.log.Warn("Don't know how to handle command", "command", ctl)
 			}
 			continue LOOP
 		case <-pulse.C:
@@ -171,32 +190,29 @@ LOOP:
 		}
 
 		if common.Debug {
-			xfrc.log.Printf("XFR Worker #%d got request for host %s\n",
-				workerID, hostname)
+			xfrc.log.Debug("XFR worker got request", "worker", workerID, "host", hostname)
 		}
 
 		if submatch = xfrc.hostRe.FindStringSubmatch(hostname); submatch == nil {
-			msg = fmt.Sprintf("Error extracting zone from hostname %s", hostname)
-			xfrc.log.Println(msg)
+			xfrc.log.Warn("Cannot extract zone from hostname", "host", hostname)
 			continue LOOP
 		} else if len(submatch) == 0 {
-			msg = fmt.Sprintf("CANTHAPPEN: Did not find zone in hostname: %s", hostname)
-			xfrc.log.Println(msg)
+			xfrc.log.Error("CANTHAPPEN: Did not find zone in hostname", "host", hostname)
 			continue LOOP
 		} else if common.Debug {
-			xfrc.log.Printf("XFR#%d - extracted zone %s from hostname %s\n",
-				workerID, submatch[1], hostname)
+			xfrc.log.Debug("Extracted zone from hostname",
+				"worker", workerID,
+				"zone", submatch[1],
+				"host", hostname)
 		}
 
 		zone = submatch[1]
-		if xfr, err = db.XfrGetByZone(zone); err != nil {
-			msg = fmt.Sprintf("Error looking up XFR of %s: %s",
-				zone, err.Error())
-			xfrc.log.Println(msg)
-			continue LOOP
-		} else if xfr != nil {
+		if _, err = db.XfrGetByZone(ctx, zone); err == nil {
 			// Looks like we've been down that road before...
 			continue LOOP
+		} else if !errors.Is(err, database.ErrNotFound) {
+			xfrc.log.Error("Cannot look up XFR", "zone", zone, "err", err)
+			continue LOOP
 		}
 
 		xfr = &data.XFR{
@@ -206,45 +222,77 @@ LOOP:
 			Status: xfrstatus.Unfinished,
 		}
 
-		if err = db.XfrAdd(xfr); err != nil {
-			msg = fmt.Sprintf("Error adding XFR of %s to database: %s",
-				zone, err.Error())
-			xfrc.log.Println(msg)
+		if err = db.XfrAdd(ctx, xfr); err != nil {
+			xfrc.log.Error("Cannot add XFR to database", "zone", zone, "err", err)
 			continue LOOP
 		} else if xfr.ID == krylib.INVALID_ID {
-			xfrc.log.Printf("I added the XFR of %s to the database, but the ID was not set!\n",
-				zone)
+			xfrc.log.Error("XFR was added to the database, but the ID was not set", "zone", zone)
 			continue LOOP
 		}
 
+		xfrc.publish(xfr)
+
 		var status xfrstatus.XfrStatus
 
-		if err = xfrc.performXfr(zone, db); err != nil {
+		if err = xfrc.performXfr(ctx, zone, db); err != nil {
 			status = xfrstatus.Refused
 		} else {
 			status = xfrstatus.Success
 		}
 
-		if err = db.XfrFinish(xfr, status); err != nil {
-			msg = fmt.Sprintf("Error finishing XFR of %s with status %s: %s",
-				zone, status.String(), err.Error())
-			xfrc.log.Println(msg)
+		if err = db.XfrFinish(ctx, xfr, status); err != nil {
+			xfrc.log.Error("Cannot finish XFR",
+				"zone", zone,
+				"status", status,
+				"err", err)
+		} else {
+			xfrc.publish(xfr)
 		}
+
+		xfrAttempts.WithLabelValues(status.String()).Inc()
 	}
 } // func (xfrc *XFRClient) worker()
 
-func (xfrc *Client) performXfr(zone string, db *database.HostDB) error {
+// publish announces the current status of an XFR on the event bus.
+func (xfrc *Client) publish(x *data.XFR) {
+	var snapshot = *x
+
+	xfrc.bus.Publish(events.Event{
+		Kind: events.XfrStatus,
+		XFR:  &snapshot,
+	})
+} // func (xfrc *Client) publish(x *data.XFR)
+
+// addHost adds a Host found in a zone transfer to the database and, if it
+// was not known before, announces it on the event bus.
+func (xfrc *Client) addHost(ctx context.Context, db database.HostDB, host *data.Host) error {
+	var (
+		err   error
+		isNew bool
+	)
+
+	if isNew, err = db.HostUpsert(ctx, host); err != nil {
+		return err
+	} else if isNew {
+		var h = *host
+		xfrc.bus.Publish(events.Event{
+			Kind: events.HostAdded,
+			Host: &h,
+		})
+	}
+
+	return nil
+} // func (xfrc *Client) addHost(ctx context.Context, db database.HostDB, host *data.Host) error
+
+func (xfrc *Client) performXfr(ctx context.Context, zone string, db database.HostDB) error {
 	var err error
-	var msg string
 	var nsRecords []*net.NS
 	var res bool
 
 	// First, need to get the nameservers for the zone:
 	if nsRecords, err = net.LookupNS(zone); err != nil {
-		msg = fmt.Sprintf("Error looking up nameservers for %s: %s",
-			zone, err.Error())
-		xfrc.log.Println(msg)
-		return errors.New(msg)
+		xfrc.log.Warn("Cannot look up nameservers", "zone", zone, "err", err)
+		return fmt.Errorf("Error looking up nameservers for %s: %w", zone, err)
 	}
 
 	servers := make([]net.IP, 0)
@@ -253,40 +301,34 @@ func (xfrc *Client) performXfr(zone string, db *database.HostDB) error {
 		var addr []net.IP
 
 		if addr, err = net.LookupIP(srv.Host); err != nil {
-			msg = fmt.Sprintf("Error looking up %s: %s",
-				srv.Host, err.Error())
-			xfrc.log.Println(msg)
+			xfrc.log.Warn("Cannot look up nameserver", "zone", zone, "server", srv.Host, "err", err)
 		} else {
 			servers = append(servers, addr...)
 		}
 	}
 
 	if len(servers) == 0 {
-		msg = fmt.Sprintf("Did not find any nameservers for %s", zone)
-		xfrc.log.Println(msg)
-		return errors.New(msg)
+		xfrc.log.Warn("Did not find any nameservers", "zone", zone)
+		return fmt.Errorf("Did not find any nameservers for %s", zone)
 	}
 
 	for _, srv := range servers {
-		if res, err = xfrc.attemptXfr(zone, srv, db); err != nil {
-			msg = fmt.Sprintf("Error asking %s for XFR of %s: %s",
-				srv.String(), zone, err.Error())
-			xfrc.log.Println(msg)
+		if res, err = xfrc.attemptXfr(ctx, zone, srv, db); err != nil {
+			xfrc.log.Warn("XFR failed", "zone", zone, "server", srv, "err", err)
 		} else if res {
 			return nil
 		}
 	}
 
-	msg = fmt.Sprintf("None of the %d servers I asked wanted to give me an XFR of %s: %s",
-		len(servers), zone, err.Error())
-	xfrc.log.Println(msg)
-	return errors.New(msg)
-} // func (xfrc *XFRClient) performXfr(zone string) error
+	// err is nil if the last server answered, but without any records.
+	xfrc.log.Info("No server allowed an XFR", "zone", zone, "servers", len(servers), "err", err)
+	return fmt.Errorf("None of the %d servers I asked wanted to give me an XFR of %s",
+		len(servers), zone)
+} // func (xfrc *XFRClient) performXfr(ctx context.Context, zone string, db database.HostDB) error
 
 // Samstag, 26. 12. 2015, 00:44
 // Maybe I should factor this method into yet more sub-methods. It's rather long...
-func (xfrc *Client) attemptXfr(zone string, srv net.IP, db *database.HostDB) (bool, error) {
-	var msg string
+func (xfrc *Client) attemptXfr(ctx context.Context, zone string, srv net.IP, db database.HostDB) (bool, error) {
 	var err error
 	var rrCnt int64
 	var xfrMsg dns.Msg
@@ -296,15 +338,13 @@ func (xfrc *Client) attemptXfr(zone string, srv net.IP, db *database.HostDB) (bo
 
 	xfrMsg.SetAxfr(zone)
 
-	xfrc.log.Printf("Attempting AXFR of %s\n", zone)
+	xfrc.log.Info("Attempting AXFR", "zone", zone, "server", srv)
 
 	xfrPath := filepath.Join(common.XfrDbgPath, zone)
 	fh, err := os.Create(xfrPath)
 	if err != nil {
-		msg = fmt.Sprintf("Error opening dbg file for XFR (%s): %s",
-			xfrPath, err.Error())
-		xfrc.log.Println(msg)
-		return false, errors.New(msg)
+		xfrc.log.Error("Cannot open debug file for XFR", "path", xfrPath, "err", err)
+		return false, fmt.Errorf("Error opening dbg file for XFR (%s): %w", xfrPath, err)
 	}
 
 	defer func() {
@@ -317,10 +357,8 @@ func (xfrc *Client) attemptXfr(zone string, srv net.IP, db *database.HostDB) (bo
 	ns := fmt.Sprintf("[%s]:53", srv.String())
 
 	if envChan, err = xfrc.res.TransferIn(&xfrMsg, ns); err != nil {
-		msg = fmt.Sprintf("Error requesting Transfer of zone %s: %s",
-			zone, err.Error())
-		xfrc.log.Println(msg)
-		return false, errors.New(msg)
+		xfrc.log.Warn("Cannot request transfer", "zone", zone, "server", srv, "err", err)
+		return false, fmt.Errorf("Error requesting Transfer of zone %s: %w", zone, err)
 	}
 
 	for envelope := range envChan {
@@ -328,14 +366,10 @@ func (xfrc *Client) attemptXfr(zone string, srv net.IP, db *database.HostDB) (bo
 		if envelope.Error != nil {
 			err = envelope.Error
 			xfrError = true
-			msg = fmt.Sprintf("Error during AXFR of %s: %s",
-				zone, envelope.Error.Error())
-			xfrc.log.Println(msg)
+			xfrc.log.Warn("Error during AXFR", "zone", zone, "server", srv, "err", envelope.Error)
 			continue
 		}
 
-		var hostExists bool
-
 	RR_LOOP:
 		for _, rr := range envelope.RR {
 			var host data.Host
@@ -353,16 +387,11 @@ func (xfrc *Client) attemptXfr(zone string, srv net.IP, db *database.HostDB) (bo
 					continue RR_LOOP
 				}
 
-				if hostExists, err = db.HostExists(host.Address.String()); err != nil {
-					msg = fmt.Sprintf("Error checking if %s is already in database: %s",
-						host.Address.String(), err.Error())
-					xfrc.log.Println(msg)
-				} else if hostExists {
-					continue RR_LOOP
-				} else if err = db.HostAdd(&host); err != nil {
-					msg = fmt.Sprintf("Error adding host %s/%s to database: %s",
-						host.Address.String(), host.Name, err.Error())
-					xfrc.log.Println(msg)
+				if err = xfrc.addHost(ctx, db, &host); err != nil {
+					xfrc.log.Error("Cannot add host to database",
+						"host", host.Name,
+						"address", host.Address,
+						"err", err)
 				}
 
 			case *dns.NS:
@@ -372,9 +401,7 @@ func (xfrc *Client) attemptXfr(zone string, srv net.IP, db *database.HostDB) (bo
 				}
 
 				if addrList, err = net.LookupHost(host.Name); err != nil {
-					msg = fmt.Sprintf("Error looking up name for Nameserver %s: %s",
-						host.Name, err.Error())
-					xfrc.log.Println(msg)
+					xfrc.log.Warn("Cannot look up nameserver", "zone", zone, "server", host.Name, "err", err)
 					continue RR_LOOP
 				} else {
 				ADDR_LOOP:
@@ -386,16 +413,11 @@ func (xfrc *Client) attemptXfr(zone string, srv net.IP, db *database.HostDB) (bo
 
 						if xfrc.addrBL.MatchesIP(nsHost.Address) {
 							continue ADDR_LOOP
-						} else if hostExists, err = db.HostExists(nsHost.Address.String()); err != nil {
-							msg = fmt.Sprintf("Error checking if %s is already in database: %s",
-								nsHost.Address.String(), err.Error())
-							xfrc.log.Println(msg)
-						} else if hostExists {
-							continue ADDR_LOOP
-						} else if err = db.HostAdd(&nsHost); err != nil {
-							msg = fmt.Sprintf("Error adding Nameserver %s to database: %s",
-								nsHost.Name, err.Error())
-							xfrc.log.Println(msg)
+						} else if err = xfrc.addHost(ctx, db, &nsHost); err != nil {
+							xfrc.log.Error("Cannot add nameserver to database",
+								"host", nsHost.Name,
+								"address", nsHost.Address,
+								"err", err)
 						}
 					}
 				}
@@ -405,13 +427,10 @@ func (xfrc *Client) attemptXfr(zone string, srv net.IP, db *database.HostDB) (bo
 				if xfrc.nameBL.Matches(host.Name) {
 					continue RR_LOOP
 				} else if addrList, err = net.LookupHost(host.Name); err != nil {
-					msg = fmt.Sprintf("Error looking up IP Address for %s: %s",
-						host.Name, err.Error())
-					xfrc.log.Println(msg)
+					xfrc.log.Warn("Cannot look up mail exchanger", "zone", zone, "host", host.Name, "err", err)
 					continue RR_LOOP
 				}
 
-			MX_HOST:
 				for _, addr := range addrList {
 					var mxHost data.Host = data.Host{
 						Name:    host.Name,
@@ -419,18 +438,11 @@ func (xfrc *Client) attemptXfr(zone string, srv net.IP, db *database.HostDB) (bo
 						Source:  data.HostSourceMx,
 					}
 
-					if hostExists, err = db.HostExists(mxHost.Address.String()); err != nil {
-						msg = fmt.Sprintf("Error checking if %s is already in database: %s",
-							mxHost.Address.String(), err.Error())
-						xfrc.log.Println(msg)
-					} else if hostExists {
-						continue MX_HOST
-					} else if err = db.HostAdd(&mxHost); err != nil {
-						msg = fmt.Sprintf("Error adding MX %s/%s to database: %s",
-							mxHost.Name,
-							mxHost.Address.String(),
-							err.Error())
-						xfrc.log.Println(msg)
+					if err = xfrc.addHost(ctx, db, &mxHost); err != nil {
+						xfrc.log.Error("Cannot add mail exchanger to database",
+							"host", mxHost.Name,
+							"address", mxHost.Address,
+							"err", err)
 					}
 				}
 
@@ -441,16 +453,11 @@ func (xfrc *Client) attemptXfr(zone string, srv net.IP, db *database.HostDB) (bo
 
 				if xfrc.nameBL.Matches(host.Name) || xfrc.addrBL.MatchesIP(host.Address) {
 					continue RR_LOOP
-				} else if hostExists, err = db.HostExists(host.Address.String()); err != nil {
-					msg = fmt.Sprintf("Error checking if %s exists in database: %s",
-						host.Address.String(), err.Error())
-					xfrc.log.Println(msg)
-				} else if hostExists {
-					continue RR_LOOP
-				} else if err = db.HostAdd(&host); err != nil {
-					msg = fmt.Sprintf("Error adding host %s/%s to database: %s",
-						host.Name, host.Address.String(), err.Error())
-					xfrc.log.Println(msg)
+				} else if err = xfrc.addHost(ctx, db, &host); err != nil {
+					xfrc.log.Error("Cannot add host to database",
+						"host", host.Name,
+						"address", host.Address,
+						"err", err)
 				}
 			}
 
@@ -462,4 +469,4 @@ func (xfrc *Client) attemptXfr(zone string, srv net.IP, db *database.HostDB) (bo
 	}
 
 	return true, nil
-} // func (xfrc *XFRClient) attemptXfr(zone string, srv net.IP, db *HostDB) (bool, error)
+} // func (xfrc *XFRClient) attemptXfr(ctx context.Context, zone string, srv net.IP, db *HostDB) (bool, error)
@@ -2,11 +2,12 @@
 // -*- coding: utf-8; mode: go; -*-
 // Created on 26. 12. 2015 by Benjamin Walkenhorst
 // (c) 2015 Benjamin Walkenhorst
-// Time-stamp: <2022-10-30 21:45:31 krylon>
+// Time-stamp: <2026-10-19 14:39:07 krylon>
 
 package xfr
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"testing"
@@ -33,14 +34,14 @@ func TestCreateClient(t *testing.T) {
 
 	requestQueue = make(chan string)
 
-	if xfrClient, err = MakeXFRClient(requestQueue); err != nil {
+	if xfrClient, err = MakeXFRClient(requestQueue, nil); err != nil {
 		t.Fatalf("Error creating XFRClient: %s", err.Error())
 	}
 } // func TestCreateClient(t *testing.T)
 
 func TestXFR(t *testing.T) {
 	var err error
-	var db *database.HostDB
+	var db database.HostDB
 
 	if db, err = database.OpenDB(common.DbPath); err != nil {
 		t.Fatalf("Error opening database: %s", err.Error())
@@ -48,7 +49,7 @@ func TestXFR(t *testing.T) {
 		defer db.Close()
 	}
 
-	if err = xfrClient.performXfr(reqZone, db); err != nil {
+	if err = xfrClient.performXfr(context.Background(), reqZone, db); err != nil {
 		t.Fatalf("Error performing XFR of %s: %s",
 			reqZone, err.Error())
 	}
@@ -56,12 +57,12 @@ func TestXFR(t *testing.T) {
 
 func TestXFRFail(t *testing.T) {
 	var err error
-	var db *database.HostDB
+	var db database.HostDB
 
 	if db, err = database.OpenDB(common.DbPath); err != nil {
 		t.Fatalf("Error opening HostDB at %s: %s",
 			common.DbPath, err.Error())
-	} else if err = xfrClient.performXfr(reqZoneFail, db); err == nil {
+	} else if err = xfrClient.performXfr(context.Background(), reqZoneFail, db); err == nil {
 		t.Fatalf("Well THAT was unexpected: XFR of %s should have failed, but apparently it did not.",
 			reqZoneFail)
 	}